@@ -0,0 +1,58 @@
+// migratebikes folds the legacy fleet tracker tables (FLEET_BIKES_TABLE and
+// FLEET_SERVICE_TABLE) into the unified bike repository (BIKES_TABLE and
+// BIKE_SERVICE_TABLE). Bikes are matched by ID or registration; unmatched
+// tracker bikes are created. Legacy items are left in place.
+//
+// Usage:
+//
+//	go run ./cmd/migratebikes --dry-run   # report only
+//	go run ./cmd/migratebikes
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/fleet"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo/dynamo"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "Report what would be migrated without writing")
+	flag.Parse()
+
+	ctx := context.Background()
+	_ = godotenv.Load()
+
+	legacy, err := fleet.NewTrackerStore(ctx)
+	if err != nil {
+		log.Fatalf("legacy tracker store: %v", err)
+	}
+	cfg := dynamo.ConfigFromEnv()
+	if cfg.BikesTable == "" || cfg.BikeServiceTable == "" {
+		log.Fatal("BIKES_TABLE and BIKE_SERVICE_TABLE must be set")
+	}
+	// Writing re-keyed entries into the table being read would corrupt it.
+	if cfg.BikesTable == os.Getenv("FLEET_BIKES_TABLE") || cfg.BikeServiceTable == os.Getenv("FLEET_SERVICE_TABLE") {
+		log.Fatal("BIKES_TABLE and BIKE_SERVICE_TABLE must differ from the legacy FLEET_BIKES_TABLE and FLEET_SERVICE_TABLE")
+	}
+	repos, err := dynamo.New(ctx, cfg)
+	if err != nil {
+		log.Fatalf("dynamo repos: %v", err)
+	}
+
+	report, err := fleet.MigrateLegacyTracker(ctx, legacy, repos.Bikes, *dryRun)
+	if err != nil {
+		log.Fatalf("migration failed: %v", err)
+	}
+
+	mode := "migrated"
+	if *dryRun {
+		mode = "would migrate"
+	}
+	log.Printf("%s %d legacy bike(s): %d created, %d merged into existing bikes", mode, report.LegacyBikes, report.Created, report.Merged)
+	log.Printf("%s %d service entr(ies), %d re-keyed to a merged bike ID", mode, report.ServiceEntries, report.RemappedEntries)
+}
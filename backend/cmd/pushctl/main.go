@@ -0,0 +1,285 @@
+// pushctl manages Web Push: VAPID keys and the subscriptions in the bbolt
+// push_subscriptions bucket (../data/push.db). bbolt lets one process open
+// the database at a time, so stop the backend before using the commands
+// that read it.
+//
+// Usage:
+//
+//	go run ./cmd/pushctl generate                   # print a new key pair
+//	go run ./cmd/pushctl rotate [-write-config]     # make a new key current
+//	go run ./cmd/pushctl keys                       # keys and the subscriptions on each
+//	go run ./cmd/pushctl list [-user rita]          # list subscriptions
+//	go run ./cmd/pushctl test -endpoint https://… | -user rita [-title …] [-body …]
+//	go run ./cmd/pushctl prune [-probe] [-dry-run]  # remove dead subscriptions
+//
+// Rotating keeps the old key in VAPID_PREVIOUS_KEYS, so existing
+// subscriptions keep working with the key they were made with until their
+// browsers subscribe again with the new one. Previous keys no subscription
+// uses any more are dropped. -write-config saves the new values to the
+// AppConfig table (APP_CONFIG_TABLE); otherwise set them yourself.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/configdb"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/push"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		usage()
+	}
+	ctx := context.Background()
+	_ = godotenv.Load()
+	loadConfig(ctx)
+
+	cmd, args := os.Args[1], os.Args[2:]
+	switch cmd {
+	case "generate":
+		generate()
+	case "rotate":
+		rotate(ctx, args)
+	case "keys":
+		keys()
+	case "list":
+		list(args)
+	case "test":
+		test(args)
+	case "prune":
+		prune(args)
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: pushctl generate | rotate [-write-config] | keys | list [-user U] | test (-endpoint E | -user U) | prune [-probe] [-dry-run]")
+	os.Exit(2)
+}
+
+func configTable() string {
+	if t := os.Getenv("APP_CONFIG_TABLE"); t != "" {
+		return t
+	}
+	return "AppConfig"
+}
+
+// loadConfig loads env from the AppConfig table, as the backend does.
+func loadConfig(ctx context.Context) {
+	if strings.EqualFold(os.Getenv("APP_CONFIG_ENABLED"), "false") {
+		return
+	}
+	if _, err := configdb.LoadEnvFromDynamo(ctx, configTable()); err != nil {
+		log.Printf("Config DB env load skipped: %v", err)
+	}
+}
+
+func openStore() *push.Store {
+	s, err := push.NewStore()
+	if err != nil {
+		log.Fatalf("push store: %v", err)
+	}
+	return s
+}
+
+func generate() {
+	k, err := push.GenerateVAPIDKey()
+	if err != nil {
+		log.Fatalf("generate: %v", err)
+	}
+	fmt.Printf("# key %s\nVAPID_PUBLIC_KEY=%s\nVAPID_PRIVATE_KEY=%s\n", k.ID, k.Public, k.Private)
+}
+
+func rotate(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("rotate", flag.ExitOnError)
+	writeConfig := fs.Bool("write-config", false, "Save the new keys to the AppConfig table")
+	_ = fs.Parse(args)
+
+	current, previous, err := push.KeysFromEnv()
+	if err != nil {
+		log.Fatalf("current keys: %v", err)
+	}
+	// Opening the store records the current key on subscriptions saved
+	// without one, before it stops being current.
+	s := openStore()
+	inUse := subscriptionsByKey(s)
+	s.Close()
+
+	next, err := push.GenerateVAPIDKey()
+	if err != nil {
+		log.Fatalf("generate: %v", err)
+	}
+	var keep []push.VAPIDKey
+	for _, k := range append([]push.VAPIDKey{current}, previous...) {
+		if inUse[k.ID] > 0 {
+			keep = append(keep, k)
+		} else {
+			log.Printf("dropping key %s: no subscriptions use it", k.ID)
+		}
+	}
+
+	vars := map[string]string{
+		"VAPID_PUBLIC_KEY":    next.Public,
+		"VAPID_PRIVATE_KEY":   next.Private,
+		"VAPID_PREVIOUS_KEYS": push.FormatPreviousKeys(keep),
+	}
+	log.Printf("new current key %s; %d previous key(s) kept for existing subscriptions", next.ID, len(keep))
+	if *writeConfig {
+		if err := configdb.SaveEnvToDynamo(ctx, configTable(), vars); err != nil {
+			log.Fatalf("write config: %v", err)
+		}
+		log.Printf("saved to %s; restart the backend to use the new key", configTable())
+		return
+	}
+	for _, name := range []string{"VAPID_PUBLIC_KEY", "VAPID_PRIVATE_KEY", "VAPID_PREVIOUS_KEYS"} {
+		fmt.Printf("%s=%s\n", name, vars[name])
+	}
+}
+
+func subscriptionsByKey(s *push.Store) map[string]int {
+	out := make(map[string]int)
+	for _, sub := range s.Subscriptions() {
+		out[sub.KeyID]++
+	}
+	return out
+}
+
+func keys() {
+	current, previous, err := push.KeysFromEnv()
+	if err != nil {
+		log.Fatalf("keys: %v", err)
+	}
+	s := openStore()
+	defer s.Close()
+	inUse := subscriptionsByKey(s)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tSTATE\tSUBSCRIPTIONS\tPUBLIC KEY")
+	fmt.Fprintf(w, "%s\tcurrent\t%d\t%s\n", current.ID, inUse[current.ID], current.Public)
+	delete(inUse, current.ID)
+	for _, k := range previous {
+		fmt.Fprintf(w, "%s\tprevious\t%d\t%s\n", k.ID, inUse[k.ID], k.Public)
+		delete(inUse, k.ID)
+	}
+	for id, n := range inUse {
+		fmt.Fprintf(w, "%s\tretired\t%d\t-\n", id, n)
+	}
+	w.Flush()
+}
+
+func list(args []string) {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	user := fs.String("user", "", "Only this user's subscriptions")
+	_ = fs.Parse(args)
+
+	s := openStore()
+	defer s.Close()
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "USER\tKEY\tCREATED\tSTATUS\tENDPOINT")
+	for _, sub := range s.Subscriptions() {
+		if *user != "" && sub.Username != *user {
+			continue
+		}
+		status := "ok"
+		if reason := s.Undeliverable(sub); reason != "" {
+			status = reason
+		}
+		created := "-"
+		if !sub.CreatedAt.IsZero() {
+			created = sub.CreatedAt.Format(time.DateOnly)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", orDash(sub.Username), orDash(sub.KeyID), created, status, sub.Endpoint)
+	}
+	w.Flush()
+}
+
+func test(args []string) {
+	fs := flag.NewFlagSet("test", flag.ExitOnError)
+	endpoint := fs.String("endpoint", "", "Send to this subscription")
+	user := fs.String("user", "", "Send to every subscription of this user")
+	title := fs.String("title", "\U0001F514 Test Notification", "Notification title")
+	body := fs.String("body", "Sent by pushctl", "Notification body")
+	_ = fs.Parse(args)
+	if (*endpoint == "") == (*user == "") {
+		log.Fatal("test needs exactly one of -endpoint or -user")
+	}
+
+	s := openStore()
+	defer s.Close()
+	sent, failed := 0, 0
+	for _, sub := range s.Subscriptions() {
+		if (*endpoint != "" && sub.Endpoint != *endpoint) || (*user != "" && sub.Username != *user) {
+			continue
+		}
+		if err := s.Push(sub, *title, *body, "/"); err != nil {
+			failed++
+			if errors.Is(err, push.ErrGone) {
+				log.Printf("%s: gone, removed", sub.Endpoint)
+			} else {
+				log.Printf("%s: %v", sub.Endpoint, err)
+			}
+			continue
+		}
+		sent++
+		log.Printf("%s: sent", sub.Endpoint)
+	}
+	if sent+failed == 0 {
+		log.Fatal("no matching subscriptions")
+	}
+	log.Printf("%d sent, %d failed", sent, failed)
+}
+
+func prune(args []string) {
+	fs := flag.NewFlagSet("prune", flag.ExitOnError)
+	probe := fs.Bool("probe", false, "Also ask the push service about each subscription (sends an empty message the app ignores)")
+	dryRun := fs.Bool("dry-run", false, "Report what would be removed without removing it")
+	_ = fs.Parse(args)
+
+	s := openStore()
+	defer s.Close()
+	removed, kept := 0, 0
+	for _, sub := range s.Subscriptions() {
+		reason := s.Undeliverable(sub)
+		if reason == "" && *probe {
+			if err := s.Probe(sub); errors.Is(err, push.ErrGone) {
+				reason = "gone from the push service"
+			} else if err != nil {
+				log.Printf("%s: probe failed, keeping: %v", sub.Endpoint, err)
+			}
+		}
+		if reason == "" {
+			kept++
+			continue
+		}
+		removed++
+		log.Printf("%s (%s): %s", sub.Endpoint, orDash(sub.Username), reason)
+		if !*dryRun {
+			if err := s.Unsubscribe(sub.Endpoint); err != nil {
+				log.Fatalf("remove %s: %v", sub.Endpoint, err)
+			}
+		}
+	}
+	mode := "removed"
+	if *dryRun {
+		mode = "would remove"
+	}
+	log.Printf("%s %d subscription(s), %d kept", mode, removed, kept)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -12,8 +12,20 @@ const (
 	// staleJumpMetres: if the GPS moves more than this in staleJumpSecs, treat it as noise.
 	staleJumpMetres = 500.0
 	staleJumpSecs   = 10.0
+	// distanceRetention is how long the per-rider distance trail is kept
+	// for ride-session mileage checks.
+	distanceRetention = 24 * time.Hour
+	// coverageSlack is how far GPS coverage may fall short of either end of
+	// a window before DistanceKm refuses to answer.
+	coverageSlack = 10 * time.Minute
 )
 
+// distanceMark is the rider's cumulative distance at a point in time.
+type distanceMark struct {
+	at      time.Time
+	totalKm float64
+}
+
 // riderState holds mutable per-rider analytics state for the current session.
 type riderState struct {
 	history         []SpeedPoint
@@ -26,6 +38,7 @@ type riderState struct {
 	lastLng         float64
 	lastTimestamp   time.Time
 	currentSpeedKph float64
+	marks           []distanceMark
 }
 
 // Store is the in-memory analytics store for all riders.
@@ -51,9 +64,11 @@ func (s *Store) Record(riderID string, lat, lng float64, speedMps *float64, ts t
 			lastLat:       lat,
 			lastLng:       lng,
 			lastTimestamp: ts,
+			marks:         []distanceMark{{at: ts}},
 		}
 		return
 	}
+	defer state.mark(ts)
 
 	distKm := haversineKm(state.lastLat, state.lastLng, lat, lng)
 	dt := ts.Sub(state.lastTimestamp).Seconds()
@@ -98,6 +113,48 @@ func (s *Store) Record(riderID string, lat, lng float64, speedMps *float64, ts t
 	state.lastTimestamp = ts
 }
 
+// mark appends the current cumulative distance to the trail and drops
+// marks older than distanceRetention.
+func (st *riderState) mark(ts time.Time) {
+	st.marks = append(st.marks, distanceMark{at: ts, totalKm: st.totalDistanceKm})
+	cutoff := ts.Add(-distanceRetention)
+	drop := 0
+	for drop < len(st.marks)-1 && st.marks[drop].at.Before(cutoff) {
+		drop++
+	}
+	st.marks = st.marks[drop:]
+}
+
+// DistanceKm returns the GPS distance the rider covered between from and
+// to. ok is false when there is no data for the rider or the GPS trail
+// doesn't cover the window, so a partial trail never under-reports.
+func (s *Store) DistanceKm(riderID string, from, to time.Time) (km float64, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state, found := s.data[riderID]
+	if !found || len(state.marks) == 0 {
+		return 0, false
+	}
+	marks := state.marks
+	if marks[0].at.After(from.Add(coverageSlack)) || marks[len(marks)-1].at.Before(to.Add(-coverageSlack)) {
+		return 0, false
+	}
+	return round2(totalAt(marks, to) - totalAt(marks, from)), true
+}
+
+// totalAt is the cumulative distance at the last mark not after t.
+func totalAt(marks []distanceMark, t time.Time) float64 {
+	total := marks[0].totalKm
+	for _, m := range marks {
+		if m.at.After(t) {
+			break
+		}
+		total = m.totalKm
+	}
+	return total
+}
+
 // GetSummary returns a snapshot of analytics for the given rider.
 // Returns (summary, true) if data exists, or a zero summary with false if not.
 func (s *Store) GetSummary(riderID string) (RiderSummary, bool) {
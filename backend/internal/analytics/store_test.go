@@ -195,3 +195,36 @@ s.GetSummary("r")
 }
 <-done
 }
+
+// ---- DistanceKm ----
+
+func TestStore_DistanceKm_Window(t *testing.T) {
+	s := newTestStore()
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	// ~1 km north every 2 minutes for 20 minutes.
+	for i := 0; i <= 10; i++ {
+		s.Record("rider1", 53.0+float64(i)*0.009, -6.0, nil, base.Add(time.Duration(i)*2*time.Minute))
+	}
+
+	km, ok := s.DistanceKm("rider1", base, base.Add(20*time.Minute))
+	if !ok || km < 9.5 || km > 10.5 {
+		t.Errorf("expected ~10 km over the whole window, got %v ok=%v", km, ok)
+	}
+	km, ok = s.DistanceKm("rider1", base.Add(10*time.Minute), base.Add(20*time.Minute))
+	if !ok || km < 4.5 || km > 5.5 {
+		t.Errorf("expected ~5 km over the second half, got %v ok=%v", km, ok)
+	}
+}
+
+func TestStore_DistanceKm_NoCoverage(t *testing.T) {
+	s := newTestStore()
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	if _, ok := s.DistanceKm("nobody", base, base.Add(time.Hour)); ok {
+		t.Error("expected no answer for unknown rider")
+	}
+	s.Record("rider1", 53.0, -6.0, nil, base.Add(40*time.Minute))
+	s.Record("rider1", 53.009, -6.0, nil, base.Add(42*time.Minute))
+	if _, ok := s.DistanceKm("rider1", base, base.Add(42*time.Minute)); ok {
+		t.Error("expected no answer when GPS started well after the window")
+	}
+}
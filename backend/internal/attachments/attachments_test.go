@@ -0,0 +1,263 @@
+package attachments
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/blobstore"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/issuereports"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo/memory"
+)
+
+func setup(t *testing.T) (*memory.AttachmentsRepo, *blobstore.FileStore) {
+	t.Helper()
+	atts := memory.NewAttachmentsRepo()
+	store, err := blobstore.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	bikes := memory.NewBikesRepo()
+	issues := memory.NewIssueReportsRepo()
+	ctx := context.Background()
+	_ = issues.Put(ctx, &repo.IssueReport{IssueID: "i1", BikeID: "b1", Type: "Minor", Description: "scratched fairing"})
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b1"})
+	_ = bikes.PutServiceEntry(ctx, &repo.ServiceEntry{ServiceID: "svc_1", BikeID: "b1", ServiceType: "oil"})
+
+	SetRepository(atts)
+	SetBlobStore(store)
+	SetBikesRepository(bikes)
+	issuereports.SetRepository(issues)
+	t.Cleanup(func() {
+		SetRepository(nil)
+		SetBlobStore(nil)
+		SetBikesRepository(nil)
+		issuereports.SetRepository(nil)
+	})
+	return atts, store
+}
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// hugePNG is a valid 1x1 PNG whose header claims 50000x50000 pixels.
+func hugePNG(t *testing.T) []byte {
+	t.Helper()
+	data := testPNG(t, 1, 1)
+	binary.BigEndian.PutUint32(data[16:], 50000)
+	binary.BigEndian.PutUint32(data[20:], 50000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func multipartUpload(t *testing.T, fields map[string]string, fileName string, data []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for k, v := range fields {
+		_ = mw.WriteField(k, v)
+	}
+	fw, _ := mw.CreateFormFile("file", fileName)
+	_, _ = fw.Write(data)
+	_ = mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/attachments", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+// ---- upload ----
+
+func TestUpload_ImageGetsThumbnail(t *testing.T) {
+	setup(t)
+	rec := httptest.NewRecorder()
+	ListOrUpload(rec, multipartUpload(t, map[string]string{"ownerType": "issue", "ownerId": "i1"}, "damage.png", testPNG(t, 800, 400)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var v View
+	_ = json.Unmarshal(rec.Body.Bytes(), &v)
+	if v.ContentType != "image/png" || v.BikeID != "b1" || v.FileName != "damage.png" {
+		t.Errorf("unexpected attachment %+v", v)
+	}
+	if v.URL == "" || v.ThumbnailURL == "" {
+		t.Fatalf("expected signed content and thumbnail URLs, got %+v", v)
+	}
+
+	rec = httptest.NewRecorder()
+	Content(rec, httptest.NewRequest(http.MethodGet, v.ThumbnailURL, nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/jpeg" {
+		t.Fatalf("expected thumbnail, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	thumb, err := jpeg.Decode(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := thumb.Bounds(); b.Dx() != 320 || b.Dy() != 160 {
+		t.Errorf("expected 320x160 thumbnail, got %dx%d", b.Dx(), b.Dy())
+	}
+}
+
+func TestUpload_ServiceEntryPDF(t *testing.T) {
+	setup(t)
+	pdf := []byte("%PDF-1.4\n% invoice\n")
+	rec := httptest.NewRecorder()
+	ListOrUpload(rec, multipartUpload(t, map[string]string{"ownerType": "service", "ownerId": "svc_1", "bikeId": "b1"}, "invoice.pdf", pdf))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var v View
+	_ = json.Unmarshal(rec.Body.Bytes(), &v)
+	if v.ContentType != "application/pdf" || v.ThumbnailURL != "" {
+		t.Errorf("unexpected attachment %+v", v)
+	}
+
+	rec = httptest.NewRecorder()
+	Content(rec, httptest.NewRequest(http.MethodGet, v.URL, nil))
+	if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), pdf) {
+		t.Errorf("expected original PDF back, got %d", rec.Code)
+	}
+}
+
+func TestUpload_Validation(t *testing.T) {
+	setup(t)
+	t.Setenv("ATTACHMENT_MAX_BYTES", "1024")
+	cases := []struct {
+		name   string
+		fields map[string]string
+		data   []byte
+		want   int
+	}{
+		{"unknown issue", map[string]string{"ownerType": "issue", "ownerId": "nope"}, []byte("%PDF-1.4"), http.StatusNotFound},
+		{"unknown service entry", map[string]string{"ownerType": "service", "ownerId": "svc_x", "bikeId": "b1"}, []byte("%PDF-1.4"), http.StatusNotFound},
+		{"service without bike", map[string]string{"ownerType": "service", "ownerId": "svc_1"}, []byte("%PDF-1.4"), http.StatusBadRequest},
+		{"bad owner type", map[string]string{"ownerType": "job", "ownerId": "j1"}, []byte("%PDF-1.4"), http.StatusBadRequest},
+		{"disallowed type", map[string]string{"ownerType": "issue", "ownerId": "i1"}, []byte("<html><script>alert(1)</script>"), http.StatusUnsupportedMediaType},
+		{"too large", map[string]string{"ownerType": "issue", "ownerId": "i1"}, append([]byte("%PDF-1.4"), make([]byte, 2048)...), http.StatusRequestEntityTooLarge},
+		{"too many pixels", map[string]string{"ownerType": "issue", "ownerId": "i1"}, hugePNG(t), http.StatusBadRequest},
+		{"no owner", map[string]string{"ownerType": "issue"}, []byte("%PDF-1.4"), http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		ListOrUpload(rec, multipartUpload(t, c.fields, "f", c.data))
+		if rec.Code != c.want {
+			t.Errorf("%s: expected %d, got %d: %s", c.name, c.want, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestWriteUploadError_StorageErrorsAre500(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeUploadError(rec, fmt.Errorf("store file: %w", errors.New("ValidationException: One of the required keys was not given a value")))
+	if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "ValidationException") {
+		t.Errorf("expected a plain 500, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+// ---- signed links ----
+
+func TestContent_RequiresValidSignature(t *testing.T) {
+	atts, _ := setup(t)
+	a, err := Upload(context.Background(), UploadRequest{OwnerType: "issue", OwnerID: "i1", FileName: "x.pdf", Data: []byte("%PDF-1.4")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list, _ := atts.ListByOwner(context.Background(), "issue", "i1"); len(list) != 1 {
+		t.Fatalf("expected one attachment, got %d", len(list))
+	}
+
+	good := signedURL(a.AttachmentID, "content", time.Now())
+	u, _ := url.Parse(good)
+	q := u.Query()
+	q.Set("sig", strings.Repeat("0", 64))
+	tampered := u.Path + "?" + q.Encode()
+	expired := signedURL(a.AttachmentID, "content", time.Now().Add(-2*urlTTL))
+	otherVariant := signedURL(a.AttachmentID, "thumbnail", time.Now())
+	u, _ = url.Parse(otherVariant)
+	swapped := "/api/attachments/" + a.AttachmentID + "/content?" + u.RawQuery
+
+	for _, target := range []string{tampered, expired, swapped, "/api/attachments/" + a.AttachmentID + "/content"} {
+		rec := httptest.NewRecorder()
+		Content(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("%s: expected 403, got %d", target, rec.Code)
+		}
+	}
+	rec := httptest.NewRecorder()
+	Content(rec, httptest.NewRequest(http.MethodGet, good, nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200 for a valid link, got %d", rec.Code)
+	}
+}
+
+// ---- delete ----
+
+func TestDelete_RemovesBlobs(t *testing.T) {
+	_, store := setup(t)
+	ctx := context.Background()
+	a, err := Upload(ctx, UploadRequest{OwnerType: "issue", OwnerID: "i1", FileName: "p.png", Data: testPNG(t, 10, 10), UploadedBy: "rider1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.ThumbnailKey == "" {
+		t.Fatal("expected a thumbnail")
+	}
+	if err := Delete(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{a.BlobKey, a.ThumbnailKey} {
+		if _, err := store.Get(ctx, key); err != blobstore.ErrNotFound {
+			t.Errorf("expected %s removed, got %v", key, err)
+		}
+	}
+	if _, ok, _ := Get(ctx, a.AttachmentID); ok {
+		t.Error("expected attachment record removed")
+	}
+}
+
+func TestContent_DeletedAttachmentIs404(t *testing.T) {
+	setup(t)
+	ctx := context.Background()
+	a, err := Upload(ctx, UploadRequest{OwnerType: "issue", OwnerID: "i1", FileName: "p.png", Data: testPNG(t, 10, 10)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	links := []string{signedURL(a.AttachmentID, "content", time.Now()), signedURL(a.AttachmentID, "thumbnail", time.Now())}
+	if err := Delete(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	for _, link := range links {
+		rec := httptest.NewRecorder()
+		Content(rec, httptest.NewRequest(http.MethodGet, link, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404 once deleted, got %d", link, rec.Code)
+		}
+	}
+}
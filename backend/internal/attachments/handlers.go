@@ -0,0 +1,252 @@
+package attachments
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/auth"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/blobstore"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+)
+
+// View is an attachment with short-lived signed download links.
+type View struct {
+	repo.Attachment
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnailUrl,omitempty"`
+}
+
+func toView(a repo.Attachment, now time.Time) View {
+	v := View{Attachment: a, URL: signedURL(a.AttachmentID, "content", now)}
+	if a.ThumbnailKey != "" {
+		v.ThumbnailURL = signedURL(a.AttachmentID, "thumbnail", now)
+	}
+	return v
+}
+
+// ListOrUpload handles GET /api/attachments?ownerType=&ownerId= and
+// POST /api/attachments (multipart: ownerType, ownerId, bikeId for service
+// entries, and the file in "file").
+func ListOrUpload(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		if q.Get("ownerType") == "" || q.Get("ownerId") == "" {
+			http.Error(w, "ownerType and ownerId required", http.StatusBadRequest)
+			return
+		}
+		items, err := List(r.Context(), q.Get("ownerType"), q.Get("ownerId"))
+		if err != nil {
+			log.Printf("[attachments] failed to list: %v", err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		now := time.Now()
+		out := make([]View, 0, len(items))
+		for _, a := range items {
+			out = append(out, toView(a, now))
+		}
+		writeJSON(w, http.StatusOK, out)
+	case http.MethodPost:
+		upload(w, r)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func upload(w http.ResponseWriter, r *http.Request) {
+	limit := MaxBytes()
+	// Leave room for the multipart boundaries and form fields.
+	r.Body = http.MaxBytesReader(w, r.Body, limit+1<<20)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var tooBig *http.MaxBytesError
+		if errors.As(err, &tooBig) {
+			http.Error(w, tooLargeMessage(limit), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "invalid multipart form", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "file required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	if header.Size > limit {
+		http.Error(w, tooLargeMessage(limit), http.StatusRequestEntityTooLarge)
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(file, limit+1))
+	if err != nil {
+		http.Error(w, "failed to read file", http.StatusBadRequest)
+		return
+	}
+	if int64(len(data)) > limit {
+		http.Error(w, tooLargeMessage(limit), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	a, err := Upload(r.Context(), UploadRequest{
+		OwnerType:  r.FormValue("ownerType"),
+		OwnerID:    r.FormValue("ownerId"),
+		BikeID:     r.FormValue("bikeId"),
+		FileName:   header.Filename,
+		Data:       data,
+		UploadedBy: auth.UsernameFromContext(r.Context()),
+	})
+	if err != nil {
+		writeUploadError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, toView(*a, time.Now()))
+}
+
+func writeUploadError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errOwnerNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, errUnsupportedType):
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+	case isValidationError(err):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("[attachments] failed to upload: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func tooLargeMessage(limit int64) string {
+	return fmt.Sprintf("file exceeds the %d MB limit", limit>>20)
+}
+
+// Detail handles GET and DELETE /api/attachments/{id}. Only the uploader
+// or a FleetManager may delete.
+func Detail(w http.ResponseWriter, r *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/attachments/"), "/")
+	if id == "" || strings.Contains(id, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	a, ok, err := Get(r.Context(), id)
+	if err != nil {
+		log.Printf("[attachments] failed to get %s: %v", id, err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "attachment not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, toView(*a, time.Now()))
+	case http.MethodDelete:
+		caller := auth.UsernameFromContext(r.Context())
+		if caller != a.UploadedBy && !auth.HasRoleOrAbove(auth.RolesFromContext(r.Context()), "FleetManager") {
+			http.Error(w, "only the uploader or a fleet manager can delete this attachment", http.StatusForbidden)
+			return
+		}
+		if err := Delete(r.Context(), a); err != nil {
+			log.Printf("[attachments] failed to delete %s: %v", id, err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// IsContentPath reports whether the path is a signed download link, which
+// is authorised by its signature rather than a bearer token.
+func IsContentPath(path string) bool {
+	return strings.HasSuffix(path, "/content") || strings.HasSuffix(path, "/thumbnail")
+}
+
+// Content handles GET /api/attachments/{id}/content and
+// /api/attachments/{id}/thumbnail with a signed exp/sig query.
+func Content(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/attachments/"), "/")
+	id, variant, ok := strings.Cut(rest, "/")
+	if !ok || (variant != "content" && variant != "thumbnail") {
+		http.NotFound(w, r)
+		return
+	}
+	if !verifySignature(id, variant, r.URL.Query(), time.Now()) {
+		http.Error(w, "link invalid or expired", http.StatusForbidden)
+		return
+	}
+	if blobs == nil {
+		http.Error(w, "attachments not configured", http.StatusNotImplemented)
+		return
+	}
+	a, found, err := Get(r.Context(), id)
+	if err != nil {
+		log.Printf("[attachments] failed to get %s: %v", id, err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "attachment not found", http.StatusNotFound)
+		return
+	}
+	key, contentType := a.BlobKey, a.ContentType
+	if variant == "thumbnail" {
+		key, contentType = a.ThumbnailKey, "image/jpeg"
+	}
+	if key == "" {
+		http.Error(w, "attachment not found", http.StatusNotFound)
+		return
+	}
+
+	rc, err := blobs.Get(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, blobstore.ErrNotFound) {
+			http.Error(w, "attachment not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("[attachments] failed to read blob %s: %v", key, err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, max-age=900")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if variant == "content" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", a.FileName))
+	}
+	if _, err := io.Copy(w, rc); err != nil {
+		log.Printf("[attachments] failed to stream %s: %v", key, err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func isValidationError(err error) bool {
+	for _, v := range []error{errInvalidOwnerType, errEmptyFile, errImageTooLarge, errOwnerIDRequired, errBikeIDRequired} {
+		if errors.Is(err, v) {
+			return true
+		}
+	}
+	return false
+}
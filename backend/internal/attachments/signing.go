@@ -0,0 +1,40 @@
+package attachments
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/signer"
+)
+
+// Download links are signed so the browser can load them directly in an
+// <img> or <a> tag, which can't send the Authorization header. A link is
+// only handed out to an authenticated caller and expires after urlTTL.
+const urlTTL = 15 * time.Minute
+
+// Links expire within minutes, so a random key when ATTACHMENT_URL_SECRET
+// is unset is fine for local dev.
+var urlSigner = signer.New("ATTACHMENT_URL_SECRET", "attachment-url", "", 32)
+
+func signaturePayload(id, variant string, exp int64) string {
+	return id + "\n" + variant + "\n" + strconv.FormatInt(exp, 10)
+}
+
+// signedURL returns /api/attachments/{id}/{variant}?exp=...&sig=...
+func signedURL(id, variant string, now time.Time) string {
+	exp := now.Add(urlTTL).Unix()
+	q := url.Values{}
+	q.Set("exp", strconv.FormatInt(exp, 10))
+	q.Set("sig", urlSigner.MAC(signaturePayload(id, variant, exp)))
+	return "/api/attachments/" + url.PathEscape(id) + "/" + variant + "?" + q.Encode()
+}
+
+// verifySignature checks a signed link's signature and expiry.
+func verifySignature(id, variant string, q url.Values, now time.Time) bool {
+	exp, err := strconv.ParseInt(q.Get("exp"), 10, 64)
+	if err != nil || now.Unix() > exp {
+		return false
+	}
+	return urlSigner.CheckMAC(signaturePayload(id, variant, exp), q.Get("sig"))
+}
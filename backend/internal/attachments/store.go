@@ -0,0 +1,258 @@
+package attachments
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/blobstore"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/issuereports"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+)
+
+// Owner types an attachment can hang off.
+const (
+	OwnerIssue   = "issue"
+	OwnerService = "service"
+)
+
+const defaultMaxBytes = 10 << 20
+
+// allowedTypes maps the sniffed content type to the extension stored on
+// the blob key. Anything else is refused.
+var allowedTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"application/pdf": ".pdf",
+}
+
+var (
+	errInvalidOwnerType = errors.New("ownerType must be issue or service")
+	errOwnerNotFound    = errors.New("owner not found")
+	errEmptyFile        = errors.New("file is empty")
+	errUnsupportedType  = errors.New("only JPEG, PNG and PDF files are allowed")
+	errImageTooLarge    = errors.New("image must be at most 40 megapixels")
+	errOwnerIDRequired  = errors.New("ownerId required")
+	errBikeIDRequired   = errors.New("bikeId required for service entry attachments")
+)
+
+var (
+	globalRepo repo.AttachmentsRepository
+	blobs      blobstore.Store
+	bikesRepo  repo.BikesRepository
+)
+
+func SetRepository(r repo.AttachmentsRepository) {
+	globalRepo = r
+}
+
+func SetBlobStore(s blobstore.Store) {
+	blobs = s
+}
+
+// SetBikesRepository lets uploads check that a service entry exists.
+func SetBikesRepository(r repo.BikesRepository) {
+	bikesRepo = r
+}
+
+// MaxBytes is the largest upload accepted, from ATTACHMENT_MAX_BYTES
+// (default 10 MB).
+func MaxBytes() int64 {
+	if v, err := strconv.ParseInt(os.Getenv("ATTACHMENT_MAX_BYTES"), 10, 64); err == nil && v > 0 {
+		return v
+	}
+	return defaultMaxBytes
+}
+
+type UploadRequest struct {
+	OwnerType  string
+	OwnerID    string
+	BikeID     string // service entries are keyed by bike
+	FileName   string
+	Data       []byte
+	UploadedBy string
+}
+
+// Upload validates the owner and the file, stores the file (and a
+// thumbnail for images) and records the attachment.
+func Upload(ctx context.Context, req UploadRequest) (*repo.Attachment, error) {
+	if globalRepo == nil || blobs == nil {
+		return nil, errors.New("attachments not configured")
+	}
+	req.OwnerType = strings.ToLower(strings.TrimSpace(req.OwnerType))
+	req.OwnerID = strings.TrimSpace(req.OwnerID)
+	req.BikeID = strings.TrimSpace(req.BikeID)
+	if req.OwnerID == "" {
+		return nil, errOwnerIDRequired
+	}
+	if len(req.Data) == 0 {
+		return nil, errEmptyFile
+	}
+	contentType := http.DetectContentType(req.Data)
+	ext, ok := allowedTypes[contentType]
+	if !ok {
+		return nil, errUnsupportedType
+	}
+	if strings.HasPrefix(contentType, "image/") {
+		if err := checkImageSize(req.Data); err != nil {
+			return nil, err
+		}
+	}
+	bikeID, err := checkOwner(ctx, req.OwnerType, req.OwnerID, req.BikeID)
+	if err != nil {
+		return nil, err
+	}
+
+	id := newID()
+	a := &repo.Attachment{
+		AttachmentID: id,
+		OwnerType:    req.OwnerType,
+		OwnerID:      req.OwnerID,
+		BikeID:       bikeID,
+		FileName:     cleanFileName(req.FileName, ext),
+		ContentType:  contentType,
+		Size:         int64(len(req.Data)),
+		BlobKey:      blobKey(req.OwnerType, req.OwnerID, id+ext),
+		UploadedBy:   req.UploadedBy,
+		CreatedAt:    time.Now(),
+	}
+	if err := blobs.Put(ctx, a.BlobKey, req.Data, contentType); err != nil {
+		return nil, fmt.Errorf("store file: %w", err)
+	}
+
+	if strings.HasPrefix(contentType, "image/") {
+		thumb, err := makeThumbnail(req.Data)
+		if err != nil {
+			// A corrupt image is still worth keeping; it just has no preview.
+			log.Printf("[attachments] thumbnail failed for %s: %v", id, err)
+		} else {
+			key := blobKey(req.OwnerType, req.OwnerID, id+"_thumb.jpg")
+			if err := blobs.Put(ctx, key, thumb, "image/jpeg"); err != nil {
+				log.Printf("[attachments] failed to store thumbnail for %s: %v", id, err)
+			} else {
+				a.ThumbnailKey = key
+			}
+		}
+	}
+
+	if err := globalRepo.Put(ctx, a); err != nil {
+		removeBlobs(ctx, a)
+		return nil, err
+	}
+	return a, nil
+}
+
+// checkOwner confirms the issue report or service entry exists and returns
+// the bike it belongs to.
+func checkOwner(ctx context.Context, ownerType, ownerID, bikeID string) (string, error) {
+	switch ownerType {
+	case OwnerIssue:
+		ir, ok, err := issuereports.Get(ctx, ownerID)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "", errOwnerNotFound
+		}
+		return ir.BikeID, nil
+	case OwnerService:
+		if bikeID == "" {
+			return "", errBikeIDRequired
+		}
+		if bikesRepo == nil {
+			return "", errors.New("bike repository not configured")
+		}
+		entries, err := bikesRepo.ListServiceEntries(ctx, bikeID)
+		if err != nil {
+			return "", err
+		}
+		for _, e := range entries {
+			if e.ServiceID == ownerID {
+				return bikeID, nil
+			}
+		}
+		return "", errOwnerNotFound
+	default:
+		return "", errInvalidOwnerType
+	}
+}
+
+func List(ctx context.Context, ownerType, ownerID string) ([]repo.Attachment, error) {
+	if globalRepo == nil {
+		return nil, errors.New("attachments not configured")
+	}
+	return globalRepo.ListByOwner(ctx, strings.ToLower(strings.TrimSpace(ownerType)), strings.TrimSpace(ownerID))
+}
+
+func Get(ctx context.Context, id string) (*repo.Attachment, bool, error) {
+	if globalRepo == nil {
+		return nil, false, errors.New("attachments not configured")
+	}
+	return globalRepo.Get(ctx, id)
+}
+
+// Delete removes the attachment record and its blobs.
+func Delete(ctx context.Context, a *repo.Attachment) error {
+	if globalRepo == nil {
+		return errors.New("attachments not configured")
+	}
+	if _, err := globalRepo.Delete(ctx, a.AttachmentID); err != nil {
+		return err
+	}
+	removeBlobs(ctx, a)
+	return nil
+}
+
+func removeBlobs(ctx context.Context, a *repo.Attachment) {
+	for _, key := range []string{a.BlobKey, a.ThumbnailKey} {
+		if key == "" {
+			continue
+		}
+		if err := blobs.Delete(ctx, key); err != nil {
+			log.Printf("[attachments] failed to delete blob %s: %v", key, err)
+		}
+	}
+}
+
+func blobKey(ownerType, ownerID, name string) string {
+	return "attachments/" + ownerType + "/" + safeSegment(ownerID) + "/" + name
+}
+
+// safeSegment keeps an ID usable as a single blob key segment.
+func safeSegment(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, s)
+	if s == "." || s == ".." {
+		return "_"
+	}
+	return s
+}
+
+// cleanFileName keeps the client's file name for display, falling back to
+// "attachment" plus the sniffed extension.
+func cleanFileName(name, ext string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		return "attachment" + ext
+	}
+	return name
+}
+
+func newID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,80 @@
+package attachments
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	_ "image/png" // register PNG for image.Decode
+)
+
+const (
+	thumbnailMaxSide = 320
+	// maxImagePixels bounds what is decoded. A small file can declare huge
+	// dimensions, and decoding allocates for every pixel it declares.
+	maxImagePixels = 40_000_000
+)
+
+// checkImageSize reads only the image header and rejects images with more
+// than maxImagePixels. Headers it can't read are left to the decoder.
+func checkImageSize(data []byte) error {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxImagePixels {
+		return errImageTooLarge
+	}
+	return nil
+}
+
+// makeThumbnail decodes a JPEG or PNG and returns a JPEG no larger than
+// thumbnailMaxSide on either side. Each output pixel averages the source
+// pixels it covers, which is good enough for previews.
+func makeThumbnail(data []byte) ([]byte, error) {
+	if err := checkImageSize(data); err != nil {
+		return nil, err
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	tw, th := w, h
+	if w > thumbnailMaxSide || h > thumbnailMaxSide {
+		if w >= h {
+			tw, th = thumbnailMaxSide, max(1, h*thumbnailMaxSide/w)
+		} else {
+			tw, th = max(1, w*thumbnailMaxSide/h), thumbnailMaxSide
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0 := b.Min.Y + y*h/th
+		y1 := max(y0+1, b.Min.Y+(y+1)*h/th)
+		for x := 0; x < tw; x++ {
+			x0 := b.Min.X + x*w/tw
+			x1 := max(x0+1, b.Min.X+(x+1)*w/tw)
+			var rs, gs, bs, as, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					r, g, b, a := src.At(sx, sy).RGBA()
+					rs, gs, bs, as = rs+uint64(r), gs+uint64(g), bs+uint64(b), as+uint64(a)
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i+0] = uint8(rs / n >> 8)
+			dst.Pix[i+1] = uint8(gs / n >> 8)
+			dst.Pix[i+2] = uint8(bs / n >> 8)
+			dst.Pix[i+3] = uint8(as / n >> 8)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,55 @@
+// Package blobstore stores attachment bytes on the local filesystem or in an
+// S3-compatible bucket.
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrNotFound is returned by Get for a key that isn't stored.
+var ErrNotFound = errors.New("blob not found")
+
+// Store is a flat key/value store for file contents. Keys are slash
+// separated paths such as "attachments/issue/abc/123.jpg".
+type Store interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// FromEnv picks the store from BLOB_STORE:
+//
+//	local (default) – files under BLOB_LOCAL_DIR (default ../data/blobs)
+//	s3              – S3_BUCKET, with optional S3_ENDPOINT for MinIO and
+//	                  friends and S3_REGION (falls back to AWS_REGION)
+func FromEnv(ctx context.Context) (Store, error) {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("BLOB_STORE"))) {
+	case "", "local":
+		dir := os.Getenv("BLOB_LOCAL_DIR")
+		if dir == "" {
+			dir = "../data/blobs"
+		}
+		return NewFileStore(dir)
+	case "s3":
+		return NewS3StoreFromEnv(ctx)
+	default:
+		return nil, errors.New("BLOB_STORE must be local or s3")
+	}
+}
+
+// validKey rejects keys that could escape a directory or bucket prefix.
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return errors.New("invalid blob key")
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return errors.New("invalid blob key")
+		}
+	}
+	return nil
+}
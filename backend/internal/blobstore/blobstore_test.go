@@ -0,0 +1,117 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+func readAll(t *testing.T, s Store, key string) string {
+	t.Helper()
+	rc, err := s.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("get %s: %v", key, err)
+	}
+	defer rc.Close()
+	b, _ := io.ReadAll(rc)
+	return string(b)
+}
+
+// ---- file store ----
+
+func TestFileStore_PutGetDelete(t *testing.T) {
+	s, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := s.Put(ctx, "attachments/issue/i1/a.jpg", []byte("jpeg"), "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readAll(t, s, "attachments/issue/i1/a.jpg"); got != "jpeg" {
+		t.Errorf("expected jpeg, got %q", got)
+	}
+	if err := s.Delete(ctx, "attachments/issue/i1/a.jpg"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, "attachments/issue/i1/a.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := s.Delete(ctx, "attachments/issue/i1/a.jpg"); err != nil {
+		t.Errorf("expected deleting a missing blob to succeed, got %v", err)
+	}
+}
+
+func TestFileStore_RejectsEscapingKeys(t *testing.T) {
+	s, _ := NewFileStore(t.TempDir())
+	for _, key := range []string{"", "/etc/passwd", "../x", "a/../../x", "a//b", `a\b`} {
+		if err := s.Put(context.Background(), key, []byte("x"), ""); err == nil {
+			t.Errorf("expected key %q to be rejected", key)
+		}
+	}
+}
+
+// ---- s3 store ----
+
+func TestS3Store_SignedRequests(t *testing.T) {
+	var mu sync.Mutex
+	objects := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+			http.Error(w, "unsigned", http.StatusForbidden)
+			return
+		}
+		if r.Header.Get("X-Amz-Content-Sha256") == "" {
+			http.Error(w, "missing payload hash", http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			b, _ := io.ReadAll(r.Body)
+			objects[r.URL.Path] = string(b)
+		case http.MethodGet:
+			v, ok := objects[r.URL.Path]
+			if !ok {
+				http.Error(w, "NoSuchKey", http.StatusNotFound)
+				return
+			}
+			_, _ = io.WriteString(w, v)
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	creds := aws.NewCredentialsCache(credentials.NewStaticCredentialsProvider("AKID", "secret", ""))
+	s, err := NewS3Store(srv.URL, "fleet", "eu-west-1", creds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := s.Put(ctx, "attachments/service/s1/a b.pdf", []byte("%PDF"), "application/pdf"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := objects["/fleet/attachments/service/s1/a b.pdf"]; !ok {
+		t.Errorf("expected path-style object key, got %v", objects)
+	}
+	if got := readAll(t, s, "attachments/service/s1/a b.pdf"); got != "%PDF" {
+		t.Errorf("expected %%PDF, got %q", got)
+	}
+	if err := s.Delete(ctx, "attachments/service/s1/a b.pdf"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, "attachments/service/s1/a b.pdf"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -44,6 +44,30 @@ func LoadEnvFromDynamo(ctx context.Context, tableName string) (int, error) {
 	return loaded, nil
 }
 
+// SaveEnvToDynamo writes the variables to the config table, in the form
+// LoadEnvFromDynamo reads, replacing any existing values.
+func SaveEnvToDynamo(ctx context.Context, tableName string, vars map[string]string) error {
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	client := dynamodb.NewFromConfig(awsCfg)
+	for key, value := range vars {
+		_, err := client.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName: &tableName,
+			Item: map[string]types.AttributeValue{
+				"key":   &types.AttributeValueMemberS{Value: key},
+				"value": &types.AttributeValueMemberS{Value: value},
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func stringAttr(attr types.AttributeValue) (string, bool) {
 	s, ok := attr.(*types.AttributeValueMemberS)
 	if !ok {
@@ -0,0 +1,180 @@
+package depots
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo/memory"
+)
+
+func setup(t *testing.T) (*memory.BikesRepo, *memory.UsersRepo) {
+	t.Helper()
+	bikes := memory.NewBikesRepo()
+	users := memory.NewUsersRepo()
+	SetRepository(memory.NewDepotsRepo())
+	SetFleetRepositories(bikes, users)
+	t.Cleanup(func() {
+		SetRepository(nil)
+		SetFleetRepositories(nil, nil)
+	})
+	return bikes, users
+}
+
+// ---- CRUD ----
+
+func TestCreate_SlugAndValidation(t *testing.T) {
+	setup(t)
+	ctx := context.Background()
+	d, err := Create(ctx, Request{Name: " Galway City ", Lat: 53.27, Lng: -9.05, Phone: "091 123456"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.DepotID != "galway-city" || d.Name != "Galway City" {
+		t.Errorf("unexpected depot %+v", d)
+	}
+	if _, err := Create(ctx, Request{Name: "Galway City"}); err == nil {
+		t.Error("expected duplicate depot to fail")
+	}
+	for _, req := range []Request{{}, {Name: "x", Lat: 91}, {Name: "x", Lng: -181}, {Name: "x", Email: "nope"}} {
+		if _, err := Create(ctx, req); err == nil {
+			t.Errorf("expected %+v to fail validation", req)
+		}
+	}
+}
+
+func TestUpdateAndDelete(t *testing.T) {
+	bikes, _ := setup(t)
+	ctx := context.Background()
+	_, _ = Create(ctx, Request{Name: "Athlone"})
+
+	d, err := Update(ctx, "athlone", Request{Name: "Athlone", ContactName: "Mary", Email: "athlone@example.ie"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.ContactName != "Mary" || d.DepotID != "athlone" {
+		t.Errorf("unexpected depot %+v", d)
+	}
+	if _, err := Update(ctx, "nope", Request{Name: "x"}); !errors.Is(err, errNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b1", Depot: "athlone"})
+	if err := Delete(ctx, "athlone"); !errors.Is(err, errInUse) {
+		t.Errorf("expected depot with bikes to be kept, got %v", err)
+	}
+	_, _ = bikes.Delete(ctx, "b1")
+	if err := Delete(ctx, "athlone"); err != nil {
+		t.Errorf("expected delete to succeed, got %v", err)
+	}
+}
+
+// ---- references ----
+
+func TestResolve(t *testing.T) {
+	setup(t)
+	ctx := context.Background()
+	if got, err := Resolve(ctx, "Anywhere"); err != nil || got != "Anywhere" {
+		t.Errorf("expected free text before depots exist, got %q %v", got, err)
+	}
+	_, _ = Create(ctx, Request{Name: "Galway"})
+	cases := map[string]string{"galway": "galway", "GALWAY": "galway", " Galway ": "galway", "": ""}
+	for ref, want := range cases {
+		if got, err := Resolve(ctx, ref); err != nil || got != want {
+			t.Errorf("Resolve(%q) = %q, %v; want %q", ref, got, err, want)
+		}
+	}
+	if _, err := Resolve(ctx, "Sligo"); !errors.Is(err, ErrUnknownDepot) {
+		t.Errorf("expected unknown depot, got %v", err)
+	}
+}
+
+func TestPerDepotViews(t *testing.T) {
+	bikes, users := setup(t)
+	ctx := context.Background()
+	d, _ := Create(ctx, Request{Name: "Galway"})
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b2", Depot: "galway"})
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b1", Depot: "Galway"}) // pre-depot free text
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b3", Depot: "athlone"})
+	_ = users.Put(ctx, &repo.User{RiderID: "r1"})
+
+	got, err := BikesAt(ctx, *d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ID != "b1" || got[1].ID != "b2" {
+		t.Errorf("expected b1 and b2, got %+v", got)
+	}
+
+	if _, err := AssignRider(ctx, "r1", d.DepotID); err != nil {
+		t.Fatal(err)
+	}
+	riders, _ := RidersAt(ctx, *d)
+	if len(riders) != 1 || riders[0].RiderID != "r1" {
+		t.Errorf("expected r1 based at galway, got %+v", riders)
+	}
+	if _, err := AssignRider(ctx, "ghost", d.DepotID); !errors.Is(err, errNotFound) {
+		t.Errorf("expected unknown rider to fail, got %v", err)
+	}
+}
+
+// ---- handlers ----
+
+func TestHandlers(t *testing.T) {
+	setup(t)
+	_, _ = Create(context.Background(), Request{Name: "Galway"})
+
+	rec := httptest.NewRecorder()
+	ListOrCreate(rec, httptest.NewRequest(http.MethodGet, "/api/depots", nil))
+	if rec.Code != http.StatusOK || !bytes.Contains(rec.Body.Bytes(), []byte(`"depotId":"galway"`)) {
+		t.Errorf("expected depot list, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	ListOrCreate(rec, httptest.NewRequest(http.MethodPost, "/api/depots", bytes.NewBufferString(`{"name":"Sligo"}`)))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected non-manager create to be refused, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	Detail(rec, httptest.NewRequest(http.MethodGet, "/api/depots/galway/bikes", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected bikes view, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	Detail(rec, httptest.NewRequest(http.MethodGet, "/api/depots/nope", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestWriteError_ClassifiesBySentinel(t *testing.T) {
+	setup(t)
+	_, _ = Create(context.Background(), Request{Name: "Galway"})
+	_, dup := Create(context.Background(), Request{Name: "Galway"})
+	cases := []struct {
+		err  error
+		want int
+	}{
+		{errNotFound, http.StatusNotFound},
+		{errInUse, http.StatusConflict},
+		{dup, http.StatusConflict},
+		{errBadEmail, http.StatusBadRequest},
+		{fmt.Errorf("update: %w", errNameRequired), http.StatusBadRequest},
+		// Storage errors that happen to read like validation are still 500s.
+		{errors.New("ValidationException: key must be a string"), http.StatusInternalServerError},
+		{errors.New("credentials required"), http.StatusInternalServerError},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		writeError(rec, "galway", c.err)
+		if rec.Code != c.want {
+			t.Errorf("%v: got %d, want %d", c.err, rec.Code, c.want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package depots
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/auth"
+)
+
+// ListOrCreate handles GET /api/depots and POST /api/depots (FleetManager).
+func ListOrCreate(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		items, err := List(r.Context())
+		if err != nil {
+			log.Printf("[depots] failed to list: %v", err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, items)
+	case http.MethodPost:
+		if !isFleetManager(r) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		d, err := Create(r.Context(), req)
+		if err != nil {
+			writeError(w, "", err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, d)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// Detail handles /api/depots/{id}:
+//
+//	GET, PUT (FleetManager), DELETE (FleetManager)
+//	GET /api/depots/{id}/bikes
+//	GET /api/depots/{id}/riders
+//	PUT/DELETE /api/depots/{id}/riders/{riderId} (FleetManager) bases or unbases a rider
+func Detail(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/depots/"), "/"), "/")
+	id := parts[0]
+	if id == "" {
+		http.NotFound(w, r)
+		return
+	}
+	d, ok, err := Get(r.Context(), id)
+	if err != nil {
+		log.Printf("[depots] failed to get %s: %v", id, err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "depot not found", http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, d)
+		case http.MethodPut:
+			if !isFleetManager(r) {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			var req Request
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "invalid request body", http.StatusBadRequest)
+				return
+			}
+			updated, err := Update(r.Context(), id, req)
+			if err != nil {
+				writeError(w, id, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, updated)
+		case http.MethodDelete:
+			if !isFleetManager(r) {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			if err := Delete(r.Context(), id); err != nil {
+				writeError(w, id, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+
+	case len(parts) == 2 && parts[1] == "bikes" && r.Method == http.MethodGet:
+		bikes, err := BikesAt(r.Context(), *d)
+		if err != nil {
+			writeError(w, id, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, bikes)
+
+	case len(parts) == 2 && parts[1] == "riders" && r.Method == http.MethodGet:
+		riders, err := RidersAt(r.Context(), *d)
+		if err != nil {
+			writeError(w, id, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, riders)
+
+	case len(parts) == 3 && parts[1] == "riders" && (r.Method == http.MethodPut || r.Method == http.MethodDelete):
+		if !isFleetManager(r) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		depotID := d.DepotID
+		if r.Method == http.MethodDelete {
+			depotID = ""
+		}
+		u, err := AssignRider(r.Context(), parts[2], depotID)
+		if err != nil {
+			writeError(w, id, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, u)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeError(w http.ResponseWriter, id string, err error) {
+	switch {
+	case errors.Is(err, errNotFound):
+		http.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, errInUse), errors.Is(err, errExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case isValidationError(err):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("[depots] depot %s: %v", id, err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func isFleetManager(r *http.Request) bool {
+	return auth.HasRoleOrAbove(auth.RolesFromContext(r.Context()), "FleetManager")
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func isValidationError(err error) bool {
+	for _, v := range []error{errNameRequired, errIDRequired, errBadLocation, errBadEmail} {
+		if errors.Is(err, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,311 @@
+// Package depots manages the depots bikes and riders are based at.
+package depots
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+)
+
+// ErrUnknownDepot is returned for a depot reference that matches no depot.
+var ErrUnknownDepot = errors.New("unknown depot")
+
+var (
+	errNotFound = errors.New("not found")
+	errInUse    = errors.New("depot still has bikes or riders based at it")
+	errExists   = errors.New("already exists")
+
+	// Validation errors, answered as 400s.
+	errNameRequired = errors.New("name required")
+	errIDRequired   = errors.New("depotId required")
+	errBadLocation  = errors.New("lat must be between -90 and 90 and lng between -180 and 180")
+	errBadEmail     = errors.New("email is not valid")
+)
+
+var (
+	globalRepo repo.DepotsRepository
+	bikesRepo  repo.BikesRepository
+	usersRepo  repo.UsersRepository
+)
+
+func SetRepository(r repo.DepotsRepository) {
+	globalRepo = r
+}
+
+// SetFleetRepositories gives the per-depot views access to bikes and riders.
+func SetFleetRepositories(bikes repo.BikesRepository, users repo.UsersRepository) {
+	bikesRepo = bikes
+	usersRepo = users
+}
+
+type Request struct {
+	DepotID     string  `json:"depotId,omitempty"` // create only; defaults to a slug of the name
+	Name        string  `json:"name"`
+	Address     string  `json:"address,omitempty"`
+	Lat         float64 `json:"lat,omitempty"`
+	Lng         float64 `json:"lng,omitempty"`
+	ContactName string  `json:"contactName,omitempty"`
+	Phone       string  `json:"phone,omitempty"`
+	Email       string  `json:"email,omitempty"`
+}
+
+func (req *Request) validate() error {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Address = strings.TrimSpace(req.Address)
+	req.ContactName = strings.TrimSpace(req.ContactName)
+	req.Phone = strings.TrimSpace(req.Phone)
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Name == "" {
+		return errNameRequired
+	}
+	if req.Lat < -90 || req.Lat > 90 || req.Lng < -180 || req.Lng > 180 {
+		return errBadLocation
+	}
+	if req.Email != "" && !strings.Contains(req.Email, "@") {
+		return errBadEmail
+	}
+	return nil
+}
+
+func (req Request) apply(d *repo.Depot) {
+	d.Name = req.Name
+	d.Address = req.Address
+	d.Lat = req.Lat
+	d.Lng = req.Lng
+	d.ContactName = req.ContactName
+	d.Phone = req.Phone
+	d.Email = req.Email
+}
+
+func List(ctx context.Context) ([]repo.Depot, error) {
+	if globalRepo == nil {
+		return nil, errors.New("depots not configured")
+	}
+	items, err := globalRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	return items, nil
+}
+
+func Get(ctx context.Context, id string) (*repo.Depot, bool, error) {
+	if globalRepo == nil {
+		return nil, false, errors.New("depots not configured")
+	}
+	return globalRepo.Get(ctx, id)
+}
+
+func Create(ctx context.Context, req Request) (*repo.Depot, error) {
+	if globalRepo == nil {
+		return nil, errors.New("depots not configured")
+	}
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+	id := slug(req.DepotID)
+	if id == "" {
+		id = slug(req.Name)
+	}
+	if id == "" {
+		return nil, errIDRequired
+	}
+	if _, exists, err := globalRepo.Get(ctx, id); err != nil {
+		return nil, err
+	} else if exists {
+		return nil, fmt.Errorf("depot %q %w", id, errExists)
+	}
+
+	now := time.Now()
+	d := &repo.Depot{DepotID: id, CreatedAt: now, UpdatedAt: now}
+	req.apply(d)
+	if err := globalRepo.Put(ctx, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func Update(ctx context.Context, id string, req Request) (*repo.Depot, error) {
+	d, ok, err := Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errNotFound
+	}
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+	req.apply(d)
+	d.UpdatedAt = time.Now()
+	if err := globalRepo.Put(ctx, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Delete removes a depot that no bike or rider is based at.
+func Delete(ctx context.Context, id string) error {
+	d, ok, err := Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errNotFound
+	}
+	bikes, err := BikesAt(ctx, *d)
+	if err != nil {
+		return err
+	}
+	riders, err := RidersAt(ctx, *d)
+	if err != nil {
+		return err
+	}
+	if len(bikes) > 0 || len(riders) > 0 {
+		return errInUse
+	}
+	_, err = globalRepo.Delete(ctx, id)
+	return err
+}
+
+// Resolve turns a depot reference (ID or name, any case) into the depot's
+// ID. An empty reference stays empty. Until any depots have been created,
+// references are taken as free text so existing data keeps working.
+func Resolve(ctx context.Context, ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || globalRepo == nil {
+		return ref, nil
+	}
+	if _, ok, err := globalRepo.Get(ctx, ref); err != nil {
+		return "", err
+	} else if ok {
+		return ref, nil
+	}
+	all, err := globalRepo.List(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(all) == 0 {
+		return ref, nil
+	}
+	for _, d := range all {
+		if matches(d, ref) {
+			return d.DepotID, nil
+		}
+	}
+	return "", fmt.Errorf("%w %q", ErrUnknownDepot, ref)
+}
+
+// matches reports whether a stored depot reference points at d. Older
+// records hold the depot's name rather than its ID.
+func matches(d repo.Depot, ref string) bool {
+	ref = strings.TrimSpace(ref)
+	return ref != "" && (strings.EqualFold(ref, d.DepotID) || strings.EqualFold(ref, d.Name))
+}
+
+// BikesAt returns the bikes based at the depot.
+func BikesAt(ctx context.Context, d repo.Depot) ([]repo.Bike, error) {
+	out := make([]repo.Bike, 0)
+	if bikesRepo == nil {
+		return out, nil
+	}
+	all, err := bikesRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range all {
+		if matches(d, b.Depot) {
+			out = append(out, b)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+// RidersAt returns the riders based at the depot.
+func RidersAt(ctx context.Context, d repo.Depot) ([]repo.User, error) {
+	out := make([]repo.User, 0)
+	if usersRepo == nil {
+		return out, nil
+	}
+	all, err := usersRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range all {
+		if matches(d, u.Depot) {
+			out = append(out, u)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].RiderID < out[j].RiderID })
+	return out, nil
+}
+
+// RiderIDsIn returns the IDs of the riders based at the depot given by ID
+// or name, for notifying a region.
+func RiderIDsIn(ctx context.Context, ref string) ([]string, error) {
+	id, err := Resolve(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	d, ok, err := Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownDepot, ref)
+	}
+	users, err := RidersAt(ctx, *d)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(users))
+	for i, u := range users {
+		ids[i] = u.RiderID
+	}
+	return ids, nil
+}
+
+// AssignRider bases the rider at the depot, or clears their depot when
+// depotID is empty.
+func AssignRider(ctx context.Context, riderID, depotID string) (*repo.User, error) {
+	if usersRepo == nil {
+		return nil, errors.New("users not configured")
+	}
+	u, ok, err := usersRepo.Get(ctx, riderID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errNotFound
+	}
+	u.Depot = depotID
+	u.UpdatedAt = time.Now()
+	if err := usersRepo.Put(ctx, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// slug lower-cases s and joins its words with dashes, e.g. "Galway City"
+// becomes "galway-city".
+func slug(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(s)) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+			dash = false
+		case b.Len() > 0 && !dash:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
@@ -0,0 +1,121 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Events aren't deleted when they end. Once an event (or a series' last
+// occurrence) is over it is archived: a scheduled or in-progress event is
+// marked completed and ArchivedAt is set, which takes it out of the normal
+// list but keeps it for GET /api/events?include=past. Archived events are
+// deleted for good after EVENT_RETENTION_DAYS (default 365; 0 keeps them
+// forever).
+
+const defaultRetentionDays = 365
+
+// retention is how long archived events are kept, or 0 for forever.
+func retention() time.Duration {
+	days := defaultRetentionDays
+	if v := strings.TrimSpace(os.Getenv("EVENT_RETENTION_DAYS")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			days = n
+		} else {
+			log.Printf("[events] invalid EVENT_RETENTION_DAYS %q, using %d", v, defaultRetentionDays)
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func retentionDescription() string {
+	r := retention()
+	if r == 0 {
+		return "forever"
+	}
+	return fmt.Sprintf("%d days", int(r.Hours()/24))
+}
+
+func isOpenStatus(s EventStatus) bool {
+	return s == "" || s == EventStatusScheduled || s == EventStatusInProgress
+}
+
+// ArchiveExpired archives every event that has ended for good by now and
+// returns how many it archived. Series without COUNT or UNTIL never end.
+func ArchiveExpired(ctx context.Context, now time.Time) (int, error) {
+	all, err := listStored(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list events: %w", err)
+	}
+	archived := 0
+	for _, e := range all {
+		if e.ArchivedAt != nil {
+			continue
+		}
+		end, err := finalEndTime(e)
+		if err != nil || !now.After(end) {
+			continue // still running, open-ended or without a usable end time
+		}
+		ok, err := archive(ctx, e.ID, now)
+		if err != nil {
+			log.Printf("[events] archive: failed to archive %s: %v", e.ID, err)
+			continue
+		}
+		if ok {
+			archived++
+			log.Printf("[events] archive: archived event %q (ended %s)", e.Title, end.Format(time.RFC3339))
+		}
+	}
+	return archived, nil
+}
+
+// archive re-reads the event under seriesMu so a concurrent edit isn't
+// overwritten, then archives it.
+func archive(ctx context.Context, id string, now time.Time) (bool, error) {
+	seriesMu.Lock()
+	defer seriesMu.Unlock()
+	e, ok, err := Get(ctx, id)
+	if err != nil || !ok || e.ArchivedAt != nil {
+		return false, err
+	}
+	if isOpenStatus(e.Status) {
+		e.Status = EventStatusCompleted
+	}
+	at := now
+	e.ArchivedAt = &at
+	e.UpdatedAt = now
+	return true, putEvent(ctx, e)
+}
+
+// PurgeArchived deletes events archived longer ago than the retention
+// period and returns how many it deleted.
+func PurgeArchived(ctx context.Context, now time.Time) (int, error) {
+	keep := retention()
+	if keep == 0 {
+		return 0, nil
+	}
+	all, err := listStored(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list events: %w", err)
+	}
+	purged := 0
+	for _, e := range all {
+		if e.ArchivedAt == nil || now.Sub(*e.ArchivedAt) < keep {
+			continue
+		}
+		ok, err := Delete(ctx, e.ID)
+		if err != nil {
+			log.Printf("[events] purge: failed to delete %s: %v", e.ID, err)
+			continue
+		}
+		if ok {
+			purged++
+			log.Printf("[events] purge: deleted event %q archived %s", e.Title, e.ArchivedAt.Format(time.RFC3339))
+		}
+	}
+	return purged, nil
+}
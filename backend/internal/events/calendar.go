@@ -0,0 +1,278 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/auth"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/signer"
+)
+
+// Calendar apps fetch subscribed feeds without the app's bearer token, so
+// each feed URL carries a signed token naming the user it was issued to,
+// what it shows – "mine" (events the user is assigned to) or "all" (every
+// event) – and the user's feed key. Feeds are subscribed to for good, so
+// tokens don't expire; instead a feed only works while its user exists and
+// still has that key. Resetting the key revokes one user's feed URLs, and
+// rotating CALENDAR_FEED_SECRET revokes everyone's.
+//
+// Token format: cal2.<base64url scope "\n" username "\n" key>.<base64url truncated HMAC>
+const feedTokenPrefix = "cal2."
+
+const (
+	feedScopeMine = "mine"
+	feedScopeAll  = "all"
+)
+
+// maxImportBytes bounds an uploaded .ics file.
+const maxImportBytes = 2 << 20
+
+var feedTokens = signer.New("CALENDAR_FEED_SECRET", "calendar-feed", feedTokenPrefix, 16)
+
+const errFeedSecretNotConfigured = "calendar feeds not configured (set CALENDAR_FEED_SECRET)"
+
+func feedToken(scope, username, key string) string {
+	return feedTokens.Token(scope + "\n" + username + "\n" + key)
+}
+
+// parseFeedToken verifies a feed token and returns its scope, user and
+// feed key.
+func parseFeedToken(token string) (scope, username, key string, ok bool) {
+	payload, ok := feedTokens.Parse(token)
+	if !ok {
+		return "", "", "", false
+	}
+	parts := strings.Split(payload, "\n")
+	if len(parts) != 3 || parts[1] == "" || parts[2] == "" || (parts[0] != feedScopeMine && parts[0] != feedScopeAll) {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], parts[2], true
+}
+
+func feedPath(scope, username, key string) string {
+	return "/api/calendar/" + url.PathEscape(feedToken(scope, username, key)) + ".ics"
+}
+
+func newFeedKey() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// feedKey returns the user's feed key, giving them a new one when reset is
+// set or they have none yet. ok is false when the user doesn't exist.
+func feedKey(ctx context.Context, username string, reset bool) (key string, ok bool, err error) {
+	if usersRepo == nil {
+		return "", false, errors.New("users not configured")
+	}
+	u, ok, err := usersRepo.Get(ctx, username)
+	if err != nil || !ok {
+		return "", false, err
+	}
+	if u.CalendarFeedKey != "" && !reset {
+		return u.CalendarFeedKey, true, nil
+	}
+	u.CalendarFeedKey = newFeedKey()
+	if err := usersRepo.Put(ctx, u); err != nil {
+		return "", false, err
+	}
+	return u.CalendarFeedKey, true, nil
+}
+
+// feedAllowed reports whether a feed token's user still exists and still
+// has the key it was issued with.
+func feedAllowed(ctx context.Context, username, key string) (bool, error) {
+	if usersRepo == nil {
+		return false, nil
+	}
+	u, ok, err := usersRepo.Get(ctx, username)
+	if err != nil || !ok {
+		return false, err
+	}
+	return u.CalendarFeedKey != "" && u.CalendarFeedKey == key, nil
+}
+
+// feedEvents returns the stored events for a feed: every event, or with a
+// username only the ones that rider is assigned to. A series the rider is
+// on loses the dates that were edited to take them off.
+func feedEvents(ctx context.Context, username string) ([]*Event, error) {
+	all, err := listStored(ctx)
+	if err != nil || username == "" {
+		return all, err
+	}
+	assigned := func(e *Event) bool {
+		for _, r := range e.AssignedRiders {
+			if r == username {
+				return true
+			}
+		}
+		return false
+	}
+
+	var out []*Event
+	series := make(map[string]*Event)
+	for _, e := range all {
+		if assigned(e) {
+			out = append(out, e)
+			if e.Recurrence != "" {
+				series[e.ID] = e
+			}
+		}
+	}
+	for _, e := range all {
+		if s := series[e.SeriesID]; s != nil && e.ID != s.ID && !assigned(e) {
+			s.ExDates = append(append([]string(nil), s.ExDates...), e.OccurrenceDate)
+		}
+	}
+	return out, nil
+}
+
+type CalendarLinks struct {
+	Mine string `json:"mine"`
+	All  string `json:"all"`
+}
+
+// HandleCalendarLinks handles the caller's feed URLs, relative to the API,
+// for subscribing from a calendar app:
+//
+//	GET  /api/calendar/links   current links
+//	POST /api/calendar/links   revoke the current links and return new ones
+func HandleCalendarLinks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !feedTokens.Configured() {
+		http.Error(w, errFeedSecretNotConfigured, http.StatusNotImplemented)
+		return
+	}
+	username := auth.UsernameFromContext(r.Context())
+	if username == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	key, ok, err := feedKey(r.Context(), username, r.Method == http.MethodPost)
+	if err != nil {
+		log.Printf("[events] failed to load calendar feed key for %s: %v", username, err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, CalendarLinks{
+		Mine: feedPath(feedScopeMine, username, key),
+		All:  feedPath(feedScopeAll, username, key),
+	})
+}
+
+// CalendarFeed handles GET /api/calendar/{token}.ics. It is authorised by
+// the signed token rather than a bearer token, and answers 404 once the
+// token's user is gone or has reset their links.
+func CalendarFeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !feedTokens.Configured() {
+		http.Error(w, errFeedSecretNotConfigured, http.StatusNotImplemented)
+		return
+	}
+	token := strings.TrimSuffix(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/calendar/"), "/"), ".ics")
+	scope, username, key, ok := parseFeedToken(token)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	allowed, err := feedAllowed(r.Context(), username, key)
+	if err != nil {
+		log.Printf("[events] failed to check calendar feed for %s: %v", username, err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		http.NotFound(w, r)
+		return
+	}
+
+	name := "Blood Bike events"
+	filter := ""
+	if scope == feedScopeMine {
+		name, filter = "My Blood Bike events", username
+	}
+	events, err := feedEvents(r.Context(), filter)
+	if err != nil {
+		log.Printf("[events] failed to build %s calendar feed for %s: %v", scope, username, err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="events.ics"`)
+	_, _ = w.Write(writeCalendar(name, events, time.Now()))
+}
+
+// ImportCalendar handles POST /api/calendar/import. The .ics file is sent
+// as the "file" field of a multipart form or as the raw request body.
+func ImportCalendar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes+1<<20)
+
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			writeImportReadError(w, err)
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "file required", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+	data, err := io.ReadAll(io.LimitReader(body, maxImportBytes+1))
+	if err != nil {
+		writeImportReadError(w, err)
+		return
+	}
+	if len(data) > maxImportBytes {
+		http.Error(w, "calendar file too large (max 2 MB)", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	res, err := Import(r.Context(), data)
+	if err != nil {
+		if errors.Is(err, ErrNotCalendar) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("[events] calendar import failed: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[events] %s imported %d events from a calendar (%d skipped)", auth.UsernameFromContext(r.Context()), len(res.Created), len(res.Skipped))
+	writeJSON(w, http.StatusOK, res)
+}
+
+func writeImportReadError(w http.ResponseWriter, err error) {
+	var tooBig *http.MaxBytesError
+	if errors.As(err, &tooBig) {
+		http.Error(w, "calendar file too large (max 2 MB)", http.StatusRequestEntityTooLarge)
+		return
+	}
+	http.Error(w, "invalid upload", http.StatusBadRequest)
+}
@@ -2,15 +2,24 @@ package events
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/auth"
 )
 
 func ListOrCreate(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		items, err := List(r.Context())
+		from, to, err := listRange(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		items, err := List(r.Context(), ListQuery{From: from, To: to, IncludePast: includesPast(r)})
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -39,6 +48,50 @@ func ListOrCreate(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// listRange reads the ?from= and ?to= dates (YYYY-MM-DD, both inclusive).
+// Given only one, the range runs from today or for defaultListDays days.
+func listRange(r *http.Request) (time.Time, time.Time, error) {
+	q := r.URL.Query()
+	fromStr, toStr := q.Get("from"), q.Get("to")
+	if fromStr == "" && toStr == "" {
+		return time.Time{}, time.Time{}, nil
+	}
+	from := dateOnly(time.Now())
+	if fromStr != "" {
+		t, err := time.Parse(dateLayout, fromStr)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("from must be a date (YYYY-MM-DD)")
+		}
+		from = t
+	}
+	to := from.AddDate(0, 0, defaultListDays)
+	if toStr != "" {
+		t, err := time.Parse(dateLayout, toStr)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("to must be a date (YYYY-MM-DD)")
+		}
+		to = t.AddDate(0, 0, 1)
+	}
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, errors.New("to must not be before from")
+	}
+	return from, to, nil
+}
+
+// includesPast reports whether ?include= asks for archived events
+// (include=past).
+func includesPast(r *http.Request) bool {
+	for _, v := range strings.Split(r.URL.Query().Get("include"), ",") {
+		if strings.TrimSpace(v) == "past" {
+			return true
+		}
+	}
+	return false
+}
+
+// GetUpdateOrDelete handles /api/events/{id}. PUT and DELETE act on the
+// whole series for a recurring event, or on a single occurrence with
+// ?occurrence=YYYY-MM-DD.
 func GetUpdateOrDelete(w http.ResponseWriter, r *http.Request) {
 	id := strings.TrimPrefix(r.URL.Path, "/api/events/")
 	id = strings.Trim(id, "/")
@@ -47,6 +100,20 @@ func GetUpdateOrDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var occurrence time.Time
+	if v := r.URL.Query().Get("occurrence"); v != "" {
+		t, err := time.Parse(dateLayout, v)
+		if err != nil {
+			http.Error(w, "occurrence must be a date (YYYY-MM-DD)", http.StatusBadRequest)
+			return
+		}
+		occurrence = t
+	}
+	if eventID, ok := strings.CutSuffix(id, "/signup"); ok {
+		handleSignUp(w, r, eventID, occurrence)
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		e, ok, err := Get(r.Context(), id)
@@ -65,12 +132,22 @@ func GetUpdateOrDelete(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "invalid request body", http.StatusBadRequest)
 			return
 		}
-		e, err := Update(r.Context(), id, req)
+		var e *Event
+		var err error
+		if occurrence.IsZero() {
+			e, err = Update(r.Context(), id, req)
+		} else {
+			e, err = UpdateOccurrence(r.Context(), id, occurrence, req)
+		}
 		if err != nil {
 			if err.Error() == "not found" {
 				http.Error(w, "event not found", http.StatusNotFound)
 				return
 			}
+			if errors.Is(err, ErrNoOccurrence) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
 			if isValidationError(err) {
 				http.Error(w, err.Error(), http.StatusBadRequest)
 			} else {
@@ -81,6 +158,23 @@ func GetUpdateOrDelete(w http.ResponseWriter, r *http.Request) {
 		}
 		writeJSON(w, http.StatusOK, e)
 	case http.MethodDelete:
+		if !occurrence.IsZero() {
+			err := DeleteOccurrence(r.Context(), id, occurrence)
+			switch {
+			case err == nil:
+				w.WriteHeader(http.StatusNoContent)
+			case err.Error() == "not found":
+				http.Error(w, "event not found", http.StatusNotFound)
+			case errors.Is(err, ErrNoOccurrence):
+				http.Error(w, err.Error(), http.StatusNotFound)
+			case isValidationError(err):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			default:
+				log.Printf("[events] failed to delete occurrence %s of %s: %v", occurrence.Format(dateLayout), id, err)
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+			}
+			return
+		}
 		ok, err := Delete(r.Context(), id)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -103,10 +197,56 @@ func writeJSON(w http.ResponseWriter, status int, v any) {
 }
 
 // isValidationError returns true for user-facing validation errors (title/location required etc.)
+// handleSignUp handles /api/events/{id}/signup: POST signs the caller up
+// and DELETE withdraws them, for the whole event or with ?occurrence= a
+// single date of a series.
+func handleSignUp(w http.ResponseWriter, r *http.Request, id string, occurrence time.Time) {
+	username := auth.UsernameFromContext(r.Context())
+	if username == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var res any
+	var err error
+	switch r.Method {
+	case http.MethodPost:
+		res, err = SignUp(r.Context(), id, occurrence, username)
+	case http.MethodDelete:
+		res, err = Withdraw(r.Context(), id, occurrence, username)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusOK, res)
+	case err.Error() == "not found":
+		http.Error(w, "event not found", http.StatusNotFound)
+	case errors.Is(err, ErrNoOccurrence), errors.Is(err, ErrNotSignedUp):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrNotQualified):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, ErrAlreadySignedUp), errors.Is(err, ErrSignUpClosed):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrNotRecurring):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("[events] sign-up change for %s on %s failed: %v", username, id, err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
 func isValidationError(err error) bool {
 	msg := err.Error()
 	return msg == "title required" ||
 		msg == "location required" ||
 		msg == "startTime and endTime required" ||
-		msg == "not found"
+		msg == "not found" ||
+		errors.Is(err, ErrInvalidRecurrence) ||
+		errors.Is(err, ErrNotRecurring) ||
+		errors.Is(err, errSeriesOnly) ||
+		errors.Is(err, errSeriesDate) ||
+		errors.Is(err, errExDate) ||
+		errors.Is(err, errRequiredRiders)
 }
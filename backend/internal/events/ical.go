@@ -0,0 +1,268 @@
+package events
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Events are published as iCalendar (RFC 5545) so riders can subscribe to
+// them from their phone calendars. Times are written in the org zone with
+// a TZID, and the calendar carries a VTIMEZONE for it, so a weekly series
+// stays at the same local time across summer time changes. A recurring
+// series becomes one VEVENT with its RRULE and EXDATEs, and each occurrence
+// edited on its own another VEVENT with the series' UID and a RECURRENCE-ID.
+
+const (
+	icalProdID      = "-//Blood Bike//Events//EN"
+	icalUIDDomain   = "@events.bloodbike"
+	icalTimeLayout  = "20060102T150405Z"
+	icalLocalLayout = "20060102T150405"
+	icalDateLayout  = "20060102"
+)
+
+// knownEventTypes are the event types the app offers. They double as
+// iCalendar CATEGORIES.
+var knownEventTypes = map[EventType]bool{
+	"delivery": true, "training": true, "maintenance": true,
+	"meeting": true, "emergency": true, "other": true,
+}
+
+// eventTimes returns when the event starts and ends. An end time at or
+// before the start runs into the next day.
+func eventTimes(e *Event) (time.Time, time.Time, error) {
+	start, err := atClock(e.Date, e.StartTime)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	end, err := atClock(e.Date, e.EndTime)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if !end.After(start) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return start, end, nil
+}
+
+// writeCalendar renders events, as stored, as an iCalendar document.
+// Events whose times can't be parsed are left out.
+func writeCalendar(name string, events []*Event, now time.Time) []byte {
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].Date.Equal(events[j].Date) {
+			return events[i].Date.Before(events[j].Date)
+		}
+		return events[i].ID < events[j].ID
+	})
+
+	var b icalBuilder
+	b.line("BEGIN", "VCALENDAR")
+	b.line("VERSION", "2.0")
+	b.line("PRODID", icalProdID)
+	b.line("CALSCALE", "GREGORIAN")
+	b.line("METHOD", "PUBLISH")
+	b.line("X-WR-CALNAME", icalText(name))
+	b.line("X-WR-TIMEZONE", orgLocation().String())
+	writeVTimezone(&b, orgLocation(), events, now)
+	byID := make(map[string]*Event, len(events))
+	for _, e := range events {
+		byID[e.ID] = e
+	}
+	for _, e := range events {
+		var series *Event
+		if e.SeriesID != "" && e.SeriesID != e.ID {
+			series = byID[e.SeriesID]
+		}
+		writeVEvent(&b, e, series, now)
+	}
+	b.line("END", "VCALENDAR")
+	return []byte(b.String())
+}
+
+// writeVEvent writes one event. An occurrence edited on its own is written
+// against its series when the series is in the same calendar, and as a
+// stand-alone event otherwise.
+func writeVEvent(b *icalBuilder, e, series *Event, now time.Time) {
+	start, end, err := eventTimes(e)
+	if err != nil {
+		return
+	}
+	stamp := e.UpdatedAt
+	if stamp.IsZero() {
+		stamp = now
+	}
+
+	b.line("BEGIN", "VEVENT")
+	recurrenceID, err := time.Parse(dateLayout, e.OccurrenceDate)
+	if err == nil && series != nil {
+		recurrenceID, err = atClock(recurrenceID, series.StartTime)
+	}
+	if series != nil && err == nil {
+		b.line("UID", series.ID+icalUIDDomain)
+		b.line("RECURRENCE-ID"+tzParam(), localTime(recurrenceID))
+	} else {
+		b.line("UID", e.ID+icalUIDDomain)
+	}
+	b.line("DTSTAMP", stamp.UTC().Format(icalTimeLayout))
+	b.line("DTSTART"+tzParam(), localTime(start))
+	b.line("DTEND"+tzParam(), localTime(end))
+	b.line("SUMMARY", icalText(e.Title))
+	if e.Description != "" {
+		b.line("DESCRIPTION", icalText(e.Description))
+	}
+	if e.Location != "" {
+		b.line("LOCATION", icalText(e.Location))
+	}
+	if e.Lat != nil && e.Lng != nil {
+		b.line("GEO", fmt.Sprintf("%.6f;%.6f", *e.Lat, *e.Lng))
+	}
+	if knownEventTypes[e.Type] {
+		b.line("CATEGORIES", strings.ToUpper(string(e.Type)))
+	}
+	if p := icalPriority(e.Priority); p > 0 {
+		b.line("PRIORITY", fmt.Sprint(p))
+	}
+	if e.Status == EventStatusCancelled {
+		b.line("STATUS", "CANCELLED")
+	} else {
+		b.line("STATUS", "CONFIRMED")
+	}
+	if rule, err := ParseRule(e.Recurrence); err == nil {
+		b.line("RRULE", rule.icalString())
+		for _, d := range e.ExDates {
+			if t, err := time.Parse(dateLayout, d); err == nil {
+				if t, err := atClock(t, e.StartTime); err == nil {
+					b.line("EXDATE"+tzParam(), localTime(t))
+				}
+			}
+		}
+	}
+	b.line("END", "VEVENT")
+}
+
+func tzParam() string {
+	return ";TZID=" + orgLocation().String()
+}
+
+func localTime(t time.Time) string {
+	return t.In(orgLocation()).Format(icalLocalLayout)
+}
+
+// writeVTimezone describes loc from the start of the earliest event's year
+// to ten years on, or past the latest event, as one STANDARD or DAYLIGHT
+// component per offset change.
+func writeVTimezone(b *icalBuilder, loc *time.Location, events []*Event, now time.Time) {
+	first, last := now.Year(), now.Year()+10
+	for _, e := range events {
+		if y := dateOnly(e.Date).Year(); y < first && y > 1 {
+			first = y
+		} else if y >= last {
+			last = y + 1
+		}
+	}
+	from := time.Date(first, 1, 1, 0, 0, 0, 0, loc)
+	to := time.Date(last, 12, 31, 0, 0, 0, 0, loc)
+
+	b.line("BEGIN", "VTIMEZONE")
+	b.line("TZID", loc.String())
+	// Parts are labelled by offset rather than IsDST: tzdata treats Irish
+	// winter time as the daylight-saving one, which calendar apps don't.
+	name, offset := from.Zone()
+	_, firstEnd := from.ZoneBounds()
+	_, nextOffset := firstEnd.In(loc).Zone()
+	writeZonePart(b, from, offset, offset, name, !firstEnd.IsZero() && offset > nextOffset)
+	for t := from; ; {
+		_, end := t.ZoneBounds()
+		if end.IsZero() || end.After(to) {
+			break
+		}
+		next := end.In(loc)
+		nextName, nextOffset := next.Zone()
+		writeZonePart(b, end, offset, nextOffset, nextName, nextOffset > offset)
+		t, offset = next, nextOffset
+	}
+	b.line("END", "VTIMEZONE")
+}
+
+// writeZonePart writes an offset change at the instant at, with DTSTART in
+// the local time before the change as RFC 5545 requires.
+func writeZonePart(b *icalBuilder, at time.Time, fromOffset, toOffset int, name string, dst bool) {
+	kind := "STANDARD"
+	if dst {
+		kind = "DAYLIGHT"
+	}
+	b.line("BEGIN", kind)
+	b.line("DTSTART", at.UTC().Add(time.Duration(fromOffset)*time.Second).Format(icalLocalLayout))
+	b.line("TZOFFSETFROM", utcOffset(fromOffset))
+	b.line("TZOFFSETTO", utcOffset(toOffset))
+	b.line("TZNAME", icalText(name))
+	b.line("END", kind)
+}
+
+func utcOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign, seconds = "-", -seconds
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds/60%60)
+}
+
+// icalPriority maps the app's priorities onto iCalendar's 1 (highest) to
+// 9 (lowest), with 0 for none.
+func icalPriority(p EventPriority) int {
+	switch p {
+	case "urgent":
+		return 1
+	case "high":
+		return 3
+	case "medium":
+		return 5
+	case "low":
+		return 9
+	}
+	return 0
+}
+
+func priorityFromICal(n int) EventPriority {
+	switch {
+	case n >= 1 && n <= 2:
+		return "urgent"
+	case n >= 3 && n <= 4:
+		return "high"
+	case n == 5:
+		return "medium"
+	case n >= 6 && n <= 9:
+		return "low"
+	}
+	return ""
+}
+
+// icalText escapes a TEXT value.
+func icalText(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+	return r.Replace(s)
+}
+
+// icalBuilder writes content lines with CRLF endings, folded at 75 octets
+// without splitting UTF-8 sequences.
+type icalBuilder struct {
+	strings.Builder
+}
+
+func (b *icalBuilder) line(name, value string) {
+	s := name + ":" + value
+	width := 75
+	for len(s) > width {
+		cut := width
+		for cut > 0 && s[cut]&0xc0 == 0x80 {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		width = 74 // continuation lines start with a space
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+}
@@ -0,0 +1,469 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Import bulk-creates events from an iCalendar file. Each VEVENT becomes
+// an event: SUMMARY, DESCRIPTION, LOCATION and GEO map across directly,
+// DTSTART and DTEND (or DURATION) become the date and start and end times
+// in the org zone, and RRULE/EXDATE make it a series. A VEVENT with a RECURRENCE-ID
+// edits (or, if cancelled, cancels) that occurrence of a series from the
+// same file. Events the app can't hold, such as ones without a location or
+// with an unsupported RRULE, are skipped and reported rather than failing
+// the whole import.
+
+var ErrNotCalendar = errors.New("not an iCalendar file")
+
+// windowsZones maps the zone names Outlook writes as TZIDs to IANA zones.
+var windowsZones = map[string]string{
+	"GMT Standard Time":       "Europe/London",
+	"Greenwich Standard Time": "UTC",
+	"W. Europe Standard Time": "Europe/Berlin",
+	"UTC":                     "UTC",
+}
+
+type ImportResult struct {
+	Created []*Event     `json:"created"`
+	Skipped []ImportSkip `json:"skipped"`
+}
+
+// ImportSkip is a VEVENT that wasn't imported, and why.
+type ImportSkip struct {
+	UID     string `json:"uid,omitempty"`
+	Summary string `json:"summary,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+type icalProp struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+type vevent []icalProp
+
+func (v vevent) get(name string) *icalProp {
+	for i := range v {
+		if v[i].Name == name {
+			return &v[i]
+		}
+	}
+	return nil
+}
+
+func (v vevent) text(name string) string {
+	if p := v.get(name); p != nil {
+		return strings.TrimSpace(icalUnescape(p.Value))
+	}
+	return ""
+}
+
+func Import(ctx context.Context, data []byte) (*ImportResult, error) {
+	vevents, err := parseICal(data)
+	if err != nil {
+		return nil, err
+	}
+	res := &ImportResult{Created: []*Event{}, Skipped: []ImportSkip{}}
+	skip := func(v vevent, reason string) {
+		res.Skipped = append(res.Skipped, ImportSkip{UID: v.text("UID"), Summary: v.text("SUMMARY"), Reason: reason})
+	}
+
+	// Series first, so the occurrences edited in the file can find them.
+	series := make(map[string]string) // UID → series event ID
+	var edits []vevent
+	for _, v := range vevents {
+		if v.get("RECURRENCE-ID") != nil {
+			edits = append(edits, v)
+			continue
+		}
+		e, err := importEvent(ctx, v)
+		if err != nil {
+			if !skippable(err) {
+				return nil, err
+			}
+			skip(v, err.Error())
+			continue
+		}
+		res.Created = append(res.Created, e)
+		if uid := v.text("UID"); uid != "" && e.Recurrence != "" {
+			series[uid] = e.ID
+		}
+	}
+
+	for _, v := range edits {
+		seriesID, ok := series[v.text("UID")]
+		if !ok {
+			// The series isn't in the file: keep the occurrence on its own.
+			e, err := importEvent(ctx, v)
+			if err != nil {
+				if !skippable(err) {
+					return nil, err
+				}
+				skip(v, err.Error())
+				continue
+			}
+			res.Created = append(res.Created, e)
+			continue
+		}
+		e, err := importEdit(ctx, seriesID, v)
+		if err != nil {
+			if !skippable(err) {
+				return nil, err
+			}
+			skip(v, err.Error())
+			continue
+		}
+		if e != nil {
+			res.Created = append(res.Created, e)
+		}
+	}
+	return res, nil
+}
+
+// skipError marks a VEVENT that is left out of an import.
+type skipError string
+
+func (e skipError) Error() string { return string(e) }
+
+func skipf(format string, args ...any) error {
+	return skipError(fmt.Sprintf(format, args...))
+}
+
+// skippable reports whether err only means the VEVENT can't be imported.
+func skippable(err error) bool {
+	var se skipError
+	return errors.As(err, &se) || isValidationError(err) || errors.Is(err, ErrNoOccurrence)
+}
+
+func importEvent(ctx context.Context, v vevent) (*Event, error) {
+	if strings.EqualFold(v.text("STATUS"), "CANCELLED") {
+		return nil, skipf("event is cancelled")
+	}
+	req, err := createRequestFromVEvent(v)
+	if err != nil {
+		return nil, err
+	}
+	if v.get("RECURRENCE-ID") != nil {
+		req.Recurrence, req.ExDates = "", nil
+	}
+	return Create(ctx, req)
+}
+
+// importEdit applies an edited (or cancelled) occurrence to its series. A
+// cancellation returns a nil event.
+func importEdit(ctx context.Context, seriesID string, v vevent) (*Event, error) {
+	rid, _, err := icalTime(*v.get("RECURRENCE-ID"))
+	if err != nil {
+		return nil, skipf("RECURRENCE-ID: %v", err)
+	}
+	if strings.EqualFold(v.text("STATUS"), "CANCELLED") {
+		return nil, DeleteOccurrence(ctx, seriesID, rid)
+	}
+	req, err := createRequestFromVEvent(v)
+	if err != nil {
+		return nil, err
+	}
+	return UpdateOccurrence(ctx, seriesID, rid, UpdateEventRequest{
+		Title:       &req.Title,
+		Description: &req.Description,
+		Date:        &req.Date,
+		StartTime:   &req.StartTime,
+		EndTime:     &req.EndTime,
+		Location:    &req.Location,
+		Lat:         req.Lat,
+		Lng:         req.Lng,
+		Type:        &req.Type,
+		Priority:    &req.Priority,
+	})
+}
+
+func createRequestFromVEvent(v vevent) (CreateEventRequest, error) {
+	req := CreateEventRequest{
+		Title:       v.text("SUMMARY"),
+		Description: v.text("DESCRIPTION"),
+		Location:    v.text("LOCATION"),
+		Type:        "other",
+		Priority:    "medium",
+	}
+
+	dtstart := v.get("DTSTART")
+	if dtstart == nil {
+		return req, skipf("DTSTART required")
+	}
+	start, allDay, err := icalTime(*dtstart)
+	if err != nil {
+		return req, skipf("DTSTART: %v", err)
+	}
+	var end time.Time
+	switch {
+	case v.get("DTEND") != nil:
+		if end, _, err = icalTime(*v.get("DTEND")); err != nil {
+			return req, skipf("DTEND: %v", err)
+		}
+	case v.get("DURATION") != nil:
+		d, err := parseICalDuration(v.get("DURATION").Value)
+		if err != nil {
+			return req, skipf("DURATION: %v", err)
+		}
+		end = start.Add(d)
+	case allDay:
+		end = start.AddDate(0, 0, 1)
+	default:
+		end = start
+	}
+
+	req.Date = dateOnly(start)
+	switch {
+	case allDay:
+		req.StartTime, req.EndTime = "00:00", "23:59"
+	case end.Sub(start) >= 24*time.Hour:
+		// Events here are held within a day; longer ones run to midnight.
+		req.StartTime, req.EndTime = clockIn(start), "23:59"
+	default:
+		req.StartTime, req.EndTime = clockIn(start), clockIn(end)
+	}
+
+	if geo := v.get("GEO"); geo != nil {
+		latStr, lngStr, _ := strings.Cut(geo.Value, ";")
+		lat, err1 := strconv.ParseFloat(strings.TrimSpace(latStr), 64)
+		lng, err2 := strconv.ParseFloat(strings.TrimSpace(lngStr), 64)
+		if err1 == nil && err2 == nil {
+			req.Lat, req.Lng = &lat, &lng
+		}
+	}
+	if cats := v.text("CATEGORIES"); cats != "" {
+		for _, c := range strings.Split(cats, ",") {
+			if t := EventType(strings.ToLower(strings.TrimSpace(c))); knownEventTypes[t] {
+				req.Type = t
+				break
+			}
+		}
+	}
+	if p := v.get("PRIORITY"); p != nil {
+		if n, err := strconv.Atoi(strings.TrimSpace(p.Value)); err == nil {
+			if pr := priorityFromICal(n); pr != "" {
+				req.Priority = pr
+			}
+		}
+	}
+
+	if rrule := v.get("RRULE"); rrule != nil {
+		req.Recurrence = rrule.Value
+		for _, p := range v {
+			if p.Name != "EXDATE" {
+				continue
+			}
+			for _, val := range strings.Split(p.Value, ",") {
+				t, _, err := icalTime(icalProp{Name: p.Name, Params: p.Params, Value: val})
+				if err != nil {
+					return req, skipf("EXDATE: %v", err)
+				}
+				req.ExDates = append(req.ExDates, dateOnly(t).Format(dateLayout))
+			}
+		}
+	}
+	return req, nil
+}
+
+func clockIn(t time.Time) string {
+	return t.In(orgLocation()).Format("15:04")
+}
+
+// icalTime parses a DATE or DATE-TIME value: UTC ("…Z"), in its TZID, or
+// floating, which is taken as the org zone like the app's own times.
+// allDay is true for a DATE.
+func icalTime(p icalProp) (t time.Time, allDay bool, err error) {
+	v := strings.TrimSpace(p.Value)
+	if strings.EqualFold(p.Params["VALUE"], "DATE") || len(v) == len(icalDateLayout) {
+		t, err = time.ParseInLocation(icalDateLayout, v, orgLocation())
+		return t, true, err
+	}
+	if strings.HasSuffix(v, "Z") {
+		t, err = time.Parse(icalTimeLayout, v)
+		return t, false, err
+	}
+	loc := orgLocation()
+	if tzid := strings.Trim(p.Params["TZID"], `"`); tzid != "" {
+		if z, ok := windowsZones[tzid]; ok {
+			tzid = z
+		}
+		if loc, err = time.LoadLocation(strings.TrimPrefix(tzid, "/")); err != nil {
+			return time.Time{}, false, fmt.Errorf("unknown time zone %q", tzid)
+		}
+	}
+	t, err = time.ParseInLocation(icalLocalLayout, v, loc)
+	return t, false, err
+}
+
+// parseICalDuration parses a DURATION such as PT1H30M, P1D or P2W.
+func parseICalDuration(s string) (time.Duration, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	sign := time.Duration(1)
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		if s[0] == '-' {
+			sign = -1
+		}
+		s = s[1:]
+	}
+	rest, ok := strings.CutPrefix(s, "P")
+	if !ok || rest == "" {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	units := map[byte]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour, 'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+	var d time.Duration
+	inTime, parts := false, 0
+	num := ""
+	for i := 0; i < len(rest); i++ {
+		c := rest[i]
+		switch {
+		case c == 'T':
+			inTime = true
+		case c >= '0' && c <= '9':
+			num += string(c)
+		default:
+			unit, ok := units[c]
+			n, err := strconv.Atoi(num)
+			if !ok || err != nil || (inTime != (c == 'H' || c == 'M' || c == 'S')) {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			d += time.Duration(n) * unit
+			num = ""
+			parts++
+		}
+	}
+	if num != "" || parts == 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return sign * d, nil
+}
+
+// parseICal unfolds and splits an iCalendar document and returns the
+// properties of each top-level VEVENT. Nested components such as VALARM
+// are skipped.
+func parseICal(data []byte) ([]vevent, error) {
+	s := strings.TrimPrefix(string(data), "\ufeff")
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\n ", "")
+	s = strings.ReplaceAll(s, "\n\t", "")
+
+	var (
+		out     []vevent
+		stack   []string
+		current vevent
+		seen    bool
+	)
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			continue
+		}
+		p, ok := parseContentLine(line)
+		if !ok {
+			continue
+		}
+		switch p.Name {
+		case "BEGIN":
+			comp := strings.ToUpper(strings.TrimSpace(p.Value))
+			if len(stack) == 0 && comp != "VCALENDAR" {
+				return nil, ErrNotCalendar
+			}
+			seen = true
+			stack = append(stack, comp)
+			if comp == "VEVENT" && len(stack) == 2 {
+				current = vevent{}
+			}
+		case "END":
+			if len(stack) == 0 {
+				return nil, ErrNotCalendar
+			}
+			if len(stack) == 2 && stack[1] == "VEVENT" {
+				out = append(out, current)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 2 && stack[1] == "VEVENT" {
+				current = append(current, p)
+			}
+		}
+	}
+	if !seen {
+		return nil, ErrNotCalendar
+	}
+	return out, nil
+}
+
+// parseContentLine splits "NAME;PARAM=value;PARAM=\"quoted\":value".
+func parseContentLine(line string) (icalProp, bool) {
+	p := icalProp{Params: map[string]string{}}
+	inQuote := false
+	nameEnd, valueStart := -1, -1
+	for i := 0; i < len(line) && valueStart < 0; i++ {
+		switch c := line[i]; {
+		case c == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case c == ';' && nameEnd < 0:
+			nameEnd = i
+		case c == ':':
+			if nameEnd < 0 {
+				nameEnd = i
+			}
+			valueStart = i + 1
+		}
+	}
+	if valueStart < 0 {
+		return p, false
+	}
+	p.Name = strings.ToUpper(line[:nameEnd])
+	p.Value = line[valueStart:]
+	if params := line[nameEnd:max(valueStart-1, nameEnd)]; params != "" {
+		for _, kv := range splitParams(strings.TrimPrefix(params, ";")) {
+			k, v, _ := strings.Cut(kv, "=")
+			p.Params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return p, true
+}
+
+// splitParams splits parameters on semicolons outside quotes.
+func splitParams(s string) []string {
+	var out []string
+	inQuote, start := false, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			inQuote = !inQuote
+		case ';':
+			if !inQuote {
+				out = append(out, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(out, s[start:])
+}
+
+// icalUnescape undoes icalText.
+func icalUnescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
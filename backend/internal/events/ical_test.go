@@ -0,0 +1,336 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// unfold joins folded lines so tests can look for whole properties.
+func unfold(s string) string {
+	return strings.ReplaceAll(s, "\r\n ", "")
+}
+
+// ---- writing ----
+
+func TestWriteCalendar(t *testing.T) {
+	setupEvents(t)
+	ctx := context.Background()
+	lat, lng := 53.2707, -9.0568
+	s, _ := Create(ctx, CreateEventRequest{
+		Title: "Night rota", Description: "Bring keys; hi-vis, radio\nand phone", Location: "Galway, Depot",
+		Date: mustDate(t, "2026-04-06"), StartTime: "22:00", EndTime: "02:00", Lat: &lat, Lng: &lng,
+		Type: "training", Priority: "high", Recurrence: "FREQ=WEEKLY;UNTIL=20260601", ExDates: []string{"2026-04-13"},
+	})
+	_, _ = UpdateOccurrence(ctx, s.ID, mustDate(t, "2026-04-20"), UpdateEventRequest{StartTime: strPtr("21:00")})
+	all, _ := listStored(ctx)
+
+	out := string(writeCalendar("Blood Bike events", all, time.Now()))
+	for _, line := range strings.Split(out, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 octets: %q", line)
+		}
+	}
+	cal := unfold(out)
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"UID:" + s.ID + icalUIDDomain + "\r\n",
+		"DTSTART;TZID=Europe/Dublin:20260406T220000\r\nDTEND;TZID=Europe/Dublin:20260407T020000\r\n",
+		`DESCRIPTION:Bring keys\; hi-vis\, radio\nand phone` + "\r\n",
+		"LOCATION:Galway\\, Depot\r\n",
+		"GEO:53.270700;-9.056800\r\n",
+		"CATEGORIES:TRAINING\r\nPRIORITY:3\r\n",
+		"RRULE:FREQ=WEEKLY;UNTIL=20260601T225959Z\r\n",
+		"EXDATE;TZID=Europe/Dublin:20260413T220000\r\n",
+		"RECURRENCE-ID;TZID=Europe/Dublin:20260420T220000\r\nDTSTAMP:",
+		"DTSTART;TZID=Europe/Dublin:20260420T210000\r\n",
+		"X-WR-TIMEZONE:Europe/Dublin\r\n",
+		"BEGIN:VTIMEZONE\r\nTZID:Europe/Dublin\r\n",
+		"BEGIN:DAYLIGHT\r\nDTSTART:20260329T010000\r\nTZOFFSETFROM:+0000\r\nTZOFFSETTO:+0100\r\nTZNAME:IST\r\nEND:DAYLIGHT\r\n",
+		"BEGIN:STANDARD\r\nDTSTART:20261025T020000\r\nTZOFFSETFROM:+0100\r\nTZOFFSETTO:+0000\r\nTZNAME:GMT\r\nEND:STANDARD\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(cal, want) {
+			t.Errorf("calendar missing %q", want)
+		}
+	}
+	if n := strings.Count(cal, "UID:"+s.ID+icalUIDDomain); n != 2 {
+		t.Errorf("expected the override to share the series UID, got %d", n)
+	}
+}
+
+// ---- feeds ----
+
+func TestFeedToken(t *testing.T) {
+	token := feedToken(feedScopeMine, "rider1", "k1")
+	if scope, user, key, ok := parseFeedToken(token); !ok || scope != feedScopeMine || user != "rider1" || key != "k1" {
+		t.Fatalf("expected mine/rider1/k1, got %q %q %q %v", scope, user, key, ok)
+	}
+	other := feedToken(feedScopeAll, "rider1", "k1")
+	forged := token[:strings.LastIndex(token, ".")] + other[strings.LastIndex(other, "."):]
+	for _, bad := range []string{"", "rider1", "cal2.", forged, token + "x", feedToken(feedScopeMine, "rider1", "")} {
+		if _, _, _, ok := parseFeedToken(bad); ok {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestCalendarFeed_Mine(t *testing.T) {
+	setupEvents(t)
+	t.Setenv("CALENDAR_FEED_SECRET", "test-secret")
+	setupRiders(t, map[string][]string{"rider1": nil})
+	ctx := context.Background()
+	key, _, _ := feedKey(ctx, "rider1", false)
+	s := newSeries(t, "2026-04-07", "FREQ=WEEKLY;COUNT=4")
+	_, _ = Update(ctx, s.ID, UpdateEventRequest{AssignedRiders: &[]string{"rider1", "rider2"}})
+	// rider1 is taken off the 14th and put on a one-off.
+	_, _ = UpdateOccurrence(ctx, s.ID, mustDate(t, "2026-04-14"), UpdateEventRequest{AssignedRiders: &[]string{"rider2"}})
+	one, _ := Create(ctx, CreateEventRequest{Title: "Drive", Location: "Athlone", Date: mustDate(t, "2026-04-15"), StartTime: "09:00", EndTime: "10:00", AssignedRiders: []string{"rider1"}})
+	other, _ := Create(ctx, CreateEventRequest{Title: "Other", Location: "Sligo", Date: mustDate(t, "2026-04-16"), StartTime: "09:00", EndTime: "10:00"})
+
+	rec := httptest.NewRecorder()
+	CalendarFeed(rec, httptest.NewRequest(http.MethodGet, feedPath(feedScopeMine, "rider1", key), nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/calendar") {
+		t.Fatalf("expected a calendar, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	cal := unfold(rec.Body.String())
+	if !strings.Contains(cal, "UID:"+one.ID) || strings.Contains(cal, "UID:"+other.ID) {
+		t.Error("expected only rider1's events")
+	}
+	if !strings.Contains(cal, "EXDATE;TZID=Europe/Dublin:20260414T180000") || strings.Contains(cal, "RECURRENCE-ID") {
+		t.Errorf("expected the 14th excluded for rider1:\n%s", cal)
+	}
+
+	rec = httptest.NewRecorder()
+	CalendarFeed(rec, httptest.NewRequest(http.MethodGet, feedPath(feedScopeAll, "rider1", key), nil))
+	if cal := rec.Body.String(); !strings.Contains(cal, "UID:"+other.ID) || !strings.Contains(cal, "RECURRENCE-ID;TZID=Europe/Dublin:20260414T180000") {
+		t.Errorf("expected every event in the org feed:\n%s", cal)
+	}
+
+	rec = httptest.NewRecorder()
+	CalendarFeed(rec, httptest.NewRequest(http.MethodGet, "/api/calendar/cal2.bm9wZQ.AAAA.ics", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected a bad token to 404, got %d", rec.Code)
+	}
+}
+
+func TestCalendarFeed_RevokedByResetOrRemovedUser(t *testing.T) {
+	setupEvents(t)
+	t.Setenv("CALENDAR_FEED_SECRET", "test-secret")
+	setupRiders(t, map[string][]string{"rider1": nil, "rider2": nil})
+	ctx := context.Background()
+	get := func(path string) int {
+		rec := httptest.NewRecorder()
+		CalendarFeed(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+
+	old, _, _ := feedKey(ctx, "rider1", false)
+	if again, _, _ := feedKey(ctx, "rider1", false); again != old {
+		t.Fatalf("expected the key kept between calls, got %q then %q", old, again)
+	}
+	fresh, _, _ := feedKey(ctx, "rider1", true)
+	if code := get(feedPath(feedScopeMine, "rider1", old)); code != http.StatusNotFound {
+		t.Errorf("expected a reset link to 404, got %d", code)
+	}
+	if code := get(feedPath(feedScopeMine, "rider1", fresh)); code != http.StatusOK {
+		t.Errorf("expected the new link to work, got %d", code)
+	}
+
+	key, _, _ := feedKey(ctx, "rider2", false)
+	_, _ = usersRepo.Delete(ctx, "rider2")
+	if code := get(feedPath(feedScopeAll, "rider2", key)); code != http.StatusNotFound {
+		t.Errorf("expected a removed user's feed to 404, got %d", code)
+	}
+	if _, ok, _ := feedKey(ctx, "rider2", false); ok {
+		t.Error("expected no links for a removed user")
+	}
+}
+
+func TestCalendarFeed_RefusedWithoutSecret(t *testing.T) {
+	setupEvents(t)
+	setupRiders(t, map[string][]string{"rider1": nil})
+	t.Setenv("CALENDAR_FEED_SECRET", "")
+	key, _, _ := feedKey(context.Background(), "rider1", false)
+
+	rec := httptest.NewRecorder()
+	HandleCalendarLinks(rec, httptest.NewRequest(http.MethodGet, "/api/calendar/links", nil))
+	if rec.Code != http.StatusNotImplemented {
+		t.Errorf("expected links to 501 without CALENDAR_FEED_SECRET, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	CalendarFeed(rec, httptest.NewRequest(http.MethodGet, feedPath(feedScopeMine, "rider1", key), nil))
+	if rec.Code != http.StatusNotImplemented {
+		t.Errorf("expected the feed to 501 without CALENDAR_FEED_SECRET, got %d", rec.Code)
+	}
+}
+
+// ---- import ----
+
+const sampleICS = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//Example//EN\r\n" +
+	"BEGIN:VTIMEZONE\r\nTZID:Europe/Dublin\r\nEND:VTIMEZONE\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:rota@example.com\r\n" +
+	"SUMMARY:On-call rota\r\n" +
+	"DESCRIPTION:Phone on\\, keys in\\nthe box\r\n" +
+	"LOCATION:Galway Depot\r\n" +
+	"GEO:53.27;-9.05\r\n" +
+	"CATEGORIES:MEETING,TRAINING\r\n" +
+	"PRIORITY:1\r\n" +
+	"DTSTART;TZID=Europe/Dublin:20260406T190000\r\n" +
+	"DTEND;TZID=Europe/Dublin:20260406T230000\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO;COUNT=4\r\n" +
+	"EXDATE;TZID=Europe/Dublin:20260413T190000\r\n" +
+	"BEGIN:VALARM\r\nACTION:DISPLAY\r\nDESCRIPTION:Reminder\r\nEND:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:rota@example.com\r\n" +
+	"RECURRENCE-ID;TZID=Europe/Dublin:20260420T190000\r\n" +
+	"SUMMARY:On-call rota (moved)\r\n" +
+	"LOCATION:Athlone\r\n" +
+	"DTSTART;TZID=Europe/Dublin:20260421T190000\r\n" +
+	"DURATION:PT4H\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:rota@example.com\r\n" +
+	"RECURRENCE-ID;TZID=Europe/Dublin:20260427T190000\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"DTSTART;TZID=Europe/Dublin:20260427T190000\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:agm@example.com\r\n" +
+	"SUMMARY:Annual general meet\r\n" +
+	" ing\r\n" +
+	"LOCATION:Clubhouse\r\n" +
+	"DTSTART;VALUE=DATE:20260502\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:nowhere@example.com\r\n" +
+	"SUMMARY:No location\r\n" +
+	"DTSTART:20260502T100000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:yearly@example.com\r\n" +
+	"SUMMARY:Yearly\r\n" +
+	"LOCATION:Somewhere\r\n" +
+	"DTSTART:20260502T100000Z\r\n" +
+	"RRULE:FREQ=YEARLY\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestImport(t *testing.T) {
+	setupEvents(t)
+	ctx := context.Background()
+	res, err := Import(ctx, []byte(sampleICS))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Created) != 3 || len(res.Skipped) != 2 {
+		t.Fatalf("expected 3 created and 2 skipped, got %+v", res)
+	}
+	if res.Skipped[0].UID != "nowhere@example.com" || res.Skipped[0].Reason != "location required" {
+		t.Errorf("unexpected skip %+v", res.Skipped[0])
+	}
+	if res.Skipped[1].UID != "yearly@example.com" || !strings.Contains(res.Skipped[1].Reason, "FREQ") {
+		t.Errorf("unexpected skip %+v", res.Skipped[1])
+	}
+
+	s := res.Created[0]
+	if s.Title != "On-call rota" || s.StartTime != "19:00" || s.EndTime != "23:00" || s.Recurrence != "FREQ=WEEKLY;BYDAY=MO;COUNT=4" {
+		t.Errorf("unexpected series %+v", s)
+	}
+	if s.Description != "Phone on, keys in\nthe box" || s.Type != "meeting" || s.Priority != "urgent" || s.Lat == nil || *s.Lat != 53.27 {
+		t.Errorf("unexpected mapping %+v", s)
+	}
+	agm := res.Created[1]
+	if agm.Title != "Annual general meeting" || agm.StartTime != "00:00" || agm.EndTime != "23:59" || agm.Date.Format(dateLayout) != "2026-05-02" {
+		t.Errorf("unexpected all-day event %+v", agm)
+	}
+	moved := res.Created[2]
+	if moved.SeriesID != s.ID || moved.OccurrenceDate != "2026-04-20" || moved.Date.Format(dateLayout) != "2026-04-21" || moved.EndTime != "23:00" {
+		t.Errorf("unexpected edited occurrence %+v", moved)
+	}
+
+	// The 13th is excluded, the 20th moved to the 21st and the 27th cancelled.
+	if got, want := listDates(t, "2026-04-01", "2026-05-01"), "2026-04-06 2026-04-21"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestImport_RoundTrip(t *testing.T) {
+	setupEvents(t)
+	ctx := context.Background()
+	lat, lng := 53.27, -9.05
+	orig, _ := Create(ctx, CreateEventRequest{
+		Title: "Training, night", Description: "Line one\nline two; ok", Location: "Galway",
+		Date: mustDate(t, "2026-04-07"), StartTime: "22:30", EndTime: "01:00", Lat: &lat, Lng: &lng,
+		Type: "training", Priority: "low", Recurrence: "FREQ=MONTHLY;BYDAY=1TU;UNTIL=20261231", ExDates: []string{"2026-05-05"},
+	})
+	all, _ := listStored(ctx)
+	cal := writeCalendar("x", all, time.Now())
+
+	setupEvents(t)
+	res, err := Import(ctx, cal)
+	if err != nil || len(res.Created) != 1 {
+		t.Fatalf("expected one event back, got %+v %v", res, err)
+	}
+	got := res.Created[0]
+	if got.Title != orig.Title || got.Description != orig.Description || got.Location != orig.Location ||
+		!got.Date.Equal(orig.Date) || got.StartTime != orig.StartTime || got.EndTime != orig.EndTime ||
+		got.Type != orig.Type || got.Priority != orig.Priority || got.Recurrence != orig.Recurrence ||
+		len(got.ExDates) != 1 || got.ExDates[0] != "2026-05-05" || *got.Lat != lat || *got.Lng != lng {
+		t.Errorf("round trip changed the event:\n got %+v\nwant %+v", got, orig)
+	}
+}
+
+func TestImportCalendar_Handler(t *testing.T) {
+	setupEvents(t)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "rota.ics")
+	_, _ = fw.Write([]byte(sampleICS))
+	_ = mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/calendar/import", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	ImportCalendar(rec, req)
+	var res ImportResult
+	_ = json.NewDecoder(rec.Body).Decode(&res)
+	if rec.Code != http.StatusOK || len(res.Created) != 3 {
+		t.Fatalf("expected 3 events imported, got %d %+v", rec.Code, res)
+	}
+
+	rec = httptest.NewRecorder()
+	ImportCalendar(rec, httptest.NewRequest(http.MethodPost, "/api/calendar/import", strings.NewReader("title,date\nx,y\n")))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected a CSV to be refused, got %d", rec.Code)
+	}
+}
+
+func TestParseICalDuration(t *testing.T) {
+	for in, want := range map[string]time.Duration{
+		"PT1H30M": 90 * time.Minute,
+		"P1D":     24 * time.Hour,
+		"P1DT2H":  26 * time.Hour,
+		"P2W":     14 * 24 * time.Hour,
+		"-PT15M":  -15 * time.Minute,
+	} {
+		if got, err := parseICalDuration(in); err != nil || got != want {
+			t.Errorf("%s: got %v %v, want %v", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "P", "PT", "1H", "P1H", "PT1D", "PT5"} {
+		if _, err := parseICalDuration(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
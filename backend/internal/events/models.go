@@ -31,6 +31,32 @@ type Event struct {
 	Status         EventStatus   `json:"status"`
 	CreatedAt      time.Time     `json:"createdAt"`
 	UpdatedAt      time.Time     `json:"updatedAt"`
+
+	// Recurrence is the series' RRULE (see rrule.go) and ExDates the
+	// occurrence dates ("2006-01-02") cancelled from it. Every occurrence
+	// List returns has SeriesID and OccurrenceDate set; a generated one
+	// keeps the series' ID, while one edited on its own has an ID of its
+	// own and may have moved to another Date.
+	Recurrence     string   `json:"recurrence,omitempty"`
+	ExDates        []string `json:"exDates,omitempty"`
+	SeriesID       string   `json:"seriesId,omitempty"`
+	OccurrenceDate string   `json:"occurrenceDate,omitempty"`
+
+	// ArchivedAt is set once the event has ended for good. Archived events
+	// only appear in List with IncludePast.
+	ArchivedAt *time.Time `json:"archivedAt,omitempty"`
+
+	// RequiredRiders caps AssignedRiders for self sign-up (0 means no
+	// limit); riders signing up once it is reached join the Waitlist.
+	// RequiredQualifications are user tags every signed-up rider must hold.
+	// See signup.go.
+	RequiredRiders         int      `json:"requiredRiders,omitempty"`
+	RequiredQualifications []string `json:"requiredQualifications,omitempty"`
+	Waitlist               []string `json:"waitlist,omitempty"`
+
+	// StaffingAlerts are the occurrence dates coordinators have already
+	// been warned about.
+	StaffingAlerts []string `json:"-"`
 }
 
 type CreateEventRequest struct {
@@ -45,6 +71,11 @@ type CreateEventRequest struct {
 	Type           EventType     `json:"type"`
 	Priority       EventPriority `json:"priority"`
 	AssignedRiders []string      `json:"assignedRiders,omitempty"`
+	Recurrence     string        `json:"recurrence,omitempty"`
+	ExDates        []string      `json:"exDates,omitempty"`
+
+	RequiredRiders         int      `json:"requiredRiders,omitempty"`
+	RequiredQualifications []string `json:"requiredQualifications,omitempty"`
 }
 
 type UpdateEventRequest struct {
@@ -60,4 +91,9 @@ type UpdateEventRequest struct {
 	Priority       *EventPriority `json:"priority,omitempty"`
 	AssignedRiders *[]string      `json:"assignedRiders,omitempty"`
 	Status         *EventStatus   `json:"status,omitempty"`
+	Recurrence     *string        `json:"recurrence,omitempty"`
+	ExDates        *[]string      `json:"exDates,omitempty"`
+
+	RequiredRiders         *int      `json:"requiredRiders,omitempty"`
+	RequiredQualifications *[]string `json:"requiredQualifications,omitempty"`
 }
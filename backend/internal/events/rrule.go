@@ -0,0 +1,373 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurring events use a subset of the iCalendar RRULE (RFC 5545):
+//
+//	FREQ=DAILY|WEEKLY|MONTHLY   required
+//	INTERVAL=n                  every n days/weeks/months (default 1)
+//	BYDAY=MO,WE or 1TU,-1FR     weekdays; MONTHLY days may carry an ordinal
+//	COUNT=n                     stop after n occurrences
+//	UNTIL=20261231[T235959Z]    stop after this date (inclusive)
+//	WKST=MO                     first day of the week for WEEKLY intervals
+//
+// Occurrences are whole dates; the event's StartTime and EndTime apply to
+// each one. As in RFC 5545 the series' first date always counts as the
+// first occurrence, and COUNT counts occurrences before exceptions are
+// removed.
+
+var ErrInvalidRecurrence = errors.New("invalid recurrence rule")
+
+type Frequency string
+
+const (
+	FreqDaily   Frequency = "DAILY"
+	FreqWeekly  Frequency = "WEEKLY"
+	FreqMonthly Frequency = "MONTHLY"
+)
+
+// WeekdayNum is a BYDAY entry. N is the ordinal within the month for
+// MONTHLY rules (1 = first, -1 = last) and 0 for every such weekday.
+type WeekdayNum struct {
+	N       int
+	Weekday time.Weekday
+}
+
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	ByDay    []WeekdayNum
+	Count    int
+	Until    time.Time // a date; zero for no end date
+	WkSt     time.Weekday
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+var weekdayNames = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// maxPeriods bounds how many days, weeks or months expansion walks through,
+// so a rule that rarely or never matches can't loop forever.
+const maxPeriods = 10000
+
+// ParseRule parses an RRULE value, with or without the "RRULE:" prefix.
+func ParseRule(s string) (*Rule, error) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 6 && strings.EqualFold(s[:6], "RRULE:") {
+		s = s[6:]
+	}
+	if s == "" {
+		return nil, fmt.Errorf("%w: empty", ErrInvalidRecurrence)
+	}
+
+	r := &Rule{Interval: 1, WkSt: time.Monday}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		key = strings.ToUpper(strings.TrimSpace(key))
+		val = strings.ToUpper(strings.TrimSpace(val))
+		if !ok || val == "" {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRecurrence, part)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("%w: %s given twice", ErrInvalidRecurrence, key)
+		}
+		seen[key] = true
+
+		switch key {
+		case "FREQ":
+			switch f := Frequency(val); f {
+			case FreqDaily, FreqWeekly, FreqMonthly:
+				r.Freq = f
+			default:
+				return nil, fmt.Errorf("%w: FREQ must be DAILY, WEEKLY or MONTHLY", ErrInvalidRecurrence)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: INTERVAL must be a positive number", ErrInvalidRecurrence)
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: COUNT must be a positive number", ErrInvalidRecurrence)
+			}
+			r.Count = n
+		case "UNTIL":
+			t, err := parseUntil(val)
+			if err != nil {
+				return nil, fmt.Errorf("%w: UNTIL must be YYYYMMDD or YYYYMMDDTHHMMSSZ", ErrInvalidRecurrence)
+			}
+			r.Until = t
+		case "BYDAY":
+			for _, d := range strings.Split(val, ",") {
+				wd, err := parseWeekdayNum(d)
+				if err != nil {
+					return nil, err
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "WKST":
+			wd, ok := weekdayCodes[val]
+			if !ok {
+				return nil, fmt.Errorf("%w: unknown WKST %q", ErrInvalidRecurrence, val)
+			}
+			r.WkSt = wd
+		default:
+			return nil, fmt.Errorf("%w: %s is not supported", ErrInvalidRecurrence, key)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, fmt.Errorf("%w: FREQ required", ErrInvalidRecurrence)
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return nil, fmt.Errorf("%w: COUNT and UNTIL can't both be given", ErrInvalidRecurrence)
+	}
+	if r.Freq != FreqMonthly {
+		for _, d := range r.ByDay {
+			if d.N != 0 {
+				return nil, fmt.Errorf("%w: BYDAY ordinals are only allowed with FREQ=MONTHLY", ErrInvalidRecurrence)
+			}
+		}
+	}
+	return r, nil
+}
+
+func parseUntil(s string) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", s); err == nil {
+		return dateOnly(t), nil
+	}
+	if t, err := time.ParseInLocation("20060102T150405", s, orgLocation()); err == nil {
+		return dateOnly(t), nil
+	}
+	return time.Parse("20060102", s)
+}
+
+func parseWeekdayNum(s string) (WeekdayNum, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 {
+		return WeekdayNum{}, fmt.Errorf("%w: unknown BYDAY %q", ErrInvalidRecurrence, s)
+	}
+	wd, ok := weekdayCodes[s[len(s)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("%w: unknown BYDAY %q", ErrInvalidRecurrence, s)
+	}
+	n := 0
+	if ord := s[:len(s)-2]; ord != "" {
+		var err error
+		n, err = strconv.Atoi(ord)
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return WeekdayNum{}, fmt.Errorf("%w: BYDAY ordinal must be 1 to 5 or -1 to -5", ErrInvalidRecurrence)
+		}
+	}
+	return WeekdayNum{N: n, Weekday: wd}, nil
+}
+
+// String formats the rule back into its canonical RRULE value.
+func (r *Rule) String() string {
+	return r.format(r.Until.Format("20060102"))
+}
+
+// icalString formats the rule for a calendar whose DTSTART is a date-time,
+// where UNTIL has to be a UTC one: the end of the UNTIL date in the org
+// zone.
+func (r *Rule) icalString() string {
+	if r.Until.IsZero() {
+		return r.format("")
+	}
+	end, _ := atClock(r.Until, "23:59:59")
+	return r.format(end.UTC().Format("20060102T150405Z"))
+}
+
+func (r *Rule) format(until string) string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = weekdayNames[d.Weekday]
+			if d.N != 0 {
+				days[i] = strconv.Itoa(d.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+until)
+	}
+	if r.WkSt != time.Monday {
+		parts = append(parts, "WKST="+weekdayNames[r.WkSt])
+	}
+	return strings.Join(parts, ";")
+}
+
+// Each calls fn with every occurrence date of a series first held on
+// start, in order, until fn returns false or the series ends. Dates are
+// midnight UTC.
+func (r *Rule) Each(start time.Time, fn func(time.Time) bool) {
+	start = dateOnly(start)
+	n := 0
+	emit := func(d time.Time) bool {
+		if !r.Until.IsZero() && d.After(r.Until) {
+			return false
+		}
+		n++
+		if !fn(d) {
+			return false
+		}
+		return r.Count == 0 || n < r.Count
+	}
+
+	// The first date always counts, even when the rule wouldn't pick it.
+	if !emit(start) {
+		return
+	}
+	for k := 0; k < maxPeriods; k++ {
+		for _, d := range r.period(start, k) {
+			if !d.After(start) {
+				continue
+			}
+			if !emit(d) {
+				return
+			}
+		}
+	}
+}
+
+// period returns the dates the rule picks in the k-th day, week or month of
+// the series, in order.
+func (r *Rule) period(start time.Time, k int) []time.Time {
+	switch r.Freq {
+	case FreqDaily:
+		d := start.AddDate(0, 0, k*r.Interval)
+		if len(r.ByDay) > 0 && !r.hasWeekday(d.Weekday()) {
+			return nil
+		}
+		return []time.Time{d}
+
+	case FreqWeekly:
+		weekStart := start.AddDate(0, 0, -((int(start.Weekday()) - int(r.WkSt) + 7) % 7))
+		weekStart = weekStart.AddDate(0, 0, 7*k*r.Interval)
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []WeekdayNum{{Weekday: start.Weekday()}}
+		}
+		out := make([]time.Time, 0, len(days))
+		for _, d := range days {
+			out = append(out, weekStart.AddDate(0, 0, (int(d.Weekday)-int(r.WkSt)+7)%7))
+		}
+		return sortDates(out)
+
+	case FreqMonthly:
+		month := time.Date(start.Year(), start.Month()+time.Month(k*r.Interval), 1, 0, 0, 0, 0, time.UTC)
+		if len(r.ByDay) == 0 {
+			d := month.AddDate(0, 0, start.Day()-1)
+			if d.Month() != month.Month() {
+				return nil // e.g. the 31st in a 30-day month
+			}
+			return []time.Time{d}
+		}
+		var out []time.Time
+		for _, wd := range r.ByDay {
+			out = append(out, weekdaysInMonth(month, wd)...)
+		}
+		return sortDates(out)
+	}
+	return nil
+}
+
+func (r *Rule) hasWeekday(wd time.Weekday) bool {
+	for _, d := range r.ByDay {
+		if d.Weekday == wd {
+			return true
+		}
+	}
+	return false
+}
+
+// weekdaysInMonth returns the dates in month matching wd: the N-th one
+// (counting from the end when N is negative), or all of them when N is 0.
+func weekdaysInMonth(month time.Time, wd WeekdayNum) []time.Time {
+	first := month.AddDate(0, 0, (int(wd.Weekday)-int(month.Weekday())+7)%7)
+	var all []time.Time
+	for d := first; d.Month() == month.Month(); d = d.AddDate(0, 0, 7) {
+		all = append(all, d)
+	}
+	switch {
+	case wd.N == 0:
+		return all
+	case wd.N > 0 && wd.N <= len(all):
+		return all[wd.N-1 : wd.N]
+	case wd.N < 0 && -wd.N <= len(all):
+		return all[len(all)+wd.N : len(all)+wd.N+1]
+	}
+	return nil
+}
+
+// sortDates sorts dates and drops duplicates, e.g. BYDAY=1MO,MO.
+func sortDates(ds []time.Time) []time.Time {
+	sort.Slice(ds, func(i, j int) bool { return ds[i].Before(ds[j]) })
+	out := ds[:0]
+	for i, d := range ds {
+		if i == 0 || !d.Equal(ds[i-1]) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// Between returns the occurrence dates from start that fall in [from, to).
+func (r *Rule) Between(start, from, to time.Time) []time.Time {
+	var out []time.Time
+	r.Each(start, func(d time.Time) bool {
+		if !d.Before(to) {
+			return false
+		}
+		if !d.Before(from) {
+			out = append(out, d)
+		}
+		return true
+	})
+	return out
+}
+
+// Occurs reports whether date is an occurrence of the series.
+func (r *Rule) Occurs(start, date time.Time) bool {
+	date = dateOnly(date)
+	found := false
+	r.Each(start, func(d time.Time) bool {
+		found = d.Equal(date)
+		return d.Before(date)
+	})
+	return found
+}
+
+// Last returns the series' final occurrence date, or false if it never ends.
+func (r *Rule) Last(start time.Time) (time.Time, bool) {
+	if r.Count == 0 && r.Until.IsZero() {
+		return time.Time{}, false
+	}
+	var last time.Time
+	r.Each(start, func(d time.Time) bool {
+		last = d
+		return true
+	})
+	return last, true
+}
@@ -0,0 +1,118 @@
+package events
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func dates(ts []time.Time) string {
+	out := make([]string, len(ts))
+	for i, t := range ts {
+		out[i] = t.Format(dateLayout)
+	}
+	return strings.Join(out, " ")
+}
+
+func mustDate(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse(dateLayout, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// ---- expansion ----
+
+func TestRule_Expand(t *testing.T) {
+	cases := []struct {
+		rule, start, from, to string
+		want                  string
+	}{
+		{"FREQ=DAILY;COUNT=3", "2026-03-30", "2026-01-01", "2027-01-01",
+			"2026-03-30 2026-03-31 2026-04-01"},
+		{"FREQ=DAILY;INTERVAL=2;UNTIL=20260407", "2026-04-01", "2026-01-01", "2027-01-01",
+			"2026-04-01 2026-04-03 2026-04-05 2026-04-07"},
+		{"FREQ=DAILY;BYDAY=MO,FR;COUNT=4", "2026-04-01", "2026-01-01", "2027-01-01",
+			"2026-04-01 2026-04-03 2026-04-06 2026-04-10"},
+		// Weekly on-call rota, Mondays and Thursdays.
+		{"FREQ=WEEKLY;BYDAY=MO,TH", "2026-04-06", "2026-04-08", "2026-04-21",
+			"2026-04-09 2026-04-13 2026-04-16 2026-04-20"},
+		{"FREQ=WEEKLY;INTERVAL=2;COUNT=3", "2026-04-01", "2026-01-01", "2027-01-01",
+			"2026-04-01 2026-04-15 2026-04-29"},
+		// The RFC 5545 WKST example: the week start changes which weeks count.
+		{"FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=MO", "1997-08-05", "1997-01-01", "1998-01-01",
+			"1997-08-05 1997-08-10 1997-08-19 1997-08-24"},
+		{"FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=SU", "1997-08-05", "1997-01-01", "1998-01-01",
+			"1997-08-05 1997-08-17 1997-08-19 1997-08-31"},
+		// Monthly training night, first Tuesday.
+		{"FREQ=MONTHLY;BYDAY=1TU;COUNT=3", "2026-01-06", "2026-01-01", "2027-01-01",
+			"2026-01-06 2026-02-03 2026-03-03"},
+		{"FREQ=MONTHLY;BYDAY=-1FR;UNTIL=20260430", "2026-01-30", "2026-01-01", "2027-01-01",
+			"2026-01-30 2026-02-27 2026-03-27 2026-04-24"},
+		// Months without a 31st are skipped.
+		{"FREQ=MONTHLY;COUNT=4", "2026-01-31", "2026-01-01", "2027-01-01",
+			"2026-01-31 2026-03-31 2026-05-31 2026-07-31"},
+		// A first date the rule wouldn't pick still counts.
+		{"FREQ=WEEKLY;BYDAY=MO;COUNT=2", "2026-04-01", "2026-01-01", "2027-01-01",
+			"2026-04-01 2026-04-06"},
+	}
+	for _, c := range cases {
+		r, err := ParseRule(c.rule)
+		if err != nil {
+			t.Fatalf("%s: %v", c.rule, err)
+		}
+		got := dates(r.Between(mustDate(t, c.start), mustDate(t, c.from), mustDate(t, c.to)))
+		if got != c.want {
+			t.Errorf("%s from %s:\n got %s\nwant %s", c.rule, c.start, got, c.want)
+		}
+	}
+}
+
+func TestRule_OccursAndLast(t *testing.T) {
+	r, _ := ParseRule("FREQ=WEEKLY;BYDAY=TU;COUNT=3")
+	start := mustDate(t, "2026-04-07")
+	if !r.Occurs(start, mustDate(t, "2026-04-21")) || r.Occurs(start, mustDate(t, "2026-04-28")) || r.Occurs(start, mustDate(t, "2026-04-08")) {
+		t.Error("expected only the three Tuesdays to occur")
+	}
+	if last, ok := r.Last(start); !ok || last.Format(dateLayout) != "2026-04-21" {
+		t.Errorf("expected last 2026-04-21, got %v %v", last, ok)
+	}
+	open, _ := ParseRule("FREQ=DAILY")
+	if _, ok := open.Last(start); ok {
+		t.Error("expected an open-ended rule to have no last date")
+	}
+}
+
+// ---- parsing ----
+
+func TestParseRule(t *testing.T) {
+	r, err := ParseRule(" rrule:freq=monthly;byday=1tu,-1fr;until=20261231T235959Z;interval=1 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.String(); got != "FREQ=MONTHLY;BYDAY=1TU,-1FR;UNTIL=20261231" {
+		t.Errorf("unexpected canonical form %q", got)
+	}
+
+	for _, bad := range []string{
+		"",
+		"COUNT=3",
+		"FREQ=YEARLY",
+		"FREQ=DAILY;COUNT=0",
+		"FREQ=DAILY;COUNT=2;UNTIL=20260101",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=MONTHLY;BYDAY=6MO",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;BYMONTH=1",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		"FREQ",
+	} {
+		if _, err := ParseRule(bad); !errors.Is(err, ErrInvalidRecurrence) {
+			t.Errorf("expected %q to be rejected, got %v", bad, err)
+		}
+	}
+}
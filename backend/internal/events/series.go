@@ -0,0 +1,338 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A recurring event is stored once, as a series with an RRULE. List
+// expands it into one event per occurrence. Cancelling a single date adds
+// it to the series' ExDates; editing a single date stores a copy of the
+// occurrence with SeriesID and OccurrenceDate set, which List shows in
+// place of the generated one. Editing the series itself changes every
+// occurrence that hasn't been edited on its own.
+
+const dateLayout = "2006-01-02"
+
+// defaultListDays is how far ahead List expands series when no range is
+// given.
+const defaultListDays = 90
+
+// seriesMu serialises changes that touch a series and its edited
+// occurrences together.
+var seriesMu sync.Mutex
+
+var (
+	ErrNotRecurring = errors.New("event does not recur")
+	ErrNoOccurrence = errors.New("occurrence not found")
+
+	errSeriesOnly = errors.New("recurrence and exDates can only be changed on the series")
+	errSeriesDate = errors.New("date required for a recurring event")
+	errExDate     = errors.New("exDates must be dates (YYYY-MM-DD)")
+)
+
+// ListQuery selects the events List returns.
+type ListQuery struct {
+	// From and To are calendar dates bounding the list, [From, To). Both
+	// zero means no range.
+	From, To time.Time
+	// IncludePast includes archived events, for history and reporting.
+	IncludePast bool
+}
+
+// List returns the events q selects, sorted by date, with every recurring
+// series expanded into its occurrences. Without a range every one-off event
+// is returned and series are expanded over the next defaultListDays days
+// (with IncludePast, from as far back as archived events are kept).
+// Occurrences that have already ended are shown as completed.
+func List(ctx context.Context, q ListQuery) ([]*Event, error) {
+	stored, err := listStored(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	from, to := q.From, q.To
+	bounded := !from.IsZero() || !to.IsZero()
+	if !bounded {
+		from = dateOnly(now)
+		to = from.AddDate(0, 0, defaultListDays)
+		if q.IncludePast {
+			from = dateOnly(now.Add(-retention()))
+			if retention() == 0 {
+				from = time.Time{}
+			}
+		}
+	}
+
+	edited := make(map[string]bool)
+	for _, e := range stored {
+		if e.SeriesID != "" {
+			edited[e.SeriesID+" "+e.OccurrenceDate] = true
+		}
+	}
+
+	out := make([]*Event, 0, len(stored))
+	for _, e := range stored {
+		if e.ArchivedAt != nil && !q.IncludePast {
+			continue
+		}
+		if e.Recurrence == "" {
+			if d := dateOnly(e.Date); !bounded || (!d.Before(from) && d.Before(to)) {
+				out = append(out, e)
+			}
+			continue
+		}
+		rule, err := ParseRule(e.Recurrence)
+		if err != nil {
+			log.Printf("[events] series %s has an invalid rule %q: %v", e.ID, e.Recurrence, err)
+			continue
+		}
+		excluded := make(map[string]bool, len(e.ExDates))
+		for _, d := range e.ExDates {
+			excluded[d] = true
+		}
+		for _, d := range rule.Between(e.Date, from, to) {
+			key := d.Format(dateLayout)
+			if excluded[key] || edited[e.ID+" "+key] {
+				continue
+			}
+			o := occurrence(e, d)
+			if end, err := eventEndTime(o); err == nil && now.After(end) && isOpenStatus(o.Status) {
+				o.Status = EventStatusCompleted
+			}
+			out = append(out, o)
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		di, dj := dateOnly(out[i].Date), dateOnly(out[j].Date)
+		if !di.Equal(dj) {
+			return di.Before(dj)
+		}
+		return out[i].StartTime < out[j].StartTime
+	})
+	return out, nil
+}
+
+// occurrence is the series as held on date d.
+func occurrence(series *Event, d time.Time) *Event {
+	o := *series
+	o.Date = d
+	o.SeriesID = series.ID
+	o.OccurrenceDate = d.Format(dateLayout)
+	return &o
+}
+
+// setRecurrence validates and normalises a series' rule and exception
+// dates. An empty rule makes e a one-off event.
+func setRecurrence(e *Event, rrule string, exDates []string) error {
+	if strings.TrimSpace(rrule) == "" {
+		e.Recurrence, e.ExDates = "", nil
+		return nil
+	}
+	rule, err := ParseRule(rrule)
+	if err != nil {
+		return err
+	}
+	if e.Date.IsZero() {
+		return errSeriesDate
+	}
+	dates := make([]string, 0, len(exDates))
+	seen := make(map[string]bool)
+	for _, d := range exDates {
+		t, err := time.Parse(dateLayout, strings.TrimSpace(d))
+		if err != nil {
+			return errExDate
+		}
+		if key := t.Format(dateLayout); !seen[key] {
+			seen[key] = true
+			dates = append(dates, key)
+		}
+	}
+	sort.Strings(dates)
+	if len(dates) == 0 {
+		dates = nil
+	}
+	e.Recurrence, e.ExDates = rule.String(), dates
+	return nil
+}
+
+// isOccurrence reports whether the series is held on date, exceptions
+// aside.
+func isOccurrence(series *Event, date time.Time) bool {
+	rule, err := ParseRule(series.Recurrence)
+	if err != nil {
+		return false
+	}
+	key := date.Format(dateLayout)
+	for _, d := range series.ExDates {
+		if d == key {
+			return false
+		}
+	}
+	return rule.Occurs(series.Date, date)
+}
+
+// seriesOverrides returns the occurrences of a series edited on their own.
+func seriesOverrides(ctx context.Context, seriesID string) ([]*Event, error) {
+	all, err := listStored(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []*Event
+	for _, e := range all {
+		if e.SeriesID == seriesID {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func findOverride(ctx context.Context, seriesID, date string) (*Event, error) {
+	overrides, err := seriesOverrides(ctx, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range overrides {
+		if o.OccurrenceDate == date {
+			return o, nil
+		}
+	}
+	return nil, nil
+}
+
+// excludeDate adds date to the series' exceptions. A series that no longer
+// exists is ignored. Callers hold seriesMu.
+func excludeDate(ctx context.Context, seriesID, date string) error {
+	series, ok, err := Get(ctx, seriesID)
+	if err != nil || !ok {
+		return err
+	}
+	for _, d := range series.ExDates {
+		if d == date {
+			return nil
+		}
+	}
+	series.ExDates = append(append([]string(nil), series.ExDates...), date)
+	sort.Strings(series.ExDates)
+	series.UpdatedAt = time.Now()
+	return putEvent(ctx, series)
+}
+
+// UpdateOccurrence edits a single occurrence of a series, leaving the rest
+// of the series as it is. The first edit stores the occurrence as an event
+// of its own; later edits change that event.
+func UpdateOccurrence(ctx context.Context, seriesID string, date time.Time, req UpdateEventRequest) (*Event, error) {
+	if req.Recurrence != nil || req.ExDates != nil {
+		return nil, errSeriesOnly
+	}
+	seriesMu.Lock()
+	defer seriesMu.Unlock()
+
+	if req.RequiredRiders != nil && *req.RequiredRiders < 0 {
+		return nil, errRequiredRiders
+	}
+	o, err := editableOccurrence(ctx, seriesID, date)
+	if err != nil {
+		return nil, err
+	}
+	applyUpdate(o, req)
+	promoteWaitlist(o)
+	o.UpdatedAt = time.Now()
+	if err := putEvent(ctx, o); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// editableOccurrence returns the occurrence of the series on date as an
+// event of its own: the stored edit if there is one, otherwise a new,
+// unsaved copy of the generated occurrence. Callers hold seriesMu.
+func editableOccurrence(ctx context.Context, seriesID string, date time.Time) (*Event, error) {
+	series, ok, err := Get(ctx, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	if series.Recurrence == "" {
+		return nil, ErrNotRecurring
+	}
+
+	date = dateOnly(date)
+	o, err := findOverride(ctx, seriesID, date.Format(dateLayout))
+	if err != nil || o != nil {
+		return o, err
+	}
+	if !isOccurrence(series, date) {
+		return nil, ErrNoOccurrence
+	}
+	o = occurrence(series, date)
+	o.ID = newID()
+	o.Recurrence, o.ExDates = "", nil
+	o.AssignedRiders = append([]string(nil), series.AssignedRiders...)
+	o.Waitlist = append([]string(nil), series.Waitlist...)
+	o.CreatedAt = time.Now()
+	return o, nil
+}
+
+// DeleteOccurrence cancels a single occurrence of a series, along with
+// any edits made to it.
+func DeleteOccurrence(ctx context.Context, seriesID string, date time.Time) error {
+	seriesMu.Lock()
+	defer seriesMu.Unlock()
+
+	series, ok, err := Get(ctx, seriesID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("not found")
+	}
+	if series.Recurrence == "" {
+		return ErrNotRecurring
+	}
+
+	date = dateOnly(date)
+	key := date.Format(dateLayout)
+	o, err := findOverride(ctx, seriesID, key)
+	if err != nil {
+		return err
+	}
+	if o == nil && !isOccurrence(series, date) {
+		return ErrNoOccurrence
+	}
+	if err := excludeDate(ctx, seriesID, key); err != nil {
+		return err
+	}
+	if o != nil {
+		if _, err := deleteStored(ctx, o.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finalEndTime is when the event is over for good: its end time, or for a
+// series the end of its last occurrence. Series without an end date have
+// none.
+func finalEndTime(e *Event) (time.Time, error) {
+	if e.Recurrence == "" {
+		return eventEndTime(e)
+	}
+	rule, err := ParseRule(e.Recurrence)
+	if err != nil {
+		return time.Time{}, err
+	}
+	last, ok := rule.Last(e.Date)
+	if !ok {
+		return time.Time{}, errors.New("series has no end")
+	}
+	return eventEndTime(occurrence(e, last))
+}
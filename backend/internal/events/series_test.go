@@ -0,0 +1,340 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo/memory"
+)
+
+func setupEvents(t *testing.T) {
+	t.Helper()
+	SetGlobalEventsRepository(memory.NewEventsRepo())
+	t.Cleanup(func() { SetGlobalEventsRepository(nil) })
+}
+
+func newSeries(t *testing.T, start, rule string, exDates ...string) *Event {
+	t.Helper()
+	e, err := Create(context.Background(), CreateEventRequest{
+		Title: "On-call", Location: "Galway", Date: mustDate(t, start),
+		StartTime: "18:00", EndTime: "22:00", Recurrence: rule, ExDates: exDates,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func listDates(t *testing.T, from, to string) string {
+	t.Helper()
+	items, err := List(context.Background(), ListQuery{From: mustDate(t, from), To: mustDate(t, to)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]time.Time, len(items))
+	for i, e := range items {
+		out[i] = e.Date
+	}
+	return dates(out)
+}
+
+func strPtr(s string) *string { return &s }
+
+// ---- listing ----
+
+func TestList_ExpandsSeries(t *testing.T) {
+	setupEvents(t)
+	ctx := context.Background()
+	s := newSeries(t, "2026-04-06", "rrule:FREQ=WEEKLY;BYDAY=MO", "2026-04-13")
+	if s.Recurrence != "FREQ=WEEKLY;BYDAY=MO" {
+		t.Errorf("expected the rule normalised, got %q", s.Recurrence)
+	}
+	_, _ = Create(ctx, CreateEventRequest{Title: "Drive", Location: "Athlone", Date: mustDate(t, "2026-04-15"), StartTime: "09:00", EndTime: "10:00"})
+	_, _ = Create(ctx, CreateEventRequest{Title: "Later", Location: "Athlone", Date: mustDate(t, "2026-06-01"), StartTime: "09:00", EndTime: "10:00"})
+
+	if got, want := listDates(t, "2026-04-01", "2026-04-28"), "2026-04-06 2026-04-15 2026-04-20 2026-04-27"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	items, _ := List(ctx, ListQuery{From: mustDate(t, "2026-04-20"), To: mustDate(t, "2026-04-21")})
+	if len(items) != 1 || items[0].ID != s.ID || items[0].SeriesID != s.ID || items[0].OccurrenceDate != "2026-04-20" {
+		t.Errorf("expected the 20th as an occurrence of the series, got %+v", items)
+	}
+}
+
+func TestCreate_RejectsInvalidRecurrence(t *testing.T) {
+	setupEvents(t)
+	ctx := context.Background()
+	req := CreateEventRequest{Title: "x", Location: "y", StartTime: "09:00", EndTime: "10:00", Recurrence: "FREQ=HOURLY"}
+	if _, err := Create(ctx, req); !isValidationError(err) {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+	req.Recurrence = "FREQ=DAILY"
+	if _, err := Create(ctx, req); !errors.Is(err, errSeriesDate) {
+		t.Errorf("expected a series without a date to be refused, got %v", err)
+	}
+	req.Date = mustDate(t, "2026-04-01")
+	req.ExDates = []string{"April 2nd"}
+	if _, err := Create(ctx, req); !errors.Is(err, errExDate) {
+		t.Errorf("expected a bad exception date to be refused, got %v", err)
+	}
+}
+
+// ---- this occurrence vs. the series ----
+
+func TestUpdateOccurrence(t *testing.T) {
+	setupEvents(t)
+	ctx := context.Background()
+	s := newSeries(t, "2026-04-07", "FREQ=WEEKLY;COUNT=4")
+
+	// Move the 14th to the 15th and give it a different location.
+	moved := mustDate(t, "2026-04-15")
+	o, err := UpdateOccurrence(ctx, s.ID, mustDate(t, "2026-04-14"), UpdateEventRequest{Date: &moved, Location: strPtr("Athlone")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.ID == s.ID || o.SeriesID != s.ID || o.OccurrenceDate != "2026-04-14" || o.Recurrence != "" {
+		t.Errorf("unexpected override %+v", o)
+	}
+	again, err := UpdateOccurrence(ctx, s.ID, mustDate(t, "2026-04-14"), UpdateEventRequest{StartTime: strPtr("19:00")})
+	if err != nil || again.ID != o.ID || again.Location != "Athlone" || again.StartTime != "19:00" {
+		t.Errorf("expected the same override edited again, got %+v %v", again, err)
+	}
+
+	// Editing the series changes the other occurrences but not the override.
+	if _, err := Update(ctx, s.ID, UpdateEventRequest{Title: strPtr("Night rota")}); err != nil {
+		t.Fatal(err)
+	}
+	items, _ := List(ctx, ListQuery{From: mustDate(t, "2026-04-01"), To: mustDate(t, "2026-05-01")})
+	if got := len(items); got != 4 {
+		t.Fatalf("expected 4 occurrences, got %d", got)
+	}
+	if items[1].ID != o.ID || items[1].Title != "On-call" || items[1].Date.Format(dateLayout) != "2026-04-15" {
+		t.Errorf("expected the moved override second, got %+v", items[1])
+	}
+	if items[2].Title != "Night rota" {
+		t.Errorf("expected the series edit on later occurrences, got %+v", items[2])
+	}
+
+	if _, err := UpdateOccurrence(ctx, s.ID, mustDate(t, "2026-04-08"), UpdateEventRequest{}); !errors.Is(err, ErrNoOccurrence) {
+		t.Errorf("expected a date off the series to be refused, got %v", err)
+	}
+	if _, err := UpdateOccurrence(ctx, s.ID, mustDate(t, "2026-04-21"), UpdateEventRequest{Recurrence: strPtr("FREQ=DAILY")}); !errors.Is(err, errSeriesOnly) {
+		t.Errorf("expected a rule change on one occurrence to be refused, got %v", err)
+	}
+	if _, err := Update(ctx, o.ID, UpdateEventRequest{Recurrence: strPtr("FREQ=DAILY")}); !errors.Is(err, errSeriesOnly) {
+		t.Errorf("expected a rule change on an override to be refused, got %v", err)
+	}
+}
+
+func TestDeleteOccurrence(t *testing.T) {
+	setupEvents(t)
+	ctx := context.Background()
+	s := newSeries(t, "2026-04-07", "FREQ=WEEKLY;COUNT=4")
+	o, _ := UpdateOccurrence(ctx, s.ID, mustDate(t, "2026-04-14"), UpdateEventRequest{Location: strPtr("Athlone")})
+
+	if err := DeleteOccurrence(ctx, s.ID, mustDate(t, "2026-04-21")); err != nil {
+		t.Fatal(err)
+	}
+	// Deleting the override cancels its date rather than restoring it.
+	if ok, err := Delete(ctx, o.ID); !ok || err != nil {
+		t.Fatalf("expected override deleted, got %v %v", ok, err)
+	}
+	if got, want := listDates(t, "2026-04-01", "2026-05-01"), "2026-04-07 2026-04-28"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	got, _, _ := Get(ctx, s.ID)
+	if len(got.ExDates) != 2 || got.ExDates[0] != "2026-04-14" {
+		t.Errorf("expected both dates excluded, got %v", got.ExDates)
+	}
+	if err := DeleteOccurrence(ctx, s.ID, mustDate(t, "2026-04-21")); !errors.Is(err, ErrNoOccurrence) {
+		t.Errorf("expected a cancelled date to be gone, got %v", err)
+	}
+
+	// Deleting the series takes its overrides with it.
+	o, _ = UpdateOccurrence(ctx, s.ID, mustDate(t, "2026-04-28"), UpdateEventRequest{Location: strPtr("Sligo")})
+	if ok, _ := Delete(ctx, s.ID); !ok {
+		t.Fatal("expected series deleted")
+	}
+	if _, ok, _ := Get(ctx, o.ID); ok {
+		t.Error("expected the override deleted with its series")
+	}
+}
+
+// ---- archive ----
+
+func TestArchiveExpired_KeepsRunningSeries(t *testing.T) {
+	setupEvents(t)
+	ctx := context.Background()
+	now := time.Now()
+	today := dateOnly(now)
+	open := newSeries(t, "2020-01-06", "FREQ=WEEKLY")
+	running := newSeries(t, "2020-01-06", "FREQ=DAILY;UNTIL="+today.AddDate(0, 0, 3).Format("20060102"))
+	ended := newSeries(t, "2020-01-06", "FREQ=DAILY;COUNT=5")
+	old, _ := Create(ctx, CreateEventRequest{Title: "x", Location: "y", Date: mustDate(t, "2020-01-06"), StartTime: "09:00", EndTime: "10:00"})
+	cancelled, _ := Create(ctx, CreateEventRequest{Title: "z", Location: "y", Date: mustDate(t, "2020-01-07"), StartTime: "09:00", EndTime: "10:00"})
+	status := EventStatusCancelled
+	_, _ = Update(ctx, cancelled.ID, UpdateEventRequest{Status: &status})
+
+	if n, err := ArchiveExpired(ctx, now); err != nil || n != 3 {
+		t.Fatalf("expected 3 events archived, got %d %v", n, err)
+	}
+	for _, e := range []*Event{open, running} {
+		if got, _, _ := Get(ctx, e.ID); got.ArchivedAt != nil {
+			t.Errorf("expected series %s left alone", e.Recurrence)
+		}
+	}
+	for _, e := range []*Event{ended, old} {
+		got, ok, _ := Get(ctx, e.ID)
+		if !ok || got.ArchivedAt == nil || got.Status != EventStatusCompleted {
+			t.Errorf("expected %s archived as completed, got %+v", e.ID, got)
+		}
+	}
+	if got, _, _ := Get(ctx, cancelled.ID); got.Status != EventStatusCancelled {
+		t.Errorf("expected a cancelled event to stay cancelled, got %s", got.Status)
+	}
+	if n, _ := ArchiveExpired(ctx, now); n != 0 {
+		t.Errorf("expected nothing left to archive, got %d", n)
+	}
+}
+
+func TestPurgeArchived_Retention(t *testing.T) {
+	setupEvents(t)
+	ctx := context.Background()
+	now := time.Now()
+	old, _ := Create(ctx, CreateEventRequest{Title: "x", Location: "y", Date: mustDate(t, "2020-01-06"), StartTime: "09:00", EndTime: "10:00"})
+	_, _ = ArchiveExpired(ctx, now)
+
+	t.Setenv("EVENT_RETENTION_DAYS", "30")
+	if n, _ := PurgeArchived(ctx, now.AddDate(0, 0, 29)); n != 0 {
+		t.Errorf("expected the event kept within retention, got %d purged", n)
+	}
+	t.Setenv("EVENT_RETENTION_DAYS", "0")
+	if n, _ := PurgeArchived(ctx, now.AddDate(10, 0, 0)); n != 0 {
+		t.Errorf("expected retention 0 to keep events forever, got %d purged", n)
+	}
+	t.Setenv("EVENT_RETENTION_DAYS", "30")
+	if n, _ := PurgeArchived(ctx, now.AddDate(0, 0, 31)); n != 1 {
+		t.Errorf("expected the event purged after retention, got %d", n)
+	}
+	if _, ok, _ := Get(ctx, old.ID); ok {
+		t.Error("expected the purged event gone")
+	}
+}
+
+func TestList_IncludePast(t *testing.T) {
+	setupEvents(t)
+	ctx := context.Background()
+	past, _ := Create(ctx, CreateEventRequest{Title: "Past", Location: "y", Date: mustDate(t, "2020-01-06"), StartTime: "09:00", EndTime: "10:00"})
+	_, _ = ArchiveExpired(ctx, time.Now())
+
+	q := ListQuery{From: mustDate(t, "2020-01-01"), To: mustDate(t, "2020-01-31")}
+	if items, _ := List(ctx, q); len(items) != 0 {
+		t.Errorf("expected archived events hidden by default, got %+v", items)
+	}
+	q.IncludePast = true
+	items, _ := List(ctx, q)
+	if len(items) != 1 || items[0].ID != past.ID || items[0].ArchivedAt == nil {
+		t.Errorf("expected the archived event with include=past, got %+v", items)
+	}
+
+	rec := httptest.NewRecorder()
+	ListOrCreate(rec, httptest.NewRequest(http.MethodGet, "/api/events?include=past", nil))
+	var got []Event
+	_ = json.NewDecoder(rec.Body).Decode(&got)
+	if rec.Code != http.StatusOK || len(got) != 1 || got[0].Status != EventStatusCompleted {
+		t.Errorf("expected the archived event from the handler, got %d %+v", rec.Code, got)
+	}
+}
+
+func TestList_PastOccurrencesCompleted(t *testing.T) {
+	setupEvents(t)
+	newSeries(t, "2020-01-06", "FREQ=WEEKLY")
+	items, _ := List(context.Background(), ListQuery{From: mustDate(t, "2020-01-06"), To: mustDate(t, "2020-01-07")})
+	if len(items) != 1 || items[0].Status != EventStatusCompleted {
+		t.Errorf("expected a past occurrence shown as completed, got %+v", items)
+	}
+}
+
+// ---- time zone ----
+
+func TestOrgTimezone(t *testing.T) {
+	// Local midnight in Irish summer time arrives as 23:00Z the day before.
+	if got := dateOnly(mustTime(t, "2026-04-05T23:00:00Z")).Format(dateLayout); got != "2026-04-06" {
+		t.Errorf("expected the 6th, got %s", got)
+	}
+	start, err := atClock(mustDate(t, "2026-07-01"), "22:00")
+	if err != nil || !start.Equal(mustTime(t, "2026-07-01T21:00:00Z")) {
+		t.Errorf("expected 22:00 IST at 21:00Z, got %v %v", start, err)
+	}
+	start, _ = atClock(mustDate(t, "2026-12-01"), "22:00")
+	if !start.Equal(mustTime(t, "2026-12-01T22:00:00Z")) {
+		t.Errorf("expected 22:00 GMT at 22:00Z, got %v", start)
+	}
+}
+
+func TestDateOnly_IdempotentBehindUTC(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	// Normalising a date again mustn't move it back a day.
+	d := dateIn(mustTime(t, "2026-04-06T02:00:00Z"), ny)
+	if d.Format(dateLayout) != "2026-04-05" || !dateIn(d, ny).Equal(d) {
+		t.Errorf("expected the 5th both times, got %s then %s", d.Format(dateLayout), dateIn(d, ny).Format(dateLayout))
+	}
+}
+
+func mustTime(t *testing.T, s string) time.Time {
+	t.Helper()
+	v, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// ---- handlers ----
+
+func TestHandlers_Occurrences(t *testing.T) {
+	setupEvents(t)
+	s := newSeries(t, "2026-04-07", "FREQ=WEEKLY;COUNT=4")
+
+	rec := httptest.NewRecorder()
+	ListOrCreate(rec, httptest.NewRequest(http.MethodGet, "/api/events?from=2026-04-14&to=2026-04-21", nil))
+	var items []Event
+	_ = json.NewDecoder(rec.Body).Decode(&items)
+	if rec.Code != http.StatusOK || len(items) != 2 {
+		t.Fatalf("expected the 14th and 21st, got %d %+v", rec.Code, items)
+	}
+	rec = httptest.NewRecorder()
+	ListOrCreate(rec, httptest.NewRequest(http.MethodGet, "/api/events?from=2026-04-21&to=2026-04-14", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected a backwards range to 400, got %d", rec.Code)
+	}
+
+	body, _ := json.Marshal(UpdateEventRequest{Location: strPtr("Athlone")})
+	rec = httptest.NewRecorder()
+	GetUpdateOrDelete(rec, httptest.NewRequest(http.MethodPut, "/api/events/"+s.ID+"?occurrence=2026-04-14", bytes.NewReader(body)))
+	var o Event
+	_ = json.NewDecoder(rec.Body).Decode(&o)
+	if rec.Code != http.StatusOK || o.SeriesID != s.ID || o.Location != "Athlone" {
+		t.Errorf("expected an override, got %d %+v", rec.Code, o)
+	}
+
+	rec = httptest.NewRecorder()
+	GetUpdateOrDelete(rec, httptest.NewRequest(http.MethodDelete, "/api/events/"+s.ID+"?occurrence=2026-04-15", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected a date off the series to 404, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	GetUpdateOrDelete(rec, httptest.NewRequest(http.MethodDelete, "/api/events/"+s.ID+"?occurrence=2026-04-21", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("expected the occurrence cancelled, got %d", rec.Code)
+	}
+}
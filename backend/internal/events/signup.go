@@ -0,0 +1,254 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+)
+
+// Riders sign themselves up for events. RequiredRiders is the event's
+// capacity: once AssignedRiders reaches it, further riders join the
+// Waitlist, and the first of them is promoted when an assigned rider
+// withdraws or the capacity is raised. Each rider must hold every one of
+// the event's RequiredQualifications as a tag on their user record.
+//
+// Signing up to a recurring series assigns the rider to every occurrence;
+// with an occurrence date it assigns them to that date only, which stores
+// the date as an edited occurrence.
+
+var (
+	ErrAlreadySignedUp = errors.New("already signed up")
+	ErrNotSignedUp     = errors.New("not signed up")
+	ErrNotQualified    = errors.New("missing required qualifications")
+	ErrSignUpClosed    = errors.New("event is not open for sign-up")
+
+	errRequiredRiders = errors.New("requiredRiders must not be negative")
+)
+
+var usersRepo repo.UsersRepository
+
+// SetUsersRepository sets where riders' qualifications (their tags) are
+// read from. Without one, no rider holds any qualification.
+func SetUsersRepository(r repo.UsersRepository) {
+	usersRepo = r
+}
+
+// SignUpResult is the outcome of a sign-up. Position is the rider's place
+// on the waitlist, from 1, when they were waitlisted.
+type SignUpResult struct {
+	Event      *Event `json:"event"`
+	Waitlisted bool   `json:"waitlisted"`
+	Position   int    `json:"position,omitempty"`
+}
+
+// WithdrawResult is the outcome of a withdrawal, naming the waitlisted
+// rider promoted into the freed place, if any.
+type WithdrawResult struct {
+	Event    *Event `json:"event"`
+	Promoted string `json:"promoted,omitempty"`
+}
+
+// SignUp adds username to the event, or to the series' occurrence on date
+// when date is set, waitlisting them if the event is full.
+func SignUp(ctx context.Context, id string, date time.Time, username string) (*SignUpResult, error) {
+	seriesMu.Lock()
+	defer seriesMu.Unlock()
+
+	e, err := signUpTarget(ctx, id, date)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSignUpOpen(e, time.Now()); err != nil {
+		return nil, err
+	}
+	if contains(e.AssignedRiders, username) || contains(e.Waitlist, username) {
+		return nil, ErrAlreadySignedUp
+	}
+	if missing, err := missingQualifications(ctx, username, e.RequiredQualifications); err != nil {
+		return nil, err
+	} else if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNotQualified, strings.Join(missing, ", "))
+	}
+
+	res := &SignUpResult{Event: e}
+	if e.RequiredRiders > 0 && len(e.AssignedRiders) >= e.RequiredRiders {
+		e.Waitlist = append(append([]string(nil), e.Waitlist...), username)
+		res.Waitlisted, res.Position = true, len(e.Waitlist)
+	} else {
+		e.AssignedRiders = append(append([]string(nil), e.AssignedRiders...), username)
+	}
+	e.UpdatedAt = time.Now()
+	if err := putEvent(ctx, e); err != nil {
+		return nil, err
+	}
+	if res.Waitlisted {
+		log.Printf("[events] %s waitlisted for %s (position %d)", username, e.ID, res.Position)
+	} else {
+		log.Printf("[events] %s signed up for %s", username, e.ID)
+	}
+	return res, nil
+}
+
+// Withdraw takes username off the event (or the series' occurrence on
+// date), promoting the first waitlisted rider into a freed place.
+func Withdraw(ctx context.Context, id string, date time.Time, username string) (*WithdrawResult, error) {
+	seriesMu.Lock()
+	defer seriesMu.Unlock()
+
+	e, err := signUpTarget(ctx, id, date)
+	if err != nil {
+		return nil, err
+	}
+	assigned, waiting := remove(e.AssignedRiders, username), remove(e.Waitlist, username)
+	if len(assigned) == len(e.AssignedRiders) && len(waiting) == len(e.Waitlist) {
+		return nil, ErrNotSignedUp
+	}
+	e.AssignedRiders, e.Waitlist = assigned, waiting
+
+	res := &WithdrawResult{Event: e}
+	if promoted := promoteWaitlist(e); len(promoted) > 0 {
+		res.Promoted = promoted[0]
+	}
+	e.UpdatedAt = time.Now()
+	if err := putEvent(ctx, e); err != nil {
+		return nil, err
+	}
+	log.Printf("[events] %s withdrew from %s", username, e.ID)
+	return res, nil
+}
+
+// signUpTarget returns the stored event a sign-up on id (and date, for a
+// single occurrence of a series) changes. Callers hold seriesMu.
+func signUpTarget(ctx context.Context, id string, date time.Time) (*Event, error) {
+	if !date.IsZero() {
+		return editableOccurrence(ctx, id, date)
+	}
+	e, ok, err := Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return e, nil
+}
+
+// checkSignUpOpen refuses sign-ups to events that are cancelled, completed,
+// archived or have already started.
+func checkSignUpOpen(e *Event, now time.Time) error {
+	if e.ArchivedAt != nil || !isOpenStatus(e.Status) || e.Status == EventStatusInProgress {
+		return ErrSignUpClosed
+	}
+	if e.Recurrence == "" {
+		if start, _, err := eventTimes(e); err == nil && !now.Before(start) {
+			return ErrSignUpClosed
+		}
+	}
+	return nil
+}
+
+// promoteWaitlist moves waitlisted riders into free places, in order, and
+// returns who was promoted.
+func promoteWaitlist(e *Event) []string {
+	var promoted []string
+	for len(e.Waitlist) > 0 && (e.RequiredRiders == 0 || len(e.AssignedRiders) < e.RequiredRiders) {
+		next := e.Waitlist[0]
+		e.Waitlist = append([]string(nil), e.Waitlist[1:]...)
+		if contains(e.AssignedRiders, next) {
+			continue // assigned by hand while waiting
+		}
+		e.AssignedRiders = append(append([]string(nil), e.AssignedRiders...), next)
+		promoted = append(promoted, next)
+		log.Printf("[events] %s promoted from the waitlist for %s", next, e.ID)
+	}
+	if len(e.Waitlist) == 0 {
+		e.Waitlist = nil
+	}
+	return promoted
+}
+
+// missingQualifications returns the required qualifications the rider's
+// tags don't include, compared case-insensitively.
+func missingQualifications(ctx context.Context, username string, required []string) ([]string, error) {
+	if len(required) == 0 {
+		return nil, nil
+	}
+	var tags []string
+	if usersRepo != nil {
+		u, ok, err := usersRepo.Get(ctx, username)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load rider %s: %w", username, err)
+		}
+		if ok {
+			tags = u.Tags
+		}
+	}
+	var missing []string
+	for _, q := range required {
+		held := false
+		for _, t := range tags {
+			if strings.EqualFold(strings.TrimSpace(t), q) {
+				held = true
+				break
+			}
+		}
+		if !held {
+			missing = append(missing, q)
+		}
+	}
+	return missing, nil
+}
+
+// cleanQualifications trims the qualifications and drops blanks and
+// duplicates.
+func cleanQualifications(qs []string) []string {
+	var out []string
+	for _, q := range qs {
+		q = strings.TrimSpace(q)
+		if q == "" {
+			continue
+		}
+		dup := false
+		for _, o := range out {
+			if strings.EqualFold(o, q) {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			out = append(out, q)
+		}
+	}
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// remove returns list without s, or list itself if s isn't in it.
+func remove(list []string, s string) []string {
+	if !contains(list, s) {
+		return list
+	}
+	out := make([]string, 0, len(list)-1)
+	for _, v := range list {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
@@ -0,0 +1,194 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo/memory"
+)
+
+func setupRiders(t *testing.T, tags map[string][]string) {
+	t.Helper()
+	users := memory.NewUsersRepo()
+	for name, ts := range tags {
+		_ = users.Put(context.Background(), &repo.User{RiderID: name, Name: name, Tags: ts})
+	}
+	SetUsersRepository(users)
+	t.Cleanup(func() { SetUsersRepository(nil) })
+}
+
+func newStaffedEvent(t *testing.T, date time.Time, required int, quals ...string) *Event {
+	t.Helper()
+	e, err := Create(context.Background(), CreateEventRequest{
+		Title: "Marshal", Location: "Galway", Date: date, StartTime: "09:00", EndTime: "12:00",
+		RequiredRiders: required, RequiredQualifications: quals,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// ---- sign-up ----
+
+func TestSignUp_WaitlistAndPromotion(t *testing.T) {
+	setupEvents(t)
+	setupRiders(t, nil)
+	ctx := context.Background()
+	e := newStaffedEvent(t, dateOnly(time.Now()).AddDate(0, 0, 10), 2)
+
+	for _, r := range []string{"ann", "bob"} {
+		if res, err := SignUp(ctx, e.ID, time.Time{}, r); err != nil || res.Waitlisted {
+			t.Fatalf("expected %s assigned, got %+v %v", r, res, err)
+		}
+	}
+	res, err := SignUp(ctx, e.ID, time.Time{}, "cat")
+	if err != nil || !res.Waitlisted || res.Position != 1 {
+		t.Fatalf("expected cat waitlisted first, got %+v %v", res, err)
+	}
+	_, _ = SignUp(ctx, e.ID, time.Time{}, "dan")
+	if _, err := SignUp(ctx, e.ID, time.Time{}, "cat"); !errors.Is(err, ErrAlreadySignedUp) {
+		t.Errorf("expected a second sign-up refused, got %v", err)
+	}
+
+	out, err := Withdraw(ctx, e.ID, time.Time{}, "ann")
+	if err != nil || out.Promoted != "cat" {
+		t.Fatalf("expected cat promoted, got %+v %v", out, err)
+	}
+	got, _, _ := Get(ctx, e.ID)
+	if len(got.AssignedRiders) != 2 || got.AssignedRiders[1] != "cat" || len(got.Waitlist) != 1 || got.Waitlist[0] != "dan" {
+		t.Errorf("unexpected riders %v waitlist %v", got.AssignedRiders, got.Waitlist)
+	}
+	if _, err := Withdraw(ctx, e.ID, time.Time{}, "ann"); !errors.Is(err, ErrNotSignedUp) {
+		t.Errorf("expected withdrawing twice refused, got %v", err)
+	}
+
+	// Raising the capacity promotes from the waitlist too.
+	three := 3
+	got, _ = Update(ctx, e.ID, UpdateEventRequest{RequiredRiders: &three})
+	if len(got.AssignedRiders) != 3 || got.Waitlist != nil {
+		t.Errorf("expected dan promoted, got %v %v", got.AssignedRiders, got.Waitlist)
+	}
+}
+
+func TestSignUp_Qualifications(t *testing.T) {
+	setupEvents(t)
+	setupRiders(t, map[string][]string{"ann": {"Rider", "advanced"}, "bob": {"Rider"}})
+	ctx := context.Background()
+	e := newStaffedEvent(t, dateOnly(time.Now()).AddDate(0, 0, 10), 0, "Advanced", " advanced ", "")
+	if len(e.RequiredQualifications) != 1 || e.RequiredQualifications[0] != "Advanced" {
+		t.Errorf("expected qualifications cleaned, got %v", e.RequiredQualifications)
+	}
+
+	if _, err := SignUp(ctx, e.ID, time.Time{}, "ann"); err != nil {
+		t.Errorf("expected ann qualified, got %v", err)
+	}
+	if _, err := SignUp(ctx, e.ID, time.Time{}, "bob"); !errors.Is(err, ErrNotQualified) {
+		t.Errorf("expected bob refused, got %v", err)
+	}
+	if _, err := SignUp(ctx, e.ID, time.Time{}, "nobody"); !errors.Is(err, ErrNotQualified) {
+		t.Errorf("expected an unknown rider refused, got %v", err)
+	}
+}
+
+func TestSignUp_Closed(t *testing.T) {
+	setupEvents(t)
+	setupRiders(t, nil)
+	ctx := context.Background()
+	past := newStaffedEvent(t, dateOnly(time.Now()).AddDate(0, 0, -1), 2)
+	if _, err := SignUp(ctx, past.ID, time.Time{}, "ann"); !errors.Is(err, ErrSignUpClosed) {
+		t.Errorf("expected a past event closed, got %v", err)
+	}
+	e := newStaffedEvent(t, dateOnly(time.Now()).AddDate(0, 0, 10), 2)
+	status := EventStatusCancelled
+	_, _ = Update(ctx, e.ID, UpdateEventRequest{Status: &status})
+	if _, err := SignUp(ctx, e.ID, time.Time{}, "ann"); !errors.Is(err, ErrSignUpClosed) {
+		t.Errorf("expected a cancelled event closed, got %v", err)
+	}
+	if _, err := SignUp(ctx, "evt_missing", time.Time{}, "ann"); err == nil || err.Error() != "not found" {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func TestSignUp_Occurrence(t *testing.T) {
+	setupEvents(t)
+	setupRiders(t, nil)
+	ctx := context.Background()
+	start := dateOnly(time.Now()).AddDate(0, 0, 7)
+	s, _ := Create(ctx, CreateEventRequest{
+		Title: "Rota", Location: "Galway", Date: start, StartTime: "18:00", EndTime: "22:00",
+		Recurrence: "FREQ=WEEKLY;COUNT=3", RequiredRiders: 1, AssignedRiders: []string{"ann"},
+	})
+
+	second := start.AddDate(0, 0, 7)
+	res, err := SignUp(ctx, s.ID, second, "bob")
+	if err != nil || !res.Waitlisted || res.Event.SeriesID != s.ID || res.Event.ID == s.ID {
+		t.Fatalf("expected bob waitlisted on an edited occurrence, got %+v %v", res, err)
+	}
+	out, err := Withdraw(ctx, s.ID, second, "ann")
+	if err != nil || out.Promoted != "bob" || out.Event.ID != res.Event.ID {
+		t.Fatalf("expected ann off the date and bob promoted, got %+v %v", out, err)
+	}
+	if got, _, _ := Get(ctx, s.ID); len(got.AssignedRiders) != 1 || got.AssignedRiders[0] != "ann" || got.Waitlist != nil {
+		t.Errorf("expected the series unchanged, got %+v", got)
+	}
+	if _, err := SignUp(ctx, s.ID, start.AddDate(0, 0, 1), "bob"); !errors.Is(err, ErrNoOccurrence) {
+		t.Errorf("expected a date off the series refused, got %v", err)
+	}
+}
+
+func TestCreate_RejectsNegativeRequiredRiders(t *testing.T) {
+	setupEvents(t)
+	_, err := Create(context.Background(), CreateEventRequest{Title: "x", Location: "y", StartTime: "09:00", EndTime: "10:00", RequiredRiders: -1})
+	if !isValidationError(err) {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+}
+
+func TestHandleSignUp_RequiresUser(t *testing.T) {
+	setupEvents(t)
+	e := newStaffedEvent(t, dateOnly(time.Now()).AddDate(0, 0, 10), 2)
+	rec := httptest.NewRecorder()
+	GetUpdateOrDelete(rec, httptest.NewRequest(http.MethodPost, "/api/events/"+e.ID+"/signup", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a user, got %d", rec.Code)
+	}
+}
+
+// ---- staffing alerts ----
+
+func TestSendStaffingAlerts(t *testing.T) {
+	setupEvents(t)
+	ctx := context.Background()
+	now := time.Now()
+	tomorrow := dateOnly(now).AddDate(0, 0, 1)
+	short := newStaffedEvent(t, tomorrow, 2)
+	_, _ = Update(ctx, short.ID, UpdateEventRequest{AssignedRiders: &[]string{"ann"}})
+	full := newStaffedEvent(t, tomorrow, 1)
+	_, _ = Update(ctx, full.ID, UpdateEventRequest{AssignedRiders: &[]string{"ann"}})
+	newStaffedEvent(t, tomorrow.AddDate(0, 0, 5), 2) // outside the window
+	newStaffedEvent(t, tomorrow, 0)                  // no requirement
+	s, _ := Create(ctx, CreateEventRequest{
+		Title: "Rota", Location: "Galway", Date: tomorrow.AddDate(0, 0, -7), StartTime: "18:00", EndTime: "22:00",
+		Recurrence: "FREQ=WEEKLY", RequiredRiders: 1,
+	})
+
+	var sent []string
+	notify := func(title, body, url string) { sent = append(sent, body) }
+	sendStaffingAlerts(ctx, notify, now)
+	if len(sent) != 2 {
+		t.Fatalf("expected alerts for the short event and the series, got %v", sent)
+	}
+	if got, _, _ := Get(ctx, s.ID); len(got.StaffingAlerts) != 1 || got.StaffingAlerts[0] != tomorrow.Format(dateLayout) {
+		t.Errorf("expected the occurrence recorded on the series, got %v", got.StaffingAlerts)
+	}
+	sendStaffingAlerts(ctx, notify, now.Add(time.Hour))
+	if len(sent) != 2 {
+		t.Errorf("expected each alert sent once, got %v", sent)
+	}
+}
@@ -0,0 +1,109 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// staffingAlertWindow is how long before an under-staffed event starts
+// that coordinators are warned about it.
+const staffingAlertWindow = 48 * time.Hour
+
+// Notifier delivers a notification to event coordinators.
+type Notifier func(title, body, url string)
+
+// StartStaffingAlerts checks every 15 minutes for events starting within
+// staffingAlertWindow that have fewer riders than RequiredRiders, and
+// notifies coordinators once per event (or per occurrence of a series).
+// Call once at server startup.
+func StartStaffingAlerts(ctx context.Context, notify Notifier) {
+	go func() {
+		ticker := time.NewTicker(15 * time.Minute)
+		defer ticker.Stop()
+		sendStaffingAlerts(ctx, notify, time.Now())
+		for {
+			select {
+			case <-ticker.C:
+				sendStaffingAlerts(ctx, notify, time.Now())
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	log.Printf("[events] staffing alerts started (%s before events start)", staffingAlertWindow)
+}
+
+func sendStaffingAlerts(ctx context.Context, notify Notifier, now time.Time) {
+	items, err := List(ctx, ListQuery{From: dateOnly(now), To: dateOnly(now.Add(staffingAlertWindow)).AddDate(0, 0, 1)})
+	if err != nil {
+		log.Printf("[events] staffing alerts: %v", err)
+		return
+	}
+	for _, e := range items {
+		if e.RequiredRiders == 0 || len(e.AssignedRiders) >= e.RequiredRiders ||
+			!isOpenStatus(e.Status) || e.Status == EventStatusInProgress {
+			continue
+		}
+		start, _, err := eventTimes(e)
+		if err != nil || !start.After(now) || start.Sub(now) > staffingAlertWindow {
+			continue
+		}
+		key := staffingKey(e)
+		if contains(e.StaffingAlerts, key) {
+			continue
+		}
+		ok, err := recordStaffingAlert(ctx, e.ID, key, now)
+		if err != nil {
+			log.Printf("[events] staffing alerts: failed to record alert for %s: %v", e.ID, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		title, body := staffingAlertText(e, start)
+		log.Printf("[events] staffing alert: %s", body)
+		if notify != nil {
+			notify(title, body, "/events")
+		}
+	}
+}
+
+// staffingKey names the occurrence an alert is for: its date in the series,
+// or the event's own date.
+func staffingKey(e *Event) string {
+	if e.OccurrenceDate != "" {
+		return e.OccurrenceDate
+	}
+	return dateOnly(e.Date).Format(dateLayout)
+}
+
+// recordStaffingAlert marks the alert for key as sent on the stored event
+// (the series, for a generated occurrence), dropping marks for dates that
+// have passed. It reports false if the alert had already been recorded.
+func recordStaffingAlert(ctx context.Context, id, key string, now time.Time) (bool, error) {
+	seriesMu.Lock()
+	defer seriesMu.Unlock()
+	e, ok, err := Get(ctx, id)
+	if err != nil || !ok || contains(e.StaffingAlerts, key) {
+		return false, err
+	}
+	yesterday := dateOnly(now).AddDate(0, 0, -1).Format(dateLayout)
+	sent := []string{key}
+	for _, d := range e.StaffingAlerts {
+		if d >= yesterday {
+			sent = append(sent, d)
+		}
+	}
+	sort.Strings(sent)
+	e.StaffingAlerts = sent
+	return true, putEvent(ctx, e)
+}
+
+func staffingAlertText(e *Event, start time.Time) (string, string) {
+	body := fmt.Sprintf("%s on %s at %s has %d of %d riders", e.Title,
+		start.In(orgLocation()).Format("Mon 2 Jan"), e.StartTime, len(e.AssignedRiders), e.RequiredRiders)
+	return "⚠️ Event Under-staffed", body
+}
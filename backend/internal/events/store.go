@@ -6,7 +6,6 @@ import (
 	"encoding/hex"
 	"errors"
 	"log"
-	"strings"
 	"time"
 
 	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
@@ -25,7 +24,76 @@ func SetGlobalEventsRepository(r repo.EventsRepository) {
 	globalEventsRepo = r
 }
 
-func List(ctx context.Context) ([]*Event, error) {
+func fromRepoEvent(item repo.Event) *Event {
+	e := &Event{
+		ID:             item.ID,
+		Title:          item.Title,
+		Description:    item.Description,
+		Date:           item.Date,
+		StartTime:      item.StartTime,
+		EndTime:        item.EndTime,
+		Location:       item.Location,
+		Lat:            item.Lat,
+		Lng:            item.Lng,
+		Type:           EventType(item.Type),
+		Priority:       EventPriority(item.Priority),
+		AssignedRiders: item.AssignedRiders,
+		Status:         EventStatus(item.Status),
+		CreatedAt:      item.CreatedAt,
+		UpdatedAt:      item.UpdatedAt,
+		Recurrence:     item.Recurrence,
+		ExDates:        item.ExDates,
+		SeriesID:       item.SeriesID,
+		OccurrenceDate: item.OccurrenceDate,
+
+		RequiredRiders:         item.RequiredRiders,
+		RequiredQualifications: item.RequiredQualifications,
+		Waitlist:               item.Waitlist,
+		StaffingAlerts:         item.StaffingAlerts,
+	}
+	if !item.ArchivedAt.IsZero() {
+		at := item.ArchivedAt
+		e.ArchivedAt = &at
+	}
+	return e
+}
+
+func toRepoEvent(e *Event) *repo.Event {
+	item := &repo.Event{
+		ID:             e.ID,
+		Title:          e.Title,
+		Description:    e.Description,
+		Date:           e.Date,
+		StartTime:      e.StartTime,
+		EndTime:        e.EndTime,
+		Location:       e.Location,
+		Lat:            e.Lat,
+		Lng:            e.Lng,
+		Type:           string(e.Type),
+		Priority:       string(e.Priority),
+		AssignedRiders: e.AssignedRiders,
+		Status:         string(e.Status),
+		CreatedAt:      e.CreatedAt,
+		UpdatedAt:      e.UpdatedAt,
+		Recurrence:     e.Recurrence,
+		ExDates:        e.ExDates,
+		SeriesID:       e.SeriesID,
+		OccurrenceDate: e.OccurrenceDate,
+
+		RequiredRiders:         e.RequiredRiders,
+		RequiredQualifications: e.RequiredQualifications,
+		Waitlist:               e.Waitlist,
+		StaffingAlerts:         e.StaffingAlerts,
+	}
+	if e.ArchivedAt != nil {
+		item.ArchivedAt = *e.ArchivedAt
+	}
+	return item
+}
+
+// listStored returns the events as stored: each recurring series once,
+// plus any occurrences edited on their own.
+func listStored(ctx context.Context) ([]*Event, error) {
 	if globalEventsRepo != nil {
 		items, err := globalEventsRepo.List(ctx)
 		if err != nil {
@@ -33,24 +101,7 @@ func List(ctx context.Context) ([]*Event, error) {
 		}
 		events := make([]*Event, 0, len(items))
 		for _, item := range items {
-			e := &Event{
-				ID:             item.ID,
-				Title:          item.Title,
-				Description:    item.Description,
-				Date:           item.Date,
-				StartTime:      item.StartTime,
-				EndTime:        item.EndTime,
-				Location:       item.Location,
-				Lat:            item.Lat,
-				Lng:            item.Lng,
-				Type:           EventType(item.Type),
-				Priority:       EventPriority(item.Priority),
-				AssignedRiders: item.AssignedRiders,
-				Status:         EventStatus(item.Status),
-				CreatedAt:      item.CreatedAt,
-				UpdatedAt:      item.UpdatedAt,
-			}
-			events = append(events, e)
+			events = append(events, fromRepoEvent(item))
 		}
 		return events, nil
 	}
@@ -58,11 +109,35 @@ func List(ctx context.Context) ([]*Event, error) {
 	// Fallback: return in-memory events
 	out := make([]*Event, 0, len(fallbackEvents))
 	for _, e := range fallbackEvents {
-		out = append(out, e)
+		cp := *e
+		out = append(out, &cp)
 	}
 	return out, nil
 }
 
+func putEvent(ctx context.Context, e *Event) error {
+	if globalEventsRepo != nil {
+		return globalEventsRepo.Put(ctx, toRepoEvent(e))
+	}
+	// Fallback: store in-memory
+	cp := *e
+	fallbackEvents[e.ID] = &cp
+	return nil
+}
+
+func deleteStored(ctx context.Context, id string) (bool, error) {
+	if globalEventsRepo != nil {
+		return globalEventsRepo.Delete(ctx, id)
+	}
+
+	// Fallback: delete in-memory
+	if _, ok := fallbackEvents[id]; !ok {
+		return false, nil
+	}
+	delete(fallbackEvents, id)
+	return true, nil
+}
+
 func Get(ctx context.Context, id string) (*Event, bool, error) {
 	if globalEventsRepo != nil {
 		item, ok, err := globalEventsRepo.Get(ctx, id)
@@ -72,29 +147,16 @@ func Get(ctx context.Context, id string) (*Event, bool, error) {
 		if !ok {
 			return nil, false, nil
 		}
-		e := &Event{
-			ID:             item.ID,
-			Title:          item.Title,
-			Description:    item.Description,
-			Date:           item.Date,
-			StartTime:      item.StartTime,
-			EndTime:        item.EndTime,
-			Location:       item.Location,
-			Lat:            item.Lat,
-			Lng:            item.Lng,
-			Type:           EventType(item.Type),
-			Priority:       EventPriority(item.Priority),
-			AssignedRiders: item.AssignedRiders,
-			Status:         EventStatus(item.Status),
-			CreatedAt:      item.CreatedAt,
-			UpdatedAt:      item.UpdatedAt,
-		}
-		return e, true, nil
+		return fromRepoEvent(*item), true, nil
 	}
 
 	// Fallback: return in-memory event
 	e, ok := fallbackEvents[id]
-	return e, ok, nil
+	if !ok {
+		return nil, false, nil
+	}
+	cp := *e
+	return &cp, true, nil
 }
 
 func Create(ctx context.Context, req CreateEventRequest) (*Event, error) {
@@ -107,6 +169,9 @@ func Create(ctx context.Context, req CreateEventRequest) (*Event, error) {
 	if req.StartTime == "" || req.EndTime == "" {
 		return nil, errors.New("startTime and endTime required")
 	}
+	if req.RequiredRiders < 0 {
+		return nil, errRequiredRiders
+	}
 
 	id := newID()
 	now := time.Now()
@@ -127,38 +192,26 @@ func Create(ctx context.Context, req CreateEventRequest) (*Event, error) {
 		Status:         EventStatusScheduled,
 		CreatedAt:      now,
 		UpdatedAt:      now,
-	}
 
-	if globalEventsRepo != nil {
-		repoItem := &repo.Event{
-			ID:             e.ID,
-			Title:          e.Title,
-			Description:    e.Description,
-			Date:           e.Date,
-			StartTime:      e.StartTime,
-			EndTime:        e.EndTime,
-			Location:       e.Location,
-			Lat:            e.Lat,
-			Lng:            e.Lng,
-			Type:           string(e.Type),
-			Priority:       string(e.Priority),
-			AssignedRiders: e.AssignedRiders,
-			Status:         string(e.Status),
-			CreatedAt:      e.CreatedAt,
-			UpdatedAt:      e.UpdatedAt,
-		}
-		if err := globalEventsRepo.Put(ctx, repoItem); err != nil {
-			return nil, err
-		}
-	} else {
-		// Fallback: store in-memory
-		fallbackEvents[id] = e
+		RequiredRiders:         req.RequiredRiders,
+		RequiredQualifications: cleanQualifications(req.RequiredQualifications),
+	}
+	if err := setRecurrence(e, req.Recurrence, req.ExDates); err != nil {
+		return nil, err
 	}
 
+	if err := putEvent(ctx, e); err != nil {
+		return nil, err
+	}
 	return e, nil
 }
 
+// Update edits an event. Given a recurring series it edits the whole
+// series; use UpdateOccurrence to edit a single date.
 func Update(ctx context.Context, id string, req UpdateEventRequest) (*Event, error) {
+	seriesMu.Lock()
+	defer seriesMu.Unlock()
+
 	e, ok, err := Get(ctx, id)
 	if err != nil {
 		return nil, err
@@ -167,6 +220,39 @@ func Update(ctx context.Context, id string, req UpdateEventRequest) (*Event, err
 		return nil, errors.New("not found")
 	}
 
+	if req.RequiredRiders != nil && *req.RequiredRiders < 0 {
+		return nil, errRequiredRiders
+	}
+	applyUpdate(e, req)
+	if req.Recurrence != nil || req.ExDates != nil {
+		if e.SeriesID != "" {
+			return nil, errSeriesOnly
+		}
+		rule, exDates := e.Recurrence, e.ExDates
+		if req.Recurrence != nil {
+			rule = *req.Recurrence
+		}
+		if req.ExDates != nil {
+			exDates = *req.ExDates
+		}
+		if err := setRecurrence(e, rule, exDates); err != nil {
+			return nil, err
+		}
+	} else if e.Recurrence != "" && e.Date.IsZero() {
+		return nil, errSeriesDate
+	}
+
+	promoteWaitlist(e)
+	e.UpdatedAt = time.Now()
+	if err := putEvent(ctx, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// applyUpdate copies the fields set in req onto e, apart from the
+// recurrence, which only a series may change.
+func applyUpdate(e *Event, req UpdateEventRequest) {
 	if req.Title != nil {
 		e.Title = *req.Title
 	}
@@ -203,106 +289,86 @@ func Update(ctx context.Context, id string, req UpdateEventRequest) (*Event, err
 	if req.Status != nil {
 		e.Status = *req.Status
 	}
-
-	e.UpdatedAt = time.Now()
-
-	if globalEventsRepo != nil {
-		repoItem := &repo.Event{
-			ID:             e.ID,
-			Title:          e.Title,
-			Description:    e.Description,
-			Date:           e.Date,
-			StartTime:      e.StartTime,
-			EndTime:        e.EndTime,
-			Location:       e.Location,
-			Lat:            e.Lat,
-			Lng:            e.Lng,
-			Type:           string(e.Type),
-			Priority:       string(e.Priority),
-			AssignedRiders: e.AssignedRiders,
-			Status:         string(e.Status),
-			CreatedAt:      e.CreatedAt,
-			UpdatedAt:      e.UpdatedAt,
-		}
-		if err := globalEventsRepo.Put(ctx, repoItem); err != nil {
-			return nil, err
-		}
-	} else {
-		// Fallback: update in-memory
-		fallbackEvents[id] = e
+	if req.RequiredRiders != nil {
+		e.RequiredRiders = *req.RequiredRiders
+	}
+	if req.RequiredQualifications != nil {
+		e.RequiredQualifications = cleanQualifications(*req.RequiredQualifications)
 	}
-
-	return e, nil
 }
 
+// Delete deletes an event. Deleting a series also deletes its edited
+// occurrences; deleting an edited occurrence cancels that date in its
+// series rather than bringing the original back.
 func Delete(ctx context.Context, id string) (bool, error) {
-	if globalEventsRepo != nil {
-		return globalEventsRepo.Delete(ctx, id)
-	}
+	seriesMu.Lock()
+	defer seriesMu.Unlock()
 
-	// Fallback: delete in-memory
-	if _, ok := fallbackEvents[id]; !ok {
-		return false, nil
+	e, ok, err := Get(ctx, id)
+	if err != nil || !ok {
+		return false, err
 	}
-	delete(fallbackEvents, id)
-	return true, nil
+	if e.Recurrence != "" {
+		overrides, err := seriesOverrides(ctx, e.ID)
+		if err != nil {
+			return false, err
+		}
+		for _, o := range overrides {
+			if _, err := deleteStored(ctx, o.ID); err != nil {
+				return false, err
+			}
+		}
+	}
+	if e.SeriesID != "" {
+		if err := excludeDate(ctx, e.SeriesID, e.OccurrenceDate); err != nil {
+			return false, err
+		}
+	}
+	return deleteStored(ctx, id)
 }
 
-// StartCleanupTicker runs PurgeExpired immediately and then on a 1-minute tick
-// for as long as ctx is alive. Call once at server startup.
+// StartCleanupTicker runs ArchiveExpired and PurgeArchived immediately and
+// then on a 1-minute tick for as long as ctx is alive. Call once at server
+// startup.
 func StartCleanupTicker(ctx context.Context) {
 	go func() {
 		ticker := time.NewTicker(1 * time.Minute)
 		defer ticker.Stop()
-		// Run once immediately so stale events from a previous run are cleared quickly.
-		PurgeExpired(ctx)
+		// Run once immediately so events that ended while the server was down are archived quickly.
+		cleanup(ctx, time.Now())
 		for {
 			select {
 			case <-ticker.C:
-				PurgeExpired(ctx)
+				cleanup(ctx, time.Now())
 			case <-ctx.Done():
 				return
 			}
 		}
 	}()
-	log.Println("[events] cleanup ticker started (runs every minute)")
+	log.Printf("[events] cleanup ticker started (runs every minute, archived events kept %s)", retentionDescription())
 }
 
-// eventEndTime combines an event's Date and EndTime ("HH:MM") into a single UTC time.
-func eventEndTime(e *Event) (time.Time, error) {
-	if e.EndTime == "" {
-		return time.Time{}, errors.New("no end time")
+func cleanup(ctx context.Context, now time.Time) {
+	if _, err := ArchiveExpired(ctx, now); err != nil {
+		log.Printf("[events] archive: %v", err)
 	}
-	// Normalize: accept "HH:MM" or "HH:MM:SS"
-	timePart := e.EndTime
-	if len(strings.Split(timePart, ":")) == 2 {
-		timePart += ":00"
+	if _, err := PurgeArchived(ctx, now); err != nil {
+		log.Printf("[events] purge: %v", err)
 	}
-	dateStr := e.Date.UTC().Format("2006-01-02")
-	return time.Parse("2006-01-02 15:04:05", dateStr+" "+timePart)
 }
 
-// PurgeExpired deletes all events whose end datetime is in the past.
-func PurgeExpired(ctx context.Context) {
-	all, err := List(ctx)
-	if err != nil {
-		log.Printf("[events] purge: failed to list events: %v", err)
-		return
+// eventEndTime is when the event ends: its Date at EndTime in the org
+// zone, or the next day for an end time at or before the start.
+func eventEndTime(e *Event) (time.Time, error) {
+	if e.EndTime == "" {
+		return time.Time{}, errors.New("no end time")
 	}
-	now := time.Now().UTC()
-	for _, e := range all {
-		end, err := eventEndTime(e)
-		if err != nil {
-			continue // skip events with unparseable end time
-		}
-		if now.After(end) {
-			if _, err := Delete(ctx, e.ID); err != nil {
-				log.Printf("[events] purge: failed to delete %s: %v", e.ID, err)
-			} else {
-				log.Printf("[events] purge: deleted expired event %q (ended %s)", e.Title, end.Format(time.RFC3339))
-			}
+	if e.StartTime != "" {
+		if _, end, err := eventTimes(e); err == nil {
+			return end, nil
 		}
 	}
+	return atClock(e.Date, e.EndTime)
 }
 
 func newID() string {
@@ -310,4 +376,3 @@ func newID() string {
 	_, _ = rand.Read(b)
 	return "evt_" + hex.EncodeToString(b)
 }
-
@@ -0,0 +1,46 @@
+package events
+
+import (
+	"strings"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/orgtime"
+)
+
+// An event's Date is a calendar date and its StartTime and EndTime are
+// wall-clock times, all in the organisation's time zone (see orgtime).
+// Dates are read in that zone too, so a date sent as local midnight
+// ("2026-04-05T23:00:00Z" in summer) is still the 6th.
+
+func orgLocation() *time.Location {
+	return orgtime.Location()
+}
+
+// dateOnly returns t's calendar date in the org zone, as midnight UTC.
+func dateOnly(t time.Time) time.Time {
+	return dateIn(t, orgLocation())
+}
+
+// dateIn returns t's calendar date in loc, as midnight UTC. A time that is
+// already midnight UTC is taken to be a date and returned as it is, so
+// dates pass through unchanged however often they are normalised, even in
+// zones behind UTC.
+func dateIn(t time.Time, loc *time.Location) time.Time {
+	if u := t.UTC(); u.Hour() == 0 && u.Minute() == 0 && u.Second() == 0 && u.Nanosecond() == 0 {
+		return u
+	}
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// atClock is the instant date's calendar date reaches the wall-clock time
+// clock ("HH:MM" or "HH:MM:SS") in the org zone.
+func atClock(date time.Time, clock string) (time.Time, error) {
+	// Normalize: accept "HH:MM" or "HH:MM:SS"
+	timePart := clock
+	if len(strings.Split(timePart, ":")) == 2 {
+		timePart += ":00"
+	}
+	dateStr := dateOnly(date).Format("2006-01-02")
+	return time.ParseInLocation("2006-01-02 15:04:05", dateStr+" "+timePart, orgLocation())
+}
@@ -0,0 +1,191 @@
+// Package fatigue computes rider duty time and rest periods from ride
+// sessions, job history and availability windows, and checks them against
+// configurable limits before a rider takes on another job.
+package fatigue
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultConfig returns the thresholds used when no env overrides are set.
+func DefaultConfig() Config {
+	return Config{
+		MaxContinuousDuty: 12 * time.Hour,
+		MinRest:           8 * time.Hour,
+		MaxDutyInWindow:   10 * time.Hour,
+		Window:            24 * time.Hour,
+		WarnMargin:        time.Hour,
+		ExpectedJobTime:   90 * time.Minute,
+		Enforcement:       EnforcementWarn,
+	}
+}
+
+// ConfigFromEnv reads FATIGUE_* env vars on top of DefaultConfig:
+//
+//	FATIGUE_MAX_CONTINUOUS_HOURS, FATIGUE_MIN_REST_HOURS,
+//	FATIGUE_MAX_DUTY_HOURS, FATIGUE_WINDOW_HOURS,
+//	FATIGUE_WARN_MINUTES, FATIGUE_EXPECTED_JOB_MINUTES,
+//	FATIGUE_ENFORCEMENT (warn|block), FATIGUE_COUNT_ON_CALL (true|false)
+func ConfigFromEnv() Config {
+	cfg := DefaultConfig()
+	cfg.MaxContinuousDuty = envDuration("FATIGUE_MAX_CONTINUOUS_HOURS", time.Hour, cfg.MaxContinuousDuty)
+	cfg.MinRest = envDuration("FATIGUE_MIN_REST_HOURS", time.Hour, cfg.MinRest)
+	cfg.MaxDutyInWindow = envDuration("FATIGUE_MAX_DUTY_HOURS", time.Hour, cfg.MaxDutyInWindow)
+	cfg.Window = envDuration("FATIGUE_WINDOW_HOURS", time.Hour, cfg.Window)
+	cfg.WarnMargin = envDuration("FATIGUE_WARN_MINUTES", time.Minute, cfg.WarnMargin)
+	cfg.ExpectedJobTime = envDuration("FATIGUE_EXPECTED_JOB_MINUTES", time.Minute, cfg.ExpectedJobTime)
+	if strings.EqualFold(strings.TrimSpace(os.Getenv("FATIGUE_ENFORCEMENT")), string(EnforcementBlock)) {
+		cfg.Enforcement = EnforcementBlock
+	}
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("FATIGUE_COUNT_ON_CALL"))) {
+	case "1", "true", "yes":
+		cfg.CountOnCall = true
+	}
+	return cfg
+}
+
+func envDuration(key string, unit time.Duration, def time.Duration) time.Duration {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return def
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil || v <= 0 {
+		return def
+	}
+	return time.Duration(v * float64(unit))
+}
+
+// Evaluate computes a rider's fatigue status at now from their duty intervals.
+//
+// Gaps shorter than cfg.MinRest do not break a duty block, so a rider doing
+// back-to-back night runs accumulates continuous duty across short breaks.
+// Duty in the rolling window only counts time actually spent on duty.
+func Evaluate(riderID string, intervals []Interval, now time.Time, cfg Config) Status {
+	st := Status{
+		RiderID:     riderID,
+		Level:       LevelOK,
+		WindowHours: cfg.Window.Hours(),
+	}
+
+	spans := normalise(intervals, now)
+	if len(spans) == 0 {
+		return st
+	}
+
+	active := merge(spans, 0)
+	blocks := merge(spans, cfg.MinRest)
+
+	windowStart := now.Add(-cfg.Window)
+	var inWindow time.Duration
+	for _, s := range active {
+		start := s.Start
+		if start.Before(windowStart) {
+			start = windowStart
+		}
+		if s.End.After(start) {
+			inWindow += s.End.Sub(start)
+		}
+	}
+	st.DutyInWindowMinutes = int(inWindow.Minutes())
+
+	last := blocks[len(blocks)-1]
+	st.OnDuty = !last.End.Before(now)
+	if !st.OnDuty {
+		end := last.End
+		st.LastDutyEnd = &end
+		st.RestMinutes = int(now.Sub(last.End).Minutes())
+	}
+
+	var continuous time.Duration
+	if st.OnDuty || now.Sub(last.End) < cfg.MinRest {
+		continuous = last.End.Sub(last.Start)
+	}
+	st.ContinuousDutyMinutes = int(continuous.Minutes())
+
+	st.Level, st.Reasons = classify(continuous, inWindow, cfg)
+	if st.Level == LevelExceeded && !st.OnDuty {
+		until := last.End.Add(cfg.MinRest)
+		st.RestRequiredUntil = &until
+	}
+	return st
+}
+
+// ProjectJob returns the reasons, if any, why taking on another job of
+// cfg.ExpectedJobTime would push the rider over a limit.
+func ProjectJob(st Status, cfg Config) []string {
+	continuous := time.Duration(st.ContinuousDutyMinutes)*time.Minute + cfg.ExpectedJobTime
+	inWindow := time.Duration(st.DutyInWindowMinutes)*time.Minute + cfg.ExpectedJobTime
+	level, reasons := classify(continuous, inWindow, cfg)
+	if level != LevelExceeded {
+		return nil
+	}
+	return reasons
+}
+
+func classify(continuous, inWindow time.Duration, cfg Config) (Level, []string) {
+	level := LevelOK
+	var reasons []string
+	check := func(value, limit time.Duration, label string) {
+		if limit <= 0 {
+			return
+		}
+		switch {
+		case value >= limit:
+			level = LevelExceeded
+			reasons = append(reasons, fmt.Sprintf("%s %s exceeds limit of %s", label, formatHours(value), formatHours(limit)))
+		case value >= limit-cfg.WarnMargin:
+			if level == LevelOK {
+				level = LevelWarning
+			}
+			reasons = append(reasons, fmt.Sprintf("%s %s is close to limit of %s", label, formatHours(value), formatHours(limit)))
+		}
+	}
+	check(continuous, cfg.MaxContinuousDuty, "continuous duty")
+	check(inWindow, cfg.MaxDutyInWindow, fmt.Sprintf("duty in last %s", formatHours(cfg.Window)))
+	return level, reasons
+}
+
+// normalise closes open intervals at now, drops future and empty intervals
+// and returns the result sorted by start time.
+func normalise(intervals []Interval, now time.Time) []Interval {
+	out := make([]Interval, 0, len(intervals))
+	for _, iv := range intervals {
+		if iv.Start.IsZero() || iv.Start.After(now) {
+			continue
+		}
+		if iv.End.IsZero() || iv.End.After(now) {
+			iv.End = now
+		}
+		if !iv.End.After(iv.Start) && !iv.End.Equal(now) {
+			continue
+		}
+		out = append(out, iv)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+	return out
+}
+
+// merge joins sorted intervals whose gap is shorter than maxGap.
+func merge(sorted []Interval, maxGap time.Duration) []Interval {
+	out := make([]Interval, 0, len(sorted))
+	for _, iv := range sorted {
+		if n := len(out); n > 0 && iv.Start.Sub(out[n-1].End) < maxGap {
+			if iv.End.After(out[n-1].End) {
+				out[n-1].End = iv.End
+			}
+			continue
+		}
+		out = append(out, Interval{Start: iv.Start, End: iv.End})
+	}
+	return out
+}
+
+func formatHours(d time.Duration) string {
+	return strconv.FormatFloat(d.Hours(), 'f', 1, 64) + "h"
+}
@@ -0,0 +1,189 @@
+package fatigue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+)
+
+var now = time.Date(2026, 3, 14, 6, 0, 0, 0, time.UTC)
+
+func testConfig() Config {
+	return Config{
+		MaxContinuousDuty: 12 * time.Hour,
+		MinRest:           8 * time.Hour,
+		MaxDutyInWindow:   10 * time.Hour,
+		Window:            24 * time.Hour,
+		WarnMargin:        time.Hour,
+		ExpectedJobTime:   90 * time.Minute,
+		Enforcement:       EnforcementWarn,
+	}
+}
+
+func span(startAgo, endAgo time.Duration) Interval {
+	iv := Interval{Start: now.Add(-startAgo)}
+	if endAgo >= 0 {
+		iv.End = now.Add(-endAgo)
+	}
+	return iv
+}
+
+// ---- Evaluate ----
+
+func TestEvaluate_NoHistory(t *testing.T) {
+	st := Evaluate("r1", nil, now, testConfig())
+	if st.Level != LevelOK || st.ContinuousDutyMinutes != 0 || st.OnDuty {
+		t.Errorf("expected fresh rider, got %+v", st)
+	}
+}
+
+func TestEvaluate_ShortBreaksDoNotResetContinuousDuty(t *testing.T) {
+	// Three night runs separated by one-hour breaks: 23:00–01:00, 02:00–04:00, 05:00–06:00 (open).
+	intervals := []Interval{span(7*time.Hour, 5*time.Hour), span(4*time.Hour, 2*time.Hour), span(time.Hour, -1)}
+	st := Evaluate("r1", intervals, now, testConfig())
+	if !st.OnDuty {
+		t.Error("expected rider to be on duty")
+	}
+	if st.ContinuousDutyMinutes != 7*60 {
+		t.Errorf("expected 420 continuous minutes, got %d", st.ContinuousDutyMinutes)
+	}
+	if st.DutyInWindowMinutes != 5*60 {
+		t.Errorf("expected 300 active minutes in window, got %d", st.DutyInWindowMinutes)
+	}
+}
+
+func TestEvaluate_LongRestResetsContinuousDuty(t *testing.T) {
+	intervals := []Interval{span(20*time.Hour, 11*time.Hour), span(2*time.Hour, time.Hour)}
+	st := Evaluate("r1", intervals, now, testConfig())
+	if st.ContinuousDutyMinutes != 60 {
+		t.Errorf("expected 60 continuous minutes after a full rest, got %d", st.ContinuousDutyMinutes)
+	}
+	if st.RestMinutes != 60 {
+		t.Errorf("expected 60 rest minutes, got %d", st.RestMinutes)
+	}
+}
+
+func TestEvaluate_FullyRested(t *testing.T) {
+	st := Evaluate("r1", []Interval{span(20*time.Hour, 10*time.Hour)}, now, testConfig())
+	if st.ContinuousDutyMinutes != 0 {
+		t.Errorf("expected no continuous duty after 10h rest, got %d", st.ContinuousDutyMinutes)
+	}
+	if st.LastDutyEnd == nil || !st.LastDutyEnd.Equal(now.Add(-10*time.Hour)) {
+		t.Errorf("unexpected lastDutyEnd: %v", st.LastDutyEnd)
+	}
+}
+
+func TestEvaluate_OverlappingIntervalsCountedOnce(t *testing.T) {
+	// A ride session and the job it was for cover the same two hours.
+	intervals := []Interval{span(3*time.Hour, time.Hour), span(3*time.Hour, time.Hour)}
+	st := Evaluate("r1", intervals, now, testConfig())
+	if st.DutyInWindowMinutes != 120 {
+		t.Errorf("expected 120 minutes, got %d", st.DutyInWindowMinutes)
+	}
+}
+
+func TestEvaluate_WindowClipsOldDuty(t *testing.T) {
+	st := Evaluate("r1", []Interval{span(26*time.Hour, 22*time.Hour)}, now, testConfig())
+	if st.DutyInWindowMinutes != 120 {
+		t.Errorf("expected only 120 minutes inside the 24h window, got %d", st.DutyInWindowMinutes)
+	}
+}
+
+func TestEvaluate_Warning(t *testing.T) {
+	st := Evaluate("r1", []Interval{span(9*time.Hour+30*time.Minute, -1)}, now, testConfig())
+	if st.Level != LevelWarning {
+		t.Errorf("expected warning, got %s (%v)", st.Level, st.Reasons)
+	}
+}
+
+func TestEvaluate_ExceededSetsRestRequiredUntil(t *testing.T) {
+	st := Evaluate("r1", []Interval{span(11*time.Hour, time.Hour)}, now, testConfig())
+	if st.Level != LevelExceeded {
+		t.Fatalf("expected exceeded, got %s", st.Level)
+	}
+	want := now.Add(-time.Hour).Add(8 * time.Hour)
+	if st.RestRequiredUntil == nil || !st.RestRequiredUntil.Equal(want) {
+		t.Errorf("expected restRequiredUntil %v, got %v", want, st.RestRequiredUntil)
+	}
+}
+
+func TestEvaluate_IgnoresFutureIntervals(t *testing.T) {
+	st := Evaluate("r1", []Interval{{Start: now.Add(time.Hour), End: now.Add(2 * time.Hour)}}, now, testConfig())
+	if st.DutyInWindowMinutes != 0 {
+		t.Errorf("expected future interval to be ignored, got %d", st.DutyInWindowMinutes)
+	}
+}
+
+// ---- ProjectJob ----
+
+func TestProjectJob_WithinLimits(t *testing.T) {
+	st := Evaluate("r1", []Interval{span(2*time.Hour, time.Hour)}, now, testConfig())
+	if reasons := ProjectJob(st, testConfig()); reasons != nil {
+		t.Errorf("expected no reasons, got %v", reasons)
+	}
+}
+
+func TestProjectJob_WouldExceed(t *testing.T) {
+	st := Evaluate("r1", []Interval{span(9*time.Hour, 30*time.Minute)}, now, testConfig())
+	if reasons := ProjectJob(st, testConfig()); len(reasons) == 0 {
+		t.Error("expected projected job to exceed the duty window limit")
+	}
+}
+
+// ---- interval sources ----
+
+func TestJobInterval(t *testing.T) {
+	j := repo.Job{
+		JobID:      "j1",
+		AcceptedBy: "r1",
+		Status:     "delivered",
+		Timestamps: map[string]any{
+			"accepted":  "2026-03-14T01:00:00Z",
+			"delivered": "2026-03-14T02:30:00Z",
+		},
+	}
+	iv, ok := jobInterval(j, now, 24*time.Hour)
+	if !ok {
+		t.Fatal("expected interval")
+	}
+	if iv.End.Sub(iv.Start) != 90*time.Minute {
+		t.Errorf("expected 90 minutes, got %v", iv.End.Sub(iv.Start))
+	}
+}
+
+func TestJobInterval_Unaccepted(t *testing.T) {
+	if _, ok := jobInterval(repo.Job{JobID: "j1", Status: "open"}, now, 24*time.Hour); ok {
+		t.Error("expected open job to be ignored")
+	}
+}
+
+func TestJobInterval_OpenJob(t *testing.T) {
+	j := repo.Job{AcceptedBy: "r1", Status: "picked-up", Timestamps: map[string]any{"pickedUp": "2026-03-14T05:00:00Z"}}
+	iv, ok := jobInterval(j, now, 24*time.Hour)
+	if !ok || !iv.End.IsZero() {
+		t.Errorf("expected open interval, got %+v ok=%v", iv, ok)
+	}
+}
+
+func TestJobInterval_StaleAcceptedJobIgnored(t *testing.T) {
+	j := repo.Job{JobID: "j1", AcceptedBy: "r1", Status: "accepted", Timestamps: map[string]any{"accepted": "2026-03-10T09:00:00Z"}}
+	if _, ok := jobInterval(j, now, 24*time.Hour); ok {
+		t.Error("expected job left accepted past the window to be ignored")
+	}
+	j.Timestamps["accepted"] = "2026-03-14T05:00:00Z"
+	if iv, ok := jobInterval(j, now, 24*time.Hour); !ok || !iv.End.IsZero() {
+		t.Errorf("expected recently accepted job to stay open, got %+v ok=%v", iv, ok)
+	}
+}
+
+func TestSessionInterval_AbandonedSessionIgnored(t *testing.T) {
+	s := repo.RideSession{SessionID: "s1", RiderID: "r1", StartTime: now.Add(-48 * time.Hour)}
+	if _, ok := sessionInterval(s, now, 24*time.Hour); ok {
+		t.Error("expected abandoned open session to be ignored")
+	}
+	s = repo.RideSession{SessionID: "s2", RiderID: "r1", StartTime: now.Add(-13 * time.Hour), EndTime: now, AutoClosed: true}
+	if _, ok := sessionInterval(s, now, 24*time.Hour); ok {
+		t.Error("expected auto-closed session to be ignored")
+	}
+}
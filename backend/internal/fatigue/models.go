@@ -0,0 +1,62 @@
+package fatigue
+
+import "time"
+
+// Level summarises how close a rider is to their duty-time limits.
+type Level string
+
+const (
+	LevelOK       Level = "ok"
+	LevelWarning  Level = "warning"
+	LevelExceeded Level = "exceeded"
+)
+
+// Enforcement controls what happens when accepting a job would exceed a limit.
+type Enforcement string
+
+const (
+	EnforcementWarn  Enforcement = "warn"
+	EnforcementBlock Enforcement = "block"
+)
+
+// Interval sources.
+const (
+	SourceRideSession = "ride-session"
+	SourceJob         = "job"
+	SourceOnCall      = "on-call"
+)
+
+// Config holds the configurable duty-time thresholds.
+type Config struct {
+	MaxContinuousDuty time.Duration // longest duty block allowed without a full rest
+	MinRest           time.Duration // a gap at least this long resets continuous duty
+	MaxDutyInWindow   time.Duration // total active duty allowed inside Window
+	Window            time.Duration // rolling window for MaxDutyInWindow, e.g. 24h
+	WarnMargin        time.Duration // warn when within this margin of a limit
+	ExpectedJobTime   time.Duration // duty added when projecting a job acceptance
+	Enforcement       Enforcement
+	CountOnCall       bool // count "available" on-call windows as duty time
+}
+
+// Interval is a single period a rider spent on duty.
+// A zero End means the interval is still open.
+type Interval struct {
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end,omitempty"`
+	Source string    `json:"source"`
+	Ref    string    `json:"ref,omitempty"` // session or job ID
+}
+
+// Status is the computed fatigue state for one rider.
+type Status struct {
+	RiderID               string     `json:"riderId"`
+	Level                 Level      `json:"level"`
+	OnDuty                bool       `json:"onDuty"`
+	ContinuousDutyMinutes int        `json:"continuousDutyMinutes"`
+	DutyInWindowMinutes   int        `json:"dutyInWindowMinutes"`
+	WindowHours           float64    `json:"windowHours"`
+	RestMinutes           int        `json:"restMinutes"`
+	LastDutyEnd           *time.Time `json:"lastDutyEnd,omitempty"`
+	RestRequiredUntil     *time.Time `json:"restRequiredUntil,omitempty"`
+	Reasons               []string   `json:"reasons,omitempty"`
+}
@@ -0,0 +1,188 @@
+package fatigue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+)
+
+var (
+	sessionsRepo repo.RideSessionsRepository
+	jobsRepo     repo.JobsRepository
+
+	configMu  sync.RWMutex
+	configSet bool
+	config    Config
+)
+
+// SetRepositories wires the ride session and job repositories used to
+// reconstruct each rider's duty history.
+func SetRepositories(sessions repo.RideSessionsRepository, jobs repo.JobsRepository) {
+	sessionsRepo = sessions
+	jobsRepo = jobs
+}
+
+// SetConfig overrides the thresholds loaded from the environment.
+func SetConfig(cfg Config) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	config, configSet = cfg, true
+}
+
+// CurrentConfig returns the thresholds in effect. They are read from the
+// environment on first use rather than at package init, so values loaded
+// from .env or the AppConfig table at startup are seen.
+func CurrentConfig() Config {
+	configMu.RLock()
+	if configSet {
+		defer configMu.RUnlock()
+		return config
+	}
+	configMu.RUnlock()
+	configMu.Lock()
+	defer configMu.Unlock()
+	if !configSet {
+		config, configSet = ConfigFromEnv(), true
+	}
+	return config
+}
+
+// ForRiders evaluates every given user at now. Users are passed in so the
+// caller can reuse a list it has already loaded.
+func ForRiders(ctx context.Context, users []repo.User, now time.Time) (map[string]Status, error) {
+	byRider, err := loadIntervals(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	cfg := CurrentConfig()
+	out := make(map[string]Status, len(users))
+	for i := range users {
+		u := &users[i]
+		intervals := append(byRider[u.RiderID], onCallIntervals(u, now, cfg)...)
+		out[u.RiderID] = Evaluate(u.RiderID, intervals, now, cfg)
+	}
+	return out, nil
+}
+
+// ForRider evaluates a single rider. u may be nil when the rider has no
+// user record yet.
+func ForRider(ctx context.Context, riderID string, u *repo.User, now time.Time) (Status, error) {
+	byRider, err := loadIntervals(ctx, now)
+	if err != nil {
+		return Status{}, err
+	}
+	cfg := CurrentConfig()
+	intervals := byRider[riderID]
+	if u != nil {
+		intervals = append(intervals, onCallIntervals(u, now, cfg)...)
+	}
+	return Evaluate(riderID, intervals, now, cfg), nil
+}
+
+func loadIntervals(ctx context.Context, now time.Time) (map[string][]Interval, error) {
+	if sessionsRepo == nil && jobsRepo == nil {
+		return nil, errors.New("fatigue repositories not configured")
+	}
+	window := CurrentConfig().Window
+	byRider := make(map[string][]Interval)
+	if sessionsRepo != nil {
+		sessions, err := sessionsRepo.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range sessions {
+			if iv, ok := sessionInterval(s, now, window); ok {
+				byRider[s.RiderID] = append(byRider[s.RiderID], iv)
+			}
+		}
+	}
+	if jobsRepo != nil {
+		jobs, err := jobsRepo.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, j := range jobs {
+			if iv, ok := jobInterval(j, now, window); ok {
+				byRider[j.AcceptedBy] = append(byRider[j.AcceptedBy], iv)
+			}
+		}
+	}
+	return byRider, nil
+}
+
+// sessionInterval converts a ride session to a duty interval. Sessions left
+// open for longer than the evaluation window, or closed by the stale
+// session sweep, are treated as abandoned.
+func sessionInterval(s repo.RideSession, now time.Time, window time.Duration) (Interval, bool) {
+	if s.RiderID == "" || s.StartTime.IsZero() || s.AutoClosed {
+		return Interval{}, false
+	}
+	if s.EndTime.IsZero() && now.Sub(s.StartTime) > window {
+		return Interval{}, false
+	}
+	return Interval{Start: s.StartTime, End: s.EndTime, Source: SourceRideSession, Ref: s.SessionID}, true
+}
+
+// jobInterval converts an accepted job to a duty interval running from
+// acceptance (or pickup) until delivery, completion or cancellation. Like
+// sessions, a job still open after the evaluation window is treated as
+// abandoned rather than as duty that never ends.
+func jobInterval(j repo.Job, now time.Time, window time.Duration) (Interval, bool) {
+	if j.AcceptedBy == "" {
+		return Interval{}, false
+	}
+	start, ok := jobTimestamp(j, "accepted", "pickedUp")
+	if !ok {
+		return Interval{}, false
+	}
+	end, ok := jobTimestamp(j, "delivered", "completed", "cancelled")
+	if !ok && isTerminalJobStatus(j.Status) {
+		end, _ = jobTimestamp(j, "updated")
+	}
+	if end.IsZero() && now.Sub(start) > window {
+		return Interval{}, false
+	}
+	return Interval{Start: start, End: end, Source: SourceJob, Ref: j.JobID}, true
+}
+
+func jobTimestamp(j repo.Job, keys ...string) (time.Time, bool) {
+	for _, k := range keys {
+		raw, ok := j.Timestamps[k].(string)
+		if !ok || raw == "" {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339, raw); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func isTerminalJobStatus(status string) bool {
+	return status == "delivered" || status == "completed" || status == "cancelled"
+}
+
+// onCallIntervals returns the rider's current availability window when
+// on-call time is configured to count as duty.
+func onCallIntervals(u *repo.User, now time.Time, cfg Config) []Interval {
+	if !cfg.CountOnCall || u.AvailableSince == "" {
+		return nil
+	}
+	if u.Status != "available" && u.Status != "on-job" {
+		return nil
+	}
+	start, err := time.Parse(time.RFC3339, u.AvailableSince)
+	if err != nil {
+		return nil
+	}
+	var end time.Time
+	if u.AvailableUntil != "" {
+		if until, err := time.Parse(time.RFC3339, u.AvailableUntil); err == nil && until.Before(now) {
+			end = until
+		}
+	}
+	return []Interval{{Start: start, End: end, Source: SourceOnCall}}
+}
@@ -0,0 +1,193 @@
+package fleet
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/issuereports"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/ridesessions"
+)
+
+// checklistItem is one line of the POWDERS-style pre-ride inspection.
+// A failed critical item grounds the bike.
+type checklistItem struct {
+	Key      string `json:"item"`
+	Label    string `json:"label"`
+	Critical bool   `json:"critical"`
+}
+
+// checklistItems is the pre-ride inspection every checkout must cover.
+var checklistItems = []checklistItem{
+	{Key: "fuel", Label: "Petrol"},
+	{Key: "oil", Label: "Oil"},
+	{Key: "water", Label: "Water / coolant"},
+	{Key: "damage", Label: "Damage"},
+	{Key: "lights", Label: "Electrics and lights", Critical: true},
+	{Key: "tyres", Label: "Rubber – tyres", Critical: true},
+	{Key: "brakes", Label: "Brakes", Critical: true},
+	{Key: "security", Label: "Security – kit and panniers secured"},
+}
+
+func findChecklistItem(key string) (checklistItem, bool) {
+	for _, it := range checklistItems {
+		if it.Key == key {
+			return it, true
+		}
+	}
+	return checklistItem{}, false
+}
+
+type CheckoutRequest struct {
+	BikeID      string                `json:"bikeId"`
+	RiderID     string                `json:"riderId,omitempty"` // ignored when authenticated
+	Odometer    int                   `json:"odometer"`
+	FuelPercent *int                  `json:"fuelPercent"`
+	Checklist   []repo.InspectionItem `json:"checklist"`
+	JobIDs      []string              `json:"jobIds,omitempty"` // defaults to the rider's jobs under way
+}
+
+type CheckinRequest struct {
+	BikeID      string `json:"bikeId"`
+	Odometer    int    `json:"odometer"`
+	FuelPercent *int   `json:"fuelPercent"`
+	Notes       string `json:"notes,omitempty"`
+}
+
+// CheckoutResult is returned by checkout. When the inspection grounds the
+// bike, Session is nil and Issues lists the faults raised.
+type CheckoutResult struct {
+	Session *repo.RideSession  `json:"session,omitempty"`
+	Bike    Motorcycle         `json:"bike"`
+	Issues  []repo.IssueReport `json:"issues,omitempty"`
+	Error   string             `json:"error,omitempty"`
+}
+
+// validateChecklist checks that every inspection item is answered exactly
+// once and returns the items in checklist order.
+func validateChecklist(items []repo.InspectionItem) ([]repo.InspectionItem, error) {
+	seen := make(map[string]repo.InspectionItem, len(items))
+	for _, it := range items {
+		key := strings.ToLower(strings.TrimSpace(it.Item))
+		if _, ok := findChecklistItem(key); !ok {
+			return nil, fmt.Errorf("unknown checklist item %q", it.Item)
+		}
+		if _, dup := seen[key]; dup {
+			return nil, fmt.Errorf("checklist item %q answered twice", key)
+		}
+		it.Item = key
+		it.Notes = strings.TrimSpace(it.Notes)
+		seen[key] = it
+	}
+
+	out := make([]repo.InspectionItem, 0, len(checklistItems))
+	var missing []string
+	for _, c := range checklistItems {
+		it, ok := seen[c.Key]
+		if !ok {
+			missing = append(missing, c.Key)
+			continue
+		}
+		out = append(out, it)
+	}
+	if len(missing) > 0 {
+		return nil, errors.New("checklist incomplete: " + strings.Join(missing, ", "))
+	}
+	return out, nil
+}
+
+func validateFuelPercent(fuel *int) error {
+	if fuel == nil {
+		return errors.New("fuelPercent required")
+	}
+	if *fuel < 0 || *fuel > 100 {
+		return errors.New("fuelPercent must be between 0 and 100")
+	}
+	return nil
+}
+
+func validateCheckout(req *CheckoutRequest) error {
+	req.BikeID = strings.TrimSpace(req.BikeID)
+	if req.BikeID == "" {
+		return errors.New("bikeId required")
+	}
+	if req.Odometer < 0 {
+		return errors.New("odometer must not be negative")
+	}
+	if err := validateFuelPercent(req.FuelPercent); err != nil {
+		return err
+	}
+	items, err := validateChecklist(req.Checklist)
+	if err != nil {
+		return err
+	}
+	req.Checklist = items
+	return nil
+}
+
+func validateCheckin(req *CheckinRequest) error {
+	req.BikeID = strings.TrimSpace(req.BikeID)
+	if req.BikeID == "" {
+		return errors.New("bikeId required")
+	}
+	if req.Odometer < 0 {
+		return errors.New("odometer must not be negative")
+	}
+	return validateFuelPercent(req.FuelPercent)
+}
+
+// checkoutBlockReason explains why the bike can't be checked out, or
+// returns "" if it can.
+func checkoutBlockReason(b repo.Bike) string {
+	return ridesessions.BlockReason(b)
+}
+
+// inspectionIssues builds an issue report request for each failed checklist
+// item. Critical failures are raised as Major.
+func inspectionIssues(bikeID, riderID string, items []repo.InspectionItem) (reqs []issuereports.CreateRequest, grounded bool) {
+	for _, it := range items {
+		if it.OK {
+			continue
+		}
+		c, _ := findChecklistItem(it.Item)
+		typ := "Minor"
+		if c.Critical {
+			typ = "Major"
+			grounded = true
+		}
+		desc := "Pre-ride check failed: " + c.Label
+		if it.Notes != "" {
+			desc += " – " + it.Notes
+		}
+		reqs = append(reqs, issuereports.CreateRequest{BikeID: bikeID, RiderID: riderID, Type: typ, Description: desc})
+	}
+	return reqs, grounded
+}
+
+// openSession returns the bike's most recent session that hasn't been
+// checked in.
+func openSession(ctx context.Context, bikeID string) (*repo.RideSession, error) {
+	sessions, err := rideSessionsRepo.ListByBike(ctx, bikeID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].StartTime.After(sessions[j].StartTime) })
+	for i := range sessions {
+		if sessions[i].EndTime.IsZero() {
+			return &sessions[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// newRecordID matches the IDs minted by the ride sessions package.
+func newRecordID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
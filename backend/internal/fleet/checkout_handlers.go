@@ -0,0 +1,230 @@
+package fleet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/auth"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/depots"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/issuereports"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/ridesessions"
+)
+
+func ensureCheckoutRepos() error {
+	if rideSessionsRepo == nil {
+		return errors.New("bike checkout not configured")
+	}
+	return nil
+}
+
+// Checklist handles GET /api/ride/checklist → the pre-ride inspection items.
+func Checklist(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, checklistItems)
+}
+
+// Checkout handles POST /api/ride/checkout. The rider submits the pre-ride
+// checklist, odometer and fuel level; failed items are raised as issue
+// reports and a failed critical item (a Major issue) grounds the bike
+// instead of checking it out.
+func Checkout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	repoBikes, err := ensureBikeRepo()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	if err := ensureCheckoutRepos(); err != nil {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+
+	var req CheckoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validateCheckout(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	riderID := auth.UsernameFromContext(r.Context())
+	if riderID == "" {
+		riderID = strings.TrimSpace(req.RiderID)
+	}
+	if riderID == "" {
+		http.Error(w, "riderId required", http.StatusBadRequest)
+		return
+	}
+
+	b, ok, err := repoBikes.Get(r.Context(), req.BikeID)
+	if err != nil {
+		log.Printf("op=Checkout bikeId=%s err=%v", req.BikeID, err)
+		http.Error(w, "failed to get bike", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "bike not found", http.StatusNotFound)
+		return
+	}
+	if reason := checkoutBlockReason(*b); reason != "" {
+		http.Error(w, reason, http.StatusConflict)
+		return
+	}
+	if req.Odometer < b.Mileage {
+		http.Error(w, fmt.Sprintf("%v (%d)", ridesessions.ErrOdometerRollback, b.Mileage), http.StatusBadRequest)
+		return
+	}
+
+	issueReqs, grounded := inspectionIssues(b.ID, riderID, req.Checklist)
+	issues := make([]repo.IssueReport, 0, len(issueReqs))
+	issueIDs := make([]string, 0, len(issueReqs))
+	for _, ir := range issueReqs {
+		created, err := issuereports.Create(r.Context(), ir)
+		if err != nil {
+			log.Printf("op=CheckoutIssue bikeId=%s err=%v", b.ID, err)
+			http.Error(w, "failed to save issue report", http.StatusInternalServerError)
+			return
+		}
+		issues = append(issues, *created)
+		issueIDs = append(issueIDs, created.IssueID)
+	}
+
+	if grounded {
+		updated, _, err := repo.UpdateBike(r.Context(), repoBikes, b.ID, func(gb *repo.Bike) (bool, error) {
+			gb.Status = BikeStatusFaultReported
+			return true, nil
+		})
+		if err != nil {
+			log.Printf("op=CheckoutGround bikeId=%s err=%v", b.ID, err)
+			http.Error(w, "failed to update bike", http.StatusInternalServerError)
+			return
+		}
+		if updated != nil {
+			b = updated
+		}
+		log.Printf("op=CheckoutRefused bikeId=%s rider=%s issues=%d", b.ID, riderID, len(issues))
+		writeJSON(w, http.StatusConflict, CheckoutResult{
+			Bike:   repoBikeToAPI(*b),
+			Issues: issues,
+			Error:  "bike failed pre-ride inspection and has been taken off the road",
+		})
+		return
+	}
+
+	session, b, err := ridesessions.Start(r.Context(), ridesessions.CreateRequest{
+		BikeID:     b.ID,
+		RiderID:    riderID,
+		StartMiles: req.Odometer,
+		StartFuel:  req.FuelPercent,
+		JobIDs:     req.JobIDs,
+		Checklist:  req.Checklist,
+		IssueIDs:   issueIDs,
+	})
+	if err != nil {
+		writeRideError(w, "Checkout", req.BikeID, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, CheckoutResult{Session: session, Bike: repoBikeToAPI(*b), Issues: issues})
+}
+
+// Checkin handles POST /api/ride/checkin. It closes the bike's open ride
+// session with the end odometer and fuel level and frees the bike.
+func Checkin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	repoBikes, err := ensureBikeRepo()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	if err := ensureCheckoutRepos(); err != nil {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+
+	var req CheckinRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validateCheckin(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	b, ok, err := repoBikes.Get(r.Context(), req.BikeID)
+	if err != nil {
+		log.Printf("op=Checkin bikeId=%s err=%v", req.BikeID, err)
+		http.Error(w, "failed to get bike", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "bike not found", http.StatusNotFound)
+		return
+	}
+	session, err := openSession(r.Context(), b.ID)
+	if err != nil {
+		log.Printf("op=CheckinSession bikeId=%s err=%v", b.ID, err)
+		http.Error(w, "failed to get ride session", http.StatusInternalServerError)
+		return
+	}
+	if session == nil {
+		http.Error(w, "bike is not checked out", http.StatusConflict)
+		return
+	}
+
+	caller := auth.UsernameFromContext(r.Context())
+	if caller != "" && caller != session.RiderID && !auth.HasRoleOrAbove(auth.RolesFromContext(r.Context()), "FleetManager") {
+		http.Error(w, "bike is checked out to another rider", http.StatusForbidden)
+		return
+	}
+	if err := ridesessions.CheckEndMiles(session, req.Odometer); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	session, err = ridesessions.EndSession(r.Context(), session.SessionID, ridesessions.EndRequest{
+		EndMiles: req.Odometer,
+		EndFuel:  req.FuelPercent,
+		Notes:    req.Notes,
+	})
+	if err != nil {
+		writeRideError(w, "Checkin", b.ID, err)
+		return
+	}
+	if b, _, err = repoBikes.Get(r.Context(), b.ID); err != nil || b == nil {
+		log.Printf("op=CheckinGet bikeId=%s err=%v", req.BikeID, err)
+		http.Error(w, "failed to get bike", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, CheckoutResult{Session: session, Bike: repoBikeToAPI(*b)})
+}
+
+// writeRideError maps a ride session service error to a response.
+func writeRideError(w http.ResponseWriter, op, bikeID string, err error) {
+	switch {
+	case errors.Is(err, ridesessions.ErrBikeNotFound):
+		http.Error(w, "bike not found", http.StatusNotFound)
+	case errors.Is(err, ridesessions.ErrBikeUnavailable), errors.Is(err, ridesessions.ErrRiderOnRide), errors.Is(err, ridesessions.ErrAlreadyEnded):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ridesessions.ErrOdometerRollback), errors.Is(err, ridesessions.ErrInvalidJob), errors.Is(err, depots.ErrUnknownDepot):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("op=%s bikeId=%s err=%v", op, bikeID, err)
+		http.Error(w, "failed to update ride", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,246 @@
+package fleet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/issuereports"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo/memory"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/ridesessions"
+)
+
+func passingChecklist() []repo.InspectionItem {
+	items := make([]repo.InspectionItem, 0, len(checklistItems))
+	for _, c := range checklistItems {
+		items = append(items, repo.InspectionItem{Item: c.Key, OK: true})
+	}
+	return items
+}
+
+func withFailed(items []repo.InspectionItem, key, notes string) []repo.InspectionItem {
+	out := append([]repo.InspectionItem(nil), items...)
+	for i := range out {
+		if out[i].Item == key {
+			out[i].OK = false
+			out[i].Notes = notes
+		}
+	}
+	return out
+}
+
+func setupCheckout(t *testing.T) (*memory.BikesRepo, *memory.RideSessionsRepo, *memory.IssueReportsRepo) {
+	t.Helper()
+	bikes := memory.NewBikesRepo()
+	sessions := memory.NewRideSessionsRepo()
+	issues := memory.NewIssueReportsRepo()
+	SetRepositories(memory.NewUsersRepo(), bikes)
+	SetRideSessionsRepository(sessions)
+	issuereports.SetRepository(issues)
+	issuereports.SetBikesRepository(bikes)
+	ridesessions.SetRepository(sessions)
+	ridesessions.SetBikesRepository(bikes)
+	t.Cleanup(func() {
+		SetRepositories(nil, nil)
+		SetRideSessionsRepository(nil)
+		issuereports.SetRepository(nil)
+		issuereports.SetBikesRepository(nil)
+		ridesessions.SetRepository(nil)
+		ridesessions.SetBikesRepository(nil)
+	})
+	return bikes, sessions, issues
+}
+
+func postJSON(t *testing.T, h http.HandlerFunc, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	raw, _ := json.Marshal(body)
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(raw)))
+	return rec
+}
+
+func fuel(n int) *int { return &n }
+
+// ---- validation ----
+
+func TestValidateChecklist(t *testing.T) {
+	if _, err := validateChecklist(passingChecklist()[1:]); err == nil {
+		t.Error("expected incomplete checklist to fail")
+	}
+	dup := append(passingChecklist(), repo.InspectionItem{Item: "brakes", OK: true})
+	if _, err := validateChecklist(dup); err == nil {
+		t.Error("expected duplicate item to fail")
+	}
+	unknown := append(passingChecklist(), repo.InspectionItem{Item: "horn", OK: true})
+	if _, err := validateChecklist(unknown); err == nil {
+		t.Error("expected unknown item to fail")
+	}
+	items := passingChecklist()
+	items[0].Item = " FUEL "
+	got, err := validateChecklist(items)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got[0].Item != "fuel" {
+		t.Errorf("expected item key normalised, got %q", got[0].Item)
+	}
+}
+
+func TestCheckoutBlockReason(t *testing.T) {
+	cases := []struct {
+		bike    repo.Bike
+		blocked bool
+	}{
+		{repo.Bike{Status: BikeStatusAvailable}, false},
+		{repo.Bike{}, false},
+		{repo.Bike{Status: BikeStatusInService}, true},
+		{repo.Bike{Status: BikeStatusFaultReported}, true},
+		{repo.Bike{Status: BikeStatusOutOfService}, true},
+		{repo.Bike{Status: BikeStatusAvailable, CurrentRiderID: "r2"}, true},
+	}
+	for _, c := range cases {
+		if got := checkoutBlockReason(c.bike) != ""; got != c.blocked {
+			t.Errorf("bike %+v: expected blocked=%v", c.bike, c.blocked)
+		}
+	}
+}
+
+// ---- checkout / check-in ----
+
+func TestCheckout_ThenCheckin(t *testing.T) {
+	bikes, sessions, issues := setupCheckout(t)
+	ctx := context.Background()
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b1", Status: BikeStatusAvailable, Mileage: 1000})
+
+	rec := postJSON(t, Checkout, "/api/ride/checkout", CheckoutRequest{
+		BikeID: "b1", RiderID: "r1", Odometer: 1000, FuelPercent: fuel(80),
+		Checklist: withFailed(passingChecklist(), "security", "pannier latch loose"),
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	b, _, _ := bikes.Get(ctx, "b1")
+	if b.Status != BikeStatusOnDuty || b.CurrentRiderID != "r1" {
+		t.Errorf("expected bike on duty with r1, got %+v", b)
+	}
+	reports, _ := issues.List(ctx)
+	if len(reports) != 1 || reports[0].Type != "Minor" {
+		t.Errorf("expected one minor issue for the failed item, got %+v", reports)
+	}
+
+	rec = postJSON(t, Checkout, "/api/ride/checkout", CheckoutRequest{
+		BikeID: "b1", RiderID: "r2", Odometer: 1000, FuelPercent: fuel(80), Checklist: passingChecklist(),
+	})
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected assigned bike to be refused, got %d", rec.Code)
+	}
+
+	rec = postJSON(t, Checkin, "/api/ride/checkin", CheckinRequest{BikeID: "b1", Odometer: 990, FuelPercent: fuel(40)})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected lower odometer to be rejected, got %d", rec.Code)
+	}
+	rec = postJSON(t, Checkin, "/api/ride/checkin", CheckinRequest{BikeID: "b1", Odometer: 1042, FuelPercent: fuel(40)})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	b, _, _ = bikes.Get(ctx, "b1")
+	if b.Status != BikeStatusAvailable || b.CurrentRiderID != "" || b.Mileage != 1042 {
+		t.Errorf("expected bike available at 1042, got %+v", b)
+	}
+	list, _ := sessions.ListByBike(ctx, "b1")
+	if len(list) != 1 || list[0].EndMiles != 1042 || *list[0].EndFuel != 40 || len(list[0].IssueIDs) != 1 {
+		t.Errorf("unexpected session %+v", list)
+	}
+
+	rec = postJSON(t, Checkin, "/api/ride/checkin", CheckinRequest{BikeID: "b1", Odometer: 1042, FuelPercent: fuel(40)})
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected second check-in to be refused, got %d", rec.Code)
+	}
+}
+
+func TestCheckout_CriticalFailureGroundsBike(t *testing.T) {
+	bikes, sessions, issues := setupCheckout(t)
+	ctx := context.Background()
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b1", Status: BikeStatusAvailable})
+
+	rec := postJSON(t, Checkout, "/api/ride/checkout", CheckoutRequest{
+		BikeID: "b1", RiderID: "r1", Odometer: 500, FuelPercent: fuel(50),
+		Checklist: withFailed(passingChecklist(), "brakes", "rear brake spongy"),
+	})
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", rec.Code)
+	}
+	b, _, _ := bikes.Get(ctx, "b1")
+	if b.Status != BikeStatusFaultReported || b.CurrentRiderID != "" {
+		t.Errorf("expected bike grounded and unassigned, got %+v", b)
+	}
+	reports, _ := issues.List(ctx)
+	if len(reports) != 1 || reports[0].Type != "Major" {
+		t.Errorf("expected one major issue, got %+v", reports)
+	}
+	if list, _ := sessions.List(ctx); len(list) != 0 {
+		t.Errorf("expected no ride session, got %d", len(list))
+	}
+}
+
+func TestCheckout_Validation(t *testing.T) {
+	bikes, _, _ := setupCheckout(t)
+	_ = bikes.Put(context.Background(), &repo.Bike{ID: "b1", Status: BikeStatusAvailable})
+
+	rec := postJSON(t, Checkout, "/api/ride/checkout", CheckoutRequest{BikeID: "b1", RiderID: "r1", Checklist: passingChecklist()})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected missing fuel to fail, got %d", rec.Code)
+	}
+	rec = postJSON(t, Checkout, "/api/ride/checkout", CheckoutRequest{BikeID: "nope", RiderID: "r1", FuelPercent: fuel(10), Checklist: passingChecklist()})
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected unknown bike to 404, got %d", rec.Code)
+	}
+}
+
+// ---- start / end ride ----
+
+func TestStartRide_EndRide(t *testing.T) {
+	bikes, sessions, _ := setupCheckout(t)
+	ctx := context.Background()
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b1", Status: BikeStatusAvailable, Mileage: 300})
+
+	rec := httptest.NewRecorder()
+	StartRide(rec, httptest.NewRequest(http.MethodPost, "/api/ride/start?bikeId=b1&riderId=r1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var res CheckoutResult
+	_ = json.NewDecoder(rec.Body).Decode(&res)
+	if res.Session == nil || res.Bike.CurrentRiderID != "r1" {
+		t.Fatalf("expected a session and the bike assigned, got %+v", res)
+	}
+
+	rec = httptest.NewRecorder()
+	StartRide(rec, httptest.NewRequest(http.MethodPost, "/api/ride/start?bikeId=b1&riderId=r2", nil))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected bike on a ride to be refused, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	EndRide(rec, httptest.NewRequest(http.MethodPost, "/api/ride/end?bikeId=b1&odometer=250", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected odometer rollback to be refused, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	EndRide(rec, httptest.NewRequest(http.MethodPost, "/api/ride/end?bikeId=b1&odometer=325", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	b, _, _ := bikes.Get(ctx, "b1")
+	if b.Status != BikeStatusAvailable || b.CurrentRiderID != "" || b.Mileage != 325 {
+		t.Errorf("expected bike released at 325, got %+v", b)
+	}
+	list, _ := sessions.ListByBike(ctx, "b1")
+	if len(list) != 1 || list[0].EndTime.IsZero() || list[0].DistanceMiles != 25 {
+		t.Errorf("expected one closed 25 mile session, got %+v", list)
+	}
+}
@@ -0,0 +1,279 @@
+package fleet
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/orgtime"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/ridesessions"
+)
+
+// Statutory document types.
+const (
+	DocumentTypeInsurance = "insurance"
+	DocumentTypeMotorTax  = "motor_tax"
+	DocumentTypeNCT       = "nct"
+)
+
+var validDocumentTypes = map[string]string{
+	DocumentTypeInsurance: "Insurance",
+	DocumentTypeMotorTax:  "Motor tax",
+	DocumentTypeNCT:       "NCT",
+}
+
+// Document statuses.
+const (
+	DocumentStatusValid    = "valid"
+	DocumentStatusExpiring = "expiring"
+	DocumentStatusExpired  = "expired"
+)
+
+// documentReminderDays are the days-before-expiry at which fleet managers
+// are reminded. Day 0 is the "has expired" notice.
+var documentReminderDays = []int{30, 14, 1, 0}
+
+// maxDocumentFileBytes caps uploaded scans so a document stays well inside
+// DynamoDB's 400KB item limit.
+const maxDocumentFileBytes = 256 * 1024
+
+var allowedDocumentContentTypes = map[string]struct{}{
+	"application/pdf": {},
+	"image/jpeg":      {},
+	"image/png":       {},
+}
+
+var documentsRepo repo.BikeDocumentsRepository
+
+func SetDocumentsRepository(r repo.BikeDocumentsRepository) {
+	documentsRepo = r
+	ridesessions.SetAvailabilityCheck(documentsBlockReason)
+}
+
+// DocumentView is a document as returned by the API: the file contents are
+// left out and the computed expiry status is added.
+type DocumentView struct {
+	repo.BikeDocument
+	Status        string `json:"status"`
+	DaysRemaining int    `json:"daysRemaining"`
+	HasFile       bool   `json:"hasFile"`
+}
+
+type CreateDocumentRequest struct {
+	BikeID     string `json:"bikeId"`
+	Type       string `json:"type"`
+	Reference  string `json:"reference,omitempty"`
+	ExpiryDate string `json:"expiryDate"`
+	FileName   string `json:"fileName,omitempty"`
+	FileData   string `json:"fileData,omitempty"` // data URI
+}
+
+type UpdateDocumentRequest struct {
+	Reference  *string `json:"reference,omitempty"`
+	ExpiryDate *string `json:"expiryDate,omitempty"`
+	FileName   *string `json:"fileName,omitempty"`
+	FileData   *string `json:"fileData,omitempty"`
+}
+
+func toDocumentView(d repo.BikeDocument, now time.Time) DocumentView {
+	v := DocumentView{BikeDocument: d, HasFile: d.FileData != ""}
+	v.FileData = ""
+	v.DaysRemaining = daysUntilExpiry(d.ExpiryDate, now)
+	v.Status = documentStatus(v.DaysRemaining)
+	return v
+}
+
+// daysUntilExpiry counts whole calendar days from now until the expiry date.
+// A document is still valid on its expiry date (0) and expired the day after.
+// Today is the date in the org time zone, so a document lapses at local
+// midnight; the two dates are then compared as UTC days so a clock change
+// doesn't shorten one.
+func daysUntilExpiry(expiry, now time.Time) int {
+	now = now.In(orgtime.Location())
+	e := time.Date(expiry.Year(), expiry.Month(), expiry.Day(), 0, 0, 0, 0, time.UTC)
+	n := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return int(e.Sub(n).Hours() / 24)
+}
+
+func documentStatus(daysRemaining int) string {
+	switch {
+	case daysRemaining < 0:
+		return DocumentStatusExpired
+	case daysRemaining <= documentReminderDays[0]:
+		return DocumentStatusExpiring
+	default:
+		return DocumentStatusValid
+	}
+}
+
+// currentDocuments keeps only the latest-expiring document of each type per
+// bike, so a renewed policy supersedes the one it replaces.
+func currentDocuments(docs []repo.BikeDocument) []repo.BikeDocument {
+	latest := make(map[string]repo.BikeDocument)
+	for _, d := range docs {
+		key := d.BikeID + "|" + d.Type
+		if cur, ok := latest[key]; !ok || d.ExpiryDate.After(cur.ExpiryDate) {
+			latest[key] = d
+		}
+	}
+	out := make([]repo.BikeDocument, 0, len(latest))
+	for _, d := range latest {
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ExpiryDate.Before(out[j].ExpiryDate) })
+	return out
+}
+
+// ExpiredDocuments returns the bike's current documents that have expired.
+// A bike with any expired document must not be taken out on a ride.
+func ExpiredDocuments(ctx context.Context, bikeID string, now time.Time) ([]repo.BikeDocument, error) {
+	if documentsRepo == nil {
+		return nil, nil
+	}
+	docs, err := documentsRepo.ListByBike(ctx, bikeID)
+	if err != nil {
+		return nil, err
+	}
+	var expired []repo.BikeDocument
+	for _, d := range currentDocuments(docs) {
+		if daysUntilExpiry(d.ExpiryDate, now) < 0 {
+			expired = append(expired, d)
+		}
+	}
+	return expired, nil
+}
+
+// documentsBlockReason is registered with ridesessions.SetAvailabilityCheck
+// so no ride starts on a bike whose documents have expired.
+func documentsBlockReason(ctx context.Context, b repo.Bike, now time.Time) (string, error) {
+	expired, err := ExpiredDocuments(ctx, b.ID, now)
+	if err != nil || len(expired) == 0 {
+		return "", err
+	}
+	parts := make([]string, 0, len(expired))
+	for _, d := range expired {
+		parts = append(parts, fmt.Sprintf("%s expired %s", validDocumentTypes[d.Type], d.ExpiryDate.Format("2006-01-02")))
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+func validateDocumentFile(dataURI string) error {
+	if dataURI == "" {
+		return nil
+	}
+	if !strings.HasPrefix(dataURI, "data:") {
+		return errors.New("fileData must be a data URI")
+	}
+	idx := strings.Index(dataURI, ",")
+	if idx < 0 || !strings.HasSuffix(dataURI[:idx], ";base64") {
+		return errors.New("fileData must be a base64 data URI")
+	}
+	contentType := strings.TrimSuffix(strings.TrimPrefix(dataURI[:idx], "data:"), ";base64")
+	if _, ok := allowedDocumentContentTypes[contentType]; !ok {
+		return errors.New("file must be a PDF, JPEG or PNG")
+	}
+	if base64.StdEncoding.DecodedLen(len(dataURI)-idx-1) > maxDocumentFileBytes {
+		return errors.New("file too large")
+	}
+	// Decode now so corrupt data is a 400 here rather than a 500 on /file.
+	if _, err := base64.StdEncoding.DecodeString(dataURI[idx+1:]); err != nil {
+		return errors.New("fileData is not valid base64")
+	}
+	return nil
+}
+
+// decodeDocumentFile splits a stored data URI into content type and bytes.
+func decodeDocumentFile(dataURI string) (string, []byte, error) {
+	idx := strings.Index(dataURI, ",")
+	if idx < 0 {
+		return "", nil, errors.New("malformed file data")
+	}
+	contentType := strings.TrimSuffix(strings.TrimPrefix(dataURI[:idx], "data:"), ";base64")
+	data, err := base64.StdEncoding.DecodeString(dataURI[idx+1:])
+	if err != nil {
+		return "", nil, err
+	}
+	return contentType, data, nil
+}
+
+// bikeExists reports whether the bike is known. Without a bike repository
+// the ID is accepted as-is.
+func bikeExists(ctx context.Context, bikeID string) (bool, error) {
+	if bikesRepo == nil {
+		return true, nil
+	}
+	_, ok, err := bikesRepo.Get(ctx, bikeID)
+	return ok, err
+}
+
+// StartDocumentReminders notifies fleet managers daily about documents
+// expiring in 30, 14 or 1 days and about documents that have expired. Each
+// reminder is sent once per document.
+func StartDocumentReminders(ctx context.Context, notify Notifier) {
+	runDaily(ctx, "document", func() { sendDocumentReminders(ctx, notify, time.Now()) })
+}
+
+func sendDocumentReminders(ctx context.Context, notify Notifier, now time.Time) {
+	if documentsRepo == nil {
+		return
+	}
+	docs, err := documentsRepo.List(ctx)
+	if err != nil {
+		log.Printf("op=DocumentReminders err=%v", err)
+		return
+	}
+	for _, d := range currentDocuments(docs) {
+		days := daysUntilExpiry(d.ExpiryDate, now)
+		threshold, ok := dueReminder(d, days)
+		if !ok {
+			continue
+		}
+		title, body := documentReminderText(d, days)
+		log.Printf("op=DocumentReminder bikeId=%s type=%s days=%d", d.BikeID, d.Type, days)
+		if notify != nil {
+			notify(title, body, "/fleet")
+		}
+		d.RemindersSent = append(d.RemindersSent, threshold)
+		d.UpdatedAt = now
+		if err := documentsRepo.Put(ctx, &d); err != nil {
+			log.Printf("op=DocumentReminderPut documentId=%s err=%v", d.DocumentID, err)
+		}
+	}
+}
+
+// dueReminder returns the tightest reminder threshold the document has
+// reached and not yet been reminded about.
+func dueReminder(d repo.BikeDocument, days int) (int, bool) {
+	sent := make(map[int]bool, len(d.RemindersSent))
+	for _, s := range d.RemindersSent {
+		sent[s] = true
+	}
+	best, found := 0, false
+	for _, t := range documentReminderDays {
+		reached := days <= t
+		if t == 0 {
+			reached = days < 0
+		}
+		if reached {
+			best, found = t, true
+		}
+	}
+	if !found || sent[best] {
+		return 0, false
+	}
+	return best, true
+}
+
+func documentReminderText(d repo.BikeDocument, days int) (string, string) {
+	label := validDocumentTypes[d.Type]
+	if days < 0 {
+		return "⛔ Bike Document Expired", fmt.Sprintf("%s for bike %s expired on %s – bike is unavailable", label, d.BikeID, d.ExpiryDate.Format("2006-01-02"))
+	}
+	return "📄 Bike Document Expiring", fmt.Sprintf("%s for bike %s expires in %d %s (%s)", label, d.BikeID, days, plural(days, "day", "days"), d.ExpiryDate.Format("2006-01-02"))
+}
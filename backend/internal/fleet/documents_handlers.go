@@ -0,0 +1,268 @@
+package fleet
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/auth"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+)
+
+// DocumentsListOrCreate handles:
+//
+//	GET  /api/fleet/documents                   → all documents (?bikeId= to filter)
+//	GET  /api/fleet/documents?expiringDays=30   → current documents expiring within N days or expired
+//	POST /api/fleet/documents                   → add a document
+func DocumentsListOrCreate(w http.ResponseWriter, r *http.Request) {
+	if documentsRepo == nil {
+		http.Error(w, "bike documents not configured", http.StatusNotImplemented)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		now := time.Now()
+		q := r.URL.Query()
+		var docs []repo.BikeDocument
+		var err error
+		if bikeID := strings.TrimSpace(q.Get("bikeId")); bikeID != "" {
+			docs, err = documentsRepo.ListByBike(r.Context(), bikeID)
+		} else {
+			docs, err = documentsRepo.List(r.Context())
+		}
+		if err != nil {
+			log.Printf("op=ListDocuments err=%v", err)
+			http.Error(w, "failed to list documents", http.StatusInternalServerError)
+			return
+		}
+
+		expiringDays := -1
+		if raw := q.Get("expiringDays"); raw != "" {
+			n, convErr := strconv.Atoi(raw)
+			if convErr != nil || n < 0 {
+				http.Error(w, "expiringDays must be a non-negative integer", http.StatusBadRequest)
+				return
+			}
+			expiringDays = n
+			docs = currentDocuments(docs)
+		}
+
+		out := make([]DocumentView, 0, len(docs))
+		for _, d := range docs {
+			v := toDocumentView(d, now)
+			if expiringDays >= 0 && v.DaysRemaining > expiringDays {
+				continue
+			}
+			out = append(out, v)
+		}
+		writeJSON(w, http.StatusOK, out)
+	case http.MethodPost:
+		var req CreateDocumentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		doc, err := newDocumentFromRequest(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		exists, err := bikeExists(r.Context(), doc.BikeID)
+		if err != nil {
+			log.Printf("op=CreateDocument bikeId=%s err=%v", doc.BikeID, err)
+			http.Error(w, "failed to get bike", http.StatusInternalServerError)
+			return
+		}
+		if !exists {
+			http.Error(w, "bike not found", http.StatusNotFound)
+			return
+		}
+		doc.UploadedBy = auth.UsernameFromContext(r.Context())
+
+		if err := documentsRepo.Put(r.Context(), doc); err != nil {
+			log.Printf("op=CreateDocument bikeId=%s err=%v", doc.BikeID, err)
+			http.Error(w, "failed to save document", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusCreated, toDocumentView(*doc, time.Now()))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// DocumentDetail handles:
+//
+//	GET    /api/fleet/documents/{id}
+//	PUT    /api/fleet/documents/{id}        → update reference, expiry or file
+//	DELETE /api/fleet/documents/{id}
+//	GET    /api/fleet/documents/{id}/file   → the uploaded scan
+func DocumentDetail(w http.ResponseWriter, r *http.Request) {
+	if documentsRepo == nil {
+		http.Error(w, "bike documents not configured", http.StatusNotImplemented)
+		return
+	}
+
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/fleet/documents/"), "/")
+	parts := strings.Split(rest, "/")
+	docID := parts[0]
+	if docID == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	doc, ok, err := documentsRepo.Get(r.Context(), docID)
+	if err != nil {
+		log.Printf("op=GetDocument documentId=%s err=%v", docID, err)
+		http.Error(w, "failed to get document", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "document not found", http.StatusNotFound)
+		return
+	}
+
+	if len(parts) > 1 {
+		if parts[1] != "file" || r.Method != http.MethodGet {
+			http.NotFound(w, r)
+			return
+		}
+		serveDocumentFile(w, doc)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, toDocumentView(*doc, time.Now()))
+	case http.MethodPut, http.MethodPatch:
+		var req UpdateDocumentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := applyDocumentUpdate(doc, req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		doc.UpdatedAt = time.Now()
+		if err := documentsRepo.Put(r.Context(), doc); err != nil {
+			log.Printf("op=UpdateDocument documentId=%s err=%v", docID, err)
+			http.Error(w, "failed to update document", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, toDocumentView(*doc, time.Now()))
+	case http.MethodDelete:
+		deleted, err := documentsRepo.Delete(r.Context(), docID)
+		if err != nil {
+			log.Printf("op=DeleteDocument documentId=%s err=%v", docID, err)
+			http.Error(w, "failed to delete document", http.StatusInternalServerError)
+			return
+		}
+		if !deleted {
+			http.Error(w, "document not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func serveDocumentFile(w http.ResponseWriter, doc *repo.BikeDocument) {
+	if doc.FileData == "" {
+		http.Error(w, "document has no file", http.StatusNotFound)
+		return
+	}
+	contentType, data, err := decodeDocumentFile(doc.FileData)
+	if err != nil {
+		log.Printf("op=DocumentFile documentId=%s err=%v", doc.DocumentID, err)
+		http.Error(w, "failed to read document file", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	if doc.FileName != "" {
+		w.Header().Set("Content-Disposition", `inline; filename="`+strings.ReplaceAll(doc.FileName, `"`, "")+`"`)
+	}
+	_, _ = w.Write(data)
+}
+
+func newDocumentFromRequest(req CreateDocumentRequest) (*repo.BikeDocument, error) {
+	bikeID := strings.TrimSpace(req.BikeID)
+	if bikeID == "" {
+		return nil, errors.New("bikeId required")
+	}
+	typ := strings.ToLower(strings.TrimSpace(req.Type))
+	if _, ok := validDocumentTypes[typ]; !ok {
+		return nil, errors.New("type must be insurance, motor_tax or nct")
+	}
+	expiry, err := parseExpiryDate(req.ExpiryDate)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateDocumentFile(req.FileData); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &repo.BikeDocument{
+		DocumentID: newDocumentID(),
+		BikeID:     bikeID,
+		Type:       typ,
+		Reference:  strings.TrimSpace(req.Reference),
+		ExpiryDate: expiry,
+		FileName:   strings.TrimSpace(req.FileName),
+		FileData:   req.FileData,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}, nil
+}
+
+func applyDocumentUpdate(doc *repo.BikeDocument, req UpdateDocumentRequest) error {
+	if req.ExpiryDate != nil {
+		expiry, err := parseExpiryDate(*req.ExpiryDate)
+		if err != nil {
+			return err
+		}
+		if !expiry.Equal(doc.ExpiryDate) {
+			doc.ExpiryDate = expiry
+			doc.RemindersSent = nil
+		}
+	}
+	if req.FileData != nil {
+		if err := validateDocumentFile(*req.FileData); err != nil {
+			return err
+		}
+		doc.FileData = *req.FileData
+	}
+	if req.FileName != nil {
+		doc.FileName = strings.TrimSpace(*req.FileName)
+	}
+	if req.Reference != nil {
+		doc.Reference = strings.TrimSpace(*req.Reference)
+	}
+	return nil
+}
+
+func parseExpiryDate(value string) (time.Time, error) {
+	if strings.TrimSpace(value) == "" {
+		return time.Time{}, errors.New("expiryDate required")
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+	}
+	return time.Time{}, errors.New("invalid expiryDate")
+}
+
+func newDocumentID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return "doc_" + hex.EncodeToString(b)
+}
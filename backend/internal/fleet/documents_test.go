@@ -0,0 +1,214 @@
+package fleet
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo/memory"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/ridesessions"
+)
+
+var docNow = time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC)
+
+func day(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// ---- expiry status ----
+
+func TestDaysUntilExpiry(t *testing.T) {
+	cases := []struct {
+		expiry time.Time
+		want   int
+		status string
+	}{
+		{day(2026, 6, 1), 0, DocumentStatusExpiring},
+		{day(2026, 5, 31), -1, DocumentStatusExpired},
+		{day(2026, 7, 1), 30, DocumentStatusExpiring},
+		{day(2026, 7, 2), 31, DocumentStatusValid},
+	}
+	for _, c := range cases {
+		got := daysUntilExpiry(c.expiry, docNow)
+		if got != c.want {
+			t.Errorf("expiry %s: expected %d days, got %d", c.expiry.Format("2006-01-02"), c.want, got)
+		}
+		if s := documentStatus(got); s != c.status {
+			t.Errorf("expiry %s: expected %s, got %s", c.expiry.Format("2006-01-02"), c.status, s)
+		}
+	}
+}
+
+func TestDaysUntilExpiry_UsesOrgDate(t *testing.T) {
+	// 23:30 UTC on 10 June is already 11 June in Dublin.
+	lateEvening := time.Date(2026, 6, 10, 23, 30, 0, 0, time.UTC)
+	if got := daysUntilExpiry(day(2026, 6, 10), lateEvening); got != -1 {
+		t.Errorf("expected the document expired after local midnight, got %d days", got)
+	}
+	// The spring clock change leaves a 23-hour day that still counts as one.
+	beforeChange := time.Date(2026, 3, 28, 12, 0, 0, 0, time.UTC)
+	if got := daysUntilExpiry(day(2026, 3, 30), beforeChange); got != 2 {
+		t.Errorf("expected 2 days across the clock change, got %d", got)
+	}
+}
+
+func TestCurrentDocuments_RenewalSupersedesExpired(t *testing.T) {
+	docs := []repo.BikeDocument{
+		{DocumentID: "old", BikeID: "b1", Type: DocumentTypeInsurance, ExpiryDate: day(2026, 1, 1)},
+		{DocumentID: "new", BikeID: "b1", Type: DocumentTypeInsurance, ExpiryDate: day(2027, 1, 1)},
+		{DocumentID: "tax", BikeID: "b1", Type: DocumentTypeMotorTax, ExpiryDate: day(2026, 9, 1)},
+	}
+	cur := currentDocuments(docs)
+	if len(cur) != 2 {
+		t.Fatalf("expected 2 current documents, got %d", len(cur))
+	}
+	for _, d := range cur {
+		if d.DocumentID == "old" {
+			t.Error("expected renewed insurance to supersede the expired one")
+		}
+	}
+}
+
+// ---- reminders ----
+
+func TestDueReminder(t *testing.T) {
+	d := repo.BikeDocument{}
+	if _, ok := dueReminder(d, 45); ok {
+		t.Error("expected no reminder 45 days out")
+	}
+	if th, ok := dueReminder(d, 20); !ok || th != 30 {
+		t.Errorf("expected 30-day reminder, got %d ok=%v", th, ok)
+	}
+	if th, ok := dueReminder(d, 10); !ok || th != 14 {
+		t.Errorf("expected 14-day reminder, got %d ok=%v", th, ok)
+	}
+	if th, ok := dueReminder(d, 0); !ok || th != 1 {
+		t.Errorf("expected 1-day reminder on expiry day, got %d ok=%v", th, ok)
+	}
+	if th, ok := dueReminder(d, -3); !ok || th != 0 {
+		t.Errorf("expected expired notice, got %d ok=%v", th, ok)
+	}
+	d.RemindersSent = []int{30, 14}
+	if _, ok := dueReminder(d, 10); ok {
+		t.Error("expected 14-day reminder not to repeat")
+	}
+}
+
+func TestSendDocumentReminders_OncePerThreshold(t *testing.T) {
+	docs := memory.NewBikeDocumentsRepo()
+	SetDocumentsRepository(docs)
+	defer SetDocumentsRepository(nil)
+	ctx := context.Background()
+	_ = docs.Put(ctx, &repo.BikeDocument{DocumentID: "d1", BikeID: "b1", Type: DocumentTypeNCT, ExpiryDate: day(2026, 6, 10)})
+
+	var sent int
+	notify := func(title, body, url string) { sent++ }
+	sendDocumentReminders(ctx, notify, docNow)
+	sendDocumentReminders(ctx, notify, docNow.Add(time.Hour))
+	if sent != 1 {
+		t.Errorf("expected one reminder, got %d", sent)
+	}
+	got, _, _ := docs.Get(ctx, "d1")
+	if len(got.RemindersSent) != 1 || got.RemindersSent[0] != 14 {
+		t.Errorf("expected 14-day reminder recorded, got %v", got.RemindersSent)
+	}
+}
+
+func TestDocumentDetail_DeleteMissingIs404(t *testing.T) {
+	docs := memory.NewBikeDocumentsRepo()
+	SetDocumentsRepository(docs)
+	defer SetDocumentsRepository(nil)
+	_ = docs.Put(context.Background(), &repo.BikeDocument{DocumentID: "d1", BikeID: "b1", Type: DocumentTypeNCT, ExpiryDate: day(2026, 6, 10)})
+
+	rec := httptest.NewRecorder()
+	DocumentDetail(rec, httptest.NewRequest(http.MethodDelete, "/api/fleet/documents/d1", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	DocumentDetail(rec, httptest.NewRequest(http.MethodDelete, "/api/fleet/documents/d1", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 deleting a missing document, got %d", rec.Code)
+	}
+}
+
+// ---- validation ----
+
+func TestValidateDocumentFile(t *testing.T) {
+	if err := validateDocumentFile(""); err != nil {
+		t.Errorf("expected empty file to be allowed, got %v", err)
+	}
+	if err := validateDocumentFile("data:application/pdf;base64,JVBERi0="); err != nil {
+		t.Errorf("expected PDF to be allowed, got %v", err)
+	}
+	if err := validateDocumentFile("data:text/html;base64,PGh0bWw+"); err == nil {
+		t.Error("expected HTML to be rejected")
+	}
+	if err := validateDocumentFile("data:application/pdf;base64,JVBERi0*!"); err == nil {
+		t.Error("expected corrupt base64 to be rejected")
+	}
+	if err := validateDocumentFile("not a data uri"); err == nil {
+		t.Error("expected non data URI to be rejected")
+	}
+}
+
+func TestNewDocumentFromRequest_Validation(t *testing.T) {
+	if _, err := newDocumentFromRequest(CreateDocumentRequest{Type: "insurance", ExpiryDate: "2026-01-01"}); err == nil {
+		t.Error("expected missing bikeId to fail")
+	}
+	if _, err := newDocumentFromRequest(CreateDocumentRequest{BikeID: "b1", Type: "mot", ExpiryDate: "2026-01-01"}); err == nil {
+		t.Error("expected unknown type to fail")
+	}
+	if _, err := newDocumentFromRequest(CreateDocumentRequest{BikeID: "b1", Type: "nct"}); err == nil {
+		t.Error("expected missing expiryDate to fail")
+	}
+	d, err := newDocumentFromRequest(CreateDocumentRequest{BikeID: "b1", Type: "Motor_Tax", ExpiryDate: "2026-12-31"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.Type != DocumentTypeMotorTax || !d.ExpiryDate.Equal(day(2026, 12, 31)) {
+		t.Errorf("unexpected document %+v", d)
+	}
+}
+
+// ---- StartRide ----
+
+func TestStartRide_BlockedByExpiredDocument(t *testing.T) {
+	ctx := context.Background()
+	bikes := memory.NewBikesRepo()
+	docs := memory.NewBikeDocumentsRepo()
+	SetRepositories(memory.NewUsersRepo(), bikes)
+	SetDocumentsRepository(docs)
+	ridesessions.SetRepository(memory.NewRideSessionsRepo())
+	ridesessions.SetBikesRepository(bikes)
+	defer func() {
+		SetRepositories(nil, nil)
+		SetDocumentsRepository(nil)
+		ridesessions.SetRepository(nil)
+		ridesessions.SetBikesRepository(nil)
+	}()
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b1", Status: "Available"})
+	_ = docs.Put(ctx, &repo.BikeDocument{DocumentID: "d1", BikeID: "b1", Type: DocumentTypeInsurance, ExpiryDate: time.Now().AddDate(0, 0, -2)})
+
+	rec := httptest.NewRecorder()
+	StartRide(rec, httptest.NewRequest(http.MethodPost, "/api/ride/start?bikeId=b1&riderId=r1", nil))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	ridesessions.ListOrCreate(rec, httptest.NewRequest(http.MethodPost, "/api/ride-sessions", strings.NewReader(`{"bikeId":"b1","riderId":"r1"}`)))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected ride sessions to refuse the bike too, got %d", rec.Code)
+	}
+
+	_ = docs.Put(ctx, &repo.BikeDocument{DocumentID: "d2", BikeID: "b1", Type: DocumentTypeInsurance, ExpiryDate: time.Now().AddDate(1, 0, 0)})
+	rec = httptest.NewRecorder()
+	StartRide(rec, httptest.NewRequest(http.MethodPost, "/api/ride/start?bikeId=b1&riderId=r1", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected renewed insurance to allow the ride, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,274 @@
+package fleet
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/ridesessions"
+)
+
+const expenseCategoryFuel = "fuel"
+
+// maxExpenseOdometerJump is the furthest an expense's odometer reading may
+// be past the bike's last one. Readings are typed from receipts and
+// advance the bike's odometer, so a typo would otherwise make every later
+// ride look like a rollback.
+const maxExpenseOdometerJump = 2000
+
+var validExpenseCategories = map[string]struct{}{
+	expenseCategoryFuel: {},
+	"tyres":             {},
+	"parts":             {},
+	"servicing":         {},
+	"insurance":         {},
+	"tax":               {},
+	"other":             {},
+}
+
+type CreateExpenseRequest struct {
+	Category string  `json:"category"`
+	Date     string  `json:"date,omitempty"`
+	Cost     float64 `json:"cost"`
+	Litres   float64 `json:"litres,omitempty"`
+	Odometer int     `json:"odometer,omitempty"`
+	Station  string  `json:"station,omitempty"`
+	Notes    string  `json:"notes,omitempty"`
+}
+
+func validateExpense(req *CreateExpenseRequest) error {
+	req.Category = strings.ToLower(strings.TrimSpace(req.Category))
+	if req.Category == "" {
+		return errors.New("category required")
+	}
+	if _, ok := validExpenseCategories[req.Category]; !ok {
+		return errors.New("category must be fuel, tyres, parts, servicing, insurance, tax or other")
+	}
+	if req.Cost < 0 || math.IsNaN(req.Cost) || math.IsInf(req.Cost, 0) {
+		return errors.New("cost must not be negative")
+	}
+	if req.Odometer < 0 {
+		return errors.New("odometer must not be negative")
+	}
+	if req.Category == expenseCategoryFuel {
+		if req.Litres <= 0 {
+			return errors.New("litres required for fuel")
+		}
+		if req.Odometer == 0 {
+			return errors.New("odometer required for fuel")
+		}
+	} else if req.Litres != 0 {
+		return errors.New("litres only applies to fuel")
+	}
+	return nil
+}
+
+// MonthlySpend is one calendar month's running costs.
+type MonthlySpend struct {
+	Month      string             `json:"month"` // YYYY-MM
+	Total      float64            `json:"total"`
+	ByCategory map[string]float64 `json:"byCategory"`
+}
+
+// CostSummary is the running-cost picture for a bike, or for all bikes at
+// a depot. Economy is worked out full-tank to full-tank: the fuel bought at
+// each fill-up after the first covers the distance since the previous one.
+type CostSummary struct {
+	BikeID         string         `json:"bikeId,omitempty"`
+	Depot          string         `json:"depot,omitempty"`
+	Bikes          int            `json:"bikes,omitempty"`
+	TotalCost      float64        `json:"totalCost"`
+	FuelCost       float64        `json:"fuelCost"`
+	Litres         float64        `json:"litres"`
+	DistanceKm     float64        `json:"distanceKm"`
+	KmPerLitre     float64        `json:"kmPerLitre,omitempty"`
+	LitresPer100Km float64        `json:"litresPer100Km,omitempty"`
+	CostPerKm      float64        `json:"costPerKm,omitempty"`
+	Monthly        []MonthlySpend `json:"monthly"`
+
+	economyKm     float64
+	economyLitres float64
+	monthly       map[string]*MonthlySpend
+}
+
+// CostReport is the fleet-wide view: every bike plus a roll-up per depot.
+type CostReport struct {
+	From   string        `json:"from,omitempty"`
+	To     string        `json:"to,omitempty"`
+	Bikes  []CostSummary `json:"bikes"`
+	Depots []CostSummary `json:"depots"`
+}
+
+// expenseWindow selects expenses dated within [from, to). A zero bound is
+// open.
+type expenseWindow struct {
+	from, to time.Time
+}
+
+func (w expenseWindow) contains(t time.Time) bool {
+	return (w.from.IsZero() || !t.Before(w.from)) && (w.to.IsZero() || t.Before(w.to))
+}
+
+func (w expenseWindow) filter(expenses []repo.Expense) []repo.Expense {
+	out := make([]repo.Expense, 0, len(expenses))
+	for _, e := range expenses {
+		if w.contains(e.Date) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// summariseExpenses builds a bike's cost summary. Distance is the span of
+// odometer readings recorded against its expenses.
+func summariseExpenses(bikeID, depot string, expenses []repo.Expense) CostSummary {
+	s := CostSummary{BikeID: bikeID, Depot: depot}
+	minOdo, maxOdo := 0, 0
+	var fills []repo.Expense
+	for _, e := range expenses {
+		s.TotalCost += e.Cost
+		s.addMonthly(e.Date.Format("2006-01"), e.Category, e.Cost)
+		if e.Category == expenseCategoryFuel {
+			s.FuelCost += e.Cost
+			s.Litres += e.Litres
+			if e.Odometer > 0 {
+				fills = append(fills, e)
+			}
+		}
+		if e.Odometer > 0 {
+			if minOdo == 0 || e.Odometer < minOdo {
+				minOdo = e.Odometer
+			}
+			if e.Odometer > maxOdo {
+				maxOdo = e.Odometer
+			}
+		}
+	}
+	s.DistanceKm = float64(maxOdo-minOdo) * ridesessions.KmPerMile
+
+	sort.Slice(fills, func(i, j int) bool { return fills[i].Odometer < fills[j].Odometer })
+	if len(fills) > 1 {
+		s.economyKm = float64(fills[len(fills)-1].Odometer-fills[0].Odometer) * ridesessions.KmPerMile
+		for _, f := range fills[1:] {
+			s.economyLitres += f.Litres
+		}
+	}
+	s.finish()
+	return s
+}
+
+func (s *CostSummary) addMonthly(month, category string, cost float64) {
+	if s.monthly == nil {
+		s.monthly = make(map[string]*MonthlySpend)
+	}
+	m := s.monthly[month]
+	if m == nil {
+		m = &MonthlySpend{Month: month, ByCategory: make(map[string]float64)}
+		s.monthly[month] = m
+	}
+	m.Total += cost
+	m.ByCategory[category] += cost
+}
+
+// add folds a bike's summary into a depot roll-up.
+func (s *CostSummary) add(b CostSummary) {
+	s.Bikes++
+	s.TotalCost += b.TotalCost
+	s.FuelCost += b.FuelCost
+	s.Litres += b.Litres
+	s.DistanceKm += b.DistanceKm
+	s.economyKm += b.economyKm
+	s.economyLitres += b.economyLitres
+	for _, m := range b.monthly {
+		for cat, cost := range m.ByCategory {
+			s.addMonthly(m.Month, cat, cost)
+		}
+	}
+}
+
+// finish derives the ratios, rounds money to cents and sorts the months.
+func (s *CostSummary) finish() {
+	if s.economyLitres > 0 && s.economyKm > 0 {
+		s.KmPerLitre = round(s.economyKm/s.economyLitres, 2)
+		s.LitresPer100Km = round(s.economyLitres/s.economyKm*100, 2)
+	}
+	if s.DistanceKm > 0 {
+		s.CostPerKm = round(s.TotalCost/s.DistanceKm, 3)
+	}
+	s.TotalCost = round(s.TotalCost, 2)
+	s.FuelCost = round(s.FuelCost, 2)
+	s.Litres = round(s.Litres, 2)
+	s.DistanceKm = round(s.DistanceKm, 1)
+
+	s.Monthly = make([]MonthlySpend, 0, len(s.monthly))
+	for _, m := range s.monthly {
+		spend := MonthlySpend{Month: m.Month, Total: round(m.Total, 2), ByCategory: make(map[string]float64, len(m.ByCategory))}
+		for cat, cost := range m.ByCategory {
+			spend.ByCategory[cat] = round(cost, 2)
+		}
+		s.Monthly = append(s.Monthly, spend)
+	}
+	sort.Slice(s.Monthly, func(i, j int) bool { return s.Monthly[i].Month < s.Monthly[j].Month })
+}
+
+func round(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
+
+// bikeExpenses pairs a bike with its expenses in the window.
+type bikeExpenses struct {
+	bike     repo.Bike
+	expenses []repo.Expense
+}
+
+func loadFleetExpenses(ctx context.Context, repoBikes repo.BikesRepository, w expenseWindow) ([]bikeExpenses, error) {
+	bikes, err := repoBikes.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(bikes, func(i, j int) bool { return bikes[i].ID < bikes[j].ID })
+	out := make([]bikeExpenses, 0, len(bikes))
+	for _, b := range bikes {
+		expenses, err := repoBikes.ListExpenses(ctx, b.ID)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, bikeExpenses{bike: b, expenses: w.filter(expenses)})
+	}
+	return out, nil
+}
+
+// buildCostReport summarises every bike and rolls the bikes up by depot.
+func buildCostReport(all []bikeExpenses) CostReport {
+	report := CostReport{Bikes: make([]CostSummary, 0, len(all)), Depots: make([]CostSummary, 0)}
+	byDepot := make(map[string]*CostSummary)
+	for _, be := range all {
+		s := summariseExpenses(be.bike.ID, be.bike.Depot, be.expenses)
+		d := byDepot[be.bike.Depot]
+		if d == nil {
+			d = &CostSummary{Depot: be.bike.Depot}
+			byDepot[be.bike.Depot] = d
+		}
+		d.add(s)
+		report.Bikes = append(report.Bikes, s)
+	}
+	for _, d := range byDepot {
+		d.finish()
+		report.Depots = append(report.Depots, *d)
+	}
+	sort.Slice(report.Depots, func(i, j int) bool { return report.Depots[i].Depot < report.Depots[j].Depot })
+	return report
+}
+
+func newExpenseID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return "exp_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,296 @@
+package fleet
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/auth"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+)
+
+// handleExpenses serves /api/fleet/bikes/{id}/expenses:
+//
+//	GET  → the bike's fuel fill-ups and running costs, newest first (?from=&to=)
+//	POST → log a fill-up or expense
+func handleExpenses(w http.ResponseWriter, r *http.Request, repoBikes repo.BikesRepository, bikeID string) {
+	switch r.Method {
+	case http.MethodGet:
+		window, err := parseExpenseWindow(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		expenses, err := repoBikes.ListExpenses(r.Context(), bikeID)
+		if err != nil {
+			log.Printf("op=ListExpenses bikeId=%s err=%v", bikeID, err)
+			http.Error(w, "failed to list expenses", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, window.filter(expenses))
+	case http.MethodPost:
+		var req CreateExpenseRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := validateExpense(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		date, err := parseServiceDate(req.Date)
+		if err != nil {
+			http.Error(w, "invalid date", http.StatusBadRequest)
+			return
+		}
+
+		bike, ok, err := repoBikes.Get(r.Context(), bikeID)
+		if err != nil {
+			http.Error(w, "failed to get bike", http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			http.Error(w, "bike not found", http.StatusNotFound)
+			return
+		}
+		if bike.Mileage > 0 && req.Odometer > bike.Mileage+maxExpenseOdometerJump {
+			http.Error(w, fmt.Sprintf("odometer is more than %d miles past the bike's last reading (%d)", maxExpenseOdometerJump, bike.Mileage), http.StatusBadRequest)
+			return
+		}
+
+		now := time.Now()
+		expense := &repo.Expense{
+			ExpenseID:  newExpenseID(),
+			BikeID:     bikeID,
+			Category:   req.Category,
+			Date:       date,
+			Cost:       round(req.Cost, 2),
+			Litres:     req.Litres,
+			Odometer:   req.Odometer,
+			Station:    strings.TrimSpace(req.Station),
+			Notes:      strings.TrimSpace(req.Notes),
+			RecordedBy: auth.UsernameFromContext(r.Context()),
+			CreatedAt:  now,
+		}
+		if err := repoBikes.PutExpense(r.Context(), expense); err != nil {
+			log.Printf("op=AddExpense bikeId=%s err=%v", bikeID, err)
+			http.Error(w, "failed to add expense", http.StatusInternalServerError)
+			return
+		}
+		if expense.Odometer > bike.Mileage {
+			_, _, err := repo.UpdateBike(r.Context(), repoBikes, bikeID, func(b *repo.Bike) (bool, error) {
+				if expense.Odometer <= b.Mileage {
+					return false, nil
+				}
+				b.Mileage = expense.Odometer
+				return true, nil
+			})
+			if err != nil {
+				log.Printf("op=AddExpenseBike bikeId=%s err=%v", bikeID, err)
+			}
+		}
+		writeJSON(w, http.StatusCreated, expense)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func handleDeleteExpense(w http.ResponseWriter, r *http.Request, repoBikes repo.BikesRepository, bikeID string) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		ExpenseID string `json:"expenseId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.ExpenseID) == "" {
+		http.Error(w, "expenseId is required", http.StatusBadRequest)
+		return
+	}
+	deleted, err := repoBikes.DeleteExpense(r.Context(), bikeID, req.ExpenseID)
+	if err != nil {
+		http.Error(w, "failed to delete expense", http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		http.Error(w, "expense not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"deleted": true})
+}
+
+// handleBikeCosts serves GET /api/fleet/bikes/{id}/costs → the bike's cost
+// summary (?from=&to=).
+func handleBikeCosts(w http.ResponseWriter, r *http.Request, repoBikes repo.BikesRepository, bikeID string) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	window, err := parseExpenseWindow(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	bike, ok, err := repoBikes.Get(r.Context(), bikeID)
+	if err != nil {
+		http.Error(w, "failed to get bike", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "bike not found", http.StatusNotFound)
+		return
+	}
+	expenses, err := repoBikes.ListExpenses(r.Context(), bikeID)
+	if err != nil {
+		log.Printf("op=BikeCosts bikeId=%s err=%v", bikeID, err)
+		http.Error(w, "failed to list expenses", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, summariseExpenses(bike.ID, bike.Depot, window.filter(expenses)))
+}
+
+// HandleCosts serves:
+//
+//	GET /api/fleet/costs             → per-bike and per-depot running costs (?from=&to=)
+//	GET /api/fleet/costs/export.csv  → every expense as CSV for the treasurer (?from=&to=&depot=)
+func HandleCosts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	repoBikes, err := ensureBikeRepo()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	q := r.URL.Query()
+	window, err := parseExpenseWindow(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	action := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/fleet/costs"), "/")
+	if action != "" && action != "export.csv" {
+		http.NotFound(w, r)
+		return
+	}
+	all, err := loadFleetExpenses(r.Context(), repoBikes, window)
+	if err != nil {
+		log.Printf("op=FleetCosts err=%v", err)
+		http.Error(w, "failed to load expenses", http.StatusInternalServerError)
+		return
+	}
+
+	if action == "" {
+		report := buildCostReport(all)
+		report.From, report.To = q.Get("from"), q.Get("to")
+		writeJSON(w, http.StatusOK, report)
+		return
+	}
+	writeExpensesCSV(w, all, strings.TrimSpace(q.Get("depot")), exportFileName(q))
+}
+
+var expenseCSVHeader = []string{"date", "bikeId", "registration", "depot", "category", "cost", "litres", "odometer", "station", "notes", "recordedBy", "expenseId"}
+
+// writeExpensesCSV writes one row per expense, oldest first.
+func writeExpensesCSV(w http.ResponseWriter, all []bikeExpenses, depot, fileName string) {
+	type row struct {
+		bike repo.Bike
+		e    repo.Expense
+	}
+	var rows []row
+	for _, be := range all {
+		if depot != "" && !strings.EqualFold(be.bike.Depot, depot) {
+			continue
+		}
+		for _, e := range be.expenses {
+			rows = append(rows, row{be.bike, e})
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].e.Date.Before(rows[j].e.Date) })
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	cw := csv.NewWriter(w)
+	_ = cw.Write(expenseCSVHeader)
+	for _, rw := range rows {
+		e := rw.e
+		litres, odometer := "", ""
+		if e.Litres > 0 {
+			litres = strconv.FormatFloat(e.Litres, 'f', 2, 64)
+		}
+		if e.Odometer > 0 {
+			odometer = strconv.Itoa(e.Odometer)
+		}
+		_ = cw.Write([]string{
+			e.Date.Format("2006-01-02"),
+			e.BikeID,
+			rw.bike.Registration,
+			rw.bike.Depot,
+			e.Category,
+			strconv.FormatFloat(e.Cost, 'f', 2, 64),
+			litres,
+			odometer,
+			csvSafe(e.Station),
+			csvSafe(e.Notes),
+			e.RecordedBy,
+			e.ExpenseID,
+		})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		log.Printf("op=ExportExpenses err=%v", err)
+	}
+}
+
+// csvSafe stops free text being read as a formula when the export is
+// opened in a spreadsheet.
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func exportFileName(q url.Values) string {
+	name := "fleet-expenses"
+	if from := q.Get("from"); from != "" {
+		name += "-from-" + from
+	}
+	if to := q.Get("to"); to != "" {
+		name += "-to-" + to
+	}
+	return name + ".csv"
+}
+
+// parseExpenseWindow reads ?from= and ?to= (YYYY-MM-DD, both inclusive).
+func parseExpenseWindow(q url.Values) (expenseWindow, error) {
+	var w expenseWindow
+	if v := strings.TrimSpace(q.Get("from")); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return w, errors.New("from must be YYYY-MM-DD")
+		}
+		w.from = t
+	}
+	if v := strings.TrimSpace(q.Get("to")); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return w, errors.New("to must be YYYY-MM-DD")
+		}
+		w.to = t.AddDate(0, 0, 1)
+	}
+	if !w.from.IsZero() && !w.to.IsZero() && !w.from.Before(w.to) {
+		return w, errors.New("from must not be after to")
+	}
+	return w, nil
+}
@@ -0,0 +1,221 @@
+package fleet
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo/memory"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/ridesessions"
+)
+
+func dateOf(s string) time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return t
+}
+
+func near(a, b float64) bool { return math.Abs(a-b) < 0.01 }
+
+// ---- validation ----
+
+func TestValidateExpense(t *testing.T) {
+	bad := []CreateExpenseRequest{
+		{},
+		{Category: "snacks", Cost: 3},
+		{Category: "tyres", Cost: -1},
+		{Category: "fuel", Cost: 40, Odometer: 1000},
+		{Category: "fuel", Cost: 40, Litres: 20},
+		{Category: "parts", Cost: 40, Litres: 20},
+	}
+	for _, req := range bad {
+		if err := validateExpense(&req); err == nil {
+			t.Errorf("expected %+v to fail", req)
+		}
+	}
+	req := CreateExpenseRequest{Category: " Fuel ", Cost: 40, Litres: 20, Odometer: 1000}
+	if err := validateExpense(&req); err != nil || req.Category != "fuel" {
+		t.Errorf("expected valid fuel fill-up, got %v (%q)", err, req.Category)
+	}
+}
+
+// ---- summaries ----
+
+func TestSummariseExpenses(t *testing.T) {
+	expenses := []repo.Expense{
+		{Category: "fuel", Date: dateOf("2026-01-05"), Cost: 30, Litres: 18, Odometer: 10000},
+		{Category: "fuel", Date: dateOf("2026-01-20"), Cost: 25, Litres: 15, Odometer: 10200},
+		{Category: "fuel", Date: dateOf("2026-02-03"), Cost: 26, Litres: 15, Odometer: 10400},
+		{Category: "tyres", Date: dateOf("2026-02-10"), Cost: 220},
+	}
+	s := summariseExpenses("b1", "galway", expenses)
+
+	if !near(s.TotalCost, 301) || !near(s.FuelCost, 81) || !near(s.Litres, 48) {
+		t.Errorf("unexpected totals %+v", s)
+	}
+	// 400 miles on the 30 litres bought after the first fill.
+	wantKm := 400 * ridesessions.KmPerMile
+	if !near(s.DistanceKm, math.Round(wantKm*10)/10) {
+		t.Errorf("expected %.1f km, got %v", wantKm, s.DistanceKm)
+	}
+	if !near(s.KmPerLitre, wantKm/30) || !near(s.LitresPer100Km, 30/wantKm*100) {
+		t.Errorf("unexpected economy %v km/l, %v l/100km", s.KmPerLitre, s.LitresPer100Km)
+	}
+	if math.Abs(s.CostPerKm-301/wantKm) > 0.001 {
+		t.Errorf("unexpected cost per km %v", s.CostPerKm)
+	}
+	if len(s.Monthly) != 2 || s.Monthly[0].Month != "2026-01" || !near(s.Monthly[0].Total, 55) ||
+		!near(s.Monthly[1].ByCategory["tyres"], 220) {
+		t.Errorf("unexpected monthly spend %+v", s.Monthly)
+	}
+}
+
+func TestSummariseExpenses_SingleFillHasNoEconomy(t *testing.T) {
+	s := summariseExpenses("b1", "", []repo.Expense{{Category: "fuel", Date: dateOf("2026-01-05"), Cost: 30, Litres: 18, Odometer: 10000}})
+	if s.KmPerLitre != 0 || s.CostPerKm != 0 {
+		t.Errorf("expected no economy from a single fill-up, got %+v", s)
+	}
+}
+
+func TestBuildCostReport_RollsUpByDepot(t *testing.T) {
+	report := buildCostReport([]bikeExpenses{
+		{repo.Bike{ID: "b1", Depot: "galway"}, []repo.Expense{{Category: "insurance", Date: dateOf("2026-03-01"), Cost: 400}}},
+		{repo.Bike{ID: "b2", Depot: "galway"}, []repo.Expense{{Category: "parts", Date: dateOf("2026-03-15"), Cost: 50.5}}},
+		{repo.Bike{ID: "b3", Depot: "athlone"}, nil},
+	})
+	if len(report.Bikes) != 3 || len(report.Depots) != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	g := report.Depots[1]
+	if g.Depot != "galway" || g.Bikes != 2 || !near(g.TotalCost, 450.5) || len(g.Monthly) != 1 || !near(g.Monthly[0].Total, 450.5) {
+		t.Errorf("unexpected galway roll-up %+v", g)
+	}
+}
+
+// ---- handlers ----
+
+func setupExpenses(t *testing.T) *memory.BikesRepo {
+	t.Helper()
+	bikes := memory.NewBikesRepo()
+	SetRepositories(memory.NewUsersRepo(), bikes)
+	t.Cleanup(func() { SetRepositories(nil, nil) })
+	_ = bikes.Put(context.Background(), &repo.Bike{ID: "b1", Registration: "212-G-1", Depot: "galway", Mileage: 9000})
+	return bikes
+}
+
+// bikeAction calls a bike sub-handler directly, past FleetBikeDetail's
+// FleetManager check.
+func bikeAction(h func(http.ResponseWriter, *http.Request, repo.BikesRepository, string), bikeID string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) { h(w, r, bikesRepo, bikeID) }
+}
+
+func TestExpenses_WritesAndCostsRequireFleetManager(t *testing.T) {
+	setupExpenses(t)
+	for _, path := range []string{"/api/fleet/bikes/b1/expenses", "/api/fleet/bikes/b1/expense-delete"} {
+		if rec := postJSON(t, FleetBikeDetail, path, map[string]string{"expenseId": "exp_1"}); rec.Code != http.StatusForbidden {
+			t.Errorf("%s: expected 403, got %d", path, rec.Code)
+		}
+	}
+	rec := httptest.NewRecorder()
+	FleetBikeDetail(rec, httptest.NewRequest(http.MethodGet, "/api/fleet/bikes/b1/costs", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected cost report to need FleetManager, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	FleetBikeDetail(rec, httptest.NewRequest(http.MethodGet, "/api/fleet/bikes/b1/expenses", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected riders to read expenses, got %d", rec.Code)
+	}
+}
+
+func TestExpenses_AddAdvancesOdometer(t *testing.T) {
+	bikes := setupExpenses(t)
+	rec := postJSON(t, bikeAction(handleExpenses, "b1"), "/api/fleet/bikes/b1/expenses", CreateExpenseRequest{
+		Category: "fuel", Date: "2026-04-02", Cost: 31.456, Litres: 17.2, Odometer: 9120, Station: "Applegreen Oranmore",
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var e repo.Expense
+	_ = json.Unmarshal(rec.Body.Bytes(), &e)
+	if e.Cost != 31.46 || !strings.HasPrefix(e.ExpenseID, "exp_") {
+		t.Errorf("unexpected expense %+v", e)
+	}
+	b, _, _ := bikes.Get(context.Background(), "b1")
+	if b.Mileage != 9120 {
+		t.Errorf("expected odometer advanced to 9120, got %d", b.Mileage)
+	}
+	if entries, _ := bikes.ListServiceEntries(context.Background(), "b1"); len(entries) != 0 {
+		t.Errorf("expected expenses kept out of service history, got %+v", entries)
+	}
+
+	rec = postJSON(t, bikeAction(handleExpenses, "nope"), "/api/fleet/bikes/nope/expenses", CreateExpenseRequest{Category: "tax", Cost: 120})
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected unknown bike to 404, got %d", rec.Code)
+	}
+}
+
+func TestExpenses_OdometerJumpRejected(t *testing.T) {
+	bikes := setupExpenses(t)
+	rec := postJSON(t, bikeAction(handleExpenses, "b1"), "/api/fleet/bikes/b1/expenses", CreateExpenseRequest{
+		Category: "fuel", Date: "2026-04-02", Cost: 30, Litres: 17, Odometer: 91200,
+	})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected mistyped odometer to be rejected, got %d", rec.Code)
+	}
+	if b, _, _ := bikes.Get(context.Background(), "b1"); b.Mileage != 9000 {
+		t.Errorf("expected odometer left at 9000, got %d", b.Mileage)
+	}
+}
+
+func TestExpenses_DeleteUnknownIs404(t *testing.T) {
+	bikes := setupExpenses(t)
+	_ = bikes.PutExpense(context.Background(), &repo.Expense{ExpenseID: "exp_1", BikeID: "b1", Category: "tax", Cost: 120})
+
+	if rec := postJSON(t, bikeAction(handleDeleteExpense, "b1"), "/api/fleet/bikes/b1/expense-delete", map[string]string{"expenseId": "exp_1"}); rec.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := postJSON(t, bikeAction(handleDeleteExpense, "b1"), "/api/fleet/bikes/b1/expense-delete", map[string]string{"expenseId": "exp_1"}); rec.Code != http.StatusNotFound {
+		t.Errorf("expected deleting it again to 404, got %d", rec.Code)
+	}
+}
+
+func TestHandleCosts_CSVExport(t *testing.T) {
+	bikes := setupExpenses(t)
+	ctx := context.Background()
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b2", Depot: "athlone"})
+	_ = bikes.PutExpense(ctx, &repo.Expense{ExpenseID: "exp_2", BikeID: "b1", Category: "parts", Date: dateOf("2026-05-02"), Cost: 12.5, Notes: "=HYPERLINK(\"x\")"})
+	_ = bikes.PutExpense(ctx, &repo.Expense{ExpenseID: "exp_1", BikeID: "b1", Category: "fuel", Date: dateOf("2026-05-01"), Cost: 30, Litres: 18, Odometer: 9200})
+	_ = bikes.PutExpense(ctx, &repo.Expense{ExpenseID: "exp_3", BikeID: "b2", Category: "tax", Date: dateOf("2026-05-03"), Cost: 90})
+	_ = bikes.PutExpense(ctx, &repo.Expense{ExpenseID: "exp_0", BikeID: "b1", Category: "tax", Date: dateOf("2026-04-30"), Cost: 90})
+
+	q := url.Values{"from": {"2026-05-01"}, "to": {"2026-05-31"}, "depot": {"Galway"}}
+	rec := httptest.NewRecorder()
+	HandleCosts(rec, httptest.NewRequest(http.MethodGet, "/api/fleet/costs/export.csv?"+q.Encode(), nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("expected CSV, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[1][11] != "exp_1" || rows[2][11] != "exp_2" {
+		t.Fatalf("expected header plus b1's two May rows oldest first, got %v", rows)
+	}
+	if rows[1][2] != "212-G-1" || rows[1][5] != "30.00" || rows[1][6] != "18.00" || rows[2][9] != "'=HYPERLINK(\"x\")" {
+		t.Errorf("unexpected rows %v", rows[1:])
+	}
+
+	rec = httptest.NewRecorder()
+	HandleCosts(rec, httptest.NewRequest(http.MethodGet, "/api/fleet/costs?from=2026-05-31&to=2026-05-01", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected inverted range to fail, got %d", rec.Code)
+	}
+}
@@ -6,10 +6,13 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/AdamGallagher339/Codename-Blood/backend/internal/auth"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/depots"
 	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/ridesessions"
 )
 
 func ensureBikeRepo() (repo.BikesRepository, error) {
@@ -42,28 +45,36 @@ func repoBikeToAPI(b repo.Bike) Motorcycle {
 	}
 }
 
-func apiBikeToRepo(m Motorcycle) repo.Bike {
-	return repo.Bike{
-		ID:               m.ID,
-		Model:            m.Model,
-		Depot:            m.Depot,
-		Mileage:          m.Mileage,
-		LastServiceMiles: m.LastServiceMiles,
-		LastServiceDate:  m.LastServiceDate,
-		Status:           m.Status,
-		CurrentRiderID:   m.CurrentRiderID,
-		LocationLat:      m.LocationLat,
-		LocationLng:      m.LocationLng,
-		UpdatedAt:        m.UpdatedAt,
-	}
+// applyMotorcycle copies the fields Motorcycle carries onto b.
+func applyMotorcycle(b *repo.Bike, m Motorcycle) {
+	b.Model = m.Model
+	b.Depot = m.Depot
+	b.Mileage = m.Mileage
+	b.LastServiceMiles = m.LastServiceMiles
+	b.LastServiceDate = m.LastServiceDate
+	b.Status = m.Status
+	b.CurrentRiderID = m.CurrentRiderID
+	b.LocationLat = m.LocationLat
+	b.LocationLng = m.LocationLng
+	b.UpdatedAt = m.UpdatedAt
 }
 
 func repoUserToAPI(u repo.User) User {
 	return User{RiderID: u.RiderID, Name: u.Name, Tags: u.Tags, UpdatedAt: u.UpdatedAt}
 }
 
-func apiUserToRepo(u User) repo.User {
-	return repo.User{RiderID: u.RiderID, Name: u.Name, Tags: u.Tags, UpdatedAt: u.UpdatedAt}
+// mergeUser applies the fleet fields of u onto the stored user, if there is
+// one, so fields User doesn't carry (status, depot, calendar feed key) are
+// kept.
+func mergeUser(stored *repo.User, u User) *repo.User {
+	out := repo.User{RiderID: u.RiderID}
+	if stored != nil {
+		out = *stored
+	}
+	out.Name = u.Name
+	out.Tags = u.Tags
+	out.UpdatedAt = u.UpdatedAt
+	return &out
 }
 
 func GetAllBikes(w http.ResponseWriter, r *http.Request) {
@@ -102,8 +113,23 @@ func RegisterBike(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "id required", http.StatusBadRequest)
 		return
 	}
+	if m.Depot, err = depots.Resolve(r.Context(), m.Depot); err != nil {
+		writeDepotError(w, err)
+		return
+	}
 	m.UpdatedAt = time.Now()
-	if err := repoBikes.Put(r.Context(), ptr(apiBikeToRepo(m))); err != nil {
+	// Motorcycle carries only some of the bike's fields, so re-registering
+	// an existing ID updates those and keeps the rest of the record.
+	_, ok, err := repo.UpdateBike(r.Context(), repoBikes, m.ID, func(b *repo.Bike) (bool, error) {
+		applyMotorcycle(b, m)
+		return true, nil
+	})
+	if err == nil && !ok {
+		b := &repo.Bike{ID: m.ID, CreatedAt: m.UpdatedAt}
+		applyMotorcycle(b, m)
+		err = repoBikes.Put(r.Context(), b)
+	}
+	if err != nil {
 		log.Printf("op=RegisterBike bikeId=%s err=%v", m.ID, err)
 		http.Error(w, "failed to register bike", http.StatusInternalServerError)
 		return
@@ -112,6 +138,9 @@ func RegisterBike(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(m)
 }
 
+// StartRide handles POST /api/ride/start?bikeId=&riderId=[&jobId=...].
+// The ride session, the bike's assignment and the rider's jobs under way
+// (or the jobId ones) are recorded together.
 func StartRide(w http.ResponseWriter, r *http.Request) {
 	repoBikes, err := ensureBikeRepo()
 	if err != nil {
@@ -119,8 +148,16 @@ func StartRide(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	bikeID := r.URL.Query().Get("bikeId")
-	rider := r.URL.Query().Get("riderId")
+	q := r.URL.Query()
+	bikeID := q.Get("bikeId")
+	rider := q.Get("riderId")
+	if rider == "" {
+		rider = auth.UsernameFromContext(r.Context())
+	}
+	if rider == "" {
+		http.Error(w, "riderId required", http.StatusBadRequest)
+		return
+	}
 
 	b, ok, err := repoBikes.Get(r.Context(), bikeID)
 	if err != nil {
@@ -132,49 +169,45 @@ func StartRide(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Bike not found", 404)
 		return
 	}
-
-	b.CurrentRiderID = rider
-	b.Status = "OnDuty"
-	b.UpdatedAt = time.Now()
-	if err := repoBikes.Put(r.Context(), b); err != nil {
-		log.Printf("op=StartRidePut bikeId=%s err=%v", bikeID, err)
-		http.Error(w, "failed to update bike", http.StatusInternalServerError)
+	if reason := checkoutBlockReason(*b); reason != "" {
+		http.Error(w, reason, http.StatusConflict)
 		return
 	}
 
-	json.NewEncoder(w).Encode(repoBikeToAPI(*b))
+	session, b, err := ridesessions.Start(r.Context(), ridesessions.CreateRequest{BikeID: bikeID, RiderID: rider, JobIDs: q["jobId"]})
+	if err != nil {
+		writeRideError(w, "StartRide", bikeID, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, CheckoutResult{Session: session, Bike: repoBikeToAPI(*b)})
 }
 
+// EndRide handles POST /api/ride/end?bikeId=[&odometer=]. It closes the
+// bike's open ride session and releases the bike.
 func EndRide(w http.ResponseWriter, r *http.Request) {
-	repoBikes, err := ensureBikeRepo()
-	if err != nil {
+	if _, err := ensureBikeRepo(); err != nil {
 		http.Error(w, err.Error(), http.StatusNotImplemented)
 		return
 	}
 
-	bikeID := r.URL.Query().Get("bikeId")
-
-	b, ok, err := repoBikes.Get(r.Context(), bikeID)
-	if err != nil {
-		log.Printf("op=EndRide bikeId=%s err=%v", bikeID, err)
-		http.Error(w, "failed to get bike", http.StatusInternalServerError)
-		return
-	}
-	if !ok {
-		http.Error(w, "Bike not found", 404)
-		return
+	q := r.URL.Query()
+	bikeID := q.Get("bikeId")
+	var miles int
+	if v := q.Get("odometer"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "odometer must be a non-negative whole number", http.StatusBadRequest)
+			return
+		}
+		miles = n
 	}
 
-	b.CurrentRiderID = ""
-	b.Status = "Available"
-	b.UpdatedAt = time.Now()
-	if err := repoBikes.Put(r.Context(), b); err != nil {
-		log.Printf("op=EndRidePut bikeId=%s err=%v", bikeID, err)
-		http.Error(w, "failed to update bike", http.StatusInternalServerError)
+	session, b, err := ridesessions.EndRide(r.Context(), bikeID, ridesessions.EndRequest{EndMiles: miles})
+	if err != nil {
+		writeRideError(w, "EndRide", bikeID, err)
 		return
 	}
-
-	json.NewEncoder(w).Encode(repoBikeToAPI(*b))
+	writeJSON(w, http.StatusOK, CheckoutResult{Session: session, Bike: repoBikeToAPI(*b)})
 }
 
 // --- User / Tag management ---
@@ -217,7 +250,13 @@ func RegisterUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	u.UpdatedAt = time.Now()
-	if err := repoUsers.Put(r.Context(), ptr(apiUserToRepo(u))); err != nil {
+	stored, _, err := repoUsers.Get(r.Context(), u.RiderID)
+	if err != nil {
+		log.Printf("op=RegisterUserGet riderId=%s err=%v", u.RiderID, err)
+		http.Error(w, "failed to get user", http.StatusInternalServerError)
+		return
+	}
+	if err := repoUsers.Put(r.Context(), mergeUser(stored, u)); err != nil {
 		log.Printf("op=RegisterUser riderId=%s err=%v", u.RiderID, err)
 		http.Error(w, "failed to register user", http.StatusInternalServerError)
 		return
@@ -262,7 +301,7 @@ func AddTagToUser(w http.ResponseWriter, r *http.Request) {
 		apiUser = repoUserToAPI(*u)
 	}
 	apiUser.AddTag(body.Tag)
-	if err := repoUsers.Put(r.Context(), ptr(apiUserToRepo(apiUser))); err != nil {
+	if err := repoUsers.Put(r.Context(), mergeUser(u, apiUser)); err != nil {
 		log.Printf("op=AddTagPut riderId=%s err=%v", body.RiderID, err)
 		http.Error(w, "failed to update user", http.StatusInternalServerError)
 		return
@@ -303,7 +342,7 @@ func RemoveTagFromUser(w http.ResponseWriter, r *http.Request) {
 	}
 	apiUser := repoUserToAPI(*u)
 	apiUser.RemoveTag(body.Tag)
-	if err := repoUsers.Put(r.Context(), ptr(apiUserToRepo(apiUser))); err != nil {
+	if err := repoUsers.Put(r.Context(), mergeUser(u, apiUser)); err != nil {
 		log.Printf("op=RemoveTagPut riderId=%s err=%v", body.RiderID, err)
 		http.Error(w, "failed to update user", http.StatusInternalServerError)
 		return
@@ -388,7 +427,7 @@ func InitializeUserRoles(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if err := repoUsers.Put(r.Context(), ptr(apiUserToRepo(apiUser))); err != nil {
+	if err := repoUsers.Put(r.Context(), mergeUser(u, apiUser)); err != nil {
 		log.Printf("op=InitializeUserRolesPut riderId=%s err=%v", body.RiderID, err)
 		http.Error(w, "failed to update user roles", http.StatusInternalServerError)
 		return
@@ -469,7 +508,7 @@ func handleUpdateUserRolesRESTful(w http.ResponseWriter, r *http.Request, userna
 		apiUser.AddTag(role)
 	}
 
-	if err := repoUsers.Put(r.Context(), ptr(apiUserToRepo(apiUser))); err != nil {
+	if err := repoUsers.Put(r.Context(), mergeUser(u, apiUser)); err != nil {
 		log.Printf("op=UpdateUserRolesPut riderId=%s err=%v", username, err)
 		http.Error(w, "failed to update user roles", http.StatusInternalServerError)
 		return
@@ -544,7 +583,3 @@ func isAdminRequest(r *http.Request) bool {
 	}
 	return false
 }
-
-func ptr[T any](v T) *T {
-	return &v
-}
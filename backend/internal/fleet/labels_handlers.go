@@ -0,0 +1,195 @@
+package fleet
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/ridesessions"
+)
+
+// HandleLabels serves printable QR labels (FleetManager):
+//
+//	GET /api/fleet/labels             → A4 PDF sheet of labels, 21 per page (?depot= limits it to one depot)
+//	GET /api/fleet/labels/{bikeId}    → the bike's QR code (?format=png|svg, ?scale= pixels per module)
+func HandleLabels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	repoBikes, err := ensureBikeRepo()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	if !bikeTokens.Configured() {
+		http.Error(w, errQRSecretNotConfigured, http.StatusNotImplemented)
+		return
+	}
+
+	bikeID := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/fleet/labels"), "/")
+	if bikeID != "" {
+		handleBikeQR(w, r, repoBikes, bikeID)
+		return
+	}
+
+	bikes, depotName, err := labelBikes(r.Context(), repoBikes, r.URL.Query().Get("depot"))
+	if err != nil {
+		writeDepotError(w, err)
+		return
+	}
+	if len(bikes) == 0 {
+		http.Error(w, "no bikes to print labels for", http.StatusNotFound)
+		return
+	}
+	labels := make([]bikeLabel, 0, len(bikes))
+	for _, b := range bikes {
+		l, err := newBikeLabel(b, depotName)
+		if err != nil {
+			log.Printf("op=BikeLabel bikeId=%s err=%v", b.ID, err)
+			http.Error(w, "failed to encode bike label", http.StatusInternalServerError)
+			return
+		}
+		labels = append(labels, l)
+	}
+
+	var buf bytes.Buffer
+	if err := writeLabelSheet(&buf, labels); err != nil {
+		log.Printf("op=LabelSheet err=%v", err)
+		http.Error(w, "failed to build label sheet", http.StatusInternalServerError)
+		return
+	}
+	name := "bike-labels.pdf"
+	if depotName != "" {
+		name = "bike-labels-" + labelFileSlug(depotName) + ".pdf"
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	_, _ = w.Write(buf.Bytes())
+}
+
+func handleBikeQR(w http.ResponseWriter, r *http.Request, repoBikes repo.BikesRepository, bikeID string) {
+	_, ok, err := repoBikes.Get(r.Context(), bikeID)
+	if err != nil {
+		log.Printf("op=BikeQR bikeId=%s err=%v", bikeID, err)
+		http.Error(w, "failed to get bike", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "bike not found", http.StatusNotFound)
+		return
+	}
+
+	q := r.URL.Query()
+	scale := 8
+	if s := q.Get("scale"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > 40 {
+			http.Error(w, "scale must be between 1 and 40", http.StatusBadRequest)
+			return
+		}
+		scale = n
+	}
+	code, err := bikeQR(bikeID)
+	if err != nil {
+		log.Printf("op=BikeQR bikeId=%s err=%v", bikeID, err)
+		http.Error(w, "failed to encode bike QR code", http.StatusInternalServerError)
+		return
+	}
+
+	switch format := q.Get("format"); format {
+	case "", "png":
+		raw, err := code.PNG(scale)
+		if err != nil {
+			log.Printf("op=BikeQRPNG bikeId=%s err=%v", bikeID, err)
+			http.Error(w, "failed to render bike QR code", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(raw)
+	case "svg":
+		w.Header().Set("Content-Type", "image/svg+xml")
+		_, _ = w.Write(code.SVG(scale))
+	default:
+		http.Error(w, "format must be png or svg", http.StatusBadRequest)
+	}
+}
+
+// labelFileSlug makes a depot name safe for a download file name.
+func labelFileSlug(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "-"):
+			b.WriteByte('-')
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
+
+type ScanRequest struct {
+	Token string `json:"token"`
+}
+
+// ScanResult tells the app what it scanned and whether the rider can take
+// the bike out. When CanStart is false, Reason says why.
+type ScanResult struct {
+	Bike     Motorcycle `json:"bike"`
+	CanStart bool       `json:"canStart"`
+	Reason   string     `json:"reason,omitempty"`
+}
+
+// ScanBike handles POST /api/ride/scan. The app posts the token read from
+// a bike's QR label; the token is verified and the bike is returned with
+// whether it can be checked out, before the rider starts the ride.
+func ScanBike(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	repoBikes, err := ensureBikeRepo()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	if !bikeTokens.Configured() {
+		http.Error(w, errQRSecretNotConfigured, http.StatusNotImplemented)
+		return
+	}
+
+	var req ScanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	bikeID, ok := parseBikeToken(req.Token)
+	if !ok {
+		http.Error(w, "not a valid bike code", http.StatusBadRequest)
+		return
+	}
+	b, ok, err := repoBikes.Get(r.Context(), bikeID)
+	if err != nil {
+		log.Printf("op=ScanBike bikeId=%s err=%v", bikeID, err)
+		http.Error(w, "failed to get bike", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "bike not found", http.StatusNotFound)
+		return
+	}
+
+	reason, err := ridesessions.UnavailableReason(r.Context(), *b, time.Now())
+	if err != nil {
+		log.Printf("op=ScanBike bikeId=%s err=%v", b.ID, err)
+		http.Error(w, "failed to check bike", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, ScanResult{Bike: repoBikeToAPI(*b), CanStart: reason == "", Reason: reason})
+}
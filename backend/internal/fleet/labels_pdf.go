@@ -0,0 +1,159 @@
+package fleet
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/qrcode"
+)
+
+// The label sheet is a plain PDF 1.4 document drawn with the standard
+// Helvetica fonts, so it needs no embedded fonts or images: each QR code is
+// a set of filled rectangles and prints sharp at any size.
+//
+// The layout is 3 × 7 labels of 70 × 42.4 mm filling an A4 page, which
+// matches the common 21-up self-adhesive sheets.
+const (
+	ptPerMM       = 72 / 25.4
+	pageWidth     = 210 * ptPerMM
+	pageHeight    = 297 * ptPerMM
+	labelCols     = 3
+	labelRows     = 7
+	labelsPerPage = labelCols * labelRows
+	labelWidth    = pageWidth / labelCols
+	labelHeight   = pageHeight / labelRows
+	labelMargin   = 3 * ptPerMM
+	labelQRSide   = 34 * ptPerMM
+)
+
+// writeLabelSheet writes the labels as an A4 PDF, one page per 21 labels.
+func writeLabelSheet(w io.Writer, labels []bikeLabel) error {
+	pages := max((len(labels)+labelsPerPage-1)/labelsPerPage, 1)
+	var streams []string
+	for p := 0; p < pages; p++ {
+		end := min((p+1)*labelsPerPage, len(labels))
+		streams = append(streams, labelPage(labels[p*labelsPerPage:end]))
+	}
+
+	// Objects: 1 catalog, 2 page tree, 3–4 fonts, then a page and its
+	// content stream per page.
+	pdf := &pdfWriter{}
+	pdf.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	kids := make([]string, pages)
+	for i := range kids {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	pdf.object("<< /Type /Catalog /Pages 2 0 R >>")
+	pdf.object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), pages))
+	pdf.object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	pdf.object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, s := range streams {
+		pdf.object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", pageWidth, pageHeight, 6+2*i))
+		pdf.object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(s), s))
+	}
+	pdf.finish()
+
+	_, err := w.Write(pdf.buf.Bytes())
+	return err
+}
+
+// labelPage draws up to 21 labels, filling rows left to right from the top.
+func labelPage(labels []bikeLabel) string {
+	var b strings.Builder
+	for i, l := range labels {
+		x := float64(i%labelCols) * labelWidth
+		top := pageHeight - float64(i/labelCols)*labelHeight
+		drawLabel(&b, l, x, top)
+	}
+	return b.String()
+}
+
+func drawLabel(b *strings.Builder, l bikeLabel, x, top float64) {
+	qx := x + labelMargin
+	qy := top - (labelHeight+labelQRSide)/2
+	drawQR(b, l.Code, qx, qy, labelQRSide)
+
+	tx := qx + labelQRSide + labelMargin
+	width := x + labelWidth - labelMargin - tx
+	ty := top - labelHeight/2 + 10
+	drawText(b, "F2", 11, tx, ty, fitText(l.Title, 11, width))
+	for _, line := range l.Lines {
+		ty -= 11
+		drawText(b, "F1", 8, tx, ty, fitText(line, 8, width))
+	}
+	drawText(b, "F1", 6.5, tx, top-labelHeight+labelMargin+6, fitText("Scan to start a ride", 6.5, width))
+}
+
+// drawQR fills the code's dark modules, quiet zone included, into a
+// side × side square whose bottom-left corner is (x, y).
+func drawQR(b *strings.Builder, c *qrcode.Code, x, y, side float64) {
+	module := side / float64(c.Size()+2*qrcode.QuietZone)
+	top := y + side - float64(qrcode.QuietZone)*module
+	left := x + float64(qrcode.QuietZone)*module
+	b.WriteString("0 g\n")
+	for _, r := range c.Runs() {
+		fmt.Fprintf(b, "%.3f %.3f %.3f %.3f re\n", left+float64(r.X)*module, top-float64(r.Y+1)*module, float64(r.Len)*module, module)
+	}
+	b.WriteString("f\n")
+}
+
+func drawText(b *strings.Builder, font string, size, x, y float64, s string) {
+	fmt.Fprintf(b, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdfString(s))
+}
+
+// fitText trims s to roughly fit width points at the given font size,
+// using Helvetica's average character width.
+func fitText(s string, size, width float64) string {
+	n := int(width / (size * 0.55))
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:max(n-1, 0)]) + "…"
+}
+
+// pdfString escapes s for a PDF literal string in WinAnsiEncoding. Runes
+// outside Latin-1 (other than the ellipsis) become '?'.
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '…':
+			b.WriteString(`\205`)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, `\%03o`, r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// pdfWriter numbers objects in the order they're written and builds the
+// cross-reference table.
+type pdfWriter struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+func (p *pdfWriter) object(body string) {
+	p.offsets = append(p.offsets, p.buf.Len())
+	fmt.Fprintf(&p.buf, "%d 0 obj\n%s\nendobj\n", len(p.offsets), body)
+}
+
+func (p *pdfWriter) finish() {
+	xref := p.buf.Len()
+	fmt.Fprintf(&p.buf, "xref\n0 %d\n0000000000 65535 f \n", len(p.offsets)+1)
+	for _, off := range p.offsets {
+		fmt.Fprintf(&p.buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&p.buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(p.offsets)+1, xref)
+}
@@ -0,0 +1,216 @@
+package fleet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/depots"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo/memory"
+)
+
+func setupLabels(t *testing.T) *memory.BikesRepo {
+	t.Helper()
+	t.Setenv("BIKE_QR_SECRET", "test-secret")
+	bikes := memory.NewBikesRepo()
+	SetRepositories(memory.NewUsersRepo(), bikes)
+	depots.SetRepository(memory.NewDepotsRepo())
+	depots.SetFleetRepositories(bikes, nil)
+	t.Cleanup(func() {
+		SetRepositories(nil, nil)
+		depots.SetRepository(nil)
+		depots.SetFleetRepositories(nil, nil)
+	})
+	return bikes
+}
+
+func getLabels(path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	HandleLabels(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+// ---- tokens ----
+
+func TestBikeToken_RoundTrip(t *testing.T) {
+	token := bikeToken("bike-007")
+	if !strings.HasPrefix(token, bikeTokenPrefix) {
+		t.Fatalf("unexpected token %q", token)
+	}
+	if id, ok := parseBikeToken(" " + token + "\n"); !ok || id != "bike-007" {
+		t.Errorf("expected bike-007, got %q ok=%v", id, ok)
+	}
+
+	other := bikeToken("bike-008")
+	forged := token[:strings.LastIndex(token, ".")] + other[strings.LastIndex(other, "."):]
+	for _, bad := range []string{"", "bike-007", "bike1.", "bike1.YmlrZS0wMDc", forged, token + "x", "bike2" + token[5:]} {
+		if _, ok := parseBikeToken(bad); ok {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+// ---- scan ----
+
+func TestScanBike(t *testing.T) {
+	bikes, _, _ := setupCheckout(t)
+	t.Setenv("BIKE_QR_SECRET", "test-secret")
+	ctx := context.Background()
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b1", Status: BikeStatusAvailable})
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b2", Status: BikeStatusOnDuty, CurrentRiderID: "r9"})
+
+	rec := postJSON(t, ScanBike, "/api/ride/scan", ScanRequest{Token: bikeToken("b1")})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var res ScanResult
+	_ = json.NewDecoder(rec.Body).Decode(&res)
+	if res.Bike.ID != "b1" || !res.CanStart || res.Reason != "" {
+		t.Errorf("expected b1 free to start, got %+v", res)
+	}
+
+	rec = postJSON(t, ScanBike, "/api/ride/scan", ScanRequest{Token: bikeToken("b2")})
+	res = ScanResult{}
+	_ = json.NewDecoder(rec.Body).Decode(&res)
+	if rec.Code != http.StatusOK || res.CanStart || !strings.Contains(res.Reason, "r9") {
+		t.Errorf("expected b2 blocked by r9, got %d %+v", rec.Code, res)
+	}
+
+	if rec := postJSON(t, ScanBike, "/api/ride/scan", ScanRequest{Token: "b1"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected bare bike ID to be refused, got %d", rec.Code)
+	}
+	if rec := postJSON(t, ScanBike, "/api/ride/scan", ScanRequest{Token: bikeToken("gone")}); rec.Code != http.StatusNotFound {
+		t.Errorf("expected unknown bike to 404, got %d", rec.Code)
+	}
+}
+
+func TestLabels_RefusedWithoutSecret(t *testing.T) {
+	bikes := setupLabels(t)
+	t.Setenv("BIKE_QR_SECRET", "")
+	_ = bikes.Put(context.Background(), &repo.Bike{ID: "b1"})
+
+	if rec := getLabels("/api/fleet/labels/b1"); rec.Code != http.StatusNotImplemented {
+		t.Errorf("expected labels to 501 without BIKE_QR_SECRET, got %d", rec.Code)
+	}
+	if rec := getLabels("/api/fleet/labels"); rec.Code != http.StatusNotImplemented {
+		t.Errorf("expected label sheet to 501 without BIKE_QR_SECRET, got %d", rec.Code)
+	}
+	if rec := postJSON(t, ScanBike, "/api/ride/scan", ScanRequest{Token: bikeToken("b1")}); rec.Code != http.StatusNotImplemented {
+		t.Errorf("expected scan to 501 without BIKE_QR_SECRET, got %d", rec.Code)
+	}
+}
+
+// ---- labels ----
+
+func TestHandleLabels_BikeQR(t *testing.T) {
+	bikes := setupLabels(t)
+	_ = bikes.Put(context.Background(), &repo.Bike{ID: "b1"})
+
+	rec := getLabels("/api/fleet/labels/b1?scale=2")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("expected png, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	img, err := png.Decode(rec.Body)
+	if err != nil {
+		t.Fatalf("invalid png: %v", err)
+	}
+	code, _ := bikeQR("b1")
+	if want := (code.Size() + 8) * 2; img.Bounds().Dx() != want {
+		t.Errorf("expected %dpx wide, got %d", want, img.Bounds().Dx())
+	}
+
+	rec = getLabels("/api/fleet/labels/b1?format=svg")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Body.String(), "<svg") {
+		t.Errorf("expected svg, got %d %.40q", rec.Code, rec.Body.String())
+	}
+	if rec := getLabels("/api/fleet/labels/b1?format=gif"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected unknown format to 400, got %d", rec.Code)
+	}
+	if rec := getLabels("/api/fleet/labels/b1?scale=0"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected bad scale to 400, got %d", rec.Code)
+	}
+	if rec := getLabels("/api/fleet/labels/nope"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected unknown bike to 404, got %d", rec.Code)
+	}
+}
+
+func TestHandleLabels_DepotSheet(t *testing.T) {
+	bikes := setupLabels(t)
+	ctx := context.Background()
+	if _, err := depots.Create(ctx, depots.Request{Name: "Galway City"}); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = depots.Create(ctx, depots.Request{Name: "Athlone"})
+	for i := 0; i < 23; i++ {
+		_ = bikes.Put(ctx, &repo.Bike{ID: fmt.Sprintf("g%02d", i), Depot: "galway-city", Registration: fmt.Sprintf("241-G-%d", i)})
+	}
+	_ = bikes.Put(ctx, &repo.Bike{ID: "a1", Depot: "athlone", Registration: "ATHLONE-ONLY"})
+
+	rec := getLabels("/api/fleet/labels?depot=Galway+City")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/pdf" {
+		t.Errorf("expected application/pdf, got %q", ct)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); !strings.Contains(cd, "bike-labels-galway-city.pdf") {
+		t.Errorf("unexpected Content-Disposition %q", cd)
+	}
+	pdf := rec.Body.Bytes()
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatal("not a PDF document")
+	}
+	if !bytes.Contains(pdf, []byte("/Count 2")) {
+		t.Error("expected 23 labels to take two pages")
+	}
+	if !bytes.Contains(pdf, []byte("(241-G-22)")) || bytes.Contains(pdf, []byte("ATHLONE-ONLY")) {
+		t.Error("expected only the depot's bikes on the sheet")
+	}
+	checkXref(t, pdf)
+
+	if rec := getLabels("/api/fleet/labels?depot=Nowhere"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected unknown depot to 400, got %d", rec.Code)
+	}
+	_, _ = depots.Create(ctx, depots.Request{Name: "Empty"})
+	if rec := getLabels("/api/fleet/labels?depot=empty"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected empty depot to 404, got %d", rec.Code)
+	}
+}
+
+// checkXref checks every cross-reference entry points at its object.
+func checkXref(t *testing.T, pdf []byte) {
+	t.Helper()
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	if m == nil {
+		t.Fatal("missing startxref")
+	}
+	off, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(pdf[off:], []byte("xref\n")) {
+		t.Fatal("startxref doesn't point at the xref table")
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf[off:], -1)
+	for i, e := range entries {
+		at, _ := strconv.Atoi(string(e[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(pdf[at:], []byte(want)) {
+			t.Errorf("xref entry %d points at %.12q", i+1, pdf[at:])
+		}
+	}
+}
+
+func TestPDFString(t *testing.T) {
+	if got := pdfString(`Ó Sé (Galway) \ 🏍`); got != `\323 S\351 \(Galway\) \\ ?` {
+		t.Errorf("unexpected escape %q", got)
+	}
+	if got := fitText("241-G-123456789", 10, 40); got != "241-G-…" {
+		t.Errorf("unexpected fit %q", got)
+	}
+}
@@ -0,0 +1,361 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/ridesessions"
+)
+
+// Service due statuses.
+const (
+	ServiceStatusOK      = "ok"
+	ServiceStatusDueSoon = "due_soon"
+	ServiceStatusOverdue = "overdue"
+)
+
+// defaultScheduleModel is the schedule applied to models without their own entry.
+const defaultScheduleModel = "default"
+
+// ServiceInterval says a service type is due every EveryKm kilometres or
+// EveryMonths months, whichever comes first. Either may be zero.
+type ServiceInterval struct {
+	ServiceType string `json:"serviceType"`
+	EveryKm     int    `json:"everyKm,omitempty"`
+	EveryMonths int    `json:"everyMonths,omitempty"`
+}
+
+// ServiceSchedule holds the service intervals for one bike model.
+type ServiceSchedule struct {
+	Model     string            `json:"model"`
+	Intervals []ServiceInterval `json:"intervals"`
+}
+
+// ServiceDue is the computed due state of one service type on one bike.
+// Every distance is in kilometres, converted from the miles the fleet
+// records, so they compare directly with the schedule's EveryKm.
+type ServiceDue struct {
+	BikeID          string     `json:"bikeId"`
+	Model           string     `json:"model,omitempty"`
+	Registration    string     `json:"registration,omitempty"`
+	ServiceType     string     `json:"serviceType"`
+	Status          string     `json:"status"`
+	Odometer        int        `json:"odometerKm,omitempty"`
+	LastServiceDate *time.Time `json:"lastServiceDate,omitempty"`
+	LastServiceKm   int        `json:"lastServiceKm,omitempty"`
+	DueAtKm         int        `json:"dueAtKm,omitempty"`
+	KmRemaining     *int       `json:"kmRemaining,omitempty"`
+	DueDate         *time.Time `json:"dueDate,omitempty"`
+	DaysRemaining   *int       `json:"daysRemaining,omitempty"`
+	NeverServiced   bool       `json:"neverServiced,omitempty"`
+}
+
+// bikeServiceState is everything the calculator needs to know about a bike.
+// Odometer readings, including those in History, are kilometres.
+type bikeServiceState struct {
+	BikeID       string
+	Model        string
+	Registration string
+	Odometer     int
+	// Fallback when there is no per-type history: the bike's last general service.
+	LastServiceDate time.Time
+	LastServiceKm   int
+	History         []ServiceEntry
+	// Baseline for services never done: when the bike joined the fleet and
+	// its first known odometer reading (0 when there is none).
+	CreatedAt       time.Time
+	FirstOdometerKm int
+}
+
+// maintenanceThresholds controls when a service counts as "due soon".
+type maintenanceThresholds struct {
+	SoonKm   int
+	SoonDays int
+}
+
+var defaultThresholds = maintenanceThresholds{SoonKm: 500, SoonDays: 30}
+
+// defaultSchedules is used unless SERVICE_SCHEDULES_FILE points at a JSON
+// array of ServiceSchedule.
+var defaultSchedules = []ServiceSchedule{
+	{
+		Model: defaultScheduleModel,
+		Intervals: []ServiceInterval{
+			{ServiceType: "oil", EveryKm: 10000, EveryMonths: 12},
+			{ServiceType: "chain", EveryKm: 5000, EveryMonths: 6},
+			{ServiceType: "tyres", EveryKm: 15000, EveryMonths: 24},
+			{ServiceType: "brakes", EveryKm: 20000, EveryMonths: 24},
+			{ServiceType: "coolant", EveryKm: 40000, EveryMonths: 24},
+		},
+	},
+}
+
+var (
+	schedulesOnce sync.Once
+	schedules     []ServiceSchedule
+)
+
+// ServiceSchedules returns the configured per-model service schedules.
+func ServiceSchedules() []ServiceSchedule {
+	schedulesOnce.Do(func() {
+		schedules = defaultSchedules
+		path := strings.TrimSpace(os.Getenv("SERVICE_SCHEDULES_FILE"))
+		if path == "" {
+			return
+		}
+		loaded, err := loadSchedules(path)
+		if err != nil {
+			log.Printf("op=LoadServiceSchedules path=%s err=%v (using defaults)", path, err)
+			return
+		}
+		schedules = loaded
+	})
+	return schedules
+}
+
+func loadSchedules(path string) ([]ServiceSchedule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var out []ServiceSchedule
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	for _, s := range out {
+		for _, iv := range s.Intervals {
+			if _, ok := validServiceTypes[iv.ServiceType]; !ok {
+				return nil, fmt.Errorf("model %q: invalid serviceType %q", s.Model, iv.ServiceType)
+			}
+			if iv.EveryKm <= 0 && iv.EveryMonths <= 0 {
+				return nil, fmt.Errorf("model %q: %s needs everyKm or everyMonths", s.Model, iv.ServiceType)
+			}
+		}
+	}
+	return out, nil
+}
+
+// scheduleForModel returns the schedule whose model matches case-insensitively,
+// falling back to the default schedule.
+func scheduleForModel(all []ServiceSchedule, model string) ServiceSchedule {
+	if s, ok := findSchedule(all, model); ok {
+		return s
+	}
+	s, _ := findSchedule(all, defaultScheduleModel)
+	return s
+}
+
+func findSchedule(all []ServiceSchedule, model string) (ServiceSchedule, bool) {
+	model = strings.TrimSpace(model)
+	if model == "" {
+		return ServiceSchedule{}, false
+	}
+	for _, s := range all {
+		if strings.EqualFold(strings.TrimSpace(s.Model), model) {
+			return s, true
+		}
+	}
+	return ServiceSchedule{}, false
+}
+
+// computeServiceDue works out every service type in the bike's schedule.
+// A service never done counts from the bike's baseline; only a bike with no
+// baseline is flagged due soon until it's serviced. A service recorded
+// without an odometer reading counts kilometres from the first reading.
+func computeServiceDue(bike bikeServiceState, sched ServiceSchedule, now time.Time, th maintenanceThresholds) []ServiceDue {
+	out := make([]ServiceDue, 0, len(sched.Intervals))
+	for _, iv := range sched.Intervals {
+		d := ServiceDue{
+			BikeID:       bike.BikeID,
+			Model:        bike.Model,
+			Registration: bike.Registration,
+			ServiceType:  iv.ServiceType,
+			Status:       ServiceStatusOK,
+			Odometer:     bike.Odometer,
+		}
+
+		lastDate, lastKm := lastServiceOf(bike, iv.ServiceType)
+		if lastDate.IsZero() && lastKm == 0 {
+			d.NeverServiced = true
+			if bike.CreatedAt.IsZero() {
+				d.Status = ServiceStatusDueSoon
+				out = append(out, d)
+				continue
+			}
+			lastDate = bike.CreatedAt
+		} else {
+			if !lastDate.IsZero() {
+				d.LastServiceDate = &lastDate
+			}
+			d.LastServiceKm = lastKm
+		}
+		if lastKm == 0 {
+			lastKm = bike.FirstOdometerKm
+		}
+
+		if iv.EveryKm > 0 && bike.Odometer > 0 {
+			d.DueAtKm = lastKm + iv.EveryKm
+			remaining := d.DueAtKm - bike.Odometer
+			d.KmRemaining = &remaining
+			d.Status = worseStatus(d.Status, statusFor(remaining, th.SoonKm))
+		}
+		if iv.EveryMonths > 0 && !lastDate.IsZero() {
+			due := lastDate.AddDate(0, iv.EveryMonths, 0)
+			days := int(math.Ceil(due.Sub(now).Hours() / 24))
+			d.DueDate = &due
+			d.DaysRemaining = &days
+			d.Status = worseStatus(d.Status, statusFor(days, th.SoonDays))
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// lastServiceOf returns the date and odometer of the most recent service of
+// the given type, falling back to the bike's last general service.
+func lastServiceOf(bike bikeServiceState, serviceType string) (time.Time, int) {
+	var latest *ServiceEntry
+	for i := range bike.History {
+		e := &bike.History[i]
+		if e.ServiceType != serviceType {
+			continue
+		}
+		if latest == nil || e.ServiceDate.After(latest.ServiceDate) {
+			latest = e
+		}
+	}
+	if latest != nil {
+		return latest.ServiceDate, latest.Odometer
+	}
+	return bike.LastServiceDate, bike.LastServiceKm
+}
+
+func statusFor(remaining, soon int) string {
+	switch {
+	case remaining <= 0:
+		return ServiceStatusOverdue
+	case remaining <= soon:
+		return ServiceStatusDueSoon
+	default:
+		return ServiceStatusOK
+	}
+}
+
+func worseStatus(a, b string) string {
+	if statusRank(b) > statusRank(a) {
+		return b
+	}
+	return a
+}
+
+// ComputeMaintenanceDue evaluates every known bike against its schedule,
+// using its odometer and per-type service history.
+func ComputeMaintenanceDue(ctx context.Context, now time.Time) ([]ServiceDue, error) {
+	states, err := loadBikeServiceStates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	all := ServiceSchedules()
+	var out []ServiceDue
+	for _, st := range states {
+		out = append(out, computeServiceDue(st, scheduleForModel(all, st.Model), now, defaultThresholds)...)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		ri, rj := statusRank(out[i].Status), statusRank(out[j].Status)
+		if ri != rj {
+			return ri > rj
+		}
+		if out[i].BikeID != out[j].BikeID {
+			return out[i].BikeID < out[j].BikeID
+		}
+		return out[i].ServiceType < out[j].ServiceType
+	})
+	return out, nil
+}
+
+func statusRank(s string) int {
+	switch s {
+	case ServiceStatusOverdue:
+		return 2
+	case ServiceStatusDueSoon:
+		return 1
+	}
+	return 0
+}
+
+func loadBikeServiceStates(ctx context.Context) ([]bikeServiceState, error) {
+	repoBikes, err := ensureBikeRepo()
+	if err != nil {
+		return nil, err
+	}
+	bikes, err := repoBikes.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	all := ServiceSchedules()
+	states := make([]bikeServiceState, 0, len(bikes))
+	for _, b := range bikes {
+		history, err := repoBikes.ListServiceEntries(ctx, b.ID)
+		if err != nil {
+			log.Printf("op=MaintenanceHistory bikeId=%s err=%v", b.ID, err)
+		}
+		// Schedules may be keyed on "Make Model" as well as the bare model.
+		model := strings.TrimSpace(b.Make + " " + b.Model)
+		if _, ok := findSchedule(all, model); !ok {
+			model = strings.TrimSpace(b.Model)
+		}
+		for i := range history {
+			history[i].Odometer = milesToKm(history[i].Odometer)
+		}
+		expenses, err := repoBikes.ListExpenses(ctx, b.ID)
+		if err != nil {
+			log.Printf("op=MaintenanceExpenses bikeId=%s err=%v", b.ID, err)
+		}
+		states = append(states, bikeServiceState{
+			BikeID:          b.ID,
+			Model:           model,
+			Registration:    b.Registration,
+			Odometer:        milesToKm(b.Mileage),
+			LastServiceDate: b.LastServiceDate,
+			LastServiceKm:   milesToKm(b.LastServiceMiles),
+			History:         history,
+			CreatedAt:       b.CreatedAt,
+			FirstOdometerKm: firstOdometerKm(history, expenses),
+		})
+	}
+	return states, nil
+}
+
+// firstOdometerKm returns the earliest odometer reading in the bike's
+// service history (already kilometres) and expense log (miles), or 0 when
+// there is none.
+func firstOdometerKm(history []ServiceEntry, expenses []repo.Expense) int {
+	var first time.Time
+	km := 0
+	for _, e := range history {
+		if e.Odometer > 0 && (first.IsZero() || e.ServiceDate.Before(first)) {
+			first, km = e.ServiceDate, e.Odometer
+		}
+	}
+	for _, e := range expenses {
+		if e.Odometer > 0 && (first.IsZero() || e.Date.Before(first)) {
+			first, km = e.Date, milesToKm(e.Odometer)
+		}
+	}
+	return km
+}
+
+// milesToKm converts an odometer reading; the fleet records miles but
+// service intervals are kilometres.
+func milesToKm(miles int) int {
+	return int(math.Round(float64(miles) * ridesessions.KmPerMile))
+}
@@ -0,0 +1,156 @@
+package fleet
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/orgtime"
+)
+
+// HandleMaintenance serves:
+//
+//	GET /api/fleet/maintenance/due        → services due soon or overdue (?all=true for every service)
+//	GET /api/fleet/maintenance/schedules  → configured per-model service intervals
+func HandleMaintenance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	action := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/fleet/maintenance"), "/")
+	switch action {
+	case "due":
+		due, err := ComputeMaintenanceDue(r.Context(), time.Now())
+		if err != nil {
+			log.Printf("op=MaintenanceDue err=%v", err)
+			http.Error(w, "failed to compute maintenance schedule", http.StatusInternalServerError)
+			return
+		}
+		if r.URL.Query().Get("all") != "true" {
+			due = filterDue(due)
+		}
+		if due == nil {
+			due = []ServiceDue{}
+		}
+		writeJSON(w, http.StatusOK, due)
+	case "schedules":
+		writeJSON(w, http.StatusOK, ServiceSchedules())
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func filterDue(all []ServiceDue) []ServiceDue {
+	var out []ServiceDue
+	for _, d := range all {
+		if d.Status != ServiceStatusOK {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// Notifier delivers a fleet reminder to fleet managers.
+type Notifier func(title, body, url string)
+
+// StartMaintenanceReminders runs a daily check (at MAINTENANCE_REMINDER_HOUR
+// in the org time zone, default 08:00) and notifies fleet managers about
+// bikes coming due.
+// Call once at server startup.
+func StartMaintenanceReminders(ctx context.Context, notify Notifier) {
+	runDaily(ctx, "maintenance", func() { sendMaintenanceReminder(ctx, notify) })
+}
+
+// reminderHour is the hour, in the org time zone, at which the daily fleet
+// reminders run.
+func reminderHour() int {
+	if v, err := strconv.Atoi(strings.TrimSpace(os.Getenv("MAINTENANCE_REMINDER_HOUR"))); err == nil && v >= 0 && v < 24 {
+		return v
+	}
+	return 8
+}
+
+func runDaily(ctx context.Context, name string, fn func()) {
+	hour := reminderHour()
+	go func() {
+		for {
+			wait := time.Until(nextRunAt(time.Now(), hour))
+			select {
+			case <-time.After(wait):
+				fn()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	log.Printf("[fleet] %s reminders scheduled daily at %02d:00 %s", name, hour, orgtime.Location())
+}
+
+// nextRunAt returns the next time the org zone's clock reaches the given
+// hour strictly after now.
+func nextRunAt(now time.Time, hour int) time.Time {
+	local := now.In(orgtime.Location())
+	next := orgtime.At(local.Year(), local.Month(), local.Day(), time.Duration(hour)*time.Hour)
+	if !next.After(now) {
+		next = orgtime.At(local.Year(), local.Month(), local.Day()+1, time.Duration(hour)*time.Hour)
+	}
+	return next
+}
+
+func sendMaintenanceReminder(ctx context.Context, notify Notifier) {
+	due, err := ComputeMaintenanceDue(ctx, time.Now())
+	if err != nil {
+		log.Printf("op=MaintenanceReminder err=%v", err)
+		return
+	}
+	title, body, ok := maintenanceSummary(due)
+	if !ok {
+		return
+	}
+	log.Printf("op=MaintenanceReminder %s", body)
+	if notify != nil {
+		notify(title, body, "/fleet")
+	}
+}
+
+// maintenanceSummary builds a one-line notification; ok is false when
+// nothing is due.
+func maintenanceSummary(due []ServiceDue) (title, body string, ok bool) {
+	overdue := map[string]bool{}
+	soon := map[string]bool{}
+	for _, d := range due {
+		switch d.Status {
+		case ServiceStatusOverdue:
+			overdue[d.BikeID] = true
+		case ServiceStatusDueSoon:
+			soon[d.BikeID] = true
+		}
+	}
+	for id := range overdue {
+		delete(soon, id)
+	}
+	if len(overdue) == 0 && len(soon) == 0 {
+		return "", "", false
+	}
+	var parts []string
+	if n := len(overdue); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d %s overdue", n, plural(n, "bike", "bikes")))
+	}
+	if n := len(soon); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d %s due soon", n, plural(n, "bike", "bikes")))
+	}
+	return "🔧 Bike Service Due", strings.Join(parts, ", "), true
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
@@ -0,0 +1,222 @@
+package fleet
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo/memory"
+)
+
+var maintNow = time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC)
+
+func oilSchedule() ServiceSchedule {
+	return ServiceSchedule{
+		Model:     "default",
+		Intervals: []ServiceInterval{{ServiceType: "oil", EveryKm: 10000, EveryMonths: 12}},
+	}
+}
+
+// ---- computeServiceDue ----
+
+func TestComputeServiceDue_NeverServiced(t *testing.T) {
+	due := computeServiceDue(bikeServiceState{BikeID: "b1", Odometer: 1200}, oilSchedule(), maintNow, defaultThresholds)
+	if len(due) != 1 || !due[0].NeverServiced || due[0].Status != ServiceStatusDueSoon {
+		t.Errorf("expected never-serviced due_soon, got %+v", due)
+	}
+}
+
+func TestComputeServiceDue_NeverServicedCountsFromBaseline(t *testing.T) {
+	fresh := bikeServiceState{BikeID: "b1", Odometer: 1200, CreatedAt: maintNow.AddDate(0, -2, 0), FirstOdometerKm: 200}
+	due := computeServiceDue(fresh, oilSchedule(), maintNow, defaultThresholds)
+	if !due[0].NeverServiced || due[0].Status != ServiceStatusOK || due[0].DueAtKm != 10200 {
+		t.Errorf("expected a new bike ok until its first interval, got %+v", due[0])
+	}
+
+	old := bikeServiceState{BikeID: "b2", Odometer: 4000, CreatedAt: maintNow.AddDate(-1, -1, 0)}
+	due = computeServiceDue(old, oilSchedule(), maintNow, defaultThresholds)
+	if !due[0].NeverServiced || due[0].Status != ServiceStatusOverdue {
+		t.Errorf("expected a bike unserviced for 13 months overdue, got %+v", due[0])
+	}
+}
+
+func TestComputeServiceDue_ServiceWithoutReadingStillChecksKm(t *testing.T) {
+	bike := bikeServiceState{
+		BikeID:          "b1",
+		Odometer:        11500,
+		FirstOdometerKm: 1000,
+		History:         []ServiceEntry{{ServiceType: "oil", ServiceDate: maintNow.AddDate(0, -1, 0)}},
+	}
+	due := computeServiceDue(bike, oilSchedule(), maintNow, defaultThresholds)
+	if due[0].Status != ServiceStatusOverdue || due[0].DueAtKm != 11000 {
+		t.Errorf("expected overdue by km from the first reading, got %+v", due[0])
+	}
+}
+
+func TestFirstOdometerKm(t *testing.T) {
+	history := []ServiceEntry{{ServiceDate: maintNow.AddDate(0, -3, 0), Odometer: 5000}}
+	expenses := []repo.Expense{
+		{Date: maintNow.AddDate(0, -6, 0)},
+		{Date: maintNow.AddDate(0, -5, 0), Odometer: 2000},
+	}
+	if got := firstOdometerKm(history, expenses); got != milesToKm(2000) {
+		t.Errorf("expected the earliest reading, got %d", got)
+	}
+	if got := firstOdometerKm(nil, nil); got != 0 {
+		t.Errorf("expected 0 with no readings, got %d", got)
+	}
+}
+
+func TestComputeServiceDue_OK(t *testing.T) {
+	bike := bikeServiceState{
+		BikeID:   "b1",
+		Odometer: 14000,
+		History:  []ServiceEntry{{ServiceType: "oil", ServiceDate: maintNow.AddDate(0, -2, 0), Odometer: 12000}},
+	}
+	due := computeServiceDue(bike, oilSchedule(), maintNow, defaultThresholds)
+	if due[0].Status != ServiceStatusOK {
+		t.Errorf("expected ok, got %s", due[0].Status)
+	}
+	if due[0].DueAtKm != 22000 || *due[0].KmRemaining != 8000 {
+		t.Errorf("unexpected km fields: dueAt=%d remaining=%d", due[0].DueAtKm, *due[0].KmRemaining)
+	}
+}
+
+func TestComputeServiceDue_OverdueByKm(t *testing.T) {
+	bike := bikeServiceState{
+		BikeID:   "b1",
+		Odometer: 23000,
+		History:  []ServiceEntry{{ServiceType: "oil", ServiceDate: maintNow.AddDate(0, -1, 0), Odometer: 12000}},
+	}
+	due := computeServiceDue(bike, oilSchedule(), maintNow, defaultThresholds)
+	if due[0].Status != ServiceStatusOverdue {
+		t.Errorf("expected overdue, got %s", due[0].Status)
+	}
+}
+
+func TestComputeServiceDue_DueSoonByDate(t *testing.T) {
+	bike := bikeServiceState{
+		BikeID:  "b1",
+		History: []ServiceEntry{{ServiceType: "oil", ServiceDate: maintNow.AddDate(0, -12, 10)}},
+	}
+	due := computeServiceDue(bike, oilSchedule(), maintNow, defaultThresholds)
+	if due[0].Status != ServiceStatusDueSoon {
+		t.Errorf("expected due_soon, got %s", due[0].Status)
+	}
+	if due[0].DaysRemaining == nil || *due[0].DaysRemaining != 10 {
+		t.Errorf("expected 10 days remaining, got %v", due[0].DaysRemaining)
+	}
+}
+
+func TestComputeServiceDue_UsesLatestEntryOfType(t *testing.T) {
+	bike := bikeServiceState{
+		BikeID: "b1",
+		History: []ServiceEntry{
+			{ServiceType: "oil", ServiceDate: maintNow.AddDate(-2, 0, 0)},
+			{ServiceType: "oil", ServiceDate: maintNow.AddDate(0, -1, 0)},
+			{ServiceType: "chain", ServiceDate: maintNow.AddDate(-3, 0, 0)},
+		},
+	}
+	due := computeServiceDue(bike, oilSchedule(), maintNow, defaultThresholds)
+	if due[0].Status != ServiceStatusOK {
+		t.Errorf("expected latest oil service to count, got %s", due[0].Status)
+	}
+}
+
+func TestComputeServiceDue_FallsBackToLastGeneralService(t *testing.T) {
+	bike := bikeServiceState{
+		BikeID:          "b1",
+		Odometer:        30000,
+		LastServiceDate: maintNow.AddDate(0, -3, 0),
+		LastServiceKm:   18000,
+	}
+	due := computeServiceDue(bike, oilSchedule(), maintNow, defaultThresholds)
+	if due[0].NeverServiced || due[0].Status != ServiceStatusOverdue {
+		t.Errorf("expected overdue from general service record, got %+v", due[0])
+	}
+}
+
+// ---- schedules ----
+
+func TestScheduleForModel_CaseInsensitiveWithDefault(t *testing.T) {
+	all := []ServiceSchedule{
+		{Model: "default", Intervals: []ServiceInterval{{ServiceType: "oil", EveryMonths: 12}}},
+		{Model: "Honda Pan European", Intervals: []ServiceInterval{{ServiceType: "oil", EveryKm: 6000}}},
+	}
+	if s := scheduleForModel(all, "honda pan european"); s.Model != "Honda Pan European" {
+		t.Errorf("expected model schedule, got %q", s.Model)
+	}
+	if s := scheduleForModel(all, "BMW R1250RT"); s.Model != "default" {
+		t.Errorf("expected default schedule, got %q", s.Model)
+	}
+}
+
+// ---- ComputeMaintenanceDue ----
+
+func TestComputeMaintenanceDue_ConvertsMilesToKm(t *testing.T) {
+	bikes := memory.NewBikesRepo()
+	SetRepositories(memory.NewUsersRepo(), bikes)
+	t.Cleanup(func() { SetRepositories(nil, nil) })
+	ctx := context.Background()
+
+	// 6000 miles since the last oil change is 9656km, within 500km of the
+	// 10000km interval. Read as kilometres it would look 4000km away.
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b1", Mileage: 12000})
+	_ = bikes.PutServiceEntry(ctx, &repo.ServiceEntry{BikeID: "b1", ServiceID: "s1", ServiceType: "oil", ServiceDate: time.Now().AddDate(0, -1, 0), Odometer: 6000})
+
+	due, err := ComputeMaintenanceDue(ctx, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range due {
+		if d.ServiceType != "oil" {
+			continue
+		}
+		if d.Odometer != 19312 || d.LastServiceKm != 9656 {
+			t.Errorf("expected readings in km, got odometer %d, last service %d", d.Odometer, d.LastServiceKm)
+		}
+		if d.Status != ServiceStatusDueSoon || d.KmRemaining == nil || *d.KmRemaining != 344 {
+			t.Errorf("expected due soon with 344km left, got %s %v", d.Status, d.KmRemaining)
+		}
+		return
+	}
+	t.Fatal("no oil service in result")
+}
+
+// ---- maintenanceSummary ----
+
+func TestMaintenanceSummary_NothingDue(t *testing.T) {
+	if _, _, ok := maintenanceSummary([]ServiceDue{{BikeID: "b1", Status: ServiceStatusOK}}); ok {
+		t.Error("expected no notification when nothing is due")
+	}
+}
+
+func TestMaintenanceSummary_CountsBikesOnce(t *testing.T) {
+	_, body, ok := maintenanceSummary([]ServiceDue{
+		{BikeID: "b1", Status: ServiceStatusOverdue},
+		{BikeID: "b1", Status: ServiceStatusDueSoon},
+		{BikeID: "b2", Status: ServiceStatusDueSoon},
+		{BikeID: "b2", Status: ServiceStatusDueSoon},
+	})
+	if !ok {
+		t.Fatal("expected notification")
+	}
+	if !strings.Contains(body, "1 bike overdue") || !strings.Contains(body, "1 bike due soon") {
+		t.Errorf("unexpected body %q", body)
+	}
+}
+
+func TestNextRunAt(t *testing.T) {
+	// maintNow is 10:00 Irish summer time.
+	if got := nextRunAt(maintNow, 8); !got.Equal(time.Date(2026, 6, 2, 7, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected tomorrow 08:00 IST, got %v", got)
+	}
+	if got := nextRunAt(maintNow, 10); !got.Equal(time.Date(2026, 6, 2, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected tomorrow 10:00 IST, got %v", got)
+	}
+	if got := nextRunAt(maintNow, 11); !got.Equal(time.Date(2026, 6, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected today 11:00 IST, got %v", got)
+	}
+}
@@ -0,0 +1,176 @@
+package fleet
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+)
+
+// BikeMerge is the outcome of folding legacy fleet tracker bikes into the
+// bike repository.
+type BikeMerge struct {
+	// Bikes are the new or updated bikes to write.
+	Bikes []repo.Bike
+	// IDMap maps each legacy tracker BikeID to the bike it was merged into.
+	IDMap   map[string]string
+	Created int
+	Merged  int
+}
+
+// MergeLegacyBikes matches each legacy tracker bike to an existing bike by
+// ID or registration (an existing bike whose ID is the registration also
+// counts) and fills in whatever the existing bike is missing. Status,
+// odometer and rider assignment on existing bikes win; unmatched legacy
+// bikes become new bikes under their tracker ID.
+func MergeLegacyBikes(existing []repo.Bike, legacy []FleetBike) BikeMerge {
+	result := BikeMerge{IDMap: make(map[string]string, len(legacy))}
+
+	byID := make(map[string]*repo.Bike, len(existing))
+	byReg := make(map[string]*repo.Bike, len(existing))
+	for i := range existing {
+		b := &existing[i]
+		byID[b.ID] = b
+		if reg := normaliseRegistration(b.Registration); reg != "" {
+			byReg[reg] = b
+		}
+		if reg := normaliseRegistration(b.ID); reg != "" {
+			if _, taken := byReg[reg]; !taken {
+				byReg[reg] = b
+			}
+		}
+	}
+
+	touched := make(map[string]*repo.Bike)
+	var order []string
+	for _, lb := range legacy {
+		target := byID[lb.BikeID]
+		if target == nil {
+			target = byReg[normaliseRegistration(lb.Registration)]
+		}
+		if target == nil {
+			nb := repo.Bike{ID: lb.BikeID, CreatedAt: lb.CreatedAt}
+			applyActive(&nb, lb.Active)
+			target = &nb
+			byID[nb.ID] = target
+			if reg := normaliseRegistration(lb.Registration); reg != "" {
+				byReg[reg] = target
+			}
+			result.Created++
+		} else {
+			result.Merged++
+		}
+		fillFromLegacy(target, lb)
+		result.IDMap[lb.BikeID] = target.ID
+		if _, seen := touched[target.ID]; !seen {
+			order = append(order, target.ID)
+		}
+		touched[target.ID] = target
+	}
+
+	for _, id := range order {
+		result.Bikes = append(result.Bikes, *touched[id])
+	}
+	return result
+}
+
+func fillFromLegacy(b *repo.Bike, lb FleetBike) {
+	if b.Make == "" {
+		b.Make = strings.TrimSpace(lb.Make)
+	}
+	if b.Model == "" {
+		b.Model = strings.TrimSpace(lb.Model)
+	}
+	if b.VehicleType == "" {
+		b.VehicleType = strings.ToLower(strings.TrimSpace(lb.VehicleType))
+	}
+	if b.Registration == "" {
+		b.Registration = strings.TrimSpace(lb.Registration)
+	}
+	if b.Depot == "" {
+		b.Depot = strings.TrimSpace(lb.LocationID)
+	}
+	if b.Status == "" {
+		applyActive(b, lb.Active)
+	}
+	if b.CreatedAt.IsZero() || (!lb.CreatedAt.IsZero() && lb.CreatedAt.Before(b.CreatedAt)) {
+		b.CreatedAt = lb.CreatedAt
+	}
+	if lb.UpdatedAt.After(b.UpdatedAt) {
+		b.UpdatedAt = lb.UpdatedAt
+	}
+}
+
+// normaliseRegistration upper-cases and strips spaces and dashes so
+// "212-G-1234" and "212 g 1234" compare equal.
+func normaliseRegistration(reg string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(reg)))
+}
+
+// MigrationReport summarises a legacy tracker migration.
+type MigrationReport struct {
+	LegacyBikes     int
+	Created         int
+	Merged          int
+	ServiceEntries  int
+	RemappedEntries int
+}
+
+// MigrateLegacyTracker copies every bike and service entry from the legacy
+// tracker tables into bikes. With dryRun nothing is written. Legacy items
+// are never deleted.
+func MigrateLegacyTracker(ctx context.Context, legacy *TrackerStore, bikes repo.BikesRepository, dryRun bool) (MigrationReport, error) {
+	var report MigrationReport
+
+	legacyBikes, err := legacy.ListBikes(ctx)
+	if err != nil {
+		return report, fmt.Errorf("list legacy bikes: %w", err)
+	}
+	existing, err := bikes.List(ctx)
+	if err != nil {
+		return report, fmt.Errorf("list bikes: %w", err)
+	}
+
+	merge := MergeLegacyBikes(existing, legacyBikes)
+	report.LegacyBikes = len(legacyBikes)
+	report.Created = merge.Created
+	report.Merged = merge.Merged
+
+	merged := make(map[string]*repo.Bike, len(merge.Bikes))
+	for i := range merge.Bikes {
+		merged[merge.Bikes[i].ID] = &merge.Bikes[i]
+	}
+
+	for _, lb := range legacyBikes {
+		entries, err := legacy.ListServiceEntries(ctx, lb.BikeID)
+		if err != nil {
+			return report, fmt.Errorf("list service history for %s: %w", lb.BikeID, err)
+		}
+		targetID := merge.IDMap[lb.BikeID]
+		for _, e := range entries {
+			report.ServiceEntries++
+			if targetID != e.BikeID {
+				report.RemappedEntries++
+			}
+			e.BikeID = targetID
+			recordServiceOnBike(merged[targetID], e)
+			if dryRun {
+				continue
+			}
+			if err := bikes.PutServiceEntry(ctx, &e); err != nil {
+				return report, fmt.Errorf("put service entry %s: %w", e.ServiceID, err)
+			}
+		}
+	}
+
+	if dryRun {
+		return report, nil
+	}
+	for i := range merge.Bikes {
+		if err := bikes.Put(ctx, &merge.Bikes[i]); err != nil {
+			return report, fmt.Errorf("put bike %s: %w", merge.Bikes[i].ID, err)
+		}
+	}
+	return report, nil
+}
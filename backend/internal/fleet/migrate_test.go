@@ -0,0 +1,97 @@
+package fleet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+)
+
+// ---- fleetBikeFromRepo / applyActive ----
+
+func TestFleetBikeFromRepo_Active(t *testing.T) {
+	cases := []struct {
+		bike repo.Bike
+		want string
+	}{
+		{repo.Bike{ID: "b1", Status: BikeStatusAvailable}, "ready"},
+		{repo.Bike{ID: "b1", Status: BikeStatusInService}, "out_of_service"},
+		{repo.Bike{ID: "b1", Status: BikeStatusOnDuty, CurrentRiderID: "rider-1"}, "rider-1"},
+	}
+	for _, c := range cases {
+		if got := fleetBikeFromRepo(c.bike).Active; got != c.want {
+			t.Errorf("status %s: expected active %q, got %q", c.bike.Status, c.want, got)
+		}
+	}
+}
+
+func TestApplyActive_RoundTrip(t *testing.T) {
+	for _, active := range []string{"ready", "out_of_service", "rider-1"} {
+		var b repo.Bike
+		applyActive(&b, active)
+		if got := fleetBikeFromRepo(b).Active; got != active {
+			t.Errorf("expected %q to round-trip, got %q", active, got)
+		}
+	}
+}
+
+// ---- MergeLegacyBikes ----
+
+func TestMergeLegacyBikes_MatchByRegistration(t *testing.T) {
+	existing := []repo.Bike{{ID: "BB21-WES", Model: "Pan European", Status: BikeStatusOnDuty, CurrentRiderID: "r1", Mileage: 42000, Registration: "212-G-1234"}}
+	legacy := []FleetBike{{BikeID: "bike_abc", Make: "Honda", Model: "ST1300", VehicleType: "motorcycle", Registration: "212 g 1234", LocationID: "galway", Active: "ready"}}
+
+	m := MergeLegacyBikes(existing, legacy)
+	if m.Merged != 1 || m.Created != 0 {
+		t.Fatalf("expected 1 merge, got merged=%d created=%d", m.Merged, m.Created)
+	}
+	if m.IDMap["bike_abc"] != "BB21-WES" {
+		t.Errorf("expected legacy ID mapped to BB21-WES, got %q", m.IDMap["bike_abc"])
+	}
+	b := m.Bikes[0]
+	if b.Model != "Pan European" || b.Make != "Honda" || b.Depot != "galway" {
+		t.Errorf("expected existing fields kept and blanks filled, got %+v", b)
+	}
+	if b.Status != BikeStatusOnDuty || b.CurrentRiderID != "r1" || b.Mileage != 42000 {
+		t.Errorf("expected live status and assignment kept, got %+v", b)
+	}
+}
+
+func TestMergeLegacyBikes_MatchByIDAsRegistration(t *testing.T) {
+	existing := []repo.Bike{{ID: "212-G-1234"}}
+	legacy := []FleetBike{{BikeID: "bike_abc", Registration: "212G1234", Active: "out_of_service"}}
+	m := MergeLegacyBikes(existing, legacy)
+	if m.IDMap["bike_abc"] != "212-G-1234" {
+		t.Errorf("expected match on ID, got %q", m.IDMap["bike_abc"])
+	}
+	if m.Bikes[0].Status != BikeStatusOutOfService {
+		t.Errorf("expected status from legacy active, got %q", m.Bikes[0].Status)
+	}
+}
+
+func TestMergeLegacyBikes_CreatesUnmatched(t *testing.T) {
+	created := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	legacy := []FleetBike{{BikeID: "bike_new", Model: "R1250RT", Registration: "241-D-1", Active: "rider-7", CreatedAt: created}}
+	m := MergeLegacyBikes(nil, legacy)
+	if m.Created != 1 || len(m.Bikes) != 1 {
+		t.Fatalf("expected one new bike, got %+v", m)
+	}
+	b := m.Bikes[0]
+	if b.ID != "bike_new" || b.CurrentRiderID != "rider-7" || b.Status != BikeStatusOnDuty || !b.CreatedAt.Equal(created) {
+		t.Errorf("unexpected new bike %+v", b)
+	}
+}
+
+func TestRecordServiceOnBike(t *testing.T) {
+	b := repo.Bike{Mileage: 1000}
+	d := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	if !recordServiceOnBike(&b, ServiceEntry{ServiceDate: d, Odometer: 1500}) {
+		t.Fatal("expected bike to change")
+	}
+	if !b.LastServiceDate.Equal(d) || b.LastServiceMiles != 1500 || b.Mileage != 1500 {
+		t.Errorf("unexpected bike %+v", b)
+	}
+	if recordServiceOnBike(&b, ServiceEntry{ServiceDate: d.AddDate(0, -1, 0), Odometer: 900}) {
+		t.Error("expected an older entry not to change the bike")
+	}
+}
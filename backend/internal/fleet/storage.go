@@ -8,6 +8,7 @@ import (
 
 var usersRepo repo.UsersRepository
 var bikesRepo repo.BikesRepository
+var rideSessionsRepo repo.RideSessionsRepository
 
 // CognitoGroupManager allows the fleet package to sync roles to Cognito groups.
 // It is intentionally tiny so we can set it from main without importing auth here.
@@ -23,6 +24,12 @@ func SetRepositories(users repo.UsersRepository, bikes repo.BikesRepository) {
 	bikesRepo = bikes
 }
 
+// SetRideSessionsRepository wires the store for the ride session opened
+// by each bike checkout.
+func SetRideSessionsRepository(sessions repo.RideSessionsRepository) {
+	rideSessionsRepo = sessions
+}
+
 func SetCognitoGroupManager(mgr CognitoGroupManager) {
 	cognitoGroups = mgr
 }
@@ -4,34 +4,39 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"log"
 	"net/http"
 	"regexp"
 	"strings"
 	"time"
-)
 
-var trackerStore *TrackerStore
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/auth"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/depots"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+)
 
 var activeUIDRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
 
-func SetTrackerStore(store *TrackerStore) {
-	trackerStore = store
-}
-
 func FleetListOrCreate(w http.ResponseWriter, r *http.Request) {
-	if trackerStore == nil {
-		http.Error(w, "fleet tracker not configured", http.StatusNotImplemented)
+	repoBikes, err := ensureBikeRepo()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
 		return
 	}
 
 	switch r.Method {
 	case http.MethodGet:
-		bikes, err := trackerStore.ListBikes(r.Context())
+		bikes, err := repoBikes.List(r.Context())
 		if err != nil {
+			log.Printf("op=FleetListBikes err=%v", err)
 			http.Error(w, "failed to list bikes", http.StatusInternalServerError)
 			return
 		}
-		writeJSON(w, http.StatusOK, bikes)
+		out := make([]FleetBike, 0, len(bikes))
+		for _, b := range bikes {
+			out = append(out, fleetBikeFromRepo(b))
+		}
+		writeJSON(w, http.StatusOK, out)
 	case http.MethodPost:
 		var req CreateFleetBikeRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -44,46 +49,44 @@ func FleetListOrCreate(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		if trackerStore != nil {
-			if err := ensureUniqueRegistration(r.Context(), req.Registration, bikeID); err != nil {
-				http.Error(w, err.Error(), http.StatusBadRequest)
-				return
-			}
+		if err := ensureUniqueRegistration(r.Context(), req.Registration, bikeID); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		depot, err := depots.Resolve(r.Context(), req.LocationID)
+		if err != nil {
+			writeDepotError(w, err)
+			return
 		}
-
-		make := strings.TrimSpace(req.Make)
-		model := strings.TrimSpace(req.Model)
-		vehicleType := strings.ToLower(strings.TrimSpace(req.VehicleType))
-		registration := strings.TrimSpace(req.Registration)
-		locationID := strings.TrimSpace(req.LocationID)
-		active := "out_of_service" // new vehicles always start out of service
 
 		now := time.Now()
-		bike := &FleetBike{
-			BikeID:       bikeID,
-			Make:         make,
-			Model:        model,
-			VehicleType:  vehicleType,
-			Registration: registration,
-			LocationID:   locationID,
-			Active:       active,
+		bike := &repo.Bike{
+			ID:           bikeID,
+			Make:         strings.TrimSpace(req.Make),
+			Model:        strings.TrimSpace(req.Model),
+			VehicleType:  strings.ToLower(strings.TrimSpace(req.VehicleType)),
+			Registration: strings.TrimSpace(req.Registration),
+			Depot:        depot,
+			Status:       BikeStatusOutOfService, // new vehicles always start out of service
 			CreatedAt:    now,
 			UpdatedAt:    now,
 		}
 
-		if err := trackerStore.PutBike(r.Context(), bike); err != nil {
+		if err := repoBikes.Put(r.Context(), bike); err != nil {
+			log.Printf("op=FleetCreateBike bikeId=%s err=%v", bikeID, err)
 			http.Error(w, "failed to save bike", http.StatusInternalServerError)
 			return
 		}
-		writeJSON(w, http.StatusCreated, bike)
+		writeJSON(w, http.StatusCreated, fleetBikeFromRepo(*bike))
 	default:
 		w.WriteHeader(http.StatusMethodNotAllowed)
 	}
 }
 
 func FleetBikeDetail(w http.ResponseWriter, r *http.Request) {
-	if trackerStore == nil {
-		http.Error(w, "fleet tracker not configured", http.StatusNotImplemented)
+	repoBikes, err := ensureBikeRepo()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
 		return
 	}
 
@@ -94,28 +97,52 @@ func FleetBikeDetail(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if action == "service" {
-		handleServiceHistory(w, r, bikeID)
+		handleServiceHistory(w, r, repoBikes, bikeID)
 		return
 	}
 
 	if action == "delete" {
-		handleDeleteBike(w, r, bikeID)
+		handleDeleteBike(w, r, repoBikes, bikeID)
 		return
 	}
 
 	if action == "service-delete" {
-		handleDeleteServiceEntry(w, r, bikeID)
+		handleDeleteServiceEntry(w, r, repoBikes, bikeID)
 		return
 	}
 
 	if action == "change-location" {
-		handleChangeLocation(w, r, bikeID)
+		handleChangeLocation(w, r, repoBikes, bikeID)
+		return
+	}
+
+	// Riders can read a bike's expenses; logging or deleting them and the
+	// cost report need FleetManager, as /api/fleet/costs does.
+	if action == "expenses" || action == "expense-delete" || action == "costs" {
+		if (action != "expenses" || r.Method != http.MethodGet) && !auth.HasRoleOrAbove(auth.RolesFromContext(r.Context()), "FleetManager") {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+	}
+
+	if action == "expenses" {
+		handleExpenses(w, r, repoBikes, bikeID)
+		return
+	}
+
+	if action == "expense-delete" {
+		handleDeleteExpense(w, r, repoBikes, bikeID)
+		return
+	}
+
+	if action == "costs" {
+		handleBikeCosts(w, r, repoBikes, bikeID)
 		return
 	}
 
 	switch r.Method {
 	case http.MethodGet:
-		bike, ok, err := trackerStore.GetBike(r.Context(), bikeID)
+		bike, ok, err := repoBikes.Get(r.Context(), bikeID)
 		if err != nil {
 			http.Error(w, "failed to get bike", http.StatusInternalServerError)
 			return
@@ -124,7 +151,7 @@ func FleetBikeDetail(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "bike not found", http.StatusNotFound)
 			return
 		}
-		writeJSON(w, http.StatusOK, bike)
+		writeJSON(w, http.StatusOK, fleetBikeFromRepo(*bike))
 	case http.MethodPatch, http.MethodPut:
 		var req UpdateFleetBikeRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -132,56 +159,99 @@ func FleetBikeDetail(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		bike, ok, err := trackerStore.GetBike(r.Context(), bikeID)
-		if err != nil {
-			http.Error(w, "failed to get bike", http.StatusInternalServerError)
-			return
-		}
-		if !ok {
-			http.Error(w, "bike not found", http.StatusNotFound)
-			return
-		}
-
 		// Make, Model, VehicleType, Registration, and LocationID are immutable after creation
+		var active string
 		if req.Active != nil {
-			active := strings.TrimSpace(*req.Active)
+			active = strings.TrimSpace(*req.Active)
 			if active != "ready" && active != "out_of_service" {
 				http.Error(w, "active must be ready or out_of_service", http.StatusBadRequest)
 				return
 			}
-			bike.Active = active
 		}
-		if err := validateBike(*bike, false); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+
+		var invalid error
+		bike, ok, err := repo.UpdateBike(r.Context(), repoBikes, bikeID, func(b *repo.Bike) (bool, error) {
+			if active != "" {
+				applyActive(b, active)
+			}
+			invalid = validateBike(fleetBikeFromRepo(*b), false)
+			return invalid == nil, nil
+		})
+		if invalid != nil {
+			http.Error(w, invalid.Error(), http.StatusBadRequest)
 			return
 		}
-
-		bike.UpdatedAt = time.Now()
-		if err := trackerStore.PutBike(r.Context(), bike); err != nil {
+		if err != nil {
 			http.Error(w, "failed to update bike", http.StatusInternalServerError)
 			return
 		}
-		writeJSON(w, http.StatusOK, bike)
+		if !ok {
+			http.Error(w, "bike not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, fleetBikeFromRepo(*bike))
 	case http.MethodDelete:
-		handleDeleteBike(w, r, bikeID)
+		handleDeleteBike(w, r, repoBikes, bikeID)
 	default:
 		w.WriteHeader(http.StatusMethodNotAllowed)
 	}
 }
 
-func handleDeleteBike(w http.ResponseWriter, r *http.Request, bikeID string) {
+func handleDeleteBike(w http.ResponseWriter, r *http.Request, repoBikes repo.BikesRepository, bikeID string) {
 	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	if err := trackerStore.DeleteBike(r.Context(), bikeID); err != nil {
+	if err := deleteBikeRecords(r.Context(), repoBikes, bikeID); err != nil {
+		log.Printf("op=DeleteBikeRecords bikeId=%s err=%v", bikeID, err)
+		http.Error(w, "failed to delete bike", http.StatusInternalServerError)
+		return
+	}
+	if _, err := repoBikes.Delete(r.Context(), bikeID); err != nil {
 		http.Error(w, "failed to delete bike", http.StatusInternalServerError)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]bool{"deleted": true})
 }
 
-func handleDeleteServiceEntry(w http.ResponseWriter, r *http.Request, bikeID string) {
+// deleteBikeRecords removes a bike's service history, expenses and
+// documents. It runs before the bike itself is deleted, so a failure
+// leaves the bike in place to retry rather than records nobody can reach.
+func deleteBikeRecords(ctx context.Context, repoBikes repo.BikesRepository, bikeID string) error {
+	entries, err := repoBikes.ListServiceEntries(ctx, bikeID)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if _, err := repoBikes.DeleteServiceEntry(ctx, bikeID, e.ServiceID); err != nil {
+			return err
+		}
+	}
+	expenses, err := repoBikes.ListExpenses(ctx, bikeID)
+	if err != nil {
+		return err
+	}
+	for _, e := range expenses {
+		if _, err := repoBikes.DeleteExpense(ctx, bikeID, e.ExpenseID); err != nil {
+			return err
+		}
+	}
+	if documentsRepo == nil {
+		return nil
+	}
+	docs, err := documentsRepo.ListByBike(ctx, bikeID)
+	if err != nil {
+		return err
+	}
+	for _, d := range docs {
+		if _, err := documentsRepo.Delete(ctx, d.DocumentID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func handleDeleteServiceEntry(w http.ResponseWriter, r *http.Request, repoBikes repo.BikesRepository, bikeID string) {
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
@@ -193,14 +263,14 @@ func handleDeleteServiceEntry(w http.ResponseWriter, r *http.Request, bikeID str
 		http.Error(w, "serviceId is required", http.StatusBadRequest)
 		return
 	}
-	if err := trackerStore.DeleteServiceEntry(r.Context(), bikeID, req.ServiceID); err != nil {
+	if _, err := repoBikes.DeleteServiceEntry(r.Context(), bikeID, req.ServiceID); err != nil {
 		http.Error(w, "failed to delete service entry", http.StatusInternalServerError)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]bool{"deleted": true})
 }
 
-func handleChangeLocation(w http.ResponseWriter, r *http.Request, bikeID string) {
+func handleChangeLocation(w http.ResponseWriter, r *http.Request, repoBikes repo.BikesRepository, bikeID string) {
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
@@ -213,32 +283,33 @@ func handleChangeLocation(w http.ResponseWriter, r *http.Request, bikeID string)
 		http.Error(w, "locationId is required", http.StatusBadRequest)
 		return
 	}
+	depot, err := depots.Resolve(r.Context(), req.LocationID)
+	if err != nil {
+		writeDepotError(w, err)
+		return
+	}
 
-	bike, ok, err := trackerStore.GetBike(r.Context(), bikeID)
+	bike, ok, err := repo.UpdateBike(r.Context(), repoBikes, bikeID, func(b *repo.Bike) (bool, error) {
+		b.Depot = depot
+		return true, nil
+	})
 	if err != nil {
-		http.Error(w, "failed to get bike", http.StatusInternalServerError)
+		http.Error(w, "failed to update bike", http.StatusInternalServerError)
 		return
 	}
 	if !ok {
 		http.Error(w, "bike not found", http.StatusNotFound)
 		return
 	}
-
-	bike.LocationID = strings.TrimSpace(req.LocationID)
-	bike.UpdatedAt = time.Now()
-
-	if err := trackerStore.PutBike(r.Context(), bike); err != nil {
-		http.Error(w, "failed to update bike", http.StatusInternalServerError)
-		return
-	}
-	writeJSON(w, http.StatusOK, bike)
+	writeJSON(w, http.StatusOK, fleetBikeFromRepo(*bike))
 }
 
-func handleServiceHistory(w http.ResponseWriter, r *http.Request, bikeID string) {
+func handleServiceHistory(w http.ResponseWriter, r *http.Request, repoBikes repo.BikesRepository, bikeID string) {
 	switch r.Method {
 	case http.MethodGet:
-		entries, err := trackerStore.ListServiceEntries(r.Context(), bikeID)
+		entries, err := repoBikes.ListServiceEntries(r.Context(), bikeID)
 		if err != nil {
+			log.Printf("op=ListServiceEntries bikeId=%s err=%v", bikeID, err)
 			http.Error(w, "failed to list service history", http.StatusInternalServerError)
 			return
 		}
@@ -260,27 +331,63 @@ func handleServiceHistory(w http.ResponseWriter, r *http.Request, bikeID string)
 			return
 		}
 
+		_, ok, err := repoBikes.Get(r.Context(), bikeID)
+		if err != nil {
+			http.Error(w, "failed to get bike", http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			http.Error(w, "bike not found", http.StatusNotFound)
+			return
+		}
+
 		now := time.Now()
 		entry := &ServiceEntry{
 			ServiceID:   newServiceID(),
 			BikeID:      bikeID,
 			ServiceType: req.ServiceType,
 			ServiceDate: serviceDate,
+			Odometer:    req.Odometer,
 			Notes:       strings.TrimSpace(req.Notes),
 			PerformedBy: strings.TrimSpace(req.PerformedBy),
 			CreatedAt:   now,
 		}
 
-		if err := trackerStore.AddServiceEntry(r.Context(), entry); err != nil {
+		if err := repoBikes.PutServiceEntry(r.Context(), entry); err != nil {
+			log.Printf("op=AddServiceEntry bikeId=%s err=%v", bikeID, err)
 			http.Error(w, "failed to add service entry", http.StatusInternalServerError)
 			return
 		}
+		_, _, err = repo.UpdateBike(r.Context(), repoBikes, bikeID, func(b *repo.Bike) (bool, error) {
+			return recordServiceOnBike(b, *entry), nil
+		})
+		if err != nil {
+			log.Printf("op=AddServiceEntryBike bikeId=%s err=%v", bikeID, err)
+		}
 		writeJSON(w, http.StatusCreated, entry)
 	default:
 		w.WriteHeader(http.StatusMethodNotAllowed)
 	}
 }
 
+// recordServiceOnBike keeps the bike's last-service and odometer fields in
+// step with its history. It reports whether the bike changed.
+func recordServiceOnBike(b *repo.Bike, e ServiceEntry) bool {
+	changed := false
+	if e.ServiceDate.After(b.LastServiceDate) {
+		b.LastServiceDate = e.ServiceDate
+		if e.Odometer > 0 {
+			b.LastServiceMiles = e.Odometer
+		}
+		changed = true
+	}
+	if e.Odometer > b.Mileage {
+		b.Mileage = e.Odometer
+		changed = true
+	}
+	return changed
+}
+
 func parseBikePath(path string) (string, string) {
 	trimmed := strings.TrimPrefix(path, "/api/fleet/bikes/")
 	trimmed = strings.Trim(trimmed, "/")
@@ -348,25 +455,41 @@ func validateServiceEntry(req CreateServiceEntryRequest) error {
 	if _, ok := validServiceTypes[req.ServiceType]; !ok {
 		return errors.New("invalid serviceType")
 	}
+	if req.Odometer < 0 {
+		return errors.New("odometer must not be negative")
+	}
 	return nil
 }
 
 func ensureUniqueRegistration(ctx context.Context, registration string, currentBikeID string) error {
 	reg := strings.TrimSpace(registration)
-	if reg == "" || trackerStore == nil {
+	if reg == "" || bikesRepo == nil {
 		return nil
 	}
 
-	match, err := trackerStore.FindBikeByRegistration(ctx, reg)
+	match, err := findBikeByRegistration(ctx, reg)
 	if err != nil {
 		return errors.New("failed to validate registration")
 	}
-	if match != nil && match.BikeID != currentBikeID {
+	if match != nil && match.ID != currentBikeID {
 		return errors.New("registration must be unique")
 	}
 	return nil
 }
 
+func findBikeByRegistration(ctx context.Context, registration string) (*repo.Bike, error) {
+	bikes, err := bikesRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range bikes {
+		if normaliseRegistration(bikes[i].Registration) == normaliseRegistration(registration) {
+			return &bikes[i], nil
+		}
+	}
+	return nil, nil
+}
+
 func parseServiceDate(value string) (time.Time, error) {
 	if strings.TrimSpace(value) == "" {
 		return time.Now(), nil
@@ -383,6 +506,16 @@ func parseServiceDate(value string) (time.Time, error) {
 	return time.Time{}, errors.New("invalid date")
 }
 
+// writeDepotError reports a depot reference that failed to resolve.
+func writeDepotError(w http.ResponseWriter, err error) {
+	if errors.Is(err, depots.ErrUnknownDepot) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("op=ResolveDepot err=%v", err)
+	http.Error(w, "failed to check depot", http.StatusInternalServerError)
+}
+
 func writeJSON(w http.ResponseWriter, status int, value any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
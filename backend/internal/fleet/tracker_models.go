@@ -1,7 +1,22 @@
 package fleet
 
-import "time"
+import (
+	"time"
 
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+)
+
+// Bike statuses stored on repo.Bike.
+const (
+	BikeStatusAvailable     = "Available"
+	BikeStatusOnDuty        = "OnDuty"
+	BikeStatusInService     = "InService"
+	BikeStatusFaultReported = "FaultReported"
+	BikeStatusOutOfService  = "OutOfService"
+)
+
+// FleetBike is the fleet tracker's view of a bike, served by /api/fleet/bikes.
+// It is also the item shape of the legacy FLEET_BIKES_TABLE.
 type FleetBike struct {
 	BikeID       string    `json:"bikeId" dynamodbav:"BikeID"`
 	Make         string    `json:"make" dynamodbav:"Make"`
@@ -10,18 +25,53 @@ type FleetBike struct {
 	Registration string    `json:"registration" dynamodbav:"Registration"`
 	LocationID   string    `json:"locationId" dynamodbav:"LocationID"`
 	Active       string    `json:"active" dynamodbav:"Active"`
+	Status       string    `json:"status,omitempty" dynamodbav:"-"`
+	Odometer     int       `json:"odometer,omitempty" dynamodbav:"-"`
 	CreatedAt    time.Time `json:"createdAt" dynamodbav:"CreatedAt"`
 	UpdatedAt    time.Time `json:"updatedAt" dynamodbav:"UpdatedAt"`
 }
 
-type ServiceEntry struct {
-	ServiceID   string    `json:"serviceId" dynamodbav:"ServiceID"`
-	BikeID      string    `json:"bikeId" dynamodbav:"BikeID"`
-	ServiceType string    `json:"serviceType" dynamodbav:"ServiceType"`
-	ServiceDate time.Time `json:"serviceDate" dynamodbav:"ServiceDate"`
-	Notes       string    `json:"notes,omitempty" dynamodbav:"Notes,omitempty"`
-	PerformedBy string    `json:"performedBy,omitempty" dynamodbav:"PerformedBy,omitempty"`
-	CreatedAt   time.Time `json:"createdAt" dynamodbav:"CreatedAt"`
+type ServiceEntry = repo.ServiceEntry
+
+// fleetBikeFromRepo renders a bike in the fleet tracker's shape. The
+// tracker's "active" field folds status and rider assignment together.
+func fleetBikeFromRepo(b repo.Bike) FleetBike {
+	active := "out_of_service"
+	switch {
+	case b.CurrentRiderID != "":
+		active = b.CurrentRiderID
+	case b.Status == BikeStatusAvailable:
+		active = "ready"
+	}
+	return FleetBike{
+		BikeID:       b.ID,
+		Make:         b.Make,
+		Model:        b.Model,
+		VehicleType:  b.VehicleType,
+		Registration: b.Registration,
+		LocationID:   b.Depot,
+		Active:       active,
+		Status:       b.Status,
+		Odometer:     b.Mileage,
+		CreatedAt:    b.CreatedAt,
+		UpdatedAt:    b.UpdatedAt,
+	}
+}
+
+// applyActive sets the bike's status and assignment from a tracker "active"
+// value: ready, out_of_service or a rider UID.
+func applyActive(b *repo.Bike, active string) {
+	switch active {
+	case "ready":
+		b.Status = BikeStatusAvailable
+		b.CurrentRiderID = ""
+	case "out_of_service":
+		b.Status = BikeStatusOutOfService
+		b.CurrentRiderID = ""
+	default:
+		b.Status = BikeStatusOnDuty
+		b.CurrentRiderID = active
+	}
 }
 
 type CreateFleetBikeRequest struct {
@@ -45,6 +95,7 @@ type UpdateFleetBikeRequest struct {
 type CreateServiceEntryRequest struct {
 	ServiceType string `json:"serviceType"`
 	ServiceDate string `json:"serviceDate,omitempty"`
+	Odometer    int    `json:"odometer,omitempty"`
 	Notes       string `json:"notes,omitempty"`
 	PerformedBy string `json:"performedBy,omitempty"`
 }
@@ -55,4 +106,6 @@ var validServiceTypes = map[string]struct{}{
 	"tyres":   {},
 	"brakes":  {},
 	"coolant": {},
+	// Recorded when a work order for an issue report is completed.
+	"repair": {},
 }
@@ -14,6 +14,10 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
+// TrackerStore reads and writes the legacy fleet tracker tables
+// (FLEET_BIKES_TABLE / FLEET_SERVICE_TABLE). Bikes and their service history
+// now live in repo.BikesRepository; this store is kept so cmd/migratebikes
+// can fold the old tables into it.
 type TrackerStore struct {
 	client       *dynamodb.Client
 	bikesTable   string
@@ -40,14 +44,18 @@ func NewTrackerStore(ctx context.Context) (*TrackerStore, error) {
 }
 
 func (s *TrackerStore) ListBikes(ctx context.Context) ([]FleetBike, error) {
-	out, err := s.client.Scan(ctx, &dynamodb.ScanInput{TableName: &s.bikesTable})
-	if err != nil {
-		return nil, err
-	}
-
 	var bikes []FleetBike
-	if err := attributevalue.UnmarshalListOfMaps(out.Items, &bikes); err != nil {
-		return nil, err
+	paginator := dynamodb.NewScanPaginator(s.client, &dynamodb.ScanInput{TableName: &s.bikesTable})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var batch []FleetBike
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &batch); err != nil {
+			return nil, err
+		}
+		bikes = append(bikes, batch...)
 	}
 	return bikes, nil
 }
@@ -121,7 +129,8 @@ func (s *TrackerStore) DeleteBike(ctx context.Context, bikeID string) error {
 }
 
 func (s *TrackerStore) ListServiceEntries(ctx context.Context, bikeID string) ([]ServiceEntry, error) {
-	out, err := s.client.Query(ctx, &dynamodb.QueryInput{
+	var entries []ServiceEntry
+	paginator := dynamodb.NewQueryPaginator(s.client, &dynamodb.QueryInput{
 		TableName:              &s.serviceTable,
 		KeyConditionExpression: awsString("BikeID = :bikeId"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
@@ -129,13 +138,16 @@ func (s *TrackerStore) ListServiceEntries(ctx context.Context, bikeID string) ([
 		},
 		ScanIndexForward: awsBool(false),
 	})
-	if err != nil {
-		return nil, err
-	}
-
-	var entries []ServiceEntry
-	if err := attributevalue.UnmarshalListOfMaps(out.Items, &entries); err != nil {
-		return nil, err
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var batch []ServiceEntry
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &batch); err != nil {
+			return nil, err
+		}
+		entries = append(entries, batch...)
 	}
 	return entries, nil
 }
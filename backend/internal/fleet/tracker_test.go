@@ -1,9 +1,16 @@
 package fleet
 
 import (
+"context"
+"encoding/json"
+"net/http"
+"net/http/httptest"
 "strings"
 "testing"
 "time"
+
+"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo/memory"
 )
 
 // ---- validateBike ----
@@ -77,6 +84,22 @@ t.Errorf("expected no error with requireAll=false, got: %v", err)
 }
 }
 
+// ---- ensureUniqueRegistration ----
+
+func TestEnsureUniqueRegistration_MatchesAsMigrationDoes(t *testing.T) {
+bikes := memory.NewBikesRepo()
+SetRepositories(memory.NewUsersRepo(), bikes)
+t.Cleanup(func() { SetRepositories(nil, nil) })
+_ = bikes.Put(context.Background(), &repo.Bike{ID: "b1", Registration: "212-G-1"})
+
+if err := ensureUniqueRegistration(context.Background(), "212 g1", "b2"); err == nil {
+t.Error("expected 212 g1 to clash with 212-G-1")
+}
+if err := ensureUniqueRegistration(context.Background(), "212G1", "b1"); err != nil {
+t.Errorf("expected the bike's own registration to be allowed, got: %v", err)
+}
+}
+
 // ---- validateServiceEntry ----
 
 func TestValidateServiceEntry_ValidTypes(t *testing.T) {
@@ -180,3 +203,149 @@ if a == b {
 t.Error("expected unique IDs, got duplicates")
 }
 }
+
+// ---- handlers over the bike repository ----
+
+func TestFleetHandlers_CreateBikeAndService(t *testing.T) {
+	SetRepositories(memory.NewUsersRepo(), memory.NewBikesRepo())
+	defer SetRepositories(nil, nil)
+
+	body := `{"make":"Honda","model":"Pan European","vehicleType":"motorcycle","registration":"212-G-1234","locationId":"galway","active":"ready"}`
+	rec := httptest.NewRecorder()
+	FleetListOrCreate(rec, httptest.NewRequest(http.MethodPost, "/api/fleet/bikes", strings.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var bike FleetBike
+	_ = json.NewDecoder(rec.Body).Decode(&bike)
+	if bike.Active != "out_of_service" || bike.LocationID != "galway" {
+		t.Errorf("unexpected created bike %+v", bike)
+	}
+
+	rec = httptest.NewRecorder()
+	FleetListOrCreate(rec, httptest.NewRequest(http.MethodPost, "/api/fleet/bikes", strings.NewReader(body)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected duplicate registration to be rejected, got %d", rec.Code)
+	}
+
+	svc := `{"serviceType":"oil","serviceDate":"2026-03-01","odometer":12000}`
+	rec = httptest.NewRecorder()
+	FleetBikeDetail(rec, httptest.NewRequest(http.MethodPost, "/api/fleet/bikes/"+bike.BikeID+"/service", strings.NewReader(svc)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	b, _, _ := bikesRepo.Get(context.Background(), bike.BikeID)
+	if b.Mileage != 12000 || b.LastServiceMiles != 12000 {
+		t.Errorf("expected service to update odometer and last service, got %+v", b)
+	}
+
+	rec = httptest.NewRecorder()
+	FleetBikeDetail(rec, httptest.NewRequest(http.MethodGet, "/api/fleet/bikes/"+bike.BikeID+"/service", nil))
+	var entries []ServiceEntry
+	_ = json.NewDecoder(rec.Body).Decode(&entries)
+	if len(entries) != 1 || entries[0].Odometer != 12000 {
+		t.Errorf("expected one service entry, got %+v", entries)
+	}
+}
+
+func TestRegisterBike_KeepsFieldsMotorcycleLacks(t *testing.T) {
+	SetRepositories(memory.NewUsersRepo(), memory.NewBikesRepo())
+	defer SetRepositories(nil, nil)
+
+	created := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	_ = bikesRepo.Put(context.Background(), &repo.Bike{ID: "BB21-WES", Make: "Honda", Registration: "212-G-1234", VehicleType: "motorcycle", CreatedAt: created})
+
+	rec := httptest.NewRecorder()
+	RegisterBike(rec, httptest.NewRequest(http.MethodPost, "/api/bike/register", strings.NewReader(`{"id":"BB21-WES","model":"Pan European","mileage":500}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	b, _, _ := bikesRepo.Get(context.Background(), "BB21-WES")
+	if b.Make != "Honda" || b.Registration != "212-G-1234" || b.VehicleType != "motorcycle" || !b.CreatedAt.Equal(created) {
+		t.Errorf("expected existing fields kept, got %+v", b)
+	}
+	if b.Model != "Pan European" || b.Mileage != 500 {
+		t.Errorf("expected posted fields applied, got %+v", b)
+	}
+}
+
+func TestRegisterUser_KeepsFieldsUserLacks(t *testing.T) {
+	SetRepositories(memory.NewUsersRepo(), memory.NewBikesRepo())
+	defer SetRepositories(nil, nil)
+
+	_ = usersRepo.Put(context.Background(), &repo.User{RiderID: "rita", Status: "available", Depot: "Galway", CalendarFeedKey: "k1"})
+
+	rec := httptest.NewRecorder()
+	RegisterUser(rec, httptest.NewRequest(http.MethodPost, "/api/user/register", strings.NewReader(`{"riderId":"rita","name":"Rita","tags":["Rider"]}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	u, _, _ := usersRepo.Get(context.Background(), "rita")
+	if u.Status != "available" || u.Depot != "Galway" || u.CalendarFeedKey != "k1" {
+		t.Errorf("expected existing fields kept, got %+v", u)
+	}
+	if u.Name != "Rita" || len(u.Tags) != 1 {
+		t.Errorf("expected posted fields applied, got %+v", u)
+	}
+}
+
+func TestDeleteBike_RemovesHistoryExpensesAndDocuments(t *testing.T) {
+	SetRepositories(memory.NewUsersRepo(), memory.NewBikesRepo())
+	defer SetRepositories(nil, nil)
+	docs := memory.NewBikeDocumentsRepo()
+	SetDocumentsRepository(docs)
+	defer SetDocumentsRepository(nil)
+
+	ctx := context.Background()
+	_ = bikesRepo.Put(ctx, &repo.Bike{ID: "b1"})
+	_ = bikesRepo.PutServiceEntry(ctx, &repo.ServiceEntry{ServiceID: "s1", BikeID: "b1", ServiceType: "oil"})
+	_ = bikesRepo.PutExpense(ctx, &repo.Expense{ExpenseID: "e1", BikeID: "b1", Category: "fuel"})
+	_ = docs.Put(ctx, &repo.BikeDocument{DocumentID: "d1", BikeID: "b1", Type: "insurance"})
+
+	rec := httptest.NewRecorder()
+	FleetBikeDetail(rec, httptest.NewRequest(http.MethodDelete, "/api/fleet/bikes/b1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	entries, _ := bikesRepo.ListServiceEntries(ctx, "b1")
+	expenses, _ := bikesRepo.ListExpenses(ctx, "b1")
+	left, _ := docs.ListByBike(ctx, "b1")
+	if len(entries) != 0 || len(expenses) != 0 || len(left) != 0 {
+		t.Errorf("expected bike records removed, got %d entries, %d expenses, %d documents", len(entries), len(expenses), len(left))
+	}
+}
+
+// assigningBikes hands the bike to a rider, as a ride starting on another
+// instance would, just before the first conditional write.
+type assigningBikes struct {
+	*memory.BikesRepo
+	raced bool
+}
+
+func (a *assigningBikes) PutIfUnchanged(ctx context.Context, b *repo.Bike, prev repo.Bike) (bool, error) {
+	if !a.raced {
+		a.raced = true
+		other := prev
+		other.CurrentRiderID, other.Status, other.UpdatedAt = "r9", BikeStatusOnDuty, time.Now()
+		_ = a.BikesRepo.Put(ctx, &other)
+	}
+	return a.BikesRepo.PutIfUnchanged(ctx, b, prev)
+}
+
+func TestChangeLocation_KeepsConcurrentRiderAssignment(t *testing.T) {
+	bikes := &assigningBikes{BikesRepo: memory.NewBikesRepo()}
+	SetRepositories(memory.NewUsersRepo(), bikes)
+	defer SetRepositories(nil, nil)
+	_ = bikes.Put(context.Background(), &repo.Bike{ID: "b1", Depot: "galway", Status: BikeStatusAvailable})
+
+	rec := httptest.NewRecorder()
+	handleChangeLocation(rec, httptest.NewRequest(http.MethodPost, "/api/fleet/bikes/b1/change-location", strings.NewReader(`{"locationId":"athlone"}`)), bikes, "b1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	b, _, _ := bikes.Get(context.Background(), "b1")
+	if b.Depot != "athlone" || b.CurrentRiderID != "r9" || b.Status != BikeStatusOnDuty {
+		t.Errorf("expected new depot with the concurrent assignment kept, got %+v", b)
+	}
+}
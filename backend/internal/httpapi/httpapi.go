@@ -5,12 +5,14 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"time"
@@ -23,15 +25,25 @@ import (
 	sestypes "github.com/aws/aws-sdk-go-v2/service/sesv2/types"
 
 	"github.com/AdamGallagher339/Codename-Blood/backend/internal/analytics"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/attachments"
 	"github.com/AdamGallagher339/Codename-Blood/backend/internal/auth"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/blobstore"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/depots"
 	"github.com/AdamGallagher339/Codename-Blood/backend/internal/events"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/fatigue"
 	"github.com/AdamGallagher339/Codename-Blood/backend/internal/fleet"
 	"github.com/AdamGallagher339/Codename-Blood/backend/internal/issuereports"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/messaging"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/notifyprefs"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/outbox"
 	"github.com/AdamGallagher339/Codename-Blood/backend/internal/push"
 	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo/boltdb"
 	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo/dynamo"
 	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo/memory"
 	"github.com/AdamGallagher339/Codename-Blood/backend/internal/ridesessions"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/scheduling"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/sms"
 	"github.com/AdamGallagher339/Codename-Blood/backend/internal/tracking"
 	"github.com/google/uuid"
 )
@@ -117,6 +129,24 @@ func NewHandler(ctx context.Context) (http.Handler, error) {
 	localAuthFlag := strings.ToLower(strings.TrimSpace(os.Getenv("LOCAL_AUTH")))
 	forceMemory := localAuthFlag == "1" || localAuthFlag == "true" || localAuthFlag == "yes"
 
+	// LOCAL_STORE=bolt keeps local fleet data (bikes, service history and
+	// documents) and the notification outbox in a bbolt file instead of
+	// memory so it survives restarts.
+	var localFleetDB *boltdb.DB
+	if strings.EqualFold(strings.TrimSpace(os.Getenv("LOCAL_STORE")), "bolt") {
+		path := os.Getenv("LOCAL_STORE_PATH")
+		if path == "" {
+			path = filepath.Join("..", "data", "fleet.db")
+		}
+		localFleetDB, err = boltdb.Open(path)
+		if err != nil {
+			log.Printf("LOCAL_STORE=bolt unavailable, falling back to memory: %v", err)
+			localFleetDB = nil
+		} else {
+			log.Printf("Local fleet store: %s", path)
+		}
+	}
+
 	// Fall back to in-memory repos when DynamoDB tables are not configured (local dev).
 	var users repo.UsersRepository = dynamoRepos.Users
 	var bikes repo.BikesRepository = dynamoRepos.Bikes
@@ -131,11 +161,15 @@ func NewHandler(ctx context.Context) (http.Handler, error) {
 	}
 	if bikes == nil || forceMemory {
 		if forceMemory {
-			log.Println("LOCAL_AUTH=1 – using in-memory bikes repo (DynamoDB bypassed)")
+			log.Println("LOCAL_AUTH=1 – using local bikes repo (DynamoDB bypassed)")
 		} else {
-			log.Println("BIKES_TABLE not set – using in-memory bikes repo")
+			log.Println("BIKES_TABLE not set – using local bikes repo")
+		}
+		if localFleetDB != nil {
+			bikes = boltdb.NewBikesRepo(localFleetDB)
+		} else {
+			bikes = memory.NewBikesRepo()
 		}
-		bikes = memory.NewBikesRepo()
 	}
 	if jobsRepo == nil || forceMemory {
 		if forceMemory {
@@ -147,12 +181,27 @@ func NewHandler(ctx context.Context) (http.Handler, error) {
 	}
 
 	fleet.SetRepositories(users, bikes)
+
+	// Depots bikes, riders and ride sessions are based at.
+	var depotsRepo repo.DepotsRepository = dynamoRepos.Depots
+	if depotsRepo == nil || forceMemory {
+		log.Println("DEPOTS_TABLE not set – using local depots repo")
+		if localFleetDB != nil {
+			depotsRepo = boltdb.NewDepotsRepo(localFleetDB)
+		} else {
+			depotsRepo = memory.NewDepotsRepo()
+		}
+	}
+	depots.SetRepository(depotsRepo)
+	depots.SetFleetRepositories(bikes, users)
 	fleet.SetCognitoGroupManager(authClient)
 
 	// Set global events repository if configured
 	if dynamoRepos.Events != nil {
 		events.SetGlobalEventsRepository(dynamoRepos.Events)
 	}
+	// Riders' tags are the qualifications event sign-up checks.
+	events.SetUsersRepository(users)
 
 	// Set ride sessions repository
 	var rideSessions repo.RideSessionsRepository = dynamoRepos.RideSessions
@@ -161,6 +210,28 @@ func NewHandler(ctx context.Context) (http.Handler, error) {
 		rideSessions = memory.NewRideSessionsRepo()
 	}
 	ridesessions.SetRepository(rideSessions)
+	ridesessions.SetBikesRepository(bikes)
+	ridesessions.SetJobsRepository(jobsRepo)
+
+	// Duty-time limits are computed from ride sessions and accepted jobs.
+	fatigue.SetRepositories(rideSessions, jobsRepo)
+
+	// Message threads between the dispatcher and the rider on each job.
+	var jobMessages repo.JobMessagesRepository = dynamoRepos.JobMessages
+	if jobMessages == nil {
+		log.Println("JOB_MESSAGES_TABLE not set – using in-memory job messages repo")
+		jobMessages = memory.NewJobMessagesRepo()
+	}
+	messaging.SetRepository(jobMessages)
+	messaging.SetJobsRepository(jobsRepo)
+
+	// What each user wants to be notified about, and when.
+	var notificationPrefs repo.NotificationPreferencesRepository = dynamoRepos.NotificationPreferences
+	if notificationPrefs == nil {
+		log.Println("NOTIFICATION_PREFS_TABLE not set – using in-memory notification preferences repo")
+		notificationPrefs = memory.NewNotificationPreferencesRepo()
+	}
+	notifyprefs.SetRepository(notificationPrefs)
 
 	// Set issue reports repository
 	var issueReportsRepo repo.IssueReportsRepository = dynamoRepos.IssueReports
@@ -169,16 +240,62 @@ func NewHandler(ctx context.Context) (http.Handler, error) {
 		issueReportsRepo = memory.NewIssueReportsRepo()
 	}
 	issuereports.SetRepository(issueReportsRepo)
+	issuereports.SetBikesRepository(bikes)
+	fleet.SetRideSessionsRepository(rideSessions)
+
+	// Set bike documents repository (insurance / motor tax / NCT)
+	var bikeDocumentsRepo repo.BikeDocumentsRepository = dynamoRepos.BikeDocuments
+	if bikeDocumentsRepo == nil {
+		log.Println("BIKE_DOCUMENTS_TABLE not set – using local bike documents repo")
+		if localFleetDB != nil {
+			bikeDocumentsRepo = boltdb.NewBikeDocumentsRepo(localFleetDB)
+		} else {
+			bikeDocumentsRepo = memory.NewBikeDocumentsRepo()
+		}
+	}
+	fleet.SetDocumentsRepository(bikeDocumentsRepo)
+
+	// Photo and file attachments for issue reports and service entries.
+	var attachmentsRepo repo.AttachmentsRepository = dynamoRepos.Attachments
+	if attachmentsRepo == nil {
+		log.Println("ATTACHMENTS_TABLE not set – using local attachments repo")
+		if localFleetDB != nil {
+			attachmentsRepo = boltdb.NewAttachmentsRepo(localFleetDB)
+		} else {
+			attachmentsRepo = memory.NewAttachmentsRepo()
+		}
+	}
+	attachments.SetRepository(attachmentsRepo)
+	attachments.SetBikesRepository(bikes)
+	if blobs, err := blobstore.FromEnv(ctx); err != nil {
+		log.Printf("Attachments disabled (blob store): %v", err)
+	} else {
+		attachments.SetBlobStore(blobs)
+	}
 
-	// Start background ticker that deletes events once their end time has passed.
+	// Start background ticker that archives events once they have ended.
 	events.StartCleanupTicker(ctx)
 
-	trackerStore, err := fleet.NewTrackerStore(ctx)
-	if err != nil {
-		log.Println("fleet tracker not initialized:", err)
-	} else {
-		fleet.SetTrackerStore(trackerStore)
+	// On-call rota: riders go on and off duty as their shifts start and end.
+	var shiftsRepo repo.ShiftsRepository = dynamoRepos.Shifts
+	if shiftsRepo == nil {
+		log.Println("SHIFTS_TABLE not set – using in-memory shifts repo")
+		shiftsRepo = memory.NewShiftsRepo()
+	}
+	scheduling.SetRepository(shiftsRepo)
+	scheduling.SetUsersRepository(users)
+	scheduling.StartDutyTicker(ctx)
+
+	// Availability calendars riders publish ahead of time.
+	var availabilityRepo repo.AvailabilityRepository = dynamoRepos.Availability
+	if availabilityRepo == nil {
+		log.Println("AVAILABILITY_TABLE not set – using in-memory availability repo")
+		availabilityRepo = memory.NewAvailabilityRepo()
 	}
+	scheduling.SetAvailabilityRepository(availabilityRepo)
+
+	// Close ride sessions riders forgot to end, freeing their bikes.
+	ridesessions.StartStaleSweeper(ctx)
 
 	applicationsTable := os.Getenv("APPLICATIONS_TABLE")
 	var applicationsDDB *dynamodb.Client
@@ -263,10 +380,26 @@ func NewHandler(ctx context.Context) (http.Handler, error) {
 	mux.HandleFunc("/api/bikes", withCORS(authClient.RequireAuth(fleet.GetAllBikes)))
 	mux.HandleFunc("/api/ride/start", withCORS(authClient.RequireAuth(fleet.StartRide)))
 	mux.HandleFunc("/api/ride/end", withCORS(authClient.RequireAuth(fleet.EndRide)))
-
-	// --- Fleet Tracker Routes (DynamoDB) ---
+	mux.HandleFunc("/api/ride/checklist", withCORS(authClient.RequireAuth(fleet.Checklist)))
+	mux.HandleFunc("/api/ride/checkout", withCORS(authClient.RequireAuth(fleet.Checkout)))
+	mux.HandleFunc("/api/ride/checkin", withCORS(authClient.RequireAuth(fleet.Checkin)))
+	mux.HandleFunc("/api/ride/scan", withCORS(authClient.RequireAuth(fleet.ScanBike)))
+
+	// --- Fleet Tracker Routes ---
+	mux.HandleFunc("/api/depots", withCORS(authClient.RequireAuth(depots.ListOrCreate)))
+	mux.HandleFunc("/api/depots/", withCORS(authClient.RequireAuth(depots.Detail)))
+	mux.HandleFunc("/api/shifts", withCORS(authClient.RequireAuth(scheduling.ListOrCreate)))
+	mux.HandleFunc("/api/shifts/", withCORS(authClient.RequireAuth(scheduling.Detail)))
+	mux.HandleFunc("/api/availability/", withCORS(authClient.RequireAuth(scheduling.Availability)))
 	mux.HandleFunc("/api/fleet/bikes", withCORS(authClient.RequireAuth(fleet.FleetListOrCreate)))
 	mux.HandleFunc("/api/fleet/bikes/", withCORS(authClient.RequireAuth(fleet.FleetBikeDetail)))
+	mux.HandleFunc("/api/fleet/maintenance/", withCORS(requireAuthAndRole("FleetManager", fleet.HandleMaintenance)))
+	mux.HandleFunc("/api/fleet/costs", withCORS(requireAuthAndRole("FleetManager", fleet.HandleCosts)))
+	mux.HandleFunc("/api/fleet/costs/", withCORS(requireAuthAndRole("FleetManager", fleet.HandleCosts)))
+	mux.HandleFunc("/api/fleet/labels", withCORS(requireAuthAndRole("FleetManager", fleet.HandleLabels)))
+	mux.HandleFunc("/api/fleet/labels/", withCORS(requireAuthAndRole("FleetManager", fleet.HandleLabels)))
+	mux.HandleFunc("/api/fleet/documents", withCORS(requireAuthAndRole("FleetManager", fleet.DocumentsListOrCreate)))
+	mux.HandleFunc("/api/fleet/documents/", withCORS(requireAuthAndRole("FleetManager", fleet.DocumentDetail)))
 
 	// --- User / Tag Routes ---
 	mux.HandleFunc("/api/users", withCORS(getAllUsers))
@@ -281,6 +414,11 @@ func NewHandler(ctx context.Context) (http.Handler, error) {
 	mux.HandleFunc("/api/events", withCORS(authClient.RequireAuth(events.ListOrCreate)))
 	mux.HandleFunc("/api/events/", withCORS(authClient.RequireAuth(events.GetUpdateOrDelete)))
 
+	// iCal feeds are signed, so calendar apps can fetch them without bearer auth.
+	mux.HandleFunc("/api/calendar/links", withCORS(authClient.RequireAuth(events.HandleCalendarLinks)))
+	mux.HandleFunc("/api/calendar/import", withCORS(requireAuthAndRole("FleetManager", events.ImportCalendar)))
+	mux.HandleFunc("/api/calendar/", withCORS(events.CalendarFeed))
+
 	// --- Ride Sessions Routes ---
 	mux.HandleFunc("/api/ride-sessions", withCORS(authClient.RequireAuth(ridesessions.ListOrCreate)))
 	mux.HandleFunc("/api/ride-sessions/", withCORS(authClient.RequireAuth(ridesessions.Detail)))
@@ -289,6 +427,16 @@ func NewHandler(ctx context.Context) (http.Handler, error) {
 	mux.HandleFunc("/api/issue-reports", withCORS(authClient.RequireAuth(issuereports.ListOrCreate)))
 	mux.HandleFunc("/api/issue-reports/", withCORS(authClient.RequireAuth(issuereports.Detail)))
 
+	// Attachments. Download links are signed, so they skip bearer auth.
+	mux.HandleFunc("/api/attachments", withCORS(authClient.RequireAuth(attachments.ListOrUpload)))
+	mux.HandleFunc("/api/attachments/", withCORS(func(w http.ResponseWriter, r *http.Request) {
+		if attachments.IsContentPath(r.URL.Path) {
+			attachments.Content(w, r)
+			return
+		}
+		authClient.RequireAuth(attachments.Detail)(w, r)
+	}))
+
 	// --- Push Notification Store ---
 	var pushStore *push.Store
 	pushStore, err = push.NewStore()
@@ -296,7 +444,65 @@ func NewHandler(ctx context.Context) (http.Handler, error) {
 		log.Println("Push notifications disabled:", err)
 	} else {
 		log.Println("Push notifications enabled")
+		pushStore.SetUsersRepository(users)
+	}
+
+	// --- SMS ---
+	// Texts go to users who opted into SMS, as the fallback when a push
+	// doesn't reach them, or instead of push when push is disabled.
+	var smsMessages repo.SMSMessagesRepository = dynamoRepos.SMSMessages
+	if smsMessages == nil {
+		log.Println("SMS_MESSAGES_TABLE not set – using in-memory SMS messages repo")
+		smsMessages = memory.NewSMSMessagesRepo()
+	}
+	var smsSender *sms.Sender
+	if gateway, err := sms.GatewayFromEnv(); err != nil {
+		log.Println("SMS disabled:", err)
+	} else {
+		smsSender = sms.NewSender(gateway, smsMessages)
+		log.Printf("SMS enabled via %s gateway", gateway.Name())
+	}
+
+	// --- Notification Outbox ---
+	// Every notification goes through the outbox, which stores it, tracks
+	// and retries each delivery and texts users push doesn't reach.
+	var notificationsRepo repo.NotificationsRepository = dynamoRepos.Notifications
+	if notificationsRepo == nil {
+		if localFleetDB != nil {
+			log.Println("NOTIFICATIONS_TABLE not set – using local notifications repo")
+			notificationsRepo = boltdb.NewNotificationsRepo(localFleetDB)
+		} else {
+			log.Println("NOTIFICATIONS_TABLE not set – using in-memory notifications repo; queued notifications and retries are lost on restart")
+			notificationsRepo = memory.NewNotificationsRepo()
+		}
+	}
+	var deliveriesRepo repo.NotificationDeliveriesRepository = dynamoRepos.NotificationDeliveries
+	if deliveriesRepo == nil {
+		if localFleetDB != nil {
+			log.Println("NOTIFICATION_DELIVERIES_TABLE not set – using local notification deliveries repo")
+			deliveriesRepo = boltdb.NewNotificationDeliveriesRepo(localFleetDB)
+		} else {
+			log.Println("NOTIFICATION_DELIVERIES_TABLE not set – using in-memory notification deliveries repo; queued notifications and retries are lost on restart")
+			deliveriesRepo = memory.NewNotificationDeliveriesRepo()
+		}
+	}
+	var pusher outbox.Pusher
+	if pushStore != nil {
+		pusher = pushStore
 	}
+	var textUser outbox.Fallback
+	if smsSender != nil {
+		textUser = func(ctx context.Context, category, username, title, body string) (bool, error) {
+			m, err := smsSender.NotifyUser(ctx, category, username, title, body)
+			if errors.Is(err, sms.ErrNoPhone) {
+				return false, nil
+			}
+			return m != nil, err
+		}
+	}
+	notifications := outbox.New(notificationsRepo, deliveriesRepo, pusher, textUser)
+	notifications.SetUsersRepository(users)
+	notifications.Start(ctx)
 
 	// --- Jobs Routes ---
 	listOrCreateJobs := authClient.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
@@ -369,15 +575,13 @@ func NewHandler(ctx context.Context) (http.Handler, error) {
 				return
 			}
 
-			// Send push notification to all subscribed riders
-			if pushStore != nil {
-				pickupAddr := body.Pickup
-				if pickupAddr == "" {
-					pickupAddr = "TBD"
-				}
-				notifBody := fmt.Sprintf("%s — Pickup: %s", body.Title, pickupAddr)
-				go pushStore.NotifyAll("🚨 New Job Posted", notifBody, "/jobs")
+			// Send push notification to riders
+			pickupAddr := body.Pickup
+			if pickupAddr == "" {
+				pickupAddr = "TBD"
 			}
+			notifBody := fmt.Sprintf("%s — Pickup: %s", body.Title, pickupAddr)
+			notifications.NotifyRole(notifyprefs.CategoryNewJobs, "Rider", "🚨 New Job Posted", notifBody, "/jobs")
 
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusCreated)
@@ -394,6 +598,11 @@ func NewHandler(ctx context.Context) (http.Handler, error) {
 			http.Error(w, "job ID required", http.StatusBadRequest)
 			return
 		}
+		// /api/jobs/{id}/messages... and /api/jobs/{id}/audit
+		if len(parts) > 1 && parts[1] != "" {
+			messaging.HandleJob(w, r)
+			return
+		}
 		switch r.Method {
 		case http.MethodGet:
 			job, found, err := jobsRepo.Get(r.Context(), jobID)
@@ -411,9 +620,10 @@ func NewHandler(ctx context.Context) (http.Handler, error) {
 		case http.MethodPut:
 			// Accept a job (rider sets acceptedBy + status)
 			var body struct {
-				Status        string `json:"status"`
-				AcceptedBy    string `json:"acceptedBy"`
-				SignatureData string `json:"signatureData,omitempty"`
+				Status          string `json:"status"`
+				AcceptedBy      string `json:"acceptedBy"`
+				SignatureData   string `json:"signatureData,omitempty"`
+				FatigueOverride bool   `json:"fatigueOverride,omitempty"`
 			}
 			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 				http.Error(w, "invalid JSON", http.StatusBadRequest)
@@ -429,6 +639,38 @@ func NewHandler(ctx context.Context) (http.Handler, error) {
 				http.Error(w, "job not found", http.StatusNotFound)
 				return
 			}
+
+			// Check the rider's duty-time limits before they take the job on.
+			// In block mode only a dispatcher (or above) can override.
+			if body.AcceptedBy != "" && (body.Status == "accepted" || body.AcceptedBy != job.AcceptedBy) {
+				var rider *repo.User
+				if dynamoRepos.Users != nil {
+					rider, _, _ = dynamoRepos.Users.Get(r.Context(), body.AcceptedBy)
+				}
+				cfg := fatigue.CurrentConfig()
+				status, err := fatigue.ForRider(r.Context(), body.AcceptedBy, rider, time.Now().UTC())
+				if err != nil {
+					log.Printf("op=FatigueCheck rider=%s err=%v", body.AcceptedBy, err)
+				} else if reasons := fatigue.ProjectJob(status, cfg); len(reasons) > 0 {
+					override := body.FatigueOverride && auth.HasRoleOrAbove(authClient.GetUserRoles(r.Context()), "Dispatcher")
+					if cfg.Enforcement == fatigue.EnforcementBlock && !override {
+						log.Printf("op=FatigueBlock job=%s rider=%s reasons=%q", jobID, body.AcceptedBy, reasons)
+						notifications.NotifyRoleOrAbove(notifyprefs.CategorySafety, "Dispatcher", "⛔ Job Blocked: Duty-Time Limits",
+							fmt.Sprintf("%s can't take job %s – %s", body.AcceptedBy, jobID, strings.Join(reasons, "; ")), "/dispatcher")
+						w.Header().Set("Content-Type", "application/json")
+						w.WriteHeader(http.StatusConflict)
+						_ = json.NewEncoder(w).Encode(map[string]any{
+							"error":   "accepting this job would exceed duty-time limits",
+							"reasons": reasons,
+							"fatigue": status,
+						})
+						return
+					}
+					log.Printf("op=FatigueWarning job=%s rider=%s override=%v reasons=%q", jobID, body.AcceptedBy, override, reasons)
+					w.Header().Set("X-Fatigue-Warning", strings.Join(reasons, "; "))
+				}
+			}
+
 			if body.Status != "" {
 				job.Status = body.Status
 			}
@@ -441,7 +683,11 @@ func NewHandler(ctx context.Context) (http.Handler, error) {
 			now := time.Now().UTC()
 			job.Timestamps["updated"] = now.Format(time.RFC3339)
 
-			// Record pickup/delivery timestamps and signature
+			// Record status timestamps (used for duty-time tracking) and signatures
+			switch body.Status {
+			case "accepted", "completed", "cancelled":
+				job.Timestamps[body.Status] = now.Format(time.RFC3339)
+			}
 			if body.Status == "picked-up" {
 				job.Timestamps["pickedUp"] = now.Format(time.RFC3339)
 				if body.SignatureData != "" {
@@ -469,6 +715,13 @@ func NewHandler(ctx context.Context) (http.Handler, error) {
 				return
 			}
 
+			// A job accepted mid-ride belongs to the rider's open ride session.
+			if body.Status == "accepted" && body.AcceptedBy != "" {
+				if err := ridesessions.LinkJob(r.Context(), body.AcceptedBy, jobID); err != nil {
+					log.Printf("op=LinkRideJob job=%s rider=%s err=%v", jobID, body.AcceptedBy, err)
+				}
+			}
+
 			// When a rider accepts a job, set their availability to "on-job"
 			if body.Status == "accepted" && body.AcceptedBy != "" && dynamoRepos.Users != nil {
 				rider, found, _ := dynamoRepos.Users.Get(r.Context(), body.AcceptedBy)
@@ -488,12 +741,13 @@ func NewHandler(ctx context.Context) (http.Handler, error) {
 			if (body.Status == "delivered" || body.Status == "completed" || body.Status == "cancelled") && job.AcceptedBy != "" && dynamoRepos.Users != nil {
 				rider, found, _ := dynamoRepos.Users.Get(r.Context(), job.AcceptedBy)
 				if found && (rider.Status == "on-job" || rider.Status == "on-delivery") {
-					// Check if the rider's availability timer has expired
+					// Check if the rider's availability timer has expired; if so
+					// clear the status so their calendar applies again
 					newStatus := "available"
 					if rider.AvailableUntil != "" {
 						expiry, err := time.Parse(time.RFC3339, rider.AvailableUntil)
 						if err == nil && now.After(expiry) {
-							newStatus = "offline"
+							newStatus = ""
 							rider.AvailableUntil = ""
 						}
 					}
@@ -506,11 +760,16 @@ func NewHandler(ctx context.Context) (http.Handler, error) {
 				}
 			}
 
-			// Send push notification to dispatchers when job is delivered
-			if body.Status == "delivered" && pushStore != nil {
+			// Send push notification to the job's dispatcher when it is
+			// delivered, or to all dispatchers if nobody is recorded as its creator
+			if body.Status == "delivered" {
 				riderName := job.AcceptedBy
 				notifBody := fmt.Sprintf("Job \"%s\" has been delivered by %s", job.Title, riderName)
-				go pushStore.NotifyAll("✅ Job Completed", notifBody, "/dispatcher")
+				if job.CreatedBy != "" {
+					notifications.NotifyUser(notifyprefs.CategoryJobUpdates, job.CreatedBy, "✅ Job Completed", notifBody, "/dispatcher")
+				} else {
+					notifications.NotifyRoleOrAbove(notifyprefs.CategoryJobUpdates, "Dispatcher", "✅ Job Completed", notifBody, "/dispatcher")
+				}
 			}
 
 			w.Header().Set("Content-Type", "application/json")
@@ -534,6 +793,8 @@ func NewHandler(ctx context.Context) (http.Handler, error) {
 
 	mux.HandleFunc("/api/jobs", withCORS(listOrCreateJobs))
 	mux.HandleFunc("/api/jobs/", withCORS(jobDetail))
+	mux.HandleFunc("/api/messages/ws", withCORS(authClient.RequireAuth(messaging.HandleStream)))
+	mux.HandleFunc("/api/notifications/preferences", withCORS(authClient.RequireAuth(notifyprefs.HandlePreferences)))
 
 	// --- Receipt Email Route ---
 	sendReceipt := authClient.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
@@ -681,6 +942,23 @@ func NewHandler(ctx context.Context) (http.Handler, error) {
 		}
 
 		now := time.Now().UTC()
+
+		riderUsers := make([]repo.User, 0, len(riderUsernames))
+		for _, username := range riderUsernames {
+			if u := byID[username]; u != nil {
+				riderUsers = append(riderUsers, *u)
+			} else {
+				riderUsers = append(riderUsers, repo.User{RiderID: username})
+			}
+		}
+		fatigueByRider, err := fatigue.ForRiders(r.Context(), riderUsers, now)
+		if err != nil {
+			log.Printf("op=ListRiderAvailability fatigue err=%v", err)
+		}
+		calendarUntil, err := scheduling.CalendarStatus(r.Context(), now)
+		if err != nil {
+			log.Printf("op=ListRiderAvailability calendar err=%v", err)
+		}
 		riders := make([]map[string]any, 0, len(riderUsernames))
 		for _, username := range riderUsernames {
 			u := byID[username]
@@ -689,39 +967,57 @@ func NewHandler(ctx context.Context) (http.Handler, error) {
 			availableUntil := ""
 			currentJobID := ""
 			name := username
+			// Whether the rider has set a status of their own, which the
+			// calendar mustn't override.
+			explicit := false
 
 			if u != nil {
 				if u.Name != "" {
 					name = u.Name
 				}
 				status = u.Status
+				explicit = status != ""
 				if status == "" {
 					status = "offline"
 				}
 				availableUntil = u.AvailableUntil
 				currentJobID = u.CurrentJobID
 
-				// Auto-expire: if availableUntil is set and in the past, mark offline
+				// Auto-expire: if availableUntil is set and in the past, clear
+				// the status so the rider falls back to their calendar
 				if status == "available" && availableUntil != "" {
 					expiry, err := time.Parse(time.RFC3339, availableUntil)
 					if err == nil && now.After(expiry) {
 						status = "offline"
+						explicit = false
 						availableUntil = ""
-						u.Status = "offline"
+						u.Status = ""
 						u.AvailableUntil = ""
+						u.AvailableSince = ""
 						u.UpdatedAt = now
 						_ = dynamoRepos.Users.Put(r.Context(), u)
 					}
 				}
 			}
 
-			riders = append(riders, map[string]any{
+			// Riders who haven't set their status are available when their
+			// calendar says so. An explicit offline wins.
+			if until, ok := calendarUntil[username]; ok && !explicit {
+				status = "available"
+				availableUntil = until.UTC().Format(time.RFC3339)
+			}
+
+			entry := map[string]any{
 				"riderId":        username,
 				"name":           name,
 				"status":         status,
 				"availableUntil": availableUntil,
 				"currentJobId":   currentJobID,
-			})
+			}
+			if fs, ok := fatigueByRider[username]; ok {
+				entry["fatigue"] = fs
+			}
+			riders = append(riders, entry)
 		}
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(riders)
@@ -775,6 +1071,10 @@ func NewHandler(ctx context.Context) (http.Handler, error) {
 			user = &repo.User{RiderID: username, Name: username, Tags: []string{"Rider"}}
 		}
 
+		// Keep the start of an ongoing availability window when it is extended.
+		if body.Status == "available" && (user.Status != "available" || user.AvailableSince == "") {
+			user.AvailableSince = time.Now().UTC().Format(time.RFC3339)
+		}
 		user.Status = body.Status
 		user.UpdatedAt = time.Now().UTC()
 
@@ -782,6 +1082,7 @@ func NewHandler(ctx context.Context) (http.Handler, error) {
 			user.AvailableUntil = time.Now().UTC().Add(time.Duration(body.Duration) * time.Hour).Format(time.RFC3339)
 		} else if body.Status == "offline" {
 			user.AvailableUntil = ""
+			user.AvailableSince = ""
 			user.CurrentJobID = ""
 		}
 
@@ -835,6 +1136,36 @@ func NewHandler(ctx context.Context) (http.Handler, error) {
 		mux.HandleFunc("/api/push/test", withCORS(authClient.RequireAuth(pushStore.HandleTestNotification)))
 	}
 
+	// Fleet reminders and work orders go to fleet managers; event staffing
+	// alerts to coordinators (dispatchers and above). Safety alerts go to
+	// dispatchers and above too, since a grounded bike changes who can ride.
+	notifyFleet := func(title, body, url string) {
+		notifications.NotifyRoleOrAbove(notifyprefs.CategoryFleet, "FleetManager", title, body, url)
+	}
+	notifyCoordinators := func(title, body, url string) {
+		notifications.NotifyRoleOrAbove(notifyprefs.CategoryEvents, "Dispatcher", title, body, url)
+	}
+	fleet.StartMaintenanceReminders(ctx, notifyFleet)
+	fleet.StartDocumentReminders(ctx, notifyFleet)
+	issuereports.SetNotifier(notifyFleet)
+	issuereports.SetSafetyNotifier(func(title, body, url string) {
+		notifications.NotifyRoleOrAbove(notifyprefs.CategorySafety, "Dispatcher", title, body, url)
+	})
+	events.StartStaffingAlerts(ctx, events.Notifier(notifyCoordinators))
+	messaging.SetNotifier(func(usernames []string, title, body, url string) {
+		notifications.NotifyUsers(notifyprefs.CategoryJobUpdates, usernames, title, body, url)
+	})
+	mux.HandleFunc("/api/admin/notifications", withCORS(authClient.RequireAuth(notifications.HandleAdmin)))
+	mux.HandleFunc("/api/admin/notifications/", withCORS(authClient.RequireAuth(notifications.HandleAdmin)))
+
+	// --- SMS Routes ---
+	if smsSender != nil {
+		// The delivery status callback comes from the gateway, not a user;
+		// it checks its own shared token.
+		mux.HandleFunc("/api/sms/status", withCORS(smsSender.HandleStatus))
+		mux.HandleFunc("/api/sms/messages", withCORS(authClient.RequireAuth(smsSender.HandleMessages)))
+	}
+
 	mux.HandleFunc("/api/applications/public", withCORS(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
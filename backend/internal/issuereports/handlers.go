@@ -2,12 +2,19 @@ package issuereports
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"strings"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/auth"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
 )
 
-// ListOrCreate handles GET /api/issue-reports and POST /api/issue-reports
+// ListOrCreate handles GET /api/issue-reports and POST /api/issue-reports.
+// GET accepts ?bikeId=, ?status= and ?assignedTo= filters.
 func ListOrCreate(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
@@ -16,7 +23,7 @@ func ListOrCreate(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		writeJSON(w, http.StatusOK, items)
+		writeJSON(w, http.StatusOK, filterReports(items, r.URL.Query()))
 	case http.MethodPost:
 		var req CreateRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -40,7 +47,9 @@ func ListOrCreate(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// Detail handles GET/PUT/DELETE /api/issue-reports/{id}
+// Detail handles GET/PUT/PATCH/DELETE /api/issue-reports/{id}.
+// PUT resolves the issue, returning a grounded bike to service; PATCH
+// assigns a mechanic or moves the work order along. Both need FleetManager.
 func Detail(w http.ResponseWriter, r *http.Request) {
 	id := strings.TrimPrefix(r.URL.Path, "/api/issue-reports/")
 	id = strings.Trim(id, "/")
@@ -62,15 +71,35 @@ func Detail(w http.ResponseWriter, r *http.Request) {
 		}
 		writeJSON(w, http.StatusOK, ir)
 	case http.MethodPut:
-		// PUT resolves the issue
-		ir, err := Resolve(r.Context(), id)
+		if !auth.HasRoleOrAbove(auth.RolesFromContext(r.Context()), "FleetManager") {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		// PUT resolves the issue; the body is optional.
+		var req ResolveRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		ir, err := Resolve(r.Context(), id, req, auth.UsernameFromContext(r.Context()))
 		if err != nil {
-			if err.Error() == "not found" {
-				http.Error(w, "issue report not found", http.StatusNotFound)
-				return
-			}
-			log.Printf("[issue-reports] failed to resolve %s: %v", id, err)
-			http.Error(w, "internal server error", http.StatusInternalServerError)
+			writeWorkOrderError(w, id, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, ir)
+	case http.MethodPatch:
+		if !auth.HasRoleOrAbove(auth.RolesFromContext(r.Context()), "FleetManager") {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		var req UpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		ir, err := UpdateWorkOrder(r.Context(), id, req, auth.UsernameFromContext(r.Context()))
+		if err != nil {
+			writeWorkOrderError(w, id, err)
 			return
 		}
 		writeJSON(w, http.StatusOK, ir)
@@ -90,6 +119,41 @@ func Detail(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func writeWorkOrderError(w http.ResponseWriter, id string, err error) {
+	switch {
+	case err.Error() == "not found":
+		http.Error(w, "issue report not found", http.StatusNotFound)
+	case errors.Is(err, errInvalidStatus):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, errAlreadyFixed):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("[issue-reports] failed to update %s: %v", id, err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func filterReports(items []repo.IssueReport, q url.Values) []repo.IssueReport {
+	bikeID, status, assignedTo := q.Get("bikeId"), q.Get("status"), q.Get("assignedTo")
+	if bikeID == "" && status == "" && assignedTo == "" {
+		return items
+	}
+	out := make([]repo.IssueReport, 0, len(items))
+	for _, ir := range items {
+		if bikeID != "" && ir.BikeID != bikeID {
+			continue
+		}
+		if status != "" && StatusOf(ir) != status {
+			continue
+		}
+		if assignedTo != "" && ir.AssignedTo != assignedTo {
+			continue
+		}
+		out = append(out, ir)
+	}
+	return out
+}
+
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -42,36 +42,26 @@ func Create(ctx context.Context, req CreateRequest) (*repo.IssueReport, error) {
 		typ = "Minor"
 	}
 
+	now := time.Now()
 	ir := &repo.IssueReport{
 		IssueID:     newID(),
 		BikeID:      req.BikeID,
 		RiderID:     req.RiderID,
 		Type:        typ,
 		Description: req.Description,
-		Timestamp:   time.Now(),
+		Timestamp:   now,
 		Resolved:    false,
+		Status:      StatusOpen,
+		UpdatedAt:   now,
 	}
 	if globalRepo != nil {
 		if err := globalRepo.Put(ctx, ir); err != nil {
 			return nil, err
 		}
 	}
-	return ir, nil
-}
-
-func Resolve(ctx context.Context, id string) (*repo.IssueReport, error) {
-	ir, ok, err := Get(ctx, id)
-	if err != nil {
-		return nil, err
-	}
-	if !ok {
-		return nil, errors.New("not found")
-	}
-	ir.Resolved = true
-	if globalRepo != nil {
-		if err := globalRepo.Put(ctx, ir); err != nil {
-			return nil, err
-		}
+	// Major faults take the bike off the road until they're fixed.
+	if typ == "Major" {
+		groundBike(ctx, ir)
 	}
 	return ir, nil
 }
@@ -0,0 +1,314 @@
+package issuereports
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+)
+
+// Work-order statuses, in workshop order.
+const (
+	StatusOpen         = "open"
+	StatusTriaged      = "triaged"
+	StatusPartsOrdered = "parts-ordered"
+	StatusInWorkshop   = "in-workshop"
+	StatusFixed        = "fixed"
+)
+
+var validStatuses = map[string]bool{
+	StatusOpen:         true,
+	StatusTriaged:      true,
+	StatusPartsOrdered: true,
+	StatusInWorkshop:   true,
+	StatusFixed:        true,
+}
+
+// Bike statuses this package moves bikes between. They match the fleet
+// package's constants, which can't be imported from here.
+const (
+	bikeStatusAvailable     = "Available"
+	bikeStatusOnDuty        = "OnDuty"
+	bikeStatusInService     = "InService"
+	bikeStatusFaultReported = "FaultReported"
+)
+
+var (
+	errInvalidStatus = errors.New("status must be open, triaged, parts-ordered, in-workshop or fixed")
+	errAlreadyFixed  = errors.New("issue already fixed")
+)
+
+var bikesRepo repo.BikesRepository
+
+// SetBikesRepository lets issue reports ground bikes and record repairs in
+// their service history.
+func SetBikesRepository(r repo.BikesRepository) {
+	bikesRepo = r
+}
+
+// Notifier delivers a workshop notification.
+type Notifier func(title, body, url string)
+
+var notify, notifySafety Notifier
+
+func SetNotifier(n Notifier) {
+	notify = n
+}
+
+// SetSafetyNotifier sets how safety alerts (critical faults and grounded
+// bikes) are delivered. They go out even during quiet hours.
+func SetSafetyNotifier(n Notifier) {
+	notifySafety = n
+}
+
+func send(title, body string) {
+	log.Printf("[issue-reports] notify: %s – %s", title, body)
+	if notify != nil {
+		notify(title, body, "/fleet")
+	}
+}
+
+func sendSafety(title, body string) {
+	log.Printf("[issue-reports] safety alert: %s – %s", title, body)
+	if notifySafety != nil {
+		notifySafety(title, body, "/fleet")
+	}
+}
+
+// StatusOf returns the work-order status, treating reports from before
+// work orders existed as open or fixed.
+func StatusOf(ir repo.IssueReport) string {
+	if ir.Status != "" {
+		return ir.Status
+	}
+	if ir.Resolved {
+		return StatusFixed
+	}
+	return StatusOpen
+}
+
+type UpdateRequest struct {
+	Status     *string `json:"status,omitempty"`
+	AssignedTo *string `json:"assignedTo,omitempty"`
+}
+
+type ResolveRequest struct {
+	Resolution  string `json:"resolution,omitempty"`
+	PerformedBy string `json:"performedBy,omitempty"`
+}
+
+// UpdateWorkOrder assigns a mechanic and/or moves the work order along.
+// Moving to fixed resolves the issue.
+func UpdateWorkOrder(ctx context.Context, id string, req UpdateRequest, actor string) (*repo.IssueReport, error) {
+	ir, ok, err := Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	if StatusOf(*ir) == StatusFixed {
+		return nil, errAlreadyFixed
+	}
+
+	status := StatusOf(*ir)
+	if req.Status != nil {
+		status = strings.ToLower(strings.TrimSpace(*req.Status))
+		if !validStatuses[status] {
+			return nil, errInvalidStatus
+		}
+	}
+	if req.AssignedTo != nil {
+		assignee := strings.TrimSpace(*req.AssignedTo)
+		if assignee != ir.AssignedTo && assignee != "" {
+			send("🔧 Work Order Assigned", fmt.Sprintf("Bike %s: %s – assigned to %s", ir.BikeID, ir.Description, assignee))
+		}
+		ir.AssignedTo = assignee
+	}
+	if status == StatusFixed {
+		if err := save(ctx, ir); err != nil {
+			return nil, err
+		}
+		return Resolve(ctx, id, ResolveRequest{}, actor)
+	}
+
+	if status != StatusOf(*ir) {
+		send("🔧 Work Order Updated", fmt.Sprintf("Bike %s: %s – now %s", ir.BikeID, ir.Description, status))
+		if status == StatusInWorkshop {
+			setBikeStatus(ctx, ir.BikeID, bikeStatusInService, func(b *repo.Bike) bool { return b.CurrentRiderID == "" })
+		}
+	}
+	ir.Status = status
+	ir.UpdatedAt = time.Now()
+	if err := save(ctx, ir); err != nil {
+		return nil, err
+	}
+	return ir, nil
+}
+
+// Resolve closes the issue, records the repair in the bike's service
+// history and returns the bike to Available once no other Major issue is
+// open against it. Resolving a fixed issue is a no-op.
+func Resolve(ctx context.Context, id string, req ResolveRequest, actor string) (*repo.IssueReport, error) {
+	ir, ok, err := Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	if ir.Resolved && StatusOf(*ir) == StatusFixed {
+		return ir, nil
+	}
+
+	// Only issues that took the bike off the road put it back on.
+	heldBike := ir.Type == "Major" || StatusOf(*ir) == StatusInWorkshop
+	now := time.Now()
+	performedBy := firstNonEmpty(req.PerformedBy, ir.AssignedTo, actor)
+	ir.Resolution = strings.TrimSpace(req.Resolution)
+
+	if bikesRepo != nil {
+		entry, err := recordRepair(ctx, ir, performedBy, now)
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil {
+			ir.ServiceID = entry.ServiceID
+		}
+	}
+
+	ir.Resolved = true
+	ir.Status = StatusFixed
+	ir.ResolvedBy = firstNonEmpty(actor, performedBy)
+	ir.ResolvedAt = now
+	ir.UpdatedAt = now
+	if err := save(ctx, ir); err != nil {
+		return nil, err
+	}
+
+	if !heldBike {
+		return ir, nil
+	}
+	if released, err := releaseBike(ctx, ir.BikeID); err != nil {
+		log.Printf("[issue-reports] failed to release bike %s: %v", ir.BikeID, err)
+	} else if released {
+		send("✅ Bike Back In Service", fmt.Sprintf("Bike %s is available again – %s fixed", ir.BikeID, ir.Description))
+	}
+	return ir, nil
+}
+
+// groundBike takes the bike off the road for a Major issue and raises a
+// safety alert. Bikes already in the workshop stay there.
+func groundBike(ctx context.Context, ir *repo.IssueReport) {
+	grounded := setBikeStatus(ctx, ir.BikeID, bikeStatusFaultReported, func(b *repo.Bike) bool {
+		return b.Status == "" || b.Status == bikeStatusAvailable || b.Status == bikeStatusOnDuty
+	})
+	if grounded {
+		sendSafety("⛔ Bike Grounded", fmt.Sprintf("Bike %s: %s", ir.BikeID, ir.Description))
+	} else {
+		sendSafety("⚠️ Critical Issue Reported", fmt.Sprintf("Bike %s: %s", ir.BikeID, ir.Description))
+	}
+}
+
+// setBikeStatus sets the bike's status when when(b) allows it and reports
+// whether it changed.
+func setBikeStatus(ctx context.Context, bikeID, status string, when func(*repo.Bike) bool) bool {
+	if bikesRepo == nil {
+		return false
+	}
+	changed := false
+	_, _, err := repo.UpdateBike(ctx, bikesRepo, bikeID, func(b *repo.Bike) (bool, error) {
+		changed = b.Status != status && when(b)
+		if changed {
+			b.Status = status
+		}
+		return changed, nil
+	})
+	if err != nil {
+		log.Printf("[issue-reports] failed to set bike %s to %s: %v", bikeID, status, err)
+		return false
+	}
+	return changed
+}
+
+// releaseBike returns a grounded or in-workshop bike to Available when no
+// other Major or in-workshop issue is still open against it.
+func releaseBike(ctx context.Context, bikeID string) (bool, error) {
+	if bikesRepo == nil || globalRepo == nil {
+		return false, nil
+	}
+	all, err := globalRepo.List(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, other := range all {
+		if other.BikeID != bikeID || other.Resolved {
+			continue
+		}
+		if other.Type == "Major" || StatusOf(other) == StatusInWorkshop {
+			return false, nil
+		}
+	}
+	return setBikeStatus(ctx, bikeID, bikeStatusAvailable, func(b *repo.Bike) bool {
+		return b.CurrentRiderID == "" && (b.Status == bikeStatusFaultReported || b.Status == bikeStatusInService)
+	}), nil
+}
+
+// recordRepair adds a "repair" entry to the bike's service history. Issues
+// against bikes that aren't in the repository are closed without one.
+func recordRepair(ctx context.Context, ir *repo.IssueReport, performedBy string, now time.Time) (*repo.ServiceEntry, error) {
+	b, ok, err := bikesRepo.Get(ctx, ir.BikeID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, nil
+	}
+	notes := fmt.Sprintf("Issue %s: %s", ir.IssueID, ir.Description)
+	if ir.Resolution != "" {
+		notes += " – " + ir.Resolution
+	}
+	entry := &repo.ServiceEntry{
+		ServiceID:   newServiceID(),
+		BikeID:      ir.BikeID,
+		ServiceType: "repair",
+		ServiceDate: now,
+		Odometer:    b.Mileage,
+		Notes:       notes,
+		PerformedBy: performedBy,
+		CreatedAt:   now,
+	}
+	if err := bikesRepo.PutServiceEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func save(ctx context.Context, ir *repo.IssueReport) error {
+	if globalRepo == nil {
+		return nil
+	}
+	return globalRepo.Put(ctx, ir)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// newServiceID matches the fleet package's service entry IDs.
+func newServiceID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return "svc_" + hex.EncodeToString(b)
+}
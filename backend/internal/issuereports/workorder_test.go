@@ -0,0 +1,137 @@
+package issuereports
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo/memory"
+)
+
+func setup(t *testing.T) (*memory.BikesRepo, *[]string) {
+	t.Helper()
+	bikes := memory.NewBikesRepo()
+	SetRepository(memory.NewIssueReportsRepo())
+	SetBikesRepository(bikes)
+	var sent []string
+	SetNotifier(func(title, body, url string) { sent = append(sent, title) })
+	SetSafetyNotifier(func(title, body, url string) { sent = append(sent, "safety: "+title) })
+	t.Cleanup(func() {
+		SetRepository(nil)
+		SetBikesRepository(nil)
+		SetNotifier(nil)
+		SetSafetyNotifier(nil)
+	})
+	_ = bikes.Put(context.Background(), &repo.Bike{ID: "b1", Status: "Available", Mileage: 8000})
+	return bikes, &sent
+}
+
+func strp(s string) *string { return &s }
+
+// ---- grounding ----
+
+func TestCreate_MajorGroundsBike(t *testing.T) {
+	bikes, sent := setup(t)
+	ctx := context.Background()
+
+	if _, err := Create(ctx, CreateRequest{BikeID: "b1", Type: "Minor", Description: "mirror loose"}); err != nil {
+		t.Fatal(err)
+	}
+	b, _, _ := bikes.Get(ctx, "b1")
+	if b.Status != "Available" {
+		t.Errorf("expected minor issue to leave bike available, got %s", b.Status)
+	}
+
+	ir, _ := Create(ctx, CreateRequest{BikeID: "b1", Type: "Major", Description: "brake failure"})
+	b, _, _ = bikes.Get(ctx, "b1")
+	if b.Status != "FaultReported" {
+		t.Errorf("expected major issue to ground bike, got %s", b.Status)
+	}
+	if ir.Status != StatusOpen || len(*sent) != 1 || (*sent)[0] != "safety: ⛔ Bike Grounded" {
+		t.Errorf("expected open work order and one safety alert, got %s / %v", ir.Status, *sent)
+	}
+}
+
+func TestCreate_MajorOnBikeInWorkshopStillAlerts(t *testing.T) {
+	bikes, sent := setup(t)
+	ctx := context.Background()
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b2", Status: "InService"})
+
+	if _, err := Create(ctx, CreateRequest{BikeID: "b2", Type: "Major", Description: "fork seal leaking"}); err != nil {
+		t.Fatal(err)
+	}
+	if b, _, _ := bikes.Get(ctx, "b2"); b.Status != "InService" {
+		t.Errorf("expected bike to stay in the workshop, got %s", b.Status)
+	}
+	if len(*sent) != 1 || (*sent)[0] != "safety: ⚠️ Critical Issue Reported" {
+		t.Errorf("expected a critical issue safety alert, got %v", *sent)
+	}
+}
+
+// ---- work orders ----
+
+func TestWorkOrder_ThroughToFixed(t *testing.T) {
+	bikes, _ := setup(t)
+	ctx := context.Background()
+	ir, _ := Create(ctx, CreateRequest{BikeID: "b1", Type: "Major", Description: "clutch slipping"})
+
+	if _, err := UpdateWorkOrder(ctx, ir.IssueID, UpdateRequest{Status: strp("waiting")}, "fm1"); err != errInvalidStatus {
+		t.Errorf("expected invalid status error, got %v", err)
+	}
+	if _, err := UpdateWorkOrder(ctx, ir.IssueID, UpdateRequest{Status: strp("in-workshop"), AssignedTo: strp("mech1")}, "fm1"); err != nil {
+		t.Fatal(err)
+	}
+	b, _, _ := bikes.Get(ctx, "b1")
+	if b.Status != "InService" {
+		t.Errorf("expected bike in service, got %s", b.Status)
+	}
+
+	fixed, err := Resolve(ctx, ir.IssueID, ResolveRequest{Resolution: "new clutch plates"}, "fm1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fixed.Resolved || fixed.Status != StatusFixed || fixed.ServiceID == "" {
+		t.Errorf("unexpected resolved issue %+v", fixed)
+	}
+	entries, _ := bikes.ListServiceEntries(ctx, "b1")
+	if len(entries) != 1 || entries[0].ServiceType != "repair" || entries[0].PerformedBy != "mech1" || entries[0].Odometer != 8000 {
+		t.Errorf("expected repair service entry, got %+v", entries)
+	}
+	b, _, _ = bikes.Get(ctx, "b1")
+	if b.Status != "Available" {
+		t.Errorf("expected bike available again, got %s", b.Status)
+	}
+
+	if _, err := UpdateWorkOrder(ctx, ir.IssueID, UpdateRequest{Status: strp("triaged")}, "fm1"); err != errAlreadyFixed {
+		t.Errorf("expected fixed issue to be closed, got %v", err)
+	}
+}
+
+func TestResolve_OtherMajorKeepsBikeGrounded(t *testing.T) {
+	bikes, _ := setup(t)
+	ctx := context.Background()
+	first, _ := Create(ctx, CreateRequest{BikeID: "b1", Type: "Major", Description: "brakes"})
+	_, _ = Create(ctx, CreateRequest{BikeID: "b1", Type: "Major", Description: "tyres"})
+
+	if _, err := Resolve(ctx, first.IssueID, ResolveRequest{}, "fm1"); err != nil {
+		t.Fatal(err)
+	}
+	b, _, _ := bikes.Get(ctx, "b1")
+	if b.Status != "FaultReported" {
+		t.Errorf("expected bike to stay grounded, got %s", b.Status)
+	}
+}
+
+func TestDetail_PatchRequiresFleetManager(t *testing.T) {
+	setup(t)
+	ir, _ := Create(context.Background(), CreateRequest{BikeID: "b1", Type: "Minor", Description: "horn"})
+
+	rec := httptest.NewRecorder()
+	Detail(rec, httptest.NewRequest(http.MethodPatch, "/api/issue-reports/"+ir.IssueID, strings.NewReader(`{"status":"triaged"}`)))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", rec.Code)
+	}
+}
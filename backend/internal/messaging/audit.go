@@ -0,0 +1,80 @@
+package messaging
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// Audit entry kinds.
+const (
+	AuditStatus  = "status"
+	AuditMessage = "message"
+	AuditRead    = "read"
+)
+
+// AuditEntry is one line of a job's audit trail.
+type AuditEntry struct {
+	At        time.Time `json:"at"`
+	Kind      string    `json:"kind"`
+	Actor     string    `json:"actor,omitempty"`
+	Detail    string    `json:"detail"`
+	MessageID string    `json:"messageId,omitempty"`
+}
+
+// statusSteps maps the job's timestamps to the step they record and who
+// took it. "updated" only says the job changed, so it isn't listed.
+var statusSteps = map[string]string{
+	"created":   "created",
+	"accepted":  "accepted",
+	"pickedUp":  "picked-up",
+	"delivered": "delivered",
+	"completed": "completed",
+	"cancelled": "cancelled",
+}
+
+// Audit returns the job's audit trail, oldest first: its status changes,
+// the messages in its thread and when each was read.
+func Audit(ctx context.Context, jobID string, c Caller) ([]AuditEntry, error) {
+	j, err := job(ctx, jobID, c)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]AuditEntry, 0)
+	for key, step := range statusSteps {
+		s, _ := j.Timestamps[key].(string)
+		at, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			continue
+		}
+		actor := j.AcceptedBy
+		if key == "created" {
+			actor = j.CreatedBy
+		}
+		out = append(out, AuditEntry{At: at, Kind: AuditStatus, Actor: actor, Detail: step})
+	}
+
+	msgs, err := globalRepo.ListByJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range msgs {
+		out = append(out, AuditEntry{At: m.SentAt, Kind: AuditMessage, Actor: m.Sender, Detail: m.Body, MessageID: m.MessageID})
+		for reader, at := range m.ReadBy {
+			out = append(out, AuditEntry{At: at, Kind: AuditRead, Actor: reader, Detail: "read", MessageID: m.MessageID})
+		}
+	}
+	sort.SliceStable(out, func(i, k int) bool {
+		if !out[i].At.Equal(out[k].At) {
+			return out[i].At.Before(out[k].At)
+		}
+		return auditOrder(out[i]) < auditOrder(out[k])
+	})
+	return out, nil
+}
+
+// auditOrder breaks ties between entries at the same second so the trail
+// reads the same every time.
+func auditOrder(e AuditEntry) string {
+	return e.Kind + "\x00" + e.MessageID + "\x00" + e.Actor + "\x00" + e.Detail
+}
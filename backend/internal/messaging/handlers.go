@@ -0,0 +1,181 @@
+package messaging
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/auth"
+	"github.com/gorilla/websocket"
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin: func(r *http.Request) bool {
+		// Same policy as the tracking stream; requests are authenticated
+		return true
+	},
+}
+
+// HandleJob handles the thread routes under /api/jobs/{id}/:
+//
+//	GET /api/jobs/{id}/messages         the thread (?since= RFC 3339 for only newer messages)
+//	POST /api/jobs/{id}/messages        sends {"body": "..."}
+//	POST /api/jobs/{id}/messages/read   marks the caller's unread messages read
+//	GET /api/jobs/{id}/audit            the job's audit trail
+func HandleJob(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/jobs/"), "/"), "/")
+	c := callerFrom(r)
+	if c.Username == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if len(parts) < 2 || parts[0] == "" {
+		http.NotFound(w, r)
+		return
+	}
+	jobID := parts[0]
+
+	switch {
+	case len(parts) == 2 && parts[1] == "messages" && r.Method == http.MethodGet:
+		var since time.Time
+		if s := r.URL.Query().Get("since"); s != "" {
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				http.Error(w, "since must be an RFC 3339 time", http.StatusBadRequest)
+				return
+			}
+			since = t
+		}
+		msgs, err := Thread(r.Context(), jobID, c, since)
+		if err != nil {
+			writeError(w, jobID, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, msgs)
+
+	case len(parts) == 2 && parts[1] == "messages" && r.Method == http.MethodPost:
+		var body struct {
+			Body string `json:"body"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		m, err := Send(r.Context(), jobID, c, body.Body)
+		if err != nil {
+			writeError(w, jobID, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, m)
+
+	case len(parts) == 3 && parts[1] == "messages" && parts[2] == "read" && r.Method == http.MethodPost:
+		msgs, err := MarkRead(r.Context(), jobID, c)
+		if err != nil {
+			writeError(w, jobID, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, msgs)
+
+	case len(parts) == 2 && parts[1] == "audit" && r.Method == http.MethodGet:
+		entries, err := Audit(r.Context(), jobID, c)
+		if err != nil {
+			writeError(w, jobID, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, entries)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// HandleStream upgrades GET /api/messages/ws to a WebSocket carrying the
+// caller's message events (new messages and read receipts) as JSON.
+func HandleStream(w http.ResponseWriter, r *http.Request) {
+	username := auth.UsernameFromContext(r.Context())
+	if username == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("[messaging] websocket upgrade error: %v", err)
+		return
+	}
+	events, cancel := Default.Subscribe(username)
+	done := make(chan struct{})
+
+	// Read loop: only keeps the connection alive and notices it closing
+	go func() {
+		defer close(done)
+		conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+		conn.SetPongHandler(func(string) error {
+			conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+			return nil
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(54 * time.Second)
+	defer func() {
+		ticker.Stop()
+		cancel()
+		conn.Close()
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		case data := <-events:
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func callerFrom(r *http.Request) Caller {
+	return Caller{
+		Username:    auth.UsernameFromContext(r.Context()),
+		Coordinator: auth.HasRoleOrAbove(auth.RolesFromContext(r.Context()), "Dispatcher"),
+	}
+}
+
+func writeError(w http.ResponseWriter, jobID string, err error) {
+	switch {
+	case errors.Is(err, errJobNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, errForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case isValidationError(err):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("[messaging] job %s: %v", jobID, err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func isValidationError(err error) bool {
+	return errors.Is(err, errBodyRequired) || errors.Is(err, errBodyTooLong)
+}
@@ -0,0 +1,99 @@
+package messaging
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+)
+
+// Event types sent on the message stream.
+const (
+	EventMessage = "message"
+	EventRead    = "read"
+)
+
+// Event is one update on a user's message stream: a new message in one of
+// their threads, or a receipt for messages they sent.
+type Event struct {
+	Type       string           `json:"type"`
+	JobID      string           `json:"jobId"`
+	Message    *repo.JobMessage `json:"message,omitempty"`
+	Reader     string           `json:"reader,omitempty"`
+	MessageIDs []string         `json:"messageIds,omitempty"`
+	At         time.Time        `json:"at,omitempty"`
+}
+
+// Hub fans events out to each user's open stream connections. A user can
+// have several (phone and desktop).
+type Hub struct {
+	mu    sync.RWMutex
+	conns map[string]map[chan []byte]struct{}
+}
+
+// Default is the hub the handlers and Send use.
+var Default = NewHub()
+
+func NewHub() *Hub {
+	return &Hub{conns: make(map[string]map[chan []byte]struct{})}
+}
+
+// Subscribe opens a stream for the user. Call cancel when the connection
+// closes.
+func (h *Hub) Subscribe(username string) (<-chan []byte, func()) {
+	ch := make(chan []byte, 64)
+	h.mu.Lock()
+	if h.conns[username] == nil {
+		h.conns[username] = make(map[chan []byte]struct{})
+	}
+	h.conns[username][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.conns[username], ch)
+			if len(h.conns[username]) == 0 {
+				delete(h.conns, username)
+			}
+			h.mu.Unlock()
+		})
+	}
+}
+
+// Online reports whether the user has a stream open.
+func (h *Hub) Online(username string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.conns[username]) > 0
+}
+
+// Publish sends the event to every stream the users have open and returns
+// the users it couldn't reach.
+func (h *Hub) Publish(usernames []string, e Event) (offline []string) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("[messaging] failed to encode %s event: %v", e.Type, err)
+		return usernames
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, u := range usernames {
+		delivered := false
+		for ch := range h.conns[u] {
+			select {
+			case ch <- data:
+				delivered = true
+			default:
+				// Connection is slow, skip it
+			}
+		}
+		if !delivered {
+			offline = append(offline, u)
+		}
+	}
+	return offline
+}
@@ -0,0 +1,223 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo/memory"
+)
+
+var (
+	dispatcher = Caller{Username: "dave"}
+	rider      = Caller{Username: "rita"}
+)
+
+// setup stores a job created by dave and accepted by rita, and records
+// pushed notifications.
+func setup(t *testing.T) *pushed {
+	t.Helper()
+	jobs := memory.NewJobsRepo()
+	_ = jobs.Put(context.Background(), &repo.Job{
+		JobID: "job1", Title: "Bloods to UHG", CreatedBy: "dave", AcceptedBy: "rita",
+		Timestamps: map[string]any{"created": "2026-10-18T10:00:00Z", "accepted": "2026-10-18T10:05:00Z", "updated": "2026-10-18T10:05:00Z"},
+	})
+	SetRepository(memory.NewJobMessagesRepo())
+	SetJobsRepository(jobs)
+	p := &pushed{}
+	SetNotifier(p.notify)
+	Default = NewHub()
+	t.Cleanup(func() {
+		SetRepository(nil)
+		SetJobsRepository(nil)
+		SetNotifier(nil)
+		Default = NewHub()
+	})
+	return p
+}
+
+type pushed struct {
+	mu    sync.Mutex
+	users [][]string
+}
+
+func (p *pushed) notify(usernames []string, _, _, _ string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.users = append(p.users, usernames)
+}
+
+func (p *pushed) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.users)
+}
+
+func receive(t *testing.T, ch <-chan []byte) Event {
+	t.Helper()
+	select {
+	case data := <-ch:
+		var e Event
+		if err := json.Unmarshal(data, &e); err != nil {
+			t.Fatal(err)
+		}
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return Event{}
+	}
+}
+
+// ---- threads ----
+
+func TestSend_Access(t *testing.T) {
+	setup(t)
+	ctx := context.Background()
+
+	if _, err := Send(ctx, "job1", Caller{Username: "mallory"}, "hi"); !errors.Is(err, errForbidden) {
+		t.Errorf("expected errForbidden for a rider not on the job, got %v", err)
+	}
+	if _, err := Send(ctx, "job1", Caller{Username: "carla", Coordinator: true}, "any update?"); err != nil {
+		t.Errorf("coordinators can use any thread: %v", err)
+	}
+	if _, err := Send(ctx, "nope", dispatcher, "hi"); !errors.Is(err, errJobNotFound) {
+		t.Errorf("expected errJobNotFound, got %v", err)
+	}
+	if _, err := Send(ctx, "job1", rider, "   "); !errors.Is(err, errBodyRequired) {
+		t.Errorf("expected an empty message to be rejected, got %v", err)
+	}
+	if _, err := Thread(ctx, "job1", Caller{Username: "mallory"}, time.Time{}); !errors.Is(err, errForbidden) {
+		t.Errorf("expected errForbidden reading the thread, got %v", err)
+	}
+}
+
+func TestWriteError_StorageErrorsAre500(t *testing.T) {
+	cases := []struct {
+		err  error
+		want int
+	}{
+		{errBodyRequired, http.StatusBadRequest},
+		{errBodyTooLong, http.StatusBadRequest},
+		{errors.New("ValidationException: ExpressionAttributeValues must not be empty"), http.StatusInternalServerError},
+		{errors.New("ValidationException: One of the required keys was not given a value"), http.StatusInternalServerError},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		writeError(rec, "job1", c.err)
+		if rec.Code != c.want {
+			t.Errorf("%v: got %d, want %d", c.err, rec.Code, c.want)
+		}
+	}
+}
+
+func TestSend_LiveAndPushFallback(t *testing.T) {
+	p := setup(t)
+	ctx := context.Background()
+
+	ch, cancel := Default.Subscribe("rita")
+	defer cancel()
+	m, err := Send(ctx, "job1", dispatcher, "Collect from the side door")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := receive(t, ch); e.Type != EventMessage || e.Message == nil || e.Message.MessageID != m.MessageID {
+		t.Errorf("unexpected event %+v", e)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if p.count() != 0 {
+		t.Error("a connected rider shouldn't be pushed")
+	}
+
+	cancel()
+	if _, err := Send(ctx, "job1", dispatcher, "Are you close?"); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for p.count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if p.count() != 1 || len(p.users[0]) != 1 || p.users[0][0] != "rita" {
+		t.Errorf("expected one push to rita, got %v", p.users)
+	}
+
+	msgs, err := Thread(ctx, "job1", rider, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 || msgs[0].Body != "Collect from the side door" {
+		t.Errorf("unexpected thread %+v", msgs)
+	}
+	newer, _ := Thread(ctx, "job1", rider, msgs[0].SentAt)
+	if len(newer) != 1 {
+		t.Errorf("expected one message after the first, got %d", len(newer))
+	}
+}
+
+func TestMarkRead(t *testing.T) {
+	setup(t)
+	ctx := context.Background()
+	m, _ := Send(ctx, "job1", dispatcher, "Collect from the side door")
+	_, _ = Send(ctx, "job1", rider, "On my way")
+
+	ch, cancel := Default.Subscribe("dave")
+	defer cancel()
+	marked, err := MarkRead(ctx, "job1", rider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(marked) != 1 || marked[0].MessageID != m.MessageID {
+		t.Fatalf("expected only dave's message marked, got %+v", marked)
+	}
+	if e := receive(t, ch); e.Type != EventRead || e.Reader != "rita" || len(e.MessageIDs) != 1 {
+		t.Errorf("unexpected receipt %+v", e)
+	}
+	again, _ := MarkRead(ctx, "job1", rider)
+	if len(again) != 0 {
+		t.Errorf("expected nothing left to mark, got %d", len(again))
+	}
+}
+
+// ---- audit ----
+
+func TestAudit(t *testing.T) {
+	setup(t)
+	ctx := context.Background()
+	_, _ = Send(ctx, "job1", dispatcher, "Collect from the side door")
+	_, _ = MarkRead(ctx, "job1", rider)
+
+	entries, err := Audit(ctx, "job1", rider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"status:dave:created", "status:rita:accepted", "message:dave:Collect from the side door", "read:rita:read"}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries %+v, want %d", len(entries), entries, len(want))
+	}
+	for i, e := range entries {
+		if got := e.Kind + ":" + e.Actor + ":" + e.Detail; got != want[i] {
+			t.Errorf("entry %d = %s, want %s", i, got, want[i])
+		}
+	}
+}
+
+// ---- handlers ----
+
+func TestHandleJob_RequiresAuth(t *testing.T) {
+	setup(t)
+	rr := httptest.NewRecorder()
+	HandleJob(rr, httptest.NewRequest(http.MethodGet, "/api/jobs/job1/messages", nil))
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("status %d, want 401", rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	HandleStream(rr, httptest.NewRequest(http.MethodGet, "/api/messages/ws", nil))
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("stream status %d, want 401", rr.Code)
+	}
+}
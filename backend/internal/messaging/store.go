@@ -0,0 +1,234 @@
+// Package messaging runs the per-job message threads between the
+// dispatcher and the rider on a job. Messages are persisted, delivered live
+// to recipients connected to the message stream (see hub.go) and by push
+// to those who aren't, and carry read receipts. A job's audit trail merges
+// its status history with its thread (see audit.go).
+package messaging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+)
+
+const (
+	// maxMessageLength bounds a single message, in characters.
+	maxMessageLength = 2000
+	// previewLength is how much of a message a push notification shows.
+	previewLength = 120
+)
+
+var (
+	errJobNotFound  = errors.New("job not found")
+	errForbidden    = errors.New("not a participant in this job")
+	errBodyRequired = errors.New("body required")
+	errBodyTooLong  = fmt.Errorf("body must be at most %d characters", maxMessageLength)
+)
+
+// Notifier pushes a notification to the given users.
+type Notifier func(usernames []string, title, body, url string)
+
+var (
+	globalRepo repo.JobMessagesRepository
+	jobsRepo   repo.JobsRepository
+	notify     Notifier
+
+	// mu serialises read receipts.
+	mu sync.Mutex
+)
+
+func SetRepository(r repo.JobMessagesRepository) {
+	globalRepo = r
+}
+
+func SetJobsRepository(r repo.JobsRepository) {
+	jobsRepo = r
+}
+
+// SetNotifier sets how recipients who aren't connected are told about new
+// messages. Without one they see them next time they open the thread.
+func SetNotifier(n Notifier) {
+	notify = n
+}
+
+// Caller is who is using a thread. Coordinators (dispatchers and above)
+// can use any job's thread; riders only those they created or accepted.
+type Caller struct {
+	Username    string
+	Coordinator bool
+}
+
+// participants returns the users a job's messages are for: whoever
+// created it and the rider who accepted it.
+func participants(j *repo.Job) []string {
+	var out []string
+	for _, u := range []string{j.CreatedBy, j.AcceptedBy} {
+		if u != "" && !contains(out, u) {
+			out = append(out, u)
+		}
+	}
+	return out
+}
+
+// job loads the job and checks the caller can use its thread.
+func job(ctx context.Context, jobID string, c Caller) (*repo.Job, error) {
+	if globalRepo == nil || jobsRepo == nil {
+		return nil, errors.New("messaging not configured")
+	}
+	j, ok, err := jobsRepo.Get(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errJobNotFound
+	}
+	if !c.Coordinator && !contains(participants(j), c.Username) {
+		return nil, errForbidden
+	}
+	return j, nil
+}
+
+// Thread returns the job's messages sent after since (all of them when
+// since is zero), oldest first.
+func Thread(ctx context.Context, jobID string, c Caller, since time.Time) ([]repo.JobMessage, error) {
+	if _, err := job(ctx, jobID, c); err != nil {
+		return nil, err
+	}
+	all, err := globalRepo.ListByJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]repo.JobMessage, 0, len(all))
+	for _, m := range all {
+		if m.SentAt.After(since) {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+// Send adds a message to the job's thread and delivers it to the other
+// participants: live to those connected, by push to the rest.
+func Send(ctx context.Context, jobID string, c Caller, body string) (*repo.JobMessage, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, errBodyRequired
+	}
+	if len([]rune(body)) > maxMessageLength {
+		return nil, errBodyTooLong
+	}
+	j, err := job(ctx, jobID, c)
+	if err != nil {
+		return nil, err
+	}
+	m := &repo.JobMessage{
+		MessageID: newID("msg_"),
+		JobID:     jobID,
+		Sender:    c.Username,
+		Body:      body,
+		SentAt:    time.Now().UTC(),
+	}
+	if err := globalRepo.Put(ctx, m); err != nil {
+		return nil, err
+	}
+
+	recipients := remove(participants(j), c.Username)
+	offline := Default.Publish(append(recipients, c.Username), Event{Type: EventMessage, JobID: jobID, Message: m})
+	if offline = remove(offline, c.Username); len(offline) > 0 && notify != nil {
+		title := "New message from " + c.Username
+		if j.Title != "" {
+			title += " on " + j.Title
+		}
+		go notify(offline, title, preview(body), "/jobs/"+jobID)
+	}
+	return m, nil
+}
+
+// MarkRead records that the caller has read every message in the thread
+// sent by someone else, and tells the senders. It returns the messages it
+// marked.
+func MarkRead(ctx context.Context, jobID string, c Caller) ([]repo.JobMessage, error) {
+	if _, err := job(ctx, jobID, c); err != nil {
+		return nil, err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+
+	all, err := globalRepo.ListByJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	marked := make([]repo.JobMessage, 0)
+	bySender := make(map[string][]string)
+	for _, m := range all {
+		if m.Sender == c.Username {
+			continue
+		}
+		if _, ok := m.ReadBy[c.Username]; ok {
+			continue
+		}
+		readBy := make(map[string]time.Time, len(m.ReadBy)+1)
+		for u, t := range m.ReadBy {
+			readBy[u] = t
+		}
+		readBy[c.Username] = now
+		m.ReadBy = readBy
+		if err := globalRepo.Put(ctx, &m); err != nil {
+			return nil, err
+		}
+		marked = append(marked, m)
+		bySender[m.Sender] = append(bySender[m.Sender], m.MessageID)
+	}
+	senders := make([]string, 0, len(bySender))
+	for s := range bySender {
+		senders = append(senders, s)
+	}
+	sort.Strings(senders)
+	for _, s := range senders {
+		Default.Publish([]string{s}, Event{Type: EventRead, JobID: jobID, Reader: c.Username, MessageIDs: bySender[s], At: now})
+	}
+	return marked, nil
+}
+
+// preview shortens a message for a push notification.
+func preview(body string) string {
+	r := []rune(body)
+	if len(r) <= previewLength {
+		return body
+	}
+	return string(r[:previewLength-1]) + "…"
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func remove(list []string, s string) []string {
+	out := make([]string, 0, len(list))
+	for _, v := range list {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func newID(prefix string) string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return prefix + hex.EncodeToString(b)
+}
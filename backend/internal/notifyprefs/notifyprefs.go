@@ -0,0 +1,216 @@
+// Package notifyprefs holds each user's notification preferences: the
+// categories they want, the channels to use and their quiet hours. Every
+// sender (push, SMS) asks Allowed before delivering to a user.
+// Safety-critical categories can't be turned off and still come through
+// during quiet hours.
+package notifyprefs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/orgtime"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/phonenum"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+)
+
+// Notification categories.
+const (
+	CategoryNewJobs    = "new-jobs"
+	CategoryJobUpdates = "job-updates"
+	CategoryEvents     = "events"
+	CategoryFleet      = "fleet-alerts"
+	CategorySafety     = "safety-alerts"
+)
+
+// Delivery channels.
+const (
+	ChannelPush  = "push"
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+)
+
+// Categories lists every category; all are on by default, and
+// safety-critical ones stay on.
+var Categories = []string{CategoryNewJobs, CategoryJobUpdates, CategoryEvents, CategoryFleet, CategorySafety}
+
+// defaultChannels is which channels are on until a user chooses.
+var defaultChannels = map[string]bool{ChannelPush: true, ChannelEmail: false, ChannelSMS: false}
+
+var globalRepo repo.NotificationPreferencesRepository
+
+func SetRepository(r repo.NotificationPreferencesRepository) {
+	globalRepo = r
+}
+
+// SafetyCritical reports whether the category is always on and overrides
+// quiet hours.
+func SafetyCritical(category string) bool {
+	return category == CategorySafety
+}
+
+// Get returns the user's preferences with every category and channel
+// filled in.
+func Get(ctx context.Context, username string) (*repo.NotificationPreferences, error) {
+	p := &repo.NotificationPreferences{Username: username}
+	if globalRepo != nil {
+		stored, ok, err := globalRepo.Get(ctx, username)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			p = stored
+		}
+	}
+	return withDefaults(p), nil
+}
+
+// Update replaces the user's preferences. Categories and channels left
+// out keep their defaults. Safety-critical categories can't be turned off.
+func Update(ctx context.Context, username string, p repo.NotificationPreferences) (*repo.NotificationPreferences, error) {
+	if globalRepo == nil {
+		return nil, errors.New("notification preferences not configured")
+	}
+	for c := range p.Categories {
+		if !contains(Categories, c) {
+			return nil, fmt.Errorf("category must be one of %s", strings.Join(Categories, ", "))
+		}
+		if SafetyCritical(c) && !p.Categories[c] {
+			return nil, fmt.Errorf("%s can't be turned off", c)
+		}
+	}
+	for c := range p.Channels {
+		if _, ok := defaultChannels[c]; !ok {
+			return nil, fmt.Errorf("channel must be one of %s", strings.Join(channelNames(), ", "))
+		}
+	}
+	if q := p.QuietHours; q != nil {
+		start, err := orgtime.ParseClock(q.Start)
+		if err != nil {
+			return nil, errors.New("quietHours start must be a time (HH:MM)")
+		}
+		end, err := orgtime.ParseClock(q.End)
+		if err != nil {
+			return nil, errors.New("quietHours end must be a time (HH:MM)")
+		}
+		if start == end {
+			return nil, errors.New("quietHours start and end must differ")
+		}
+	}
+	if strings.TrimSpace(p.Phone) != "" {
+		phone, err := phonenum.E164(p.Phone)
+		if err != nil {
+			return nil, err
+		}
+		p.Phone = phone
+	} else {
+		p.Phone = ""
+	}
+	if p.Channels[ChannelSMS] && p.Phone == "" {
+		return nil, errors.New("phone must be set to turn on SMS")
+	}
+	p.Username = username
+	p.UpdatedAt = time.Now().UTC()
+	if err := globalRepo.Put(ctx, &p); err != nil {
+		return nil, err
+	}
+	return withDefaults(&p), nil
+}
+
+// Allowed reports whether the user wants a notification in category over
+// channel at now. An empty category is never filtered (a test the user
+// asked for). If the preferences can't be loaded the notification goes
+// out rather than being lost.
+func Allowed(ctx context.Context, username, category, channel string, now time.Time) bool {
+	if category == "" || username == "" {
+		return true
+	}
+	p, err := Get(ctx, username)
+	if err != nil {
+		log.Printf("[notifyprefs] failed to load preferences for %s: %v", username, err)
+		return true
+	}
+	return allows(p, category, channel, now)
+}
+
+// Filter returns the users who want a notification in category over
+// channel at now.
+func Filter(ctx context.Context, usernames []string, category, channel string, now time.Time) []string {
+	out := make([]string, 0, len(usernames))
+	for _, u := range usernames {
+		if Allowed(ctx, u, category, channel, now) {
+			out = append(out, u)
+		}
+	}
+	return out
+}
+
+func allows(p *repo.NotificationPreferences, category, channel string, now time.Time) bool {
+	if !p.Channels[channel] {
+		return false
+	}
+	if SafetyCritical(category) {
+		return true
+	}
+	return p.Categories[category] && !InQuietHours(p.QuietHours, now)
+}
+
+// InQuietHours reports whether now falls in the quiet hours, read in the
+// org time zone.
+func InQuietHours(q *repo.QuietHours, now time.Time) bool {
+	if q == nil {
+		return false
+	}
+	start, err1 := orgtime.ParseClock(q.Start)
+	end, err2 := orgtime.ParseClock(q.End)
+	if err1 != nil || err2 != nil || start == end {
+		return false
+	}
+	local := now.In(orgtime.Location())
+	clock := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute + time.Duration(local.Second())*time.Second
+	if start < end {
+		return clock >= start && clock < end
+	}
+	return clock >= start || clock < end
+}
+
+func withDefaults(p *repo.NotificationPreferences) *repo.NotificationPreferences {
+	out := *p
+	out.Categories = make(map[string]bool, len(Categories))
+	for _, c := range Categories {
+		on, ok := p.Categories[c]
+		out.Categories[c] = on || !ok || SafetyCritical(c)
+	}
+	out.Channels = make(map[string]bool, len(defaultChannels))
+	for c, def := range defaultChannels {
+		on, ok := p.Channels[c]
+		if !ok {
+			on = def
+		}
+		out.Channels[c] = on
+	}
+	return &out
+}
+
+func channelNames() []string {
+	out := make([]string, 0, len(defaultChannels))
+	for c := range defaultChannels {
+		out = append(out, c)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package notifyprefs
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo/memory"
+)
+
+func setup(t *testing.T) {
+	t.Helper()
+	SetRepository(memory.NewNotificationPreferencesRepo())
+	t.Cleanup(func() { SetRepository(nil) })
+}
+
+// dublin returns a wall-clock time in January, when Dublin is on UTC.
+func dublin(hour, minute int) time.Time {
+	return time.Date(2027, 1, 12, hour, minute, 0, 0, time.UTC)
+}
+
+func TestDefaults(t *testing.T) {
+	setup(t)
+	p, err := Get(context.Background(), "rita")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range Categories {
+		if !p.Categories[c] {
+			t.Errorf("category %s should default on", c)
+		}
+	}
+	if !p.Channels[ChannelPush] || p.Channels[ChannelSMS] || p.Channels[ChannelEmail] {
+		t.Errorf("unexpected default channels %v", p.Channels)
+	}
+	if !Allowed(context.Background(), "rita", CategoryNewJobs, ChannelPush, dublin(3, 0)) {
+		t.Error("new jobs by push should be allowed by default")
+	}
+}
+
+func TestUpdate_Validation(t *testing.T) {
+	setup(t)
+	ctx := context.Background()
+	bad := []repo.NotificationPreferences{
+		{Categories: map[string]bool{"gossip": true}},
+		{Channels: map[string]bool{"pigeon": true}},
+		{QuietHours: &repo.QuietHours{Start: "late", End: "07:00"}},
+		{QuietHours: &repo.QuietHours{Start: "07:00", End: "07:00"}},
+		{Channels: map[string]bool{ChannelSMS: true}},
+		{Phone: "091 123456"},
+		{Categories: map[string]bool{CategorySafety: false}},
+	}
+	for i, p := range bad {
+		if _, err := Update(ctx, "rita", p); err == nil {
+			t.Errorf("case %d: expected an error", i)
+		}
+	}
+}
+
+func TestAllowed_CategoriesChannelsAndQuietHours(t *testing.T) {
+	setup(t)
+	ctx := context.Background()
+	_, err := Update(ctx, "rita", repo.NotificationPreferences{
+		Categories: map[string]bool{CategoryEvents: false},
+		Channels:   map[string]bool{ChannelSMS: true},
+		QuietHours: &repo.QuietHours{Start: "22:30", End: "07:00"},
+		Phone:      "087 123 4567",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := Get(ctx, "rita"); p.Phone != "+353871234567" {
+		t.Errorf("expected the phone normalised, got %q", p.Phone)
+	}
+	cases := []struct {
+		category, channel string
+		at                time.Time
+		want              bool
+	}{
+		{CategoryNewJobs, ChannelPush, dublin(12, 0), true},
+		{CategoryEvents, ChannelPush, dublin(12, 0), false},
+		{CategoryNewJobs, ChannelEmail, dublin(12, 0), false},
+		{CategoryNewJobs, ChannelSMS, dublin(12, 0), true},
+		{CategoryNewJobs, ChannelPush, dublin(23, 0), false},
+		{CategoryNewJobs, ChannelPush, dublin(6, 59), false},
+		{CategoryNewJobs, ChannelPush, dublin(7, 0), true},
+		{CategorySafety, ChannelPush, dublin(2, 0), true},
+		{"", ChannelPush, dublin(2, 0), true},
+	}
+	for _, c := range cases {
+		if got := Allowed(ctx, "rita", c.category, c.channel, c.at); got != c.want {
+			t.Errorf("%s by %s at %s: got %v, want %v", c.category, c.channel, c.at.Format("15:04"), got, c.want)
+		}
+	}
+	if got := Filter(ctx, []string{"rita", "dave"}, CategoryEvents, ChannelPush, dublin(12, 0)); len(got) != 1 || got[0] != "dave" {
+		t.Errorf("expected only dave to want events, got %v", got)
+	}
+}
+
+func TestAllowed_SafetyAlwaysOn(t *testing.T) {
+	prefs := memory.NewNotificationPreferencesRepo()
+	SetRepository(prefs)
+	t.Cleanup(func() { SetRepository(nil) })
+	ctx := context.Background()
+	// Stored before safety alerts were locked on.
+	if err := prefs.Put(ctx, &repo.NotificationPreferences{
+		Username:   "rita",
+		Categories: map[string]bool{CategorySafety: false},
+		QuietHours: &repo.QuietHours{Start: "22:30", End: "07:00"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !Allowed(ctx, "rita", CategorySafety, ChannelPush, dublin(2, 0)) {
+		t.Error("expected a safety alert to get through during quiet hours")
+	}
+	if Allowed(ctx, "rita", CategoryNewJobs, ChannelPush, dublin(2, 0)) {
+		t.Error("expected new jobs to wait for quiet hours to end")
+	}
+	if p, _ := Get(ctx, "rita"); !p.Categories[CategorySafety] {
+		t.Error("expected safety alerts to read as on")
+	}
+}
+
+func TestQuietHours_SummerTime(t *testing.T) {
+	q := &repo.QuietHours{Start: "22:00", End: "23:00"}
+	// 21:30 UTC is 22:30 Irish summer time.
+	if !InQuietHours(q, time.Date(2027, 7, 1, 21, 30, 0, 0, time.UTC)) {
+		t.Error("expected quiet hours to follow the org zone")
+	}
+}
+
+func TestHandlePreferences_RequiresAuth(t *testing.T) {
+	setup(t)
+	rr := httptest.NewRecorder()
+	HandlePreferences(rr, httptest.NewRequest(http.MethodGet, "/api/notifications/preferences", nil))
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("status %d, want 401", rr.Code)
+	}
+}
@@ -0,0 +1,59 @@
+// Package orgtime holds the organisation's time zone: ORG_TIMEZONE,
+// default Europe/Dublin. Calendar dates and wall-clock times riders and
+// coordinators enter (event times, weekly availability) are in this zone.
+package orgtime
+
+import (
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // the org zone and imported TZIDs must resolve without system zoneinfo
+)
+
+const defaultTimezone = "Europe/Dublin"
+
+var (
+	locOnce sync.Once
+	loc     *time.Location
+)
+
+// Location returns the organisation's time zone.
+func Location() *time.Location {
+	locOnce.Do(func() {
+		name := strings.TrimSpace(os.Getenv("ORG_TIMEZONE"))
+		if name == "" {
+			name = defaultTimezone
+		}
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			log.Printf("[orgtime] unknown ORG_TIMEZONE %q, using %s: %v", name, defaultTimezone, err)
+			l, _ = time.LoadLocation(defaultTimezone)
+		}
+		loc = l
+	})
+	return loc
+}
+
+// ParseClock parses a wall-clock time, "HH:MM" or "HH:MM:SS", returning
+// it as an offset from midnight.
+func ParseClock(clock string) (time.Duration, error) {
+	layout := "15:04:05"
+	if strings.Count(clock, ":") == 1 {
+		layout = "15:04"
+	}
+	t, err := time.Parse(layout, strings.TrimSpace(clock))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second, nil
+}
+
+// At is the instant the calendar date year-month-day reaches the
+// wall-clock time clock in the org zone. Times skipped or repeated by a DST
+// change resolve as time.Date does.
+func At(year int, month time.Month, day int, clock time.Duration) time.Time {
+	h, m, sec := int(clock/time.Hour), int(clock%time.Hour/time.Minute), int(clock%time.Minute/time.Second)
+	return time.Date(year, month, day, h, m, sec, 0, Location())
+}
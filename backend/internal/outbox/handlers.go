@@ -0,0 +1,143 @@
+package outbox
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/auth"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+)
+
+// Summary is a notification with a count of its deliveries by status.
+type Summary struct {
+	repo.OutboxNotification
+	Deliveries map[string]int `json:"deliveries"`
+}
+
+// Detail is a notification with every delivery attempt.
+type Detail struct {
+	repo.OutboxNotification
+	Deliveries []repo.NotificationDelivery `json:"deliveries"`
+}
+
+// HandleAdmin handles /api/admin/notifications..., for admins:
+//
+//	GET /api/admin/notifications                    notifications, newest first (?status=)
+//	GET /api/admin/notifications/dead-letters       deliveries that ran out of attempts
+//	GET /api/admin/notifications/{id}               a notification and its deliveries
+//	POST /api/admin/notifications/{id}/retry        requeues its dead deliveries
+func (o *Outbox) HandleAdmin(w http.ResponseWriter, r *http.Request) {
+	if auth.UsernameFromContext(r.Context()) == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !auth.HasRoleOrAbove(auth.RolesFromContext(r.Context()), "BloodBikeAdmin") {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/notifications"), "/")
+	parts := strings.Split(rest, "/")
+
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+		out, err := o.summaries(r, strings.TrimSpace(r.URL.Query().Get("status")))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, out)
+
+	case rest == "dead-letters" && r.Method == http.MethodGet:
+		all, err := o.deliveries.List(r.Context())
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		dead := make([]repo.NotificationDelivery, 0)
+		for _, d := range all {
+			if d.Status == DeliveryDead {
+				dead = append(dead, d)
+			}
+		}
+		sort.Slice(dead, func(i, j int) bool { return dead[i].UpdatedAt.After(dead[j].UpdatedAt) })
+		writeJSON(w, http.StatusOK, dead)
+
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		n, ok, err := o.notifications.Get(r.Context(), parts[0])
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if !ok {
+			writeError(w, errNotificationNotFound)
+			return
+		}
+		ds, err := o.deliveries.ListByNotification(r.Context(), n.NotificationID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, Detail{OutboxNotification: *n, Deliveries: ds})
+
+	case len(parts) == 2 && parts[1] == "retry" && r.Method == http.MethodPost:
+		n, err := o.Retry(r.Context(), parts[0])
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, n)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (o *Outbox) summaries(r *http.Request, status string) ([]Summary, error) {
+	ns, err := o.notifications.List(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	ds, err := o.deliveries.List(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]map[string]int)
+	for _, d := range ds {
+		if counts[d.NotificationID] == nil {
+			counts[d.NotificationID] = make(map[string]int)
+		}
+		counts[d.NotificationID][d.Status]++
+	}
+	out := make([]Summary, 0, len(ns))
+	for _, n := range ns {
+		if status != "" && n.Status != status {
+			continue
+		}
+		c := counts[n.NotificationID]
+		if c == nil {
+			c = map[string]int{}
+		}
+		out = append(out, Summary{OutboxNotification: n, Deliveries: c})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errNotificationNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	log.Printf("[outbox] %v", err)
+	http.Error(w, "internal server error", http.StatusInternalServerError)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
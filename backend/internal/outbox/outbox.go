@@ -0,0 +1,552 @@
+// Package outbox is the durable notification outbox. Notify stores a
+// notification; a worker resolves its audience into one delivery per
+// recipient (a push subscription, or a user's phone when push doesn't reach
+// them), sends each, retries failures with exponential backoff and
+// dead-letters a delivery after maxAttempts. Everything is stored, so
+// notifications still queued or waiting to retry when the server stops are
+// picked up when it starts again. Delivery is at least once: a send cut off
+// by a restart is tried again. Finished notifications are purged after
+// retention.
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/auth"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/depots"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/notifyprefs"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/push"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+)
+
+// Notification statuses.
+const (
+	StatusQueued  = "queued"  // stored, recipients not resolved yet
+	StatusSending = "sending" // some deliveries still pending
+	StatusDone    = "done"    // every delivery sent or skipped
+	StatusFailed  = "failed"  // finished with at least one dead delivery
+)
+
+// Delivery statuses.
+const (
+	DeliveryPending = repo.DeliveryPending
+	DeliverySent    = "sent"
+	DeliverySkipped = "skipped" // the user's preferences turned it away
+	DeliveryDead    = "dead"
+)
+
+const (
+	// maxAttempts is how many times a delivery is tried before it is
+	// dead-lettered.
+	maxAttempts = 5
+	// The wait before retry n is baseBackoff * 2^(n-1), at most maxBackoff.
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 30 * time.Minute
+	// pollInterval is how often the worker looks for due retries.
+	pollInterval = 15 * time.Second
+	// Finished notifications and their deliveries are kept for retention,
+	// then purged, checking every purgeInterval.
+	retention     = 30 * 24 * time.Hour
+	purgeInterval = time.Hour
+)
+
+var errNotificationNotFound = errors.New("notification not found")
+
+// Pusher sends push notifications; *push.Store implements it.
+type Pusher interface {
+	Recipients(category string, a repo.NotificationAudience, now time.Time) []*push.Subscription
+	Subscription(endpoint string) (*push.Subscription, bool)
+	Push(sub *push.Subscription, title, body, url string) error
+}
+
+// Fallback reaches a user another way (SMS) when push doesn't. It reports
+// false when the user's preferences turn the notification away.
+type Fallback func(ctx context.Context, category, username, title, body string) (bool, error)
+
+// Outbox stores notifications and works through their deliveries.
+type Outbox struct {
+	notifications repo.NotificationsRepository
+	deliveries    repo.NotificationDeliveriesRepository
+	pusher        Pusher
+	fallback      Fallback
+	users         repo.UsersRepository
+	now           func() time.Time
+	wake          chan struct{}
+
+	// mu allows one pass over the outbox at a time.
+	mu         sync.Mutex
+	lastPurged time.Time
+}
+
+// New returns an outbox. pusher may be nil when push is disabled, and
+// fallback nil when there is no other channel.
+func New(notifications repo.NotificationsRepository, deliveries repo.NotificationDeliveriesRepository, pusher Pusher, fallback Fallback) *Outbox {
+	return &Outbox{
+		notifications: notifications,
+		deliveries:    deliveries,
+		pusher:        pusher,
+		fallback:      fallback,
+		now:           time.Now,
+		wake:          make(chan struct{}, 1),
+	}
+}
+
+// SetUsersRepository lets role audiences be listed from users' roles, so
+// members push doesn't reach are texted. Without it only named users are.
+func (o *Outbox) SetUsersRepository(r repo.UsersRepository) {
+	o.users = r
+}
+
+// Notify stores a notification for the audience and wakes the worker.
+func (o *Outbox) Notify(ctx context.Context, category string, a repo.NotificationAudience, title, body, url string) (*repo.OutboxNotification, error) {
+	if title == "" {
+		return nil, errors.New("title required")
+	}
+	now := o.now().UTC()
+	n := &repo.OutboxNotification{
+		NotificationID: newID("ntf_"),
+		Category:       category,
+		Title:          title,
+		Body:           body,
+		URL:            url,
+		Audience:       a,
+		Status:         StatusQueued,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := o.notifications.Put(ctx, n); err != nil {
+		return nil, err
+	}
+	o.poke()
+	return n, nil
+}
+
+// NotifyUser queues a notification for the user.
+func (o *Outbox) NotifyUser(category, username, title, body, url string) {
+	o.NotifyUsers(category, []string{username}, title, body, url)
+}
+
+// NotifyUsers queues a notification for the users.
+func (o *Outbox) NotifyUsers(category string, usernames []string, title, body, url string) {
+	o.enqueue(category, repo.NotificationAudience{Usernames: usernames}, title, body, url)
+}
+
+// NotifyRole queues a notification for users with the role.
+func (o *Outbox) NotifyRole(category, role, title, body, url string) {
+	o.enqueue(category, repo.NotificationAudience{Role: role}, title, body, url)
+}
+
+// NotifyRoleOrAbove queues a notification for users with the role or a
+// higher one.
+func (o *Outbox) NotifyRoleOrAbove(category, role, title, body, url string) {
+	o.enqueue(category, repo.NotificationAudience{Role: role, OrAbove: true}, title, body, url)
+}
+
+// NotifyRegion queues a notification for the users based at a depot (by
+// ID or name).
+func (o *Outbox) NotifyRegion(ctx context.Context, category, region, title, body, url string) error {
+	usernames, err := depots.RiderIDsIn(ctx, region)
+	if err != nil || len(usernames) == 0 {
+		return err
+	}
+	_, err = o.Notify(ctx, category, repo.NotificationAudience{Usernames: usernames}, title, body, url)
+	return err
+}
+
+func (o *Outbox) enqueue(category string, a repo.NotificationAudience, title, body, url string) {
+	if _, err := o.Notify(context.Background(), category, a, title, body, url); err != nil {
+		log.Printf("[outbox] failed to queue %q: %v", title, err)
+	}
+}
+
+// Start runs the worker until ctx is done: once straight away, to pick up
+// anything left from before a restart, then on every new notification and
+// every pollInterval.
+func (o *Outbox) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for {
+			o.process(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-o.wake:
+			}
+		}
+	}()
+	log.Printf("[outbox] worker started, polling every %s", pollInterval)
+}
+
+func (o *Outbox) poke() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// process makes one pass: it resolves queued notifications, attempts the
+// deliveries that are due, settles the notifications they belong to and
+// purges finished ones past retention.
+func (o *Outbox) process(ctx context.Context) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	queued, err := o.notifications.ListByStatus(ctx, StatusQueued)
+	if err != nil {
+		log.Printf("[outbox] list queued notifications: %v", err)
+		return
+	}
+	byID := make(map[string]*repo.OutboxNotification, len(queued))
+	touched := make(map[string]bool)
+	for i := range queued {
+		n := &queued[i]
+		byID[n.NotificationID] = n
+		if err := o.resolve(ctx, n); err != nil {
+			log.Printf("[outbox] resolve %s: %v", n.NotificationID, err)
+			continue
+		}
+		touched[n.NotificationID] = true
+	}
+
+	now := o.now().UTC()
+	due, err := o.deliveries.ListDue(ctx, now)
+	if err != nil {
+		log.Printf("[outbox] list due deliveries: %v", err)
+		return
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].CreatedAt.Before(due[j].CreatedAt) })
+	for i := range due {
+		d := &due[i]
+		n, ok := byID[d.NotificationID]
+		if !ok {
+			if n, ok, err = o.notifications.Get(ctx, d.NotificationID); err != nil {
+				log.Printf("[outbox] get notification %s: %v", d.NotificationID, err)
+				continue
+			}
+			if !ok {
+				continue
+			}
+			byID[n.NotificationID] = n
+		}
+		o.attempt(ctx, n, d)
+		touched[n.NotificationID] = true
+	}
+
+	for id := range touched {
+		if err := o.settle(ctx, byID[id]); err != nil {
+			log.Printf("[outbox] settle %s: %v", id, err)
+		}
+	}
+
+	if now.Sub(o.lastPurged) >= purgeInterval {
+		if err := o.purge(ctx, now.Add(-retention)); err != nil {
+			log.Printf("[outbox] purge: %v", err)
+		} else {
+			o.lastPurged = now
+		}
+	}
+}
+
+// purge deletes done and failed notifications last updated before cutoff,
+// with their deliveries. Deliveries go first, so a purge cut short leaves
+// the notification to be purged next time.
+func (o *Outbox) purge(ctx context.Context, cutoff time.Time) error {
+	purged := 0
+	for _, status := range []string{StatusDone, StatusFailed} {
+		ns, err := o.notifications.ListByStatus(ctx, status)
+		if err != nil {
+			return err
+		}
+		for _, n := range ns {
+			if !n.UpdatedAt.Before(cutoff) {
+				continue
+			}
+			ds, err := o.deliveries.ListByNotification(ctx, n.NotificationID)
+			if err != nil {
+				return err
+			}
+			for _, d := range ds {
+				if _, err := o.deliveries.Delete(ctx, d.DeliveryID); err != nil {
+					return err
+				}
+			}
+			if _, err := o.notifications.Delete(ctx, n.NotificationID); err != nil {
+				return err
+			}
+			purged++
+		}
+	}
+	if purged > 0 {
+		log.Printf("[outbox] purged %d notification(s) finished before %s", purged, cutoff.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// resolve creates the notification's deliveries: one per push subscription
+// in its audience, and one by the fallback for each member of the audience
+// with no subscription. Deliveries left from an interrupted earlier attempt
+// are kept as they are.
+func (o *Outbox) resolve(ctx context.Context, n *repo.OutboxNotification) error {
+	existing, err := o.existing(ctx, n.NotificationID)
+	if err != nil {
+		return err
+	}
+	var subs []*push.Subscription
+	if o.pusher != nil {
+		subs = o.pusher.Recipients(n.Category, n.Audience, o.now())
+	}
+	covered := make(map[string]bool)
+	for _, sub := range subs {
+		covered[sub.Username] = true
+		if err := o.add(ctx, n, existing, notifyprefs.ChannelPush, sub.Username, sub.Endpoint); err != nil {
+			return err
+		}
+	}
+	if o.fallback != nil {
+		members, err := o.members(ctx, n.Audience)
+		if err != nil {
+			return err
+		}
+		for _, u := range members {
+			if u == "" || covered[u] {
+				continue
+			}
+			covered[u] = true
+			if err := o.add(ctx, n, existing, notifyprefs.ChannelSMS, u, ""); err != nil {
+				return err
+			}
+		}
+	}
+	n.Status = StatusSending
+	n.UpdatedAt = o.now().UTC()
+	return o.notifications.Put(ctx, n)
+}
+
+// members lists the usernames in the audience: the named users, or the
+// users holding the role. An audience of everyone has no list.
+func (o *Outbox) members(ctx context.Context, a repo.NotificationAudience) ([]string, error) {
+	if len(a.Usernames) > 0 || a.Role == "" || o.users == nil {
+		return a.Usernames, nil
+	}
+	users, err := o.users.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, u := range users {
+		if (a.OrAbove && auth.HasRoleOrAbove(u.Tags, a.Role)) || (!a.OrAbove && auth.HasRole(u.Tags, a.Role)) {
+			out = append(out, u.RiderID)
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// add stores a pending delivery unless the notification already has it.
+func (o *Outbox) add(ctx context.Context, n *repo.OutboxNotification, existing map[string]bool, channel, username, endpoint string) error {
+	id := deliveryID(n.NotificationID, channel, username, endpoint)
+	if existing[id] {
+		return nil
+	}
+	existing[id] = true
+	now := o.now().UTC()
+	return o.deliveries.Put(ctx, &repo.NotificationDelivery{
+		DeliveryID:     id,
+		NotificationID: n.NotificationID,
+		Channel:        channel,
+		Username:       username,
+		Endpoint:       endpoint,
+		Status:         DeliveryPending,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	})
+}
+
+func (o *Outbox) existing(ctx context.Context, notificationID string) (map[string]bool, error) {
+	ds, err := o.deliveries.ListByNotification(ctx, notificationID)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]bool, len(ds))
+	for _, d := range ds {
+		out[d.DeliveryID] = true
+	}
+	return out, nil
+}
+
+// attempt tries a delivery once and records the outcome.
+func (o *Outbox) attempt(ctx context.Context, n *repo.OutboxNotification, d *repo.NotificationDelivery) {
+	sent, err := o.send(ctx, n, d)
+	now := o.now().UTC()
+	d.Attempts++
+	d.UpdatedAt = now
+	switch {
+	case err == nil && sent:
+		d.Status = DeliverySent
+		d.LastError = ""
+	case err == nil:
+		d.Status = DeliverySkipped
+		d.LastError = ""
+	case errors.Is(err, push.ErrGone) || errors.Is(err, push.ErrKeyRetired) || d.Attempts >= maxAttempts:
+		d.Status = DeliveryDead
+		d.LastError = err.Error()
+		log.Printf("[outbox] delivery %s dead after %d attempt(s): %v", d.DeliveryID, d.Attempts, err)
+	default:
+		d.LastError = err.Error()
+		d.NextAttemptAt = now.Add(backoff(d.Attempts))
+	}
+	if err := o.deliveries.Put(ctx, d); err != nil {
+		log.Printf("[outbox] save delivery %s: %v", d.DeliveryID, err)
+	}
+}
+
+func (o *Outbox) send(ctx context.Context, n *repo.OutboxNotification, d *repo.NotificationDelivery) (bool, error) {
+	switch d.Channel {
+	case notifyprefs.ChannelPush:
+		if o.pusher == nil {
+			return false, errors.New("push disabled")
+		}
+		sub, ok := o.pusher.Subscription(d.Endpoint)
+		if !ok {
+			return false, push.ErrGone
+		}
+		return true, o.pusher.Push(sub, n.Title, n.Body, n.URL)
+	case notifyprefs.ChannelSMS:
+		if o.fallback == nil {
+			return false, errors.New("no fallback channel")
+		}
+		return o.fallback(ctx, n.Category, d.Username, n.Title, n.Body)
+	default:
+		return false, errors.New("unknown channel " + d.Channel)
+	}
+}
+
+// settle falls back for users whose every push delivery is dead, then sets
+// the notification's status from its deliveries.
+func (o *Outbox) settle(ctx context.Context, n *repo.OutboxNotification) error {
+	ds, err := o.deliveries.ListByNotification(ctx, n.NotificationID)
+	if err != nil {
+		return err
+	}
+	if o.fallback != nil {
+		existing := make(map[string]bool, len(ds))
+		reachable := make(map[string]bool)
+		for _, d := range ds {
+			existing[d.DeliveryID] = true
+			if d.Channel != notifyprefs.ChannelPush || d.Status != DeliveryDead {
+				reachable[d.Username] = true
+			}
+		}
+		added := false
+		for _, d := range ds {
+			if d.Username == "" || reachable[d.Username] {
+				continue
+			}
+			reachable[d.Username] = true
+			if err := o.add(ctx, n, existing, notifyprefs.ChannelSMS, d.Username, ""); err != nil {
+				return err
+			}
+			added = true
+		}
+		if added {
+			o.poke()
+			return nil
+		}
+	}
+
+	status := StatusDone
+	for _, d := range ds {
+		if d.Status == DeliveryPending {
+			status = StatusSending
+			break
+		}
+		if d.Status == DeliveryDead {
+			status = StatusFailed
+		}
+	}
+	if status == n.Status {
+		return nil
+	}
+	n.Status = status
+	n.UpdatedAt = o.now().UTC()
+	return o.notifications.Put(ctx, n)
+}
+
+// Retry puts the notification's dead deliveries back in the queue with a
+// fresh set of attempts.
+func (o *Outbox) Retry(ctx context.Context, notificationID string) (*repo.OutboxNotification, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	n, ok, err := o.notifications.Get(ctx, notificationID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errNotificationNotFound
+	}
+	ds, err := o.deliveries.ListByNotification(ctx, notificationID)
+	if err != nil {
+		return nil, err
+	}
+	now := o.now().UTC()
+	retried := 0
+	for i := range ds {
+		d := &ds[i]
+		if d.Status != DeliveryDead {
+			continue
+		}
+		d.Status = DeliveryPending
+		d.Attempts = 0
+		d.NextAttemptAt = now
+		d.UpdatedAt = now
+		if err := o.deliveries.Put(ctx, d); err != nil {
+			return nil, err
+		}
+		retried++
+	}
+	if retried > 0 {
+		n.Status = StatusSending
+		n.UpdatedAt = now
+		if err := o.notifications.Put(ctx, n); err != nil {
+			return nil, err
+		}
+		o.poke()
+	}
+	return n, nil
+}
+
+// backoff is the wait after the given number of failed attempts.
+func backoff(attempts int) time.Duration {
+	d := baseBackoff
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
+
+// deliveryID is stable for a notification, channel and recipient, so
+// resolving a notification twice doesn't deliver it twice.
+func deliveryID(notificationID, channel, username, endpoint string) string {
+	sum := sha256.Sum256([]byte(channel + "\x00" + username + "\x00" + endpoint))
+	return notificationID + "_" + hex.EncodeToString(sum[:6])
+}
+
+func newID(prefix string) string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return prefix + hex.EncodeToString(b)
+}
@@ -0,0 +1,307 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/depots"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/push"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo/memory"
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+// fakePusher has a subscription per endpoint and fails pushes to the
+// endpoints in fail.
+type fakePusher struct {
+	subs   []*push.Subscription
+	fail   map[string]error
+	pushed map[string]int
+}
+
+func newFakePusher(subs map[string]string) *fakePusher {
+	p := &fakePusher{fail: map[string]error{}, pushed: map[string]int{}}
+	for endpoint, username := range subs {
+		p.subs = append(p.subs, &push.Subscription{Subscription: webpush.Subscription{Endpoint: endpoint}, Username: username})
+	}
+	return p
+}
+
+func (p *fakePusher) Recipients(_ string, a repo.NotificationAudience, _ time.Time) []*push.Subscription {
+	var out []*push.Subscription
+	for _, sub := range p.subs {
+		if len(a.Usernames) == 0 || contains(a.Usernames, sub.Username) {
+			out = append(out, sub)
+		}
+	}
+	return out
+}
+
+func (p *fakePusher) Subscription(endpoint string) (*push.Subscription, bool) {
+	for _, sub := range p.subs {
+		if sub.Endpoint == endpoint {
+			return sub, true
+		}
+	}
+	return nil, false
+}
+
+func (p *fakePusher) Push(sub *push.Subscription, _, _, _ string) error {
+	if err := p.fail[sub.Endpoint]; err != nil {
+		return err
+	}
+	p.pushed[sub.Endpoint]++
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+type testOutbox struct {
+	*Outbox
+	clock  time.Time
+	texted []string
+}
+
+func setup(t *testing.T, p Pusher) *testOutbox {
+	t.Helper()
+	to := &testOutbox{clock: time.Date(2027, 1, 12, 12, 0, 0, 0, time.UTC)}
+	fallback := func(_ context.Context, _, username, _, _ string) (bool, error) {
+		to.texted = append(to.texted, username)
+		return true, nil
+	}
+	to.Outbox = New(memory.NewNotificationsRepo(), memory.NewNotificationDeliveriesRepo(), p, fallback)
+	to.now = func() time.Time { return to.clock }
+	return to
+}
+
+func (to *testOutbox) advance(d time.Duration) {
+	to.clock = to.clock.Add(d)
+	to.process(context.Background())
+}
+
+func (to *testOutbox) status(t *testing.T, id string) string {
+	t.Helper()
+	n, ok, err := to.notifications.Get(context.Background(), id)
+	if err != nil || !ok {
+		t.Fatalf("notification %s: %v", id, err)
+	}
+	return n.Status
+}
+
+// ---- delivery ----
+
+func TestDeliver(t *testing.T) {
+	p := newFakePusher(map[string]string{"rita-phone": "rita", "dave": "dave"})
+	o := setup(t, p)
+	n, err := o.Notify(context.Background(), "job-updates", repo.NotificationAudience{}, "Job delivered", "", "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := o.status(t, n.NotificationID); got != StatusQueued {
+		t.Errorf("status before the worker runs = %s", got)
+	}
+	o.advance(0)
+	if got := o.status(t, n.NotificationID); got != StatusDone {
+		t.Errorf("status = %s, want done", got)
+	}
+	if p.pushed["rita-phone"] != 1 || p.pushed["dave"] != 1 {
+		t.Errorf("pushed %v", p.pushed)
+	}
+	o.advance(time.Hour)
+	if p.pushed["rita-phone"] != 1 {
+		t.Error("a sent delivery should not be sent again")
+	}
+}
+
+func TestRetryBackoffAndDeadLetter(t *testing.T) {
+	p := newFakePusher(map[string]string{"dave": "dave"})
+	p.fail["dave"] = errors.New("push service returned 500")
+	o := setup(t, p)
+	o.fallback = nil
+	n, _ := o.Notify(context.Background(), "events", repo.NotificationAudience{}, "Shift uncovered", "", "/")
+
+	o.advance(0)
+	ds, _ := o.deliveries.ListByNotification(context.Background(), n.NotificationID)
+	if len(ds) != 1 || ds[0].Attempts != 1 || ds[0].Status != DeliveryPending {
+		t.Fatalf("after one failure: %+v", ds)
+	}
+	if want := o.clock.Add(baseBackoff); !ds[0].NextAttemptAt.Equal(want) {
+		t.Errorf("next attempt %v, want %v", ds[0].NextAttemptAt, want)
+	}
+
+	o.advance(baseBackoff / 2)
+	ds, _ = o.deliveries.ListByNotification(context.Background(), n.NotificationID)
+	if ds[0].Attempts != 1 {
+		t.Error("should not retry before the backoff")
+	}
+	for i := 0; i < maxAttempts; i++ {
+		o.advance(maxBackoff)
+	}
+	ds, _ = o.deliveries.ListByNotification(context.Background(), n.NotificationID)
+	if ds[0].Status != DeliveryDead || ds[0].Attempts != maxAttempts {
+		t.Errorf("expected dead after %d attempts, got %+v", maxAttempts, ds[0])
+	}
+	if got := o.status(t, n.NotificationID); got != StatusFailed {
+		t.Errorf("status = %s, want failed", got)
+	}
+
+	delete(p.fail, "dave")
+	if _, err := o.Retry(context.Background(), n.NotificationID); err != nil {
+		t.Fatal(err)
+	}
+	o.advance(0)
+	if got := o.status(t, n.NotificationID); got != StatusDone || p.pushed["dave"] != 1 {
+		t.Errorf("after retry: status %s, pushed %v", got, p.pushed)
+	}
+}
+
+func TestFallback(t *testing.T) {
+	p := newFakePusher(map[string]string{"rita-phone": "rita"})
+	p.fail["rita-phone"] = push.ErrGone
+	o := setup(t, p)
+	n, _ := o.Notify(context.Background(), "job-updates", repo.NotificationAudience{Usernames: []string{"rita", "sam"}}, "Job delivered", "", "/")
+
+	o.advance(0)
+	if len(o.texted) != 1 || o.texted[0] != "sam" {
+		t.Errorf("sam has no subscription and should be texted straight away, texted %v", o.texted)
+	}
+	o.advance(0)
+	if len(o.texted) != 2 || o.texted[1] != "rita" {
+		t.Errorf("rita's push is gone so they should be texted, texted %v", o.texted)
+	}
+	if got := o.status(t, n.NotificationID); got != StatusFailed {
+		t.Errorf("status = %s, want failed (the push delivery is dead)", got)
+	}
+	o.advance(time.Hour)
+	if len(o.texted) != 2 {
+		t.Errorf("fallbacks should happen once, texted %v", o.texted)
+	}
+}
+
+func TestFallback_RoleAudience(t *testing.T) {
+	// rita is a rider on push; sam is a rider with SMS only; dave dispatches.
+	p := newFakePusher(map[string]string{"rita-phone": "rita"})
+	o := setup(t, p)
+	users := memory.NewUsersRepo()
+	ctx := context.Background()
+	_ = users.Put(ctx, &repo.User{RiderID: "rita", Tags: []string{"Rider"}})
+	_ = users.Put(ctx, &repo.User{RiderID: "sam", Tags: []string{"Rider"}})
+	_ = users.Put(ctx, &repo.User{RiderID: "dave", Tags: []string{"Dispatcher"}})
+	o.SetUsersRepository(users)
+
+	o.NotifyRole("new-jobs", "Rider", "New Job Posted", "", "/jobs")
+	o.advance(0)
+	if len(o.texted) != 1 || o.texted[0] != "sam" {
+		t.Errorf("only sam should be texted, texted %v", o.texted)
+	}
+	if p.pushed["rita-phone"] != 1 {
+		t.Errorf("rita should get the push, pushed %v", p.pushed)
+	}
+
+	// Without push at all every rider is texted.
+	o = setup(t, nil)
+	o.SetUsersRepository(users)
+	o.NotifyRole("new-jobs", "Rider", "New Job Posted", "", "/jobs")
+	o.advance(0)
+	if len(o.texted) != 2 {
+		t.Errorf("both riders should be texted without push, texted %v", o.texted)
+	}
+}
+
+func TestNotifyRegion_TextsDepotRiders(t *testing.T) {
+	o := setup(t, nil)
+	users := memory.NewUsersRepo()
+	ctx := context.Background()
+	_ = users.Put(ctx, &repo.User{RiderID: "gus", Depot: "galway"})
+	_ = users.Put(ctx, &repo.User{RiderID: "abe", Depot: "athlone"})
+	depots.SetRepository(memory.NewDepotsRepo())
+	depots.SetFleetRepositories(nil, users)
+	t.Cleanup(func() {
+		depots.SetRepository(nil)
+		depots.SetFleetRepositories(nil, nil)
+	})
+	if _, err := depots.Create(ctx, depots.Request{DepotID: "galway", Name: "Galway"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := o.NotifyRegion(ctx, "new-jobs", "Galway", "New Job Posted", "", "/jobs"); err != nil {
+		t.Fatalf("NotifyRegion: %v", err)
+	}
+	o.advance(0)
+	if len(o.texted) != 1 || o.texted[0] != "gus" {
+		t.Errorf("only gus should be texted, texted %v", o.texted)
+	}
+	if err := o.NotifyRegion(ctx, "new-jobs", "Nowhere", "x", "", ""); !errors.Is(err, depots.ErrUnknownDepot) {
+		t.Errorf("expected unknown depot error, got %v", err)
+	}
+}
+
+func TestPurgeAfterRetention(t *testing.T) {
+	p := newFakePusher(map[string]string{"dave": "dave"})
+	o := setup(t, p)
+	ctx := context.Background()
+	old, _ := o.Notify(ctx, "events", repo.NotificationAudience{}, "Old", "", "/")
+	o.advance(0)
+
+	o.advance(retention - time.Hour)
+	recent, _ := o.Notify(ctx, "events", repo.NotificationAudience{}, "Recent", "", "/")
+	o.advance(0)
+	o.advance(2 * time.Hour)
+
+	if _, ok, _ := o.notifications.Get(ctx, old.NotificationID); ok {
+		t.Error("expected the old notification to be purged")
+	}
+	if ds, _ := o.deliveries.ListByNotification(ctx, old.NotificationID); len(ds) != 0 {
+		t.Errorf("expected the old deliveries to be purged, got %+v", ds)
+	}
+	if got := o.status(t, recent.NotificationID); got != StatusDone {
+		t.Errorf("recent status = %s, want done and kept", got)
+	}
+	if ds, _ := o.deliveries.ListByNotification(ctx, recent.NotificationID); len(ds) != 1 {
+		t.Errorf("expected the recent delivery to be kept, got %+v", ds)
+	}
+}
+
+func TestRestartPicksUpQueued(t *testing.T) {
+	p := newFakePusher(map[string]string{"dave": "dave"})
+	notifications := memory.NewNotificationsRepo()
+	deliveries := memory.NewNotificationDeliveriesRepo()
+	first := New(notifications, deliveries, p, nil)
+	n, _ := first.Notify(context.Background(), "events", repo.NotificationAudience{}, "Shift uncovered", "", "/")
+
+	// A new outbox over the same storage, as after a restart.
+	second := New(notifications, deliveries, p, nil)
+	second.process(context.Background())
+	got, _, _ := notifications.Get(context.Background(), n.NotificationID)
+	if got.Status != StatusDone || p.pushed["dave"] != 1 {
+		t.Errorf("after restart: status %s, pushed %v", got.Status, p.pushed)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	if backoff(1) != baseBackoff || backoff(2) != 2*baseBackoff || backoff(20) != maxBackoff {
+		t.Errorf("backoff: %v %v %v", backoff(1), backoff(2), backoff(20))
+	}
+}
+
+// ---- admin endpoint ----
+
+func TestHandleAdmin_RequiresAuth(t *testing.T) {
+	o := setup(t, nil)
+	rec := httptest.NewRecorder()
+	o.HandleAdmin(rec, httptest.NewRequest(http.MethodGet, "/api/admin/notifications", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("got %d, want 401", rec.Code)
+	}
+}
@@ -0,0 +1,55 @@
+// Package phonenum normalises phone numbers to E.164 ("+353871234567").
+// Numbers without a country code are taken as Irish.
+package phonenum
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalid is returned for numbers that can't be normalised.
+var ErrInvalid = errors.New("phone must be an Irish mobile (e.g. 087 123 4567) or an international number starting with +")
+
+const irishPrefix = "+353"
+
+// E164 normalises raw to E.164. Irish numbers may be written nationally
+// ("087 123 4567"), with 00 or without the + ("00353 87…", "353 87…"),
+// and must be mobiles (08x), since they are used for SMS. Other countries
+// need the + and are only checked for length.
+func E164(raw string) (string, error) {
+	s := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')', '\t':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(raw))
+
+	switch {
+	case strings.HasPrefix(s, "+"):
+	case strings.HasPrefix(s, "00"):
+		s = "+" + s[2:]
+	case strings.HasPrefix(s, "353"):
+		s = "+" + s
+	case strings.HasPrefix(s, "0"):
+		s = irishPrefix + s[1:]
+	default:
+		return "", ErrInvalid
+	}
+	// A national trunk 0 kept after the country code: +353 (0)87…
+	if strings.HasPrefix(s, irishPrefix+"0") {
+		s = irishPrefix + s[len(irishPrefix)+1:]
+	}
+
+	digits := s[1:]
+	if len(digits) < 8 || len(digits) > 15 || strings.TrimLeft(digits, "0123456789") != "" {
+		return "", ErrInvalid
+	}
+	if strings.HasPrefix(s, irishPrefix) {
+		national := s[len(irishPrefix):]
+		if len(national) != 9 || national[0] != '8' {
+			return "", ErrInvalid
+		}
+	}
+	return s, nil
+}
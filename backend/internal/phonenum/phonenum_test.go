@@ -0,0 +1,26 @@
+package phonenum
+
+import "testing"
+
+func TestE164(t *testing.T) {
+	ok := map[string]string{
+		"087 123 4567":       "+353871234567",
+		"(087) 123-4567":     "+353871234567",
+		"+353 87 123 4567":   "+353871234567",
+		"+353 (0)87 1234567": "+353871234567",
+		"00353871234567":     "+353871234567",
+		"353851234567":       "+353851234567",
+		"+44 7700 900123":    "+447700900123",
+	}
+	for in, want := range ok {
+		got, err := E164(in)
+		if err != nil || got != want {
+			t.Errorf("E164(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "091 123456", "+353 1 234 5678", "87 123 4567", "+353 87 123", "087 12a 4567", "+1234"} {
+		if got, err := E164(in); err == nil {
+			t.Errorf("E164(%q) = %q, want an error", in, got)
+		}
+	}
+}
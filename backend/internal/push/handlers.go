@@ -5,11 +5,14 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"time"
 
-	webpush "github.com/SherClockHolmes/webpush-go"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/auth"
 )
 
-// HandleVAPIDPublicKey returns the VAPID public key so the frontend can subscribe.
+// HandleVAPIDPublicKey returns the VAPID public key so the frontend can
+// subscribe, and its ID for the frontend to send back with the
+// subscription.
 // GET /api/push/vapid-key
 func (s *Store) HandleVAPIDPublicKey(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -18,12 +21,15 @@ func (s *Store) HandleVAPIDPublicKey(w http.ResponseWriter, r *http.Request) {
 	}
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]string{
-		"publicKey": s.vapidPublic,
+		"publicKey": s.current.Public,
+		"keyId":     s.current.ID,
 	})
 }
 
-// HandleSubscribe stores a push subscription from the client.
-// POST /api/push/subscribe  { "endpoint": "...", "keys": { "p256dh": "...", "auth": "..." } }
+// HandleSubscribe stores a push subscription from the client, owned by the
+// caller and tagged with their roles and the VAPID key it was made with
+// (keyId; the current key if left out).
+// POST /api/push/subscribe  { "endpoint": "...", "keys": { "p256dh": "...", "auth": "..." }, "keyId": "..." }
 func (s *Store) HandleSubscribe(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -33,8 +39,8 @@ func (s *Store) HandleSubscribe(w http.ResponseWriter, r *http.Request) {
 	body, _ := io.ReadAll(r.Body)
 	log.Printf("Push subscribe raw body: %s", string(body))
 
-	var sub webpush.Subscription
-	if err := json.Unmarshal(body, &sub); err != nil {
+	var sub Subscription
+	if err := json.Unmarshal(body, &sub.Subscription); err != nil {
 		log.Printf("Push subscribe decode error: %v", err)
 		http.Error(w, "invalid JSON", http.StatusBadRequest)
 		return
@@ -43,11 +49,31 @@ func (s *Store) HandleSubscribe(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "endpoint required", http.StatusBadRequest)
 		return
 	}
+	if err := ValidateSubscriptionKeys(sub.Keys); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var key struct {
+		KeyID string `json:"keyId"`
+	}
+	_ = json.Unmarshal(body, &key)
+	if key.KeyID != "" && !s.HasKey(key.KeyID) {
+		http.Error(w, "keyId is not a current VAPID key; subscribe again with the key from /api/push/vapid-key", http.StatusConflict)
+		return
+	}
+	sub.KeyID = key.KeyID
 	log.Printf("Push subscribe parsed: endpoint=%s p256dh=%s auth=%s",
 		truncate(sub.Endpoint, 60),
 		truncate(sub.Keys.P256dh, 20),
 		truncate(sub.Keys.Auth, 20))
 
+	sub.Username = auth.UsernameFromContext(r.Context())
+	sub.CreatedAt = time.Now().UTC()
+	if sub.Username == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	if err := s.Subscribe(&sub); err != nil {
 		http.Error(w, "failed to subscribe", http.StatusInternalServerError)
 		return
@@ -79,14 +105,15 @@ func (s *Store) HandleUnsubscribe(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(map[string]string{"status": "unsubscribed"})
 }
 
-// HandleTestNotification sends a test push to all subscribers.
+// HandleTestNotification sends a test push to the caller's devices.
 // POST /api/push/test
 func (s *Store) HandleTestNotification(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	count := s.SubscriberCount()
+	username := auth.UsernameFromContext(r.Context())
+	count := s.UserSubscriberCount(username)
 	if count == 0 {
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(map[string]any{
@@ -95,7 +122,7 @@ func (s *Store) HandleTestNotification(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
-	s.NotifyAll("\U0001F514 Test Notification", "If you see this, push notifications are working!", "/")
+	s.NotifyUser("", username, "\U0001F514 Test Notification", "If you see this, push notifications are working!", "/")
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]any{
 		"status":      "sent",
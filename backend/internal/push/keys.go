@@ -0,0 +1,139 @@
+package push
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+// ErrKeyRetired is returned by Push when the subscription was made with a
+// VAPID key the server no longer has. The browser has to subscribe again.
+var ErrKeyRetired = errors.New("push subscription uses a retired VAPID key")
+
+// VAPIDKey is a VAPID key pair, base64url encoded as the browser and
+// webpush-go expect. Browsers bind a subscription to the public key it was
+// made with, so every subscription records the ID of its key and is sent
+// with that key until the user subscribes again.
+type VAPIDKey struct {
+	ID      string
+	Public  string
+	Private string
+}
+
+// NewVAPIDKey returns the key pair with its ID filled in, after checking
+// the private key belongs to the public one.
+func NewVAPIDKey(public, private string) (VAPIDKey, error) {
+	k := VAPIDKey{ID: KeyID(public), Public: strings.TrimSpace(public), Private: strings.TrimSpace(private)}
+	pub, err := decodeKey(k.Public)
+	if err != nil {
+		return k, fmt.Errorf("VAPID public key: %w", err)
+	}
+	priv, err := decodeKey(k.Private)
+	if err != nil {
+		return k, fmt.Errorf("VAPID private key: %w", err)
+	}
+	key, err := ecdh.P256().NewPrivateKey(priv)
+	if err != nil {
+		return k, fmt.Errorf("VAPID private key: %w", err)
+	}
+	if !bytes.Equal(key.PublicKey().Bytes(), pub) {
+		return k, fmt.Errorf("VAPID private key %s does not match its public key", k.ID)
+	}
+	return k, nil
+}
+
+// GenerateVAPIDKey creates a new key pair.
+func GenerateVAPIDKey() (VAPIDKey, error) {
+	private, public, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		return VAPIDKey{}, err
+	}
+	return NewVAPIDKey(public, private)
+}
+
+// KeyID is a short, stable ID for a public key.
+func KeyID(public string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(public)))
+	return base64.RawURLEncoding.EncodeToString(sum[:6])
+}
+
+// KeysFromEnv reads the current key pair from VAPID_PUBLIC_KEY and
+// VAPID_PRIVATE_KEY, and the keys it replaced, still used by subscriptions
+// made before a rotation, from VAPID_PREVIOUS_KEYS as comma-separated
+// "public:private" pairs.
+func KeysFromEnv() (current VAPIDKey, previous []VAPIDKey, err error) {
+	pub, priv := os.Getenv("VAPID_PUBLIC_KEY"), os.Getenv("VAPID_PRIVATE_KEY")
+	if pub == "" || priv == "" {
+		return current, nil, errors.New("VAPID_PUBLIC_KEY and VAPID_PRIVATE_KEY must be set")
+	}
+	if current, err = NewVAPIDKey(pub, priv); err != nil {
+		return current, nil, err
+	}
+	previous, err = ParsePreviousKeys(os.Getenv("VAPID_PREVIOUS_KEYS"))
+	return current, previous, err
+}
+
+// ParsePreviousKeys parses a VAPID_PREVIOUS_KEYS value.
+func ParsePreviousKeys(s string) ([]VAPIDKey, error) {
+	var out []VAPIDKey
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		pub, priv, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, errors.New("VAPID_PREVIOUS_KEYS must be comma-separated public:private pairs")
+		}
+		k, err := NewVAPIDKey(pub, priv)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, k)
+	}
+	return out, nil
+}
+
+// FormatPreviousKeys is the VAPID_PREVIOUS_KEYS value for keys.
+func FormatPreviousKeys(keys []VAPIDKey) string {
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k.Public + ":" + k.Private
+	}
+	return strings.Join(pairs, ",")
+}
+
+// ValidateSubscriptionKeys checks a subscription's keys can encrypt a
+// payload (RFC 8291): p256dh must be a P-256 public key and auth a 16-byte
+// secret. Without this a bad subscription is only found when a send fails.
+func ValidateSubscriptionKeys(keys webpush.Keys) error {
+	dh, err := decodeKey(keys.P256dh)
+	if err != nil {
+		return fmt.Errorf("keys.p256dh must be base64url: %w", err)
+	}
+	if _, err := ecdh.P256().NewPublicKey(dh); err != nil {
+		return errors.New("keys.p256dh must be a P-256 public key")
+	}
+	secret, err := decodeKey(keys.Auth)
+	if err != nil {
+		return fmt.Errorf("keys.auth must be base64url: %w", err)
+	}
+	if len(secret) != 16 {
+		return errors.New("keys.auth must be 16 bytes")
+	}
+	return nil
+}
+
+// decodeKey decodes base64url or standard base64, padded or not, as
+// browsers and tools differ.
+func decodeKey(s string) ([]byte, error) {
+	s = strings.TrimRight(strings.TrimSpace(s), "=")
+	s = strings.NewReplacer("+", "-", "/", "_").Replace(s)
+	return base64.RawURLEncoding.DecodeString(s)
+}
@@ -1,39 +1,66 @@
 package push
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/auth"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/depots"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/notifyprefs"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
 	webpush "github.com/SherClockHolmes/webpush-go"
 	bolt "go.etcd.io/bbolt"
 )
 
 var bucketName = []byte("push_subscriptions")
 
+// Subscription is a browser's push subscription and the user it belongs
+// to. Role audiences are looked up in the users repository when sending,
+// so a user's current roles decide what they get. Subscriptions saved
+// before users were recorded have no Username and only get NotifyAll.
+// KeyID is the VAPID key the browser subscribed with (see keys.go).
+type Subscription struct {
+	webpush.Subscription
+	Username  string    `json:"username,omitempty"`
+	KeyID     string    `json:"keyId,omitempty"`
+	CreatedAt time.Time `json:"createdAt,omitempty"`
+}
+
 // Store manages Web Push subscriptions, persisted in bbolt.
 type Store struct {
 	db            *bolt.DB
-	vapidPublic   string
-	vapidPrivate  string
+	current       VAPIDKey
+	keys          map[string]VAPIDKey // current and previous keys, by ID
 	vapidContact  string
 	mu            sync.RWMutex
-	subscriptions map[string]*webpush.Subscription // key = endpoint
+	subscriptions map[string]*Subscription // key = endpoint
+	users         repo.UsersRepository
 }
 
+// ErrGone is returned by Push when the push service says the subscription
+// no longer exists; the subscription has been removed.
+var ErrGone = errors.New("push subscription expired")
+
 // NewStore opens (or creates) the push subscription database.
+// Subscriptions saved before key IDs were recorded are stamped with the
+// current key, which is the one they were made with unless the keys were
+// changed by hand.
 func NewStore() (*Store, error) {
-	vapidPub := os.Getenv("VAPID_PUBLIC_KEY")
-	vapidPriv := os.Getenv("VAPID_PRIVATE_KEY")
-	vapidContact := os.Getenv("VAPID_CONTACT")
-	if vapidPub == "" || vapidPriv == "" {
-		return nil, fmt.Errorf("VAPID_PUBLIC_KEY and VAPID_PRIVATE_KEY must be set")
+	current, previous, err := KeysFromEnv()
+	if err != nil {
+		return nil, err
 	}
+	vapidContact := os.Getenv("VAPID_CONTACT")
 	if vapidContact == "" {
 		vapidContact = "admin@bloodbike.app"
 	}
@@ -60,17 +87,20 @@ func NewStore() (*Store, error) {
 
 	s := &Store{
 		db:            db,
-		vapidPublic:   vapidPub,
-		vapidPrivate:  vapidPriv,
+		current:       current,
+		keys:          map[string]VAPIDKey{current.ID: current},
 		vapidContact:  vapidContact,
-		subscriptions: make(map[string]*webpush.Subscription),
+		subscriptions: make(map[string]*Subscription),
+	}
+	for _, k := range previous {
+		s.keys[k.ID] = k
 	}
 
 	// Load existing subscriptions into memory
 	_ = db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket(bucketName)
 		return b.ForEach(func(k, v []byte) error {
-			var sub webpush.Subscription
+			var sub Subscription
 			if err := json.Unmarshal(v, &sub); err == nil {
 				s.subscriptions[string(k)] = &sub
 			}
@@ -78,17 +108,53 @@ func NewStore() (*Store, error) {
 		})
 	})
 
-	log.Printf("Push store initialized with %d subscription(s)", len(s.subscriptions))
+	for _, sub := range s.subscriptions {
+		if sub.KeyID == "" {
+			sub.KeyID = current.ID
+			if err := s.Subscribe(sub); err != nil {
+				log.Printf("Push: failed to record key ID for %s...: %v", truncate(sub.Endpoint, 40), err)
+			}
+		}
+	}
+
+	log.Printf("Push store initialized with %d subscription(s), VAPID key %s (%d previous)", len(s.subscriptions), current.ID, len(previous))
 	return s, nil
 }
 
+// SetUsersRepository lets role audiences be resolved to the users who hold
+// the role now. Without it role notifications reach nobody.
+func (s *Store) SetUsersRepository(r repo.UsersRepository) {
+	s.users = r
+}
+
+// Close closes the subscription database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
 // VAPIDPublicKey returns the public VAPID key for the frontend.
 func (s *Store) VAPIDPublicKey() string {
-	return s.vapidPublic
+	return s.current.Public
+}
+
+// CurrentKey returns the key new subscriptions are made with.
+func (s *Store) CurrentKey() VAPIDKey {
+	return s.current
+}
+
+// HasKey reports whether the server still has the key with the ID.
+func (s *Store) HasKey(id string) bool {
+	_, ok := s.keys[id]
+	return ok
 }
 
-// Subscribe persists a push subscription.
-func (s *Store) Subscribe(sub *webpush.Subscription) error {
+// Subscribe persists a push subscription, replacing any earlier one for
+// the same endpoint. A subscription with no KeyID is taken to use the
+// current key.
+func (s *Store) Subscribe(sub *Subscription) error {
+	if sub.KeyID == "" {
+		sub.KeyID = s.current.ID
+	}
 	data, err := json.Marshal(sub)
 	if err != nil {
 		return err
@@ -101,7 +167,7 @@ func (s *Store) Subscribe(sub *webpush.Subscription) error {
 	s.mu.Lock()
 	s.subscriptions[sub.Endpoint] = sub
 	s.mu.Unlock()
-	log.Printf("Push subscription added: %s...", truncate(sub.Endpoint, 60))
+	log.Printf("Push subscription added for %q: %s...", sub.Username, truncate(sub.Endpoint, 60))
 	return nil
 }
 
@@ -119,21 +185,190 @@ func (s *Store) Unsubscribe(endpoint string) error {
 	return nil
 }
 
+// Every Notify method takes the notification's category (see notifyprefs)
+// and skips users whose preferences turn it, or push, off, or who are in
+// their quiet hours. An empty category is delivered regardless. They send
+// once and only log failures; the notification outbox uses Recipients and
+// Push instead, so it can track and retry each delivery.
+
 // NotifyAll sends a push notification to all subscribers.
 // Failed/expired subscriptions are automatically removed.
-func (s *Store) NotifyAll(title, body, url string) {
+func (s *Store) NotifyAll(category, title, body, url string) {
+	s.deliver(category, func(*Subscription) bool { return true }, title, body, url)
+}
+
+// NotifyUser sends a push notification to every device the user has
+// subscribed.
+func (s *Store) NotifyUser(category, username, title, body, url string) {
+	s.NotifyUsers(category, []string{username}, title, body, url)
+}
+
+// NotifyUsers sends a push notification to every device the users have
+// subscribed.
+func (s *Store) NotifyUsers(category string, usernames []string, title, body, url string) {
+	s.deliver(category, forUsers(usernames), title, body, url)
+}
+
+// NotifyRole sends a push notification to users with the role, so
+// NotifyRole("Rider") reaches riders but not dispatchers.
+func (s *Store) NotifyRole(category, role, title, body, url string) {
+	s.deliver(category, forUsers(s.withRole(context.Background(), role, false)), title, body, url)
+}
+
+// NotifyRoleOrAbove sends a push notification to users with the role or
+// a higher one, so NotifyRoleOrAbove("Dispatcher") also reaches fleet
+// managers and admins.
+func (s *Store) NotifyRoleOrAbove(category, role, title, body, url string) {
+	s.deliver(category, forUsers(s.withRole(context.Background(), role, true)), title, body, url)
+}
+
+// NotifyRegion sends a push notification to the users based at a depot
+// (by ID or name).
+func (s *Store) NotifyRegion(ctx context.Context, category, region, title, body, url string) error {
+	usernames, err := depots.RiderIDsIn(ctx, region)
+	if err != nil {
+		return err
+	}
+	s.NotifyUsers(category, usernames, title, body, url)
+	return nil
+}
+
+// deliver sends to the subscriptions match selects whose users want the
+// category by push right now.
+func (s *Store) deliver(category string, match func(*Subscription) bool, title, body, url string) {
+	s.send(s.subscribersWhere(wants(category, time.Now(), match)), title, body, url)
+}
+
+// Recipients returns the subscriptions of the audience whose users want
+// the category by push at now.
+func (s *Store) Recipients(category string, a repo.NotificationAudience, now time.Time) []*Subscription {
+	match := func(*Subscription) bool { return true }
+	switch {
+	case len(a.Usernames) > 0:
+		match = forUsers(a.Usernames)
+	case a.Role != "":
+		match = forUsers(s.withRole(context.Background(), a.Role, a.OrAbove))
+	}
+	return s.subscribersWhere(wants(category, now, match))
+}
+
+// Subscription returns the subscription for an endpoint, if it still
+// exists.
+func (s *Store) Subscription(endpoint string) (*Subscription, bool) {
 	s.mu.RLock()
-	subs := make([]*webpush.Subscription, 0, len(s.subscriptions))
+	defer s.mu.RUnlock()
+	sub, ok := s.subscriptions[endpoint]
+	return sub, ok
+}
+
+// Subscriptions returns every subscription, oldest first.
+func (s *Store) Subscriptions() []*Subscription {
+	subs := s.subscribersWhere(func(*Subscription) bool { return true })
+	sort.Slice(subs, func(i, j int) bool { return subs[i].CreatedAt.Before(subs[j].CreatedAt) })
+	return subs
+}
+
+// Undeliverable says why nothing can be sent to the subscription, without
+// asking the push service, or returns "" when nothing is known to be
+// wrong with it.
+func (s *Store) Undeliverable(sub *Subscription) string {
+	if !s.HasKey(sub.KeyID) {
+		return ErrKeyRetired.Error() + " (" + sub.KeyID + ")"
+	}
+	if err := ValidateSubscriptionKeys(sub.Keys); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// subscribersWhere returns the subscriptions match selects.
+func (s *Store) subscribersWhere(match func(*Subscription) bool) []*Subscription {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	subs := make([]*Subscription, 0, len(s.subscriptions))
 	for _, sub := range s.subscriptions {
-		subs = append(subs, sub)
+		if match(sub) {
+			subs = append(subs, sub)
+		}
 	}
-	s.mu.RUnlock()
+	return subs
+}
 
+func forUsers(usernames []string) func(*Subscription) bool {
+	want := make(map[string]bool, len(usernames))
+	for _, u := range usernames {
+		if u != "" {
+			want[u] = true
+		}
+	}
+	return func(sub *Subscription) bool { return want[sub.Username] }
+}
+
+// withRole lists the users holding the role (or, with orAbove, a higher
+// one) according to the users repository.
+func (s *Store) withRole(ctx context.Context, role string, orAbove bool) []string {
+	if s.users == nil {
+		log.Printf("Push: no users repository, nobody gets the %s notification", role)
+		return nil
+	}
+	users, err := s.users.List(ctx)
+	if err != nil {
+		log.Printf("Push: failed to list users with role %s: %v", role, err)
+		return nil
+	}
+	var out []string
+	for _, u := range users {
+		if (orAbove && auth.HasRoleOrAbove(u.Tags, role)) || (!orAbove && auth.HasRole(u.Tags, role)) {
+			out = append(out, u.RiderID)
+		}
+	}
+	return out
+}
+
+// wants narrows match to subscriptions whose users' preferences allow the
+// category by push at now. Subscriptions with no user can't have
+// preferences and are left in.
+func wants(category string, now time.Time, match func(*Subscription) bool) func(*Subscription) bool {
+	allowed := make(map[string]bool)
+	return func(sub *Subscription) bool {
+		if !match(sub) {
+			return false
+		}
+		if sub.Username == "" {
+			return true
+		}
+		ok, seen := allowed[sub.Username]
+		if !seen {
+			ok = notifyprefs.Allowed(context.Background(), sub.Username, category, notifyprefs.ChannelPush, now)
+			allowed[sub.Username] = ok
+		}
+		return ok
+	}
+}
+
+// send delivers the notification to subs.
+// Failed/expired subscriptions are automatically removed.
+func (s *Store) send(subs []*Subscription, title, body, url string) {
 	if len(subs) == 0 {
-		log.Println("Push: no subscribers to notify")
+		log.Printf("Push: no subscribers to notify: %s", title)
 		return
 	}
+	log.Printf("Push: sending notification to %d subscriber(s): %s", len(subs), title)
 
+	var wg sync.WaitGroup
+	for _, sub := range subs {
+		wg.Add(1)
+		go func(sub *Subscription) {
+			defer wg.Done()
+			_ = s.Push(sub, title, body, url)
+		}(sub)
+	}
+	wg.Wait()
+}
+
+// Push sends the notification to one subscription. A subscription the
+// push service reports gone is removed and ErrGone returned.
+func (s *Store) Push(sub *Subscription, title, body, url string) error {
 	payload, _ := json.Marshal(map[string]any{
 		"notification": map[string]any{
 			"title":   title,
@@ -150,37 +385,50 @@ func (s *Store) NotifyAll(title, body, url string) {
 		},
 	})
 
-	log.Printf("Push: sending notification to %d subscriber(s): %s", len(subs), title)
+	err := s.post(sub, payload, 60, webpush.UrgencyHigh)
+	if errors.Is(err, ErrGone) {
+		_ = s.Unsubscribe(sub.Endpoint)
+	}
+	return err
+}
 
-	var wg sync.WaitGroup
-	for _, sub := range subs {
-		wg.Add(1)
-		go func(sub *webpush.Subscription) {
-			defer wg.Done()
-			resp, err := webpush.SendNotification(payload, sub, &webpush.Options{
-				Subscriber:      s.vapidContact,
-				VAPIDPublicKey:  s.vapidPublic,
-				VAPIDPrivateKey: s.vapidPrivate,
-				TTL:             60,
-				Urgency:         webpush.UrgencyHigh,
-			})
-			if err != nil {
-				log.Printf("Push send error (%s...): %v", truncate(sub.Endpoint, 40), err)
-				return
-			}
-			defer resp.Body.Close()
-			respBody, _ := io.ReadAll(resp.Body)
-			if resp.StatusCode >= 400 {
-				log.Printf("Push rejected (%s...): status %d body=%s", truncate(sub.Endpoint, 40), resp.StatusCode, string(respBody))
-				if resp.StatusCode == 404 || resp.StatusCode == 410 {
-					_ = s.Unsubscribe(sub.Endpoint)
-				}
-			} else {
-				log.Printf("Push sent OK (%s...): status %d", truncate(sub.Endpoint, 40), resp.StatusCode)
-			}
-		}(sub)
+// Probe sends the subscription an empty message, which the app's service
+// worker ignores, to find out whether the push service still has it. It
+// returns ErrGone if not, leaving the subscription for the caller to
+// remove.
+func (s *Store) Probe(sub *Subscription) error {
+	return s.post(sub, nil, 0, webpush.UrgencyVeryLow)
+}
+
+// post encrypts the payload for the subscription (RFC 8291, done by
+// webpush-go) and sends it signed with the subscription's VAPID key.
+func (s *Store) post(sub *Subscription, payload []byte, ttl int, urgency webpush.Urgency) error {
+	key, ok := s.keys[sub.KeyID]
+	if !ok {
+		return fmt.Errorf("%w (%s)", ErrKeyRetired, sub.KeyID)
 	}
-	wg.Wait()
+	resp, err := webpush.SendNotification(payload, &sub.Subscription, &webpush.Options{
+		Subscriber:      s.vapidContact,
+		VAPIDPublicKey:  key.Public,
+		VAPIDPrivateKey: key.Private,
+		TTL:             ttl,
+		Urgency:         urgency,
+	})
+	if err != nil {
+		log.Printf("Push send error (%s...): %v", truncate(sub.Endpoint, 40), err)
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 400 {
+		log.Printf("Push rejected (%s...): status %d body=%s", truncate(sub.Endpoint, 40), resp.StatusCode, string(respBody))
+		if resp.StatusCode == 404 || resp.StatusCode == 410 {
+			return ErrGone
+		}
+		return fmt.Errorf("push service returned %d", resp.StatusCode)
+	}
+	log.Printf("Push sent OK (%s...): status %d", truncate(sub.Endpoint, 40), resp.StatusCode)
+	return nil
 }
 
 // SubscriberCount returns the number of active push subscribers.
@@ -190,6 +438,11 @@ func (s *Store) SubscriberCount() int {
 	return len(s.subscriptions)
 }
 
+// UserSubscriberCount returns how many devices the user has subscribed.
+func (s *Store) UserSubscriberCount(username string) int {
+	return len(s.subscribersWhere(forUsers([]string{username})))
+}
+
 func truncate(s string, n int) string {
 	if len(s) <= n {
 		return s
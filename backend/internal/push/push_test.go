@@ -0,0 +1,209 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/notifyprefs"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo/memory"
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+func testStore() *Store {
+	users := memory.NewUsersRepo()
+	ctx := context.Background()
+	_ = users.Put(ctx, &repo.User{RiderID: "rita", Tags: []string{"Rider"}})
+	_ = users.Put(ctx, &repo.User{RiderID: "dave", Tags: []string{"Dispatcher"}})
+	_ = users.Put(ctx, &repo.User{RiderID: "fran", Tags: []string{"FleetManager"}})
+	_ = users.Put(ctx, &repo.User{RiderID: "ada", Tags: []string{"BloodBikeAdmin", "Rider"}})
+	s := &Store{subscriptions: make(map[string]*Subscription), users: users}
+	for _, sub := range []Subscription{
+		{Subscription: webpush.Subscription{Endpoint: "rita-phone"}, Username: "rita"},
+		{Subscription: webpush.Subscription{Endpoint: "rita-laptop"}, Username: "rita"},
+		{Subscription: webpush.Subscription{Endpoint: "dave"}, Username: "dave"},
+		{Subscription: webpush.Subscription{Endpoint: "fran"}, Username: "fran"},
+		{Subscription: webpush.Subscription{Endpoint: "ada"}, Username: "ada"},
+		{Subscription: webpush.Subscription{Endpoint: "legacy"}},
+	} {
+		sub := sub
+		s.subscriptions[sub.Endpoint] = &sub
+	}
+	return s
+}
+
+func endpoints(subs []*Subscription) []string {
+	out := make([]string, len(subs))
+	for i, sub := range subs {
+		out[i] = sub.Endpoint
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestTargeting(t *testing.T) {
+	s := testStore()
+	ctx := context.Background()
+	cases := []struct {
+		name  string
+		match func(*Subscription) bool
+		want  []string
+	}{
+		{"user", forUsers([]string{"rita"}), []string{"rita-laptop", "rita-phone"}},
+		{"users", forUsers([]string{"dave", "fran", ""}), []string{"dave", "fran"}},
+		{"role", forUsers(s.withRole(ctx, "Rider", false)), []string{"ada", "rita-laptop", "rita-phone"}},
+		{"role or above", forUsers(s.withRole(ctx, "Dispatcher", true)), []string{"ada", "dave", "fran"}},
+		{"fleet managers", forUsers(s.withRole(ctx, "fleet-manager", true)), []string{"ada", "fran"}},
+	}
+	for _, c := range cases {
+		got := endpoints(s.subscribersWhere(c.match))
+		if len(got) != len(c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+				break
+			}
+		}
+	}
+	if n := s.UserSubscriberCount("rita"); n != 2 {
+		t.Errorf("UserSubscriberCount(rita) = %d, want 2", n)
+	}
+}
+
+func TestTargeting_Preferences(t *testing.T) {
+	notifyprefs.SetRepository(memory.NewNotificationPreferencesRepo())
+	t.Cleanup(func() { notifyprefs.SetRepository(nil) })
+	if _, err := notifyprefs.Update(context.Background(), "rita", repo.NotificationPreferences{
+		Categories: map[string]bool{notifyprefs.CategoryNewJobs: false},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	s := testStore()
+	now := time.Now()
+	got := endpoints(s.subscribersWhere(wants(notifyprefs.CategoryNewJobs, now, forUsers(s.withRole(context.Background(), "Rider", false)))))
+	if len(got) != 1 || got[0] != "ada" {
+		t.Errorf("expected rita to be skipped for new jobs, got %v", got)
+	}
+	got = endpoints(s.subscribersWhere(wants(notifyprefs.CategoryNewJobs, now, func(*Subscription) bool { return true })))
+	if len(got) != 4 {
+		t.Errorf("expected everyone but rita (including the legacy subscription), got %v", got)
+	}
+}
+
+func TestRecipients_SafetyDuringQuietHours(t *testing.T) {
+	notifyprefs.SetRepository(memory.NewNotificationPreferencesRepo())
+	t.Cleanup(func() { notifyprefs.SetRepository(nil) })
+	if _, err := notifyprefs.Update(context.Background(), "dave", repo.NotificationPreferences{
+		QuietHours: &repo.QuietHours{Start: "22:00", End: "07:00"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	s := testStore()
+	night := time.Date(2027, 1, 12, 2, 0, 0, 0, time.UTC)
+	a := repo.NotificationAudience{Usernames: []string{"dave"}}
+	if got := s.Recipients(notifyprefs.CategoryJobUpdates, a, night); len(got) != 0 {
+		t.Errorf("expected job updates held during quiet hours, got %v", endpoints(got))
+	}
+	if got := s.Recipients(notifyprefs.CategorySafety, a, night); len(got) != 1 {
+		t.Errorf("expected the safety alert to reach dave, got %v", endpoints(got))
+	}
+}
+
+func TestRecipients(t *testing.T) {
+	s := testStore()
+	now := time.Now()
+	cases := []struct {
+		name string
+		a    repo.NotificationAudience
+		want int
+	}{
+		{"everyone", repo.NotificationAudience{}, 6},
+		{"users", repo.NotificationAudience{Usernames: []string{"rita", "dave"}}, 3},
+		{"role", repo.NotificationAudience{Role: "Rider"}, 3},
+		{"role or above", repo.NotificationAudience{Role: "Dispatcher", OrAbove: true}, 3},
+	}
+	for _, c := range cases {
+		if got := s.Recipients(notifyprefs.CategoryJobUpdates, c.a, now); len(got) != c.want {
+			t.Errorf("%s: got %v, want %d", c.name, endpoints(got), c.want)
+		}
+	}
+	if _, ok := s.Subscription("dave"); !ok {
+		t.Error("expected dave's subscription")
+	}
+}
+
+func TestRecipients_UsesCurrentRoles(t *testing.T) {
+	s := testStore()
+	ctx := context.Background()
+	// dave subscribed as a dispatcher and has since gone back to riding.
+	_ = s.users.Put(ctx, &repo.User{RiderID: "dave", Tags: []string{"Rider"}})
+
+	got := endpoints(s.Recipients(notifyprefs.CategoryJobUpdates, repo.NotificationAudience{Role: "Dispatcher", OrAbove: true}, time.Now()))
+	if len(got) != 2 || got[0] != "ada" || got[1] != "fran" {
+		t.Errorf("expected dave left out of dispatcher alerts, got %v", got)
+	}
+	got = endpoints(s.Recipients(notifyprefs.CategoryNewJobs, repo.NotificationAudience{Role: "Rider"}, time.Now()))
+	if len(got) != 4 {
+		t.Errorf("expected dave's device to get rider alerts, got %v", got)
+	}
+
+	s.users = nil
+	if got := s.Recipients(notifyprefs.CategoryNewJobs, repo.NotificationAudience{Role: "Rider"}, time.Now()); len(got) != 0 {
+		t.Errorf("expected no role recipients without a users repository, got %v", endpoints(got))
+	}
+}
+
+// ---- VAPID keys ----
+
+func TestVAPIDKeys(t *testing.T) {
+	k, err := GenerateVAPIDKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.ID != KeyID(k.Public) || len(k.ID) != 8 {
+		t.Errorf("unexpected key ID %q", k.ID)
+	}
+	other, _ := GenerateVAPIDKey()
+	if _, err := NewVAPIDKey(k.Public, other.Private); err == nil {
+		t.Error("a private key from another pair should be refused")
+	}
+
+	parsed, err := ParsePreviousKeys(FormatPreviousKeys([]VAPIDKey{k, other}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed) != 2 || parsed[0] != k || parsed[1] != other {
+		t.Errorf("round trip gave %+v", parsed)
+	}
+	if _, err := ParsePreviousKeys("not-a-pair"); err == nil {
+		t.Error("a malformed VAPID_PREVIOUS_KEYS should be refused")
+	}
+}
+
+func TestUndeliverable(t *testing.T) {
+	k, _ := GenerateVAPIDKey()
+	s := &Store{current: k, keys: map[string]VAPIDKey{k.ID: k}, subscriptions: make(map[string]*Subscription)}
+	// A browser's subscription keys: a P-256 public key and a 16-byte secret.
+	browser, _ := GenerateVAPIDKey()
+	keys := webpush.Keys{P256dh: browser.Public, Auth: "AAAAAAAAAAAAAAAAAAAAAA"}
+
+	if reason := s.Undeliverable(&Subscription{Subscription: webpush.Subscription{Keys: keys}, KeyID: k.ID}); reason != "" {
+		t.Errorf("a good subscription was undeliverable: %s", reason)
+	}
+	if reason := s.Undeliverable(&Subscription{Subscription: webpush.Subscription{Keys: keys}, KeyID: "retired1"}); reason == "" {
+		t.Error("a subscription on a retired key should be undeliverable")
+	}
+	bad := webpush.Keys{P256dh: "AAAA", Auth: keys.Auth}
+	if reason := s.Undeliverable(&Subscription{Subscription: webpush.Subscription{Keys: bad}, KeyID: k.ID}); reason == "" {
+		t.Error("a subscription with a bad p256dh should be undeliverable")
+	}
+	if err := s.post(&Subscription{Subscription: webpush.Subscription{Keys: keys}, KeyID: "retired1"}, nil, 0, webpush.UrgencyLow); !errors.Is(err, ErrKeyRetired) {
+		t.Errorf("sending on a retired key: got %v", err)
+	}
+}
@@ -0,0 +1,507 @@
+// Package qrcode encodes short payloads as QR codes (ISO/IEC 18004) and
+// renders them as PNG or SVG. It supports byte mode and versions 1–10
+// (up to 271 bytes at level Low), which is all the fleet's bike labels
+// need.
+package qrcode
+
+import "errors"
+
+// Level is the error correction level. Higher levels survive more damage
+// at the cost of a denser code.
+type Level int
+
+const (
+	Low      Level = iota // recovers ~7% of codewords
+	Medium                // ~15%
+	Quartile              // ~25%
+	High                  // ~30%
+)
+
+const maxVersion = 10
+
+// QuietZone is the blank margin, in modules, scanners expect around a code.
+const QuietZone = 4
+
+var ErrTooLong = errors.New("qrcode: data too long")
+
+// Code is an encoded QR symbol.
+type Code struct {
+	Version int
+	Level   Level
+	Mask    int
+
+	size     int
+	modules  []bool // row-major, true is dark
+	function []bool // finder, timing, alignment and format modules
+}
+
+// Size is the width and height in modules, excluding the quiet zone.
+func (c *Code) Size() int { return c.size }
+
+// Black reports whether the module at column x, row y is dark. Modules
+// outside the symbol are light.
+func (c *Code) Black(x, y int) bool {
+	if x < 0 || y < 0 || x >= c.size || y >= c.size {
+		return false
+	}
+	return c.modules[y*c.size+x]
+}
+
+// Encode encodes data in byte mode, using the smallest version that fits.
+func Encode(data []byte, level Level) (*Code, error) {
+	if level < Low || level > High {
+		return nil, errors.New("qrcode: invalid level")
+	}
+	version := 0
+	for v := 1; v <= maxVersion; v++ {
+		if len(data) <= capacity(v, level) {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrTooLong
+	}
+
+	size := 17 + 4*version
+	c := &Code{
+		Version:  version,
+		Level:    level,
+		size:     size,
+		modules:  make([]bool, size*size),
+		function: make([]bool, size*size),
+	}
+	c.drawFunctionPatterns()
+	c.drawCodewords(interleave(version, level, dataCodewords(data, version, level)))
+	c.applyBestMask()
+	return c, nil
+}
+
+// blockSpec describes how a version's codewords split into error
+// correction blocks: g1 blocks of d1 data codewords followed by g2 blocks
+// of d1+1, each with ec error correction codewords.
+type blockSpec struct {
+	ec, g1, d1, g2 int
+}
+
+// blocks is indexed by version, then level (Low, Medium, Quartile, High).
+var blocks = [maxVersion + 1][4]blockSpec{
+	1:  {{7, 1, 19, 0}, {10, 1, 16, 0}, {13, 1, 13, 0}, {17, 1, 9, 0}},
+	2:  {{10, 1, 34, 0}, {16, 1, 28, 0}, {22, 1, 22, 0}, {28, 1, 16, 0}},
+	3:  {{15, 1, 55, 0}, {26, 1, 44, 0}, {18, 2, 17, 0}, {22, 2, 13, 0}},
+	4:  {{20, 1, 80, 0}, {18, 2, 32, 0}, {26, 2, 24, 0}, {16, 4, 9, 0}},
+	5:  {{26, 1, 108, 0}, {24, 2, 43, 0}, {18, 2, 15, 2}, {22, 2, 11, 2}},
+	6:  {{18, 2, 68, 0}, {16, 4, 27, 0}, {24, 4, 19, 0}, {28, 4, 15, 0}},
+	7:  {{20, 2, 78, 0}, {18, 4, 31, 0}, {18, 2, 14, 4}, {26, 4, 13, 1}},
+	8:  {{24, 2, 97, 0}, {22, 2, 38, 2}, {22, 4, 18, 2}, {26, 4, 14, 2}},
+	9:  {{30, 2, 116, 0}, {22, 3, 36, 2}, {20, 4, 16, 4}, {24, 4, 12, 4}},
+	10: {{18, 2, 68, 2}, {26, 4, 43, 1}, {24, 6, 19, 2}, {28, 6, 15, 2}},
+}
+
+// alignment lists the alignment pattern centre coordinates per version.
+var alignment = [maxVersion + 1][]int{
+	2:  {6, 18},
+	3:  {6, 22},
+	4:  {6, 26},
+	5:  {6, 30},
+	6:  {6, 34},
+	7:  {6, 22, 38},
+	8:  {6, 24, 42},
+	9:  {6, 26, 46},
+	10: {6, 28, 50},
+}
+
+// formatBits is the level's two-bit code in the format information.
+var formatBits = [4]int{Low: 1, Medium: 0, Quartile: 3, High: 2}
+
+func numDataCodewords(version int, level Level) int {
+	b := blocks[version][level]
+	return b.g1*b.d1 + b.g2*(b.d1+1)
+}
+
+// countBits is the width of the byte-mode character count field.
+func countBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+// capacity is the number of bytes that fit in the version at the level.
+func capacity(version int, level Level) int {
+	return (numDataCodewords(version, level)*8 - 4 - countBits(version)) / 8
+}
+
+type bitBuffer []bool
+
+func (b *bitBuffer) append(val, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, val>>i&1 == 1)
+	}
+}
+
+// dataCodewords builds the mode indicator, count, payload, terminator and
+// padding.
+func dataCodewords(data []byte, version int, level Level) []byte {
+	total := numDataCodewords(version, level)
+	var bits bitBuffer
+	bits.append(0x4, 4) // byte mode
+	bits.append(len(data), countBits(version))
+	for _, d := range data {
+		bits.append(int(d), 8)
+	}
+	bits.append(0, min(4, total*8-len(bits)))
+	bits.append(0, (8-len(bits)%8)%8)
+
+	out := make([]byte, 0, total)
+	for i := 0; i < len(bits); i += 8 {
+		var v byte
+		for j := 0; j < 8; j++ {
+			if bits[i+j] {
+				v |= 1 << (7 - j)
+			}
+		}
+		out = append(out, v)
+	}
+	for pad := byte(0xEC); len(out) < total; pad ^= 0xEC ^ 0x11 {
+		out = append(out, pad)
+	}
+	return out
+}
+
+// interleave splits the data into blocks, appends each block's error
+// correction and interleaves the result column by column.
+func interleave(version int, level Level, data []byte) []byte {
+	spec := blocks[version][level]
+	divisor := rsDivisor(spec.ec)
+	var dataBlocks, ecBlocks [][]byte
+	for i, off := 0, 0; i < spec.g1+spec.g2; i++ {
+		n := spec.d1
+		if i >= spec.g1 {
+			n++
+		}
+		block := data[off : off+n]
+		off += n
+		dataBlocks = append(dataBlocks, block)
+		ecBlocks = append(ecBlocks, rsRemainder(block, divisor))
+	}
+
+	out := make([]byte, 0, len(data)+spec.ec*len(dataBlocks))
+	for i := 0; i <= spec.d1; i++ {
+		for _, b := range dataBlocks {
+			if i < len(b) {
+				out = append(out, b[i])
+			}
+		}
+	}
+	for i := 0; i < spec.ec; i++ {
+		for _, b := range ecBlocks {
+			out = append(out, b[i])
+		}
+	}
+	return out
+}
+
+// ---- Reed–Solomon over GF(2^8) with the QR polynomial 0x11D ----
+
+func gfMul(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11D
+		z ^= int(y>>i&1) * int(x)
+	}
+	return byte(z)
+}
+
+// rsDivisor returns the generator polynomial of the given degree, highest
+// coefficient first, without the leading 1.
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMul(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMul(root, 0x02)
+	}
+	return result
+}
+
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coef := range divisor {
+			result[i] ^= gfMul(coef, factor)
+		}
+	}
+	return result
+}
+
+// ---- Module placement ----
+
+func (c *Code) set(x, y int, dark bool) {
+	c.modules[y*c.size+x] = dark
+}
+
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.set(x, y, dark)
+	c.function[y*c.size+x] = true
+}
+
+func (c *Code) drawFunctionPatterns() {
+	for i := 0; i < c.size; i++ {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+	c.drawFinder(3, 3)
+	c.drawFinder(c.size-4, 3)
+	c.drawFinder(3, c.size-4)
+
+	pos := alignment[c.Version]
+	last := len(pos) - 1
+	for i, y := range pos {
+		for j, x := range pos {
+			// Skip the three corners occupied by finder patterns.
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			c.drawAlignment(x, y)
+		}
+	}
+
+	c.drawFormat(0) // reserve the area; redrawn once the mask is chosen
+	c.drawVersion()
+}
+
+// drawFinder draws a finder pattern and its separator centred on (x, y).
+func (c *Code) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || yy < 0 || xx >= c.size || yy >= c.size {
+				continue
+			}
+			d := max(abs(dx), abs(dy))
+			c.setFunction(xx, yy, d != 2 && d != 4)
+		}
+	}
+}
+
+func (c *Code) drawAlignment(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// formatWord is the 15-bit BCH-protected format information.
+func formatWord(level Level, mask int) int {
+	data := formatBits[level]<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+func (c *Code) drawFormat(mask int) {
+	bits := formatWord(c.Level, mask)
+	bit := func(i int) bool { return bits>>i&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		c.setFunction(8, i, bit(i))
+	}
+	c.setFunction(8, 7, bit(6))
+	c.setFunction(8, 8, bit(7))
+	c.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		c.setFunction(c.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.size-15+i, bit(i))
+	}
+	c.setFunction(8, c.size-8, true) // the dark module
+}
+
+// drawVersion draws the version information blocks for version 7 and up.
+func (c *Code) drawVersion() {
+	if c.Version < 7 {
+		return
+	}
+	rem := c.Version
+	for i := 0; i < 12; i++ {
+		rem = rem<<1 ^ (rem>>11)*0x1F25
+	}
+	bits := c.Version<<12 | rem
+	for i := 0; i < 18; i++ {
+		dark := bits>>i&1 == 1
+		a, b := c.size-11+i%3, i/3
+		c.setFunction(a, b, dark)
+		c.setFunction(b, a, dark)
+	}
+}
+
+// drawCodewords places the codewords in the zigzag order, two columns at
+// a time from the bottom right, skipping function modules.
+func (c *Code) drawCodewords(data []byte) {
+	i := 0
+	for right := c.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < c.size; vert++ {
+			y := vert
+			if upward {
+				y = c.size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if c.function[y*c.size+x] || i >= len(data)*8 {
+					continue
+				}
+				c.set(x, y, data[i>>3]>>(7-i&7)&1 == 1)
+				i++
+			}
+		}
+	}
+}
+
+// ---- Masking ----
+
+func maskBit(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+// applyMask XORs the mask over the data modules; applying it twice undoes it.
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if !c.function[y*c.size+x] && maskBit(mask, x, y) {
+				c.modules[y*c.size+x] = !c.modules[y*c.size+x]
+			}
+		}
+	}
+}
+
+// applyBestMask tries every mask and keeps the one with the lowest penalty.
+func (c *Code) applyBestMask() {
+	best, bestScore := 0, -1
+	for m := 0; m < 8; m++ {
+		c.applyMask(m)
+		c.drawFormat(m)
+		if score := c.penalty(); bestScore < 0 || score < bestScore {
+			best, bestScore = m, score
+		}
+		c.applyMask(m)
+	}
+	c.Mask = best
+	c.applyMask(best)
+	c.drawFormat(best)
+}
+
+// penalty scores the symbol with the standard's four rules: long runs,
+// 2×2 blocks, finder-like patterns and an unbalanced dark ratio.
+func (c *Code) penalty() int {
+	score := 0
+	line := make([]bool, c.size)
+	for _, horizontal := range []bool{true, false} {
+		for a := 0; a < c.size; a++ {
+			for b := 0; b < c.size; b++ {
+				if horizontal {
+					line[b] = c.Black(b, a)
+				} else {
+					line[b] = c.Black(a, b)
+				}
+			}
+			score += linePenalty(line)
+		}
+	}
+
+	dark := 0
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			d := c.Black(x, y)
+			if d {
+				dark++
+			}
+			if x+1 < c.size && y+1 < c.size && d == c.Black(x+1, y) && d == c.Black(x, y+1) && d == c.Black(x+1, y+1) {
+				score += 3
+			}
+		}
+	}
+	total := c.size * c.size
+	score += abs(dark*20-total*10) / total * 10
+	return score
+}
+
+var finderLike = [2][11]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+func linePenalty(line []bool) int {
+	score := 0
+	run := 1
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && line[i] == line[i-1] {
+			run++
+			continue
+		}
+		if run >= 5 {
+			score += run - 2
+		}
+		run = 1
+	}
+	for i := 0; i+11 <= len(line); i++ {
+		for _, p := range finderLike {
+			match := true
+			for j, v := range p {
+				if line[i+j] != v {
+					match = false
+					break
+				}
+			}
+			if match {
+				score += 40
+			}
+		}
+	}
+	return score
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
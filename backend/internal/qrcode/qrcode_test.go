@@ -0,0 +1,241 @@
+package qrcode
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// ---- Reference values ----
+
+func TestRSRemainder_HelloWorld(t *testing.T) {
+	// "HELLO WORLD" at 1-M, from the worked example in the standard.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := rsRemainder(data, rsDivisor(10)); !bytes.Equal(got, want) {
+		t.Errorf("ec codewords = %v, want %v", got, want)
+	}
+}
+
+func TestFormatAndVersionWords(t *testing.T) {
+	if got := formatWord(Low, 0); got != 0x77C4 {
+		t.Errorf("format L/0 = %#x, want 0x77c4", got)
+	}
+	if got := formatWord(Medium, 0); got != 0x5412 {
+		t.Errorf("format M/0 = %#x, want 0x5412", got)
+	}
+	c := &Code{Version: 7, size: 45, modules: make([]bool, 45*45), function: make([]bool, 45*45)}
+	c.drawVersion()
+	// 0x07C94: the low bits go in the top right block, three per row.
+	bits := 0
+	for i := 0; i < 18; i++ {
+		if c.Black(c.size-11+i%3, i/3) {
+			bits |= 1 << i
+		}
+	}
+	if bits != 0x07C94 {
+		t.Errorf("version 7 info = %#x, want 0x7c94", bits)
+	}
+}
+
+func TestCapacity(t *testing.T) {
+	cases := []struct {
+		version int
+		level   Level
+		want    int
+	}{
+		{1, Low, 17}, {1, Medium, 14}, {1, High, 7},
+		{3, Medium, 42}, {7, Quartile, 86}, {10, Low, 271}, {10, High, 119},
+	}
+	for _, c := range cases {
+		if got := capacity(c.version, c.level); got != c.want {
+			t.Errorf("capacity(%d, %d) = %d, want %d", c.version, c.level, got, c.want)
+		}
+	}
+}
+
+// ---- Round trip ----
+
+// decode reads a symbol back: format information, unmasking, codeword
+// order, block de-interleaving and the Reed–Solomon check.
+func decode(t *testing.T, c *Code) []byte {
+	t.Helper()
+	format := 0
+	for i := 0; i <= 5; i++ {
+		if c.Black(8, i) {
+			format |= 1 << i
+		}
+	}
+	for i, p := range [][2]int{{8, 7}, {8, 8}, {7, 8}} {
+		if c.Black(p[0], p[1]) {
+			format |= 1 << (6 + i)
+		}
+	}
+	for i := 9; i < 15; i++ {
+		if c.Black(14-i, 8) {
+			format |= 1 << i
+		}
+	}
+	if format != formatWord(c.Level, c.Mask) {
+		t.Fatalf("format information %#x doesn't match level %d mask %d", format, c.Level, c.Mask)
+	}
+
+	var bits []bool
+	for right := c.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < c.size; vert++ {
+			y := vert
+			if (right+1)&2 == 0 {
+				y = c.size - 1 - vert
+			}
+			for x := right; x >= right-1; x-- {
+				if !c.function[y*c.size+x] {
+					bits = append(bits, c.Black(x, y) != maskBit(c.Mask, x, y))
+				}
+			}
+		}
+	}
+	raw := make([]byte, len(bits)/8)
+	for i := range raw {
+		for j := 0; j < 8; j++ {
+			if bits[i*8+j] {
+				raw[i] |= 1 << (7 - j)
+			}
+		}
+	}
+
+	spec := blocks[c.Version][c.Level]
+	n := spec.g1 + spec.g2
+	var data []byte
+	blockData := make([][]byte, n)
+	pos := 0
+	for i := 0; i <= spec.d1; i++ {
+		for b := 0; b < n; b++ {
+			if i < spec.d1 || b >= spec.g1 {
+				blockData[b] = append(blockData[b], raw[pos])
+				pos++
+			}
+		}
+	}
+	for b := 0; b < n; b++ {
+		ec := make([]byte, spec.ec)
+		for i := range ec {
+			ec[i] = raw[pos+i*n+b]
+		}
+		if got := rsRemainder(blockData[b], rsDivisor(spec.ec)); !bytes.Equal(got, ec) {
+			t.Fatalf("block %d fails the error correction check", b)
+		}
+		data = append(data, blockData[b]...)
+	}
+
+	if data[0]>>4 != 0x4 {
+		t.Fatalf("expected byte mode, got %#x", data[0]>>4)
+	}
+	var payload []byte
+	bitAt := func(i int) int { return int(data[i/8]>>(7-i%8)) & 1 }
+	read := func(off, n int) int {
+		v := 0
+		for i := 0; i < n; i++ {
+			v = v<<1 | bitAt(off+i)
+		}
+		return v
+	}
+	length := read(4, countBits(c.Version))
+	off := 4 + countBits(c.Version)
+	for i := 0; i < length; i++ {
+		payload = append(payload, byte(read(off+8*i, 8)))
+	}
+	return payload
+}
+
+func TestEncode_RoundTrip(t *testing.T) {
+	for _, level := range []Level{Low, Medium, Quartile, High} {
+		for _, n := range []int{0, 1, 14, 42, 60, 100, 119} {
+			data := []byte(strings.Repeat("bike1.Z2Fsd2F5LTAx.", 10)[:n])
+			c, err := Encode(data, level)
+			if err != nil {
+				t.Fatalf("level %d, %d bytes: %v", level, n, err)
+			}
+			if c.Size() != 17+4*c.Version {
+				t.Errorf("version %d has size %d", c.Version, c.Size())
+			}
+			if got := decode(t, c); !bytes.Equal(got, data) {
+				t.Errorf("level %d, %d bytes: decoded %q", level, n, got)
+			}
+		}
+	}
+}
+
+func TestEncode_PicksSmallestVersion(t *testing.T) {
+	c, _ := Encode([]byte(strings.Repeat("x", 14)), Medium)
+	if c.Version != 1 {
+		t.Errorf("expected version 1, got %d", c.Version)
+	}
+	c, _ = Encode([]byte(strings.Repeat("x", 15)), Medium)
+	if c.Version != 2 {
+		t.Errorf("expected version 2, got %d", c.Version)
+	}
+	if _, err := Encode(make([]byte, 272), Low); err != ErrTooLong {
+		t.Errorf("expected ErrTooLong, got %v", err)
+	}
+}
+
+func TestEncode_FinderPatterns(t *testing.T) {
+	c, _ := Encode([]byte("hello"), Medium)
+	for _, corner := range [][2]int{{0, 0}, {c.size - 7, 0}, {0, c.size - 7}} {
+		for dy := 0; dy < 7; dy++ {
+			for dx := 0; dx < 7; dx++ {
+				ring := max(abs(dx-3), abs(dy-3))
+				if want := ring != 2; c.Black(corner[0]+dx, corner[1]+dy) != want {
+					t.Fatalf("finder at %v wrong at (%d,%d)", corner, dx, dy)
+				}
+			}
+		}
+	}
+	for i := 8; i < c.size-8; i++ {
+		if c.Black(i, 6) != (i%2 == 0) || c.Black(6, i) != (i%2 == 0) {
+			t.Fatalf("timing pattern broken at %d", i)
+		}
+	}
+}
+
+// ---- Rendering ----
+
+func TestPNG(t *testing.T) {
+	c, _ := Encode([]byte("hello"), Medium)
+	raw, err := c.PNG(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("invalid png: %v", err)
+	}
+	side := (c.size + 2*QuietZone) * 4
+	if b := img.Bounds(); b.Dx() != side || b.Dy() != side {
+		t.Fatalf("expected %dx%d, got %v", side, side, b)
+	}
+	if r, _, _, _ := img.At(0, 0).RGBA(); r == 0 {
+		t.Error("expected a light quiet zone")
+	}
+	if r, _, _, _ := img.At(QuietZone*4, QuietZone*4).RGBA(); r != 0 {
+		t.Error("expected the finder's corner to be dark")
+	}
+}
+
+func TestSVG(t *testing.T) {
+	c, _ := Encode([]byte("hello"), Medium)
+	svg := string(c.SVG(8))
+	if !strings.HasPrefix(svg, "<svg") || !strings.HasSuffix(svg, "</svg>") {
+		t.Fatalf("not an svg document: %.60s", svg)
+	}
+	if !strings.Contains(svg, `viewBox="0 0 29 29"`) {
+		t.Errorf("expected a 29 module viewBox, got %.120s", svg)
+	}
+	if got := strings.Count(svg, "z"); got != len(c.Runs()) {
+		t.Errorf("expected one rect per run, got %d for %d runs", got, len(c.Runs()))
+	}
+}
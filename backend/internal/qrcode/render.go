@@ -0,0 +1,76 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// Image renders the code at scale pixels per module, with the quiet zone.
+func (c *Code) Image(scale int) image.Image {
+	scale = max(scale, 1)
+	side := (c.size + 2*QuietZone) * scale
+	img := image.NewPaletted(image.Rect(0, 0, side, side), color.Palette{color.White, color.Black})
+	for py := 0; py < side; py++ {
+		y := py/scale - QuietZone
+		for px := 0; px < side; px++ {
+			if c.Black(px/scale-QuietZone, y) {
+				img.SetColorIndex(px, py, 1)
+			}
+		}
+	}
+	return img
+}
+
+// PNG renders the code as a PNG at scale pixels per module.
+func (c *Code) PNG(scale int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, c.Image(scale)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG renders the code as a standalone SVG document. The viewBox is in
+// modules, so the image scales cleanly; scale sets the default pixel size.
+func (c *Code) SVG(scale int) []byte {
+	scale = max(scale, 1)
+	side := c.size + 2*QuietZone
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" version="1.1" viewBox="0 0 %d %d" width="%d" height="%d" shape-rendering="crispEdges">`,
+		side, side, side*scale, side*scale)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, side, side)
+	for _, r := range c.Runs() {
+		fmt.Fprintf(&buf, "M%d %dh%dv1h-%dz", r.X+QuietZone, r.Y+QuietZone, r.Len, r.Len)
+	}
+	buf.WriteString(`"/></svg>`)
+	return buf.Bytes()
+}
+
+// Run is a horizontal stretch of dark modules, in module coordinates
+// without the quiet zone.
+type Run struct {
+	X, Y, Len int
+}
+
+// Runs returns the dark modules row by row as horizontal runs, which
+// vector renderers can draw as one rectangle each.
+func (c *Code) Runs() []Run {
+	var runs []Run
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; {
+			if !c.Black(x, y) {
+				x++
+				continue
+			}
+			start := x
+			for x < c.size && c.Black(x, y) {
+				x++
+			}
+			runs = append(runs, Run{X: start, Y: y, Len: x - start})
+		}
+	}
+	return runs
+}
@@ -0,0 +1,213 @@
+// Package boltdb provides bbolt-backed implementations of the repo
+// interfaces, so local development keeps its data across restarts without
+// DynamoDB.
+package boltdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	bikesBucket         = []byte("bikes")
+	bikeServiceBucket   = []byte("bike_service")
+	bikeExpensesBucket  = []byte("bike_expenses")
+	bikeDocumentsBucket = []byte("bike_documents")
+	attachmentsBucket   = []byte("attachments")
+	depotsBucket        = []byte("depots")
+
+	notificationsBucket          = []byte("notifications")
+	notificationDeliveriesBucket = []byte("notification_deliveries")
+)
+
+// DB is an open bbolt database holding one bucket per repository.
+type DB struct {
+	db *bolt.DB
+}
+
+// Open opens (or creates) the database at path.
+func Open(path string) (*DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 2 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bikesBucket, bikeServiceBucket, bikeExpensesBucket, bikeDocumentsBucket, attachmentsBucket, depotsBucket, notificationsBucket, notificationDeliveriesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		for _, name := range [][]byte{bikeServiceBucket, bikeExpensesBucket} {
+			if err := nestFlatKeys(tx.Bucket(name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &DB{db: db}, nil
+}
+
+// Close closes the underlying database.
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
+// ── bucket helpers ──────────────────────────────────────────────────────
+
+func getJSON[T any](d *DB, bucket []byte, key string) (*T, bool, error) {
+	var out *T
+	err := d.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(bucket).Get([]byte(key))
+		if raw == nil {
+			return nil
+		}
+		var v T
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		out = &v
+		return nil
+	})
+	if err != nil || out == nil {
+		return nil, false, err
+	}
+	return out, true, nil
+}
+
+func putJSON(d *DB, bucket []byte, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return d.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put([]byte(key), data)
+	})
+}
+
+func deleteKey(d *DB, bucket []byte, key string) (bool, error) {
+	var existed bool
+	err := d.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		existed = b.Get([]byte(key)) != nil
+		if !existed {
+			return nil
+		}
+		return b.Delete([]byte(key))
+	})
+	return existed, err
+}
+
+// listJSON decodes every value whose key starts with prefix (all values
+// when prefix is empty).
+func listJSON[T any](d *DB, bucket []byte, prefix string) ([]T, error) {
+	out := make([]T, 0)
+	err := d.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucket).Cursor()
+		p := []byte(prefix)
+		for k, v := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, v = c.Next() {
+			var item T
+			if err := json.Unmarshal(v, &item); err != nil {
+				return err
+			}
+			out = append(out, item)
+		}
+		return nil
+	})
+	return out, err
+}
+
+// Per-bike data lives in a nested bucket per bike, so one bike's items are
+// listed without a prefix scan that another bike's ID could match.
+
+func putNestedJSON(d *DB, bucket []byte, parent, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return d.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(bucket).CreateBucketIfNotExists([]byte(parent))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(key), data)
+	})
+}
+
+func deleteNestedKey(d *DB, bucket []byte, parent, key string) (bool, error) {
+	var existed bool
+	err := d.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket).Bucket([]byte(parent))
+		if b == nil || b.Get([]byte(key)) == nil {
+			return nil
+		}
+		existed = true
+		return b.Delete([]byte(key))
+	})
+	return existed, err
+}
+
+func listNestedJSON[T any](d *DB, bucket []byte, parent string) ([]T, error) {
+	out := make([]T, 0)
+	err := d.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket).Bucket([]byte(parent))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(_, v []byte) error {
+			var item T
+			if err := json.Unmarshal(v, &item); err != nil {
+				return err
+			}
+			out = append(out, item)
+			return nil
+		})
+	})
+	return out, err
+}
+
+// nestFlatKeys moves "<parent>/<key>" values, as earlier versions stored
+// per-bike data, into the parent's nested bucket. Generated item IDs never
+// contain "/", so the key is whatever follows the last one.
+func nestFlatKeys(b *bolt.Bucket) error {
+	type item struct{ parent, key, value []byte }
+	var flat []item
+	err := b.ForEach(func(k, v []byte) error {
+		if v == nil {
+			return nil // already a nested bucket
+		}
+		i := bytes.LastIndexByte(k, '/')
+		if i < 0 {
+			return nil
+		}
+		flat = append(flat, item{parent: append([]byte(nil), k[:i]...), key: append([]byte(nil), k[i+1:]...), value: append([]byte(nil), v...)})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, it := range flat {
+		child, err := b.CreateBucketIfNotExists(it.parent)
+		if err != nil {
+			return err
+		}
+		if err := child.Put(it.key, it.value); err != nil {
+			return err
+		}
+		if err := b.Delete(append(append(append([]byte(nil), it.parent...), '/'), it.key...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
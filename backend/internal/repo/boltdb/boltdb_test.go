@@ -0,0 +1,209 @@
+package boltdb
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	bolt "go.etcd.io/bbolt"
+)
+
+var ctx = context.Background()
+
+func openTestDB(t *testing.T) (*DB, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fleet.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return db, path
+}
+
+// ---- BikesRepo ----
+
+func TestBikesRepo_PersistsAcrossReopen(t *testing.T) {
+	db, path := openTestDB(t)
+	r := NewBikesRepo(db)
+	if err := r.Put(ctx, &repo.Bike{ID: "b1", Model: "Pan European", Registration: "212-G-1234"}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	db.Close()
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer db.Close()
+	got, ok, err := NewBikesRepo(db).Get(ctx, "b1")
+	if err != nil || !ok {
+		t.Fatalf("Get: ok=%v err=%v", ok, err)
+	}
+	if got.Registration != "212-G-1234" {
+		t.Errorf("expected registration to persist, got %+v", got)
+	}
+}
+
+func TestBikesRepo_ListGetDelete(t *testing.T) {
+	db, _ := openTestDB(t)
+	defer db.Close()
+	r := NewBikesRepo(db)
+	_ = r.Put(ctx, &repo.Bike{ID: "b1"})
+	_ = r.Put(ctx, &repo.Bike{ID: "b2"})
+
+	list, err := r.List(ctx)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("expected 2 bikes, got %d err=%v", len(list), err)
+	}
+	if _, ok, _ := r.Get(ctx, "missing"); ok {
+		t.Error("expected missing bike not found")
+	}
+	if ok, _ := r.Delete(ctx, "b1"); !ok {
+		t.Error("expected delete to report existing bike")
+	}
+	if ok, _ := r.Delete(ctx, "b1"); ok {
+		t.Error("expected second delete to report not found")
+	}
+}
+
+func TestBikesRepo_ServiceEntriesScopedToBike(t *testing.T) {
+	db, _ := openTestDB(t)
+	defer db.Close()
+	r := NewBikesRepo(db)
+	d := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	_ = r.PutServiceEntry(ctx, &repo.ServiceEntry{ServiceID: "s1", BikeID: "b1", ServiceType: "oil", ServiceDate: d})
+	_ = r.PutServiceEntry(ctx, &repo.ServiceEntry{ServiceID: "s2", BikeID: "b1", ServiceType: "chain", ServiceDate: d.AddDate(0, 1, 0)})
+	_ = r.PutServiceEntry(ctx, &repo.ServiceEntry{ServiceID: "s3", BikeID: "b10", ServiceType: "oil", ServiceDate: d})
+	_ = r.PutServiceEntry(ctx, &repo.ServiceEntry{ServiceID: "s4", BikeID: "b1/x", ServiceType: "oil", ServiceDate: d})
+
+	entries, err := r.ListServiceEntries(ctx, "b1")
+	if err != nil {
+		t.Fatalf("ListServiceEntries: %v", err)
+	}
+	if len(entries) != 2 || entries[0].ServiceID != "s2" {
+		t.Errorf("expected b1's 2 entries newest first, got %+v", entries)
+	}
+	if ok, _ := r.DeleteServiceEntry(ctx, "b1", "s1"); !ok {
+		t.Error("expected delete to succeed")
+	}
+	entries, _ = r.ListServiceEntries(ctx, "b1")
+	if len(entries) != 1 {
+		t.Errorf("expected 1 entry after delete, got %d", len(entries))
+	}
+}
+
+func TestOpen_NestsFlatServiceKeys(t *testing.T) {
+	db, path := openTestDB(t)
+	_ = db.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bikeServiceBucket).Put([]byte("b1/s1"), []byte(`{"serviceId":"s1","bikeId":"b1","serviceType":"oil"}`))
+	})
+	db.Close()
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer db.Close()
+	entries, err := NewBikesRepo(db).ListServiceEntries(ctx, "b1")
+	if err != nil || len(entries) != 1 || entries[0].ServiceID != "s1" {
+		t.Errorf("expected the flat entry under b1, got %+v (err %v)", entries, err)
+	}
+}
+
+func TestBikesRepo_ExpensesSeparateFromServiceHistory(t *testing.T) {
+	db, _ := openTestDB(t)
+	defer db.Close()
+	r := NewBikesRepo(db)
+	now := time.Now()
+	_ = r.PutServiceEntry(ctx, &repo.ServiceEntry{ServiceID: "svc_1", BikeID: "b1", ServiceType: "oil", ServiceDate: now})
+	_ = r.PutExpense(ctx, &repo.Expense{ExpenseID: "exp_1", BikeID: "b1", Category: "fuel", Date: now.Add(-time.Hour), Cost: 30, Litres: 18})
+	_ = r.PutExpense(ctx, &repo.Expense{ExpenseID: "exp_2", BikeID: "b1", Category: "tyres", Date: now, Cost: 180})
+	_ = r.PutExpense(ctx, &repo.Expense{ExpenseID: "exp_3", BikeID: "b10", Category: "tax", Date: now, Cost: 90})
+
+	got, err := r.ListExpenses(ctx, "b1")
+	if err != nil {
+		t.Fatalf("ListExpenses: %v", err)
+	}
+	if len(got) != 2 || got[0].ExpenseID != "exp_2" || got[1].Litres != 18 {
+		t.Fatalf("expected b1's expenses newest first, got %+v", got)
+	}
+	if entries, _ := r.ListServiceEntries(ctx, "b1"); len(entries) != 1 {
+		t.Errorf("expected service history untouched, got %+v", entries)
+	}
+	if ok, _ := r.DeleteExpense(ctx, "b1", "exp_1"); !ok {
+		t.Error("expected delete to report existing expense")
+	}
+}
+
+// ---- BikeDocumentsRepo ----
+
+func TestBikeDocumentsRepo_ListByBike(t *testing.T) {
+	db, _ := openTestDB(t)
+	defer db.Close()
+	r := NewBikeDocumentsRepo(db)
+	_ = r.Put(ctx, &repo.BikeDocument{DocumentID: "d1", BikeID: "b1", Type: "insurance"})
+	_ = r.Put(ctx, &repo.BikeDocument{DocumentID: "d2", BikeID: "b2", Type: "nct"})
+
+	docs, err := r.ListByBike(ctx, "b1")
+	if err != nil || len(docs) != 1 || docs[0].DocumentID != "d1" {
+		t.Errorf("expected only d1, got %+v err=%v", docs, err)
+	}
+}
+
+// ---- AttachmentsRepo ----
+
+func TestAttachmentsRepo_ListByOwner(t *testing.T) {
+	db, _ := openTestDB(t)
+	defer db.Close()
+	r := NewAttachmentsRepo(db)
+	now := time.Now()
+	_ = r.Put(ctx, &repo.Attachment{AttachmentID: "a2", OwnerType: "issue", OwnerID: "i1", BlobKey: "k2", CreatedAt: now})
+	_ = r.Put(ctx, &repo.Attachment{AttachmentID: "a1", OwnerType: "issue", OwnerID: "i1", BlobKey: "k1", CreatedAt: now.Add(-time.Hour)})
+	_ = r.Put(ctx, &repo.Attachment{AttachmentID: "a3", OwnerType: "service", OwnerID: "i1", CreatedAt: now})
+
+	got, err := r.ListByOwner(ctx, "issue", "i1")
+	if err != nil {
+		t.Fatalf("ListByOwner: %v", err)
+	}
+	if len(got) != 2 || got[0].AttachmentID != "a1" || got[1].AttachmentID != "a2" {
+		t.Fatalf("expected a1, a2 oldest first, got %+v", got)
+	}
+	if got[0].BlobKey != "k1" {
+		t.Errorf("expected blob key to persist, got %q", got[0].BlobKey)
+	}
+	if ok, _ := r.Delete(ctx, "a1"); !ok {
+		t.Error("expected delete to report existing attachment")
+	}
+}
+
+// ---- Notification outbox ----
+
+func TestNotificationDeliveriesRepo_DueDeliveriesSurviveReopen(t *testing.T) {
+	db, path := openTestDB(t)
+	now := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	_ = NewNotificationsRepo(db).Put(ctx, &repo.OutboxNotification{NotificationID: "n1", Status: "pending", CreatedAt: now})
+	r := NewNotificationDeliveriesRepo(db)
+	_ = r.Put(ctx, &repo.NotificationDelivery{DeliveryID: "d1", NotificationID: "n1", Status: repo.DeliveryPending, NextAttemptAt: now.Add(-time.Minute), CreatedAt: now})
+	_ = r.Put(ctx, &repo.NotificationDelivery{DeliveryID: "d2", NotificationID: "n1", Status: repo.DeliveryPending, NextAttemptAt: now.Add(time.Hour), CreatedAt: now})
+	_ = r.Put(ctx, &repo.NotificationDelivery{DeliveryID: "d3", NotificationID: "n2", Status: "sent", CreatedAt: now})
+	db.Close()
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer db.Close()
+	r = NewNotificationDeliveriesRepo(db)
+	due, err := r.ListDue(ctx, now)
+	if err != nil || len(due) != 1 || due[0].DeliveryID != "d1" {
+		t.Errorf("expected only d1 due, got %+v err=%v", due, err)
+	}
+	if byN, _ := r.ListByNotification(ctx, "n1"); len(byN) != 2 {
+		t.Errorf("expected two deliveries for n1, got %+v", byN)
+	}
+	if pending, _ := NewNotificationsRepo(db).ListByStatus(ctx, "pending"); len(pending) != 1 {
+		t.Errorf("expected the pending notification to persist, got %+v", pending)
+	}
+}
@@ -0,0 +1,243 @@
+package boltdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	bolt "go.etcd.io/bbolt"
+)
+
+// ── Bikes ───────────────────────────────────────────────────────────────
+
+type BikesRepo struct {
+	db *DB
+}
+
+func NewBikesRepo(db *DB) *BikesRepo {
+	return &BikesRepo{db: db}
+}
+
+func (r *BikesRepo) List(_ context.Context) ([]repo.Bike, error) {
+	return listJSON[repo.Bike](r.db, bikesBucket, "")
+}
+
+func (r *BikesRepo) Get(_ context.Context, bikeID string) (*repo.Bike, bool, error) {
+	return getJSON[repo.Bike](r.db, bikesBucket, bikeID)
+}
+
+func (r *BikesRepo) Put(_ context.Context, b *repo.Bike) error {
+	if b == nil || b.ID == "" {
+		return errors.New("id required")
+	}
+	return putJSON(r.db, bikesBucket, b.ID, b)
+}
+
+func (r *BikesRepo) PutIfUnchanged(_ context.Context, b *repo.Bike, prev repo.Bike) (bool, error) {
+	if b == nil || b.ID == "" {
+		return false, errors.New("id required")
+	}
+	data, err := json.Marshal(b)
+	if err != nil {
+		return false, err
+	}
+	var written bool
+	err = r.db.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bikesBucket)
+		raw := bucket.Get([]byte(b.ID))
+		if raw == nil {
+			return nil
+		}
+		var cur repo.Bike
+		if err := json.Unmarshal(raw, &cur); err != nil {
+			return err
+		}
+		if cur.CurrentRiderID != prev.CurrentRiderID || !cur.UpdatedAt.Equal(prev.UpdatedAt) {
+			return nil
+		}
+		written = true
+		return bucket.Put([]byte(b.ID), data)
+	})
+	return written, err
+}
+
+func (r *BikesRepo) Delete(_ context.Context, bikeID string) (bool, error) {
+	return deleteKey(r.db, bikesBucket, bikeID)
+}
+
+// Service entries are stored in a nested bucket per bike.
+func (r *BikesRepo) ListServiceEntries(_ context.Context, bikeID string) ([]repo.ServiceEntry, error) {
+	entries, err := listNestedJSON[repo.ServiceEntry](r.db, bikeServiceBucket, bikeID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ServiceDate.After(entries[j].ServiceDate) })
+	return entries, nil
+}
+
+func (r *BikesRepo) PutServiceEntry(_ context.Context, e *repo.ServiceEntry) error {
+	if e == nil || e.BikeID == "" || e.ServiceID == "" {
+		return errors.New("bikeId and serviceId required")
+	}
+	return putNestedJSON(r.db, bikeServiceBucket, e.BikeID, e.ServiceID, e)
+}
+
+func (r *BikesRepo) DeleteServiceEntry(_ context.Context, bikeID, serviceID string) (bool, error) {
+	return deleteNestedKey(r.db, bikeServiceBucket, bikeID, serviceID)
+}
+
+// Expenses are nested per bike like service entries.
+func (r *BikesRepo) ListExpenses(_ context.Context, bikeID string) ([]repo.Expense, error) {
+	expenses, err := listNestedJSON[repo.Expense](r.db, bikeExpensesBucket, bikeID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(expenses, func(i, j int) bool { return expenses[i].Date.After(expenses[j].Date) })
+	return expenses, nil
+}
+
+func (r *BikesRepo) PutExpense(_ context.Context, e *repo.Expense) error {
+	if e == nil || e.BikeID == "" || e.ExpenseID == "" {
+		return errors.New("bikeId and expenseId required")
+	}
+	return putNestedJSON(r.db, bikeExpensesBucket, e.BikeID, e.ExpenseID, e)
+}
+
+func (r *BikesRepo) DeleteExpense(_ context.Context, bikeID, expenseID string) (bool, error) {
+	return deleteNestedKey(r.db, bikeExpensesBucket, bikeID, expenseID)
+}
+
+// ── Bike Documents ──────────────────────────────────────────────────────
+
+type BikeDocumentsRepo struct {
+	db *DB
+}
+
+func NewBikeDocumentsRepo(db *DB) *BikeDocumentsRepo {
+	return &BikeDocumentsRepo{db: db}
+}
+
+func (r *BikeDocumentsRepo) List(_ context.Context) ([]repo.BikeDocument, error) {
+	return listJSON[repo.BikeDocument](r.db, bikeDocumentsBucket, "")
+}
+
+func (r *BikeDocumentsRepo) Get(_ context.Context, documentID string) (*repo.BikeDocument, bool, error) {
+	return getJSON[repo.BikeDocument](r.db, bikeDocumentsBucket, documentID)
+}
+
+func (r *BikeDocumentsRepo) Put(_ context.Context, d *repo.BikeDocument) error {
+	if d == nil || d.DocumentID == "" {
+		return errors.New("documentId required")
+	}
+	return putJSON(r.db, bikeDocumentsBucket, d.DocumentID, d)
+}
+
+func (r *BikeDocumentsRepo) Delete(_ context.Context, documentID string) (bool, error) {
+	return deleteKey(r.db, bikeDocumentsBucket, documentID)
+}
+
+func (r *BikeDocumentsRepo) ListByBike(ctx context.Context, bikeID string) ([]repo.BikeDocument, error) {
+	all, err := r.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]repo.BikeDocument, 0)
+	for _, d := range all {
+		if d.BikeID == bikeID {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+// ── Depots ──────────────────────────────────────────────────────────────
+
+type DepotsRepo struct {
+	db *DB
+}
+
+func NewDepotsRepo(db *DB) *DepotsRepo {
+	return &DepotsRepo{db: db}
+}
+
+func (r *DepotsRepo) List(_ context.Context) ([]repo.Depot, error) {
+	return listJSON[repo.Depot](r.db, depotsBucket, "")
+}
+
+func (r *DepotsRepo) Get(_ context.Context, depotID string) (*repo.Depot, bool, error) {
+	return getJSON[repo.Depot](r.db, depotsBucket, depotID)
+}
+
+func (r *DepotsRepo) Put(_ context.Context, d *repo.Depot) error {
+	if d == nil || d.DepotID == "" {
+		return errors.New("depotId required")
+	}
+	return putJSON(r.db, depotsBucket, d.DepotID, d)
+}
+
+func (r *DepotsRepo) Delete(_ context.Context, depotID string) (bool, error) {
+	return deleteKey(r.db, depotsBucket, depotID)
+}
+
+// ── Attachments ─────────────────────────────────────────────────────────
+
+// attachmentRecord persists the blob keys, which repo.Attachment keeps out
+// of its JSON.
+type attachmentRecord struct {
+	repo.Attachment
+	BlobKey      string `json:"blobKey"`
+	ThumbnailKey string `json:"thumbnailKey,omitempty"`
+}
+
+func (rec attachmentRecord) toRepo() repo.Attachment {
+	a := rec.Attachment
+	a.BlobKey = rec.BlobKey
+	a.ThumbnailKey = rec.ThumbnailKey
+	return a
+}
+
+type AttachmentsRepo struct {
+	db *DB
+}
+
+func NewAttachmentsRepo(db *DB) *AttachmentsRepo {
+	return &AttachmentsRepo{db: db}
+}
+
+func (r *AttachmentsRepo) Get(_ context.Context, attachmentID string) (*repo.Attachment, bool, error) {
+	rec, ok, err := getJSON[attachmentRecord](r.db, attachmentsBucket, attachmentID)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	a := rec.toRepo()
+	return &a, true, nil
+}
+
+func (r *AttachmentsRepo) Put(_ context.Context, a *repo.Attachment) error {
+	if a == nil || a.AttachmentID == "" {
+		return errors.New("attachmentId required")
+	}
+	rec := attachmentRecord{Attachment: *a, BlobKey: a.BlobKey, ThumbnailKey: a.ThumbnailKey}
+	return putJSON(r.db, attachmentsBucket, a.AttachmentID, rec)
+}
+
+func (r *AttachmentsRepo) Delete(_ context.Context, attachmentID string) (bool, error) {
+	return deleteKey(r.db, attachmentsBucket, attachmentID)
+}
+
+func (r *AttachmentsRepo) ListByOwner(_ context.Context, ownerType, ownerID string) ([]repo.Attachment, error) {
+	all, err := listJSON[attachmentRecord](r.db, attachmentsBucket, "")
+	if err != nil {
+		return nil, err
+	}
+	out := make([]repo.Attachment, 0)
+	for _, rec := range all {
+		if rec.OwnerType == ownerType && rec.OwnerID == ownerID {
+			out = append(out, rec.toRepo())
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
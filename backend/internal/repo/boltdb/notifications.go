@@ -0,0 +1,102 @@
+package boltdb
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+)
+
+// ── Notification Outbox ─────────────────────────────────────────────────
+
+type NotificationsRepo struct {
+	db *DB
+}
+
+func NewNotificationsRepo(db *DB) *NotificationsRepo {
+	return &NotificationsRepo{db: db}
+}
+
+func (r *NotificationsRepo) List(_ context.Context) ([]repo.OutboxNotification, error) {
+	return listJSON[repo.OutboxNotification](r.db, notificationsBucket, "")
+}
+
+func (r *NotificationsRepo) ListByStatus(ctx context.Context, status string) ([]repo.OutboxNotification, error) {
+	all, err := r.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]repo.OutboxNotification, 0)
+	for _, n := range all {
+		if n.Status == status {
+			out = append(out, n)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (r *NotificationsRepo) Get(_ context.Context, notificationID string) (*repo.OutboxNotification, bool, error) {
+	return getJSON[repo.OutboxNotification](r.db, notificationsBucket, notificationID)
+}
+
+func (r *NotificationsRepo) Put(_ context.Context, n *repo.OutboxNotification) error {
+	if n == nil || n.NotificationID == "" {
+		return errors.New("notificationId required")
+	}
+	return putJSON(r.db, notificationsBucket, n.NotificationID, n)
+}
+
+func (r *NotificationsRepo) Delete(_ context.Context, notificationID string) (bool, error) {
+	return deleteKey(r.db, notificationsBucket, notificationID)
+}
+
+type NotificationDeliveriesRepo struct {
+	db *DB
+}
+
+func NewNotificationDeliveriesRepo(db *DB) *NotificationDeliveriesRepo {
+	return &NotificationDeliveriesRepo{db: db}
+}
+
+func (r *NotificationDeliveriesRepo) List(_ context.Context) ([]repo.NotificationDelivery, error) {
+	return listJSON[repo.NotificationDelivery](r.db, notificationDeliveriesBucket, "")
+}
+
+func (r *NotificationDeliveriesRepo) ListByNotification(ctx context.Context, notificationID string) ([]repo.NotificationDelivery, error) {
+	return r.filter(ctx, func(d repo.NotificationDelivery) bool { return d.NotificationID == notificationID })
+}
+
+func (r *NotificationDeliveriesRepo) ListDue(ctx context.Context, now time.Time) ([]repo.NotificationDelivery, error) {
+	return r.filter(ctx, func(d repo.NotificationDelivery) bool {
+		return d.Status == repo.DeliveryPending && !d.NextAttemptAt.After(now)
+	})
+}
+
+func (r *NotificationDeliveriesRepo) filter(ctx context.Context, keep func(repo.NotificationDelivery) bool) ([]repo.NotificationDelivery, error) {
+	all, err := r.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]repo.NotificationDelivery, 0)
+	for _, d := range all {
+		if keep(d) {
+			out = append(out, d)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (r *NotificationDeliveriesRepo) Put(_ context.Context, d *repo.NotificationDelivery) error {
+	if d == nil || d.DeliveryID == "" {
+		return errors.New("deliveryId required")
+	}
+	return putJSON(r.db, notificationDeliveriesBucket, d.DeliveryID, d)
+}
+
+func (r *NotificationDeliveriesRepo) Delete(_ context.Context, deliveryID string) (bool, error) {
+	return deleteKey(r.db, notificationDeliveriesBucket, deliveryID)
+}
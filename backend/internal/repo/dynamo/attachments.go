@@ -0,0 +1,114 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type attachmentsRepo struct {
+	client *dynamodb.Client
+	table  *tableMeta
+	name   string
+}
+
+func newAttachmentsRepo(client *dynamodb.Client, tableName string) repo.AttachmentsRepository {
+	return &attachmentsRepo{client: client, table: newTableMeta(client, tableName), name: tableName}
+}
+
+func (r *attachmentsRepo) Get(ctx context.Context, attachmentID string) (*repo.Attachment, bool, error) {
+	if attachmentID == "" {
+		return nil, false, errors.New("attachmentId required")
+	}
+	pk, err := r.table.partitionKey(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	out, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &r.name,
+		Key:       map[string]types.AttributeValue{pk: &types.AttributeValueMemberS{Value: attachmentID}},
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if len(out.Item) == 0 {
+		return nil, false, nil
+	}
+	var a repo.Attachment
+	if err := attributevalue.UnmarshalMap(out.Item, &a); err != nil {
+		return nil, false, err
+	}
+	return &a, true, nil
+}
+
+func (r *attachmentsRepo) Put(ctx context.Context, a *repo.Attachment) error {
+	if a == nil {
+		return errors.New("attachment required")
+	}
+	if a.AttachmentID == "" {
+		return errors.New("attachmentId required")
+	}
+	item, err := attributevalue.MarshalMap(a)
+	if err != nil {
+		return err
+	}
+	pk, err := r.table.partitionKey(ctx)
+	if err != nil {
+		return err
+	}
+	item[pk] = &types.AttributeValueMemberS{Value: a.AttachmentID}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{TableName: &r.name, Item: item})
+	if err != nil {
+		log.Printf("op=AttachmentsPut table=%s attachmentId=%s err=%v", r.name, a.AttachmentID, err)
+		return fmt.Errorf("put attachment: %w", err)
+	}
+	return nil
+}
+
+func (r *attachmentsRepo) Delete(ctx context.Context, attachmentID string) (bool, error) {
+	if attachmentID == "" {
+		return false, errors.New("attachmentId required")
+	}
+	pk, err := r.table.partitionKey(ctx)
+	if err != nil {
+		return false, err
+	}
+	out, err := r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:    &r.name,
+		Key:          map[string]types.AttributeValue{pk: &types.AttributeValueMemberS{Value: attachmentID}},
+		ReturnValues: types.ReturnValueAllOld,
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(out.Attributes) > 0, nil
+}
+
+func (r *attachmentsRepo) ListByOwner(ctx context.Context, ownerType, ownerID string) ([]repo.Attachment, error) {
+	// Full scan with filter — an issue or service entry only has a few attachments
+	raw, err := scanAll(ctx, r.client, &dynamodb.ScanInput{
+		TableName:        &r.name,
+		FilterExpression: strPtr("OwnerType = :ot AND OwnerID = :oid"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":ot":  &types.AttributeValueMemberS{Value: ownerType},
+			":oid": &types.AttributeValueMemberS{Value: ownerID},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	items := make([]repo.Attachment, 0, len(raw))
+	if err := attributevalue.UnmarshalListOfMaps(raw, &items); err != nil {
+		return nil, err
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].CreatedAt.Before(items[j].CreatedAt) })
+	return items, nil
+}
@@ -0,0 +1,88 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type availabilityRepo struct {
+	client *dynamodb.Client
+	table  *tableMeta
+	name   string
+}
+
+func newAvailabilityRepo(client *dynamodb.Client, tableName string) repo.AvailabilityRepository {
+	return &availabilityRepo{client: client, table: newTableMeta(client, tableName), name: tableName}
+}
+
+func (r *availabilityRepo) List(ctx context.Context) ([]repo.RiderAvailability, error) {
+	raw, err := scanAll(ctx, r.client, &dynamodb.ScanInput{TableName: &r.name})
+	if err != nil {
+		return nil, err
+	}
+	items := make([]repo.RiderAvailability, 0, len(raw))
+	if err := attributevalue.UnmarshalListOfMaps(raw, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *availabilityRepo) Get(ctx context.Context, riderID string) (*repo.RiderAvailability, bool, error) {
+	if riderID == "" {
+		return nil, false, errors.New("riderId required")
+	}
+	pk, err := r.table.partitionKey(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	out, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{TableName: &r.name, Key: map[string]types.AttributeValue{pk: &types.AttributeValueMemberS{Value: riderID}}})
+	if err != nil {
+		return nil, false, err
+	}
+	if len(out.Item) == 0 {
+		return nil, false, nil
+	}
+	var a repo.RiderAvailability
+	if err := attributevalue.UnmarshalMap(out.Item, &a); err != nil {
+		return nil, false, err
+	}
+	return &a, true, nil
+}
+
+func (r *availabilityRepo) Put(ctx context.Context, a *repo.RiderAvailability) error {
+	if a == nil || a.RiderID == "" {
+		return errors.New("riderId required")
+	}
+	pk, err := r.table.partitionKey(ctx)
+	if err != nil {
+		return err
+	}
+	item, err := attributevalue.MarshalMap(a)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	item[pk] = &types.AttributeValueMemberS{Value: a.RiderID}
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{TableName: &r.name, Item: item})
+	return err
+}
+
+func (r *availabilityRepo) Delete(ctx context.Context, riderID string) (bool, error) {
+	if riderID == "" {
+		return false, errors.New("riderId required")
+	}
+	pk, err := r.table.partitionKey(ctx)
+	if err != nil {
+		return false, err
+	}
+	out, err := r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{TableName: &r.name, Key: map[string]types.AttributeValue{pk: &types.AttributeValueMemberS{Value: riderID}}, ReturnValues: types.ReturnValueAllOld})
+	if err != nil {
+		return false, err
+	}
+	return len(out.Attributes) > 0, nil
+}
@@ -0,0 +1,172 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type bikeDocumentsRepo struct {
+	client *dynamodb.Client
+	table  *tableMeta
+	name   string
+}
+
+type bikeDocumentItem struct {
+	DocumentID    string    `dynamodbav:"DocumentID"`
+	BikeID        string    `dynamodbav:"BikeID"`
+	Type          string    `dynamodbav:"Type"`
+	Reference     string    `dynamodbav:"Reference,omitempty"`
+	ExpiryDate    time.Time `dynamodbav:"ExpiryDate"`
+	FileName      string    `dynamodbav:"FileName,omitempty"`
+	FileData      string    `dynamodbav:"FileData,omitempty"`
+	RemindersSent []int     `dynamodbav:"RemindersSent,omitempty"`
+	UploadedBy    string    `dynamodbav:"UploadedBy,omitempty"`
+	CreatedAt     time.Time `dynamodbav:"CreatedAt"`
+	UpdatedAt     time.Time `dynamodbav:"UpdatedAt"`
+}
+
+func newBikeDocumentsRepo(client *dynamodb.Client, tableName string) repo.BikeDocumentsRepository {
+	return &bikeDocumentsRepo{client: client, table: newTableMeta(client, tableName), name: tableName}
+}
+
+func (r *bikeDocumentsRepo) List(ctx context.Context) ([]repo.BikeDocument, error) {
+	items, err := scanAll(ctx, r.client, &dynamodb.ScanInput{TableName: &r.name})
+	if err != nil {
+		return nil, err
+	}
+	return toRepoDocuments(items)
+}
+
+func (r *bikeDocumentsRepo) Get(ctx context.Context, documentID string) (*repo.BikeDocument, bool, error) {
+	if documentID == "" {
+		return nil, false, errors.New("documentId required")
+	}
+	pk, err := r.table.partitionKey(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	out, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &r.name,
+		Key:       map[string]types.AttributeValue{pk: &types.AttributeValueMemberS{Value: documentID}},
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if len(out.Item) == 0 {
+		return nil, false, nil
+	}
+	var it bikeDocumentItem
+	if err := attributevalue.UnmarshalMap(out.Item, &it); err != nil {
+		return nil, false, err
+	}
+	d := toRepoDocument(it)
+	return &d, true, nil
+}
+
+func (r *bikeDocumentsRepo) Put(ctx context.Context, d *repo.BikeDocument) error {
+	if d == nil {
+		return errors.New("document required")
+	}
+	if d.DocumentID == "" {
+		return errors.New("documentId required")
+	}
+	it := bikeDocumentItem{
+		DocumentID:    d.DocumentID,
+		BikeID:        d.BikeID,
+		Type:          d.Type,
+		Reference:     d.Reference,
+		ExpiryDate:    d.ExpiryDate,
+		FileName:      d.FileName,
+		FileData:      d.FileData,
+		RemindersSent: d.RemindersSent,
+		UploadedBy:    d.UploadedBy,
+		CreatedAt:     d.CreatedAt,
+		UpdatedAt:     d.UpdatedAt,
+	}
+	item, err := attributevalue.MarshalMap(it)
+	if err != nil {
+		return err
+	}
+	pk, err := r.table.partitionKey(ctx)
+	if err != nil {
+		return err
+	}
+	item[pk] = &types.AttributeValueMemberS{Value: d.DocumentID}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{TableName: &r.name, Item: item})
+	if err != nil {
+		log.Printf("op=BikeDocumentsPut table=%s documentId=%s err=%v", r.name, d.DocumentID, err)
+		return fmt.Errorf("put bike document: %w", err)
+	}
+	return nil
+}
+
+func (r *bikeDocumentsRepo) Delete(ctx context.Context, documentID string) (bool, error) {
+	if documentID == "" {
+		return false, errors.New("documentId required")
+	}
+	pk, err := r.table.partitionKey(ctx)
+	if err != nil {
+		return false, err
+	}
+	out, err := r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:    &r.name,
+		Key:          map[string]types.AttributeValue{pk: &types.AttributeValueMemberS{Value: documentID}},
+		ReturnValues: types.ReturnValueAllOld,
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(out.Attributes) > 0, nil
+}
+
+func (r *bikeDocumentsRepo) ListByBike(ctx context.Context, bikeID string) ([]repo.BikeDocument, error) {
+	// Full scan with filter — a bike only ever has a handful of documents
+	items, err := scanAll(ctx, r.client, &dynamodb.ScanInput{
+		TableName:        &r.name,
+		FilterExpression: strPtr("BikeID = :bid"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":bid": &types.AttributeValueMemberS{Value: bikeID},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toRepoDocuments(items)
+}
+
+func toRepoDocuments(raw []map[string]types.AttributeValue) ([]repo.BikeDocument, error) {
+	var items []bikeDocumentItem
+	if err := attributevalue.UnmarshalListOfMaps(raw, &items); err != nil {
+		return nil, err
+	}
+	docs := make([]repo.BikeDocument, 0, len(items))
+	for _, it := range items {
+		docs = append(docs, toRepoDocument(it))
+	}
+	return docs, nil
+}
+
+func toRepoDocument(it bikeDocumentItem) repo.BikeDocument {
+	return repo.BikeDocument{
+		DocumentID:    it.DocumentID,
+		BikeID:        it.BikeID,
+		Type:          it.Type,
+		Reference:     it.Reference,
+		ExpiryDate:    it.ExpiryDate,
+		FileName:      it.FileName,
+		FileData:      it.FileData,
+		RemindersSent: it.RemindersSent,
+		UploadedBy:    it.UploadedBy,
+		CreatedAt:     it.CreatedAt,
+		UpdatedAt:     it.UpdatedAt,
+	}
+}
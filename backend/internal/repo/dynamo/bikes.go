@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"time"
 
 	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
@@ -17,13 +18,19 @@ type bikesRepo struct {
 	client *dynamodb.Client
 	table  *tableMeta
 	name   string
+
+	// Service history lives in its own table keyed by BikeID + ServiceID.
+	serviceTable string
 }
 
 type bikeItem struct {
 	ID               string    `dynamodbav:"id,omitempty"`
 	BikeID           string    `dynamodbav:"bikeId,omitempty"`
 	BikeIDLegacy     string    `dynamodbav:"BikeID,omitempty"`
+	Make             string    `dynamodbav:"make,omitempty"`
 	Model            string    `dynamodbav:"model,omitempty"`
+	VehicleType      string    `dynamodbav:"vehicleType,omitempty"`
+	Registration     string    `dynamodbav:"registration,omitempty"`
 	Depot            string    `dynamodbav:"depot,omitempty"`
 	Mileage          int       `dynamodbav:"mileage,omitempty"`
 	LastServiceMiles int       `dynamodbav:"lastServiceMiles,omitempty"`
@@ -32,46 +39,27 @@ type bikeItem struct {
 	CurrentRiderID   string    `dynamodbav:"currentRiderId,omitempty"`
 	LocationLat      float64   `dynamodbav:"locationLat,omitempty"`
 	LocationLng      float64   `dynamodbav:"locationLng,omitempty"`
+	CreatedAt        time.Time `dynamodbav:"createdAt,omitempty"`
 	UpdatedAt        time.Time `dynamodbav:"updatedAt,omitempty"`
 }
 
-func newBikesRepo(client *dynamodb.Client, tableName string) repo.BikesRepository {
-	return &bikesRepo{client: client, table: newTableMeta(client, tableName), name: tableName}
+func newBikesRepo(client *dynamodb.Client, tableName, serviceTable string) repo.BikesRepository {
+	return &bikesRepo{client: client, table: newTableMeta(client, tableName), name: tableName, serviceTable: serviceTable}
 }
 
 func (r *bikesRepo) List(ctx context.Context) ([]repo.Bike, error) {
-	out, err := r.client.Scan(ctx, &dynamodb.ScanInput{TableName: &r.name})
+	raw, err := scanAll(ctx, r.client, &dynamodb.ScanInput{TableName: &r.name})
 	if err != nil {
 		return nil, err
 	}
-	items := make([]bikeItem, 0, len(out.Items))
-	if err := attributevalue.UnmarshalListOfMaps(out.Items, &items); err != nil {
+	items := make([]bikeItem, 0, len(raw))
+	if err := attributevalue.UnmarshalListOfMaps(raw, &items); err != nil {
 		return nil, err
 	}
 
 	bikes := make([]repo.Bike, 0, len(items))
 	for _, it := range items {
-		id := it.ID
-		if id == "" {
-			if it.BikeID != "" {
-				id = it.BikeID
-			} else {
-				id = it.BikeIDLegacy
-			}
-		}
-		bikes = append(bikes, repo.Bike{
-			ID:               id,
-			Model:            it.Model,
-			Depot:            it.Depot,
-			Mileage:          it.Mileage,
-			LastServiceMiles: it.LastServiceMiles,
-			LastServiceDate:  it.LastServiceDate,
-			Status:           it.Status,
-			CurrentRiderID:   it.CurrentRiderID,
-			LocationLat:      it.LocationLat,
-			LocationLng:      it.LocationLng,
-			UpdatedAt:        it.UpdatedAt,
-		})
+		bikes = append(bikes, toRepoBike(it))
 	}
 	return bikes, nil
 }
@@ -98,50 +86,86 @@ func (r *bikesRepo) Get(ctx context.Context, bikeID string) (*repo.Bike, bool, e
 	if err := attributevalue.UnmarshalMap(out.Item, &it); err != nil {
 		return nil, false, err
 	}
-	id := it.ID
-	if id == "" {
-		if it.BikeID != "" {
-			id = it.BikeID
-		} else {
-			id = it.BikeIDLegacy
-		}
+	b := toRepoBike(it)
+	return &b, true, nil
+}
+
+func (r *bikesRepo) Put(ctx context.Context, b *repo.Bike) error {
+	input, err := r.putInput(ctx, b)
+	if err != nil {
+		return err
 	}
-	b := &repo.Bike{
-		ID:               id,
-		Model:            it.Model,
-		Depot:            it.Depot,
-		Mileage:          it.Mileage,
-		LastServiceMiles: it.LastServiceMiles,
-		LastServiceDate:  it.LastServiceDate,
-		Status:           it.Status,
-		CurrentRiderID:   it.CurrentRiderID,
-		LocationLat:      it.LocationLat,
-		LocationLng:      it.LocationLng,
-		UpdatedAt:        it.UpdatedAt,
+	if _, err := r.client.PutItem(ctx, input); err != nil {
+		log.Printf("op=BikesPut table=%s bikeId=%s err=%v", r.name, b.ID, err)
+		return fmt.Errorf("put bike: %w", err)
 	}
-	return b, true, nil
+	return nil
 }
 
-func (r *bikesRepo) Put(ctx context.Context, b *repo.Bike) error {
+// PutIfUnchanged makes the write conditional on the stored rider and
+// updatedAt, so of two instances assigning the same bike only one wins.
+func (r *bikesRepo) PutIfUnchanged(ctx context.Context, b *repo.Bike, prev repo.Bike) (bool, error) {
+	input, err := r.putInput(ctx, b)
+	if err != nil {
+		return false, err
+	}
+	pk, err := r.table.partitionKey(ctx)
+	if err != nil {
+		return false, err
+	}
+	updatedAt, err := attributevalue.Marshal(prev.UpdatedAt)
+	if err != nil {
+		return false, err
+	}
+	// Empty fields are omitted when written, so "unchanged" includes absent.
+	riderCond := "currentRiderId = :rider"
+	if prev.CurrentRiderID == "" {
+		riderCond = "(attribute_not_exists(currentRiderId) OR currentRiderId = :rider)"
+	}
+	updatedCond := "updatedAt = :updatedAt"
+	if prev.UpdatedAt.IsZero() {
+		updatedCond = "(attribute_not_exists(updatedAt) OR updatedAt = :updatedAt)"
+	}
+	input.ConditionExpression = strPtr("attribute_exists(#pk) AND " + riderCond + " AND " + updatedCond)
+	input.ExpressionAttributeNames = map[string]string{"#pk": pk}
+	input.ExpressionAttributeValues = map[string]types.AttributeValue{
+		":rider":     &types.AttributeValueMemberS{Value: prev.CurrentRiderID},
+		":updatedAt": updatedAt,
+	}
+	if _, err := r.client.PutItem(ctx, input); err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return false, nil
+		}
+		log.Printf("op=BikesPutIfUnchanged table=%s bikeId=%s err=%v", r.name, b.ID, err)
+		return false, fmt.Errorf("put bike: %w", err)
+	}
+	return true, nil
+}
+
+func (r *bikesRepo) putInput(ctx context.Context, b *repo.Bike) (*dynamodb.PutItemInput, error) {
 	if b == nil {
-		return errors.New("bike required")
+		return nil, errors.New("bike required")
 	}
 	if b.ID == "" {
-		return errors.New("id required")
+		return nil, errors.New("id required")
 	}
 	if b.UpdatedAt.IsZero() {
 		b.UpdatedAt = time.Now()
 	}
 	pk, err := r.table.partitionKey(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	it := bikeItem{
 		ID:               b.ID,
 		BikeID:           b.ID,
 		BikeIDLegacy:     b.ID,
+		Make:             b.Make,
 		Model:            b.Model,
+		VehicleType:      b.VehicleType,
+		Registration:     b.Registration,
 		Depot:            b.Depot,
 		Mileage:          b.Mileage,
 		LastServiceMiles: b.LastServiceMiles,
@@ -150,20 +174,15 @@ func (r *bikesRepo) Put(ctx context.Context, b *repo.Bike) error {
 		CurrentRiderID:   b.CurrentRiderID,
 		LocationLat:      b.LocationLat,
 		LocationLng:      b.LocationLng,
+		CreatedAt:        b.CreatedAt,
 		UpdatedAt:        b.UpdatedAt,
 	}
 	item, err := attributevalue.MarshalMap(it)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	item[pk] = &types.AttributeValueMemberS{Value: b.ID}
-
-	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{TableName: &r.name, Item: item})
-	if err != nil {
-		log.Printf("op=BikesPut table=%s bikeId=%s err=%v", r.name, b.ID, err)
-		return fmt.Errorf("put bike: %w", err)
-	}
-	return nil
+	return &dynamodb.PutItemInput{TableName: &r.name, Item: item}, nil
 }
 
 func (r *bikesRepo) Delete(ctx context.Context, bikeID string) (bool, error) {
@@ -184,3 +203,212 @@ func (r *bikesRepo) Delete(ctx context.Context, bikeID string) (bool, error) {
 	}
 	return len(out.Attributes) > 0, nil
 }
+
+func (r *bikesRepo) ListServiceEntries(ctx context.Context, bikeID string) ([]repo.ServiceEntry, error) {
+	if r.serviceTable == "" {
+		return nil, errServiceTableNotConfigured
+	}
+	items, err := queryAll(ctx, r.client, &dynamodb.QueryInput{
+		TableName:              &r.serviceTable,
+		KeyConditionExpression: strPtr("BikeID = :bikeId"),
+		FilterExpression:       strPtr("attribute_not_exists(EntryType)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":bikeId": &types.AttributeValueMemberS{Value: bikeID},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var entries []repo.ServiceEntry
+	if err := attributevalue.UnmarshalListOfMaps(items, &entries); err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ServiceDate.After(entries[j].ServiceDate) })
+	return entries, nil
+}
+
+func (r *bikesRepo) PutServiceEntry(ctx context.Context, e *repo.ServiceEntry) error {
+	if r.serviceTable == "" {
+		return errServiceTableNotConfigured
+	}
+	if e == nil || e.BikeID == "" || e.ServiceID == "" {
+		return errors.New("bikeId and serviceId required")
+	}
+	item, err := attributevalue.MarshalMap(e)
+	if err != nil {
+		return err
+	}
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{TableName: &r.serviceTable, Item: item})
+	if err != nil {
+		log.Printf("op=BikeServicePut table=%s bikeId=%s err=%v", r.serviceTable, e.BikeID, err)
+		return fmt.Errorf("put service entry: %w", err)
+	}
+	return nil
+}
+
+func (r *bikesRepo) DeleteServiceEntry(ctx context.Context, bikeID, serviceID string) (bool, error) {
+	if r.serviceTable == "" {
+		return false, errServiceTableNotConfigured
+	}
+	out, err := r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: &r.serviceTable,
+		Key: map[string]types.AttributeValue{
+			"BikeID":    &types.AttributeValueMemberS{Value: bikeID},
+			"ServiceID": &types.AttributeValueMemberS{Value: serviceID},
+		},
+		ReturnValues: types.ReturnValueAllOld,
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(out.Attributes) > 0, nil
+}
+
+// Expenses share the service table, keyed BikeID/ServiceID like service
+// entries and told apart by EntryType.
+const entryTypeExpense = "expense"
+
+type expenseItem struct {
+	BikeID     string    `dynamodbav:"BikeID"`
+	ExpenseID  string    `dynamodbav:"ServiceID"`
+	EntryType  string    `dynamodbav:"EntryType"`
+	Category   string    `dynamodbav:"Category"`
+	Date       time.Time `dynamodbav:"ServiceDate"`
+	Cost       float64   `dynamodbav:"Cost"`
+	Litres     float64   `dynamodbav:"Litres,omitempty"`
+	Odometer   int       `dynamodbav:"Odometer,omitempty"`
+	Station    string    `dynamodbav:"Station,omitempty"`
+	Notes      string    `dynamodbav:"Notes,omitempty"`
+	RecordedBy string    `dynamodbav:"RecordedBy,omitempty"`
+	CreatedAt  time.Time `dynamodbav:"CreatedAt"`
+}
+
+func (r *bikesRepo) ListExpenses(ctx context.Context, bikeID string) ([]repo.Expense, error) {
+	if r.serviceTable == "" {
+		return nil, errServiceTableNotConfigured
+	}
+	raw, err := queryAll(ctx, r.client, &dynamodb.QueryInput{
+		TableName:              &r.serviceTable,
+		KeyConditionExpression: strPtr("BikeID = :bikeId"),
+		FilterExpression:       strPtr("EntryType = :type"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":bikeId": &types.AttributeValueMemberS{Value: bikeID},
+			":type":   &types.AttributeValueMemberS{Value: entryTypeExpense},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var items []expenseItem
+	if err := attributevalue.UnmarshalListOfMaps(raw, &items); err != nil {
+		return nil, err
+	}
+	expenses := make([]repo.Expense, 0, len(items))
+	for _, it := range items {
+		expenses = append(expenses, repo.Expense{
+			ExpenseID:  it.ExpenseID,
+			BikeID:     it.BikeID,
+			Category:   it.Category,
+			Date:       it.Date,
+			Cost:       it.Cost,
+			Litres:     it.Litres,
+			Odometer:   it.Odometer,
+			Station:    it.Station,
+			Notes:      it.Notes,
+			RecordedBy: it.RecordedBy,
+			CreatedAt:  it.CreatedAt,
+		})
+	}
+	sort.Slice(expenses, func(i, j int) bool { return expenses[i].Date.After(expenses[j].Date) })
+	return expenses, nil
+}
+
+func (r *bikesRepo) PutExpense(ctx context.Context, e *repo.Expense) error {
+	if r.serviceTable == "" {
+		return errServiceTableNotConfigured
+	}
+	if e == nil || e.BikeID == "" || e.ExpenseID == "" {
+		return errors.New("bikeId and expenseId required")
+	}
+	item, err := attributevalue.MarshalMap(expenseItem{
+		BikeID:     e.BikeID,
+		ExpenseID:  e.ExpenseID,
+		EntryType:  entryTypeExpense,
+		Category:   e.Category,
+		Date:       e.Date,
+		Cost:       e.Cost,
+		Litres:     e.Litres,
+		Odometer:   e.Odometer,
+		Station:    e.Station,
+		Notes:      e.Notes,
+		RecordedBy: e.RecordedBy,
+		CreatedAt:  e.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{TableName: &r.serviceTable, Item: item})
+	if err != nil {
+		log.Printf("op=BikeExpensePut table=%s bikeId=%s err=%v", r.serviceTable, e.BikeID, err)
+		return fmt.Errorf("put expense: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpense only removes expense items, never a service entry that
+// happens to share the ID.
+func (r *bikesRepo) DeleteExpense(ctx context.Context, bikeID, expenseID string) (bool, error) {
+	if r.serviceTable == "" {
+		return false, errServiceTableNotConfigured
+	}
+	out, err := r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: &r.serviceTable,
+		Key: map[string]types.AttributeValue{
+			"BikeID":    &types.AttributeValueMemberS{Value: bikeID},
+			"ServiceID": &types.AttributeValueMemberS{Value: expenseID},
+		},
+		ConditionExpression: strPtr("EntryType = :type"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":type": &types.AttributeValueMemberS{Value: entryTypeExpense},
+		},
+		ReturnValues: types.ReturnValueAllOld,
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return false, nil
+		}
+		return false, err
+	}
+	return len(out.Attributes) > 0, nil
+}
+
+var errServiceTableNotConfigured = errors.New("bike service table not configured (set BIKE_SERVICE_TABLE)")
+
+func toRepoBike(it bikeItem) repo.Bike {
+	id := it.ID
+	if id == "" {
+		if it.BikeID != "" {
+			id = it.BikeID
+		} else {
+			id = it.BikeIDLegacy
+		}
+	}
+	return repo.Bike{
+		ID:               id,
+		Make:             it.Make,
+		Model:            it.Model,
+		VehicleType:      it.VehicleType,
+		Registration:     it.Registration,
+		Depot:            it.Depot,
+		Mileage:          it.Mileage,
+		LastServiceMiles: it.LastServiceMiles,
+		LastServiceDate:  it.LastServiceDate,
+		Status:           it.Status,
+		CurrentRiderID:   it.CurrentRiderID,
+		LocationLat:      it.LocationLat,
+		LocationLng:      it.LocationLng,
+		CreatedAt:        it.CreatedAt,
+		UpdatedAt:        it.UpdatedAt,
+	}
+}
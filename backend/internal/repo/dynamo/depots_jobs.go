@@ -3,6 +3,7 @@ package dynamo
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
@@ -17,10 +18,31 @@ type depotsRepo struct {
 }
 
 type depotItem struct {
-	DepotID string  `dynamodbav:"depotId,omitempty"`
-	Name    string  `dynamodbav:"name,omitempty"`
-	Lat     float64 `dynamodbav:"lat,omitempty"`
-	Lng     float64 `dynamodbav:"lng,omitempty"`
+	DepotID     string    `dynamodbav:"depotId,omitempty"`
+	Name        string    `dynamodbav:"name,omitempty"`
+	Address     string    `dynamodbav:"address,omitempty"`
+	Lat         float64   `dynamodbav:"lat,omitempty"`
+	Lng         float64   `dynamodbav:"lng,omitempty"`
+	ContactName string    `dynamodbav:"contactName,omitempty"`
+	Phone       string    `dynamodbav:"phone,omitempty"`
+	Email       string    `dynamodbav:"email,omitempty"`
+	CreatedAt   time.Time `dynamodbav:"createdAt,omitempty"`
+	UpdatedAt   time.Time `dynamodbav:"updatedAt,omitempty"`
+}
+
+func toRepoDepot(it depotItem) repo.Depot {
+	return repo.Depot{
+		DepotID:     it.DepotID,
+		Name:        it.Name,
+		Address:     it.Address,
+		Lat:         it.Lat,
+		Lng:         it.Lng,
+		ContactName: it.ContactName,
+		Phone:       it.Phone,
+		Email:       it.Email,
+		CreatedAt:   it.CreatedAt,
+		UpdatedAt:   it.UpdatedAt,
+	}
 }
 
 func newDepotsRepo(client *dynamodb.Client, tableName string) repo.DepotsRepository {
@@ -38,7 +60,7 @@ func (r *depotsRepo) List(ctx context.Context) ([]repo.Depot, error) {
 	}
 	depots := make([]repo.Depot, 0, len(items))
 	for _, it := range items {
-		depots = append(depots, repo.Depot{DepotID: it.DepotID, Name: it.Name, Lat: it.Lat, Lng: it.Lng})
+		depots = append(depots, toRepoDepot(it))
 	}
 	return depots, nil
 }
@@ -62,7 +84,8 @@ func (r *depotsRepo) Get(ctx context.Context, depotID string) (*repo.Depot, bool
 	if err := attributevalue.UnmarshalMap(out.Item, &it); err != nil {
 		return nil, false, err
 	}
-	return &repo.Depot{DepotID: it.DepotID, Name: it.Name, Lat: it.Lat, Lng: it.Lng}, true, nil
+	d := toRepoDepot(it)
+	return &d, true, nil
 }
 
 func (r *depotsRepo) Put(ctx context.Context, d *repo.Depot) error {
@@ -76,7 +99,18 @@ func (r *depotsRepo) Put(ctx context.Context, d *repo.Depot) error {
 	if err != nil {
 		return err
 	}
-	it := depotItem{DepotID: d.DepotID, Name: d.Name, Lat: d.Lat, Lng: d.Lng}
+	it := depotItem{
+		DepotID:     d.DepotID,
+		Name:        d.Name,
+		Address:     d.Address,
+		Lat:         d.Lat,
+		Lng:         d.Lng,
+		ContactName: d.ContactName,
+		Phone:       d.Phone,
+		Email:       d.Email,
+		CreatedAt:   d.CreatedAt,
+		UpdatedAt:   d.UpdatedAt,
+	}
 	item, err := attributevalue.MarshalMap(it)
 	if err != nil {
 		return err
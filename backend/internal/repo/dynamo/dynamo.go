@@ -11,36 +11,65 @@ import (
 )
 
 type Repositories struct {
-	Users        repo.UsersRepository
-	Bikes        repo.BikesRepository
-	Depots       repo.DepotsRepository
-	Jobs         repo.JobsRepository
-	Events       repo.EventsRepository
-	RideSessions repo.RideSessionsRepository
-	IssueReports repo.IssueReportsRepository
+	Users                   repo.UsersRepository
+	Bikes                   repo.BikesRepository
+	Depots                  repo.DepotsRepository
+	Jobs                    repo.JobsRepository
+	Events                  repo.EventsRepository
+	RideSessions            repo.RideSessionsRepository
+	IssueReports            repo.IssueReportsRepository
+	BikeDocuments           repo.BikeDocumentsRepository
+	Attachments             repo.AttachmentsRepository
+	Shifts                  repo.ShiftsRepository
+	Availability            repo.AvailabilityRepository
+	JobMessages             repo.JobMessagesRepository
+	NotificationPreferences repo.NotificationPreferencesRepository
+	SMSMessages             repo.SMSMessagesRepository
+	Notifications           repo.NotificationsRepository
+	NotificationDeliveries  repo.NotificationDeliveriesRepository
 }
 
 type Config struct {
-	Region            string
-	UsersTable        string
-	BikesTable        string
-	DepotsTable       string
-	JobsTable         string
-	EventsTable       string
-	RideSessionsTable string
-	IssueReportsTable string
+	Region                      string
+	UsersTable                  string
+	BikesTable                  string
+	BikeServiceTable            string
+	DepotsTable                 string
+	JobsTable                   string
+	EventsTable                 string
+	RideSessionsTable           string
+	IssueReportsTable           string
+	BikeDocumentsTable          string
+	AttachmentsTable            string
+	ShiftsTable                 string
+	AvailabilityTable           string
+	JobMessagesTable            string
+	NotificationPrefsTable      string
+	SMSMessagesTable            string
+	NotificationsTable          string
+	NotificationDeliveriesTable string
 }
 
 func ConfigFromEnv() Config {
 	return Config{
-		Region:            os.Getenv("AWS_REGION"),
-		UsersTable:        os.Getenv("USERS_TABLE"),
-		BikesTable:        os.Getenv("BIKES_TABLE"),
-		DepotsTable:       os.Getenv("DEPOTS_TABLE"),
-		JobsTable:         os.Getenv("JOBS_TABLE"),
-		EventsTable:       os.Getenv("EVENTS_TABLE"),
-		RideSessionsTable: os.Getenv("RIDE_SESSIONS_TABLE"),
-		IssueReportsTable: os.Getenv("ISSUE_REPORTS_TABLE"),
+		Region:                      os.Getenv("AWS_REGION"),
+		UsersTable:                  os.Getenv("USERS_TABLE"),
+		BikesTable:                  os.Getenv("BIKES_TABLE"),
+		BikeServiceTable:            os.Getenv("BIKE_SERVICE_TABLE"),
+		DepotsTable:                 os.Getenv("DEPOTS_TABLE"),
+		JobsTable:                   os.Getenv("JOBS_TABLE"),
+		EventsTable:                 os.Getenv("EVENTS_TABLE"),
+		RideSessionsTable:           os.Getenv("RIDE_SESSIONS_TABLE"),
+		IssueReportsTable:           os.Getenv("ISSUE_REPORTS_TABLE"),
+		BikeDocumentsTable:          os.Getenv("BIKE_DOCUMENTS_TABLE"),
+		AttachmentsTable:            os.Getenv("ATTACHMENTS_TABLE"),
+		ShiftsTable:                 os.Getenv("SHIFTS_TABLE"),
+		AvailabilityTable:           os.Getenv("AVAILABILITY_TABLE"),
+		JobMessagesTable:            os.Getenv("JOB_MESSAGES_TABLE"),
+		NotificationPrefsTable:      os.Getenv("NOTIFICATION_PREFS_TABLE"),
+		SMSMessagesTable:            os.Getenv("SMS_MESSAGES_TABLE"),
+		NotificationsTable:          os.Getenv("NOTIFICATIONS_TABLE"),
+		NotificationDeliveriesTable: os.Getenv("NOTIFICATION_DELIVERIES_TABLE"),
 	}
 }
 
@@ -63,7 +92,7 @@ func New(ctx context.Context, cfg Config) (*Repositories, error) {
 		repos.Users = newUsersRepo(ddb, cfg.UsersTable)
 	}
 	if cfg.BikesTable != "" {
-		repos.Bikes = newBikesRepo(ddb, cfg.BikesTable)
+		repos.Bikes = newBikesRepo(ddb, cfg.BikesTable, cfg.BikeServiceTable)
 	}
 	if cfg.DepotsTable != "" {
 		repos.Depots = newDepotsRepo(ddb, cfg.DepotsTable)
@@ -80,6 +109,33 @@ func New(ctx context.Context, cfg Config) (*Repositories, error) {
 	if cfg.IssueReportsTable != "" {
 		repos.IssueReports = newIssueReportsRepo(ddb, cfg.IssueReportsTable)
 	}
+	if cfg.BikeDocumentsTable != "" {
+		repos.BikeDocuments = newBikeDocumentsRepo(ddb, cfg.BikeDocumentsTable)
+	}
+	if cfg.AttachmentsTable != "" {
+		repos.Attachments = newAttachmentsRepo(ddb, cfg.AttachmentsTable)
+	}
+	if cfg.ShiftsTable != "" {
+		repos.Shifts = newShiftsRepo(ddb, cfg.ShiftsTable)
+	}
+	if cfg.AvailabilityTable != "" {
+		repos.Availability = newAvailabilityRepo(ddb, cfg.AvailabilityTable)
+	}
+	if cfg.JobMessagesTable != "" {
+		repos.JobMessages = newJobMessagesRepo(ddb, cfg.JobMessagesTable)
+	}
+	if cfg.NotificationPrefsTable != "" {
+		repos.NotificationPreferences = newNotificationPrefsRepo(ddb, cfg.NotificationPrefsTable)
+	}
+	if cfg.SMSMessagesTable != "" {
+		repos.SMSMessages = newSMSMessagesRepo(ddb, cfg.SMSMessagesTable)
+	}
+	if cfg.NotificationsTable != "" {
+		repos.Notifications = newNotificationsRepo(ddb, cfg.NotificationsTable)
+	}
+	if cfg.NotificationDeliveriesTable != "" {
+		repos.NotificationDeliveries = newNotificationDeliveriesRepo(ddb, cfg.NotificationDeliveriesTable)
+	}
 
 	return repos, nil
 }
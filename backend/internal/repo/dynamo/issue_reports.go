@@ -27,6 +27,14 @@ type issueReportItem struct {
 	Description string    `dynamodbav:"Description,omitempty"`
 	Timestamp   time.Time `dynamodbav:"Timestamp"`
 	Resolved    bool      `dynamodbav:"Resolved"`
+
+	Status     string    `dynamodbav:"Status,omitempty"`
+	AssignedTo string    `dynamodbav:"AssignedTo,omitempty"`
+	Resolution string    `dynamodbav:"Resolution,omitempty"`
+	ResolvedBy string    `dynamodbav:"ResolvedBy,omitempty"`
+	ResolvedAt time.Time `dynamodbav:"ResolvedAt,omitempty"`
+	ServiceID  string    `dynamodbav:"ServiceID,omitempty"`
+	UpdatedAt  time.Time `dynamodbav:"UpdatedAt,omitempty"`
 }
 
 func newIssueReportsRepo(client *dynamodb.Client, tableName string) repo.IssueReportsRepository {
@@ -93,6 +101,14 @@ func (r *issueReportsRepo) Put(ctx context.Context, ir *repo.IssueReport) error
 		Description: ir.Description,
 		Timestamp:   ir.Timestamp,
 		Resolved:    ir.Resolved,
+
+		Status:     ir.Status,
+		AssignedTo: ir.AssignedTo,
+		Resolution: ir.Resolution,
+		ResolvedBy: ir.ResolvedBy,
+		ResolvedAt: ir.ResolvedAt,
+		ServiceID:  ir.ServiceID,
+		UpdatedAt:  ir.UpdatedAt,
 	}
 	item, err := attributevalue.MarshalMap(it)
 	if err != nil {
@@ -140,5 +156,13 @@ func toRepoIssue(it issueReportItem) repo.IssueReport {
 		Description: it.Description,
 		Timestamp:   it.Timestamp,
 		Resolved:    it.Resolved,
+
+		Status:     it.Status,
+		AssignedTo: it.AssignedTo,
+		Resolution: it.Resolution,
+		ResolvedBy: it.ResolvedBy,
+		ResolvedAt: it.ResolvedAt,
+		ServiceID:  it.ServiceID,
+		UpdatedAt:  it.UpdatedAt,
 	}
 }
@@ -0,0 +1,94 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type jobMessagesRepo struct {
+	client *dynamodb.Client
+	table  *tableMeta
+	name   string
+}
+
+func newJobMessagesRepo(client *dynamodb.Client, tableName string) repo.JobMessagesRepository {
+	return &jobMessagesRepo{client: client, table: newTableMeta(client, tableName), name: tableName}
+}
+
+func (r *jobMessagesRepo) Get(ctx context.Context, messageID string) (*repo.JobMessage, bool, error) {
+	if messageID == "" {
+		return nil, false, errors.New("messageId required")
+	}
+	pk, err := r.table.partitionKey(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	out, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &r.name,
+		Key:       map[string]types.AttributeValue{pk: &types.AttributeValueMemberS{Value: messageID}},
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if len(out.Item) == 0 {
+		return nil, false, nil
+	}
+	var m repo.JobMessage
+	if err := attributevalue.UnmarshalMap(out.Item, &m); err != nil {
+		return nil, false, err
+	}
+	return &m, true, nil
+}
+
+func (r *jobMessagesRepo) Put(ctx context.Context, m *repo.JobMessage) error {
+	if m == nil {
+		return errors.New("message required")
+	}
+	if m.MessageID == "" {
+		return errors.New("messageId required")
+	}
+	item, err := attributevalue.MarshalMap(m)
+	if err != nil {
+		return err
+	}
+	pk, err := r.table.partitionKey(ctx)
+	if err != nil {
+		return err
+	}
+	item[pk] = &types.AttributeValueMemberS{Value: m.MessageID}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{TableName: &r.name, Item: item})
+	if err != nil {
+		log.Printf("op=JobMessagesPut table=%s messageId=%s err=%v", r.name, m.MessageID, err)
+		return fmt.Errorf("put job message: %w", err)
+	}
+	return nil
+}
+
+func (r *jobMessagesRepo) ListByJob(ctx context.Context, jobID string) ([]repo.JobMessage, error) {
+	// Full scan with filter — a job's thread is short-lived and small
+	raw, err := scanAll(ctx, r.client, &dynamodb.ScanInput{
+		TableName:        &r.name,
+		FilterExpression: strPtr("JobID = :jid"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":jid": &types.AttributeValueMemberS{Value: jobID},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	items := make([]repo.JobMessage, 0, len(raw))
+	if err := attributevalue.UnmarshalListOfMaps(raw, &items); err != nil {
+		return nil, err
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].SentAt.Before(items[j].SentAt) })
+	return items, nil
+}
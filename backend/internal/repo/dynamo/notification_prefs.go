@@ -0,0 +1,61 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type notificationPrefsRepo struct {
+	client *dynamodb.Client
+	table  *tableMeta
+	name   string
+}
+
+func newNotificationPrefsRepo(client *dynamodb.Client, tableName string) repo.NotificationPreferencesRepository {
+	return &notificationPrefsRepo{client: client, table: newTableMeta(client, tableName), name: tableName}
+}
+
+func (r *notificationPrefsRepo) Get(ctx context.Context, username string) (*repo.NotificationPreferences, bool, error) {
+	if username == "" {
+		return nil, false, errors.New("username required")
+	}
+	pk, err := r.table.partitionKey(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	out, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{TableName: &r.name, Key: map[string]types.AttributeValue{pk: &types.AttributeValueMemberS{Value: username}}})
+	if err != nil {
+		return nil, false, err
+	}
+	if len(out.Item) == 0 {
+		return nil, false, nil
+	}
+	var p repo.NotificationPreferences
+	if err := attributevalue.UnmarshalMap(out.Item, &p); err != nil {
+		return nil, false, err
+	}
+	return &p, true, nil
+}
+
+func (r *notificationPrefsRepo) Put(ctx context.Context, p *repo.NotificationPreferences) error {
+	if p == nil || p.Username == "" {
+		return errors.New("username required")
+	}
+	pk, err := r.table.partitionKey(ctx)
+	if err != nil {
+		return err
+	}
+	item, err := attributevalue.MarshalMap(p)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	item[pk] = &types.AttributeValueMemberS{Value: p.Username}
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{TableName: &r.name, Item: item})
+	return err
+}
@@ -0,0 +1,257 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Global secondary indexes the outbox tables need, so the worker queries
+// its pending work instead of scanning everything it has ever sent.
+const (
+	// notificationsStatusIndex on the notifications table: Status + CreatedAt.
+	notificationsStatusIndex = "Status-CreatedAt-index"
+	// deliveriesStatusIndex on the deliveries table: Status + NextAttemptAt.
+	deliveriesStatusIndex = "Status-NextAttemptAt-index"
+	// deliveriesNotificationIndex on the deliveries table: NotificationID + CreatedAt.
+	deliveriesNotificationIndex = "NotificationID-CreatedAt-index"
+)
+
+// scanAll reads every page of a scan.
+func scanAll(ctx context.Context, client *dynamodb.Client, in *dynamodb.ScanInput) ([]map[string]types.AttributeValue, error) {
+	var items []map[string]types.AttributeValue
+	paginator := dynamodb.NewScanPaginator(client, in)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page.Items...)
+	}
+	return items, nil
+}
+
+// queryAll reads every page of a query.
+func queryAll(ctx context.Context, client *dynamodb.Client, in *dynamodb.QueryInput) ([]map[string]types.AttributeValue, error) {
+	var items []map[string]types.AttributeValue
+	paginator := dynamodb.NewQueryPaginator(client, in)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page.Items...)
+	}
+	return items, nil
+}
+
+type notificationsRepo struct {
+	client *dynamodb.Client
+	table  *tableMeta
+	name   string
+}
+
+func newNotificationsRepo(client *dynamodb.Client, tableName string) repo.NotificationsRepository {
+	return &notificationsRepo{client: client, table: newTableMeta(client, tableName), name: tableName}
+}
+
+func (r *notificationsRepo) List(ctx context.Context) ([]repo.OutboxNotification, error) {
+	raw, err := scanAll(ctx, r.client, &dynamodb.ScanInput{TableName: &r.name})
+	if err != nil {
+		return nil, err
+	}
+	items := make([]repo.OutboxNotification, 0, len(raw))
+	if err := attributevalue.UnmarshalListOfMaps(raw, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *notificationsRepo) ListByStatus(ctx context.Context, status string) ([]repo.OutboxNotification, error) {
+	raw, err := queryAll(ctx, r.client, &dynamodb.QueryInput{
+		TableName:                &r.name,
+		IndexName:                strPtr(notificationsStatusIndex),
+		KeyConditionExpression:   strPtr("#status = :status"),
+		ExpressionAttributeNames: map[string]string{"#status": "Status"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":status": &types.AttributeValueMemberS{Value: status},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	items := make([]repo.OutboxNotification, 0, len(raw))
+	if err := attributevalue.UnmarshalListOfMaps(raw, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *notificationsRepo) Get(ctx context.Context, notificationID string) (*repo.OutboxNotification, bool, error) {
+	if notificationID == "" {
+		return nil, false, errors.New("notificationId required")
+	}
+	pk, err := r.table.partitionKey(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	out, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{TableName: &r.name, Key: map[string]types.AttributeValue{pk: &types.AttributeValueMemberS{Value: notificationID}}})
+	if err != nil {
+		return nil, false, err
+	}
+	if len(out.Item) == 0 {
+		return nil, false, nil
+	}
+	var n repo.OutboxNotification
+	if err := attributevalue.UnmarshalMap(out.Item, &n); err != nil {
+		return nil, false, err
+	}
+	return &n, true, nil
+}
+
+func (r *notificationsRepo) Put(ctx context.Context, n *repo.OutboxNotification) error {
+	if n == nil || n.NotificationID == "" {
+		return errors.New("notificationId required")
+	}
+	pk, err := r.table.partitionKey(ctx)
+	if err != nil {
+		return err
+	}
+	item, err := attributevalue.MarshalMap(n)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	item[pk] = &types.AttributeValueMemberS{Value: n.NotificationID}
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{TableName: &r.name, Item: item})
+	return err
+}
+
+func (r *notificationsRepo) Delete(ctx context.Context, notificationID string) (bool, error) {
+	if notificationID == "" {
+		return false, errors.New("notificationId required")
+	}
+	pk, err := r.table.partitionKey(ctx)
+	if err != nil {
+		return false, err
+	}
+	out, err := r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:    &r.name,
+		Key:          map[string]types.AttributeValue{pk: &types.AttributeValueMemberS{Value: notificationID}},
+		ReturnValues: types.ReturnValueAllOld,
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(out.Attributes) > 0, nil
+}
+
+type notificationDeliveriesRepo struct {
+	client *dynamodb.Client
+	table  *tableMeta
+	name   string
+}
+
+func newNotificationDeliveriesRepo(client *dynamodb.Client, tableName string) repo.NotificationDeliveriesRepository {
+	return &notificationDeliveriesRepo{client: client, table: newTableMeta(client, tableName), name: tableName}
+}
+
+func (r *notificationDeliveriesRepo) List(ctx context.Context) ([]repo.NotificationDelivery, error) {
+	raw, err := scanAll(ctx, r.client, &dynamodb.ScanInput{TableName: &r.name})
+	if err != nil {
+		return nil, err
+	}
+	items := make([]repo.NotificationDelivery, 0, len(raw))
+	if err := attributevalue.UnmarshalListOfMaps(raw, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *notificationDeliveriesRepo) ListByNotification(ctx context.Context, notificationID string) ([]repo.NotificationDelivery, error) {
+	raw, err := queryAll(ctx, r.client, &dynamodb.QueryInput{
+		TableName:              &r.name,
+		IndexName:              strPtr(deliveriesNotificationIndex),
+		KeyConditionExpression: strPtr("NotificationID = :nid"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":nid": &types.AttributeValueMemberS{Value: notificationID},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	items := make([]repo.NotificationDelivery, 0, len(raw))
+	if err := attributevalue.UnmarshalListOfMaps(raw, &items); err != nil {
+		return nil, err
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].CreatedAt.Before(items[j].CreatedAt) })
+	return items, nil
+}
+
+func (r *notificationDeliveriesRepo) ListDue(ctx context.Context, now time.Time) ([]repo.NotificationDelivery, error) {
+	at, err := attributevalue.Marshal(now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	raw, err := queryAll(ctx, r.client, &dynamodb.QueryInput{
+		TableName:                &r.name,
+		IndexName:                strPtr(deliveriesStatusIndex),
+		KeyConditionExpression:   strPtr("#status = :status AND NextAttemptAt <= :now"),
+		ExpressionAttributeNames: map[string]string{"#status": "Status"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":status": &types.AttributeValueMemberS{Value: repo.DeliveryPending},
+			":now":    at,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	items := make([]repo.NotificationDelivery, 0, len(raw))
+	if err := attributevalue.UnmarshalListOfMaps(raw, &items); err != nil {
+		return nil, err
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].CreatedAt.Before(items[j].CreatedAt) })
+	return items, nil
+}
+
+func (r *notificationDeliveriesRepo) Put(ctx context.Context, d *repo.NotificationDelivery) error {
+	if d == nil || d.DeliveryID == "" {
+		return errors.New("deliveryId required")
+	}
+	pk, err := r.table.partitionKey(ctx)
+	if err != nil {
+		return err
+	}
+	item, err := attributevalue.MarshalMap(d)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	item[pk] = &types.AttributeValueMemberS{Value: d.DeliveryID}
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{TableName: &r.name, Item: item})
+	return err
+}
+
+func (r *notificationDeliveriesRepo) Delete(ctx context.Context, deliveryID string) (bool, error) {
+	if deliveryID == "" {
+		return false, errors.New("deliveryId required")
+	}
+	pk, err := r.table.partitionKey(ctx)
+	if err != nil {
+		return false, err
+	}
+	out, err := r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:    &r.name,
+		Key:          map[string]types.AttributeValue{pk: &types.AttributeValueMemberS{Value: deliveryID}},
+		ReturnValues: types.ReturnValueAllOld,
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(out.Attributes) > 0, nil
+}
@@ -28,6 +28,22 @@ type rideSessionItem struct {
 	EndTime    time.Time `dynamodbav:"EndTime,omitempty"`
 	StartMiles int       `dynamodbav:"StartMiles,omitempty"`
 	EndMiles   int       `dynamodbav:"EndMiles,omitempty"`
+	StartFuel  *int      `dynamodbav:"StartFuel,omitempty"`
+	EndFuel    *int      `dynamodbav:"EndFuel,omitempty"`
+
+	Checklist []repo.InspectionItem `dynamodbav:"Checklist,omitempty"`
+	IssueIDs  []string              `dynamodbav:"IssueIDs,omitempty"`
+	Notes     string                `dynamodbav:"Notes,omitempty"`
+
+	GPSMiles          *float64  `dynamodbav:"GPSMiles,omitempty"`
+	MileageFlagged    bool      `dynamodbav:"MileageFlagged,omitempty"`
+	MileageReviewedBy string    `dynamodbav:"MileageReviewedBy,omitempty"`
+	MileageReviewedAt time.Time `dynamodbav:"MileageReviewedAt,omitempty"`
+
+	JobIDs          []string `dynamodbav:"JobIDs,omitempty"`
+	DurationMinutes int      `dynamodbav:"DurationMinutes,omitempty"`
+	DistanceMiles   int      `dynamodbav:"DistanceMiles,omitempty"`
+	AutoClosed      bool     `dynamodbav:"AutoClosed,omitempty"`
 }
 
 func newRideSessionsRepo(client *dynamodb.Client, tableName string) repo.RideSessionsRepository {
@@ -92,6 +108,21 @@ func (r *rideSessionsRepo) Put(ctx context.Context, s *repo.RideSession) error {
 		EndTime:    s.EndTime,
 		StartMiles: s.StartMiles,
 		EndMiles:   s.EndMiles,
+		StartFuel:  s.StartFuel,
+		EndFuel:    s.EndFuel,
+		Checklist:  s.Checklist,
+		IssueIDs:   s.IssueIDs,
+		Notes:      s.Notes,
+
+		GPSMiles:          s.GPSMiles,
+		MileageFlagged:    s.MileageFlagged,
+		MileageReviewedBy: s.MileageReviewedBy,
+		MileageReviewedAt: s.MileageReviewedAt,
+
+		JobIDs:          s.JobIDs,
+		DurationMinutes: s.DurationMinutes,
+		DistanceMiles:   s.DistanceMiles,
+		AutoClosed:      s.AutoClosed,
 	}
 	item, err := attributevalue.MarshalMap(it)
 	if err != nil {
@@ -164,6 +195,21 @@ func toRepoSession(it rideSessionItem) repo.RideSession {
 		EndTime:    it.EndTime,
 		StartMiles: it.StartMiles,
 		EndMiles:   it.EndMiles,
+		StartFuel:  it.StartFuel,
+		EndFuel:    it.EndFuel,
+		Checklist:  it.Checklist,
+		IssueIDs:   it.IssueIDs,
+		Notes:      it.Notes,
+
+		GPSMiles:          it.GPSMiles,
+		MileageFlagged:    it.MileageFlagged,
+		MileageReviewedBy: it.MileageReviewedBy,
+		MileageReviewedAt: it.MileageReviewedAt,
+
+		JobIDs:          it.JobIDs,
+		DurationMinutes: it.DurationMinutes,
+		DistanceMiles:   it.DistanceMiles,
+		AutoClosed:      it.AutoClosed,
 	}
 }
 
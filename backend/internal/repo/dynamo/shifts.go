@@ -0,0 +1,88 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type shiftsRepo struct {
+	client *dynamodb.Client
+	table  *tableMeta
+	name   string
+}
+
+func newShiftsRepo(client *dynamodb.Client, tableName string) repo.ShiftsRepository {
+	return &shiftsRepo{client: client, table: newTableMeta(client, tableName), name: tableName}
+}
+
+func (r *shiftsRepo) List(ctx context.Context) ([]repo.Shift, error) {
+	raw, err := scanAll(ctx, r.client, &dynamodb.ScanInput{TableName: &r.name})
+	if err != nil {
+		return nil, err
+	}
+	items := make([]repo.Shift, 0, len(raw))
+	if err := attributevalue.UnmarshalListOfMaps(raw, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *shiftsRepo) Get(ctx context.Context, shiftID string) (*repo.Shift, bool, error) {
+	if shiftID == "" {
+		return nil, false, errors.New("shiftId required")
+	}
+	pk, err := r.table.partitionKey(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	out, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{TableName: &r.name, Key: map[string]types.AttributeValue{pk: &types.AttributeValueMemberS{Value: shiftID}}})
+	if err != nil {
+		return nil, false, err
+	}
+	if len(out.Item) == 0 {
+		return nil, false, nil
+	}
+	var s repo.Shift
+	if err := attributevalue.UnmarshalMap(out.Item, &s); err != nil {
+		return nil, false, err
+	}
+	return &s, true, nil
+}
+
+func (r *shiftsRepo) Put(ctx context.Context, s *repo.Shift) error {
+	if s == nil || s.ShiftID == "" {
+		return errors.New("shiftId required")
+	}
+	pk, err := r.table.partitionKey(ctx)
+	if err != nil {
+		return err
+	}
+	item, err := attributevalue.MarshalMap(s)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	item[pk] = &types.AttributeValueMemberS{Value: s.ShiftID}
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{TableName: &r.name, Item: item})
+	return err
+}
+
+func (r *shiftsRepo) Delete(ctx context.Context, shiftID string) (bool, error) {
+	if shiftID == "" {
+		return false, errors.New("shiftId required")
+	}
+	pk, err := r.table.partitionKey(ctx)
+	if err != nil {
+		return false, err
+	}
+	out, err := r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{TableName: &r.name, Key: map[string]types.AttributeValue{pk: &types.AttributeValueMemberS{Value: shiftID}}, ReturnValues: types.ReturnValueAllOld})
+	if err != nil {
+		return false, err
+	}
+	return len(out.Attributes) > 0, nil
+}
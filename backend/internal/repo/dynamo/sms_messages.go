@@ -0,0 +1,73 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type smsMessagesRepo struct {
+	client *dynamodb.Client
+	table  *tableMeta
+	name   string
+}
+
+func newSMSMessagesRepo(client *dynamodb.Client, tableName string) repo.SMSMessagesRepository {
+	return &smsMessagesRepo{client: client, table: newTableMeta(client, tableName), name: tableName}
+}
+
+func (r *smsMessagesRepo) List(ctx context.Context) ([]repo.SMSMessage, error) {
+	raw, err := scanAll(ctx, r.client, &dynamodb.ScanInput{TableName: &r.name})
+	if err != nil {
+		return nil, err
+	}
+	items := make([]repo.SMSMessage, 0, len(raw))
+	if err := attributevalue.UnmarshalListOfMaps(raw, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *smsMessagesRepo) Get(ctx context.Context, messageID string) (*repo.SMSMessage, bool, error) {
+	if messageID == "" {
+		return nil, false, errors.New("messageId required")
+	}
+	pk, err := r.table.partitionKey(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	out, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{TableName: &r.name, Key: map[string]types.AttributeValue{pk: &types.AttributeValueMemberS{Value: messageID}}})
+	if err != nil {
+		return nil, false, err
+	}
+	if len(out.Item) == 0 {
+		return nil, false, nil
+	}
+	var a repo.SMSMessage
+	if err := attributevalue.UnmarshalMap(out.Item, &a); err != nil {
+		return nil, false, err
+	}
+	return &a, true, nil
+}
+
+func (r *smsMessagesRepo) Put(ctx context.Context, a *repo.SMSMessage) error {
+	if a == nil || a.MessageID == "" {
+		return errors.New("messageId required")
+	}
+	pk, err := r.table.partitionKey(ctx)
+	if err != nil {
+		return err
+	}
+	item, err := attributevalue.MarshalMap(a)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	item[pk] = &types.AttributeValueMemberS{Value: a.MessageID}
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{TableName: &r.name, Item: item})
+	return err
+}
@@ -4,7 +4,9 @@ package memory
 
 import (
 	"context"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
 )
@@ -60,12 +62,18 @@ func (r *UsersRepo) Delete(_ context.Context, riderID string) (bool, error) {
 // ── Bikes ───────────────────────────────────────────────────────────────
 
 type BikesRepo struct {
-	mu    sync.RWMutex
-	items map[string]repo.Bike
+	mu       sync.RWMutex
+	items    map[string]repo.Bike
+	services map[string]map[string]repo.ServiceEntry // bikeID → serviceID → entry
+	expenses map[string]map[string]repo.Expense      // bikeID → expenseID → expense
 }
 
 func NewBikesRepo() *BikesRepo {
-	return &BikesRepo{items: make(map[string]repo.Bike)}
+	return &BikesRepo{
+		items:    make(map[string]repo.Bike),
+		services: make(map[string]map[string]repo.ServiceEntry),
+		expenses: make(map[string]map[string]repo.Expense),
+	}
 }
 
 func (r *BikesRepo) List(_ context.Context) ([]repo.Bike, error) {
@@ -95,6 +103,17 @@ func (r *BikesRepo) Put(_ context.Context, b *repo.Bike) error {
 	return nil
 }
 
+func (r *BikesRepo) PutIfUnchanged(_ context.Context, b *repo.Bike, prev repo.Bike) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cur, ok := r.items[b.ID]
+	if !ok || cur.CurrentRiderID != prev.CurrentRiderID || !cur.UpdatedAt.Equal(prev.UpdatedAt) {
+		return false, nil
+	}
+	r.items[b.ID] = *b
+	return true, nil
+}
+
 func (r *BikesRepo) Delete(_ context.Context, bikeID string) (bool, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -105,6 +124,68 @@ func (r *BikesRepo) Delete(_ context.Context, bikeID string) (bool, error) {
 	return true, nil
 }
 
+func (r *BikesRepo) ListServiceEntries(_ context.Context, bikeID string) ([]repo.ServiceEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]repo.ServiceEntry, 0, len(r.services[bikeID]))
+	for _, e := range r.services[bikeID] {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ServiceDate.After(out[j].ServiceDate) })
+	return out, nil
+}
+
+func (r *BikesRepo) PutServiceEntry(_ context.Context, e *repo.ServiceEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.services[e.BikeID] == nil {
+		r.services[e.BikeID] = make(map[string]repo.ServiceEntry)
+	}
+	r.services[e.BikeID][e.ServiceID] = *e
+	return nil
+}
+
+func (r *BikesRepo) DeleteServiceEntry(_ context.Context, bikeID, serviceID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.services[bikeID][serviceID]; !ok {
+		return false, nil
+	}
+	delete(r.services[bikeID], serviceID)
+	return true, nil
+}
+
+func (r *BikesRepo) ListExpenses(_ context.Context, bikeID string) ([]repo.Expense, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]repo.Expense, 0, len(r.expenses[bikeID]))
+	for _, e := range r.expenses[bikeID] {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Date.After(out[j].Date) })
+	return out, nil
+}
+
+func (r *BikesRepo) PutExpense(_ context.Context, e *repo.Expense) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.expenses[e.BikeID] == nil {
+		r.expenses[e.BikeID] = make(map[string]repo.Expense)
+	}
+	r.expenses[e.BikeID][e.ExpenseID] = *e
+	return nil
+}
+
+func (r *BikesRepo) DeleteExpense(_ context.Context, bikeID, expenseID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.expenses[bikeID][expenseID]; !ok {
+		return false, nil
+	}
+	delete(r.expenses[bikeID], expenseID)
+	return true, nil
+}
+
 // ── Depots ──────────────────────────────────────────────────────────────
 
 type DepotsRepo struct {
@@ -249,6 +330,332 @@ func (r *EventsRepo) Delete(_ context.Context, id string) (bool, error) {
 	return true, nil
 }
 
+// ── Job Messages ────────────────────────────────────────────────────────
+
+type JobMessagesRepo struct {
+	mu    sync.RWMutex
+	items map[string]repo.JobMessage
+}
+
+func NewJobMessagesRepo() *JobMessagesRepo {
+	return &JobMessagesRepo{items: make(map[string]repo.JobMessage)}
+}
+
+func (r *JobMessagesRepo) Get(_ context.Context, messageID string) (*repo.JobMessage, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	m, ok := r.items[messageID]
+	if !ok {
+		return nil, false, nil
+	}
+	return &m, true, nil
+}
+
+func (r *JobMessagesRepo) Put(_ context.Context, m *repo.JobMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items[m.MessageID] = *m
+	return nil
+}
+
+func (r *JobMessagesRepo) ListByJob(_ context.Context, jobID string) ([]repo.JobMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]repo.JobMessage, 0)
+	for _, m := range r.items {
+		if m.JobID == jobID {
+			out = append(out, m)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].SentAt.Before(out[j].SentAt) })
+	return out, nil
+}
+
+// ── Notification Preferences ────────────────────────────────────────────
+
+type NotificationPreferencesRepo struct {
+	mu    sync.RWMutex
+	items map[string]repo.NotificationPreferences
+}
+
+func NewNotificationPreferencesRepo() *NotificationPreferencesRepo {
+	return &NotificationPreferencesRepo{items: make(map[string]repo.NotificationPreferences)}
+}
+
+func (r *NotificationPreferencesRepo) Get(_ context.Context, username string) (*repo.NotificationPreferences, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.items[username]
+	if !ok {
+		return nil, false, nil
+	}
+	return &p, true, nil
+}
+
+func (r *NotificationPreferencesRepo) Put(_ context.Context, p *repo.NotificationPreferences) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items[p.Username] = *p
+	return nil
+}
+
+// ── SMS Messages ────────────────────────────────────────────────────────
+
+type SMSMessagesRepo struct {
+	mu    sync.RWMutex
+	items map[string]repo.SMSMessage
+}
+
+func NewSMSMessagesRepo() *SMSMessagesRepo {
+	return &SMSMessagesRepo{items: make(map[string]repo.SMSMessage)}
+}
+
+func (r *SMSMessagesRepo) List(_ context.Context) ([]repo.SMSMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]repo.SMSMessage, 0, len(r.items))
+	for _, m := range r.items {
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+func (r *SMSMessagesRepo) Get(_ context.Context, messageID string) (*repo.SMSMessage, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	m, ok := r.items[messageID]
+	if !ok {
+		return nil, false, nil
+	}
+	return &m, true, nil
+}
+
+func (r *SMSMessagesRepo) Put(_ context.Context, m *repo.SMSMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items[m.MessageID] = *m
+	return nil
+}
+
+// ── Notification Outbox ─────────────────────────────────────────────────
+
+type NotificationsRepo struct {
+	mu    sync.RWMutex
+	items map[string]repo.OutboxNotification
+}
+
+func NewNotificationsRepo() *NotificationsRepo {
+	return &NotificationsRepo{items: make(map[string]repo.OutboxNotification)}
+}
+
+func (r *NotificationsRepo) List(_ context.Context) ([]repo.OutboxNotification, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]repo.OutboxNotification, 0, len(r.items))
+	for _, n := range r.items {
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+func (r *NotificationsRepo) ListByStatus(_ context.Context, status string) ([]repo.OutboxNotification, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]repo.OutboxNotification, 0)
+	for _, n := range r.items {
+		if n.Status == status {
+			out = append(out, n)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (r *NotificationsRepo) Get(_ context.Context, notificationID string) (*repo.OutboxNotification, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	n, ok := r.items[notificationID]
+	if !ok {
+		return nil, false, nil
+	}
+	return &n, true, nil
+}
+
+func (r *NotificationsRepo) Put(_ context.Context, n *repo.OutboxNotification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items[n.NotificationID] = *n
+	return nil
+}
+
+func (r *NotificationsRepo) Delete(_ context.Context, notificationID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.items[notificationID]; !ok {
+		return false, nil
+	}
+	delete(r.items, notificationID)
+	return true, nil
+}
+
+type NotificationDeliveriesRepo struct {
+	mu    sync.RWMutex
+	items map[string]repo.NotificationDelivery
+}
+
+func NewNotificationDeliveriesRepo() *NotificationDeliveriesRepo {
+	return &NotificationDeliveriesRepo{items: make(map[string]repo.NotificationDelivery)}
+}
+
+func (r *NotificationDeliveriesRepo) List(_ context.Context) ([]repo.NotificationDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]repo.NotificationDelivery, 0, len(r.items))
+	for _, d := range r.items {
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+func (r *NotificationDeliveriesRepo) ListByNotification(_ context.Context, notificationID string) ([]repo.NotificationDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]repo.NotificationDelivery, 0)
+	for _, d := range r.items {
+		if d.NotificationID == notificationID {
+			out = append(out, d)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (r *NotificationDeliveriesRepo) ListDue(_ context.Context, now time.Time) ([]repo.NotificationDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]repo.NotificationDelivery, 0)
+	for _, d := range r.items {
+		if d.Status == repo.DeliveryPending && !d.NextAttemptAt.After(now) {
+			out = append(out, d)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (r *NotificationDeliveriesRepo) Put(_ context.Context, d *repo.NotificationDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items[d.DeliveryID] = *d
+	return nil
+}
+
+func (r *NotificationDeliveriesRepo) Delete(_ context.Context, deliveryID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.items[deliveryID]; !ok {
+		return false, nil
+	}
+	delete(r.items, deliveryID)
+	return true, nil
+}
+
+// ── Shifts ──────────────────────────────────────────────────────────────
+
+type ShiftsRepo struct {
+	mu    sync.RWMutex
+	items map[string]repo.Shift
+}
+
+func NewShiftsRepo() *ShiftsRepo {
+	return &ShiftsRepo{items: make(map[string]repo.Shift)}
+}
+
+func (r *ShiftsRepo) List(_ context.Context) ([]repo.Shift, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]repo.Shift, 0, len(r.items))
+	for _, s := range r.items {
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+func (r *ShiftsRepo) Get(_ context.Context, shiftID string) (*repo.Shift, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.items[shiftID]
+	if !ok {
+		return nil, false, nil
+	}
+	return &s, true, nil
+}
+
+func (r *ShiftsRepo) Put(_ context.Context, s *repo.Shift) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items[s.ShiftID] = *s
+	return nil
+}
+
+func (r *ShiftsRepo) Delete(_ context.Context, shiftID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.items[shiftID]; !ok {
+		return false, nil
+	}
+	delete(r.items, shiftID)
+	return true, nil
+}
+
+// ── Rider Availability ──────────────────────────────────────────────────
+
+type AvailabilityRepo struct {
+	mu    sync.RWMutex
+	items map[string]repo.RiderAvailability
+}
+
+func NewAvailabilityRepo() *AvailabilityRepo {
+	return &AvailabilityRepo{items: make(map[string]repo.RiderAvailability)}
+}
+
+func (r *AvailabilityRepo) List(_ context.Context) ([]repo.RiderAvailability, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]repo.RiderAvailability, 0, len(r.items))
+	for _, a := range r.items {
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+func (r *AvailabilityRepo) Get(_ context.Context, riderID string) (*repo.RiderAvailability, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	a, ok := r.items[riderID]
+	if !ok {
+		return nil, false, nil
+	}
+	return &a, true, nil
+}
+
+func (r *AvailabilityRepo) Put(_ context.Context, a *repo.RiderAvailability) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items[a.RiderID] = *a
+	return nil
+}
+
+func (r *AvailabilityRepo) Delete(_ context.Context, riderID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.items[riderID]; !ok {
+		return false, nil
+	}
+	delete(r.items, riderID)
+	return true, nil
+}
+
 // ── Ride Sessions ───────────────────────────────────────────────────────
 
 type RideSessionsRepo struct {
@@ -356,3 +763,114 @@ func (r *IssueReportsRepo) Delete(_ context.Context, issueID string) (bool, erro
 	delete(r.items, issueID)
 	return true, nil
 }
+
+// ── Bike Documents ──────────────────────────────────────────────────────
+
+type BikeDocumentsRepo struct {
+	mu    sync.RWMutex
+	items map[string]repo.BikeDocument
+}
+
+func NewBikeDocumentsRepo() *BikeDocumentsRepo {
+	return &BikeDocumentsRepo{items: make(map[string]repo.BikeDocument)}
+}
+
+func (r *BikeDocumentsRepo) List(_ context.Context) ([]repo.BikeDocument, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]repo.BikeDocument, 0, len(r.items))
+	for _, d := range r.items {
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+func (r *BikeDocumentsRepo) Get(_ context.Context, documentID string) (*repo.BikeDocument, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	d, ok := r.items[documentID]
+	if !ok {
+		return nil, false, nil
+	}
+	return &d, true, nil
+}
+
+func (r *BikeDocumentsRepo) Put(_ context.Context, d *repo.BikeDocument) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items[d.DocumentID] = *d
+	return nil
+}
+
+func (r *BikeDocumentsRepo) Delete(_ context.Context, documentID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.items[documentID]; !ok {
+		return false, nil
+	}
+	delete(r.items, documentID)
+	return true, nil
+}
+
+func (r *BikeDocumentsRepo) ListByBike(_ context.Context, bikeID string) ([]repo.BikeDocument, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]repo.BikeDocument, 0)
+	for _, d := range r.items {
+		if d.BikeID == bikeID {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+// ── Attachments ─────────────────────────────────────────────────────────
+
+type AttachmentsRepo struct {
+	mu    sync.RWMutex
+	items map[string]repo.Attachment
+}
+
+func NewAttachmentsRepo() *AttachmentsRepo {
+	return &AttachmentsRepo{items: make(map[string]repo.Attachment)}
+}
+
+func (r *AttachmentsRepo) Get(_ context.Context, attachmentID string) (*repo.Attachment, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	a, ok := r.items[attachmentID]
+	if !ok {
+		return nil, false, nil
+	}
+	return &a, true, nil
+}
+
+func (r *AttachmentsRepo) Put(_ context.Context, a *repo.Attachment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items[a.AttachmentID] = *a
+	return nil
+}
+
+func (r *AttachmentsRepo) Delete(_ context.Context, attachmentID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.items[attachmentID]; !ok {
+		return false, nil
+	}
+	delete(r.items, attachmentID)
+	return true, nil
+}
+
+func (r *AttachmentsRepo) ListByOwner(_ context.Context, ownerType, ownerID string) ([]repo.Attachment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]repo.Attachment, 0)
+	for _, a := range r.items {
+		if a.OwnerType == ownerType && a.OwnerID == ownerID {
+			out = append(out, a)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
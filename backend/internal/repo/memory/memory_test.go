@@ -220,3 +220,51 @@ _, _ = r.List(ctx)
 }
 <-done
 }
+
+// ---- BikesRepo service history ----
+
+func TestBikesRepo_ServiceEntries(t *testing.T) {
+	r := NewBikesRepo()
+	older := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.AddDate(0, 3, 0)
+	_ = r.PutServiceEntry(ctx, &repo.ServiceEntry{ServiceID: "s1", BikeID: "b1", ServiceType: "oil", ServiceDate: older})
+	_ = r.PutServiceEntry(ctx, &repo.ServiceEntry{ServiceID: "s2", BikeID: "b1", ServiceType: "chain", ServiceDate: newer})
+	_ = r.PutServiceEntry(ctx, &repo.ServiceEntry{ServiceID: "s3", BikeID: "b2", ServiceType: "oil", ServiceDate: newer})
+
+	entries, err := r.ListServiceEntries(ctx, "b1")
+	if err != nil {
+		t.Fatalf("ListServiceEntries: %v", err)
+	}
+	if len(entries) != 2 || entries[0].ServiceID != "s2" {
+		t.Errorf("expected 2 entries newest first, got %+v", entries)
+	}
+
+	ok, err := r.DeleteServiceEntry(ctx, "b1", "s1")
+	if err != nil || !ok {
+		t.Fatalf("DeleteServiceEntry: ok=%v err=%v", ok, err)
+	}
+	if ok, _ := r.DeleteServiceEntry(ctx, "b1", "s1"); ok {
+		t.Error("expected second delete to report not found")
+	}
+}
+
+// ---- NotificationDeliveriesRepo ----
+
+func TestNotificationDeliveriesRepo_ListDue(t *testing.T) {
+	r := NewNotificationDeliveriesRepo()
+	now := time.Date(2027, 1, 12, 12, 0, 0, 0, time.UTC)
+	_ = r.Put(ctx, &repo.NotificationDelivery{DeliveryID: "due", Status: repo.DeliveryPending, NextAttemptAt: now})
+	_ = r.Put(ctx, &repo.NotificationDelivery{DeliveryID: "later", Status: repo.DeliveryPending, NextAttemptAt: now.Add(time.Minute)})
+	_ = r.Put(ctx, &repo.NotificationDelivery{DeliveryID: "sent", Status: "sent", NextAttemptAt: now.Add(-time.Hour)})
+
+	due, err := r.ListDue(ctx, now)
+	if err != nil || len(due) != 1 || due[0].DeliveryID != "due" {
+		t.Errorf("expected only the due delivery, got %+v (err %v)", due, err)
+	}
+	if ok, _ := r.Delete(ctx, "due"); !ok {
+		t.Error("expected delete to report existing delivery")
+	}
+	if ok, _ := r.Delete(ctx, "due"); ok {
+		t.Error("expected second delete to report not found")
+	}
+}
@@ -2,6 +2,7 @@ package repo
 
 import (
 	"context"
+	"fmt"
 	"time"
 )
 
@@ -15,8 +16,13 @@ type User struct {
 	Tags           []string  `json:"tags,omitempty"`
 	Status         string    `json:"status,omitempty"`
 	AvailableUntil string    `json:"availableUntil,omitempty"`
+	AvailableSince string    `json:"availableSince,omitempty"`
 	CurrentJobID   string    `json:"currentJobId,omitempty"`
+	Depot          string    `json:"depot,omitempty"`
 	UpdatedAt      time.Time `json:"updatedAt,omitempty"`
+	// CalendarFeedKey is carried in the user's calendar feed URLs; changing
+	// it revokes them. It is never sent to clients.
+	CalendarFeedKey string `json:"-"`
 }
 
 type UsersRepository interface {
@@ -26,10 +32,15 @@ type UsersRepository interface {
 	Delete(ctx context.Context, riderID string) (bool, error)
 }
 
-// Bike is aligned with internal/fleet.Motorcycle JSON.
+// Bike is the single bike aggregate behind both /api/bikes (served as
+// fleet.Motorcycle) and /api/fleet/bikes (served as fleet.FleetBike).
+// Depot doubles as the fleet tracker's location and Mileage is the odometer.
 type Bike struct {
 	ID               string    `json:"id"`
+	Make             string    `json:"make,omitempty"`
 	Model            string    `json:"model,omitempty"`
+	VehicleType      string    `json:"vehicleType,omitempty"`
+	Registration     string    `json:"registration,omitempty"`
 	Depot            string    `json:"depot,omitempty"`
 	Mileage          int       `json:"mileage,omitempty"`
 	LastServiceMiles int       `json:"lastServiceMiles,omitempty"`
@@ -38,24 +49,102 @@ type Bike struct {
 	CurrentRiderID   string    `json:"currentRiderId,omitempty"`
 	LocationLat      float64   `json:"locationLat,omitempty"`
 	LocationLng      float64   `json:"locationLng,omitempty"`
+	CreatedAt        time.Time `json:"createdAt,omitempty"`
 	UpdatedAt        time.Time `json:"updatedAt,omitempty"`
 }
 
+// ServiceEntry is one item of a bike's service history.
+type ServiceEntry struct {
+	ServiceID   string    `json:"serviceId" dynamodbav:"ServiceID"`
+	BikeID      string    `json:"bikeId" dynamodbav:"BikeID"`
+	ServiceType string    `json:"serviceType" dynamodbav:"ServiceType"`
+	ServiceDate time.Time `json:"serviceDate" dynamodbav:"ServiceDate"`
+	Odometer    int       `json:"odometer,omitempty" dynamodbav:"Odometer,omitempty"`
+	Notes       string    `json:"notes,omitempty" dynamodbav:"Notes,omitempty"`
+	PerformedBy string    `json:"performedBy,omitempty" dynamodbav:"PerformedBy,omitempty"`
+	CreatedAt   time.Time `json:"createdAt" dynamodbav:"CreatedAt"`
+}
+
 type BikesRepository interface {
 	List(ctx context.Context) ([]Bike, error)
 	Get(ctx context.Context, bikeID string) (*Bike, bool, error)
 	Put(ctx context.Context, b *Bike) error
+	// PutIfUnchanged writes b only if the stored bike still has prev's
+	// CurrentRiderID and UpdatedAt, and reports whether it did. Every
+	// change to an existing bike goes through it (see UpdateBike), so a
+	// rider assigned by another instance is never written over.
+	PutIfUnchanged(ctx context.Context, b *Bike, prev Bike) (bool, error)
 	Delete(ctx context.Context, bikeID string) (bool, error)
+
+	// Service history, newest first.
+	ListServiceEntries(ctx context.Context, bikeID string) ([]ServiceEntry, error)
+	PutServiceEntry(ctx context.Context, e *ServiceEntry) error
+	DeleteServiceEntry(ctx context.Context, bikeID, serviceID string) (bool, error)
+	// Fuel and running costs, newest first.
+	ListExpenses(ctx context.Context, bikeID string) ([]Expense, error)
+	PutExpense(ctx context.Context, e *Expense) error
+	DeleteExpense(ctx context.Context, bikeID, expenseID string) (bool, error)
 }
 
-// These are included for forward compatibility; the backend currently
-// doesn't expose depot/job endpoints.
+// maxBikeUpdateAttempts bounds the re-reads when a bike keeps changing
+// under UpdateBike.
+const maxBikeUpdateAttempts = 5
+
+// UpdateBike reads a bike, lets apply change it and writes it back with
+// PutIfUnchanged, reading it again if another writer got there first. It
+// is how anything other than a whole-record create writes a bike, so no
+// write drops a rider assignment made meanwhile. apply reports whether it
+// changed the bike; nothing is written when it didn't, or when it returns
+// an error. ok is false when the bike doesn't exist.
+func UpdateBike(ctx context.Context, bikes BikesRepository, bikeID string, apply func(b *Bike) (bool, error)) (b *Bike, ok bool, err error) {
+	for attempt := 0; attempt < maxBikeUpdateAttempts; attempt++ {
+		b, ok, err = bikes.Get(ctx, bikeID)
+		if err != nil || !ok {
+			return nil, ok, err
+		}
+		prev := *b
+		changed, err := apply(b)
+		if err != nil || !changed {
+			return b, true, err
+		}
+		b.UpdatedAt = time.Now()
+		written, err := bikes.PutIfUnchanged(ctx, b, prev)
+		if err != nil || written {
+			return b, true, err
+		}
+	}
+	return nil, true, fmt.Errorf("bike %s kept changing while being updated", bikeID)
+}
 
+// Expense is a fuel fill-up or other running cost for a bike. Litres and
+// Station are only set for fuel; Odometer is in miles like Bike.Mileage.
+type Expense struct {
+	ExpenseID  string    `json:"expenseId"`
+	BikeID     string    `json:"bikeId"`
+	Category   string    `json:"category"`
+	Date       time.Time `json:"date"`
+	Cost       float64   `json:"cost"`
+	Litres     float64   `json:"litres,omitempty"`
+	Odometer   int       `json:"odometer,omitempty"`
+	Station    string    `json:"station,omitempty"`
+	Notes      string    `json:"notes,omitempty"`
+	RecordedBy string    `json:"recordedBy,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// Depot is a base bikes and riders operate from. Bike.Depot,
+// User.Depot and RideSession.Depot hold its DepotID.
 type Depot struct {
-	DepotID string  `json:"depotId"`
-	Name    string  `json:"name,omitempty"`
-	Lat     float64 `json:"lat,omitempty"`
-	Lng     float64 `json:"lng,omitempty"`
+	DepotID     string    `json:"depotId"`
+	Name        string    `json:"name,omitempty"`
+	Address     string    `json:"address,omitempty"`
+	Lat         float64   `json:"lat,omitempty"`
+	Lng         float64   `json:"lng,omitempty"`
+	ContactName string    `json:"contactName,omitempty"`
+	Phone       string    `json:"phone,omitempty"`
+	Email       string    `json:"email,omitempty"`
+	CreatedAt   time.Time `json:"createdAt,omitempty"`
+	UpdatedAt   time.Time `json:"updatedAt,omitempty"`
 }
 
 type DepotsRepository interface {
@@ -98,6 +187,27 @@ type Event struct {
 	Status         string    `json:"status"         dynamodbav:"status"`
 	CreatedAt      time.Time `json:"createdAt"      dynamodbav:"createdAt"`
 	UpdatedAt      time.Time `json:"updatedAt"      dynamodbav:"updatedAt"`
+
+	// A recurring series carries its RRULE and the occurrence dates
+	// ("2006-01-02") removed from it. An occurrence edited on its own is
+	// stored as a separate event pointing back at its series and the date
+	// it replaces.
+	Recurrence     string   `json:"recurrence,omitempty"     dynamodbav:"recurrence,omitempty"`
+	ExDates        []string `json:"exDates,omitempty"        dynamodbav:"exDates,omitempty"`
+	SeriesID       string   `json:"seriesId,omitempty"       dynamodbav:"seriesId,omitempty"`
+	OccurrenceDate string   `json:"occurrenceDate,omitempty" dynamodbav:"occurrenceDate,omitempty"`
+
+	// Set once the event has ended for good; archived events are kept for
+	// reporting until the retention period runs out.
+	ArchivedAt time.Time `json:"archivedAt,omitempty" dynamodbav:"archivedAt,omitempty"`
+
+	// Staffing: how many riders the event needs, the user tags each of
+	// them must hold, riders waiting for a place in sign-up order, and the
+	// occurrence dates coordinators have been warned are under-staffed.
+	RequiredRiders         int      `json:"requiredRiders,omitempty"         dynamodbav:"requiredRiders,omitempty"`
+	RequiredQualifications []string `json:"requiredQualifications,omitempty" dynamodbav:"requiredQualifications,omitempty"`
+	Waitlist               []string `json:"waitlist,omitempty"               dynamodbav:"waitlist,omitempty"`
+	StaffingAlerts         []string `json:"staffingAlerts,omitempty"         dynamodbav:"staffingAlerts,omitempty"`
 }
 
 type EventsRepository interface {
@@ -107,17 +217,262 @@ type EventsRepository interface {
 	Delete(ctx context.Context, eventID string) (bool, error)
 }
 
+// ── Job Messages ────────────────────────────────────────────────────────
+
+// JobMessage is one message in a job's thread between the dispatcher and
+// the rider. ReadBy records when each recipient read it.
+type JobMessage struct {
+	MessageID string               `json:"messageId" dynamodbav:"MessageID"`
+	JobID     string               `json:"jobId" dynamodbav:"JobID"`
+	Sender    string               `json:"sender" dynamodbav:"Sender"`
+	Body      string               `json:"body" dynamodbav:"Body"`
+	SentAt    time.Time            `json:"sentAt" dynamodbav:"SentAt"`
+	ReadBy    map[string]time.Time `json:"readBy,omitempty" dynamodbav:"ReadBy,omitempty"`
+}
+
+type JobMessagesRepository interface {
+	Get(ctx context.Context, messageID string) (*JobMessage, bool, error)
+	Put(ctx context.Context, m *JobMessage) error
+	// ListByJob returns a job's messages, oldest first.
+	ListByJob(ctx context.Context, jobID string) ([]JobMessage, error)
+}
+
+// ── Notification Preferences ────────────────────────────────────────────
+
+// NotificationPreferences is what a user wants to be notified about and
+// how. Categories and Channels missing from the maps take their defaults
+// (see notifyprefs).
+type NotificationPreferences struct {
+	Username   string          `json:"username"`
+	Categories map[string]bool `json:"categories,omitempty"`
+	Channels   map[string]bool `json:"channels,omitempty"`
+	QuietHours *QuietHours     `json:"quietHours,omitempty"`
+	// Phone is where SMS notifications go, in E.164.
+	Phone     string    `json:"phone,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt,omitempty"`
+}
+
+// QuietHours is a daily period, in the org time zone, when only
+// safety-critical notifications are delivered. End before Start runs
+// overnight.
+type QuietHours struct {
+	Start string `json:"start"` // "HH:MM"
+	End   string `json:"end"`   // "HH:MM"
+}
+
+type NotificationPreferencesRepository interface {
+	Get(ctx context.Context, username string) (*NotificationPreferences, bool, error)
+	Put(ctx context.Context, p *NotificationPreferences) error
+}
+
+// ── SMS Messages ────────────────────────────────────────────────────────
+
+// SMSMessage is one text message sent, or refused, through the SMS
+// gateway, with its delivery status.
+type SMSMessage struct {
+	MessageID  string    `json:"messageId" dynamodbav:"MessageID"`
+	To         string    `json:"to" dynamodbav:"To"`
+	Username   string    `json:"username,omitempty" dynamodbav:"Username,omitempty"`
+	Category   string    `json:"category,omitempty" dynamodbav:"Category,omitempty"`
+	Body       string    `json:"body" dynamodbav:"Body"`
+	Gateway    string    `json:"gateway" dynamodbav:"Gateway"`
+	ProviderID string    `json:"providerId,omitempty" dynamodbav:"ProviderID,omitempty"`
+	Status     string    `json:"status" dynamodbav:"Status"`
+	Error      string    `json:"error,omitempty" dynamodbav:"Error,omitempty"`
+	CreatedAt  time.Time `json:"createdAt" dynamodbav:"CreatedAt"`
+	UpdatedAt  time.Time `json:"updatedAt" dynamodbav:"UpdatedAt"`
+}
+
+type SMSMessagesRepository interface {
+	List(ctx context.Context) ([]SMSMessage, error)
+	Get(ctx context.Context, messageID string) (*SMSMessage, bool, error)
+	Put(ctx context.Context, m *SMSMessage) error
+}
+
+// ── Notification Outbox ─────────────────────────────────────────────────
+
+// NotificationAudience is who a notification is for: the named users, the
+// users with a role (or that role or above), or everyone when empty.
+type NotificationAudience struct {
+	Usernames []string `json:"usernames,omitempty" dynamodbav:"Usernames,omitempty"`
+	Role      string   `json:"role,omitempty" dynamodbav:"Role,omitempty"`
+	OrAbove   bool     `json:"orAbove,omitempty" dynamodbav:"OrAbove,omitempty"`
+}
+
+// OutboxNotification is a notification waiting in, or sent from, the
+// notification outbox. Its deliveries are tracked separately.
+type OutboxNotification struct {
+	NotificationID string               `json:"notificationId" dynamodbav:"NotificationID"`
+	Category       string               `json:"category,omitempty" dynamodbav:"Category,omitempty"`
+	Title          string               `json:"title" dynamodbav:"Title"`
+	Body           string               `json:"body" dynamodbav:"Body"`
+	URL            string               `json:"url,omitempty" dynamodbav:"URL,omitempty"`
+	Audience       NotificationAudience `json:"audience" dynamodbav:"Audience"`
+	Status         string               `json:"status" dynamodbav:"Status"`
+	CreatedAt      time.Time            `json:"createdAt" dynamodbav:"CreatedAt"`
+	UpdatedAt      time.Time            `json:"updatedAt" dynamodbav:"UpdatedAt"`
+}
+
+type NotificationsRepository interface {
+	List(ctx context.Context) ([]OutboxNotification, error)
+	ListByStatus(ctx context.Context, status string) ([]OutboxNotification, error)
+	Get(ctx context.Context, notificationID string) (*OutboxNotification, bool, error)
+	Put(ctx context.Context, n *OutboxNotification) error
+	Delete(ctx context.Context, notificationID string) (bool, error)
+}
+
+// NotificationDelivery is one notification's delivery to one recipient
+// over one channel: a push subscription (Endpoint) or a user's phone.
+type NotificationDelivery struct {
+	DeliveryID     string    `json:"deliveryId" dynamodbav:"DeliveryID"`
+	NotificationID string    `json:"notificationId" dynamodbav:"NotificationID"`
+	Channel        string    `json:"channel" dynamodbav:"Channel"`
+	Username       string    `json:"username,omitempty" dynamodbav:"Username,omitempty"`
+	Endpoint       string    `json:"endpoint,omitempty" dynamodbav:"Endpoint,omitempty"`
+	Status         string    `json:"status" dynamodbav:"Status"`
+	Attempts       int       `json:"attempts" dynamodbav:"Attempts"`
+	NextAttemptAt  time.Time `json:"nextAttemptAt,omitempty" dynamodbav:"NextAttemptAt,omitempty"`
+	LastError      string    `json:"lastError,omitempty" dynamodbav:"LastError,omitempty"`
+	CreatedAt      time.Time `json:"createdAt" dynamodbav:"CreatedAt"`
+	UpdatedAt      time.Time `json:"updatedAt" dynamodbav:"UpdatedAt"`
+}
+
+// DeliveryPending is the status of a delivery still to be attempted.
+const DeliveryPending = "pending"
+
+type NotificationDeliveriesRepository interface {
+	List(ctx context.Context) ([]NotificationDelivery, error)
+	ListByNotification(ctx context.Context, notificationID string) ([]NotificationDelivery, error)
+	// ListDue returns the pending deliveries whose next attempt is at or
+	// before now.
+	ListDue(ctx context.Context, now time.Time) ([]NotificationDelivery, error)
+	Put(ctx context.Context, d *NotificationDelivery) error
+	Delete(ctx context.Context, deliveryID string) (bool, error)
+}
+
+// ── Shifts ──────────────────────────────────────────────────────────────
+
+// Shift is one slot on a region's on-call rota. Region holds a DepotID.
+type Shift struct {
+	ShiftID            string      `json:"shiftId"                dynamodbav:"shiftId"`
+	Region             string      `json:"region"                 dynamodbav:"region"`
+	Start              time.Time   `json:"start"                  dynamodbav:"start"`
+	End                time.Time   `json:"end"                    dynamodbav:"end"`
+	RequiredRiders     int         `json:"requiredRiders"         dynamodbav:"requiredRiders"`
+	RequiresDispatcher bool        `json:"requiresDispatcher"     dynamodbav:"requiresDispatcher"`
+	Riders             []string    `json:"riders,omitempty"       dynamodbav:"riders,omitempty"`
+	Dispatcher         string      `json:"dispatcher,omitempty"   dynamodbav:"dispatcher,omitempty"`
+	Notes              string      `json:"notes,omitempty"        dynamodbav:"notes,omitempty"`
+	Swaps              []ShiftSwap `json:"swaps,omitempty"        dynamodbav:"swaps,omitempty"`
+	CreatedAt          time.Time   `json:"createdAt"              dynamodbav:"createdAt"`
+	UpdatedAt          time.Time   `json:"updatedAt"              dynamodbav:"updatedAt"`
+
+	// OnDuty are the riders the shift has made available; they go off duty
+	// again when the shift ends or they leave it.
+	OnDuty []string `json:"onDuty,omitempty" dynamodbav:"onDuty,omitempty"`
+}
+
+// ShiftSwap is a rider's request to hand their place on a shift to another
+// rider, which a coordinator approves or rejects.
+type ShiftSwap struct {
+	SwapID      string    `json:"swapId"              dynamodbav:"swapId"`
+	FromRider   string    `json:"fromRider"           dynamodbav:"fromRider"`
+	ToRider     string    `json:"toRider"             dynamodbav:"toRider"`
+	Note        string    `json:"note,omitempty"      dynamodbav:"note,omitempty"`
+	Status      string    `json:"status"              dynamodbav:"status"`
+	RequestedAt time.Time `json:"requestedAt"         dynamodbav:"requestedAt"`
+	DecidedBy   string    `json:"decidedBy,omitempty" dynamodbav:"decidedBy,omitempty"`
+	DecidedAt   time.Time `json:"decidedAt,omitempty" dynamodbav:"decidedAt,omitempty"`
+}
+
+type ShiftsRepository interface {
+	List(ctx context.Context) ([]Shift, error)
+	Get(ctx context.Context, shiftID string) (*Shift, bool, error)
+	Put(ctx context.Context, s *Shift) error
+	Delete(ctx context.Context, shiftID string) (bool, error)
+}
+
+// ── Rider Availability ──────────────────────────────────────────────────
+
+// RiderAvailability is a rider's availability calendar: one-off slots and
+// weekly patterns published in advance, alongside the live User.Status.
+type RiderAvailability struct {
+	RiderID   string               `json:"riderId"          dynamodbav:"riderId"`
+	Slots     []AvailabilitySlot   `json:"slots,omitempty"  dynamodbav:"slots,omitempty"`
+	Weekly    []WeeklyAvailability `json:"weekly,omitempty" dynamodbav:"weekly,omitempty"`
+	UpdatedAt time.Time            `json:"updatedAt"        dynamodbav:"updatedAt"`
+}
+
+// AvailabilitySlot is a one-off period the rider is available, or with
+// Unavailable set, away (overriding their weekly patterns).
+type AvailabilitySlot struct {
+	SlotID      string    `json:"slotId"                dynamodbav:"slotId"`
+	Start       time.Time `json:"start"                 dynamodbav:"start"`
+	End         time.Time `json:"end"                   dynamodbav:"end"`
+	Unavailable bool      `json:"unavailable,omitempty" dynamodbav:"unavailable,omitempty"`
+	Note        string    `json:"note,omitempty"        dynamodbav:"note,omitempty"`
+}
+
+// WeeklyAvailability repeats every week on Weekday (0 = Sunday) from
+// StartTime to EndTime, wall-clock "HH:MM" in the org time zone; an
+// EndTime at or before StartTime runs into the next day. From and Until
+// ("2006-01-02", both optional and inclusive) bound the days it applies.
+type WeeklyAvailability struct {
+	PatternID string `json:"patternId"       dynamodbav:"patternId"`
+	Weekday   int    `json:"weekday"         dynamodbav:"weekday"`
+	StartTime string `json:"startTime"       dynamodbav:"startTime"`
+	EndTime   string `json:"endTime"         dynamodbav:"endTime"`
+	From      string `json:"from,omitempty"  dynamodbav:"from,omitempty"`
+	Until     string `json:"until,omitempty" dynamodbav:"until,omitempty"`
+}
+
+type AvailabilityRepository interface {
+	List(ctx context.Context) ([]RiderAvailability, error)
+	Get(ctx context.Context, riderID string) (*RiderAvailability, bool, error)
+	Put(ctx context.Context, a *RiderAvailability) error
+	Delete(ctx context.Context, riderID string) (bool, error)
+}
+
 // ── Ride Sessions ───────────────────────────────────────────────────────
 
+// RideSession is one use of a bike. Sessions opened through the checkout
+// flow also carry the pre-ride inspection and fuel levels.
 type RideSession struct {
-	SessionID  string    `json:"sessionId"  dynamodbav:"SessionID"`
-	BikeID     string    `json:"bikeId"     dynamodbav:"BikeID"`
-	RiderID    string    `json:"riderId"    dynamodbav:"RiderID"`
-	Depot      string    `json:"depot"      dynamodbav:"Depot"`
-	StartTime  time.Time `json:"startTime"  dynamodbav:"StartTime"`
-	EndTime    time.Time `json:"endTime"    dynamodbav:"EndTime"`
-	StartMiles int       `json:"startMiles" dynamodbav:"StartMiles"`
-	EndMiles   int       `json:"endMiles"   dynamodbav:"EndMiles"`
+	SessionID  string           `json:"sessionId"           dynamodbav:"SessionID"`
+	BikeID     string           `json:"bikeId"              dynamodbav:"BikeID"`
+	RiderID    string           `json:"riderId"             dynamodbav:"RiderID"`
+	Depot      string           `json:"depot"               dynamodbav:"Depot"`
+	StartTime  time.Time        `json:"startTime"           dynamodbav:"StartTime"`
+	EndTime    time.Time        `json:"endTime"             dynamodbav:"EndTime"`
+	StartMiles int              `json:"startMiles"          dynamodbav:"StartMiles"`
+	EndMiles   int              `json:"endMiles"            dynamodbav:"EndMiles"`
+	StartFuel  *int             `json:"startFuel,omitempty" dynamodbav:"StartFuel,omitempty"`
+	EndFuel    *int             `json:"endFuel,omitempty"   dynamodbav:"EndFuel,omitempty"`
+	Checklist  []InspectionItem `json:"checklist,omitempty" dynamodbav:"Checklist,omitempty"`
+	IssueIDs   []string         `json:"issueIds,omitempty"  dynamodbav:"IssueIDs,omitempty"`
+	Notes      string           `json:"notes,omitempty"     dynamodbav:"Notes,omitempty"`
+
+	// Mileage reconciliation against the rider's GPS trail. A flagged
+	// session stays in the fleet manager's review queue until reviewed.
+	GPSMiles          *float64  `json:"gpsMiles,omitempty"          dynamodbav:"GPSMiles,omitempty"`
+	MileageFlagged    bool      `json:"mileageFlagged,omitempty"    dynamodbav:"MileageFlagged,omitempty"`
+	MileageReviewedBy string    `json:"mileageReviewedBy,omitempty" dynamodbav:"MileageReviewedBy,omitempty"`
+	MileageReviewedAt time.Time `json:"mileageReviewedAt,omitempty" dynamodbav:"MileageReviewedAt,omitempty"`
+
+	// The jobs the ride was for, and its duration and odometer distance
+	// once ended. AutoClosed marks a session the stale-session sweep
+	// closed because the rider never ended it; its end time isn't real.
+	JobIDs          []string `json:"jobIds,omitempty"          dynamodbav:"JobIDs,omitempty"`
+	DurationMinutes int      `json:"durationMinutes,omitempty" dynamodbav:"DurationMinutes,omitempty"`
+	DistanceMiles   int      `json:"distanceMiles,omitempty"   dynamodbav:"DistanceMiles,omitempty"`
+	AutoClosed      bool     `json:"autoClosed,omitempty"      dynamodbav:"AutoClosed,omitempty"`
+}
+
+// InspectionItem is one line of a pre-ride checklist.
+type InspectionItem struct {
+	Item  string `json:"item"            dynamodbav:"Item"`
+	OK    bool   `json:"ok"              dynamodbav:"OK"`
+	Notes string `json:"notes,omitempty" dynamodbav:"Notes,omitempty"`
 }
 
 type RideSessionsRepository interface {
@@ -130,6 +485,8 @@ type RideSessionsRepository interface {
 
 // ── Issue Reports ───────────────────────────────────────────────────────
 
+// IssueReport is a fault reported against a bike. It doubles as the
+// workshop work order: Status tracks the repair and AssignedTo the mechanic.
 type IssueReport struct {
 	IssueID     string    `json:"issueId"     dynamodbav:"IssueID"`
 	BikeID      string    `json:"bikeId"      dynamodbav:"BikeID"`
@@ -138,6 +495,14 @@ type IssueReport struct {
 	Description string    `json:"description" dynamodbav:"Description"`
 	Timestamp   time.Time `json:"timestamp"   dynamodbav:"Timestamp"`
 	Resolved    bool      `json:"resolved"    dynamodbav:"Resolved"`
+
+	Status     string    `json:"status,omitempty"     dynamodbav:"Status,omitempty"`
+	AssignedTo string    `json:"assignedTo,omitempty" dynamodbav:"AssignedTo,omitempty"`
+	Resolution string    `json:"resolution,omitempty" dynamodbav:"Resolution,omitempty"`
+	ResolvedBy string    `json:"resolvedBy,omitempty" dynamodbav:"ResolvedBy,omitempty"`
+	ResolvedAt time.Time `json:"resolvedAt,omitempty" dynamodbav:"ResolvedAt,omitempty"`
+	ServiceID  string    `json:"serviceId,omitempty"  dynamodbav:"ServiceID,omitempty"`
+	UpdatedAt  time.Time `json:"updatedAt,omitempty"  dynamodbav:"UpdatedAt,omitempty"`
 }
 
 type IssueReportsRepository interface {
@@ -146,3 +511,56 @@ type IssueReportsRepository interface {
 	Put(ctx context.Context, r *IssueReport) error
 	Delete(ctx context.Context, issueID string) (bool, error)
 }
+
+// ── Bike Documents ──────────────────────────────────────────────────────
+
+// BikeDocument is a statutory document (insurance, motor tax, NCT) held for
+// a bike. FileData is an optional data URI of the scanned document.
+type BikeDocument struct {
+	DocumentID    string    `json:"documentId"              dynamodbav:"DocumentID"`
+	BikeID        string    `json:"bikeId"                  dynamodbav:"BikeID"`
+	Type          string    `json:"type"                    dynamodbav:"Type"`
+	Reference     string    `json:"reference,omitempty"     dynamodbav:"Reference,omitempty"`
+	ExpiryDate    time.Time `json:"expiryDate"              dynamodbav:"ExpiryDate"`
+	FileName      string    `json:"fileName,omitempty"      dynamodbav:"FileName,omitempty"`
+	FileData      string    `json:"fileData,omitempty"      dynamodbav:"FileData,omitempty"`
+	RemindersSent []int     `json:"remindersSent,omitempty" dynamodbav:"RemindersSent,omitempty"`
+	UploadedBy    string    `json:"uploadedBy,omitempty"    dynamodbav:"UploadedBy,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"               dynamodbav:"CreatedAt"`
+	UpdatedAt     time.Time `json:"updatedAt"               dynamodbav:"UpdatedAt"`
+}
+
+type BikeDocumentsRepository interface {
+	List(ctx context.Context) ([]BikeDocument, error)
+	Get(ctx context.Context, documentID string) (*BikeDocument, bool, error)
+	Put(ctx context.Context, d *BikeDocument) error
+	Delete(ctx context.Context, documentID string) (bool, error)
+	ListByBike(ctx context.Context, bikeID string) ([]BikeDocument, error)
+}
+
+// ── Attachments ─────────────────────────────────────────────────────────
+
+// Attachment is a photo or file attached to an issue report or service
+// entry. The bytes live in blob storage under BlobKey; ThumbnailKey is set
+// for images.
+type Attachment struct {
+	AttachmentID string    `json:"attachmentId" dynamodbav:"AttachmentID"`
+	OwnerType    string    `json:"ownerType" dynamodbav:"OwnerType"`
+	OwnerID      string    `json:"ownerId" dynamodbav:"OwnerID"`
+	BikeID       string    `json:"bikeId,omitempty" dynamodbav:"BikeID,omitempty"`
+	FileName     string    `json:"fileName" dynamodbav:"FileName"`
+	ContentType  string    `json:"contentType" dynamodbav:"ContentType"`
+	Size         int64     `json:"size" dynamodbav:"Size"`
+	BlobKey      string    `json:"-" dynamodbav:"BlobKey"`
+	ThumbnailKey string    `json:"-" dynamodbav:"ThumbnailKey,omitempty"`
+	UploadedBy   string    `json:"uploadedBy,omitempty" dynamodbav:"UploadedBy,omitempty"`
+	CreatedAt    time.Time `json:"createdAt" dynamodbav:"CreatedAt"`
+}
+
+type AttachmentsRepository interface {
+	Get(ctx context.Context, attachmentID string) (*Attachment, bool, error)
+	Put(ctx context.Context, a *Attachment) error
+	Delete(ctx context.Context, attachmentID string) (bool, error)
+	// ListByOwner returns an owner's attachments, oldest first.
+	ListByOwner(ctx context.Context, ownerType, ownerID string) ([]Attachment, error)
+}
@@ -2,15 +2,34 @@ package ridesessions
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/auth"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/depots"
 )
 
-// ListOrCreate handles GET /api/ride-sessions and POST /api/ride-sessions
+// ListOrCreate handles GET /api/ride-sessions and POST /api/ride-sessions,
+// which starts a ride: the session, the bike's assignment and its jobs.
+// GET ?flagged=true lists sessions awaiting mileage review (FleetManager).
 func ListOrCreate(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
+		if r.URL.Query().Get("flagged") == "true" {
+			if !auth.HasRoleOrAbove(auth.RolesFromContext(r.Context()), "FleetManager") {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			items, err := List(r.Context())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, http.StatusOK, FlaggedForReview(items))
+			return
+		}
 		bikeID := r.URL.Query().Get("bikeId")
 		if bikeID != "" {
 			items, err := ListByBike(r.Context(), bikeID)
@@ -34,12 +53,17 @@ func ListOrCreate(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
 			return
 		}
-		s, err := Create(r.Context(), req)
+		s, _, err := Start(r.Context(), req)
 		if err != nil {
-			if isValidationError(err) {
+			switch {
+			case isValidationError(err):
 				http.Error(w, err.Error(), http.StatusBadRequest)
-			} else {
-				log.Printf("[ride-sessions] failed to create: %v", err)
+			case errors.Is(err, ErrBikeNotFound):
+				http.Error(w, err.Error(), http.StatusNotFound)
+			case isConflict(err):
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				log.Printf("[ride-sessions] failed to start ride: %v", err)
 				http.Error(w, "internal server error", http.StatusInternalServerError)
 			}
 			return
@@ -50,7 +74,9 @@ func ListOrCreate(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// Detail handles GET/PUT/DELETE /api/ride-sessions/{id}
+// Detail handles GET/PUT/DELETE /api/ride-sessions/{id} (PUT ends the ride
+// and releases the bike) and
+// POST /api/ride-sessions/{id}/review (FleetManager) to clear a mileage flag.
 func Detail(w http.ResponseWriter, r *http.Request) {
 	id := strings.TrimPrefix(r.URL.Path, "/api/ride-sessions/")
 	id = strings.Trim(id, "/")
@@ -58,6 +84,10 @@ func Detail(w http.ResponseWriter, r *http.Request) {
 		http.NotFound(w, r)
 		return
 	}
+	if sessionID, ok := strings.CutSuffix(id, "/review"); ok {
+		reviewMileage(w, r, sessionID)
+		return
+	}
 
 	switch r.Method {
 	case http.MethodGet:
@@ -83,6 +113,14 @@ func Detail(w http.ResponseWriter, r *http.Request) {
 				http.Error(w, "ride session not found", http.StatusNotFound)
 				return
 			}
+			if isValidationError(err) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if isConflict(err) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
 			log.Printf("[ride-sessions] failed to end session %s: %v", id, err)
 			http.Error(w, "internal server error", http.StatusInternalServerError)
 			return
@@ -104,13 +142,47 @@ func Detail(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func reviewMileage(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !auth.HasRoleOrAbove(auth.RolesFromContext(r.Context()), "FleetManager") {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	s, err := ReviewMileage(r.Context(), id, auth.UsernameFromContext(r.Context()))
+	if err != nil {
+		switch {
+		case err.Error() == "not found":
+			http.Error(w, "ride session not found", http.StatusNotFound)
+		case errors.Is(err, errNotFlagged):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			log.Printf("[ride-sessions] failed to review session %s: %v", id, err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, s)
+}
+
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(v)
 }
 
+// isConflict reports errors from the bike or session being in the wrong
+// state for the request.
+func isConflict(err error) bool {
+	return errors.Is(err, ErrBikeUnavailable) || errors.Is(err, ErrRiderOnRide) || errors.Is(err, ErrAlreadyEnded)
+}
+
 func isValidationError(err error) bool {
+	if errors.Is(err, ErrOdometerRollback) || errors.Is(err, depots.ErrUnknownDepot) || errors.Is(err, ErrInvalidJob) {
+		return true
+	}
 	msg := err.Error()
 	return msg == "bikeId required" || msg == "riderId required"
 }
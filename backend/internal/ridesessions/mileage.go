@@ -0,0 +1,114 @@
+package ridesessions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/analytics"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+)
+
+// KmPerMile converts odometer readings, which are in miles, to km: GPS
+// distance and running costs are measured in km.
+const KmPerMile = 1.609344
+
+// A ride's odometer distance is flagged when it differs from the GPS
+// distance by more than discrepancyMinMiles and discrepancyRatio of the
+// GPS distance. GPS cuts corners between fixes, so small gaps are normal.
+const (
+	discrepancyMinMiles = 2.0
+	discrepancyRatio    = 0.2
+)
+
+// ErrOdometerRollback is returned for a reading lower than the bike's last
+// known odometer or the session's start reading.
+var ErrOdometerRollback = errors.New("odometer reading lower than last recorded")
+
+var bikesRepo repo.BikesRepository
+
+// SetBikesRepository lets sessions validate readings against, and update,
+// the bike's odometer.
+func SetBikesRepository(r repo.BikesRepository) {
+	bikesRepo = r
+}
+
+// gpsDistanceKm is the rider's GPS distance over a window; swapped in tests.
+var gpsDistanceKm = analytics.GlobalStore.DistanceKm
+
+// CheckStartMiles rejects a start reading below the bike's odometer.
+// Bikes that aren't in the repository are taken on trust.
+func CheckStartMiles(ctx context.Context, bikeID string, miles int) error {
+	if bikesRepo == nil {
+		return nil
+	}
+	b, ok, err := bikesRepo.Get(ctx, bikeID)
+	if err != nil || !ok {
+		return err
+	}
+	if miles < b.Mileage {
+		return fmt.Errorf("%w (%d)", ErrOdometerRollback, b.Mileage)
+	}
+	return nil
+}
+
+// lastKnownMiles is the bike's odometer, or 0 when it isn't known.
+func lastKnownMiles(ctx context.Context, bikeID string) (int, error) {
+	if bikesRepo == nil {
+		return 0, nil
+	}
+	b, ok, err := bikesRepo.Get(ctx, bikeID)
+	if err != nil || !ok {
+		return 0, err
+	}
+	return b.Mileage, nil
+}
+
+// CheckEndMiles rejects an end reading below the session's start reading.
+func CheckEndMiles(s *repo.RideSession, miles int) error {
+	if miles < s.StartMiles {
+		return fmt.Errorf("%w (%d at start)", ErrOdometerRollback, s.StartMiles)
+	}
+	return nil
+}
+
+// ReconcileMileage compares the session's odometer distance with the
+// rider's GPS distance over the same window and flags large differences.
+// Sessions without GPS coverage are left unflagged.
+func ReconcileMileage(s *repo.RideSession) {
+	s.GPSMiles = nil
+	s.MileageFlagged = false
+	if s.StartTime.IsZero() || s.EndTime.IsZero() {
+		return
+	}
+	km, ok := gpsDistanceKm(s.RiderID, s.StartTime, s.EndTime)
+	if !ok {
+		return
+	}
+	gps := math.Round(km/KmPerMile*10) / 10
+	s.GPSMiles = &gps
+	s.MileageFlagged = mileageDiscrepancy(float64(s.EndMiles-s.StartMiles), gps)
+	if s.MileageFlagged {
+		log.Printf("op=MileageDiscrepancy sessionId=%s bikeId=%s odometerMiles=%d gpsMiles=%.1f",
+			s.SessionID, s.BikeID, s.EndMiles-s.StartMiles, gps)
+	}
+}
+
+func mileageDiscrepancy(odometerMiles, gpsMiles float64) bool {
+	diff := math.Abs(odometerMiles - gpsMiles)
+	return diff > discrepancyMinMiles && diff > gpsMiles*discrepancyRatio
+}
+
+// FlaggedForReview returns ended sessions whose mileage was flagged and
+// hasn't been reviewed yet.
+func FlaggedForReview(sessions []repo.RideSession) []repo.RideSession {
+	out := make([]repo.RideSession, 0)
+	for _, s := range sessions {
+		if s.MileageFlagged && s.MileageReviewedBy == "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
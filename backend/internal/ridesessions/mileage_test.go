@@ -0,0 +1,110 @@
+package ridesessions
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo/memory"
+)
+
+func setup(t *testing.T, gpsKm float64, gpsOK bool) *memory.BikesRepo {
+	t.Helper()
+	bikes := memory.NewBikesRepo()
+	SetRepository(memory.NewRideSessionsRepo())
+	SetBikesRepository(bikes)
+	orig := gpsDistanceKm
+	gpsDistanceKm = func(string, time.Time, time.Time) (float64, bool) { return gpsKm, gpsOK }
+	t.Cleanup(func() {
+		SetRepository(nil)
+		SetBikesRepository(nil)
+		gpsDistanceKm = orig
+	})
+	return bikes
+}
+
+// ---- discrepancy rule ----
+
+func TestMileageDiscrepancy(t *testing.T) {
+	cases := []struct {
+		odometer, gps float64
+		want          bool
+	}{
+		{40, 38, false},  // within 2 miles
+		{100, 90, false}, // within 20%
+		{60, 40, true},
+		{5, 0, true},
+	}
+	for _, c := range cases {
+		if got := mileageDiscrepancy(c.odometer, c.gps); got != c.want {
+			t.Errorf("odometer %v gps %v: expected %v, got %v", c.odometer, c.gps, c.want, got)
+		}
+	}
+}
+
+// ---- sessions ----
+
+func TestCreate_RejectsOdometerRollback(t *testing.T) {
+	bikes := setup(t, 0, false)
+	ctx := context.Background()
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b1", Mileage: 5000})
+
+	if _, err := Create(ctx, CreateRequest{BikeID: "b1", RiderID: "r1", StartMiles: 4900}); !errors.Is(err, ErrOdometerRollback) {
+		t.Errorf("expected rollback error, got %v", err)
+	}
+	s, err := Create(ctx, CreateRequest{BikeID: "b1", RiderID: "r1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.StartMiles != 5000 {
+		t.Errorf("expected missing start reading to default to 5000, got %d", s.StartMiles)
+	}
+}
+
+func TestEndSession_UpdatesOdometerAndFlags(t *testing.T) {
+	bikes := setup(t, 16, true) // ~10 miles of GPS
+	ctx := context.Background()
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b1", Mileage: 5000})
+
+	s, _ := Create(ctx, CreateRequest{BikeID: "b1", RiderID: "r1", StartMiles: 5000})
+	if _, err := EndSession(ctx, s.SessionID, EndRequest{EndMiles: 4999}); !errors.Is(err, ErrOdometerRollback) {
+		t.Errorf("expected rollback error, got %v", err)
+	}
+	ended, err := EndSession(ctx, s.SessionID, EndRequest{EndMiles: 5060})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ended.MileageFlagged || ended.GPSMiles == nil || *ended.GPSMiles != 9.9 {
+		t.Errorf("expected 60 miles vs ~10 GPS miles to be flagged, got %+v", ended)
+	}
+	b, _, _ := bikes.Get(ctx, "b1")
+	if b.Mileage != 5060 {
+		t.Errorf("expected bike odometer 5060, got %d", b.Mileage)
+	}
+
+	all, _ := List(ctx)
+	if len(FlaggedForReview(all)) != 1 {
+		t.Fatal("expected session in review queue")
+	}
+	if _, err := ReviewMileage(ctx, s.SessionID, "fm1"); err != nil {
+		t.Fatalf("review: %v", err)
+	}
+	all, _ = List(ctx)
+	if len(FlaggedForReview(all)) != 0 {
+		t.Error("expected reviewed session to leave the queue")
+	}
+}
+
+func TestEndSession_NoGPSNotFlagged(t *testing.T) {
+	bikes := setup(t, 0, false)
+	ctx := context.Background()
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b1"})
+
+	s, _ := Create(ctx, CreateRequest{BikeID: "b1", RiderID: "r1", StartMiles: 100})
+	ended, _ := EndSession(ctx, s.SessionID, EndRequest{EndMiles: 400})
+	if ended.MileageFlagged || ended.GPSMiles != nil {
+		t.Errorf("expected no GPS comparison, got %+v", ended)
+	}
+}
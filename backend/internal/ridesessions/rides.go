@@ -0,0 +1,422 @@
+package ridesessions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+)
+
+// A ride is a session together with the bike it's on and the jobs it's
+// for. Start assigns the bike and then creates the session; EndSession
+// closes the session and releases the bike. The bike is assigned and
+// released with a conditional write (BikesRepository.PutIfUnchanged), so
+// when two instances race for one bike only one rider gets it. If the
+// second write fails the first is undone rather than leaving the session
+// and bike disagreeing. rideMu only saves this instance from racing itself.
+
+var rideMu sync.Mutex
+
+var (
+	ErrBikeUnavailable = errors.New("bike unavailable")
+	ErrRiderOnRide     = errors.New("rider already has an open ride session")
+	ErrAlreadyEnded    = errors.New("ride session already ended")
+	ErrBikeNotFound    = errors.New("bike not found")
+	ErrInvalidJob      = errors.New("job is not under way for this rider")
+)
+
+// Bike statuses rides move bikes between. They match the fleet package's
+// constants, which can't be imported from here.
+const (
+	bikeStatusAvailable     = "Available"
+	bikeStatusOnDuty        = "OnDuty"
+	bikeStatusInService     = "InService"
+	bikeStatusFaultReported = "FaultReported"
+	bikeStatusOutOfService  = "OutOfService"
+)
+
+var jobsRepo repo.JobsRepository
+
+// SetJobsRepository lets rides link the jobs the rider has under way.
+func SetJobsRepository(r repo.JobsRepository) {
+	jobsRepo = r
+}
+
+// BlockReason explains why the bike can't be taken out, or returns "" if
+// it can.
+func BlockReason(b repo.Bike) string {
+	switch b.Status {
+	case bikeStatusInService:
+		return "bike is in service"
+	case bikeStatusFaultReported:
+		return "bike has a reported fault"
+	case bikeStatusOutOfService:
+		return "bike is out of service"
+	}
+	if b.CurrentRiderID != "" {
+		return "bike is already checked out to " + b.CurrentRiderID
+	}
+	if b.Status == bikeStatusOnDuty {
+		return "bike is already on duty"
+	}
+	return ""
+}
+
+// availabilityCheck is the extra check registered with SetAvailabilityCheck.
+var availabilityCheck func(ctx context.Context, b repo.Bike, now time.Time) (string, error)
+
+// SetAvailabilityCheck registers a check made on top of BlockReason before
+// a bike is taken out. It returns why the bike can't be, or "". Fleet uses
+// it to refuse bikes with expired documents.
+func SetAvailabilityCheck(f func(ctx context.Context, b repo.Bike, now time.Time) (string, error)) {
+	availabilityCheck = f
+}
+
+// UnavailableReason explains why the bike can't be taken out now, from
+// BlockReason and the registered availability check, or returns "" if it
+// can.
+func UnavailableReason(ctx context.Context, b repo.Bike, now time.Time) (string, error) {
+	if reason := BlockReason(b); reason != "" {
+		return reason, nil
+	}
+	if availabilityCheck == nil {
+		return "", nil
+	}
+	return availabilityCheck(ctx, b, now)
+}
+
+// maxSessionAge is how long a session can stay open before the sweep
+// closes it: RIDE_SESSION_MAX_HOURS, default 12.
+func maxSessionAge() time.Duration {
+	if v, err := strconv.ParseFloat(strings.TrimSpace(os.Getenv("RIDE_SESSION_MAX_HOURS")), 64); err == nil && v > 0 {
+		return time.Duration(v * float64(time.Hour))
+	}
+	return 12 * time.Hour
+}
+
+func isStale(s repo.RideSession, now time.Time) bool {
+	return s.EndTime.IsZero() && now.Sub(s.StartTime) > maxSessionAge()
+}
+
+// Start starts a ride: it creates the session, links the jobs the rider
+// has under way (or the ones asked for) and puts the bike on duty with
+// the rider. A stale session still holding the bike or rider is closed
+// first.
+func Start(ctx context.Context, req CreateRequest) (*repo.RideSession, *repo.Bike, error) {
+	if globalRepo == nil || bikesRepo == nil {
+		return nil, nil, errors.New("ride sessions not configured")
+	}
+	req.BikeID = strings.TrimSpace(req.BikeID)
+	req.RiderID = strings.TrimSpace(req.RiderID)
+	if req.BikeID == "" {
+		return nil, nil, errors.New("bikeId required")
+	}
+	if req.RiderID == "" {
+		return nil, nil, errors.New("riderId required")
+	}
+
+	rideMu.Lock()
+	defer rideMu.Unlock()
+	now := time.Now()
+
+	open, err := openSessions(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, s := range open {
+		if s.BikeID != req.BikeID && s.RiderID != req.RiderID {
+			continue
+		}
+		if isStale(s, now) {
+			if err := autoClose(ctx, &s, now); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		if s.BikeID == req.BikeID {
+			return nil, nil, fmt.Errorf("%w: bike is already out with %s", ErrBikeUnavailable, s.RiderID)
+		}
+		return nil, nil, fmt.Errorf("%w (%s on bike %s)", ErrRiderOnRide, s.SessionID, s.BikeID)
+	}
+
+	b, ok, err := bikesRepo.Get(ctx, req.BikeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return nil, nil, ErrBikeNotFound
+	}
+	reason, err := UnavailableReason(ctx, *b, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	if reason != "" {
+		return nil, nil, fmt.Errorf("%w: %s", ErrBikeUnavailable, reason)
+	}
+
+	if req.JobIDs, err = activeJobs(ctx, req.RiderID, req.JobIDs); err != nil {
+		return nil, nil, err
+	}
+
+	prev := *b
+	b.CurrentRiderID = req.RiderID
+	b.Status = bikeStatusOnDuty
+	b.UpdatedAt = now
+	claimed, err := bikesRepo.PutIfUnchanged(ctx, b, prev)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !claimed {
+		return nil, nil, fmt.Errorf("%w: bike was just taken by another rider", ErrBikeUnavailable)
+	}
+	s, err := Create(ctx, req)
+	if err != nil {
+		if ok, rerr := bikesRepo.PutIfUnchanged(ctx, &prev, *b); rerr != nil || !ok {
+			log.Printf("[ride-sessions] failed to release bike %s after session create failed: %v", b.ID, rerr)
+		}
+		return nil, nil, err
+	}
+	log.Printf("[ride-sessions] ride started session=%s bike=%s rider=%s jobs=%v", s.SessionID, s.BikeID, s.RiderID, s.JobIDs)
+	return s, b, nil
+}
+
+// EndRide ends the bike's open session. A bike assigned without a session
+// (from before rides kept one) is just released, with a nil session.
+func EndRide(ctx context.Context, bikeID string, req EndRequest) (*repo.RideSession, *repo.Bike, error) {
+	if globalRepo == nil || bikesRepo == nil {
+		return nil, nil, errors.New("ride sessions not configured")
+	}
+	sessions, err := globalRepo.ListByBike(ctx, bikeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var open *repo.RideSession
+	for i := range sessions {
+		if sessions[i].EndTime.IsZero() && (open == nil || sessions[i].StartTime.After(open.StartTime)) {
+			open = &sessions[i]
+		}
+	}
+
+	var s *repo.RideSession
+	if open != nil {
+		if s, err = EndSession(ctx, open.SessionID, req); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		rideMu.Lock()
+		err = releaseBike(ctx, &repo.RideSession{BikeID: bikeID}, req.EndMiles)
+		rideMu.Unlock()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	b, ok, err := bikesRepo.Get(ctx, bikeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return nil, nil, ErrBikeNotFound
+	}
+	return s, b, nil
+}
+
+// finish works out the duration and odometer distance of an ended session.
+func finish(s *repo.RideSession) {
+	s.DurationMinutes = int(math.Round(s.EndTime.Sub(s.StartTime).Minutes()))
+	s.DistanceMiles = 0
+	if s.EndMiles > s.StartMiles {
+		s.DistanceMiles = s.EndMiles - s.StartMiles
+	}
+}
+
+// releaseBike takes the rider off the bike, returns an on-duty bike to
+// Available and advances the odometer to miles. A bike since handed to
+// someone else keeps its rider. The write is conditional, so a bike that
+// changes meanwhile is read again. Callers hold rideMu.
+func releaseBike(ctx context.Context, s *repo.RideSession, miles int) error {
+	_, _, err := repo.UpdateBike(ctx, bikesRepo, s.BikeID, func(b *repo.Bike) (bool, error) {
+		return releasedBike(b, s, miles), nil
+	})
+	return err
+}
+
+// releasedBike applies a release to b and reports whether it changed.
+func releasedBike(b *repo.Bike, s *repo.RideSession, miles int) bool {
+	changed := false
+	if s.RiderID == "" || b.CurrentRiderID == s.RiderID {
+		if b.CurrentRiderID != "" {
+			b.CurrentRiderID = ""
+			changed = true
+		}
+		if b.Status == bikeStatusOnDuty {
+			b.Status = bikeStatusAvailable
+			changed = true
+		}
+	}
+	if miles > b.Mileage {
+		b.Mileage = miles
+		changed = true
+	}
+	return changed
+}
+
+// openSessions returns every session that hasn't ended.
+func openSessions(ctx context.Context) ([]repo.RideSession, error) {
+	all, err := globalRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]repo.RideSession, 0)
+	for _, s := range all {
+		if s.EndTime.IsZero() {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+// autoClose closes a stale session and releases its bike. Callers hold
+// rideMu.
+func autoClose(ctx context.Context, s *repo.RideSession, now time.Time) error {
+	s.EndTime = now
+	s.AutoClosed = true
+	finish(s)
+	if err := globalRepo.Put(ctx, s); err != nil {
+		return err
+	}
+	log.Printf("[ride-sessions] auto-closed stale session %s (bike %s, rider %s, open %dm)", s.SessionID, s.BikeID, s.RiderID, s.DurationMinutes)
+	if bikesRepo == nil {
+		return nil
+	}
+	return releaseBike(ctx, s, 0)
+}
+
+// CloseStale auto-closes every session open for longer than
+// RIDE_SESSION_MAX_HOURS and returns the ones it closed.
+func CloseStale(ctx context.Context, now time.Time) ([]repo.RideSession, error) {
+	if globalRepo == nil {
+		return nil, errors.New("ride sessions not configured")
+	}
+	rideMu.Lock()
+	defer rideMu.Unlock()
+	open, err := openSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	closed := make([]repo.RideSession, 0)
+	for _, s := range open {
+		if !isStale(s, now) {
+			continue
+		}
+		if err := autoClose(ctx, &s, now); err != nil {
+			return closed, err
+		}
+		closed = append(closed, s)
+	}
+	return closed, nil
+}
+
+// StartStaleSweeper runs CloseStale every 15 minutes for as long as ctx is
+// alive. Call once at server startup.
+func StartStaleSweeper(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(15 * time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := CloseStale(ctx, time.Now()); err != nil {
+					log.Printf("[ride-sessions] stale session sweep failed: %v", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	log.Printf("[ride-sessions] stale session sweep started (closes sessions open over %s)", maxSessionAge())
+}
+
+var activeJobStatuses = map[string]bool{"accepted": true, "picked-up": true}
+
+func jobUnderWay(j repo.Job, riderID string) bool {
+	return j.AcceptedBy == riderID && activeJobStatuses[j.Status]
+}
+
+// activeJobs returns the jobs a ride is for: the ones asked for, each of
+// which must be under way for the rider, or else every job the rider has
+// under way. Without a jobs repository the requested IDs are taken on
+// trust.
+func activeJobs(ctx context.Context, riderID string, requested []string) ([]string, error) {
+	ids := make([]string, 0, len(requested))
+	seen := make(map[string]bool)
+	for _, id := range requested {
+		if id = strings.TrimSpace(id); id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if jobsRepo == nil {
+		return ids, nil
+	}
+	if len(ids) > 0 {
+		for _, id := range ids {
+			j, ok, err := jobsRepo.Get(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			if !ok || !jobUnderWay(*j, riderID) {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidJob, id)
+			}
+		}
+		return ids, nil
+	}
+
+	jobs, err := jobsRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, j := range jobs {
+		if jobUnderWay(j, riderID) {
+			ids = append(ids, j.JobID)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// LinkJob adds a job the rider accepted mid-ride to their open session.
+// Riders without an open session are left alone.
+func LinkJob(ctx context.Context, riderID, jobID string) error {
+	if globalRepo == nil || riderID == "" || jobID == "" {
+		return nil
+	}
+	rideMu.Lock()
+	defer rideMu.Unlock()
+	open, err := openSessions(ctx)
+	if err != nil {
+		return err
+	}
+	for _, s := range open {
+		if s.RiderID != riderID {
+			continue
+		}
+		for _, id := range s.JobIDs {
+			if id == jobID {
+				return nil
+			}
+		}
+		s.JobIDs = append(s.JobIDs, jobID)
+		return globalRepo.Put(ctx, &s)
+	}
+	return nil
+}
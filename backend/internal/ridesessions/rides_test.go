@@ -0,0 +1,287 @@
+package ridesessions
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo/memory"
+)
+
+func setupRides(t *testing.T) (*memory.BikesRepo, *memory.JobsRepo) {
+	t.Helper()
+	bikes := setup(t, 0, false)
+	jobs := memory.NewJobsRepo()
+	SetJobsRepository(jobs)
+	t.Cleanup(func() { SetJobsRepository(nil) })
+	return bikes, jobs
+}
+
+// failingBikes refuses writes once fail is set.
+type failingBikes struct {
+	*memory.BikesRepo
+	fail bool
+}
+
+func (f *failingBikes) Put(ctx context.Context, b *repo.Bike) error {
+	if f.fail {
+		return errors.New("write failed")
+	}
+	return f.BikesRepo.Put(ctx, b)
+}
+
+func (f *failingBikes) PutIfUnchanged(ctx context.Context, b *repo.Bike, prev repo.Bike) (bool, error) {
+	if f.fail {
+		return false, errors.New("write failed")
+	}
+	return f.BikesRepo.PutIfUnchanged(ctx, b, prev)
+}
+
+// racingBikes assigns the bike to another rider, as another instance
+// would, just before the first conditional write.
+type racingBikes struct {
+	*memory.BikesRepo
+	raced bool
+}
+
+func (r *racingBikes) PutIfUnchanged(ctx context.Context, b *repo.Bike, prev repo.Bike) (bool, error) {
+	if !r.raced {
+		r.raced = true
+		other := prev
+		other.CurrentRiderID, other.Status, other.UpdatedAt = "r9", bikeStatusOnDuty, time.Now()
+		_ = r.BikesRepo.Put(ctx, &other)
+	}
+	return r.BikesRepo.PutIfUnchanged(ctx, b, prev)
+}
+
+// ---- start / end ----
+
+func TestStart_AssignsBikeAndLinksJobs(t *testing.T) {
+	bikes, jobs := setupRides(t)
+	ctx := context.Background()
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b1", Status: bikeStatusAvailable, Mileage: 1200, Depot: "galway"})
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b2", Status: bikeStatusAvailable})
+	_ = jobs.Put(ctx, &repo.Job{JobID: "j2", Status: "picked-up", AcceptedBy: "r1"})
+	_ = jobs.Put(ctx, &repo.Job{JobID: "j1", Status: "accepted", AcceptedBy: "r1"})
+	_ = jobs.Put(ctx, &repo.Job{JobID: "j3", Status: "delivered", AcceptedBy: "r1"})
+	_ = jobs.Put(ctx, &repo.Job{JobID: "j4", Status: "accepted", AcceptedBy: "r2"})
+
+	s, b, err := Start(ctx, CreateRequest{BikeID: "b1", RiderID: "r1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.JobIDs) != 2 || s.JobIDs[0] != "j1" || s.JobIDs[1] != "j2" {
+		t.Errorf("expected the rider's jobs under way, got %v", s.JobIDs)
+	}
+	if s.StartMiles != 1200 || s.Depot != "galway" {
+		t.Errorf("expected defaults from the bike, got %+v", s)
+	}
+	if b.Status != bikeStatusOnDuty || b.CurrentRiderID != "r1" {
+		t.Errorf("expected bike on duty with r1, got %+v", b)
+	}
+
+	if _, _, err := Start(ctx, CreateRequest{BikeID: "b1", RiderID: "r2"}); !errors.Is(err, ErrBikeUnavailable) {
+		t.Errorf("expected taken bike to be refused, got %v", err)
+	}
+	if _, _, err := Start(ctx, CreateRequest{BikeID: "b2", RiderID: "r1"}); !errors.Is(err, ErrRiderOnRide) {
+		t.Errorf("expected second ride for r1 to be refused, got %v", err)
+	}
+	if _, _, err := Start(ctx, CreateRequest{BikeID: "nope", RiderID: "r2"}); !errors.Is(err, ErrBikeNotFound) {
+		t.Errorf("expected unknown bike to be refused, got %v", err)
+	}
+	if _, _, err := Start(ctx, CreateRequest{BikeID: "b2", RiderID: "r2", JobIDs: []string{"j1"}}); !errors.Is(err, ErrInvalidJob) {
+		t.Errorf("expected another rider's job to be refused, got %v", err)
+	}
+	s2, _, err := Start(ctx, CreateRequest{BikeID: "b2", RiderID: "r2", JobIDs: []string{"j4", " j4 "}})
+	if err != nil || len(s2.JobIDs) != 1 {
+		t.Errorf("expected j4 linked once, got %+v %v", s2, err)
+	}
+}
+
+func TestEndSession_ReleasesBike(t *testing.T) {
+	bikes, _ := setupRides(t)
+	ctx := context.Background()
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b1", Status: bikeStatusAvailable, Mileage: 100})
+
+	s, _, err := Start(ctx, CreateRequest{BikeID: "b1", RiderID: "r1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Back-date the start so the duration is measurable.
+	s.StartTime = s.StartTime.Add(-90 * time.Minute)
+	_ = globalRepo.Put(ctx, s)
+
+	ended, err := EndSession(ctx, s.SessionID, EndRequest{EndMiles: 142, EndFuel: fuel(30), Notes: " ok "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ended.DurationMinutes != 90 || ended.DistanceMiles != 42 || *ended.EndFuel != 30 || ended.Notes != "ok" {
+		t.Errorf("unexpected ended session %+v", ended)
+	}
+	b, _, _ := bikes.Get(ctx, "b1")
+	if b.Status != bikeStatusAvailable || b.CurrentRiderID != "" || b.Mileage != 142 {
+		t.Errorf("expected bike released at 142, got %+v", b)
+	}
+	if _, err := EndSession(ctx, s.SessionID, EndRequest{EndMiles: 150}); !errors.Is(err, ErrAlreadyEnded) {
+		t.Errorf("expected second end to be refused, got %v", err)
+	}
+}
+
+func TestEndRide_ByBike(t *testing.T) {
+	bikes, _ := setupRides(t)
+	ctx := context.Background()
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b1", Status: bikeStatusAvailable})
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b2", Status: bikeStatusOnDuty, CurrentRiderID: "r9"})
+
+	_, _, _ = Start(ctx, CreateRequest{BikeID: "b1", RiderID: "r1"})
+	s, b, err := EndRide(ctx, "b1", EndRequest{})
+	if err != nil || s == nil || s.EndTime.IsZero() || b.CurrentRiderID != "" {
+		t.Errorf("expected b1's session ended and bike released, got %+v %+v %v", s, b, err)
+	}
+
+	// Bikes assigned before rides kept sessions are just released.
+	s, b, err = EndRide(ctx, "b2", EndRequest{})
+	if err != nil || s != nil || b.CurrentRiderID != "" || b.Status != bikeStatusAvailable {
+		t.Errorf("expected b2 released without a session, got %+v %+v %v", s, b, err)
+	}
+}
+
+func TestStart_RollsBackWhenBikeWriteFails(t *testing.T) {
+	setupRides(t)
+	ctx := context.Background()
+	bikes := &failingBikes{BikesRepo: memory.NewBikesRepo()}
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b1", Status: bikeStatusAvailable})
+	SetBikesRepository(bikes)
+
+	bikes.fail = true
+	if _, _, err := Start(ctx, CreateRequest{BikeID: "b1", RiderID: "r1"}); err == nil {
+		t.Fatal("expected failed bike write to fail the start")
+	}
+	if all, _ := List(ctx); len(all) != 0 {
+		t.Errorf("expected the session to be rolled back, got %+v", all)
+	}
+
+	bikes.fail = false
+	s, _, err := Start(ctx, CreateRequest{BikeID: "b1", RiderID: "r1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bikes.fail = true
+	if _, err := EndSession(ctx, s.SessionID, EndRequest{}); err == nil {
+		t.Fatal("expected failed bike write to fail the end")
+	}
+	if got, _, _ := Get(ctx, s.SessionID); !got.EndTime.IsZero() {
+		t.Error("expected the session to be reopened")
+	}
+}
+
+func TestStart_LosesRaceForBike(t *testing.T) {
+	setupRides(t)
+	ctx := context.Background()
+	bikes := &racingBikes{BikesRepo: memory.NewBikesRepo()}
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b1", Status: bikeStatusAvailable})
+	SetBikesRepository(bikes)
+
+	if _, _, err := Start(ctx, CreateRequest{BikeID: "b1", RiderID: "r1"}); !errors.Is(err, ErrBikeUnavailable) {
+		t.Fatalf("expected ErrBikeUnavailable, got %v", err)
+	}
+	if all, _ := List(ctx); len(all) != 0 {
+		t.Errorf("expected no session for the losing rider, got %+v", all)
+	}
+	if b, _, _ := bikes.Get(ctx, "b1"); b.CurrentRiderID != "r9" {
+		t.Errorf("expected the winner to keep the bike, got %q", b.CurrentRiderID)
+	}
+}
+
+func TestStart_RunsAvailabilityCheck(t *testing.T) {
+	bikes, _ := setupRides(t)
+	ctx := context.Background()
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b1", Status: bikeStatusAvailable})
+	SetAvailabilityCheck(func(_ context.Context, b repo.Bike, _ time.Time) (string, error) {
+		if b.ID == "b1" {
+			return "Insurance expired 2026-10-01", nil
+		}
+		return "", nil
+	})
+	t.Cleanup(func() { SetAvailabilityCheck(nil) })
+
+	if _, _, err := Start(ctx, CreateRequest{BikeID: "b1", RiderID: "r1"}); !errors.Is(err, ErrBikeUnavailable) {
+		t.Fatalf("expected ErrBikeUnavailable, got %v", err)
+	}
+	if b, _, _ := bikes.Get(ctx, "b1"); b.CurrentRiderID != "" || b.Status != bikeStatusAvailable {
+		t.Errorf("expected the bike left alone, got %+v", b)
+	}
+	if all, _ := List(ctx); len(all) != 0 {
+		t.Errorf("expected no session, got %+v", all)
+	}
+}
+
+// ---- jobs accepted mid-ride ----
+
+func TestLinkJob(t *testing.T) {
+	bikes, _ := setupRides(t)
+	ctx := context.Background()
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b1", Status: bikeStatusAvailable})
+	s, _, _ := Start(ctx, CreateRequest{BikeID: "b1", RiderID: "r1"})
+
+	for i := 0; i < 2; i++ {
+		if err := LinkJob(ctx, "r1", "j9"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = LinkJob(ctx, "r2", "j8")
+	got, _, _ := Get(ctx, s.SessionID)
+	if len(got.JobIDs) != 1 || got.JobIDs[0] != "j9" {
+		t.Errorf("expected j9 linked once, got %v", got.JobIDs)
+	}
+}
+
+// ---- stale sessions ----
+
+func TestCloseStale(t *testing.T) {
+	bikes, _ := setupRides(t)
+	t.Setenv("RIDE_SESSION_MAX_HOURS", "6")
+	ctx := context.Background()
+	now := time.Now()
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b1", Status: bikeStatusOnDuty, CurrentRiderID: "r1"})
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b2", Status: bikeStatusOnDuty, CurrentRiderID: "r2"})
+	_ = globalRepo.Put(ctx, &repo.RideSession{SessionID: "old", BikeID: "b1", RiderID: "r1", StartTime: now.Add(-7 * time.Hour)})
+	_ = globalRepo.Put(ctx, &repo.RideSession{SessionID: "new", BikeID: "b2", RiderID: "r2", StartTime: now.Add(-5 * time.Hour)})
+
+	closed, err := CloseStale(ctx, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(closed) != 1 || closed[0].SessionID != "old" || !closed[0].AutoClosed || closed[0].DurationMinutes != 420 {
+		t.Fatalf("expected only the 7 hour session auto-closed, got %+v", closed)
+	}
+	b, _, _ := bikes.Get(ctx, "b1")
+	if b.CurrentRiderID != "" || b.Status != bikeStatusAvailable {
+		t.Errorf("expected b1 released, got %+v", b)
+	}
+	if b, _, _ := bikes.Get(ctx, "b2"); b.CurrentRiderID != "r2" {
+		t.Errorf("expected b2 still out, got %+v", b)
+	}
+}
+
+func TestStart_ClosesStaleSessionOnBike(t *testing.T) {
+	bikes, _ := setupRides(t)
+	ctx := context.Background()
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b1", Status: bikeStatusOnDuty, CurrentRiderID: "r1"})
+	_ = globalRepo.Put(ctx, &repo.RideSession{SessionID: "old", BikeID: "b1", RiderID: "r1", StartTime: time.Now().Add(-13 * time.Hour)})
+
+	s, b, err := Start(ctx, CreateRequest{BikeID: "b1", RiderID: "r2"})
+	if err != nil {
+		t.Fatalf("expected stale session not to block the bike, got %v", err)
+	}
+	if b.CurrentRiderID != "r2" || s.RiderID != "r2" {
+		t.Errorf("expected r2 on b1, got %+v", b)
+	}
+	if old, _, _ := Get(ctx, "old"); !old.AutoClosed || old.EndTime.IsZero() {
+		t.Errorf("expected the stale session auto-closed, got %+v", old)
+	}
+}
+
+func fuel(n int) *int { return &n }
@@ -5,8 +5,11 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
+	"log"
+	"strings"
 	"time"
 
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/depots"
 	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
 )
 
@@ -37,6 +40,8 @@ func ListByBike(ctx context.Context, bikeID string) ([]repo.RideSession, error)
 	return globalRepo.ListByBike(ctx, bikeID)
 }
 
+// Create records a session without touching the bike. Start is the ride
+// service built on it that also assigns the bike and links jobs.
 func Create(ctx context.Context, req CreateRequest) (*repo.RideSession, error) {
 	if req.BikeID == "" {
 		return nil, errors.New("bikeId required")
@@ -45,13 +50,38 @@ func Create(ctx context.Context, req CreateRequest) (*repo.RideSession, error) {
 		return nil, errors.New("riderId required")
 	}
 
+	// A missing start reading defaults to the bike's last known odometer.
+	startMiles := req.StartMiles
+	if startMiles == 0 {
+		miles, err := lastKnownMiles(ctx, req.BikeID)
+		if err != nil {
+			return nil, err
+		}
+		startMiles = miles
+	} else if err := CheckStartMiles(ctx, req.BikeID, startMiles); err != nil {
+		return nil, err
+	}
+
+	// A missing depot defaults to the bike's.
+	depot, err := depots.Resolve(ctx, req.Depot)
+	if err != nil {
+		return nil, err
+	}
+	if depot == "" {
+		depot = bikeDepot(ctx, req.BikeID)
+	}
+
 	s := &repo.RideSession{
 		SessionID:  newID(),
 		BikeID:     req.BikeID,
 		RiderID:    req.RiderID,
-		Depot:      req.Depot,
+		Depot:      depot,
 		StartTime:  time.Now(),
-		StartMiles: req.StartMiles,
+		StartMiles: startMiles,
+		StartFuel:  req.StartFuel,
+		Checklist:  req.Checklist,
+		IssueIDs:   req.IssueIDs,
+		JobIDs:     req.JobIDs,
 	}
 	if globalRepo != nil {
 		if err := globalRepo.Put(ctx, s); err != nil {
@@ -61,7 +91,23 @@ func Create(ctx context.Context, req CreateRequest) (*repo.RideSession, error) {
 	return s, nil
 }
 
+// bikeDepot is the bike's depot, or "" when it isn't known.
+func bikeDepot(ctx context.Context, bikeID string) string {
+	if bikesRepo == nil {
+		return ""
+	}
+	b, ok, err := bikesRepo.Get(ctx, bikeID)
+	if err != nil || !ok {
+		return ""
+	}
+	return b.Depot
+}
+
+// EndSession closes the session, works out its duration and distance and
+// releases the bike. If the bike can't be updated the session is reopened.
 func EndSession(ctx context.Context, id string, req EndRequest) (*repo.RideSession, error) {
+	rideMu.Lock()
+	defer rideMu.Unlock()
 	s, ok, err := Get(ctx, id)
 	if err != nil {
 		return nil, err
@@ -69,21 +115,85 @@ func EndSession(ctx context.Context, id string, req EndRequest) (*repo.RideSessi
 	if !ok {
 		return nil, errors.New("not found")
 	}
+	if !s.EndTime.IsZero() {
+		return nil, ErrAlreadyEnded
+	}
+	// An end reading of 0 means none was reported.
+	if req.EndMiles > 0 {
+		if err := CheckEndMiles(s, req.EndMiles); err != nil {
+			return nil, err
+		}
+	}
+	before := *s
 	s.EndTime = time.Now()
 	s.EndMiles = req.EndMiles
-	if globalRepo != nil {
-		if err := globalRepo.Put(ctx, s); err != nil {
+	if req.EndFuel != nil {
+		s.EndFuel = req.EndFuel
+	}
+	if notes := strings.TrimSpace(req.Notes); notes != "" {
+		s.Notes = notes
+	}
+	finish(s)
+	if req.EndMiles > 0 {
+		ReconcileMileage(s)
+	}
+	if err := globalRepo.Put(ctx, s); err != nil {
+		return nil, err
+	}
+	if bikesRepo != nil {
+		if err := releaseBike(ctx, s, req.EndMiles); err != nil {
+			if rerr := globalRepo.Put(ctx, &before); rerr != nil {
+				log.Printf("[ride-sessions] failed to reopen session %s: %v", s.SessionID, rerr)
+			}
 			return nil, err
 		}
 	}
 	return s, nil
 }
 
+// ReviewMileage marks a flagged session's mileage as reviewed.
+func ReviewMileage(ctx context.Context, id, reviewer string) (*repo.RideSession, error) {
+	s, ok, err := Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	if !s.MileageFlagged {
+		return nil, errNotFlagged
+	}
+	s.MileageReviewedBy = reviewer
+	s.MileageReviewedAt = time.Now()
+	if err := globalRepo.Put(ctx, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+var errNotFlagged = errors.New("ride session mileage is not flagged")
+
+// Delete removes a session. Deleting an open session frees its bike.
 func Delete(ctx context.Context, id string) (bool, error) {
 	if globalRepo == nil {
 		return false, errors.New("ride sessions not configured")
 	}
-	return globalRepo.Delete(ctx, id)
+	rideMu.Lock()
+	defer rideMu.Unlock()
+	s, ok, err := globalRepo.Get(ctx, id)
+	if err != nil || !ok {
+		return false, err
+	}
+	deleted, err := globalRepo.Delete(ctx, id)
+	if err != nil || !deleted {
+		return deleted, err
+	}
+	if s.EndTime.IsZero() && bikesRepo != nil {
+		if err := releaseBike(ctx, s, 0); err != nil {
+			log.Printf("[ride-sessions] failed to release bike %s: %v", s.BikeID, err)
+		}
+	}
+	return true, nil
 }
 
 func newID() string {
@@ -95,12 +205,20 @@ func newID() string {
 // Request types
 
 type CreateRequest struct {
-	BikeID     string `json:"bikeId"`
-	RiderID    string `json:"riderId"`
-	Depot      string `json:"depot"`
-	StartMiles int    `json:"startMiles"`
+	BikeID     string   `json:"bikeId"`
+	RiderID    string   `json:"riderId"`
+	Depot      string   `json:"depot"`
+	StartMiles int      `json:"startMiles"`
+	StartFuel  *int     `json:"startFuel,omitempty"`
+	JobIDs     []string `json:"jobIds,omitempty"`
+
+	// Set by the fleet checkout from its pre-ride inspection.
+	Checklist []repo.InspectionItem `json:"-"`
+	IssueIDs  []string              `json:"-"`
 }
 
 type EndRequest struct {
-	EndMiles int `json:"endMiles"`
+	EndMiles int    `json:"endMiles"`
+	EndFuel  *int   `json:"endFuel,omitempty"`
+	Notes    string `json:"notes,omitempty"`
 }
@@ -0,0 +1,34 @@
+package ridesessions
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/depots"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo/memory"
+)
+
+func TestCreate_ValidatesDepot(t *testing.T) {
+	bikes := setup(t, 0, false)
+	ctx := context.Background()
+	depots.SetRepository(memory.NewDepotsRepo())
+	t.Cleanup(func() { depots.SetRepository(nil) })
+	if _, err := depots.Create(ctx, depots.Request{Name: "Galway"}); err != nil {
+		t.Fatal(err)
+	}
+	_ = bikes.Put(ctx, &repo.Bike{ID: "b1", Depot: "galway"})
+
+	if _, err := Create(ctx, CreateRequest{BikeID: "b1", RiderID: "r1", Depot: "Sligo"}); !errors.Is(err, depots.ErrUnknownDepot) {
+		t.Errorf("expected unknown depot to be rejected, got %v", err)
+	}
+	s, err := Create(ctx, CreateRequest{BikeID: "b1", RiderID: "r1", Depot: "GALWAY"})
+	if err != nil || s.Depot != "galway" {
+		t.Errorf("expected depot name resolved to its ID, got %+v %v", s, err)
+	}
+	s, err = Create(ctx, CreateRequest{BikeID: "b1", RiderID: "r1"})
+	if err != nil || s.Depot != "galway" {
+		t.Errorf("expected depot to default to the bike's, got %+v %v", s, err)
+	}
+}
@@ -0,0 +1,331 @@
+package scheduling
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/orgtime"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+)
+
+// Riders publish when they'll be available ahead of time in an
+// availability calendar: one-off slots and weekly patterns. Windows expands
+// a calendar over a period by merging its patterns and available slots and
+// then cutting out the slots marked unavailable. The status a rider toggles
+// in the app still wins while it lasts; the calendar covers the rest.
+
+// maxSlotLength bounds a single availability slot.
+const maxSlotLength = 14 * 24 * time.Hour
+
+var (
+	errSlotNotFound    = errors.New("slot not found")
+	errPatternNotFound = errors.New("weekly pattern not found")
+)
+
+var availabilityRepo repo.AvailabilityRepository
+
+func SetAvailabilityRepository(r repo.AvailabilityRepository) {
+	availabilityRepo = r
+}
+
+// Window is a period a rider is available, [Start, End).
+type Window struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+type SlotRequest struct {
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Unavailable bool      `json:"unavailable,omitempty"`
+	Note        string    `json:"note,omitempty"`
+}
+
+type PatternRequest struct {
+	Weekday   int    `json:"weekday"`
+	StartTime string `json:"startTime"`
+	EndTime   string `json:"endTime"`
+	From      string `json:"from,omitempty"`
+	Until     string `json:"until,omitempty"`
+}
+
+// Calendar returns the rider's calendar, empty if they haven't published
+// one.
+func Calendar(ctx context.Context, riderID string) (*repo.RiderAvailability, error) {
+	if availabilityRepo == nil {
+		return nil, errors.New("availability not configured")
+	}
+	a, ok, err := availabilityRepo.Get(ctx, riderID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return &repo.RiderAvailability{RiderID: riderID}, nil
+	}
+	return a, nil
+}
+
+// AddSlot adds a one-off slot to the rider's calendar, dropping slots that
+// ended before now.
+func AddSlot(ctx context.Context, riderID string, req SlotRequest, now time.Time) (*repo.AvailabilitySlot, error) {
+	if req.Start.IsZero() || req.End.IsZero() {
+		return nil, invalid("start and end required")
+	}
+	if !req.End.After(req.Start) {
+		return nil, invalid("end must be after start")
+	}
+	if req.End.Sub(req.Start) > maxSlotLength {
+		return nil, invalid("a slot can't be longer than 14 days")
+	}
+	if !req.End.After(now) {
+		return nil, invalid("end must be in the future")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+
+	a, err := Calendar(ctx, riderID)
+	if err != nil {
+		return nil, err
+	}
+	slot := repo.AvailabilitySlot{
+		SlotID:      newID("avs_"),
+		Start:       req.Start.UTC(),
+		End:         req.End.UTC(),
+		Unavailable: req.Unavailable,
+		Note:        strings.TrimSpace(req.Note),
+	}
+	slots := []repo.AvailabilitySlot{slot}
+	for _, s := range a.Slots {
+		if s.End.After(now) {
+			slots = append(slots, s)
+		}
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i].Start.Before(slots[j].Start) })
+	a.Slots = slots
+	if err := putCalendar(ctx, a); err != nil {
+		return nil, err
+	}
+	return &slot, nil
+}
+
+func DeleteSlot(ctx context.Context, riderID, slotID string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	a, err := Calendar(ctx, riderID)
+	if err != nil {
+		return err
+	}
+	slots := make([]repo.AvailabilitySlot, 0, len(a.Slots))
+	for _, s := range a.Slots {
+		if s.SlotID != slotID {
+			slots = append(slots, s)
+		}
+	}
+	if len(slots) == len(a.Slots) {
+		return errSlotNotFound
+	}
+	a.Slots = slots
+	return putCalendar(ctx, a)
+}
+
+// AddPattern adds a weekly pattern to the rider's calendar.
+func AddPattern(ctx context.Context, riderID string, req PatternRequest) (*repo.WeeklyAvailability, error) {
+	if req.Weekday < 0 || req.Weekday > 6 {
+		return nil, invalid("weekday must be between 0 (Sunday) and 6 (Saturday)")
+	}
+	if _, err := orgtime.ParseClock(req.StartTime); err != nil {
+		return nil, invalid("startTime must be a time (HH:MM)")
+	}
+	if _, err := orgtime.ParseClock(req.EndTime); err != nil {
+		return nil, invalid("endTime must be a time (HH:MM)")
+	}
+	for _, d := range []string{req.From, req.Until} {
+		if _, err := parseDay(d); err != nil {
+			return nil, invalid("from and until must be dates (YYYY-MM-DD)")
+		}
+	}
+	if req.From != "" && req.Until != "" && req.Until < req.From {
+		return nil, invalid("until must not be before from")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+
+	a, err := Calendar(ctx, riderID)
+	if err != nil {
+		return nil, err
+	}
+	p := repo.WeeklyAvailability{
+		PatternID: newID("avw_"),
+		Weekday:   req.Weekday,
+		StartTime: strings.TrimSpace(req.StartTime),
+		EndTime:   strings.TrimSpace(req.EndTime),
+		From:      req.From,
+		Until:     req.Until,
+	}
+	a.Weekly = append(append([]repo.WeeklyAvailability(nil), a.Weekly...), p)
+	if err := putCalendar(ctx, a); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func DeletePattern(ctx context.Context, riderID, patternID string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	a, err := Calendar(ctx, riderID)
+	if err != nil {
+		return err
+	}
+	weekly := make([]repo.WeeklyAvailability, 0, len(a.Weekly))
+	for _, p := range a.Weekly {
+		if p.PatternID != patternID {
+			weekly = append(weekly, p)
+		}
+	}
+	if len(weekly) == len(a.Weekly) {
+		return errPatternNotFound
+	}
+	a.Weekly = weekly
+	return putCalendar(ctx, a)
+}
+
+func putCalendar(ctx context.Context, a *repo.RiderAvailability) error {
+	a.UpdatedAt = time.Now()
+	return availabilityRepo.Put(ctx, a)
+}
+
+// Windows returns the periods within [from, to) the calendar has the rider
+// available, in order and merged where they touch.
+func Windows(a repo.RiderAvailability, from, to time.Time) []Window {
+	var avail []Window
+	loc := orgtime.Location()
+	first := from.In(loc).AddDate(0, 0, -1) // an overnight pattern from the day before
+	for d := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, loc); d.Before(to); d = d.AddDate(0, 0, 1) {
+		for _, p := range a.Weekly {
+			if w, ok := patternOn(p, d); ok {
+				avail = append(avail, w)
+			}
+		}
+	}
+	for _, s := range a.Slots {
+		if !s.Unavailable {
+			avail = append(avail, Window{Start: s.Start, End: s.End})
+		}
+	}
+	out := clip(merge(avail), from, to)
+	for _, s := range a.Slots {
+		if s.Unavailable {
+			out = subtract(out, Window{Start: s.Start, End: s.End})
+		}
+	}
+	return out
+}
+
+// patternOn returns the window the pattern gives on day d (midnight in the
+// org zone), if it applies that day.
+func patternOn(p repo.WeeklyAvailability, d time.Time) (Window, bool) {
+	if int(d.Weekday()) != p.Weekday {
+		return Window{}, false
+	}
+	day := d.Format("2006-01-02")
+	if (p.From != "" && day < p.From) || (p.Until != "" && day > p.Until) {
+		return Window{}, false
+	}
+	startClock, err := orgtime.ParseClock(p.StartTime)
+	if err != nil {
+		return Window{}, false
+	}
+	endClock, err := orgtime.ParseClock(p.EndTime)
+	if err != nil {
+		return Window{}, false
+	}
+	start := orgtime.At(d.Year(), d.Month(), d.Day(), startClock)
+	end := orgtime.At(d.Year(), d.Month(), d.Day(), endClock)
+	if !end.After(start) {
+		end = orgtime.At(d.Year(), d.Month(), d.Day()+1, endClock)
+	}
+	return Window{Start: start, End: end}, true
+}
+
+// merge sorts windows and joins any that overlap or touch.
+func merge(ws []Window) []Window {
+	sort.Slice(ws, func(i, j int) bool { return ws[i].Start.Before(ws[j].Start) })
+	var out []Window
+	for _, w := range ws {
+		if n := len(out); n > 0 && !w.Start.After(out[n-1].End) {
+			if w.End.After(out[n-1].End) {
+				out[n-1].End = w.End
+			}
+			continue
+		}
+		out = append(out, w)
+	}
+	return out
+}
+
+func clip(ws []Window, from, to time.Time) []Window {
+	var out []Window
+	for _, w := range ws {
+		if w.Start.Before(from) {
+			w.Start = from
+		}
+		if w.End.After(to) {
+			w.End = to
+		}
+		if w.End.After(w.Start) {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// subtract cuts cut out of the windows.
+func subtract(ws []Window, cut Window) []Window {
+	var out []Window
+	for _, w := range ws {
+		if !cut.Start.Before(w.End) || !w.Start.Before(cut.End) {
+			out = append(out, w)
+			continue
+		}
+		if w.Start.Before(cut.Start) {
+			out = append(out, Window{Start: w.Start, End: cut.Start})
+		}
+		if cut.End.Before(w.End) {
+			out = append(out, Window{Start: cut.End, End: w.End})
+		}
+	}
+	return out
+}
+
+// CalendarStatus returns, for every rider their calendar has available at
+// now, when that availability runs out (looking at most a week ahead).
+func CalendarStatus(ctx context.Context, now time.Time) (map[string]time.Time, error) {
+	if availabilityRepo == nil {
+		return nil, nil
+	}
+	all, err := availabilityRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]time.Time)
+	for _, a := range all {
+		ws := Windows(a, now, now.AddDate(0, 0, 7))
+		if len(ws) > 0 && !ws[0].Start.After(now) {
+			out[a.RiderID] = ws[0].End
+		}
+	}
+	return out, nil
+}
+
+// parseDay checks an optional "2006-01-02" date.
+func parseDay(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
@@ -0,0 +1,154 @@
+package scheduling
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/auth"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+)
+
+// defaultForecastDays is how far ahead the forecast looks when no range is
+// given.
+const defaultForecastDays = 7
+
+// CalendarView is a rider's calendar with the windows it gives them over
+// the requested range.
+type CalendarView struct {
+	repo.RiderAvailability
+	Windows []Window `json:"windows"`
+}
+
+// Availability handles /api/availability/...:
+//
+//	GET /api/availability/me                       the caller's calendar and windows (?from=&to=)
+//	POST /api/availability/me/slots                adds a one-off slot
+//	DELETE /api/availability/me/slots/{slotId}
+//	POST /api/availability/me/weekly               adds a weekly pattern
+//	DELETE /api/availability/me/weekly/{patternId}
+//	GET /api/availability/forecast                 riders available per hour (?from=&days=&region=, coordinators)
+//	GET /api/availability/{riderId}                a rider's calendar and windows (coordinators)
+func Availability(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/availability/"), "/"), "/")
+	id := parts[0]
+	if id == "" {
+		http.NotFound(w, r)
+		return
+	}
+	username := auth.UsernameFromContext(r.Context())
+
+	switch {
+	case id == "me" && username == "":
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+
+	case len(parts) == 1 && id == "forecast" && r.Method == http.MethodGet:
+		if !isCoordinator(r) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		v := r.URL.Query()
+		from, err := parseTime(v.Get("from"))
+		if err != nil {
+			http.Error(w, "from must be a date (YYYY-MM-DD) or RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+		if from.IsZero() {
+			from = time.Now()
+		}
+		days := defaultForecastDays
+		if s := v.Get("days"); s != "" {
+			if days, err = strconv.Atoi(s); err != nil {
+				http.Error(w, "days must be a number", http.StatusBadRequest)
+				return
+			}
+		}
+		hours, err := Forecast(r.Context(), from, days*24, strings.TrimSpace(v.Get("region")))
+		if err != nil {
+			writeAvailabilityError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, hours)
+
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		rider := id
+		if id == "me" {
+			rider = username
+		} else if !isCoordinator(r) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		q, err := parseQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		a, err := Calendar(r.Context(), rider)
+		if err != nil {
+			writeAvailabilityError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, CalendarView{RiderAvailability: *a, Windows: nonNil(Windows(*a, q.From, q.To))})
+
+	case id == "me" && len(parts) == 2 && parts[1] == "slots" && r.Method == http.MethodPost:
+		var req SlotRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		slot, err := AddSlot(r.Context(), username, req, time.Now())
+		if err != nil {
+			writeAvailabilityError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, slot)
+
+	case id == "me" && len(parts) == 3 && parts[1] == "slots" && r.Method == http.MethodDelete:
+		if err := DeleteSlot(r.Context(), username, parts[2]); err != nil {
+			writeAvailabilityError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case id == "me" && len(parts) == 2 && parts[1] == "weekly" && r.Method == http.MethodPost:
+		var req PatternRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		p, err := AddPattern(r.Context(), username, req)
+		if err != nil {
+			writeAvailabilityError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, p)
+
+	case id == "me" && len(parts) == 3 && parts[1] == "weekly" && r.Method == http.MethodDelete:
+		if err := DeletePattern(r.Context(), username, parts[2]); err != nil {
+			writeAvailabilityError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeAvailabilityError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errSlotNotFound) || errors.Is(err, errPatternNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	writeError(w, "availability", err)
+}
+
+func nonNil(ws []Window) []Window {
+	if ws == nil {
+		return []Window{}
+	}
+	return ws
+}
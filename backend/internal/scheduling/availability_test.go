@@ -0,0 +1,216 @@
+package scheduling
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo/memory"
+)
+
+func setupAvailability(t *testing.T) {
+	t.Helper()
+	SetAvailabilityRepository(memory.NewAvailabilityRepo())
+	t.Cleanup(func() { SetAvailabilityRepository(nil) })
+}
+
+func utc(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// ---- calendar ----
+
+func TestWindows(t *testing.T) {
+	// 2027-01-04 is a Monday; Dublin is on UTC in winter.
+	a := repo.RiderAvailability{
+		RiderID: "alice",
+		Weekly: []repo.WeeklyAvailability{
+			{PatternID: "p1", Weekday: 1, StartTime: "18:00", EndTime: "02:00"},
+			{PatternID: "p2", Weekday: 2, StartTime: "01:00", EndTime: "06:00"},
+		},
+		Slots: []repo.AvailabilitySlot{
+			{SlotID: "s1", Start: utc("2027-01-04T12:00:00Z"), End: utc("2027-01-04T18:30:00Z")},
+			{SlotID: "s2", Start: utc("2027-01-04T22:00:00Z"), End: utc("2027-01-04T23:00:00Z"), Unavailable: true},
+		},
+	}
+	got := Windows(a, utc("2027-01-04T00:00:00Z"), utc("2027-01-06T00:00:00Z"))
+	want := []Window{
+		{utc("2027-01-04T12:00:00Z"), utc("2027-01-04T22:00:00Z")},
+		{utc("2027-01-04T23:00:00Z"), utc("2027-01-05T06:00:00Z")},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if !got[i].Start.Equal(want[i].Start) || !got[i].End.Equal(want[i].End) {
+			t.Errorf("window %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestWindows_PatternDatesAndSummerTime(t *testing.T) {
+	a := repo.RiderAvailability{Weekly: []repo.WeeklyAvailability{
+		{Weekday: 1, StartTime: "09:00", EndTime: "17:00", From: "2027-06-14", Until: "2027-06-21"},
+	}}
+	got := Windows(a, utc("2027-06-01T00:00:00Z"), utc("2027-07-01T00:00:00Z"))
+	if len(got) != 2 {
+		t.Fatalf("expected the two Mondays from 14 to 21 June, got %v", got)
+	}
+	// 09:00 Irish summer time is 08:00 UTC.
+	if !got[0].Start.Equal(utc("2027-06-14T08:00:00Z")) || !got[1].End.Equal(utc("2027-06-21T16:00:00Z")) {
+		t.Errorf("unexpected windows %v", got)
+	}
+}
+
+func TestSlotsAndPatterns(t *testing.T) {
+	setupAvailability(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	if _, err := AddSlot(ctx, "alice", SlotRequest{Start: now.Add(2 * time.Hour), End: now.Add(time.Hour)}, now); err == nil {
+		t.Error("expected end before start to be rejected")
+	}
+	if _, err := AddSlot(ctx, "alice", SlotRequest{Start: now.Add(-3 * time.Hour), End: now.Add(-time.Hour)}, now); err == nil {
+		t.Error("expected a past slot to be rejected")
+	}
+	if _, err := AddSlot(ctx, "alice", SlotRequest{Start: now, End: now.AddDate(0, 0, 15)}, now); err == nil {
+		t.Error("expected a 15-day slot to be rejected")
+	}
+	if _, err := AddPattern(ctx, "alice", PatternRequest{Weekday: 7, StartTime: "09:00", EndTime: "17:00"}); err == nil {
+		t.Error("expected weekday 7 to be rejected")
+	}
+	if _, err := AddPattern(ctx, "alice", PatternRequest{Weekday: 1, StartTime: "9am", EndTime: "17:00"}); err == nil {
+		t.Error("expected a bad clock time to be rejected")
+	}
+
+	slot, err := AddSlot(ctx, "alice", SlotRequest{Start: now.Add(time.Hour), End: now.Add(3 * time.Hour), Note: " evening "}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := AddPattern(ctx, "alice", PatternRequest{Weekday: 6, StartTime: "10:00", EndTime: "16:00"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := Calendar(ctx, "alice")
+	if len(a.Slots) != 1 || a.Slots[0].Note != "evening" || len(a.Weekly) != 1 {
+		t.Fatalf("unexpected calendar %+v", a)
+	}
+
+	// Slots that have ended are dropped when another is added.
+	later := now.Add(4 * time.Hour)
+	if _, err := AddSlot(ctx, "alice", SlotRequest{Start: later, End: later.Add(time.Hour)}, later); err != nil {
+		t.Fatal(err)
+	}
+	a, _ = Calendar(ctx, "alice")
+	if len(a.Slots) != 1 || a.Slots[0].SlotID == slot.SlotID {
+		t.Errorf("expected the ended slot to be pruned, got %+v", a.Slots)
+	}
+
+	if err := DeletePattern(ctx, "alice", p.PatternID); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeletePattern(ctx, "alice", p.PatternID); !errors.Is(err, errPatternNotFound) {
+		t.Errorf("expected errPatternNotFound, got %v", err)
+	}
+	if err := DeleteSlot(ctx, "alice", "nope"); !errors.Is(err, errSlotNotFound) {
+		t.Errorf("expected errSlotNotFound, got %v", err)
+	}
+}
+
+func TestCalendarStatus(t *testing.T) {
+	setupAvailability(t)
+	ctx := context.Background()
+	now := time.Now()
+	if _, err := AddSlot(ctx, "alice", SlotRequest{Start: now.Add(-time.Hour), End: now.Add(2 * time.Hour)}, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AddSlot(ctx, "bob", SlotRequest{Start: now.Add(time.Hour), End: now.Add(2 * time.Hour)}, now); err != nil {
+		t.Fatal(err)
+	}
+	got, err := CalendarStatus(ctx, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if until, ok := got["alice"]; !ok || !until.Equal(now.Add(2*time.Hour).UTC()) {
+		t.Errorf("expected alice available for two hours, got %v", got)
+	}
+	if _, ok := got["bob"]; ok {
+		t.Error("bob isn't available until later")
+	}
+}
+
+// ---- forecast ----
+
+func TestForecast(t *testing.T) {
+	users := setup(t)
+	setupAvailability(t)
+	ctx := context.Background()
+	now := time.Now()
+	from := now.Add(24 * time.Hour).Truncate(time.Hour)
+
+	_ = users.Put(ctx, &repo.User{RiderID: "alice", Depot: "galway"})
+	_ = users.Put(ctx, &repo.User{RiderID: "bob", Depot: "elsewhere"})
+	// Alice is free for two and a half hours; only two whole hours count.
+	if _, err := AddSlot(ctx, "alice", SlotRequest{Start: from, End: from.Add(150 * time.Minute)}, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AddSlot(ctx, "bob", SlotRequest{Start: from, End: from.Add(time.Hour)}, now); err != nil {
+		t.Fatal(err)
+	}
+	// Carol is on the rota for the third hour.
+	s := newShift(t, from.Add(2*time.Hour), 1, 1)
+	if _, err := AssignRider(ctx, s.ShiftID, "carol"); err != nil {
+		t.Fatal(err)
+	}
+
+	hours, err := Forecast(ctx, from, 4, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := []int{2, 1, 1, 0}
+	for i, h := range hours {
+		if h.Available != counts[i] {
+			t.Errorf("hour %d: %d available %v, want %d", i, h.Available, h.Riders, counts[i])
+		}
+	}
+
+	hours, err = Forecast(ctx, from, 1, "Galway")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hours[0].Riders) != 1 || hours[0].Riders[0] != "alice" {
+		t.Errorf("expected only alice in Galway, got %v", hours[0].Riders)
+	}
+
+	if _, err := Forecast(ctx, from, 0, ""); err == nil {
+		t.Error("expected an empty forecast to be rejected")
+	}
+}
+
+func TestAvailabilityHandlers_Auth(t *testing.T) {
+	setupAvailability(t)
+	cases := []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/api/availability/me", http.StatusUnauthorized},
+		{http.MethodPost, "/api/availability/me/slots", http.StatusUnauthorized},
+		{http.MethodGet, "/api/availability/forecast", http.StatusForbidden},
+		{http.MethodGet, "/api/availability/alice", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		rr := httptest.NewRecorder()
+		Availability(rr, httptest.NewRequest(c.method, c.path, nil))
+		if rr.Code != c.want {
+			t.Errorf("%s %s: status %d, want %d", c.method, c.path, rr.Code, c.want)
+		}
+	}
+}
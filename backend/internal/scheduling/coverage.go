@@ -0,0 +1,50 @@
+package scheduling
+
+import (
+	"context"
+	"time"
+)
+
+// Gap is a shift that isn't fully covered: it is short of riders, or
+// needs a dispatcher and has none.
+type Gap struct {
+	ShiftID         string    `json:"shiftId"`
+	Region          string    `json:"region"`
+	Start           time.Time `json:"start"`
+	End             time.Time `json:"end"`
+	RequiredRiders  int       `json:"requiredRiders"`
+	AssignedRiders  int       `json:"assignedRiders"`
+	RidersShort     int       `json:"ridersShort"`
+	NeedsDispatcher bool      `json:"needsDispatcher"`
+}
+
+// Gaps returns the coverage gaps among the shifts q selects that haven't
+// ended by now, in start order.
+func Gaps(ctx context.Context, q Query, now time.Time) ([]Gap, error) {
+	shifts, err := List(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Gap, 0)
+	for _, s := range shifts {
+		if !now.Before(s.End) {
+			continue
+		}
+		g := Gap{
+			ShiftID:         s.ShiftID,
+			Region:          s.Region,
+			Start:           s.Start,
+			End:             s.End,
+			RequiredRiders:  s.RequiredRiders,
+			AssignedRiders:  len(s.Riders),
+			NeedsDispatcher: s.RequiresDispatcher && s.Dispatcher == "",
+		}
+		if g.AssignedRiders < g.RequiredRiders {
+			g.RidersShort = g.RequiredRiders - g.AssignedRiders
+		}
+		if g.RidersShort > 0 || g.NeedsDispatcher {
+			out = append(out, g)
+		}
+	}
+	return out, nil
+}
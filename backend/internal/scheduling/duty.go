@@ -0,0 +1,139 @@
+package scheduling
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+)
+
+// Riders on a shift are put on duty when it starts: their status becomes
+// "available" until the shift's end, the same as toggling availability
+// in the app. When the shift ends, or a rider leaves it part-way through,
+// they go offline again unless they have since changed their
+// availability themselves. A rider out on a job keeps that status and
+// becomes available when the job is done.
+
+// StartDutyTicker syncs riders' availability with the rota immediately and
+// then every minute for as long as ctx is alive. Call once at server
+// startup.
+func StartDutyTicker(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		syncDuty(ctx, time.Now())
+		for {
+			select {
+			case <-ticker.C:
+				syncDuty(ctx, time.Now())
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	log.Println("[scheduling] duty ticker started (runs every minute)")
+}
+
+func syncDuty(ctx context.Context, now time.Time) {
+	if globalRepo == nil || usersRepo == nil {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+
+	all, err := globalRepo.List(ctx)
+	if err != nil {
+		log.Printf("[scheduling] duty: failed to list shifts: %v", err)
+		return
+	}
+	for i := range all {
+		s := &all[i]
+		var want []string
+		if !now.Before(s.Start) && now.Before(s.End) {
+			want = s.Riders
+		}
+		onDuty, changed := s.OnDuty, false
+		for _, r := range s.OnDuty {
+			if !contains(want, r) {
+				offDuty(ctx, s, r)
+				onDuty, changed = remove(onDuty, r), true
+			}
+		}
+		for _, r := range want {
+			if !contains(onDuty, r) && goOnDuty(ctx, s, r, now) {
+				onDuty, changed = append(append([]string(nil), onDuty...), r), true
+			}
+		}
+		if !changed {
+			continue
+		}
+		s.OnDuty = onDuty
+		if err := globalRepo.Put(ctx, s); err != nil {
+			log.Printf("[scheduling] duty: failed to save shift %s: %v", s.ShiftID, err)
+		}
+	}
+}
+
+// goOnDuty makes the rider available until the end of the shift and
+// reports whether it did.
+func goOnDuty(ctx context.Context, s *repo.Shift, rider string, now time.Time) bool {
+	u, ok, err := usersRepo.Get(ctx, rider)
+	if err != nil {
+		log.Printf("[scheduling] duty: failed to load rider %s: %v", rider, err)
+		return false
+	}
+	if !ok {
+		u = &repo.User{RiderID: rider, Name: rider, Tags: []string{"Rider"}}
+	}
+	until := s.End.UTC().Format(time.RFC3339)
+	if u.AvailableUntil == "" || u.AvailableUntil < until {
+		u.AvailableUntil = until
+	}
+	switch u.Status {
+	case "available":
+		if u.AvailableSince == "" {
+			u.AvailableSince = s.Start.UTC().Format(time.RFC3339)
+		}
+	case "on-job", "on-delivery":
+		// Keep the job status; finishing the job makes them available.
+	default:
+		u.Status = "available"
+		u.AvailableSince = s.Start.UTC().Format(time.RFC3339)
+	}
+	u.UpdatedAt = now
+	if err := usersRepo.Put(ctx, u); err != nil {
+		log.Printf("[scheduling] duty: failed to put %s on duty: %v", rider, err)
+		return false
+	}
+	log.Printf("[scheduling] %s on duty for shift %s until %s", rider, s.ShiftID, until)
+	return true
+}
+
+// offDuty clears the availability the shift gave the rider, if it is still
+// in place, leaving them with no status of their own so their calendar
+// applies again.
+func offDuty(ctx context.Context, s *repo.Shift, rider string) {
+	if usersRepo == nil {
+		return
+	}
+	u, ok, err := usersRepo.Get(ctx, rider)
+	if err != nil || !ok {
+		if err != nil {
+			log.Printf("[scheduling] duty: failed to load rider %s: %v", rider, err)
+		}
+		return
+	}
+	if u.Status != "available" || u.AvailableUntil != s.End.UTC().Format(time.RFC3339) {
+		return
+	}
+	u.Status = ""
+	u.AvailableUntil = ""
+	u.AvailableSince = ""
+	u.UpdatedAt = time.Now()
+	if err := usersRepo.Put(ctx, u); err != nil {
+		log.Printf("[scheduling] duty: failed to take %s off duty: %v", rider, err)
+		return
+	}
+	log.Printf("[scheduling] %s off duty after shift %s", rider, s.ShiftID)
+}
@@ -0,0 +1,123 @@
+package scheduling
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/depots"
+)
+
+// maxForecastHours bounds how far ahead a forecast looks.
+const maxForecastHours = 31 * 24
+
+// ForecastHour is how many riders are available for the whole of the hour
+// starting at Start.
+type ForecastHour struct {
+	Start     time.Time `json:"start"`
+	Available int       `json:"available"`
+	Riders    []string  `json:"riders"`
+}
+
+// Forecast returns, hour by hour from from, the riders available for each
+// hour: riders their calendar has available and riders on a rota shift.
+// A non-empty region narrows it to riders based there and shifts in it.
+func Forecast(ctx context.Context, from time.Time, hours int, region string) ([]ForecastHour, error) {
+	if hours <= 0 || hours > maxForecastHours {
+		return nil, invalid("a forecast must cover between 1 hour and 31 days")
+	}
+	if availabilityRepo == nil {
+		return nil, errors.New("availability not configured")
+	}
+	from = from.Truncate(time.Hour)
+	to := from.Add(time.Duration(hours) * time.Hour)
+
+	region, err := depots.Resolve(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	var based map[string]bool
+	if region != "" {
+		if based, err = ridersIn(ctx, region); err != nil {
+			return nil, err
+		}
+	}
+
+	windows := make(map[string][]Window)
+	cals, err := availabilityRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range cals {
+		if based != nil && !based[a.RiderID] {
+			continue
+		}
+		windows[a.RiderID] = append(windows[a.RiderID], Windows(a, from, to)...)
+	}
+	if globalRepo != nil {
+		shifts, err := List(ctx, Query{From: from, To: to, Region: region})
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range shifts {
+			for _, r := range s.Riders {
+				windows[r] = append(windows[r], Window{Start: s.Start, End: s.End})
+			}
+		}
+	}
+	for r, ws := range windows {
+		windows[r] = merge(ws)
+	}
+
+	out := make([]ForecastHour, 0, hours)
+	for h := from; h.Before(to); h = h.Add(time.Hour) {
+		fh := ForecastHour{Start: h, Riders: []string{}}
+		for r, ws := range windows {
+			if covers(ws, h, h.Add(time.Hour)) {
+				fh.Riders = append(fh.Riders, r)
+			}
+		}
+		sort.Strings(fh.Riders)
+		fh.Available = len(fh.Riders)
+		out = append(out, fh)
+	}
+	return out, nil
+}
+
+// covers reports whether one of the merged windows spans [start, end).
+func covers(ws []Window, start, end time.Time) bool {
+	for _, w := range ws {
+		if !w.Start.After(start) && !w.End.Before(end) {
+			return true
+		}
+	}
+	return false
+}
+
+// ridersIn returns the riders based in the region. Older riders hold the
+// depot's name rather than its ID, and before any depots have been created
+// a region is free text.
+func ridersIn(ctx context.Context, region string) (map[string]bool, error) {
+	out := make(map[string]bool)
+	if usersRepo == nil {
+		return out, nil
+	}
+	refs := []string{region}
+	if d, ok, err := depots.Get(ctx, region); err == nil && ok {
+		refs = append(refs, d.Name)
+	}
+	all, err := usersRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range all {
+		for _, ref := range refs {
+			if strings.EqualFold(strings.TrimSpace(u.Depot), ref) {
+				out[u.RiderID] = true
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,293 @@
+package scheduling
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/auth"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/depots"
+)
+
+// defaultListDays is how far ahead shifts are listed when no range is given.
+const defaultListDays = 14
+
+// ListOrCreate handles GET /api/shifts (?from=&to=&region=&rider=) and
+// POST /api/shifts (coordinators).
+func ListOrCreate(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		q, err := parseQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		items, err := List(r.Context(), q)
+		if err != nil {
+			writeError(w, "", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, items)
+	case http.MethodPost:
+		if !isCoordinator(r) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		s, err := Create(r.Context(), req)
+		if err != nil {
+			writeError(w, "", err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, s)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// Detail handles /api/shifts/...:
+//
+//	GET /api/shifts/mine                      the caller's shifts
+//	GET /api/shifts/gaps                      shifts short of riders or a dispatcher (coordinators)
+//	GET /api/shifts/swaps                     pending swap requests (coordinators)
+//	GET, PUT, DELETE /api/shifts/{id}         (PUT and DELETE coordinators)
+//	PUT/DELETE /api/shifts/{id}/riders/{riderId} assigns or removes a rider (coordinators)
+//	POST /api/shifts/{id}/swaps               the caller asks to hand their place to toRider
+//	DELETE /api/shifts/{id}/swaps/{swapId}    the requester cancels a pending swap
+//	POST /api/shifts/{id}/swaps/{swapId}/approve, .../reject (coordinators)
+func Detail(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/shifts/"), "/"), "/")
+	id := parts[0]
+	if id == "" {
+		http.NotFound(w, r)
+		return
+	}
+	username := auth.UsernameFromContext(r.Context())
+
+	switch {
+	case len(parts) == 1 && id == "mine" && r.Method == http.MethodGet:
+		q, err := parseQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if username == "" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		q.Rider = username
+		items, err := List(r.Context(), q)
+		if err != nil {
+			writeError(w, "", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, items)
+
+	case len(parts) == 1 && id == "gaps" && r.Method == http.MethodGet:
+		if !isCoordinator(r) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		q, err := parseQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		gaps, err := Gaps(r.Context(), q, time.Now())
+		if err != nil {
+			writeError(w, "", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, gaps)
+
+	case len(parts) == 1 && id == "swaps" && r.Method == http.MethodGet:
+		if !isCoordinator(r) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		swaps, err := PendingSwaps(r.Context(), time.Now())
+		if err != nil {
+			writeError(w, "", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, swaps)
+
+	case len(parts) == 1:
+		switch r.Method {
+		case http.MethodGet:
+			s, ok, err := Get(r.Context(), id)
+			if err != nil {
+				writeError(w, id, err)
+				return
+			}
+			if !ok {
+				http.Error(w, "shift not found", http.StatusNotFound)
+				return
+			}
+			writeJSON(w, http.StatusOK, s)
+		case http.MethodPut:
+			if !isCoordinator(r) {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			var req Request
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "invalid request body", http.StatusBadRequest)
+				return
+			}
+			s, err := Update(r.Context(), id, req)
+			if err != nil {
+				writeError(w, id, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, s)
+		case http.MethodDelete:
+			if !isCoordinator(r) {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			if err := Delete(r.Context(), id); err != nil {
+				writeError(w, id, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+
+	case len(parts) == 3 && parts[1] == "riders" && (r.Method == http.MethodPut || r.Method == http.MethodDelete):
+		if !isCoordinator(r) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		assign := AssignRider
+		if r.Method == http.MethodDelete {
+			assign = UnassignRider
+		}
+		s, err := assign(r.Context(), id, parts[2])
+		if err != nil {
+			writeError(w, id, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, s)
+
+	case len(parts) == 2 && parts[1] == "swaps" && r.Method == http.MethodPost:
+		if username == "" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var body struct {
+			ToRider string `json:"toRider"`
+			Note    string `json:"note,omitempty"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		_, sw, err := RequestSwap(r.Context(), id, username, body.ToRider, body.Note)
+		if err != nil {
+			writeError(w, id, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, sw)
+
+	case len(parts) == 3 && parts[1] == "swaps" && r.Method == http.MethodDelete:
+		s, err := CancelSwap(r.Context(), id, parts[2], username)
+		if err != nil {
+			writeError(w, id, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, s)
+
+	case len(parts) == 4 && parts[1] == "swaps" && (parts[3] == "approve" || parts[3] == "reject") && r.Method == http.MethodPost:
+		if !isCoordinator(r) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		s, err := DecideSwap(r.Context(), id, parts[2], username, parts[3] == "approve")
+		if err != nil {
+			writeError(w, id, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, s)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// parseQuery reads ?from= and ?to= (RFC 3339 times or YYYY-MM-DD dates,
+// from today for defaultListDays days by default), ?region= and ?rider=.
+func parseQuery(r *http.Request) (Query, error) {
+	v := r.URL.Query()
+	q := Query{Region: strings.TrimSpace(v.Get("region")), Rider: strings.TrimSpace(v.Get("rider"))}
+	var err error
+	if q.From, err = parseTime(v.Get("from")); err != nil {
+		return q, invalid("from must be a date (YYYY-MM-DD) or RFC 3339 time")
+	}
+	if q.To, err = parseTime(v.Get("to")); err != nil {
+		return q, invalid("to must be a date (YYYY-MM-DD) or RFC 3339 time")
+	}
+	if q.From.IsZero() {
+		q.From = time.Now().UTC().Truncate(24 * time.Hour)
+	}
+	if q.To.IsZero() {
+		q.To = q.From.AddDate(0, 0, defaultListDays)
+	}
+	if !q.To.After(q.From) {
+		return q, invalid("to must be after from")
+	}
+	return q, nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+func writeError(w http.ResponseWriter, id string, err error) {
+	switch {
+	case errors.Is(err, errNotFound):
+		http.Error(w, "shift not found", http.StatusNotFound)
+	case errors.Is(err, errSwapNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, errNotRequester):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, errConflict), errors.Is(err, errEnded), errors.Is(err, errOnRota), errors.Is(err, errNotOnRota),
+		errors.Is(err, errSwapDecided), errors.Is(err, errSwapPending):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case isValidationError(err):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("[scheduling] shift %s: %v", id, err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// isCoordinator reports whether the caller runs the rota: dispatchers and
+// above.
+func isCoordinator(r *http.Request) bool {
+	return auth.HasRoleOrAbove(auth.RolesFromContext(r.Context()), "Dispatcher")
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func isValidationError(err error) bool {
+	return errors.Is(err, errInvalid) || errors.Is(err, depots.ErrUnknownDepot)
+}
@@ -0,0 +1,293 @@
+package scheduling
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/depots"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo/memory"
+)
+
+func setup(t *testing.T) *memory.UsersRepo {
+	t.Helper()
+	users := memory.NewUsersRepo()
+	SetRepository(memory.NewShiftsRepo())
+	SetUsersRepository(users)
+	depots.SetRepository(memory.NewDepotsRepo())
+	_, _ = depots.Create(context.Background(), depots.Request{Name: "Galway"})
+	t.Cleanup(func() {
+		SetRepository(nil)
+		SetUsersRepository(nil)
+		depots.SetRepository(nil)
+	})
+	return users
+}
+
+func newShift(t *testing.T, start time.Time, hours, riders int) *repo.Shift {
+	t.Helper()
+	s, err := Create(context.Background(), Request{
+		Region: "Galway", Start: start, End: start.Add(time.Duration(hours) * time.Hour),
+		RequiredRiders: riders, RequiresDispatcher: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func night(days int) time.Time {
+	return time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, days).Add(19 * time.Hour)
+}
+
+// ---- shifts ----
+
+func TestCreate_Validation(t *testing.T) {
+	setup(t)
+	ctx := context.Background()
+	s := newShift(t, night(1), 12, 2)
+	if s.Region != "galway" || s.ShiftID == "" {
+		t.Errorf("expected the region resolved to the depot, got %+v", s)
+	}
+	start := night(1)
+	for _, req := range []Request{
+		{Start: start, End: start.Add(time.Hour)},
+		{Region: "Galway", Start: start, End: start},
+		{Region: "Galway", Start: start, End: start.Add(25 * time.Hour)},
+		{Region: "Galway", Start: start, End: start.Add(time.Hour), RequiredRiders: -1},
+		{Region: "Nowhere", Start: start, End: start.Add(time.Hour)},
+	} {
+		if _, err := Create(ctx, req); err == nil || !isValidationError(err) {
+			t.Errorf("expected %+v to fail validation, got %v", req, err)
+		}
+	}
+}
+
+func TestWriteError_StorageErrorsAre500(t *testing.T) {
+	cases := []struct {
+		err  error
+		want int
+	}{
+		{invalid("end must be after start"), http.StatusBadRequest},
+		{depots.ErrUnknownDepot, http.StatusBadRequest},
+		{errors.New("ValidationException: ExpressionAttributeValues must not be empty"), http.StatusInternalServerError},
+		{errors.New("ValidationException: One of the required keys was not given a value"), http.StatusInternalServerError},
+		{errors.New("operation error DynamoDB: can't reach endpoint"), http.StatusInternalServerError},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		writeError(rec, "s1", c.err)
+		if rec.Code != c.want {
+			t.Errorf("%v: got %d, want %d", c.err, rec.Code, c.want)
+		}
+	}
+}
+
+func TestAssign_Overlaps(t *testing.T) {
+	setup(t)
+	ctx := context.Background()
+	a := newShift(t, night(1), 12, 2)
+	b := newShift(t, night(1).Add(6*time.Hour), 12, 2)
+	c := newShift(t, night(2), 12, 2)
+
+	if _, err := AssignRider(ctx, a.ShiftID, "ann"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AssignRider(ctx, a.ShiftID, "ann"); !errors.Is(err, errOnRota) {
+		t.Errorf("expected a second assignment refused, got %v", err)
+	}
+	if _, err := AssignRider(ctx, b.ShiftID, "ann"); !errors.Is(err, errConflict) {
+		t.Errorf("expected an overlapping shift refused, got %v", err)
+	}
+	if _, err := AssignRider(ctx, c.ShiftID, "ann"); err != nil {
+		t.Errorf("expected the next night allowed, got %v", err)
+	}
+	if _, err := Update(ctx, b.ShiftID, Request{Region: "galway", Start: b.Start, End: b.End, Dispatcher: "ann"}); !errors.Is(err, errConflict) {
+		t.Errorf("expected ann refused as dispatcher on an overlapping shift, got %v", err)
+	}
+
+	mine, _ := List(ctx, Query{Rider: "ann"})
+	if len(mine) != 2 || mine[0].ShiftID != a.ShiftID {
+		t.Errorf("expected ann's two shifts in order, got %+v", mine)
+	}
+	if _, err := UnassignRider(ctx, a.ShiftID, "ann"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AssignRider(ctx, b.ShiftID, "ann"); err != nil {
+		t.Errorf("expected ann free once off the first shift, got %v", err)
+	}
+}
+
+// ---- swaps ----
+
+func TestSwaps(t *testing.T) {
+	setup(t)
+	ctx := context.Background()
+	s := newShift(t, night(1), 12, 2)
+	_, _ = AssignRider(ctx, s.ShiftID, "ann")
+	_, _ = AssignRider(ctx, s.ShiftID, "bob")
+	other := newShift(t, night(1), 12, 1)
+	_, _ = AssignRider(ctx, other.ShiftID, "dan")
+
+	if _, _, err := RequestSwap(ctx, s.ShiftID, "cat", "eve", ""); !errors.Is(err, errNotOnRota) {
+		t.Errorf("expected a rider off the shift refused, got %v", err)
+	}
+	if _, _, err := RequestSwap(ctx, s.ShiftID, "ann", "bob", ""); !errors.Is(err, errOnRota) {
+		t.Errorf("expected a swap with a rider already on the shift refused, got %v", err)
+	}
+	if _, _, err := RequestSwap(ctx, s.ShiftID, "ann", "dan", ""); !errors.Is(err, errConflict) {
+		t.Errorf("expected a busy rider refused, got %v", err)
+	}
+	_, sw, err := RequestSwap(ctx, s.ShiftID, "ann", "cat", "wedding")
+	if err != nil || sw.Status != SwapPending {
+		t.Fatalf("expected a pending swap, got %+v %v", sw, err)
+	}
+	if _, _, err := RequestSwap(ctx, s.ShiftID, "ann", "eve", ""); !errors.Is(err, errSwapPending) {
+		t.Errorf("expected one pending swap per rider, got %v", err)
+	}
+	if pending, _ := PendingSwaps(ctx, time.Now()); len(pending) != 1 || pending[0].ShiftID != s.ShiftID || pending[0].ToRider != "cat" {
+		t.Errorf("unexpected pending swaps %+v", pending)
+	}
+	if _, err := CancelSwap(ctx, s.ShiftID, sw.SwapID, "bob"); !errors.Is(err, errNotRequester) {
+		t.Errorf("expected only the requester to cancel, got %v", err)
+	}
+
+	got, err := DecideSwap(ctx, s.ShiftID, sw.SwapID, "disp", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Riders) != 2 || got.Riders[0] != "cat" || got.Riders[1] != "bob" {
+		t.Errorf("expected cat in ann's place, got %v", got.Riders)
+	}
+	if got.Swaps[0].Status != SwapApproved || got.Swaps[0].DecidedBy != "disp" {
+		t.Errorf("unexpected swap %+v", got.Swaps[0])
+	}
+	if _, err := DecideSwap(ctx, s.ShiftID, sw.SwapID, "disp", false); !errors.Is(err, errSwapDecided) {
+		t.Errorf("expected a decided swap final, got %v", err)
+	}
+
+	_, sw, _ = RequestSwap(ctx, s.ShiftID, "bob", "eve", "")
+	got, _ = DecideSwap(ctx, s.ShiftID, sw.SwapID, "disp", false)
+	if got.Riders[1] != "bob" || got.Swaps[1].Status != SwapRejected {
+		t.Errorf("expected a rejected swap to leave bob on, got %+v", got)
+	}
+}
+
+// ---- coverage ----
+
+func TestGaps(t *testing.T) {
+	setup(t)
+	ctx := context.Background()
+	now := time.Now()
+	short := newShift(t, night(1), 12, 2)
+	_, _ = AssignRider(ctx, short.ShiftID, "ann")
+	covered, _ := Create(ctx, Request{Region: "galway", Start: night(2), End: night(2).Add(12 * time.Hour), RequiredRiders: 1, RequiresDispatcher: true, Dispatcher: "disp"})
+	_, _ = AssignRider(ctx, covered.ShiftID, "ann")
+	noDispatcher := newShift(t, night(3), 12, 0)
+
+	gaps, err := Gaps(ctx, Query{}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gaps) != 2 {
+		t.Fatalf("expected 2 gaps, got %+v", gaps)
+	}
+	if gaps[0].ShiftID != short.ShiftID || gaps[0].RidersShort != 1 || !gaps[0].NeedsDispatcher {
+		t.Errorf("unexpected gap %+v", gaps[0])
+	}
+	if gaps[1].ShiftID != noDispatcher.ShiftID || gaps[1].RidersShort != 0 || !gaps[1].NeedsDispatcher {
+		t.Errorf("unexpected gap %+v", gaps[1])
+	}
+	if gaps, _ := Gaps(ctx, Query{}, night(5)); len(gaps) != 0 {
+		t.Errorf("expected ended shifts ignored, got %+v", gaps)
+	}
+}
+
+// ---- duty ----
+
+func TestSyncDuty(t *testing.T) {
+	users := setup(t)
+	ctx := context.Background()
+	start := night(1)
+	s := newShift(t, start, 12, 2)
+	_, _ = AssignRider(ctx, s.ShiftID, "ann")
+	_, _ = AssignRider(ctx, s.ShiftID, "bob")
+	_ = users.Put(ctx, &repo.User{RiderID: "bob", Status: "on-job", CurrentJobID: "job1"})
+
+	syncDuty(ctx, start.Add(-time.Minute))
+	if u, ok, _ := users.Get(ctx, "ann"); ok && u.Status == "available" {
+		t.Error("expected nobody on duty before the shift")
+	}
+
+	syncDuty(ctx, start.Add(time.Minute))
+	until := s.End.Format(time.RFC3339)
+	ann, _, _ := users.Get(ctx, "ann")
+	if ann.Status != "available" || ann.AvailableUntil != until || ann.AvailableSince != start.Format(time.RFC3339) {
+		t.Errorf("expected ann available for the shift, got %+v", ann)
+	}
+	bob, _, _ := users.Get(ctx, "bob")
+	if bob.Status != "on-job" || bob.AvailableUntil != until {
+		t.Errorf("expected bob kept on his job until the shift's end, got %+v", bob)
+	}
+	got, _, _ := Get(ctx, s.ShiftID)
+	if len(got.OnDuty) != 2 {
+		t.Errorf("expected both recorded on duty, got %v", got.OnDuty)
+	}
+
+	// Ann leaves part-way through; bob's job ends and he extends himself.
+	_, _ = UnassignRider(ctx, s.ShiftID, "ann")
+	bob.Status, bob.AvailableUntil = "available", s.End.Add(time.Hour).Format(time.RFC3339)
+	_ = users.Put(ctx, bob)
+	syncDuty(ctx, start.Add(time.Hour))
+	if ann, _, _ := users.Get(ctx, "ann"); ann.Status != "" || ann.AvailableUntil != "" {
+		t.Errorf("expected ann off duty with no status set, got %+v", ann)
+	}
+
+	syncDuty(ctx, s.End)
+	if bob, _, _ := users.Get(ctx, "bob"); bob.Status != "available" {
+		t.Errorf("expected bob's own extension kept, got %+v", bob)
+	}
+	if got, _, _ := Get(ctx, s.ShiftID); got.OnDuty != nil {
+		t.Errorf("expected nobody on duty after the shift, got %v", got.OnDuty)
+	}
+}
+
+// ---- handlers ----
+
+func TestHandlers_RequireCoordinator(t *testing.T) {
+	setup(t)
+	s := newShift(t, night(1), 12, 2)
+	for _, tc := range []struct{ method, path string }{
+		{http.MethodPost, "/api/shifts"},
+		{http.MethodGet, "/api/shifts/gaps"},
+		{http.MethodPut, "/api/shifts/" + s.ShiftID + "/riders/ann"},
+		{http.MethodPost, "/api/shifts/" + s.ShiftID + "/swaps/swp_x/approve"},
+	} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(tc.method, tc.path, bytes.NewReader([]byte(`{}`)))
+		if tc.path == "/api/shifts" {
+			ListOrCreate(rec, req)
+		} else {
+			Detail(rec, req)
+		}
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("%s %s: expected 403, got %d", tc.method, tc.path, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	ListOrCreate(rec, httptest.NewRequest(http.MethodGet, "/api/shifts?from=2026-01-02&to=2026-01-01", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected a backwards range to 400, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	Detail(rec, httptest.NewRequest(http.MethodGet, "/api/shifts/"+s.ShiftID, nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected the shift, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,348 @@
+// Package scheduling runs each region's on-call rota: shifts with the
+// riders and dispatcher covering them, swap requests between riders, and
+// checks for shifts that aren't fully covered. Riders on a shift are made
+// available when it starts and go offline when it ends (see duty.go).
+package scheduling
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/depots"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+)
+
+// maxShiftLength bounds a single shift.
+const maxShiftLength = 24 * time.Hour
+
+var (
+	errNotFound  = errors.New("not found")
+	errConflict  = errors.New("already on an overlapping shift")
+	errEnded     = errors.New("shift has ended")
+	errNotOnRota = errors.New("rider is not on this shift")
+	errOnRota    = errors.New("rider is already on this shift")
+
+	// errInvalid marks a request the caller got wrong; see invalid.
+	errInvalid = errors.New("invalid request")
+)
+
+// invalid returns a validation error carrying msg that matches errInvalid,
+// so handlers answer it with a 400.
+func invalid(msg string) error {
+	return invalidError(msg)
+}
+
+type invalidError string
+
+func (e invalidError) Error() string        { return string(e) }
+func (e invalidError) Is(target error) bool { return target == errInvalid }
+
+var (
+	globalRepo repo.ShiftsRepository
+	usersRepo  repo.UsersRepository
+
+	// mu serialises read-modify-write changes to shifts.
+	mu sync.Mutex
+)
+
+func SetRepository(r repo.ShiftsRepository) {
+	globalRepo = r
+}
+
+// SetUsersRepository gives the rota access to riders' availability.
+func SetUsersRepository(r repo.UsersRepository) {
+	usersRepo = r
+}
+
+// Request creates or replaces a shift's details. Riders are assigned
+// separately.
+type Request struct {
+	Region             string    `json:"region"`
+	Start              time.Time `json:"start"`
+	End                time.Time `json:"end"`
+	RequiredRiders     int       `json:"requiredRiders"`
+	RequiresDispatcher bool      `json:"requiresDispatcher"`
+	Dispatcher         string    `json:"dispatcher,omitempty"`
+	Notes              string    `json:"notes,omitempty"`
+}
+
+func (req *Request) validate(ctx context.Context) error {
+	req.Dispatcher = strings.TrimSpace(req.Dispatcher)
+	req.Notes = strings.TrimSpace(req.Notes)
+	if strings.TrimSpace(req.Region) == "" {
+		return invalid("region required")
+	}
+	region, err := depots.Resolve(ctx, req.Region)
+	if err != nil {
+		return err
+	}
+	req.Region = region
+	if req.Start.IsZero() || req.End.IsZero() {
+		return invalid("start and end required")
+	}
+	if !req.End.After(req.Start) {
+		return invalid("end must be after start")
+	}
+	if req.End.Sub(req.Start) > maxShiftLength {
+		return invalid("a shift can't be longer than 24 hours")
+	}
+	if req.RequiredRiders < 0 {
+		return invalid("requiredRiders must not be negative")
+	}
+	return nil
+}
+
+func (req Request) apply(s *repo.Shift) {
+	s.Region = req.Region
+	s.Start = req.Start.UTC()
+	s.End = req.End.UTC()
+	s.RequiredRiders = req.RequiredRiders
+	s.RequiresDispatcher = req.RequiresDispatcher
+	s.Dispatcher = req.Dispatcher
+	s.Notes = req.Notes
+}
+
+// Query selects shifts overlapping [From, To). Region and Rider narrow it
+// to one region or to the shifts a user rides or dispatches.
+type Query struct {
+	From, To time.Time
+	Region   string
+	Rider    string
+}
+
+// List returns the shifts q selects, in start order.
+func List(ctx context.Context, q Query) ([]repo.Shift, error) {
+	if globalRepo == nil {
+		return nil, errors.New("shifts not configured")
+	}
+	all, err := globalRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]repo.Shift, 0)
+	for _, s := range all {
+		if !q.From.IsZero() && !s.End.After(q.From) {
+			continue
+		}
+		if !q.To.IsZero() && !s.Start.Before(q.To) {
+			continue
+		}
+		if q.Region != "" && !strings.EqualFold(s.Region, q.Region) {
+			continue
+		}
+		if q.Rider != "" && !onShift(s, q.Rider) {
+			continue
+		}
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Start.Equal(out[j].Start) {
+			return out[i].Start.Before(out[j].Start)
+		}
+		return out[i].ShiftID < out[j].ShiftID
+	})
+	return out, nil
+}
+
+func Get(ctx context.Context, id string) (*repo.Shift, bool, error) {
+	if globalRepo == nil {
+		return nil, false, errors.New("shifts not configured")
+	}
+	return globalRepo.Get(ctx, id)
+}
+
+func Create(ctx context.Context, req Request) (*repo.Shift, error) {
+	if globalRepo == nil {
+		return nil, errors.New("shifts not configured")
+	}
+	if err := req.validate(ctx); err != nil {
+		return nil, err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+
+	now := time.Now()
+	s := &repo.Shift{ShiftID: newID("shf_"), CreatedAt: now, UpdatedAt: now}
+	req.apply(s)
+	if err := checkDispatcher(ctx, s); err != nil {
+		return nil, err
+	}
+	if err := globalRepo.Put(ctx, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Update replaces a shift's details, keeping its riders and swaps.
+func Update(ctx context.Context, id string, req Request) (*repo.Shift, error) {
+	if err := req.validate(ctx); err != nil {
+		return nil, err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+
+	s, err := getShift(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	req.apply(s)
+	if err := checkDispatcher(ctx, s); err != nil {
+		return nil, err
+	}
+	for _, r := range s.Riders {
+		if err := checkFree(ctx, s, r); err != nil {
+			return nil, fmt.Errorf("%s is %w", r, err)
+		}
+	}
+	s.UpdatedAt = time.Now()
+	if err := globalRepo.Put(ctx, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Delete removes a shift. Riders it made available go off duty.
+func Delete(ctx context.Context, id string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	s, err := getShift(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, r := range s.OnDuty {
+		offDuty(ctx, s, r)
+	}
+	_, err = globalRepo.Delete(ctx, id)
+	return err
+}
+
+// AssignRider puts the rider on the shift.
+func AssignRider(ctx context.Context, id, rider string) (*repo.Shift, error) {
+	rider = strings.TrimSpace(rider)
+	if rider == "" {
+		return nil, invalid("riderId required")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+
+	s, err := getShift(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !time.Now().Before(s.End) {
+		return nil, errEnded
+	}
+	if contains(s.Riders, rider) {
+		return nil, errOnRota
+	}
+	if err := checkFree(ctx, s, rider); err != nil {
+		return nil, err
+	}
+	s.Riders = append(append([]string(nil), s.Riders...), rider)
+	s.UpdatedAt = time.Now()
+	if err := globalRepo.Put(ctx, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// UnassignRider takes the rider off the shift.
+func UnassignRider(ctx context.Context, id, rider string) (*repo.Shift, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	s, err := getShift(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !contains(s.Riders, rider) {
+		return nil, errNotOnRota
+	}
+	s.Riders = remove(s.Riders, rider)
+	s.UpdatedAt = time.Now()
+	if err := globalRepo.Put(ctx, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// getShift loads a shift for changing. Callers hold mu.
+func getShift(ctx context.Context, id string) (*repo.Shift, error) {
+	s, ok, err := Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errNotFound
+	}
+	return s, nil
+}
+
+// checkFree refuses a user who rides or dispatches another shift that
+// overlaps s.
+func checkFree(ctx context.Context, s *repo.Shift, user string) error {
+	all, err := globalRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, o := range all {
+		if o.ShiftID != s.ShiftID && o.Start.Before(s.End) && s.Start.Before(o.End) && onShift(o, user) {
+			return errConflict
+		}
+	}
+	return nil
+}
+
+func checkDispatcher(ctx context.Context, s *repo.Shift) error {
+	if s.Dispatcher == "" {
+		return nil
+	}
+	if contains(s.Riders, s.Dispatcher) {
+		return invalid("the dispatcher can't also ride the shift")
+	}
+	if err := checkFree(ctx, s, s.Dispatcher); err != nil {
+		return fmt.Errorf("dispatcher %s is %w", s.Dispatcher, err)
+	}
+	return nil
+}
+
+// onShift reports whether user rides or dispatches the shift.
+func onShift(s repo.Shift, user string) bool {
+	return s.Dispatcher == user || contains(s.Riders, user)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func remove(list []string, s string) []string {
+	out := make([]string, 0, len(list))
+	for _, v := range list {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+func newID(prefix string) string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return prefix + hex.EncodeToString(b)
+}
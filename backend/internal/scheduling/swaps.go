@@ -0,0 +1,187 @@
+package scheduling
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+)
+
+const (
+	SwapPending   = "pending"
+	SwapApproved  = "approved"
+	SwapRejected  = "rejected"
+	SwapCancelled = "cancelled"
+)
+
+var (
+	errSwapNotFound = errors.New("swap request not found")
+	errSwapDecided  = errors.New("swap request has already been decided")
+	errSwapPending  = errors.New("a swap request for this shift is already pending")
+	errNotRequester = errors.New("only the rider who asked can cancel a swap request")
+)
+
+// RequestSwap asks for from's place on the shift to go to to.
+func RequestSwap(ctx context.Context, id, from, to, note string) (*repo.Shift, *repo.ShiftSwap, error) {
+	to = strings.TrimSpace(to)
+	if to == "" {
+		return nil, nil, invalid("toRider required")
+	}
+	if to == from {
+		return nil, nil, invalid("can't swap a shift with yourself")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+
+	s, err := getShift(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !time.Now().Before(s.End) {
+		return nil, nil, errEnded
+	}
+	if !contains(s.Riders, from) {
+		return nil, nil, errNotOnRota
+	}
+	if onShift(*s, to) {
+		return nil, nil, errOnRota
+	}
+	for _, sw := range s.Swaps {
+		if sw.FromRider == from && sw.Status == SwapPending {
+			return nil, nil, errSwapPending
+		}
+	}
+	if err := checkFree(ctx, s, to); err != nil {
+		return nil, nil, err
+	}
+
+	sw := repo.ShiftSwap{
+		SwapID:      newID("swp_"),
+		FromRider:   from,
+		ToRider:     to,
+		Note:        strings.TrimSpace(note),
+		Status:      SwapPending,
+		RequestedAt: time.Now(),
+	}
+	s.Swaps = append(append([]repo.ShiftSwap(nil), s.Swaps...), sw)
+	s.UpdatedAt = time.Now()
+	if err := globalRepo.Put(ctx, s); err != nil {
+		return nil, nil, err
+	}
+	return s, &sw, nil
+}
+
+// DecideSwap approves or rejects a pending swap. Approving it hands the
+// requester's place on the shift to the other rider.
+func DecideSwap(ctx context.Context, id, swapID, decidedBy string, approve bool) (*repo.Shift, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	s, err := getShift(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	sw, err := pendingSwap(s, swapID)
+	if err != nil {
+		return nil, err
+	}
+	sw.Status = SwapRejected
+	if approve {
+		if !time.Now().Before(s.End) {
+			return nil, errEnded
+		}
+		if !contains(s.Riders, sw.FromRider) {
+			return nil, errNotOnRota
+		}
+		if onShift(*s, sw.ToRider) {
+			return nil, errOnRota
+		}
+		if err := checkFree(ctx, s, sw.ToRider); err != nil {
+			return nil, err
+		}
+		riders := append([]string(nil), s.Riders...)
+		for i, r := range riders {
+			if r == sw.FromRider {
+				riders[i] = sw.ToRider
+			}
+		}
+		s.Riders = riders
+		sw.Status = SwapApproved
+	}
+	sw.DecidedBy, sw.DecidedAt = decidedBy, time.Now()
+	s.UpdatedAt = time.Now()
+	if err := globalRepo.Put(ctx, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// CancelSwap withdraws a pending swap; only its requester may.
+func CancelSwap(ctx context.Context, id, swapID, username string) (*repo.Shift, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	s, err := getShift(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	sw, err := pendingSwap(s, swapID)
+	if err != nil {
+		return nil, err
+	}
+	if sw.FromRider != username {
+		return nil, errNotRequester
+	}
+	sw.Status = SwapCancelled
+	sw.DecidedBy, sw.DecidedAt = username, time.Now()
+	s.UpdatedAt = time.Now()
+	if err := globalRepo.Put(ctx, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// pendingSwap returns the swap on s for changing in place.
+func pendingSwap(s *repo.Shift, swapID string) (*repo.ShiftSwap, error) {
+	s.Swaps = append([]repo.ShiftSwap(nil), s.Swaps...)
+	for i := range s.Swaps {
+		if s.Swaps[i].SwapID == swapID {
+			if s.Swaps[i].Status != SwapPending {
+				return nil, errSwapDecided
+			}
+			return &s.Swaps[i], nil
+		}
+	}
+	return nil, errSwapNotFound
+}
+
+// PendingSwap is a swap awaiting a decision, with the shift it is for.
+type PendingSwap struct {
+	ShiftID string    `json:"shiftId"`
+	Region  string    `json:"region"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	repo.ShiftSwap
+}
+
+// PendingSwaps returns the swap requests awaiting a decision on shifts that
+// haven't ended, oldest request first.
+func PendingSwaps(ctx context.Context, now time.Time) ([]PendingSwap, error) {
+	shifts, err := List(ctx, Query{From: now})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]PendingSwap, 0)
+	for _, s := range shifts {
+		for _, sw := range s.Swaps {
+			if sw.Status == SwapPending {
+				out = append(out, PendingSwap{ShiftID: s.ShiftID, Region: s.Region, Start: s.Start, End: s.End, ShiftSwap: sw})
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].RequestedAt.Before(out[j].RequestedAt) })
+	return out, nil
+}
@@ -0,0 +1,97 @@
+// Package signer signs tokens with an HMAC key read from the environment,
+// for codes and links that are used without the app's bearer token: bike
+// QR labels, calendar feed URLs and attachment downloads.
+package signer
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+var enc = base64.RawURLEncoding
+
+// Signer signs payloads for one purpose. The purpose is mixed into every
+// MAC, so a token made for one use never verifies for another, even when
+// two uses share a key.
+type Signer struct {
+	env      string
+	purpose  string
+	prefix   string
+	macBytes int
+
+	once   sync.Once
+	random []byte
+}
+
+// New returns a signer keyed by the env var. Tokens start with prefix,
+// which names the format, and carry the first macBytes of the HMAC-SHA256.
+// The key is read when used, so env loaded after startup is seen.
+func New(env, purpose, prefix string, macBytes int) *Signer {
+	return &Signer{env: env, purpose: purpose, prefix: prefix, macBytes: macBytes}
+}
+
+func (s *Signer) key() []byte {
+	if k := os.Getenv(s.env); k != "" {
+		return []byte(k)
+	}
+	s.once.Do(func() {
+		log.Printf("[signer] %s not set – %s tokens will stop working on restart", s.env, s.purpose)
+		s.random = make([]byte, 32)
+		_, _ = rand.Read(s.random)
+	})
+	return s.random
+}
+
+// Configured reports whether the env var is set. Without it the key is
+// random per process, so nothing signed verifies after a restart or on
+// another instance.
+func (s *Signer) Configured() bool {
+	return os.Getenv(s.env) != ""
+}
+
+func (s *Signer) sum(payload string) []byte {
+	mac := hmac.New(sha256.New, s.key())
+	mac.Write([]byte(s.purpose + "\n" + payload))
+	return mac.Sum(nil)[:s.macBytes]
+}
+
+// MAC returns the payload's MAC, base64url encoded, for callers that carry
+// the payload themselves.
+func (s *Signer) MAC(payload string) string {
+	return enc.EncodeToString(s.sum(payload))
+}
+
+// CheckMAC reports whether mac is the payload's MAC.
+func (s *Signer) CheckMAC(payload, mac string) bool {
+	raw, err := enc.DecodeString(mac)
+	return err == nil && hmac.Equal(raw, s.sum(payload))
+}
+
+// Token returns the payload and its MAC as one string:
+// <prefix><base64url payload>.<base64url MAC>
+func (s *Signer) Token(payload string) string {
+	return s.prefix + enc.EncodeToString([]byte(payload)) + "." + s.MAC(payload)
+}
+
+// Parse verifies a token and returns its payload.
+func (s *Signer) Parse(token string) (string, bool) {
+	rest, ok := strings.CutPrefix(token, s.prefix)
+	if !ok {
+		return "", false
+	}
+	rawPayload, mac, ok := strings.Cut(rest, ".")
+	if !ok {
+		return "", false
+	}
+	payload, err := enc.DecodeString(rawPayload)
+	if err != nil || !s.CheckMAC(string(payload), mac) {
+		return "", false
+	}
+	return string(payload), true
+}
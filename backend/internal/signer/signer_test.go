@@ -0,0 +1,46 @@
+package signer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToken_RoundTrip(t *testing.T) {
+	t.Setenv("TEST_SIGNER_SECRET", "s3cret")
+	s := New("TEST_SIGNER_SECRET", "test", "t1.", 12)
+	token := s.Token("hello\nworld")
+	if !strings.HasPrefix(token, "t1.") {
+		t.Fatalf("unexpected token %q", token)
+	}
+	if got, ok := s.Parse(token); !ok || got != "hello\nworld" {
+		t.Errorf("got %q ok=%v", got, ok)
+	}
+	for _, bad := range []string{"", "t1.", token + "x", "t2" + token[2:], token[:strings.LastIndex(token, ".")]} {
+		if _, ok := s.Parse(bad); ok {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestPurposeSeparatesTokens(t *testing.T) {
+	t.Setenv("TEST_SIGNER_SECRET", "s3cret")
+	a := New("TEST_SIGNER_SECRET", "a", "", 16)
+	b := New("TEST_SIGNER_SECRET", "b", "", 16)
+	if _, ok := b.Parse(a.Token("x")); ok {
+		t.Error("a token for one purpose should not verify for another")
+	}
+	if !a.CheckMAC("x", a.MAC("x")) || a.CheckMAC("y", a.MAC("x")) {
+		t.Error("CheckMAC")
+	}
+}
+
+func TestConfigured(t *testing.T) {
+	t.Setenv("TEST_SIGNER_SECRET", "")
+	s := New("TEST_SIGNER_SECRET", "test", "", 16)
+	if s.Configured() {
+		t.Error("expected unset key to be reported")
+	}
+	if _, ok := s.Parse(s.Token("x")); !ok {
+		t.Error("the random key should still sign and verify within the process")
+	}
+}
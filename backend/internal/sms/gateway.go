@@ -0,0 +1,143 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Gateway hands a text message to an SMS provider. ref is our message ID,
+// which the provider echoes back in delivery status callbacks; Send
+// returns the provider's own ID for the message, if it has one.
+type Gateway interface {
+	Name() string
+	Send(ctx context.Context, to, body, ref string) (providerID string, err error)
+}
+
+// GatewayFromEnv picks the gateway from SMS_GATEWAY:
+//
+//	stub (default)  writes messages to SMS_STUB_FILE (../data/sms.log)
+//	http            posts them to SMS_GATEWAY_URL
+func GatewayFromEnv() (Gateway, error) {
+	switch kind := strings.ToLower(strings.TrimSpace(os.Getenv("SMS_GATEWAY"))); kind {
+	case "", "stub":
+		path := strings.TrimSpace(os.Getenv("SMS_STUB_FILE"))
+		if path == "" {
+			path = filepath.Join("..", "data", "sms.log")
+		}
+		return NewFileGateway(path), nil
+	case "http":
+		return NewHTTPGateway(HTTPGatewayConfig{
+			URL:            os.Getenv("SMS_GATEWAY_URL"),
+			Token:          os.Getenv("SMS_GATEWAY_TOKEN"),
+			From:           os.Getenv("SMS_FROM"),
+			StatusCallback: os.Getenv("SMS_STATUS_CALLBACK_URL"),
+		})
+	default:
+		return nil, fmt.Errorf("unknown SMS_GATEWAY %q (want stub or http)", kind)
+	}
+}
+
+// FileGateway is the local stub: it appends each message to a file as a
+// JSON line instead of sending it.
+type FileGateway struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileGateway(path string) *FileGateway {
+	return &FileGateway{path: path}
+}
+
+func (g *FileGateway) Name() string { return "stub" }
+
+func (g *FileGateway) Send(_ context.Context, to, body, ref string) (string, error) {
+	line, err := json.Marshal(map[string]any{"at": time.Now().UTC(), "to": to, "body": body, "ref": ref})
+	if err != nil {
+		return "", err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(g.path), 0o755); err != nil {
+		return "", err
+	}
+	f, err := os.OpenFile(g.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return "", err
+	}
+	return "stub-" + ref, nil
+}
+
+// HTTPGatewayConfig configures an HTTP SMS gateway.
+type HTTPGatewayConfig struct {
+	URL            string // endpoint messages are POSTed to
+	Token          string // sent as a Bearer token
+	From           string // sender ID or number
+	StatusCallback string // where the provider should POST delivery status (optional)
+}
+
+// HTTPGateway posts each message as JSON:
+//
+//	{"to": "+353…", "from": "…", "body": "…", "reference": "sms_…", "statusCallback": "…"}
+//
+// and expects a 2xx reply, optionally {"id": "…"}.
+type HTTPGateway struct {
+	cfg    HTTPGatewayConfig
+	client *http.Client
+}
+
+func NewHTTPGateway(cfg HTTPGatewayConfig) (*HTTPGateway, error) {
+	if strings.TrimSpace(cfg.URL) == "" {
+		return nil, fmt.Errorf("SMS_GATEWAY_URL must be set for the http SMS gateway")
+	}
+	return &HTTPGateway{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+func (g *HTTPGateway) Name() string { return "http" }
+
+func (g *HTTPGateway) Send(ctx context.Context, to, body, ref string) (string, error) {
+	payload, err := json.Marshal(map[string]string{
+		"to":             to,
+		"from":           g.cfg.From,
+		"body":           body,
+		"reference":      ref,
+		"statusCallback": g.cfg.StatusCallback,
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.cfg.URL, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if g.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.cfg.Token)
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("gateway returned %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	var out struct {
+		ID string `json:"id"`
+	}
+	_ = json.Unmarshal(respBody, &out)
+	return out.ID, nil
+}
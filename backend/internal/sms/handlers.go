@@ -0,0 +1,97 @@
+package sms
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/auth"
+)
+
+// HandleStatus handles POST /api/sms/status, the gateway's delivery report
+// callback:
+//
+//	{"reference": "sms_…", "status": "delivered" | "failed", "error": "…"}
+//
+// It sits outside auth, so the gateway must send SMS_CALLBACK_TOKEN in the
+// X-SMS-Token header. With no token configured the callback is off.
+func (s *Sender) HandleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	token := os.Getenv("SMS_CALLBACK_TOKEN")
+	if token == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-SMS-Token")), []byte(token)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var body struct {
+		Reference string `json:"reference"`
+		Status    string `json:"status"`
+		Error     string `json:"error"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(body.Reference) == "" {
+		http.Error(w, "reference required", http.StatusBadRequest)
+		return
+	}
+	m, err := s.UpdateStatus(r.Context(), body.Reference, strings.ToLower(body.Status), body.Error)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, m)
+}
+
+// HandleMessages handles GET /api/sms/messages (?username=&status=), the
+// log of texts sent, for dispatchers and above.
+func (s *Sender) HandleMessages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if auth.UsernameFromContext(r.Context()) == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !auth.HasRoleOrAbove(auth.RolesFromContext(r.Context()), "Dispatcher") {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	q := r.URL.Query()
+	ms, err := s.Messages(r.Context(), strings.TrimSpace(q.Get("username")), strings.TrimSpace(q.Get("status")))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ms)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errMessageNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case strings.Contains(err.Error(), "must"), strings.Contains(err.Error(), "required"):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("[sms] %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
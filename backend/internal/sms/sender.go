@@ -0,0 +1,212 @@
+// Package sms sends text messages through a pluggable gateway (see
+// gateway.go), with Irish numbers normalised to E.164, per-number and
+// overall rate limits, and every message recorded so its delivery status
+// can be tracked. It is the fallback channel when a push doesn't reach a
+// user.
+package sms
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/notifyprefs"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/phonenum"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+)
+
+// Message statuses.
+const (
+	StatusSent        = "sent"
+	StatusDelivered   = "delivered"
+	StatusFailed      = "failed"
+	StatusRateLimited = "rate-limited"
+)
+
+const (
+	// maxBodyLength keeps a message to at most three SMS segments.
+	maxBodyLength = 459
+	// perNumberLimit messages may go to one number each perNumberWindow.
+	perNumberLimit  = 5
+	perNumberWindow = time.Hour
+	// globalLimit messages may go out in total each globalWindow.
+	globalLimit  = 60
+	globalWindow = time.Minute
+)
+
+var (
+	// ErrRateLimited is returned when a rate limit holds a message back.
+	ErrRateLimited = errors.New("sms rate limit reached")
+	// ErrNoPhone is returned when the user has no phone number saved.
+	ErrNoPhone = errors.New("no phone number for user")
+
+	errMessageNotFound = errors.New("message not found")
+)
+
+// Sender sends and tracks text messages.
+type Sender struct {
+	gateway Gateway
+	repo    repo.SMSMessagesRepository
+	now     func() time.Time
+
+	mu        sync.Mutex
+	perNumber map[string][]time.Time
+	global    []time.Time
+}
+
+func NewSender(gateway Gateway, r repo.SMSMessagesRepository) *Sender {
+	return &Sender{
+		gateway:   gateway,
+		repo:      r,
+		now:       time.Now,
+		perNumber: make(map[string][]time.Time),
+	}
+}
+
+// Send texts body to the number and records the message. The record is
+// returned even when sending fails, along with the error.
+func (s *Sender) Send(ctx context.Context, to, username, category, body string) (*repo.SMSMessage, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, errors.New("body required")
+	}
+	if r := []rune(body); len(r) > maxBodyLength {
+		body = string(r[:maxBodyLength-1]) + "…"
+	}
+	number, err := phonenum.E164(to)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now().UTC()
+	m := &repo.SMSMessage{
+		MessageID: newID("sms_"),
+		To:        number,
+		Username:  username,
+		Category:  category,
+		Body:      body,
+		Gateway:   s.gateway.Name(),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if !s.allow(number, now) {
+		m.Status = StatusRateLimited
+		if err := s.repo.Put(ctx, m); err != nil {
+			return nil, err
+		}
+		log.Printf("[sms] rate limit reached, not sending %s to %s", m.MessageID, number)
+		return m, ErrRateLimited
+	}
+
+	providerID, sendErr := s.gateway.Send(ctx, number, body, m.MessageID)
+	m.ProviderID = providerID
+	m.Status = StatusSent
+	if sendErr != nil {
+		m.Status = StatusFailed
+		m.Error = sendErr.Error()
+		log.Printf("[sms] send %s to %s failed: %v", m.MessageID, number, sendErr)
+	}
+	if err := s.repo.Put(ctx, m); err != nil {
+		return nil, err
+	}
+	if sendErr != nil {
+		return m, fmt.Errorf("send sms: %w", sendErr)
+	}
+	return m, nil
+}
+
+// NotifyUser texts the user if their preferences allow the category by
+// SMS right now and they have a phone number saved.
+func (s *Sender) NotifyUser(ctx context.Context, category, username, title, body string) (*repo.SMSMessage, error) {
+	if !notifyprefs.Allowed(ctx, username, category, notifyprefs.ChannelSMS, s.now()) {
+		return nil, nil
+	}
+	p, err := notifyprefs.Get(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if p.Phone == "" {
+		return nil, ErrNoPhone
+	}
+	text := title
+	if body != "" {
+		text += ": " + body
+	}
+	return s.Send(ctx, p.Phone, username, category, text)
+}
+
+// UpdateStatus records a delivery report from the gateway. Only
+// "delivered" and "failed" are accepted.
+func (s *Sender) UpdateStatus(ctx context.Context, messageID, status, reason string) (*repo.SMSMessage, error) {
+	if status != StatusDelivered && status != StatusFailed {
+		return nil, fmt.Errorf("status must be %s or %s", StatusDelivered, StatusFailed)
+	}
+	m, ok, err := s.repo.Get(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errMessageNotFound
+	}
+	m.Status = status
+	m.Error = reason
+	m.UpdatedAt = s.now().UTC()
+	if err := s.repo.Put(ctx, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Messages lists recorded messages, newest first, optionally only those
+// for a user or with a status.
+func (s *Sender) Messages(ctx context.Context, username, status string) ([]repo.SMSMessage, error) {
+	all, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]repo.SMSMessage, 0, len(all))
+	for _, m := range all {
+		if (username == "" || m.Username == username) && (status == "" || m.Status == status) {
+			out = append(out, m)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+// allow takes a slot from both rate limits, or reports that one is full.
+func (s *Sender) allow(number string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.global = since(s.global, now.Add(-globalWindow))
+	recent := since(s.perNumber[number], now.Add(-perNumberWindow))
+	if len(s.global) >= globalLimit || len(recent) >= perNumberLimit {
+		s.perNumber[number] = recent
+		return false
+	}
+	s.global = append(s.global, now)
+	s.perNumber[number] = append(recent, now)
+	return true
+}
+
+// since drops the times at or before cutoff from the oldest-first list.
+func since(times []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(times) && !times[i].After(cutoff) {
+		i++
+	}
+	return times[i:]
+}
+
+func newID(prefix string) string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return prefix + hex.EncodeToString(b)
+}
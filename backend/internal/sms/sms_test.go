@@ -0,0 +1,230 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/notifyprefs"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo"
+	"github.com/AdamGallagher339/Codename-Blood/backend/internal/repo/memory"
+)
+
+// fakeGateway records what it is asked to send.
+type fakeGateway struct {
+	sent []string
+	err  error
+}
+
+func (g *fakeGateway) Name() string { return "fake" }
+
+func (g *fakeGateway) Send(_ context.Context, to, body, ref string) (string, error) {
+	if g.err != nil {
+		return "", g.err
+	}
+	g.sent = append(g.sent, to)
+	return "p-" + ref, nil
+}
+
+func newTestSender(t *testing.T) (*Sender, *fakeGateway) {
+	t.Helper()
+	g := &fakeGateway{}
+	s := NewSender(g, memory.NewSMSMessagesRepo())
+	now := time.Date(2027, 1, 12, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	return s, g
+}
+
+// ---- sending ----
+
+func TestSend_NormalisesAndRecords(t *testing.T) {
+	s, g := newTestSender(t)
+	m, err := s.Send(context.Background(), "087 123 4567", "rita", notifyprefs.CategoryJobUpdates, "Job delivered")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.To != "+353871234567" || m.Status != StatusSent || m.ProviderID != "p-"+m.MessageID {
+		t.Errorf("unexpected record %+v", m)
+	}
+	if len(g.sent) != 1 || g.sent[0] != "+353871234567" {
+		t.Errorf("gateway got %v", g.sent)
+	}
+	if _, err := s.Send(context.Background(), "091 123456", "rita", "", "hi"); err == nil {
+		t.Error("a landline should be refused")
+	}
+}
+
+func TestSend_GatewayFailureRecorded(t *testing.T) {
+	s, g := newTestSender(t)
+	g.err = errors.New("boom")
+	m, err := s.Send(context.Background(), "+353871234567", "rita", "", "hi")
+	if err == nil || m == nil || m.Status != StatusFailed || m.Error != "boom" {
+		t.Fatalf("expected a failed record, got %+v, %v", m, err)
+	}
+}
+
+func TestSend_RateLimits(t *testing.T) {
+	s, g := newTestSender(t)
+	ctx := context.Background()
+	for i := 0; i < perNumberLimit; i++ {
+		if _, err := s.Send(ctx, "0871234567", "rita", "", "hi"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m, err := s.Send(ctx, "0871234567", "rita", "", "hi")
+	if !errors.Is(err, ErrRateLimited) || m.Status != StatusRateLimited {
+		t.Fatalf("expected rate limit, got %+v, %v", m, err)
+	}
+	if _, err := s.Send(ctx, "0877654321", "sam", "", "hi"); err != nil {
+		t.Errorf("another number should still get through: %v", err)
+	}
+
+	later := s.now().Add(perNumberWindow)
+	s.now = func() time.Time { return later }
+	if _, err := s.Send(ctx, "0871234567", "rita", "", "hi"); err != nil {
+		t.Errorf("limit should reset after the window: %v", err)
+	}
+	if len(g.sent) != perNumberLimit+2 {
+		t.Errorf("gateway sent %d, want %d", len(g.sent), perNumberLimit+2)
+	}
+}
+
+func TestNotifyUser_Preferences(t *testing.T) {
+	notifyprefs.SetRepository(memory.NewNotificationPreferencesRepo())
+	t.Cleanup(func() { notifyprefs.SetRepository(nil) })
+	s, g := newTestSender(t)
+	ctx := context.Background()
+
+	if m, err := s.NotifyUser(ctx, notifyprefs.CategoryJobUpdates, "rita", "Delivered", "Job 1"); m != nil || err != nil {
+		t.Fatalf("SMS is off by default, got %+v, %v", m, err)
+	}
+	if _, err := notifyprefs.Update(ctx, "rita", repo.NotificationPreferences{
+		Channels: map[string]bool{notifyprefs.ChannelSMS: true},
+		Phone:    "+353 (0) 87 123 4567",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	m, err := s.NotifyUser(ctx, notifyprefs.CategoryJobUpdates, "rita", "Delivered", "Job 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Body != "Delivered: Job 1" || len(g.sent) != 1 {
+		t.Errorf("unexpected message %+v", m)
+	}
+}
+
+// ---- delivery status ----
+
+func TestUpdateStatus(t *testing.T) {
+	s, _ := newTestSender(t)
+	ctx := context.Background()
+	m, _ := s.Send(ctx, "0871234567", "rita", "", "hi")
+	if _, err := s.UpdateStatus(ctx, m.MessageID, "queued", ""); err == nil {
+		t.Error("unknown status should be refused")
+	}
+	if _, err := s.UpdateStatus(ctx, "sms_missing", StatusDelivered, ""); !errors.Is(err, errMessageNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+	if _, err := s.UpdateStatus(ctx, m.MessageID, StatusDelivered, ""); err != nil {
+		t.Fatal(err)
+	}
+	ms, _ := s.Messages(ctx, "rita", StatusDelivered)
+	if len(ms) != 1 {
+		t.Errorf("expected 1 delivered message, got %d", len(ms))
+	}
+}
+
+func TestHandleStatus(t *testing.T) {
+	s, _ := newTestSender(t)
+	m, _ := s.Send(context.Background(), "0871234567", "rita", "", "hi")
+	post := func(token, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/sms/status", strings.NewReader(body))
+		req.Header.Set("X-SMS-Token", token)
+		rec := httptest.NewRecorder()
+		s.HandleStatus(rec, req)
+		return rec.Code
+	}
+	body := `{"reference":"` + m.MessageID + `","status":"delivered"}`
+
+	t.Setenv("SMS_CALLBACK_TOKEN", "")
+	if code := post("", body); code != http.StatusNotFound {
+		t.Errorf("callback without a token configured: got %d", code)
+	}
+	t.Setenv("SMS_CALLBACK_TOKEN", "secret")
+	if code := post("wrong", body); code != http.StatusUnauthorized {
+		t.Errorf("bad token: got %d", code)
+	}
+	if code := post("secret", body); code != http.StatusOK {
+		t.Errorf("good token: got %d", code)
+	}
+	if got, _, _ := s.repo.Get(context.Background(), m.MessageID); got.Status != StatusDelivered {
+		t.Errorf("status = %s", got.Status)
+	}
+}
+
+func TestHandleMessages_RequiresAuth(t *testing.T) {
+	s, _ := newTestSender(t)
+	rec := httptest.NewRecorder()
+	s.HandleMessages(rec, httptest.NewRequest(http.MethodGet, "/api/sms/messages", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("got %d, want 401", rec.Code)
+	}
+}
+
+// ---- gateways ----
+
+func TestFileGateway(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sms.log")
+	g := NewFileGateway(path)
+	if _, err := g.Send(context.Background(), "+353871234567", "hi", "sms_1"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var line map[string]any
+	if err := json.Unmarshal(data, &line); err != nil || line["to"] != "+353871234567" || line["ref"] != "sms_1" {
+		t.Errorf("unexpected stub line %s", data)
+	}
+}
+
+func TestHTTPGateway(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte(`{"id":"prov-1"}`))
+	}))
+	defer srv.Close()
+
+	g, err := NewHTTPGateway(HTTPGatewayConfig{URL: srv.URL, Token: "tok", From: "BloodBikes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := g.Send(context.Background(), "+353871234567", "hi", "sms_1")
+	if err != nil || id != "prov-1" {
+		t.Fatalf("got %q, %v", id, err)
+	}
+	if got["to"] != "+353871234567" || got["from"] != "BloodBikes" || got["reference"] != "sms_1" {
+		t.Errorf("unexpected payload %v", got)
+	}
+
+	bad, _ := NewHTTPGateway(HTTPGatewayConfig{URL: srv.URL})
+	if _, err := bad.Send(context.Background(), "+353871234567", "hi", "sms_2"); err == nil {
+		t.Error("a rejected send should be an error")
+	}
+	if _, err := NewHTTPGateway(HTTPGatewayConfig{}); err == nil {
+		t.Error("a gateway without a URL should be refused")
+	}
+}
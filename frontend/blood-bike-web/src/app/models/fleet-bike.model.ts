@@ -10,7 +10,7 @@ export interface FleetBike {
   updatedAt: Date;
 }
 
-export type FleetServiceType = 'oil' | 'chain' | 'tyres' | 'brakes' | 'coolant';
+export type FleetServiceType = 'oil' | 'chain' | 'tyres' | 'brakes' | 'coolant' | 'repair';
 
 export interface ServiceEntry {
   serviceId: string;
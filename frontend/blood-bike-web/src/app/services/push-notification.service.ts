@@ -32,10 +32,14 @@ export class PushNotificationService {
       await this.ensurePushServiceWorker();
 
       // 1. Get VAPID public key from backend
-      const { publicKey } = await firstValueFrom(
-        this.http.get<{ publicKey: string }>('/api/push/vapid-key')
+      const { publicKey, keyId } = await firstValueFrom(
+        this.http.get<{ publicKey: string; keyId: string }>('/api/push/vapid-key')
       );
-      console.log('Push: got VAPID key, SwPush.isEnabled =', this.swPush.isEnabled);
+      console.log('Push: got VAPID key', keyId, 'SwPush.isEnabled =', this.swPush.isEnabled);
+
+      // A subscription made with an older key (before a key rotation) can't
+      // be reused; the browser refuses to subscribe again until it is gone.
+      await this.dropSubscriptionForOtherKey(publicKey);
 
       let subJson: any;
 
@@ -57,9 +61,9 @@ export class PushNotificationService {
         subJson = sub.toJSON();
       }
 
-      // 2. Send subscription to backend
+      // 2. Send subscription to backend, with the key it was made with
       await firstValueFrom(
-        this.http.post('/api/push/subscribe', subJson)
+        this.http.post('/api/push/subscribe', { ...subJson, keyId })
       );
 
       this.subscribed = true;
@@ -109,6 +113,22 @@ export class PushNotificationService {
     }
   }
 
+  /** Unsubscribe the browser if its subscription uses a different VAPID key */
+  private async dropSubscriptionForOtherKey(publicKey: string): Promise<void> {
+    const reg = await navigator.serviceWorker.ready;
+    const existing = await reg.pushManager.getSubscription();
+    const existingKey = existing?.options.applicationServerKey;
+    if (!existing || !existingKey) return;
+
+    const wanted = this.urlBase64ToUint8Array(publicKey);
+    const current = new Uint8Array(existingKey);
+    const same = current.length === wanted.length && current.every((b, i) => b === wanted[i]);
+    if (!same) {
+      console.log('Push: existing subscription uses an old VAPID key, resubscribing');
+      await existing.unsubscribe();
+    }
+  }
+
   /** Convert a base64url-encoded string to a Uint8Array (for applicationServerKey) */
   private urlBase64ToUint8Array(base64String: string): Uint8Array {
     const padding = '='.repeat((4 - (base64String.length % 4)) % 4);
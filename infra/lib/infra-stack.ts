@@ -6,6 +6,8 @@ import * as golambda from '@aws-cdk/aws-lambda-go-alpha';
 import * as apigw from 'aws-cdk-lib/aws-apigateway';
 import * as cognito from 'aws-cdk-lib/aws-cognito';
 import * as iam from 'aws-cdk-lib/aws-iam';
+import * as s3 from 'aws-cdk-lib/aws-s3';
+import * as secretsmanager from 'aws-cdk-lib/aws-secretsmanager';
 
 
 export class InfraStack extends Stack {
@@ -65,6 +67,13 @@ export class InfraStack extends Stack {
       billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,
     });
 
+    const bikeServiceTable = new dynamodb.Table(this, 'BikeServiceTable', {
+      tableName: 'BikeService',
+      partitionKey: { name: 'BikeID', type: dynamodb.AttributeType.STRING },
+      sortKey: { name: 'ServiceID', type: dynamodb.AttributeType.STRING },
+      billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,
+    });
+
     // Optional / forward-compat tables (backend code supports them but may not expose endpoints yet)
     const depotsTable = new dynamodb.Table(this, 'DepotsTable', {
       tableName: 'Depots',
@@ -83,6 +92,117 @@ export class InfraStack extends Stack {
       projectionType: dynamodb.ProjectionType.ALL,
     });
 
+    // Notification outbox: every notification and each recipient's
+    // delivery, so queued sends and retries survive a restart.
+    const notificationsTable = new dynamodb.Table(this, 'NotificationsTable', {
+      tableName: 'Notifications',
+      partitionKey: { name: 'NotificationID', type: dynamodb.AttributeType.STRING },
+      billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,
+    });
+    notificationsTable.addGlobalSecondaryIndex({
+      indexName: 'Status-CreatedAt-index',
+      partitionKey: { name: 'Status', type: dynamodb.AttributeType.STRING },
+      sortKey: { name: 'CreatedAt', type: dynamodb.AttributeType.STRING },
+      projectionType: dynamodb.ProjectionType.ALL,
+    });
+
+    const notificationDeliveriesTable = new dynamodb.Table(this, 'NotificationDeliveriesTable', {
+      tableName: 'NotificationDeliveries',
+      partitionKey: { name: 'DeliveryID', type: dynamodb.AttributeType.STRING },
+      billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,
+    });
+    notificationDeliveriesTable.addGlobalSecondaryIndex({
+      indexName: 'Status-NextAttemptAt-index',
+      partitionKey: { name: 'Status', type: dynamodb.AttributeType.STRING },
+      sortKey: { name: 'NextAttemptAt', type: dynamodb.AttributeType.STRING },
+      projectionType: dynamodb.ProjectionType.ALL,
+    });
+    notificationDeliveriesTable.addGlobalSecondaryIndex({
+      indexName: 'NotificationID-CreatedAt-index',
+      partitionKey: { name: 'NotificationID', type: dynamodb.AttributeType.STRING },
+      sortKey: { name: 'CreatedAt', type: dynamodb.AttributeType.STRING },
+      projectionType: dynamodb.ProjectionType.ALL,
+    });
+
+    // On-call rota shifts and swap requests
+    const shiftsTable = new dynamodb.Table(this, 'ShiftsTable', {
+      tableName: 'Shifts',
+      partitionKey: { name: 'shiftId', type: dynamodb.AttributeType.STRING },
+      billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,
+    });
+
+    // Riders' availability slots and weekly patterns
+    const availabilityTable = new dynamodb.Table(this, 'AvailabilityTable', {
+      tableName: 'Availability',
+      partitionKey: { name: 'riderId', type: dynamodb.AttributeType.STRING },
+      billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,
+    });
+
+    // Per-job message threads between dispatch and riders
+    const jobMessagesTable = new dynamodb.Table(this, 'JobMessagesTable', {
+      tableName: 'JobMessages',
+      partitionKey: { name: 'MessageID', type: dynamodb.AttributeType.STRING },
+      billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,
+    });
+
+    // Per-user notification categories, channels and quiet hours
+    const notificationPrefsTable = new dynamodb.Table(this, 'NotificationPrefsTable', {
+      tableName: 'NotificationPreferences',
+      partitionKey: { name: 'Username', type: dynamodb.AttributeType.STRING },
+      billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,
+    });
+
+    // Text messages sent or refused through the SMS gateway
+    const smsMessagesTable = new dynamodb.Table(this, 'SMSMessagesTable', {
+      tableName: 'SMSMessages',
+      partitionKey: { name: 'MessageID', type: dynamodb.AttributeType.STRING },
+      billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,
+    });
+
+    // Insurance, motor tax and NCT documents with their expiry dates
+    const bikeDocumentsTable = new dynamodb.Table(this, 'BikeDocumentsTable', {
+      tableName: 'BikeDocuments',
+      partitionKey: { name: 'DocumentID', type: dynamodb.AttributeType.STRING },
+      billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,
+    });
+
+    // Photo and file attachments on issue reports and service entries
+    const attachmentsTable = new dynamodb.Table(this, 'AttachmentsTable', {
+      tableName: 'Attachments',
+      partitionKey: { name: 'AttachmentID', type: dynamodb.AttributeType.STRING },
+      billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,
+    });
+
+    // Attachment files and their thumbnails (BLOB_STORE=s3). Files are only
+    // served through the API's signed links, never straight from the bucket.
+    const attachmentsBucket = new s3.Bucket(this, 'AttachmentsBucket', {
+      blockPublicAccess: s3.BlockPublicAccess.BLOCK_ALL,
+      encryption: s3.BucketEncryption.S3_MANAGED,
+      enforceSSL: true,
+    });
+
+    // HMAC keys for links used without a Cognito token (see the signer
+    // package). Rotating one invalidates every link it signed.
+    const attachmentUrlSecret = new secretsmanager.Secret(this, 'AttachmentUrlSecret', {
+      description: 'Signs attachment download links (ATTACHMENT_URL_SECRET)',
+      generateSecretString: { passwordLength: 64, excludePunctuation: true },
+    });
+    const bikeQrSecret = new secretsmanager.Secret(this, 'BikeQrSecret', {
+      description: 'Signs bike QR label tokens (BIKE_QR_SECRET); rotating it invalidates printed labels',
+      generateSecretString: { passwordLength: 64, excludePunctuation: true },
+    });
+    const calendarFeedSecret = new secretsmanager.Secret(this, 'CalendarFeedSecret', {
+      description: 'Signs iCal feed URLs (CALENDAR_FEED_SECRET)',
+      generateSecretString: { passwordLength: 64, excludePunctuation: true },
+    });
+
+    // Shared with the SMS gateway, which sends it in X-SMS-Token on delivery
+    // status callbacks.
+    const smsCallbackToken = new secretsmanager.Secret(this, 'SmsCallbackToken', {
+      description: 'Authenticates SMS gateway status callbacks (SMS_CALLBACK_TOKEN)',
+      generateSecretString: { passwordLength: 48, excludePunctuation: true },
+    });
+
     // ------------------------------
     //      GET BIKES LAMBDA
     // ------------------------------
@@ -207,27 +327,64 @@ export class InfraStack extends Stack {
           // DynamoDB tables (main backend)
           USERS_TABLE: usersTable.tableName,
           BIKES_TABLE: bikesTable.tableName,
+          BIKE_SERVICE_TABLE: bikeServiceTable.tableName,
           DEPOTS_TABLE: depotsTable.tableName,
           JOBS_TABLE: jobsTable.tableName,
 
           // DynamoDB tables (fleet tracker)
           FLEET_BIKES_TABLE: fleetBikesTable.tableName,
           FLEET_SERVICE_TABLE: fleetServiceTable.tableName,
+          BIKE_DOCUMENTS_TABLE: bikeDocumentsTable.tableName,
 
           // DynamoDB tables (ride sessions & issue reports)
           RIDE_SESSIONS_TABLE: rideSessionsTable.tableName,
           ISSUE_REPORTS_TABLE: issueReportsTable.tableName,
+          ATTACHMENTS_TABLE: attachmentsTable.tableName,
+
+          // Attachment files
+          BLOB_STORE: 's3',
+          S3_BUCKET: attachmentsBucket.bucketName,
+
+          // Link signing keys
+          ATTACHMENT_URL_SECRET: attachmentUrlSecret.secretValue.unsafeUnwrap(),
+          BIKE_QR_SECRET: bikeQrSecret.secretValue.unsafeUnwrap(),
+          CALENDAR_FEED_SECRET: calendarFeedSecret.secretValue.unsafeUnwrap(),
+
+          // DynamoDB tables (notifications)
+          NOTIFICATIONS_TABLE: notificationsTable.tableName,
+          NOTIFICATION_DELIVERIES_TABLE: notificationDeliveriesTable.tableName,
+          NOTIFICATION_PREFS_TABLE: notificationPrefsTable.tableName,
+          SMS_MESSAGES_TABLE: smsMessagesTable.tableName,
+          SMS_CALLBACK_TOKEN: smsCallbackToken.secretValue.unsafeUnwrap(),
+
+          // DynamoDB tables (rota & availability)
+          SHIFTS_TABLE: shiftsTable.tableName,
+          AVAILABILITY_TABLE: availabilityTable.tableName,
+
+          // DynamoDB tables (job messages)
+          JOB_MESSAGES_TABLE: jobMessagesTable.tableName,
         },
       });
 
       usersTable.grantReadWriteData(backendApiLambda);
       bikesTable.grantReadWriteData(backendApiLambda);
+      bikeServiceTable.grantReadWriteData(backendApiLambda);
       depotsTable.grantReadWriteData(backendApiLambda);
       jobsTable.grantReadWriteData(backendApiLambda);
       fleetBikesTable.grantReadWriteData(backendApiLambda);
       fleetServiceTable.grantReadWriteData(backendApiLambda);
       rideSessionsTable.grantReadWriteData(backendApiLambda);
       issueReportsTable.grantReadWriteData(backendApiLambda);
+      notificationsTable.grantReadWriteData(backendApiLambda);
+      notificationDeliveriesTable.grantReadWriteData(backendApiLambda);
+      shiftsTable.grantReadWriteData(backendApiLambda);
+      availabilityTable.grantReadWriteData(backendApiLambda);
+      jobMessagesTable.grantReadWriteData(backendApiLambda);
+      notificationPrefsTable.grantReadWriteData(backendApiLambda);
+      smsMessagesTable.grantReadWriteData(backendApiLambda);
+      bikeDocumentsTable.grantReadWriteData(backendApiLambda);
+      attachmentsTable.grantReadWriteData(backendApiLambda);
+      attachmentsBucket.grantReadWrite(backendApiLambda);
 
       // Allow syncing roles to Cognito groups (Admin* APIs).
       backendApiLambda.addToRolePolicy(
@@ -244,7 +401,8 @@ export class InfraStack extends Stack {
       // ------------------------------
       //      /api/* ROUTING
       // ------------------------------
-      // Public endpoints: /api/health and signup/confirm/signin.
+      // Public endpoints: /api/health, signup/confirm/signin, signed
+      // attachment links, calendar feeds and SMS status callbacks.
       // Protected endpoints: everything else under /api/{proxy+} via Cognito authorizer.
 
       const backendIntegration = new apigw.LambdaIntegration(backendApiLambda);
@@ -286,6 +444,73 @@ export class InfraStack extends Stack {
         allowHeaders: ['Authorization', 'Content-Type'],
       });
 
+      // /api/attachments (protected) with public, signed content links:
+      // GET /api/attachments/{id}/content|thumbnail?exp=&sig=
+      const attachmentsResource = apiResource.addResource('attachments');
+      attachmentsResource.addMethod('ANY', backendIntegration, {
+        authorizer,
+        authorizationType: apigw.AuthorizationType.COGNITO,
+      });
+      attachmentsResource.addCorsPreflight({
+        allowOrigins: apigw.Cors.ALL_ORIGINS,
+        allowMethods: apigw.Cors.ALL_METHODS,
+        allowHeaders: ['Authorization', 'Content-Type'],
+      });
+
+      const attachmentResource = attachmentsResource.addResource('{id}');
+      attachmentResource.addMethod('ANY', backendIntegration, {
+        authorizer,
+        authorizationType: apigw.AuthorizationType.COGNITO,
+      });
+      attachmentResource.addCorsPreflight({
+        allowOrigins: apigw.Cors.ALL_ORIGINS,
+        allowMethods: apigw.Cors.ALL_METHODS,
+        allowHeaders: ['Authorization', 'Content-Type'],
+      });
+
+      for (const variant of ['content', 'thumbnail']) {
+        const variantResource = attachmentResource.addResource(variant);
+        variantResource.addMethod('GET', backendIntegration);
+        variantResource.addCorsPreflight({
+          allowOrigins: apigw.Cors.ALL_ORIGINS,
+          allowMethods: ['GET', 'OPTIONS'],
+          allowHeaders: ['Authorization', 'Content-Type'],
+        });
+      }
+
+      // /api/calendar/links and /import (protected); calendar apps fetch the
+      // signed feeds, GET /api/calendar/{token}.ics, without a token.
+      const calendarResource = apiResource.addResource('calendar');
+      for (const path of ['links', 'import']) {
+        const protectedResource = calendarResource.addResource(path);
+        protectedResource.addMethod('ANY', backendIntegration, {
+          authorizer,
+          authorizationType: apigw.AuthorizationType.COGNITO,
+        });
+        protectedResource.addCorsPreflight({
+          allowOrigins: apigw.Cors.ALL_ORIGINS,
+          allowMethods: apigw.Cors.ALL_METHODS,
+          allowHeaders: ['Authorization', 'Content-Type'],
+        });
+      }
+      const calendarFeedResource = calendarResource.addResource('{token}');
+      calendarFeedResource.addMethod('GET', backendIntegration);
+      calendarFeedResource.addMethod('HEAD', backendIntegration);
+
+      // POST /api/sms/status (public; checked against SMS_CALLBACK_TOKEN)
+      const smsResource = apiResource.addResource('sms');
+      smsResource.addResource('status').addMethod('POST', backendIntegration);
+      const smsMessagesResource = smsResource.addResource('messages');
+      smsMessagesResource.addMethod('ANY', backendIntegration, {
+        authorizer,
+        authorizationType: apigw.AuthorizationType.COGNITO,
+      });
+      smsMessagesResource.addCorsPreflight({
+        allowOrigins: apigw.Cors.ALL_ORIGINS,
+        allowMethods: apigw.Cors.ALL_METHODS,
+        allowHeaders: ['Authorization', 'Content-Type'],
+      });
+
       // /api/{proxy+} (protected)
       const apiProxy = apiResource.addProxy({ anyMethod: false });
       apiProxy.addMethod('ANY', backendIntegration, {
@@ -308,8 +533,20 @@ export class InfraStack extends Stack {
       // Outputs for backend integration
       new CfnOutput(this, 'UsersTableName', { value: usersTable.tableName });
       new CfnOutput(this, 'BikesTableName', { value: bikesTable.tableName });
+      new CfnOutput(this, 'BikeServiceTableName', { value: bikeServiceTable.tableName });
       new CfnOutput(this, 'DepotsTableName', { value: depotsTable.tableName });
       new CfnOutput(this, 'JobsTableName', { value: jobsTable.tableName });
+      new CfnOutput(this, 'NotificationsTableName', { value: notificationsTable.tableName });
+      new CfnOutput(this, 'NotificationDeliveriesTableName', { value: notificationDeliveriesTable.tableName });
+      new CfnOutput(this, 'ShiftsTableName', { value: shiftsTable.tableName });
+      new CfnOutput(this, 'AvailabilityTableName', { value: availabilityTable.tableName });
+      new CfnOutput(this, 'JobMessagesTableName', { value: jobMessagesTable.tableName });
+      new CfnOutput(this, 'NotificationPreferencesTableName', { value: notificationPrefsTable.tableName });
+      new CfnOutput(this, 'SMSMessagesTableName', { value: smsMessagesTable.tableName });
+      new CfnOutput(this, 'BikeDocumentsTableName', { value: bikeDocumentsTable.tableName });
+      new CfnOutput(this, 'AttachmentsTableName', { value: attachmentsTable.tableName });
+      new CfnOutput(this, 'AttachmentsBucketName', { value: attachmentsBucket.bucketName });
+      new CfnOutput(this, 'SmsCallbackTokenSecretArn', { value: smsCallbackToken.secretArn });
 
 
   }